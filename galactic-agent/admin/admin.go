@@ -0,0 +1,187 @@
+// Package admin serves a read-only JSON view of the agent for dashboards
+// and scripts that can't speak gRPC: its VPCs and attachments, the routes
+// it programmed and its configuration, secrets redacted. Like the
+// dashboard it has no authentication, so it should only listen on
+// localhost or a unix socket only trusted users can reach.
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/datum-cloud/galactic-agent/state"
+)
+
+// VPC is a VPC with attachments on this agent.
+type VPC struct {
+	VPC         string             `json:"vpc"`
+	Tenant      string             `json:"tenant"`
+	Attachments []state.Attachment `json:"attachments"`
+	// Routes counts the routes received for its attachments.
+	Routes int `json:"routes"`
+}
+
+// Route is a route received from a controller and programmed.
+type Route struct {
+	Tenant string `json:"tenant"`
+	VPC    string `json:"vpc"`
+	state.Egress
+}
+
+// Source is where the API reads the agent from.
+type Source struct {
+	VPCs   func() []VPC
+	Routes func() []Route
+	Config func() map[string]any
+}
+
+// Handler serves:
+//
+//	/api/v1/vpcs          every VPC
+//	/api/v1/vpcs/{vpc}    one VPC
+//	/api/v1/routes        every route, or those of ?vpc=
+//	/api/v1/config        the settings, secrets redacted
+func Handler(src Source) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/vpcs", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, src.VPCs())
+	})
+	mux.HandleFunc("GET /api/v1/vpcs/{vpc}", func(w http.ResponseWriter, r *http.Request) {
+		for _, v := range src.VPCs() {
+			if v.VPC == r.PathValue("vpc") {
+				writeJSON(w, v)
+				return
+			}
+		}
+		http.Error(w, "vpc not found", http.StatusNotFound)
+	})
+	mux.HandleFunc("GET /api/v1/routes", func(w http.ResponseWriter, r *http.Request) {
+		routes := src.Routes()
+		if vpc := r.URL.Query().Get("vpc"); vpc != "" {
+			var matching []Route
+			for _, route := range routes {
+				if route.VPC == vpc {
+					matching = append(matching, route)
+				}
+			}
+			routes = matching
+		}
+		if routes == nil {
+			routes = []Route{}
+		}
+		writeJSON(w, routes)
+	})
+	mux.HandleFunc("GET /api/v1/config", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, redact(src.Config()))
+	})
+	return mux
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		slog.Warn("Admin API: encode JSON", "err", err)
+	}
+}
+
+// secretSuffixes end the names of settings whose values are redacted;
+// headers may carry credentials, e.g. tracing_headers.
+var secretSuffixes = []string{"password", "token", "secret", "key", "psk", "headers"}
+
+// redact returns settings with the values of secrets replaced, in nested
+// maps and lists too.
+func redact(settings map[string]any) map[string]any {
+	out := make(map[string]any, len(settings))
+	for k, v := range settings {
+		if isSecret(k) {
+			if v != nil && v != "" {
+				v = "REDACTED"
+			}
+		} else {
+			v = redactValue(v)
+		}
+		out[k] = v
+	}
+	return out
+}
+
+func redactValue(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		return redact(v)
+	case []any:
+		out := make([]any, len(v))
+		for i, e := range v {
+			out[i] = redactValue(e)
+		}
+		return out
+	}
+	return v
+}
+
+func isSecret(key string) bool {
+	key = strings.ToLower(key)
+	for _, s := range secretSuffixes {
+		if strings.HasSuffix(key, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// Serve exposes the API on addr, host:port, and socketPath, a unix socket,
+// whichever are set, until ctx is done.
+func Serve(ctx context.Context, addr, socketPath string, src Source) error {
+	var listeners []net.Listener
+	if addr != "" {
+		l, err := net.Listen("tcp", addr)
+		if err != nil {
+			return err
+		}
+		listeners = append(listeners, l)
+	}
+	if socketPath != "" {
+		// unlinked first, as the local API's socket
+		if err := os.Remove(socketPath); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		l, err := net.Listen("unix", socketPath)
+		if err != nil {
+			for _, l := range listeners {
+				l.Close() //nolint:errcheck
+			}
+			return err
+		}
+		listeners = append(listeners, l)
+	}
+	s := &http.Server{Handler: Handler(src), ReadHeaderTimeout: 5 * time.Second}
+
+	routineErr := make(chan error, len(listeners))
+	for _, l := range listeners {
+		go func() {
+			slog.Info("Admin API listening", "network", l.Addr().Network(), "address", l.Addr().String())
+			if err := s.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				routineErr <- err
+				return
+			}
+			routineErr <- nil
+		}()
+	}
+
+	var err error
+	select {
+	case <-ctx.Done():
+	case err = <-routineErr:
+	}
+	s.Close() //nolint:errcheck
+	return err
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"github.com/spf13/viper"
+
+	"github.com/datum-cloud/galactic-agent/admin"
+)
+
+// adminSource reads the admin API's views from the tenant stores.
+func adminSource() admin.Source {
+	return admin.Source{
+		VPCs:   adminVPCs,
+		Routes: adminRoutes,
+		Config: viper.AllSettings,
+	}
+}
+
+func adminVPCs() []admin.VPC {
+	vpcs := []admin.VPC{}
+	index := make(map[string]int)
+	for _, t := range tenantMap.All() {
+		st := domains[t].store.Snapshot()
+		for _, a := range st.Attachments {
+			i, ok := index[a.VPC]
+			if !ok {
+				i = len(vpcs)
+				index[a.VPC] = i
+				vpcs = append(vpcs, admin.VPC{VPC: a.VPC, Tenant: t.Name})
+			}
+			vpcs[i].Attachments = append(vpcs[i].Attachments, a)
+		}
+		for _, e := range st.Egress {
+			if i, ok := index[endpointVPC(e.Endpoint)]; ok {
+				vpcs[i].Routes++
+			}
+		}
+	}
+	return vpcs
+}
+
+func adminRoutes() []admin.Route {
+	routes := []admin.Route{}
+	for _, t := range tenantMap.All() {
+		for _, e := range domains[t].store.Snapshot().Egress {
+			routes = append(routes, admin.Route{Tenant: t.Name, VPC: endpointVPC(e.Endpoint), Egress: e})
+		}
+	}
+	return routes
+}
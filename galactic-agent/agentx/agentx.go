@@ -0,0 +1,287 @@
+// Package agentx is an AgentX (RFC 2741) subagent: it connects to the
+// master agent of the host's SNMP daemon, such as net-snmp's snmpd with
+// "master agentx", registers a subtree and answers the master's Get,
+// GetNext and GetBulk requests for it. The subtree is read-only.
+package agentx
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultSocket is where net-snmp's master agent listens by default.
+const DefaultSocket = "/var/agentx/master"
+
+// Subagent serves the variables Handler returns under Root. Socket is a
+// unix socket path, or tcp:host:port.
+type Subagent struct {
+	Socket string
+	Root   OID
+	Name   string
+	// Handler returns the variables under Root; it is called for every
+	// request, so values are current.
+	Handler func() ([]Var, error)
+
+	mu     sync.Mutex
+	packet uint32
+}
+
+const retryInterval = 10 * time.Second
+
+// Run keeps a session with the master agent open until ctx is done,
+// reconnecting whenever the master goes away.
+func (s *Subagent) Run(ctx context.Context) error {
+	for {
+		err := s.session(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		slog.Warn("AgentX session", "retry_in", retryInterval, "err", err)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(retryInterval):
+		}
+	}
+}
+
+func (s *Subagent) dial(ctx context.Context) (net.Conn, error) {
+	var d net.Dialer
+	if addr, ok := strings.CutPrefix(s.Socket, "tcp:"); ok {
+		return d.DialContext(ctx, "tcp", addr)
+	}
+	return d.DialContext(ctx, "unix", s.Socket)
+}
+
+func (s *Subagent) session(ctx context.Context) error {
+	conn, err := s.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close() //nolint:errcheck
+
+	// Open: default timeout, the root as id, Name as description
+	e := &encoder{}
+	e.u8(0)
+	e.b = append(e.b, 0, 0, 0)
+	e.oid(s.Root, false)
+	e.octets([]byte(s.Name))
+	res, err := s.call(conn, header{Type: pduOpen}, e.b)
+	if err != nil {
+		return fmt.Errorf("open: %w", err)
+	}
+	sessionID := res.SessionID
+
+	// Register: default timeout and priority, no range
+	e = &encoder{}
+	e.u8(0)
+	e.u8(defaultPriority)
+	e.u8(0)
+	e.u8(0)
+	e.oid(s.Root, false)
+	if _, err := s.call(conn, header{Type: pduRegister, SessionID: sessionID}, e.b); err != nil {
+		return fmt.Errorf("register %s: %w", s.Root, err)
+	}
+	slog.Info("AgentX registered", "root", s.Root, "socket", s.Socket)
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			e := &encoder{}
+			e.u8(reasonShutdown)
+			e.b = append(e.b, 0, 0, 0)
+			s.write(conn, pdu(header{Type: pduClose, SessionID: sessionID, PacketID: s.nextPacket()}, e.b)) //nolint:errcheck
+			conn.Close()                                                                                    //nolint:errcheck
+		case <-done:
+		}
+	}()
+
+	for {
+		h, d, err := read(conn)
+		if err != nil {
+			return err
+		}
+		switch h.Type {
+		case pduGet, pduGetNext, pduGetBulk:
+			err = s.serve(conn, h, d)
+		case pduTestSet:
+			err = s.respond(conn, h, errNotWritable, 1, nil)
+		case pduClose:
+			return errors.New("closed by the master agent")
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Subagent) nextPacket() uint32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.packet++
+	return s.packet
+}
+
+func (s *Subagent) write(conn net.Conn, b []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := conn.Write(b)
+	return err
+}
+
+// call sends a request and waits for its response, before the session
+// serves anything else.
+func (s *Subagent) call(conn net.Conn, h header, payload []byte) (header, error) {
+	h.PacketID = s.nextPacket()
+	if err := s.write(conn, pdu(h, payload)); err != nil {
+		return header{}, err
+	}
+	res, d, err := read(conn)
+	if err != nil {
+		return header{}, err
+	}
+	if res.Type != pduResponse || res.PacketID != h.PacketID {
+		return header{}, fmt.Errorf("unexpected PDU type %d", res.Type)
+	}
+	if _, err := d.u32(); err != nil {
+		return header{}, err
+	}
+	code, err := d.u16()
+	if err != nil {
+		return header{}, err
+	}
+	if code != errNone {
+		return header{}, fmt.Errorf("error %d", code)
+	}
+	return res, nil
+}
+
+func read(conn net.Conn) (header, *decoder, error) {
+	b := make([]byte, headerLength)
+	if _, err := io.ReadFull(conn, b); err != nil {
+		return header{}, nil, err
+	}
+	// the master answers in the byte order of the Open
+	if b[2]&flagNetworkByteOrder == 0 {
+		return header{}, nil, errors.New("PDU not in network byte order")
+	}
+	h := header{
+		Type:        b[1],
+		Flags:       b[2],
+		SessionID:   binary.BigEndian.Uint32(b[4:]),
+		Transaction: binary.BigEndian.Uint32(b[8:]),
+		PacketID:    binary.BigEndian.Uint32(b[12:]),
+	}
+	n := binary.BigEndian.Uint32(b[16:])
+	if n > maxPayloadLength {
+		return header{}, nil, fmt.Errorf("payload of %d bytes", n)
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(conn, payload); err != nil {
+		return header{}, nil, err
+	}
+	return h, &decoder{b: payload}, nil
+}
+
+func (s *Subagent) respond(conn net.Conn, req header, code, index uint16, vars []Var) error {
+	e := &encoder{}
+	e.u32(0) // sysUpTime is the master's to fill in
+	e.u16(code)
+	e.u16(index)
+	for _, v := range vars {
+		e.varbind(v)
+	}
+	return s.write(conn, pdu(header{Type: pduResponse, SessionID: req.SessionID, Transaction: req.Transaction, PacketID: req.PacketID}, e.b))
+}
+
+// serve answers a Get, GetNext or GetBulk request.
+func (s *Subagent) serve(conn net.Conn, h header, d *decoder) error {
+	if h.Flags&flagNonDefaultContext != 0 {
+		if _, err := d.octets(); err != nil {
+			return err
+		}
+	}
+	var nonRepeaters, maxRepetitions uint16
+	if h.Type == pduGetBulk {
+		var err error
+		if nonRepeaters, err = d.u16(); err != nil {
+			return err
+		}
+		if maxRepetitions, err = d.u16(); err != nil {
+			return err
+		}
+	}
+	ranges, err := d.ranges()
+	if err != nil {
+		return err
+	}
+	vars, err := s.Handler()
+	if err != nil {
+		slog.Warn("AgentX", "err", err)
+		return s.respond(conn, h, errProcessing, 1, nil)
+	}
+	sort.Slice(vars, func(i, j int) bool { return compare(vars[i].OID, vars[j].OID) < 0 })
+
+	var out []Var
+	switch h.Type {
+	case pduGet:
+		for _, r := range ranges {
+			out = append(out, get(vars, r.start))
+		}
+	case pduGetNext:
+		for _, r := range ranges {
+			out = append(out, next(vars, r))
+		}
+	case pduGetBulk:
+		n := min(int(nonRepeaters), len(ranges))
+		for _, r := range ranges[:n] {
+			out = append(out, next(vars, r))
+		}
+		repeaters := ranges[n:]
+		for range maxRepetitions {
+			ended := true
+			for i, r := range repeaters {
+				v := next(vars, r)
+				out = append(out, v)
+				if v.Type != endOfMibView {
+					ended = false
+					repeaters[i].start, repeaters[i].include = v.OID, false
+				}
+			}
+			if ended || len(repeaters) == 0 {
+				break
+			}
+		}
+	}
+	return s.respond(conn, h, errNone, 0, out)
+}
+
+func get(vars []Var, oid OID) Var {
+	i := sort.Search(len(vars), func(i int) bool { return compare(vars[i].OID, oid) >= 0 })
+	if i < len(vars) && compare(vars[i].OID, oid) == 0 {
+		return vars[i]
+	}
+	return Var{OID: oid, Type: noSuchObject}
+}
+
+func next(vars []Var, r searchRange) Var {
+	i := sort.Search(len(vars), func(i int) bool {
+		c := compare(vars[i].OID, r.start)
+		return c > 0 || (c == 0 && r.include)
+	})
+	if i < len(vars) && (len(r.end) == 0 || compare(vars[i].OID, r.end) < 0) {
+		return vars[i]
+	}
+	return Var{OID: r.start, Type: endOfMibView}
+}
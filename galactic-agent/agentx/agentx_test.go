@@ -0,0 +1,161 @@
+package agentx
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The PDUs below are written out from the layouts of RFC 2741 section 6,
+// in network byte order, rather than with the encoder.
+
+// golden decodes hex with spaces and line breaks for readability.
+func golden(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(strings.Join(strings.Fields(s), ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// master is the master agent's end of a session.
+type master struct {
+	t    *testing.T
+	conn net.Conn
+}
+
+func (m *master) send(pdu string) {
+	m.t.Helper()
+	if _, err := m.conn.Write(golden(m.t, pdu)); err != nil {
+		m.t.Fatal(err)
+	}
+}
+
+// expect reads a PDU and checks it is want.
+func (m *master) expect(what, want string) {
+	m.t.Helper()
+	m.conn.SetReadDeadline(time.Now().Add(5 * time.Second)) //nolint:errcheck
+	h := make([]byte, 20)
+	if _, err := io.ReadFull(m.conn, h); err != nil {
+		m.t.Fatalf("%s: %v", what, err)
+	}
+	payload := make([]byte, binary.BigEndian.Uint32(h[16:]))
+	if _, err := io.ReadFull(m.conn, payload); err != nil {
+		m.t.Fatalf("%s: %v", what, err)
+	}
+	if got, want := append(h, payload...), golden(m.t, want); !bytes.Equal(got, want) {
+		m.t.Fatalf("%s:\n got %x\nwant %x", what, got, want)
+	}
+}
+
+func TestSessionInterop(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close() //nolint:errcheck
+
+	root := OID{1, 3, 6, 1, 4, 1, 99999}
+	s := &Subagent{
+		Socket: "tcp:" + listener.Addr().String(),
+		Root:   root,
+		Name:   "galactic",
+		Handler: func() ([]Var, error) {
+			return []Var{
+				NewGauge32(root.Append(3, 0), 7),
+				NewInteger(root.Append(1, 0), 1),
+				NewString(root.Append(2, 0), "up"),
+			}, nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- s.Run(ctx) }()
+
+	conn, err := listener.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close() //nolint:errcheck
+	m := &master{t: t, conn: conn}
+
+	// header: version, type, flags (network byte order), reserved,
+	// session, transaction and packet ids, payload length
+	m.expect("open", `
+		01 01 10 00  00000000 00000000 00000001 0000001c
+		00 000000
+		02 04 00 00  00000001 0001869f
+		00000008 67616c6163746963`)
+	m.send(`
+		01 12 10 00  0000002a 00000000 00000001 00000008
+		00000000 0000 0000`)
+	m.expect("register", `
+		01 03 10 00  0000002a 00000000 00000002 00000010
+		00 7f 00 00
+		02 04 00 00  00000001 0001869f`)
+	m.send(`
+		01 12 10 00  0000002a 00000000 00000002 00000008
+		00000000 0000 0000`)
+
+	// Get .1.0, and .9.0 which does not exist; the end of each range is
+	// the null OID
+	m.send(`
+		01 05 10 00  0000002a 00000007 00000064 00000030
+		04 04 00 00  00000001 0001869f 00000001 00000000  00 00 00 00
+		04 04 00 00  00000001 0001869f 00000009 00000000  00 00 00 00`)
+	m.expect("get response", `
+		01 12 10 00  0000002a 00000007 00000064 0000003c
+		00000000 0000 0000
+		0002 0000  04 04 00 00  00000001 0001869f 00000001 00000000  00000001
+		0080 0000  04 04 00 00  00000001 0001869f 00000009 00000000`)
+
+	// GetNext the root, and .1.0
+	m.send(`
+		01 06 10 00  0000002a 00000008 00000065 00000028
+		02 04 00 00  00000001 0001869f  00 00 00 00
+		04 04 00 00  00000001 0001869f 00000001 00000000  00 00 00 00`)
+	m.expect("getnext response", `
+		01 12 10 00  0000002a 00000008 00000065 00000044
+		00000000 0000 0000
+		0002 0000  04 04 00 00  00000001 0001869f 00000001 00000000  00000001
+		0004 0000  04 04 00 00  00000001 0001869f 00000002 00000000  00000002 75700000`)
+
+	// GetBulk from .2.0, no non-repeaters and 3 repetitions, which stop
+	// at the end of the view
+	m.send(`
+		01 07 10 00  0000002a 00000009 00000066 0000001c
+		0000 0003
+		04 04 00 00  00000001 0001869f 00000002 00000000  00 00 00 00`)
+	m.expect("getbulk response", `
+		01 12 10 00  0000002a 00000009 00000066 0000003c
+		00000000 0000 0000
+		0042 0000  04 04 00 00  00000001 0001869f 00000003 00000000  00000007
+		0082 0000  04 04 00 00  00000001 0001869f 00000003 00000000`)
+
+	// TestSet is refused with notWritable on the first binding; the
+	// CleanupSet that follows gets no response
+	m.send(`
+		01 08 10 00  0000002a 0000000a 00000067 0000001c
+		0002 0000  04 04 00 00  00000001 0001869f 00000001 00000000  00000002`)
+	m.expect("testset response", `
+		01 12 10 00  0000002a 0000000a 00000067 00000008
+		00000000 0011 0001`)
+	m.send(`01 0b 10 00  0000002a 0000000a 00000068 00000000`)
+
+	// stopping closes the session with reasonShutdown
+	cancel()
+	m.expect("close", `
+		01 02 10 00  0000002a 00000000 00000003 00000004
+		05 000000`)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,281 @@
+package agentx
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PDU types, RFC 2741 section 6.1, of the subset spoken: the subtree is
+// read-only, so a TestSet is refused, and the CleanupSet that follows it
+// needs no answer.
+const (
+	pduOpen     = 1
+	pduClose    = 2
+	pduRegister = 3
+	pduGet      = 5
+	pduGetNext  = 6
+	pduGetBulk  = 7
+	pduTestSet  = 8
+	pduResponse = 18
+)
+
+// header flags
+const (
+	flagNonDefaultContext = 0x08
+	flagNetworkByteOrder  = 0x10
+)
+
+// response errors
+const (
+	errNone        = 0
+	errNotWritable = 17
+	errProcessing  = 268
+)
+
+// reasonShutdown closes a session because the agent stops.
+const reasonShutdown = 5
+
+// defaultPriority is the registration priority net-snmp's subagents use.
+const defaultPriority = 127
+
+const (
+	headerLength     = 20
+	maxPayloadLength = 1 << 20
+	// internetPrefixLen is the length of 1.3.6.1, which OIDs below
+	// 1.3.6.1.<n> omit by setting prefix to n.
+	internetPrefixLen = 4
+)
+
+// VarType is the type of a variable binding's value, of those the agent
+// serves.
+type VarType uint16
+
+const (
+	Integer      VarType = 2
+	OctetString  VarType = 4
+	Gauge32      VarType = 66
+	noSuchObject VarType = 128
+	endOfMibView VarType = 130
+)
+
+// OID is an object identifier.
+type OID []uint32
+
+// ParseOID parses a dotted OID such as 1.3.6.1.4.1.
+func ParseOID(s string) (OID, error) {
+	var oid OID
+	for _, part := range strings.Split(strings.TrimPrefix(s, "."), ".") {
+		n, err := strconv.ParseUint(part, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid OID %q", s)
+		}
+		oid = append(oid, uint32(n))
+	}
+	return oid, nil
+}
+
+func (o OID) String() string {
+	parts := make([]string, len(o))
+	for i, n := range o {
+		parts[i] = strconv.FormatUint(uint64(n), 10)
+	}
+	return strings.Join(parts, ".")
+}
+
+// Append returns o followed by subids, without sharing o's array.
+func (o OID) Append(subids ...uint32) OID {
+	return append(append(OID(nil), o...), subids...)
+}
+
+// compare orders OIDs lexicographically, as SNMP walks them.
+func compare(a, b OID) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		switch {
+		case a[i] < b[i]:
+			return -1
+		case a[i] > b[i]:
+			return 1
+		}
+	}
+	return len(a) - len(b)
+}
+
+// Var is a variable binding.
+type Var struct {
+	OID   OID
+	Type  VarType
+	Int   int64
+	Uint  uint64
+	Bytes []byte
+}
+
+func NewInteger(oid OID, v int64) Var  { return Var{OID: oid, Type: Integer, Int: v} }
+func NewGauge32(oid OID, v uint32) Var { return Var{OID: oid, Type: Gauge32, Uint: uint64(v)} }
+func NewString(oid OID, v string) Var  { return Var{OID: oid, Type: OctetString, Bytes: []byte(v)} }
+
+// encoder appends network byte order fields; the agent sets
+// flagNetworkByteOrder on everything it sends, which the master agent then
+// uses too.
+type encoder struct{ b []byte }
+
+func (e *encoder) u8(v uint8)   { e.b = append(e.b, v) }
+func (e *encoder) u16(v uint16) { e.b = binary.BigEndian.AppendUint16(e.b, v) }
+func (e *encoder) u32(v uint32) { e.b = binary.BigEndian.AppendUint32(e.b, v) }
+
+func (e *encoder) oid(o OID, include bool) {
+	prefix := uint8(0)
+	if len(o) > internetPrefixLen && compare(o[:internetPrefixLen], OID{1, 3, 6, 1}) == 0 && o[internetPrefixLen] < 256 {
+		prefix = uint8(o[internetPrefixLen])
+		o = o[internetPrefixLen+1:]
+	}
+	e.u8(uint8(len(o)))
+	e.u8(prefix)
+	if include {
+		e.u8(1)
+	} else {
+		e.u8(0)
+	}
+	e.u8(0)
+	for _, n := range o {
+		e.u32(n)
+	}
+}
+
+func (e *encoder) octets(s []byte) {
+	e.u32(uint32(len(s)))
+	e.b = append(e.b, s...)
+	for len(e.b)%4 != 0 {
+		e.b = append(e.b, 0)
+	}
+}
+
+func (e *encoder) varbind(v Var) {
+	e.u16(uint16(v.Type))
+	e.u16(0)
+	e.oid(v.OID, false)
+	switch v.Type {
+	case Integer:
+		e.u32(uint32(int32(v.Int)))
+	case Gauge32:
+		e.u32(uint32(v.Uint))
+	case OctetString:
+		e.octets(v.Bytes)
+	}
+}
+
+// header is the fixed part of every PDU.
+type header struct {
+	Type        uint8
+	Flags       uint8
+	SessionID   uint32
+	Transaction uint32
+	PacketID    uint32
+}
+
+// pdu frames payload with h.
+func pdu(h header, payload []byte) []byte {
+	e := &encoder{}
+	e.u8(1)
+	e.u8(h.Type)
+	e.u8(h.Flags | flagNetworkByteOrder)
+	e.u8(0)
+	e.u32(h.SessionID)
+	e.u32(h.Transaction)
+	e.u32(h.PacketID)
+	e.u32(uint32(len(payload)))
+	return append(e.b, payload...)
+}
+
+var errShort = errors.New("agentx: truncated PDU")
+
+// decoder reads network byte order fields.
+type decoder struct {
+	b []byte
+}
+
+func (d *decoder) u8() (uint8, error) {
+	if len(d.b) < 1 {
+		return 0, errShort
+	}
+	v := d.b[0]
+	d.b = d.b[1:]
+	return v, nil
+}
+
+func (d *decoder) u16() (uint16, error) {
+	if len(d.b) < 2 {
+		return 0, errShort
+	}
+	v := binary.BigEndian.Uint16(d.b)
+	d.b = d.b[2:]
+	return v, nil
+}
+
+func (d *decoder) u32() (uint32, error) {
+	if len(d.b) < 4 {
+		return 0, errShort
+	}
+	v := binary.BigEndian.Uint32(d.b)
+	d.b = d.b[4:]
+	return v, nil
+}
+
+func (d *decoder) oid() (OID, bool, error) {
+	if len(d.b) < 4 {
+		return nil, false, errShort
+	}
+	n, prefix, include := int(d.b[0]), d.b[1], d.b[2] != 0
+	d.b = d.b[4:]
+	var o OID
+	if prefix != 0 {
+		o = OID{1, 3, 6, 1, uint32(prefix)}
+	}
+	for range n {
+		v, err := d.u32()
+		if err != nil {
+			return nil, false, err
+		}
+		o = append(o, v)
+	}
+	return o, include, nil
+}
+
+func (d *decoder) octets() ([]byte, error) {
+	n, err := d.u32()
+	if err != nil {
+		return nil, err
+	}
+	padded := (int(n) + 3) &^ 3
+	if len(d.b) < padded {
+		return nil, errShort
+	}
+	v := d.b[:n]
+	d.b = d.b[padded:]
+	return v, nil
+}
+
+// searchRange is a requested OID and the end of the range to look in.
+type searchRange struct {
+	start   OID
+	include bool
+	end     OID
+}
+
+func (d *decoder) ranges() ([]searchRange, error) {
+	var ranges []searchRange
+	for len(d.b) > 0 {
+		start, include, err := d.oid()
+		if err != nil {
+			return nil, err
+		}
+		end, _, err := d.oid()
+		if err != nil {
+			return nil, err
+		}
+		ranges = append(ranges, searchRange{start: start, include: include, end: end})
+	}
+	return ranges, nil
+}
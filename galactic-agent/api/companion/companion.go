@@ -0,0 +1,134 @@
+package companion
+
+import (
+	"context"
+	"crypto/subtle"
+	"log/slog"
+	"net"
+	"strings"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// Server serves the Windows helper over TCP. The helper runs outside the
+// agent's host, so every call must carry Token as a bearer token.
+type Server struct {
+	UnimplementedCompanionServer
+	Addr  string
+	Token string
+
+	// RoutesHandler returns the routes the Windows host should have now.
+	RoutesHandler func() (*RouteSet, error)
+	// InterfacesHandler applies a report and returns the addresses accepted
+	// as return destinations.
+	InterfacesHandler func(*InterfaceReport) ([]string, error)
+
+	mu      sync.Mutex
+	changed chan struct{}
+}
+
+// Notify wakes WatchRoutes streams to resend the route set if it changed.
+// It does not block.
+func (s *Server) Notify() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.changed != nil {
+		close(s.changed)
+		s.changed = nil
+	}
+}
+
+func (s *Server) changes() <-chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.changed == nil {
+		s.changed = make(chan struct{})
+	}
+	return s.changed
+}
+
+func (s *Server) WatchRoutes(req *WatchRoutesRequest, stream grpc.ServerStreamingServer[RouteSet]) error {
+	slog.Info("Companion watching routes", "host", req.GetHost())
+	var last *RouteSet
+	for {
+		changed := s.changes()
+		set, err := s.RoutesHandler()
+		if err != nil {
+			return err
+		}
+		if !proto.Equal(set, last) {
+			if err := stream.Send(set); err != nil {
+				return err
+			}
+			last = set
+		}
+		select {
+		case <-stream.Context().Done():
+			slog.Info("Companion gone", "host", req.GetHost())
+			return nil
+		case <-changed:
+		}
+	}
+}
+
+func (s *Server) ReportInterfaces(ctx context.Context, req *InterfaceReport) (*InterfaceReportReply, error) {
+	accepted, err := s.InterfacesHandler(req)
+	if err != nil {
+		return nil, err
+	}
+	return &InterfaceReportReply{Accepted: accepted}, nil
+}
+
+func (s *Server) authorize(ctx context.Context) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, v := range md.Get("authorization") {
+		if token, ok := strings.CutPrefix(v, "Bearer "); ok && subtle.ConstantTimeCompare([]byte(token), []byte(s.Token)) == 1 {
+			return nil
+		}
+	}
+	return status.Error(codes.Unauthenticated, "missing or wrong companion token")
+}
+
+func (s *Server) Serve(ctx context.Context) error {
+	listener, err := net.Listen("tcp", s.Addr)
+	if err != nil {
+		return err
+	}
+	defer listener.Close() //nolint:errcheck
+
+	srv := grpc.NewServer(
+		grpc.UnaryInterceptor(func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			if err := s.authorize(ctx); err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}),
+		grpc.StreamInterceptor(func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			if err := s.authorize(ss.Context()); err != nil {
+				return err
+			}
+			return handler(srv, ss)
+		}),
+	)
+	RegisterCompanionServer(srv, s)
+
+	routineErr := make(chan error, 1)
+	go func() {
+		slog.Info("Companion listening", "address", "tcp://"+listener.Addr().String())
+		if err := srv.Serve(listener); err != nil {
+			routineErr <- err
+			return
+		}
+		routineErr <- nil
+	}()
+
+	<-ctx.Done()
+	srv.Stop()
+	slog.Info("Companion stopped")
+	return <-routineErr
+}
@@ -0,0 +1,108 @@
+package gnmi
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ParsePath parses the string form of a path, /elem/elem[key=value]/leaf.
+// Key values may contain / but not ].
+func ParsePath(s string) (*Path, error) {
+	p := &Path{}
+	s = strings.TrimPrefix(s, "/")
+	for s != "" {
+		end := strings.IndexAny(s, "/[")
+		if end < 0 {
+			end = len(s)
+		}
+		elem := &PathElem{Name: s[:end]}
+		if elem.Name == "" {
+			return nil, fmt.Errorf("empty element in path %q", s)
+		}
+		s = s[end:]
+		for strings.HasPrefix(s, "[") {
+			end := strings.IndexByte(s, ']')
+			k, v, ok := strings.Cut(s[1:max(end, 1)], "=")
+			if end < 0 || !ok || k == "" {
+				return nil, fmt.Errorf("malformed key in path at %q", s)
+			}
+			if elem.Key == nil {
+				elem.Key = make(map[string]string)
+			}
+			elem.Key[k] = v
+			s = s[end+1:]
+		}
+		if s != "" && s[0] != '/' {
+			return nil, fmt.Errorf("unexpected %q in path", s)
+		}
+		s = strings.TrimPrefix(s, "/")
+		p.Elem = append(p.Elem, elem)
+	}
+	return p, nil
+}
+
+// PathString renders p in the form ParsePath reads, keys sorted by name.
+func PathString(p *Path) string {
+	var b strings.Builder
+	for _, e := range p.GetElem() {
+		b.WriteString("/")
+		b.WriteString(e.Name)
+		keys := make([]string, 0, len(e.Key))
+		for k := range e.Key {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Fprintf(&b, "[%s=%s]", k, e.Key[k])
+		}
+	}
+	if b.Len() == 0 {
+		return "/"
+	}
+	return b.String()
+}
+
+// Join returns the path p names relative to prefix.
+func Join(prefix, p *Path) *Path {
+	joined := &Path{Origin: prefix.GetOrigin(), Target: prefix.GetTarget()}
+	joined.Elem = append(joined.Elem, prefix.GetElem()...)
+	joined.Elem = append(joined.Elem, p.GetElem()...)
+	if o := p.GetOrigin(); o != "" {
+		joined.Origin = o
+	}
+	return joined
+}
+
+// Match reports whether leaf is in the subtree pattern selects. In pattern,
+// an element named * matches any one element, ... any number of them, and
+// a key that is missing or * any value.
+func Match(pattern, leaf *Path) bool {
+	return match(pattern.GetElem(), leaf.GetElem())
+}
+
+func match(pattern, leaf []*PathElem) bool {
+	if len(pattern) == 0 {
+		return true
+	}
+	if pattern[0].Name == "..." {
+		for i := 0; i <= len(leaf); i++ {
+			if match(pattern[1:], leaf[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(leaf) == 0 {
+		return false
+	}
+	if pattern[0].Name != "*" && pattern[0].Name != leaf[0].Name {
+		return false
+	}
+	for k, v := range pattern[0].Key {
+		if v != "*" && leaf[0].Key[k] != v {
+			return false
+		}
+	}
+	return match(pattern[1:], leaf[1:])
+}
@@ -0,0 +1,351 @@
+package gnmi
+
+import (
+	"context"
+	"crypto/tls"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// Version is the gNMI version the subset follows.
+const Version = "0.10.0"
+
+// Leaf is one value of the tree a Server serves. Config leaves are the
+// ones Set may change.
+type Leaf struct {
+	Path   *Path
+	Value  *TypedValue
+	Config bool
+}
+
+// Server serves a tree of leaves over gNMI. It knows nothing about the
+// tree: StateHandler returns all of it, and SetHandler applies changes.
+type Server struct {
+	UnimplementedGNMIServer
+	Addr string
+	// TLSConfig secures the listener; nil serves plaintext.
+	TLSConfig *tls.Config
+	Model     *ModelData
+
+	// StateHandler returns every leaf of the tree.
+	StateHandler func() ([]Leaf, error)
+	// SetHandler applies all of updates, or none of them and returns
+	// an error.
+	SetHandler func(updates []Leaf) error
+
+	mu      sync.Mutex
+	changed chan struct{}
+}
+
+// changeInterval is how often ON_CHANGE subscriptions look for changes
+// that Notify was not told about, such as counters.
+const changeInterval = time.Second
+
+// defaultSampleInterval applies to SAMPLE subscriptions without one.
+const defaultSampleInterval = 10 * time.Second
+
+// Notify wakes ON_CHANGE subscriptions to look for changes. It does not
+// block.
+func (s *Server) Notify() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.changed != nil {
+		close(s.changed)
+		s.changed = nil
+	}
+}
+
+func (s *Server) changes() <-chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.changed == nil {
+		s.changed = make(chan struct{})
+	}
+	return s.changed
+}
+
+func (s *Server) Capabilities(ctx context.Context, req *CapabilityRequest) (*CapabilityResponse, error) {
+	return &CapabilityResponse{
+		SupportedModels:    []*ModelData{s.Model},
+		SupportedEncodings: []Encoding{Encoding_JSON, Encoding_JSON_IETF, Encoding_PROTO},
+		GNMIVersion:        Version,
+	}, nil
+}
+
+// checkEncoding accepts the encodings leaves are valid in: they are scalar
+// typed values.
+func checkEncoding(e Encoding) error {
+	switch e {
+	case Encoding_JSON, Encoding_JSON_IETF, Encoding_PROTO:
+		return nil
+	}
+	return status.Errorf(codes.Unimplemented, "encoding %s is not supported", e)
+}
+
+func (s *Server) Get(ctx context.Context, req *GetRequest) (*GetResponse, error) {
+	if err := checkEncoding(req.Encoding); err != nil {
+		return nil, err
+	}
+	leaves, err := s.StateHandler()
+	if err != nil {
+		return nil, err
+	}
+	paths := req.Path
+	if len(paths) == 0 {
+		paths = []*Path{{}}
+	}
+	n := &Notification{Timestamp: time.Now().UnixNano()}
+	for _, p := range paths {
+		pattern := Join(req.Prefix, p)
+		found := false
+		for _, l := range leaves {
+			if !Match(pattern, l.Path) || !wanted(req.Type, l) {
+				continue
+			}
+			found = true
+			n.Update = append(n.Update, &Update{Path: l.Path, Val: l.Value})
+		}
+		if !found {
+			return nil, status.Errorf(codes.NotFound, "no data at %s", PathString(pattern))
+		}
+	}
+	return &GetResponse{Notification: []*Notification{n}}, nil
+}
+
+func wanted(t GetRequest_DataType, l Leaf) bool {
+	switch t {
+	case GetRequest_CONFIG:
+		return l.Config
+	case GetRequest_STATE, GetRequest_OPERATIONAL:
+		return !l.Config
+	}
+	return true
+}
+
+// unsupported refuses a request carrying fields left out of the subset,
+// which would otherwise be ignored.
+func unsupported(msgs ...proto.Message) error {
+	for _, m := range msgs {
+		if len(m.ProtoReflect().GetUnknown()) > 0 {
+			return status.Errorf(codes.Unimplemented, "%s has fields this server does not support", m.ProtoReflect().Descriptor().Name())
+		}
+	}
+	return nil
+}
+
+func (s *Server) Set(ctx context.Context, req *SetRequest) (*SetResponse, error) {
+	if err := unsupported(req); err != nil {
+		return nil, err
+	}
+	if len(req.Delete) > 0 {
+		return nil, status.Error(codes.InvalidArgument, "delete is not supported")
+	}
+	resp := &SetResponse{Prefix: req.Prefix}
+	var updates []Leaf
+	add := func(u *Update, op UpdateResult_Operation) {
+		p := Join(req.Prefix, u.Path)
+		updates = append(updates, Leaf{Path: p, Value: u.Val, Config: true})
+		resp.Response = append(resp.Response, &UpdateResult{Path: u.Path, Op: op})
+	}
+	for _, u := range req.Replace {
+		add(u, UpdateResult_REPLACE)
+	}
+	for _, u := range req.Update {
+		add(u, UpdateResult_UPDATE)
+	}
+	if err := s.SetHandler(updates); err != nil {
+		return nil, err
+	}
+	resp.Timestamp = time.Now().UnixNano()
+	s.Notify()
+	return resp, nil
+}
+
+// subscription is one path of a streaming subscription and what it last
+// sent.
+type subscription struct {
+	pattern  *Path
+	onChange bool
+	interval time.Duration
+	next     time.Time
+	last     map[string]*TypedValue
+}
+
+func (s *Server) Subscribe(stream grpc.BidiStreamingServer[SubscribeRequest, SubscribeResponse]) error {
+	req, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	list := req.GetSubscribe()
+	if list == nil {
+		return status.Error(codes.InvalidArgument, "the first request must be a subscription list")
+	}
+	if err := checkEncoding(list.Encoding); err != nil {
+		return err
+	}
+	if err := unsupported(req, list); err != nil {
+		return err
+	}
+	subs := make([]*subscription, 0, len(list.Subscription))
+	for _, sub := range list.Subscription {
+		if err := unsupported(sub); err != nil {
+			return err
+		}
+		subs = append(subs, &subscription{
+			pattern:  Join(list.Prefix, sub.Path),
+			onChange: sub.Mode != SubscriptionMode_SAMPLE,
+			interval: time.Duration(sub.SampleInterval),
+		})
+	}
+	if len(subs) == 0 {
+		subs = append(subs, &subscription{pattern: Join(list.Prefix, &Path{}), onChange: true})
+	}
+
+	switch list.Mode {
+	case SubscriptionList_ONCE:
+		return s.sendAll(stream, subs)
+	case SubscriptionList_STREAM:
+		return s.stream(stream, subs, list.UpdatesOnly)
+	}
+	return status.Errorf(codes.Unimplemented, "%s subscriptions are not supported", list.Mode)
+}
+
+// sendAll sends the current value of every subscribed leaf, then a sync
+// response.
+func (s *Server) sendAll(stream grpc.BidiStreamingServer[SubscribeRequest, SubscribeResponse], subs []*subscription) error {
+	leaves, err := s.StateHandler()
+	if err != nil {
+		return err
+	}
+	n := &Notification{Timestamp: time.Now().UnixNano()}
+	for _, l := range leaves {
+		for _, sub := range subs {
+			if Match(sub.pattern, l.Path) {
+				n.Update = append(n.Update, &Update{Path: l.Path, Val: l.Value})
+				break
+			}
+		}
+	}
+	if len(n.Update) > 0 {
+		if err := stream.Send(&SubscribeResponse{Response: &SubscribeResponse_Update{Update: n}}); err != nil {
+			return err
+		}
+	}
+	return stream.Send(&SubscribeResponse{Response: &SubscribeResponse_SyncResponse{SyncResponse: true}})
+}
+
+// stream serves a STREAM subscription until the client goes away: ON_CHANGE
+// and TARGET_DEFINED paths send changed and deleted leaves, SAMPLE paths
+// every leaf each interval.
+func (s *Server) stream(stream grpc.BidiStreamingServer[SubscribeRequest, SubscribeResponse], subs []*subscription, updatesOnly bool) error {
+	ctx := stream.Context()
+	for _, sub := range subs {
+		if sub.onChange {
+			sub.interval = changeInterval
+		} else if sub.interval <= 0 {
+			sub.interval = defaultSampleInterval
+		}
+	}
+	synced := false
+	for {
+		changed := s.changes()
+		leaves, err := s.StateHandler()
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		n := &Notification{Timestamp: now.UnixNano()}
+		for _, sub := range subs {
+			if synced && !sub.onChange && now.Before(sub.next) {
+				continue
+			}
+			sub.next = now.Add(sub.interval)
+			current := make(map[string]*TypedValue)
+			for _, l := range leaves {
+				if !Match(sub.pattern, l.Path) {
+					continue
+				}
+				key := PathString(l.Path)
+				current[key] = l.Value
+				last, seen := sub.last[key]
+				if seen && sub.onChange && proto.Equal(last, l.Value) {
+					continue
+				}
+				if !synced && updatesOnly {
+					continue
+				}
+				n.Update = append(n.Update, &Update{Path: l.Path, Val: l.Value})
+			}
+			for key := range sub.last {
+				if _, ok := current[key]; !ok {
+					if p, err := ParsePath(key); err == nil {
+						n.Delete = append(n.Delete, p)
+					}
+				}
+			}
+			sub.last = current
+		}
+		if len(n.Update) > 0 || len(n.Delete) > 0 {
+			if err := stream.Send(&SubscribeResponse{Response: &SubscribeResponse_Update{Update: n}}); err != nil {
+				return err
+			}
+		}
+		if !synced {
+			if err := stream.Send(&SubscribeResponse{Response: &SubscribeResponse_SyncResponse{SyncResponse: true}}); err != nil {
+				return err
+			}
+			synced = true
+		}
+
+		wait := time.Until(subs[0].next)
+		for _, sub := range subs[1:] {
+			wait = min(wait, time.Until(sub.next))
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-changed:
+		case <-time.After(wait):
+		}
+	}
+}
+
+func (s *Server) Serve(ctx context.Context) error {
+	listener, err := net.Listen("tcp", s.Addr)
+	if err != nil {
+		return err
+	}
+	defer listener.Close() //nolint:errcheck
+
+	var opts []grpc.ServerOption
+	scheme := "tcp"
+	if s.TLSConfig != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(s.TLSConfig)))
+		scheme = "tls"
+	}
+	srv := grpc.NewServer(opts...)
+	RegisterGNMIServer(srv, s)
+
+	routineErr := make(chan error, 1)
+	go func() {
+		slog.Info("gNMI listening", "address", scheme+"://"+listener.Addr().String())
+		if err := srv.Serve(listener); err != nil {
+			routineErr <- err
+			return
+		}
+		routineErr <- nil
+	}()
+
+	<-ctx.Done()
+	srv.Stop()
+	slog.Info("gNMI stopped")
+	return <-routineErr
+}
@@ -0,0 +1,281 @@
+package gnmi
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// The tests speak to the server the way a standard client does, with
+// messages encoded by hand from the field numbers of the upstream
+// gnmi.proto rather than with the types of this package.
+
+// rawCodec passes messages through as the bytes they are encoded to.
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v any) ([]byte, error) { return *v.(*[]byte), nil }
+
+func (rawCodec) Unmarshal(b []byte, v any) error {
+	*v.(*[]byte) = append([]byte(nil), b...)
+	return nil
+}
+
+func (rawCodec) Name() string { return "proto" }
+
+// field is one field of an upstream message: a varint, or bytes which are
+// a string or an embedded message.
+type field struct {
+	num    protowire.Number
+	varint uint64
+	bytes  []byte
+	isMsg  bool
+}
+
+func msg(fields ...field) []byte {
+	var b []byte
+	for _, f := range fields {
+		if f.bytes == nil && !f.isMsg {
+			b = protowire.AppendTag(b, f.num, protowire.VarintType)
+			b = protowire.AppendVarint(b, f.varint)
+			continue
+		}
+		b = protowire.AppendTag(b, f.num, protowire.BytesType)
+		b = protowire.AppendBytes(b, f.bytes)
+	}
+	return b
+}
+
+func varint(n protowire.Number, v uint64) field { return field{num: n, varint: v} }
+func str(n protowire.Number, s string) field    { return field{num: n, bytes: []byte(s), isMsg: true} }
+func sub(n protowire.Number, b []byte) field    { return field{num: n, bytes: b, isMsg: true} }
+
+// upstream path /state/limit[operation=register]/rate: Path.elem is 3,
+// PathElem.name 1 and PathElem.key 2, a map entry of key 1 and value 2
+var ratePath = msg(
+	sub(3, msg(str(1, "state"))),
+	sub(3, msg(str(1, "limit"), sub(2, msg(str(1, "operation"), str(2, "register"))))),
+	sub(3, msg(str(1, "rate"))),
+)
+
+// parse splits an upstream message into its fields, by number.
+func parse(t *testing.T, b []byte) map[protowire.Number][]field {
+	t.Helper()
+	fields := make(map[protowire.Number][]field)
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			t.Fatalf("malformed tag: %v", protowire.ParseError(n))
+		}
+		b = b[n:]
+		f := field{num: num}
+		switch typ {
+		case protowire.VarintType:
+			f.varint, n = protowire.ConsumeVarint(b)
+		case protowire.BytesType:
+			f.bytes, n = protowire.ConsumeBytes(b)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			t.Fatalf("malformed field %d: %v", num, protowire.ParseError(n))
+		}
+		b = b[n:]
+		fields[num] = append(fields[num], f)
+	}
+	return fields
+}
+
+func serve(t *testing.T, s *Server) *grpc.ClientConn {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer()
+	RegisterGNMIServer(srv, s)
+	go srv.Serve(listener) //nolint:errcheck
+	t.Cleanup(srv.Stop)
+	conn, err := grpc.NewClient(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithDefaultCallOptions(grpc.ForceCodec(rawCodec{})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() }) //nolint:errcheck
+	return conn
+}
+
+func testServer(set *[]Leaf) *Server {
+	rate, _ := ParsePath("/state/limit[operation=register]/rate")
+	return &Server{
+		Model: &ModelData{Name: "galactic-agent"},
+		StateHandler: func() ([]Leaf, error) {
+			return []Leaf{{Path: rate, Value: &TypedValue{Value: &TypedValue_UintVal{UintVal: 5}}}}, nil
+		},
+		SetHandler: func(updates []Leaf) error {
+			*set = append(*set, updates...)
+			return nil
+		},
+	}
+}
+
+// checkRate checks an upstream Notification holds the rate of 5.
+func checkRate(t *testing.T, notification []byte) {
+	t.Helper()
+	n := parse(t, notification)
+	if len(n[1]) != 1 || n[1][0].varint == 0 {
+		t.Errorf("notification without a timestamp: %v", n)
+	}
+	if len(n[4]) != 1 {
+		t.Fatalf("%d updates, want 1", len(n[4]))
+	}
+	u := parse(t, n[4][0].bytes)
+	if p, err := pathOf(t, u[1][0].bytes); err != nil || p != "/state/limit[operation=register]/rate" {
+		t.Errorf("update of %q, %v", p, err)
+	}
+	// TypedValue.uint_val is 3
+	if v := parse(t, u[3][0].bytes); len(v[3]) != 1 || v[3][0].varint != 5 {
+		t.Errorf("value %v, want uint_val 5", v)
+	}
+}
+
+// pathOf renders an upstream Path.
+func pathOf(t *testing.T, b []byte) (string, error) {
+	var s string
+	for _, e := range parse(t, b)[3] {
+		elem := parse(t, e.bytes)
+		if len(elem[1]) != 1 {
+			return "", fmt.Errorf("element without a name")
+		}
+		s += "/" + string(elem[1][0].bytes)
+		for _, k := range elem[2] {
+			kv := parse(t, k.bytes)
+			s += fmt.Sprintf("[%s=%s]", kv[1][0].bytes, kv[2][0].bytes)
+		}
+	}
+	return s, nil
+}
+
+func TestCapabilitiesInterop(t *testing.T) {
+	var set []Leaf
+	conn := serve(t, testServer(&set))
+	req, res := []byte{}, []byte(nil)
+	if err := conn.Invoke(context.Background(), "/gnmi.gNMI/Capabilities", &req, &res); err != nil {
+		t.Fatal(err)
+	}
+	f := parse(t, res)
+	// supported_models 1, supported_encodings 2 (packed), gNMI_version 3
+	if len(f[1]) != 1 || string(parse(t, f[1][0].bytes)[1][0].bytes) != "galactic-agent" {
+		t.Errorf("models %v", f[1])
+	}
+	if len(f[3]) != 1 || string(f[3][0].bytes) != Version {
+		t.Errorf("version %v", f[3])
+	}
+	var encodings []uint64
+	for _, e := range f[2] {
+		for b := e.bytes; len(b) > 0; {
+			v, n := protowire.ConsumeVarint(b)
+			encodings = append(encodings, v)
+			b = b[n:]
+		}
+	}
+	if fmt.Sprint(encodings) != "[0 4 2]" {
+		t.Errorf("encodings %v, want JSON, JSON_IETF and PROTO", encodings)
+	}
+}
+
+func TestGetInterop(t *testing.T) {
+	var set []Leaf
+	conn := serve(t, testServer(&set))
+	// GetRequest: path 2, encoding 5 (JSON_IETF is 4)
+	req := msg(sub(2, ratePath), varint(5, 4))
+	var res []byte
+	if err := conn.Invoke(context.Background(), "/gnmi.gNMI/Get", &req, &res); err != nil {
+		t.Fatal(err)
+	}
+	// GetResponse.notification is 1
+	n := parse(t, res)[1]
+	if len(n) != 1 {
+		t.Fatalf("%d notifications, want 1", len(n))
+	}
+	checkRate(t, n[0].bytes)
+}
+
+func TestSetInterop(t *testing.T) {
+	var set []Leaf
+	conn := serve(t, testServer(&set))
+	// SetRequest.update is 4: Update path 1, val 3 with uint_val 3
+	req := msg(sub(4, msg(sub(1, ratePath), sub(3, msg(varint(3, 7))))))
+	var res []byte
+	if err := conn.Invoke(context.Background(), "/gnmi.gNMI/Set", &req, &res); err != nil {
+		t.Fatal(err)
+	}
+	if len(set) != 1 || PathString(set[0].Path) != "/state/limit[operation=register]/rate" || set[0].Value.GetUintVal() != 7 {
+		t.Errorf("set %v", set)
+	}
+	// SetResponse.response is 2, UpdateResult.op 4 with UPDATE 3
+	r := parse(t, res)[2]
+	if len(r) != 1 || parse(t, r[0].bytes)[4][0].varint != 3 {
+		t.Errorf("response %v", r)
+	}
+
+	// union_replace, 6, is left out: refused rather than ignored
+	set = nil
+	req = msg(sub(6, msg(sub(1, ratePath), sub(3, msg(varint(3, 7))))))
+	if err := conn.Invoke(context.Background(), "/gnmi.gNMI/Set", &req, &res); status.Code(err) != codes.Unimplemented || len(set) != 0 {
+		t.Errorf("union_replace: %v, set %v", err, set)
+	}
+}
+
+func TestSubscribeInterop(t *testing.T) {
+	var set []Leaf
+	conn := serve(t, testServer(&set))
+	desc := &grpc.StreamDesc{ServerStreams: true, ClientStreams: true}
+	subscribe := func(mode uint64) (grpc.ClientStream, error) {
+		stream, err := conn.NewStream(context.Background(), desc, "/gnmi.gNMI/Subscribe")
+		if err != nil {
+			t.Fatal(err)
+		}
+		// SubscribeRequest.subscribe 1: SubscriptionList subscription 2
+		// with Subscription.path 1, and mode 5
+		req := msg(sub(1, msg(sub(2, msg(sub(1, ratePath))), varint(5, mode))))
+		if err := stream.SendMsg(&req); err != nil {
+			t.Fatal(err)
+		}
+		return stream, stream.CloseSend()
+	}
+
+	stream, err := subscribe(1) // ONCE
+	if err != nil {
+		t.Fatal(err)
+	}
+	var res []byte
+	if err := stream.RecvMsg(&res); err != nil {
+		t.Fatal(err)
+	}
+	// SubscribeResponse.update is 1, sync_response 3
+	update := parse(t, res)[1]
+	if len(update) != 1 {
+		t.Fatalf("first response %v, want an update", parse(t, res))
+	}
+	checkRate(t, update[0].bytes)
+	if err := stream.RecvMsg(&res); err != nil {
+		t.Fatal(err)
+	}
+	if sync := parse(t, res)[3]; len(sync) != 1 || sync[0].varint != 1 {
+		t.Errorf("second response %v, want sync_response", parse(t, res))
+	}
+
+	stream, err = subscribe(2) // POLL
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := stream.RecvMsg(&res); status.Code(err) != codes.Unimplemented {
+		t.Errorf("POLL subscription: %v", err)
+	}
+}
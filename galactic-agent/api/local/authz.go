@@ -0,0 +1,205 @@
+package local
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+
+	"golang.org/x/sys/unix"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/datum-cloud/galactic-agent/srv6/endpoint"
+)
+
+// Rule grants the callers it matches access to VPCs. A caller matches when
+// every identity field set on the rule matches: UID and GID are the peer
+// credentials of the unix socket connection, Token is a bearer token sent
+// in the authorization metadata. VPCs are hex ids; "*" is any VPC.
+type Rule struct {
+	UID   *uint32  `mapstructure:"uid"`
+	GID   *uint32  `mapstructure:"gid"`
+	Token string   `mapstructure:"token"`
+	VPCs  []string `mapstructure:"vpcs"`
+}
+
+// Policy is a list of rules; a call is allowed if any rule allows it, and
+// denied otherwise. An empty policy allows everything.
+type Policy []Rule
+
+// Caller is who made a local API call.
+type Caller struct {
+	Cred  *unix.Ucred // nil if the transport did not provide peer credentials
+	Token string
+}
+
+func (c Caller) String() string {
+	s := "unknown"
+	if c.Cred != nil {
+		s = fmt.Sprintf("uid=%d gid=%d pid=%d", c.Cred.Uid, c.Cred.Gid, c.Cred.Pid)
+	}
+	if c.Token != "" {
+		s += " with token"
+	}
+	return s
+}
+
+func (r Rule) matches(c Caller) bool {
+	if r.UID == nil && r.GID == nil && r.Token == "" {
+		return false
+	}
+	if r.UID != nil && (c.Cred == nil || c.Cred.Uid != *r.UID) {
+		return false
+	}
+	if r.GID != nil && (c.Cred == nil || c.Cred.Gid != *r.GID) {
+		return false
+	}
+	if r.Token != "" && subtle.ConstantTimeCompare([]byte(r.Token), []byte(c.Token)) != 1 {
+		return false
+	}
+	return true
+}
+
+// Allowed reports whether c may operate on vpc. An empty vpc stands for
+// all VPCs and is only allowed by "*".
+func (p Policy) Allowed(c Caller, vpc string) bool {
+	if len(p) == 0 {
+		return true
+	}
+	if vpc != "" {
+		if padded, _, err := endpoint.PadHex(vpc, "0"); err == nil {
+			vpc = padded
+		}
+	}
+	for _, r := range p {
+		if !r.matches(c) {
+			continue
+		}
+		if slices.Contains(r.VPCs, "*") {
+			return true
+		}
+		if vpc == "" {
+			continue
+		}
+		if slices.ContainsFunc(r.VPCs, func(v string) bool {
+			padded, _, err := endpoint.PadHex(v, "0")
+			return err == nil && padded == vpc
+		}) {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate rejects rules that could never match or name invalid VPCs.
+func (p Policy) Validate() error {
+	for i, r := range p {
+		if r.UID == nil && r.GID == nil && r.Token == "" {
+			return fmt.Errorf("rule %d: no uid, gid or token", i)
+		}
+		for _, v := range r.VPCs {
+			if v == "*" {
+				continue
+			}
+			if _, _, err := endpoint.PadHex(v, "0"); err != nil {
+				return fmt.Errorf("rule %d: %w", i, err)
+			}
+		}
+	}
+	return nil
+}
+
+type vpcRequest interface {
+	GetVpc() string
+}
+
+// interceptor denies calls for VPCs the caller has no rule for. Calls not
+// scoped to a VPC, such as Status, need "*"; the items of a batch are
+// checked one by one as they run.
+func (p Policy) interceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	var vpc string
+	switch r := req.(type) {
+	case *RegisterBatchRequest, *DeregisterBatchRequest:
+		return handler(ctx, req)
+	case vpcRequest:
+		vpc = r.GetVpc()
+	}
+	caller := CallerFrom(ctx)
+	if !p.Allowed(caller, vpc) {
+		if vpc == "" {
+			return nil, status.Errorf(codes.PermissionDenied, "%s may not call %s", caller, info.FullMethod)
+		}
+		return nil, status.Errorf(codes.PermissionDenied, "%s may not access vpc %q", caller, vpc)
+	}
+	return handler(ctx, req)
+}
+
+// CallerFrom identifies the caller of the call ctx belongs to.
+func CallerFrom(ctx context.Context) Caller {
+	var c Caller
+	if p, ok := peer.FromContext(ctx); ok {
+		if info, ok := p.AuthInfo.(peerCredInfo); ok {
+			c.Cred = info.cred
+		}
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		for _, v := range md.Get("authorization") {
+			if token, ok := strings.CutPrefix(v, "Bearer "); ok {
+				c.Token = token
+			}
+		}
+	}
+	return c
+}
+
+// peerCredInfo carries SO_PEERCRED of a unix socket connection.
+type peerCredInfo struct {
+	credentials.CommonAuthInfo
+	cred *unix.Ucred
+}
+
+func (peerCredInfo) AuthType() string { return "peercred" }
+
+// peerCreds is a transport credential that only records the peer
+// credentials of unix socket connections; it does not encrypt.
+type peerCreds struct{}
+
+func (peerCreds) ClientHandshake(_ context.Context, _ string, conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	return conn, peerCredInfo{}, nil
+}
+
+func (peerCreds) ServerHandshake(conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	uc, ok := conn.(*net.UnixConn)
+	if !ok {
+		return conn, peerCredInfo{}, nil
+	}
+	raw, err := uc.SyscallConn()
+	if err != nil {
+		return nil, nil, err
+	}
+	var cred *unix.Ucred
+	var credErr error
+	err = raw.Control(func(fd uintptr) {
+		cred, credErr = unix.GetsockoptUcred(int(fd), unix.SOL_SOCKET, unix.SO_PEERCRED)
+	})
+	if err = errors.Join(err, credErr); err != nil {
+		return nil, nil, err
+	}
+	return conn, peerCredInfo{CommonAuthInfo: credentials.CommonAuthInfo{SecurityLevel: credentials.NoSecurity}, cred: cred}, nil
+}
+
+func (peerCreds) Info() credentials.ProtocolInfo {
+	return credentials.ProtocolInfo{SecurityProtocol: "peercred"}
+}
+
+func (peerCreds) Clone() credentials.TransportCredentials { return peerCreds{} }
+
+func (peerCreds) OverrideServerName(string) error { return nil }
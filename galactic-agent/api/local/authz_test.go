@@ -0,0 +1,49 @@
+package local
+
+import (
+	"context"
+	"testing"
+
+	"golang.org/x/sys/unix"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// TestInterceptorUnscoped checks a caller limited to one VPC may use it
+// but not call Status, which is not scoped to a VPC, and that "*" may.
+func TestInterceptorUnscoped(t *testing.T) {
+	gid, uid := uint32(1001), uint32(0)
+	p := Policy{
+		{GID: &gid, VPCs: []string{"ab"}},
+		{UID: &uid, VPCs: []string{"*"}},
+	}
+	call := func(cred *unix.Ucred, method string, req any) codes.Code {
+		ctx := peer.NewContext(context.Background(), &peer.Peer{AuthInfo: peerCredInfo{cred: cred}})
+		info := &grpc.UnaryServerInfo{FullMethod: method}
+		_, err := p.interceptor(ctx, req, info, func(context.Context, any) (any, error) { return nil, nil })
+		return status.Code(err)
+	}
+	caller := &unix.Ucred{Uid: 1001, Gid: 1001}
+	root := &unix.Ucred{Uid: 0, Gid: 0}
+	for _, tc := range []struct {
+		name   string
+		cred   *unix.Ucred
+		method string
+		req    any
+		want   codes.Code
+	}{
+		{"own vpc", caller, "Register", &RegisterRequest{Vpc: "0000000000ab"}, codes.OK},
+		{"other vpc", caller, "Register", &RegisterRequest{Vpc: "cd"}, codes.PermissionDenied},
+		{"status", caller, "Status", &StatusRequest{}, codes.PermissionDenied},
+		{"all vpcs", caller, "ListAttachments", &ListAttachmentsRequest{}, codes.PermissionDenied},
+		{"batch", caller, "RegisterBatch", &RegisterBatchRequest{}, codes.OK},
+		{"status with *", root, "Status", &StatusRequest{}, codes.OK},
+		{"unknown caller", nil, "Status", &StatusRequest{}, codes.PermissionDenied},
+	} {
+		if got := call(tc.cred, "/galactic.local.Local/"+tc.method, tc.req); got != tc.want {
+			t.Errorf("%s: %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
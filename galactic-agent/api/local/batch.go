@@ -0,0 +1,69 @@
+package local
+
+import (
+	"context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// maxBatchItems bounds the items of a RegisterBatch or DeregisterBatch
+// request.
+const maxBatchItems = 1024
+
+func (l *Local) RegisterBatch(ctx context.Context, req *RegisterBatchRequest) (*BatchReply, error) {
+	if len(req.GetItems()) > maxBatchItems {
+		return nil, status.Errorf(codes.InvalidArgument, "%d items, at most %d", len(req.GetItems()), maxBatchItems)
+	}
+	reply := &BatchReply{Results: make([]*BatchResult, len(req.GetItems()))}
+	for i, item := range req.GetItems() {
+		if err := hexIDs(item); err != nil {
+			reply.Results[i] = batchResult(err)
+			continue
+		}
+		reply.Results[i] = l.batchItem(ctx, item.GetVpc(), func() error {
+			_, err := l.Register(ctx, item)
+			return err
+		})
+	}
+	return reply, nil
+}
+
+func (l *Local) DeregisterBatch(ctx context.Context, req *DeregisterBatchRequest) (*BatchReply, error) {
+	if len(req.GetItems()) > maxBatchItems {
+		return nil, status.Errorf(codes.InvalidArgument, "%d items, at most %d", len(req.GetItems()), maxBatchItems)
+	}
+	reply := &BatchReply{Results: make([]*BatchResult, len(req.GetItems()))}
+	for i, item := range req.GetItems() {
+		if err := hexIDs(item); err != nil {
+			reply.Results[i] = batchResult(err)
+			continue
+		}
+		reply.Results[i] = l.batchItem(ctx, item.GetVpc(), func() error {
+			_, err := l.Deregister(ctx, item)
+			return err
+		})
+	}
+	return reply, nil
+}
+
+// batchItem runs call for an item of vpc, checking the policy the unary
+// interceptor checks for single calls, and returns its result. Once ctx is
+// done the items left fail without running.
+func (l *Local) batchItem(ctx context.Context, vpc string, call func() error) *BatchResult {
+	if err := ctx.Err(); err != nil {
+		return batchResult(status.FromContextError(err).Err())
+	}
+	if caller := CallerFrom(ctx); !l.Policy.Allowed(caller, vpc) {
+		return batchResult(status.Errorf(codes.PermissionDenied, "%s may not access vpc %q", caller, vpc))
+	}
+	return batchResult(call())
+}
+
+func batchResult(err error) *BatchResult {
+	if err == nil {
+		return &BatchResult{}
+	}
+	s := status.Convert(err)
+	return &BatchResult{Code: uint32(s.Code()), Error: s.Message()}
+}
@@ -0,0 +1,96 @@
+package local
+
+import (
+	"sync/atomic"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/datum-cloud/galactic-agent/srv6/endpoint"
+)
+
+// eventBuffer is how many events a WatchEvents subscriber may fall behind
+// before it misses some.
+const eventBuffer = 256
+
+type subscriber struct {
+	vpc     string // padded, see endpoint.PadHex
+	events  chan *Event
+	dropped atomic.Uint32
+}
+
+// Publish sends e to the WatchEvents subscribers. It never blocks: a
+// subscriber whose buffer is full misses e and is told so.
+func (l *Local) Publish(e *Event) {
+	// registrations carry the vpc as given, routes padded
+	vpc := padVPC(e.GetVpc())
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for s := range l.subscribers {
+		if s.vpc != "" && vpc != "" && vpc != s.vpc {
+			continue
+		}
+		select {
+		case s.events <- e:
+		default:
+			s.dropped.Add(1)
+		}
+	}
+}
+
+// padVPC pads vpc for comparison, leaving it as it is if malformed.
+func padVPC(vpc string) string {
+	if padded, _, err := endpoint.PadHex(vpc, "0"); err == nil {
+		return padded
+	}
+	return vpc
+}
+
+func (l *Local) subscribe(vpc string) *subscriber {
+	s := &subscriber{vpc: padVPC(vpc), events: make(chan *Event, eventBuffer)}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.subscribers == nil {
+		l.subscribers = make(map[*subscriber]struct{})
+	}
+	l.subscribers[s] = struct{}{}
+	return s
+}
+
+func (l *Local) unsubscribe(s *subscriber) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.subscribers, s)
+}
+
+// WatchEvents sends the events published from the time it is called until
+// the stream ends.
+func (l *Local) WatchEvents(req *WatchEventsRequest, stream grpc.ServerStreamingServer[Event]) error {
+	// streams bypass the unary interceptor
+	if caller := CallerFrom(stream.Context()); !l.Policy.Allowed(caller, req.GetVpc()) {
+		return status.Errorf(codes.PermissionDenied, "%s may not access vpc %q", caller, req.GetVpc())
+	}
+	l.mu.Lock()
+	stopping := l.stopping
+	l.mu.Unlock()
+	s := l.subscribe(req.GetVpc())
+	defer l.unsubscribe(s)
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-stopping:
+			return nil
+		case e := <-s.events:
+			if n := s.dropped.Swap(0); n > 0 {
+				if err := stream.Send(&Event{Type: Event_DROPPED, TimeUnixNano: e.GetTimeUnixNano(), Dropped: n}); err != nil {
+					return err
+				}
+			}
+			if err := stream.Send(e); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -2,65 +2,371 @@ package local
 
 import (
 	"context"
-	"log"
+	"errors"
+	"log/slog"
 	"net"
 	"os"
+	"sync"
+	"time"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/datum-cloud/galactic-agent/srv6/endpoint"
 )
 
 type Local struct {
 	UnimplementedLocalServer
-	SocketPath        string
-	RegisterHandler   func(string, string, []string) error
-	DeregisterHandler func(string, string, []string) error
+	SocketPath string
+
+	// RegisterHandler and DeregisterHandler get the context of the call,
+	// which CallerFrom identifies the caller from, and the generation of
+	// the request, 0 if it has none.
+	RegisterHandler   func(ctx context.Context, vpc, vpcAttachment string, networks []string, anycast bool, name string, generation uint64) error
+	DeregisterHandler func(ctx context.Context, vpc, vpcAttachment string, networks []string, generation uint64) error
+
+	// AllocateHandler and ReleaseHandler are optional; without them the
+	// attachment id RPCs are unimplemented.
+	AllocateHandler func(vpc, owner string) (string, error)
+	ReleaseHandler  func(vpc, vpcAttachment string) error
+
+	// ListHandler returns the registered attachments of vpc, or of all VPCs
+	// if vpc is empty. GetAttachment is served from it too.
+	ListHandler func(vpc string) ([]*Attachment, error)
+
+	// RoutesHandler is optional; without it the GetRoutes RPC is
+	// unimplemented.
+	RoutesHandler func(ctx context.Context, req *GetRoutesRequest) (*GetRoutesReply, error)
+
+	// ProbeHandler is optional; without it the Probe RPC is unimplemented.
+	ProbeHandler func(ctx context.Context, req *ProbeRequest) (*ProbeReply, error)
+
+	// StatusHandler is optional; without it the Status RPC is unimplemented.
+	StatusHandler func() (*StatusReply, error)
+
+	// Policy restricts which callers may operate on which VPCs; empty
+	// allows every caller everything.
+	Policy Policy
+
+	// DrainTimeout is how long Serve waits, once ctx is done, for the calls
+	// in flight to complete before cutting them off.
+	DrainTimeout time.Duration
+
+	mu          sync.Mutex
+	changed     chan struct{}
+	subscribers map[*subscriber]struct{}
+
+	server   *grpc.Server
+	listener net.Listener
+	served   chan error
+	stopping chan struct{}
 }
 
 func (l *Local) Register(ctx context.Context, req *RegisterRequest) (*RegisterReply, error) {
-	if err := l.RegisterHandler(req.GetVpc(), req.GetVpcattachment(), req.GetNetworks()); err != nil {
+	if err := hexIDs(req); err != nil {
+		return nil, err
+	}
+	if err := l.RegisterHandler(ctx, req.GetVpc(), req.GetVpcattachment(), req.GetNetworks(), req.GetAnycast(), req.GetName(), req.GetGeneration()); err != nil {
 		return nil, err
 	}
 	return &RegisterReply{Confirmed: true}, nil
 }
 
 func (l *Local) Deregister(ctx context.Context, req *DeregisterRequest) (*DeregisterReply, error) {
-	if err := l.DeregisterHandler(req.GetVpc(), req.GetVpcattachment(), req.GetNetworks()); err != nil {
+	if err := hexIDs(req); err != nil {
+		return nil, err
+	}
+	if err := l.DeregisterHandler(ctx, req.GetVpc(), req.GetVpcattachment(), req.GetNetworks(), req.GetGeneration()); err != nil {
 		return nil, err
 	}
 	return &DeregisterReply{Confirmed: true}, nil
 }
 
+// hexIDs rewrites the base62 ids of a Register or Deregister request to
+// the hex ids the handlers and the policy take. Other requests are left
+// alone.
+func hexIDs(req any) error {
+	var base62 *bool
+	var vpc, vpcAttachment *string
+	switch r := req.(type) {
+	case *RegisterRequest:
+		base62, vpc, vpcAttachment = &r.Base62, &r.Vpc, &r.Vpcattachment
+	case *DeregisterRequest:
+		base62, vpc, vpcAttachment = &r.Base62, &r.Vpc, &r.Vpcattachment
+	default:
+		return nil
+	}
+	if !*base62 {
+		return nil
+	}
+	v, a, err := endpoint.Base62ToHex(*vpc, *vpcAttachment)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	*base62, *vpc, *vpcAttachment = false, v, a
+	return nil
+}
+
+// idInterceptor applies hexIDs before the policy sees the ids.
+func idInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if err := hexIDs(req); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (l *Local) AllocateAttachment(ctx context.Context, req *AllocateAttachmentRequest) (*AllocateAttachmentReply, error) {
+	if l.AllocateHandler == nil {
+		return l.UnimplementedLocalServer.AllocateAttachment(ctx, req)
+	}
+	vpcAttachment, err := l.AllocateHandler(req.GetVpc(), req.GetOwner())
+	if err != nil {
+		return nil, err
+	}
+	return &AllocateAttachmentReply{Vpcattachment: vpcAttachment}, nil
+}
+
+func (l *Local) ReleaseAttachment(ctx context.Context, req *ReleaseAttachmentRequest) (*ReleaseAttachmentReply, error) {
+	if l.ReleaseHandler == nil {
+		return l.UnimplementedLocalServer.ReleaseAttachment(ctx, req)
+	}
+	if err := l.ReleaseHandler(req.GetVpc(), req.GetVpcattachment()); err != nil {
+		return nil, err
+	}
+	return &ReleaseAttachmentReply{Confirmed: true}, nil
+}
+
+func (l *Local) ListAttachments(ctx context.Context, req *ListAttachmentsRequest) (*ListAttachmentsReply, error) {
+	if l.ListHandler == nil {
+		return l.UnimplementedLocalServer.ListAttachments(ctx, req)
+	}
+	attachments, err := l.ListHandler(req.GetVpc())
+	if err != nil {
+		return nil, err
+	}
+	return &ListAttachmentsReply{Attachments: attachments}, nil
+}
+
+func (l *Local) GetAttachment(ctx context.Context, req *GetAttachmentRequest) (*Attachment, error) {
+	if l.ListHandler == nil {
+		return l.UnimplementedLocalServer.GetAttachment(ctx, req)
+	}
+	vpc, vpcAttachment, err := endpoint.PadHex(req.GetVpc(), req.GetVpcattachment())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	attachments, err := l.ListHandler(vpc)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range attachments {
+		if a.GetVpcattachment() == vpcAttachment {
+			return a, nil
+		}
+	}
+	return nil, status.Errorf(codes.NotFound, "attachment %s/%s not registered", req.GetVpc(), req.GetVpcattachment())
+}
+
+func (l *Local) GetRoutes(ctx context.Context, req *GetRoutesRequest) (*GetRoutesReply, error) {
+	if l.RoutesHandler == nil {
+		return l.UnimplementedLocalServer.GetRoutes(ctx, req)
+	}
+	return l.RoutesHandler(ctx, req)
+}
+
+func (l *Local) Probe(ctx context.Context, req *ProbeRequest) (*ProbeReply, error) {
+	if l.ProbeHandler == nil {
+		return l.UnimplementedLocalServer.Probe(ctx, req)
+	}
+	return l.ProbeHandler(ctx, req)
+}
+
+func (l *Local) Status(ctx context.Context, req *StatusRequest) (*StatusReply, error) {
+	if l.StatusHandler == nil {
+		return l.UnimplementedLocalServer.Status(ctx, req)
+	}
+	return l.StatusHandler()
+}
+
+// Notify wakes Watch streams to report what changed in ListHandler's view.
+// It does not block, so it may be called with the registry locked.
+func (l *Local) Notify() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.changed != nil {
+		close(l.changed)
+		l.changed = nil
+	}
+}
+
+func (l *Local) changes() <-chan struct{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.changed == nil {
+		l.changed = make(chan struct{})
+	}
+	return l.changed
+}
+
+// Watch relists the attachments on every Notify and sends those that
+// differ from what the stream last saw. Bursts of changes are coalesced.
+func (l *Local) Watch(req *WatchRequest, stream grpc.ServerStreamingServer[AttachmentEvent]) error {
+	if l.ListHandler == nil {
+		return l.UnimplementedLocalServer.Watch(req, stream)
+	}
+	// streams bypass the unary interceptor
+	if caller := CallerFrom(stream.Context()); !l.Policy.Allowed(caller, req.GetVpc()) {
+		return status.Errorf(codes.PermissionDenied, "%s may not access vpc %q", caller, req.GetVpc())
+	}
+	l.mu.Lock()
+	stopping := l.stopping
+	l.mu.Unlock()
+	known := make(map[string]*Attachment)
+	for synced := false; ; synced = true {
+		// taken before listing so that a change made meanwhile is not missed
+		changed := l.changes()
+		attachments, err := l.ListHandler(req.GetVpc())
+		if err != nil {
+			return err
+		}
+		seen := make(map[string]bool, len(attachments))
+		for _, a := range attachments {
+			key := a.GetVpc() + "/" + a.GetVpcattachment()
+			seen[key] = true
+			if old, ok := known[key]; ok && proto.Equal(old, a) {
+				continue
+			}
+			known[key] = a
+			if err := stream.Send(&AttachmentEvent{Type: AttachmentEvent_UPDATED, Attachment: a}); err != nil {
+				return err
+			}
+		}
+		for key, a := range known {
+			if seen[key] {
+				continue
+			}
+			delete(known, key)
+			if err := stream.Send(&AttachmentEvent{Type: AttachmentEvent_REMOVED, Attachment: a}); err != nil {
+				return err
+			}
+		}
+		if !synced {
+			if err := stream.Send(&AttachmentEvent{Type: AttachmentEvent_SYNCED}); err != nil {
+				return err
+			}
+		}
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-stopping:
+			return nil
+		case <-changed:
+		}
+	}
+}
+
 func (l *Local) Serve(ctx context.Context) error {
+	s := grpc.NewServer(
+		grpc.Creds(peerCreds{}),
+		grpc.ChainUnaryInterceptor(idInterceptor, l.Policy.interceptor),
+	)
+	RegisterLocalServer(s, l)
+
+	reflection.Register(s)
+
+	served := make(chan error, 1)
+	l.mu.Lock()
+	l.server, l.served, l.stopping = s, served, make(chan struct{})
+	err := l.listen(l.SocketPath)
+	l.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	select {
+	case <-ctx.Done():
+	case err = <-served:
+		s.Stop()
+		slog.Info("gRPC stopped")
+		return err
+	}
+	// no new calls are accepted; those in flight complete, watches end
+	l.mu.Lock()
+	l.listener = nil
+	close(l.stopping)
+	l.mu.Unlock()
+	stopped := make(chan struct{})
+	go func() {
+		s.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(l.DrainTimeout):
+		slog.Warn("gRPC calls in flight cut off", "drain_timeout", l.DrainTimeout)
+		s.Stop()
+		<-stopped
+	}
+	slog.Info("gRPC stopped")
+	return nil
+}
+
+// Serving reports whether the API accepts connections on its socket.
+func (l *Local) Serving() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.listener != nil
+}
+
+// Rebind moves the API to a new socket at socketPath and removes the old
+// one. Connections made on the old socket are served until they close.
+func (l *Local) Rebind(socketPath string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.server == nil {
+		return errors.New("not serving")
+	}
+	if socketPath == l.SocketPath {
+		return nil
+	}
+	return l.listen(socketPath)
+}
+
+// listen serves on a socket at socketPath in place of the current one; the
+// caller holds l.mu.
+func (l *Local) listen(socketPath string) error {
 	// unix socket should be unlinked if it exists first
 	// see: https://github.com/golang/go/issues/70985
-	err := os.Remove(l.SocketPath)
+	err := os.Remove(socketPath)
 	if err != nil && !os.IsNotExist(err) {
 		return err
 	}
-	listener, err := net.Listen("unix", l.SocketPath)
+	listener, err := net.Listen("unix", socketPath)
 	if err != nil {
 		return err
 	}
-	defer listener.Close() //nolint:errcheck
-
-	s := grpc.NewServer()
-	RegisterLocalServer(s, l)
-
-	reflection.Register(s)
-
-	routineErr := make(chan error, 1)
+	old := l.listener
+	l.listener, l.SocketPath = listener, socketPath
 	go func() {
-		log.Printf("gRPC listening: unix://%s", l.SocketPath)
-		if err := s.Serve(listener); err != nil {
-			routineErr <- err
+		slog.Info("gRPC listening", "socket", socketPath)
+		err := l.server.Serve(listener)
+		// a listener Rebind replaced is not a failure
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if l.listener != listener {
 			return
 		}
-		routineErr <- nil
+		select {
+		case l.served <- err:
+		default:
+		}
 	}()
-
-	<-ctx.Done()
-	s.Stop()
-	log.Println("gRPC stopped")
-	return <-routineErr
+	if old != nil {
+		// closing a unix listener unlinks its socket
+		old.Close() //nolint:errcheck
+	}
+	return nil
 }
@@ -21,11 +21,204 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+type AttachmentEvent_Type int32
+
+const (
+	// UPDATED carries an attachment that was registered or changed.
+	AttachmentEvent_UPDATED AttachmentEvent_Type = 0
+	// REMOVED carries the last state of an attachment that is gone.
+	AttachmentEvent_REMOVED AttachmentEvent_Type = 1
+	// SYNCED follows the initial events; it carries no attachment.
+	AttachmentEvent_SYNCED AttachmentEvent_Type = 2
+)
+
+// Enum value maps for AttachmentEvent_Type.
+var (
+	AttachmentEvent_Type_name = map[int32]string{
+		0: "UPDATED",
+		1: "REMOVED",
+		2: "SYNCED",
+	}
+	AttachmentEvent_Type_value = map[string]int32{
+		"UPDATED": 0,
+		"REMOVED": 1,
+		"SYNCED":  2,
+	}
+)
+
+func (x AttachmentEvent_Type) Enum() *AttachmentEvent_Type {
+	p := new(AttachmentEvent_Type)
+	*p = x
+	return p
+}
+
+func (x AttachmentEvent_Type) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (AttachmentEvent_Type) Descriptor() protoreflect.EnumDescriptor {
+	return file_local_proto_enumTypes[0].Descriptor()
+}
+
+func (AttachmentEvent_Type) Type() protoreflect.EnumType {
+	return &file_local_proto_enumTypes[0]
+}
+
+func (x AttachmentEvent_Type) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use AttachmentEvent_Type.Descriptor instead.
+func (AttachmentEvent_Type) EnumDescriptor() ([]byte, []int) {
+	return file_local_proto_rawDescGZIP(), []int{20, 0}
+}
+
+type Event_Type int32
+
+const (
+	Event_UNSPECIFIED   Event_Type = 0
+	Event_REGISTERED    Event_Type = 1
+	Event_DEREGISTERED  Event_Type = 2
+	Event_ROUTE_ADDED   Event_Type = 3
+	Event_ROUTE_DELETED Event_Type = 4
+	// MQTT_CONNECTED is sent whenever the broker of tenant (re)connects.
+	Event_MQTT_CONNECTED Event_Type = 5
+	// ERROR carries an operation that failed, or a route refused, and
+	// why.
+	Event_ERROR Event_Type = 6
+	// DROPPED tells the subscriber how many events it missed.
+	Event_DROPPED Event_Type = 7
+)
+
+// Enum value maps for Event_Type.
+var (
+	Event_Type_name = map[int32]string{
+		0: "UNSPECIFIED",
+		1: "REGISTERED",
+		2: "DEREGISTERED",
+		3: "ROUTE_ADDED",
+		4: "ROUTE_DELETED",
+		5: "MQTT_CONNECTED",
+		6: "ERROR",
+		7: "DROPPED",
+	}
+	Event_Type_value = map[string]int32{
+		"UNSPECIFIED":    0,
+		"REGISTERED":     1,
+		"DEREGISTERED":   2,
+		"ROUTE_ADDED":    3,
+		"ROUTE_DELETED":  4,
+		"MQTT_CONNECTED": 5,
+		"ERROR":          6,
+		"DROPPED":        7,
+	}
+)
+
+func (x Event_Type) Enum() *Event_Type {
+	p := new(Event_Type)
+	*p = x
+	return p
+}
+
+func (x Event_Type) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Event_Type) Descriptor() protoreflect.EnumDescriptor {
+	return file_local_proto_enumTypes[1].Descriptor()
+}
+
+func (Event_Type) Type() protoreflect.EnumType {
+	return &file_local_proto_enumTypes[1]
+}
+
+func (x Event_Type) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Event_Type.Descriptor instead.
+func (Event_Type) EnumDescriptor() ([]byte, []int) {
+	return file_local_proto_rawDescGZIP(), []int{22, 0}
+}
+
+type ProbeHop_Kind int32
+
+const (
+	// TIMEOUT: nothing came back in time.
+	ProbeHop_TIMEOUT ProbeHop_Kind = 0
+	// TIME_EXCEEDED: a router on the way ran out of hop limit.
+	ProbeHop_TIME_EXCEEDED ProbeHop_Kind = 1
+	// UNREACHABLE: a router could not forward the probe.
+	ProbeHop_UNREACHABLE ProbeHop_Kind = 2
+	// REPLY: the responder sent the probe back.
+	ProbeHop_REPLY ProbeHop_Kind = 3
+)
+
+// Enum value maps for ProbeHop_Kind.
+var (
+	ProbeHop_Kind_name = map[int32]string{
+		0: "TIMEOUT",
+		1: "TIME_EXCEEDED",
+		2: "UNREACHABLE",
+		3: "REPLY",
+	}
+	ProbeHop_Kind_value = map[string]int32{
+		"TIMEOUT":       0,
+		"TIME_EXCEEDED": 1,
+		"UNREACHABLE":   2,
+		"REPLY":         3,
+	}
+)
+
+func (x ProbeHop_Kind) Enum() *ProbeHop_Kind {
+	p := new(ProbeHop_Kind)
+	*p = x
+	return p
+}
+
+func (x ProbeHop_Kind) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ProbeHop_Kind) Descriptor() protoreflect.EnumDescriptor {
+	return file_local_proto_enumTypes[2].Descriptor()
+}
+
+func (ProbeHop_Kind) Type() protoreflect.EnumType {
+	return &file_local_proto_enumTypes[2]
+}
+
+func (x ProbeHop_Kind) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ProbeHop_Kind.Descriptor instead.
+func (ProbeHop_Kind) EnumDescriptor() ([]byte, []int) {
+	return file_local_proto_rawDescGZIP(), []int{25, 0}
+}
+
 type RegisterRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Vpc           string                 `protobuf:"bytes,1,opt,name=vpc,proto3" json:"vpc,omitempty"`
 	Vpcattachment string                 `protobuf:"bytes,2,opt,name=vpcattachment,proto3" json:"vpcattachment,omitempty"`
 	Networks      []string               `protobuf:"bytes,3,rep,name=networks,proto3" json:"networks,omitempty"`
+	// anycast allows the networks to also be registered by other attachments
+	// of the VPC that set it too. Without it a network already registered by
+	// another attachment is handled by the agent's duplicate_networks policy.
+	Anycast bool `protobuf:"varint,4,opt,name=anycast,proto3" json:"anycast,omitempty"`
+	// name optionally publishes the attachment's addresses under
+	// <name>.<vpc>.<dns_domain> when the agent registers names in DNS; any
+	// number of attachments may share a name. It must be a DNS label.
+	Name string `protobuf:"bytes,5,opt,name=name,proto3" json:"name,omitempty"`
+	// generation optionally orders the registrations and deregistrations of
+	// the attachment, e.g. by the revision of the pod it serves. Repeating
+	// the attachment's generation confirms without doing anything again; an
+	// older one, or the one it was deregistered with, fails with
+	// FAILED_PRECONDITION. 0 is always applied.
+	Generation uint64 `protobuf:"varint,6,opt,name=generation,proto3" json:"generation,omitempty"`
+	// base62 takes vpc and vpcattachment as the base62 ids found in the
+	// attachment's interface names rather than hex.
+	Base62        bool `protobuf:"varint,7,opt,name=base62,proto3" json:"base62,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -81,6 +274,34 @@ func (x *RegisterRequest) GetNetworks() []string {
 	return nil
 }
 
+func (x *RegisterRequest) GetAnycast() bool {
+	if x != nil {
+		return x.Anycast
+	}
+	return false
+}
+
+func (x *RegisterRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *RegisterRequest) GetGeneration() uint64 {
+	if x != nil {
+		return x.Generation
+	}
+	return 0
+}
+
+func (x *RegisterRequest) GetBase62() bool {
+	if x != nil {
+		return x.Base62
+	}
+	return false
+}
+
 type RegisterReply struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Confirmed     bool                   `protobuf:"varint,1,opt,name=confirmed,proto3" json:"confirmed,omitempty"`
@@ -130,6 +351,13 @@ type DeregisterRequest struct {
 	Vpc           string                 `protobuf:"bytes,1,opt,name=vpc,proto3" json:"vpc,omitempty"`
 	Vpcattachment string                 `protobuf:"bytes,2,opt,name=vpcattachment,proto3" json:"vpcattachment,omitempty"`
 	Networks      []string               `protobuf:"bytes,3,rep,name=networks,proto3" json:"networks,omitempty"`
+	// generation is that of the registration being undone, see
+	// RegisterRequest; an older one than the attachment's fails with
+	// FAILED_PRECONDITION, so that a late deregistration leaves a newer
+	// registration in place.
+	Generation uint64 `protobuf:"varint,4,opt,name=generation,proto3" json:"generation,omitempty"`
+	// base62 is that of RegisterRequest.
+	Base62        bool `protobuf:"varint,5,opt,name=base62,proto3" json:"base62,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -185,6 +413,20 @@ func (x *DeregisterRequest) GetNetworks() []string {
 	return nil
 }
 
+func (x *DeregisterRequest) GetGeneration() uint64 {
+	if x != nil {
+		return x.Generation
+	}
+	return 0
+}
+
+func (x *DeregisterRequest) GetBase62() bool {
+	if x != nil {
+		return x.Base62
+	}
+	return false
+}
+
 type DeregisterReply struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Confirmed     bool                   `protobuf:"varint,1,opt,name=confirmed,proto3" json:"confirmed,omitempty"`
@@ -229,27 +471,2124 @@ func (x *DeregisterReply) GetConfirmed() bool {
 	return false
 }
 
-var File_local_proto protoreflect.FileDescriptor
+type RegisterBatchRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// items are at most 1024.
+	Items         []*RegisterRequest `protobuf:"bytes,1,rep,name=items,proto3" json:"items,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
 
-const file_local_proto_rawDesc = "" +
-	"\n" +
-	"\vlocal.proto\x12\blocal.v1\"e\n" +
-	"\x0fRegisterRequest\x12\x10\n" +
-	"\x03vpc\x18\x01 \x01(\tR\x03vpc\x12$\n" +
-	"\rvpcattachment\x18\x02 \x01(\tR\rvpcattachment\x12\x1a\n" +
-	"\bnetworks\x18\x03 \x03(\tR\bnetworks\"-\n" +
-	"\rRegisterReply\x12\x1c\n" +
-	"\tconfirmed\x18\x01 \x01(\bR\tconfirmed\"g\n" +
-	"\x11DeregisterRequest\x12\x10\n" +
-	"\x03vpc\x18\x01 \x01(\tR\x03vpc\x12$\n" +
-	"\rvpcattachment\x18\x02 \x01(\tR\rvpcattachment\x12\x1a\n" +
-	"\bnetworks\x18\x03 \x03(\tR\bnetworks\"/\n" +
-	"\x0fDeregisterReply\x12\x1c\n" +
-	"\tconfirmed\x18\x01 \x01(\bR\tconfirmed2\x8d\x01\n" +
+func (x *RegisterBatchRequest) Reset() {
+	*x = RegisterBatchRequest{}
+	mi := &file_local_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RegisterBatchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RegisterBatchRequest) ProtoMessage() {}
+
+func (x *RegisterBatchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_local_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RegisterBatchRequest.ProtoReflect.Descriptor instead.
+func (*RegisterBatchRequest) Descriptor() ([]byte, []int) {
+	return file_local_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *RegisterBatchRequest) GetItems() []*RegisterRequest {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+type DeregisterBatchRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// items are at most 1024.
+	Items         []*DeregisterRequest `protobuf:"bytes,1,rep,name=items,proto3" json:"items,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeregisterBatchRequest) Reset() {
+	*x = DeregisterBatchRequest{}
+	mi := &file_local_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeregisterBatchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeregisterBatchRequest) ProtoMessage() {}
+
+func (x *DeregisterBatchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_local_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeregisterBatchRequest.ProtoReflect.Descriptor instead.
+func (*DeregisterBatchRequest) Descriptor() ([]byte, []int) {
+	return file_local_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *DeregisterBatchRequest) GetItems() []*DeregisterRequest {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+type BatchReply struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// results are those of the items of the request, in their order.
+	Results       []*BatchResult `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchReply) Reset() {
+	*x = BatchReply{}
+	mi := &file_local_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchReply) ProtoMessage() {}
+
+func (x *BatchReply) ProtoReflect() protoreflect.Message {
+	mi := &file_local_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchReply.ProtoReflect.Descriptor instead.
+func (*BatchReply) Descriptor() ([]byte, []int) {
+	return file_local_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *BatchReply) GetResults() []*BatchResult {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+type BatchResult struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// code is the gRPC status code the item would have failed the call
+	// with, 0 (OK) if it succeeded.
+	Code          uint32 `protobuf:"varint,1,opt,name=code,proto3" json:"code,omitempty"`
+	Error         string `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchResult) Reset() {
+	*x = BatchResult{}
+	mi := &file_local_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchResult) ProtoMessage() {}
+
+func (x *BatchResult) ProtoReflect() protoreflect.Message {
+	mi := &file_local_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchResult.ProtoReflect.Descriptor instead.
+func (*BatchResult) Descriptor() ([]byte, []int) {
+	return file_local_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *BatchResult) GetCode() uint32 {
+	if x != nil {
+		return x.Code
+	}
+	return 0
+}
+
+func (x *BatchResult) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type AllocateAttachmentRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Vpc           string                 `protobuf:"bytes,1,opt,name=vpc,proto3" json:"vpc,omitempty"`
+	Owner         string                 `protobuf:"bytes,2,opt,name=owner,proto3" json:"owner,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AllocateAttachmentRequest) Reset() {
+	*x = AllocateAttachmentRequest{}
+	mi := &file_local_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AllocateAttachmentRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AllocateAttachmentRequest) ProtoMessage() {}
+
+func (x *AllocateAttachmentRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_local_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AllocateAttachmentRequest.ProtoReflect.Descriptor instead.
+func (*AllocateAttachmentRequest) Descriptor() ([]byte, []int) {
+	return file_local_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *AllocateAttachmentRequest) GetVpc() string {
+	if x != nil {
+		return x.Vpc
+	}
+	return ""
+}
+
+func (x *AllocateAttachmentRequest) GetOwner() string {
+	if x != nil {
+		return x.Owner
+	}
+	return ""
+}
+
+type AllocateAttachmentReply struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Vpcattachment string                 `protobuf:"bytes,1,opt,name=vpcattachment,proto3" json:"vpcattachment,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AllocateAttachmentReply) Reset() {
+	*x = AllocateAttachmentReply{}
+	mi := &file_local_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AllocateAttachmentReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AllocateAttachmentReply) ProtoMessage() {}
+
+func (x *AllocateAttachmentReply) ProtoReflect() protoreflect.Message {
+	mi := &file_local_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AllocateAttachmentReply.ProtoReflect.Descriptor instead.
+func (*AllocateAttachmentReply) Descriptor() ([]byte, []int) {
+	return file_local_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *AllocateAttachmentReply) GetVpcattachment() string {
+	if x != nil {
+		return x.Vpcattachment
+	}
+	return ""
+}
+
+type ReleaseAttachmentRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Vpc           string                 `protobuf:"bytes,1,opt,name=vpc,proto3" json:"vpc,omitempty"`
+	Vpcattachment string                 `protobuf:"bytes,2,opt,name=vpcattachment,proto3" json:"vpcattachment,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReleaseAttachmentRequest) Reset() {
+	*x = ReleaseAttachmentRequest{}
+	mi := &file_local_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReleaseAttachmentRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReleaseAttachmentRequest) ProtoMessage() {}
+
+func (x *ReleaseAttachmentRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_local_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReleaseAttachmentRequest.ProtoReflect.Descriptor instead.
+func (*ReleaseAttachmentRequest) Descriptor() ([]byte, []int) {
+	return file_local_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *ReleaseAttachmentRequest) GetVpc() string {
+	if x != nil {
+		return x.Vpc
+	}
+	return ""
+}
+
+func (x *ReleaseAttachmentRequest) GetVpcattachment() string {
+	if x != nil {
+		return x.Vpcattachment
+	}
+	return ""
+}
+
+type ReleaseAttachmentReply struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Confirmed     bool                   `protobuf:"varint,1,opt,name=confirmed,proto3" json:"confirmed,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReleaseAttachmentReply) Reset() {
+	*x = ReleaseAttachmentReply{}
+	mi := &file_local_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReleaseAttachmentReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReleaseAttachmentReply) ProtoMessage() {}
+
+func (x *ReleaseAttachmentReply) ProtoReflect() protoreflect.Message {
+	mi := &file_local_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReleaseAttachmentReply.ProtoReflect.Descriptor instead.
+func (*ReleaseAttachmentReply) Descriptor() ([]byte, []int) {
+	return file_local_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *ReleaseAttachmentReply) GetConfirmed() bool {
+	if x != nil {
+		return x.Confirmed
+	}
+	return false
+}
+
+type Attachment struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Vpc            string                 `protobuf:"bytes,1,opt,name=vpc,proto3" json:"vpc,omitempty"`
+	Vpcattachment  string                 `protobuf:"bytes,2,opt,name=vpcattachment,proto3" json:"vpcattachment,omitempty"`
+	Vrf            string                 `protobuf:"bytes,3,opt,name=vrf,proto3" json:"vrf,omitempty"`
+	Table          uint32                 `protobuf:"varint,4,opt,name=table,proto3" json:"table,omitempty"`
+	HostInterface  string                 `protobuf:"bytes,5,opt,name=host_interface,json=hostInterface,proto3" json:"host_interface,omitempty"`
+	GuestInterface string                 `protobuf:"bytes,6,opt,name=guest_interface,json=guestInterface,proto3" json:"guest_interface,omitempty"`
+	Srv6Endpoint   string                 `protobuf:"bytes,7,opt,name=srv6_endpoint,json=srv6Endpoint,proto3" json:"srv6_endpoint,omitempty"`
+	Networks       []string               `protobuf:"bytes,8,rep,name=networks,proto3" json:"networks,omitempty"`
+	CreatedUnix    int64                  `protobuf:"varint,9,opt,name=created_unix,json=createdUnix,proto3" json:"created_unix,omitempty"`
+	// anycast lists the networks registered with the anycast flag.
+	Anycast []string `protobuf:"bytes,10,rep,name=anycast,proto3" json:"anycast,omitempty"`
+	// mtu is the MTU pushed by the controller, 0 if none was.
+	Mtu uint32 `protobuf:"varint,11,opt,name=mtu,proto3" json:"mtu,omitempty"`
+	// routes is the number of routes received from the controller that are
+	// installed for the attachment.
+	Routes uint32 `protobuf:"varint,12,opt,name=routes,proto3" json:"routes,omitempty"`
+	// delivery is how the last Register or Deregister sent to the controller
+	// for the attachment fared, as the agent sends them in the background:
+	// "pending", "delivered", or "failed: " and the error; empty if none was
+	// sent since the agent started.
+	Delivery string `protobuf:"bytes,13,opt,name=delivery,proto3" json:"delivery,omitempty"`
+	// name is the name the attachment was last registered with.
+	Name string `protobuf:"bytes,14,opt,name=name,proto3" json:"name,omitempty"`
+	// generation is the highest the attachment was registered or
+	// deregistered with, 0 if none was given.
+	Generation    uint64 `protobuf:"varint,15,opt,name=generation,proto3" json:"generation,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Attachment) Reset() {
+	*x = Attachment{}
+	mi := &file_local_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Attachment) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Attachment) ProtoMessage() {}
+
+func (x *Attachment) ProtoReflect() protoreflect.Message {
+	mi := &file_local_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Attachment.ProtoReflect.Descriptor instead.
+func (*Attachment) Descriptor() ([]byte, []int) {
+	return file_local_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *Attachment) GetVpc() string {
+	if x != nil {
+		return x.Vpc
+	}
+	return ""
+}
+
+func (x *Attachment) GetVpcattachment() string {
+	if x != nil {
+		return x.Vpcattachment
+	}
+	return ""
+}
+
+func (x *Attachment) GetVrf() string {
+	if x != nil {
+		return x.Vrf
+	}
+	return ""
+}
+
+func (x *Attachment) GetTable() uint32 {
+	if x != nil {
+		return x.Table
+	}
+	return 0
+}
+
+func (x *Attachment) GetHostInterface() string {
+	if x != nil {
+		return x.HostInterface
+	}
+	return ""
+}
+
+func (x *Attachment) GetGuestInterface() string {
+	if x != nil {
+		return x.GuestInterface
+	}
+	return ""
+}
+
+func (x *Attachment) GetSrv6Endpoint() string {
+	if x != nil {
+		return x.Srv6Endpoint
+	}
+	return ""
+}
+
+func (x *Attachment) GetNetworks() []string {
+	if x != nil {
+		return x.Networks
+	}
+	return nil
+}
+
+func (x *Attachment) GetCreatedUnix() int64 {
+	if x != nil {
+		return x.CreatedUnix
+	}
+	return 0
+}
+
+func (x *Attachment) GetAnycast() []string {
+	if x != nil {
+		return x.Anycast
+	}
+	return nil
+}
+
+func (x *Attachment) GetMtu() uint32 {
+	if x != nil {
+		return x.Mtu
+	}
+	return 0
+}
+
+func (x *Attachment) GetRoutes() uint32 {
+	if x != nil {
+		return x.Routes
+	}
+	return 0
+}
+
+func (x *Attachment) GetDelivery() string {
+	if x != nil {
+		return x.Delivery
+	}
+	return ""
+}
+
+func (x *Attachment) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Attachment) GetGeneration() uint64 {
+	if x != nil {
+		return x.Generation
+	}
+	return 0
+}
+
+type ListAttachmentsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// vpc optionally restricts the list to one VPC.
+	Vpc           string `protobuf:"bytes,1,opt,name=vpc,proto3" json:"vpc,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListAttachmentsRequest) Reset() {
+	*x = ListAttachmentsRequest{}
+	mi := &file_local_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListAttachmentsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListAttachmentsRequest) ProtoMessage() {}
+
+func (x *ListAttachmentsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_local_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListAttachmentsRequest.ProtoReflect.Descriptor instead.
+func (*ListAttachmentsRequest) Descriptor() ([]byte, []int) {
+	return file_local_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *ListAttachmentsRequest) GetVpc() string {
+	if x != nil {
+		return x.Vpc
+	}
+	return ""
+}
+
+type ListAttachmentsReply struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Attachments   []*Attachment          `protobuf:"bytes,1,rep,name=attachments,proto3" json:"attachments,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListAttachmentsReply) Reset() {
+	*x = ListAttachmentsReply{}
+	mi := &file_local_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListAttachmentsReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListAttachmentsReply) ProtoMessage() {}
+
+func (x *ListAttachmentsReply) ProtoReflect() protoreflect.Message {
+	mi := &file_local_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListAttachmentsReply.ProtoReflect.Descriptor instead.
+func (*ListAttachmentsReply) Descriptor() ([]byte, []int) {
+	return file_local_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *ListAttachmentsReply) GetAttachments() []*Attachment {
+	if x != nil {
+		return x.Attachments
+	}
+	return nil
+}
+
+type GetAttachmentRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Vpc           string                 `protobuf:"bytes,1,opt,name=vpc,proto3" json:"vpc,omitempty"`
+	Vpcattachment string                 `protobuf:"bytes,2,opt,name=vpcattachment,proto3" json:"vpcattachment,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetAttachmentRequest) Reset() {
+	*x = GetAttachmentRequest{}
+	mi := &file_local_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetAttachmentRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetAttachmentRequest) ProtoMessage() {}
+
+func (x *GetAttachmentRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_local_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetAttachmentRequest.ProtoReflect.Descriptor instead.
+func (*GetAttachmentRequest) Descriptor() ([]byte, []int) {
+	return file_local_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *GetAttachmentRequest) GetVpc() string {
+	if x != nil {
+		return x.Vpc
+	}
+	return ""
+}
+
+func (x *GetAttachmentRequest) GetVpcattachment() string {
+	if x != nil {
+		return x.Vpcattachment
+	}
+	return ""
+}
+
+type GetRoutesRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Vpc   string                 `protobuf:"bytes,1,opt,name=vpc,proto3" json:"vpc,omitempty"`
+	// vpcattachment optionally restricts the routes to one attachment.
+	Vpcattachment string `protobuf:"bytes,2,opt,name=vpcattachment,proto3" json:"vpcattachment,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetRoutesRequest) Reset() {
+	*x = GetRoutesRequest{}
+	mi := &file_local_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetRoutesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetRoutesRequest) ProtoMessage() {}
+
+func (x *GetRoutesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_local_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetRoutesRequest.ProtoReflect.Descriptor instead.
+func (*GetRoutesRequest) Descriptor() ([]byte, []int) {
+	return file_local_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *GetRoutesRequest) GetVpc() string {
+	if x != nil {
+		return x.Vpc
+	}
+	return ""
+}
+
+func (x *GetRoutesRequest) GetVpcattachment() string {
+	if x != nil {
+		return x.Vpcattachment
+	}
+	return ""
+}
+
+type GetRoutesReply struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Routes []*ProgrammedRoute     `protobuf:"bytes,1,rep,name=routes,proto3" json:"routes,omitempty"`
+	// kernel_error is why the kernel could not be read, e.g. with the ovs
+	// datapath; the routes then come from the state alone.
+	KernelError   string `protobuf:"bytes,2,opt,name=kernel_error,json=kernelError,proto3" json:"kernel_error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetRoutesReply) Reset() {
+	*x = GetRoutesReply{}
+	mi := &file_local_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetRoutesReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetRoutesReply) ProtoMessage() {}
+
+func (x *GetRoutesReply) ProtoReflect() protoreflect.Message {
+	mi := &file_local_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetRoutesReply.ProtoReflect.Descriptor instead.
+func (*GetRoutesReply) Descriptor() ([]byte, []int) {
+	return file_local_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *GetRoutesReply) GetRoutes() []*ProgrammedRoute {
+	if x != nil {
+		return x.Routes
+	}
+	return nil
+}
+
+func (x *GetRoutesReply) GetKernelError() string {
+	if x != nil {
+		return x.KernelError
+	}
+	return ""
+}
+
+type ProgrammedRoute struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// kind is ingress, egress, or neighbor for the proxy neighbor entry of an
+	// egress route to a host.
+	Kind          string `protobuf:"bytes,1,opt,name=kind,proto3" json:"kind,omitempty"`
+	Vpcattachment string `protobuf:"bytes,2,opt,name=vpcattachment,proto3" json:"vpcattachment,omitempty"`
+	Srv6Endpoint  string `protobuf:"bytes,3,opt,name=srv6_endpoint,json=srv6Endpoint,proto3" json:"srv6_endpoint,omitempty"`
+	// network is the destination of an egress route or neighbor, empty for
+	// ingress.
+	Network string `protobuf:"bytes,4,opt,name=network,proto3" json:"network,omitempty"`
+	// srv6_segments are the segments of an egress route in the state.
+	Srv6Segments []string `protobuf:"bytes,5,rep,name=srv6_segments,json=srv6Segments,proto3" json:"srv6_segments,omitempty"`
+	// table is the VRF table of the attachment.
+	Table uint32 `protobuf:"varint,6,opt,name=table,proto3" json:"table,omitempty"`
+	// in_state and in_kernel tell whether the agent's state and the kernel
+	// hold the route.
+	InState  bool `protobuf:"varint,7,opt,name=in_state,json=inState,proto3" json:"in_state,omitempty"`
+	InKernel bool `protobuf:"varint,8,opt,name=in_kernel,json=inKernel,proto3" json:"in_kernel,omitempty"`
+	// kernel_segments are the segments of an egress route in the kernel.
+	KernelSegments []string `protobuf:"bytes,9,rep,name=kernel_segments,json=kernelSegments,proto3" json:"kernel_segments,omitempty"`
+	// mismatch tells how the route in the kernel differs from the state's,
+	// e.g. "segments [fc00::1], want [fc00::2]"; empty if they agree.
+	Mismatch      string `protobuf:"bytes,10,opt,name=mismatch,proto3" json:"mismatch,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProgrammedRoute) Reset() {
+	*x = ProgrammedRoute{}
+	mi := &file_local_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProgrammedRoute) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProgrammedRoute) ProtoMessage() {}
+
+func (x *ProgrammedRoute) ProtoReflect() protoreflect.Message {
+	mi := &file_local_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProgrammedRoute.ProtoReflect.Descriptor instead.
+func (*ProgrammedRoute) Descriptor() ([]byte, []int) {
+	return file_local_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *ProgrammedRoute) GetKind() string {
+	if x != nil {
+		return x.Kind
+	}
+	return ""
+}
+
+func (x *ProgrammedRoute) GetVpcattachment() string {
+	if x != nil {
+		return x.Vpcattachment
+	}
+	return ""
+}
+
+func (x *ProgrammedRoute) GetSrv6Endpoint() string {
+	if x != nil {
+		return x.Srv6Endpoint
+	}
+	return ""
+}
+
+func (x *ProgrammedRoute) GetNetwork() string {
+	if x != nil {
+		return x.Network
+	}
+	return ""
+}
+
+func (x *ProgrammedRoute) GetSrv6Segments() []string {
+	if x != nil {
+		return x.Srv6Segments
+	}
+	return nil
+}
+
+func (x *ProgrammedRoute) GetTable() uint32 {
+	if x != nil {
+		return x.Table
+	}
+	return 0
+}
+
+func (x *ProgrammedRoute) GetInState() bool {
+	if x != nil {
+		return x.InState
+	}
+	return false
+}
+
+func (x *ProgrammedRoute) GetInKernel() bool {
+	if x != nil {
+		return x.InKernel
+	}
+	return false
+}
+
+func (x *ProgrammedRoute) GetKernelSegments() []string {
+	if x != nil {
+		return x.KernelSegments
+	}
+	return nil
+}
+
+func (x *ProgrammedRoute) GetMismatch() string {
+	if x != nil {
+		return x.Mismatch
+	}
+	return ""
+}
+
+type WatchRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// vpc optionally restricts the stream to one VPC.
+	Vpc           string `protobuf:"bytes,1,opt,name=vpc,proto3" json:"vpc,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchRequest) Reset() {
+	*x = WatchRequest{}
+	mi := &file_local_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchRequest) ProtoMessage() {}
+
+func (x *WatchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_local_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchRequest.ProtoReflect.Descriptor instead.
+func (*WatchRequest) Descriptor() ([]byte, []int) {
+	return file_local_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *WatchRequest) GetVpc() string {
+	if x != nil {
+		return x.Vpc
+	}
+	return ""
+}
+
+type AttachmentEvent struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Type          AttachmentEvent_Type   `protobuf:"varint,1,opt,name=type,proto3,enum=local.v1.AttachmentEvent_Type" json:"type,omitempty"`
+	Attachment    *Attachment            `protobuf:"bytes,2,opt,name=attachment,proto3" json:"attachment,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AttachmentEvent) Reset() {
+	*x = AttachmentEvent{}
+	mi := &file_local_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AttachmentEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AttachmentEvent) ProtoMessage() {}
+
+func (x *AttachmentEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_local_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AttachmentEvent.ProtoReflect.Descriptor instead.
+func (*AttachmentEvent) Descriptor() ([]byte, []int) {
+	return file_local_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *AttachmentEvent) GetType() AttachmentEvent_Type {
+	if x != nil {
+		return x.Type
+	}
+	return AttachmentEvent_UPDATED
+}
+
+func (x *AttachmentEvent) GetAttachment() *Attachment {
+	if x != nil {
+		return x.Attachment
+	}
+	return nil
+}
+
+type WatchEventsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// vpc optionally restricts the stream to one VPC; events of no VPC, such
+	// as MQTT_CONNECTED, are sent regardless.
+	Vpc           string `protobuf:"bytes,1,opt,name=vpc,proto3" json:"vpc,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchEventsRequest) Reset() {
+	*x = WatchEventsRequest{}
+	mi := &file_local_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchEventsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchEventsRequest) ProtoMessage() {}
+
+func (x *WatchEventsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_local_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchEventsRequest.ProtoReflect.Descriptor instead.
+func (*WatchEventsRequest) Descriptor() ([]byte, []int) {
+	return file_local_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *WatchEventsRequest) GetVpc() string {
+	if x != nil {
+		return x.Vpc
+	}
+	return ""
+}
+
+type Event struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Type          Event_Type             `protobuf:"varint,1,opt,name=type,proto3,enum=local.v1.Event_Type" json:"type,omitempty"`
+	TimeUnixNano  int64                  `protobuf:"varint,2,opt,name=time_unix_nano,json=timeUnixNano,proto3" json:"time_unix_nano,omitempty"`
+	Tenant        string                 `protobuf:"bytes,3,opt,name=tenant,proto3" json:"tenant,omitempty"`
+	Vpc           string                 `protobuf:"bytes,4,opt,name=vpc,proto3" json:"vpc,omitempty"`
+	Vpcattachment string                 `protobuf:"bytes,5,opt,name=vpcattachment,proto3" json:"vpcattachment,omitempty"`
+	Network       string                 `protobuf:"bytes,6,opt,name=network,proto3" json:"network,omitempty"`
+	Srv6Endpoint  string                 `protobuf:"bytes,7,opt,name=srv6_endpoint,json=srv6Endpoint,proto3" json:"srv6_endpoint,omitempty"`
+	Srv6Segments  []string               `protobuf:"bytes,8,rep,name=srv6_segments,json=srv6Segments,proto3" json:"srv6_segments,omitempty"`
+	// operation is what failed for ERROR, e.g. "register" or "route_add".
+	Operation     string `protobuf:"bytes,9,opt,name=operation,proto3" json:"operation,omitempty"`
+	Error         string `protobuf:"bytes,10,opt,name=error,proto3" json:"error,omitempty"`
+	Dropped       uint32 `protobuf:"varint,11,opt,name=dropped,proto3" json:"dropped,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Event) Reset() {
+	*x = Event{}
+	mi := &file_local_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Event) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Event) ProtoMessage() {}
+
+func (x *Event) ProtoReflect() protoreflect.Message {
+	mi := &file_local_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Event.ProtoReflect.Descriptor instead.
+func (*Event) Descriptor() ([]byte, []int) {
+	return file_local_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *Event) GetType() Event_Type {
+	if x != nil {
+		return x.Type
+	}
+	return Event_UNSPECIFIED
+}
+
+func (x *Event) GetTimeUnixNano() int64 {
+	if x != nil {
+		return x.TimeUnixNano
+	}
+	return 0
+}
+
+func (x *Event) GetTenant() string {
+	if x != nil {
+		return x.Tenant
+	}
+	return ""
+}
+
+func (x *Event) GetVpc() string {
+	if x != nil {
+		return x.Vpc
+	}
+	return ""
+}
+
+func (x *Event) GetVpcattachment() string {
+	if x != nil {
+		return x.Vpcattachment
+	}
+	return ""
+}
+
+func (x *Event) GetNetwork() string {
+	if x != nil {
+		return x.Network
+	}
+	return ""
+}
+
+func (x *Event) GetSrv6Endpoint() string {
+	if x != nil {
+		return x.Srv6Endpoint
+	}
+	return ""
+}
+
+func (x *Event) GetSrv6Segments() []string {
+	if x != nil {
+		return x.Srv6Segments
+	}
+	return nil
+}
+
+func (x *Event) GetOperation() string {
+	if x != nil {
+		return x.Operation
+	}
+	return ""
+}
+
+func (x *Event) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *Event) GetDropped() uint32 {
+	if x != nil {
+		return x.Dropped
+	}
+	return 0
+}
+
+type ProbeRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Vpc   string                 `protobuf:"bytes,1,opt,name=vpc,proto3" json:"vpc,omitempty"`
+	// vpcattachment optionally picks the attachment whose VRF is looked up;
+	// by default it is the first registered attachment of the VPC.
+	Vpcattachment string `protobuf:"bytes,2,opt,name=vpcattachment,proto3" json:"vpcattachment,omitempty"`
+	// destination is an address in the VPC.
+	Destination string `protobuf:"bytes,3,opt,name=destination,proto3" json:"destination,omitempty"`
+	// max_hops bounds the trace, 30 if 0.
+	MaxHops uint32 `protobuf:"varint,4,opt,name=max_hops,json=maxHops,proto3" json:"max_hops,omitempty"`
+	// timeout_ms is how long to wait for each hop, 1000 if 0.
+	TimeoutMs     uint32 `protobuf:"varint,5,opt,name=timeout_ms,json=timeoutMs,proto3" json:"timeout_ms,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProbeRequest) Reset() {
+	*x = ProbeRequest{}
+	mi := &file_local_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProbeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProbeRequest) ProtoMessage() {}
+
+func (x *ProbeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_local_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProbeRequest.ProtoReflect.Descriptor instead.
+func (*ProbeRequest) Descriptor() ([]byte, []int) {
+	return file_local_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *ProbeRequest) GetVpc() string {
+	if x != nil {
+		return x.Vpc
+	}
+	return ""
+}
+
+func (x *ProbeRequest) GetVpcattachment() string {
+	if x != nil {
+		return x.Vpcattachment
+	}
+	return ""
+}
+
+func (x *ProbeRequest) GetDestination() string {
+	if x != nil {
+		return x.Destination
+	}
+	return ""
+}
+
+func (x *ProbeRequest) GetMaxHops() uint32 {
+	if x != nil {
+		return x.MaxHops
+	}
+	return 0
+}
+
+func (x *ProbeRequest) GetTimeoutMs() uint32 {
+	if x != nil {
+		return x.TimeoutMs
+	}
+	return 0
+}
+
+type ProbeReply struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Vpcattachment string                 `protobuf:"bytes,1,opt,name=vpcattachment,proto3" json:"vpcattachment,omitempty"`
+	Vrf           string                 `protobuf:"bytes,2,opt,name=vrf,proto3" json:"vrf,omitempty"`
+	Table         uint32                 `protobuf:"varint,3,opt,name=table,proto3" json:"table,omitempty"`
+	// network is the route to destination in the VRF and srv6_segments its
+	// segments, in the order the route was received.
+	Network      string   `protobuf:"bytes,4,opt,name=network,proto3" json:"network,omitempty"`
+	Srv6Segments []string `protobuf:"bytes,5,rep,name=srv6_segments,json=srv6Segments,proto3" json:"srv6_segments,omitempty"`
+	// responder is the address answering probes on the node the segments
+	// lead to.
+	Responder string `protobuf:"bytes,6,opt,name=responder,proto3" json:"responder,omitempty"`
+	// local is set when the segments lead back to this node, which is not
+	// traced.
+	Local bool `protobuf:"varint,7,opt,name=local,proto3" json:"local,omitempty"`
+	// reached is set when the responder answered.
+	Reached       bool        `protobuf:"varint,8,opt,name=reached,proto3" json:"reached,omitempty"`
+	Hops          []*ProbeHop `protobuf:"bytes,9,rep,name=hops,proto3" json:"hops,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProbeReply) Reset() {
+	*x = ProbeReply{}
+	mi := &file_local_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProbeReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProbeReply) ProtoMessage() {}
+
+func (x *ProbeReply) ProtoReflect() protoreflect.Message {
+	mi := &file_local_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProbeReply.ProtoReflect.Descriptor instead.
+func (*ProbeReply) Descriptor() ([]byte, []int) {
+	return file_local_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *ProbeReply) GetVpcattachment() string {
+	if x != nil {
+		return x.Vpcattachment
+	}
+	return ""
+}
+
+func (x *ProbeReply) GetVrf() string {
+	if x != nil {
+		return x.Vrf
+	}
+	return ""
+}
+
+func (x *ProbeReply) GetTable() uint32 {
+	if x != nil {
+		return x.Table
+	}
+	return 0
+}
+
+func (x *ProbeReply) GetNetwork() string {
+	if x != nil {
+		return x.Network
+	}
+	return ""
+}
+
+func (x *ProbeReply) GetSrv6Segments() []string {
+	if x != nil {
+		return x.Srv6Segments
+	}
+	return nil
+}
+
+func (x *ProbeReply) GetResponder() string {
+	if x != nil {
+		return x.Responder
+	}
+	return ""
+}
+
+func (x *ProbeReply) GetLocal() bool {
+	if x != nil {
+		return x.Local
+	}
+	return false
+}
+
+func (x *ProbeReply) GetReached() bool {
+	if x != nil {
+		return x.Reached
+	}
+	return false
+}
+
+func (x *ProbeReply) GetHops() []*ProbeHop {
+	if x != nil {
+		return x.Hops
+	}
+	return nil
+}
+
+type ProbeHop struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// hop_limit is the hop limit of the outer header the probe was sent with.
+	HopLimit uint32        `protobuf:"varint,1,opt,name=hop_limit,json=hopLimit,proto3" json:"hop_limit,omitempty"`
+	Kind     ProbeHop_Kind `protobuf:"varint,2,opt,name=kind,proto3,enum=local.v1.ProbeHop_Kind" json:"kind,omitempty"`
+	Address  string        `protobuf:"bytes,3,opt,name=address,proto3" json:"address,omitempty"`
+	RttNanos int64         `protobuf:"varint,4,opt,name=rtt_nanos,json=rttNanos,proto3" json:"rtt_nanos,omitempty"`
+	// icmp_code is the ICMPv6 code of TIME_EXCEEDED and UNREACHABLE hops.
+	IcmpCode uint32 `protobuf:"varint,5,opt,name=icmp_code,json=icmpCode,proto3" json:"icmp_code,omitempty"`
+	// segment is set when address is one of the route's segments.
+	Segment       bool `protobuf:"varint,6,opt,name=segment,proto3" json:"segment,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProbeHop) Reset() {
+	*x = ProbeHop{}
+	mi := &file_local_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProbeHop) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProbeHop) ProtoMessage() {}
+
+func (x *ProbeHop) ProtoReflect() protoreflect.Message {
+	mi := &file_local_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProbeHop.ProtoReflect.Descriptor instead.
+func (*ProbeHop) Descriptor() ([]byte, []int) {
+	return file_local_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *ProbeHop) GetHopLimit() uint32 {
+	if x != nil {
+		return x.HopLimit
+	}
+	return 0
+}
+
+func (x *ProbeHop) GetKind() ProbeHop_Kind {
+	if x != nil {
+		return x.Kind
+	}
+	return ProbeHop_TIMEOUT
+}
+
+func (x *ProbeHop) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+func (x *ProbeHop) GetRttNanos() int64 {
+	if x != nil {
+		return x.RttNanos
+	}
+	return 0
+}
+
+func (x *ProbeHop) GetIcmpCode() uint32 {
+	if x != nil {
+		return x.IcmpCode
+	}
+	return 0
+}
+
+func (x *ProbeHop) GetSegment() bool {
+	if x != nil {
+		return x.Segment
+	}
+	return false
+}
+
+type StatusRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StatusRequest) Reset() {
+	*x = StatusRequest{}
+	mi := &file_local_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatusRequest) ProtoMessage() {}
+
+func (x *StatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_local_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StatusRequest.ProtoReflect.Descriptor instead.
+func (*StatusRequest) Descriptor() ([]byte, []int) {
+	return file_local_proto_rawDescGZIP(), []int{26}
+}
+
+type StatusReply struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Version   string                 `protobuf:"bytes,1,opt,name=version,proto3" json:"version,omitempty"`
+	Commit    string                 `protobuf:"bytes,2,opt,name=commit,proto3" json:"commit,omitempty"`
+	BuildDate string                 `protobuf:"bytes,3,opt,name=build_date,json=buildDate,proto3" json:"build_date,omitempty"`
+	// schema is the fingerprint of the envelope schema the agent speaks.
+	Schema string `protobuf:"bytes,4,opt,name=schema,proto3" json:"schema,omitempty"`
+	// config_file is the config file the agent read, empty if none.
+	ConfigFile string `protobuf:"bytes,5,opt,name=config_file,json=configFile,proto3" json:"config_file,omitempty"`
+	// profile is the profile of config_file applied, empty if none.
+	Profile string   `protobuf:"bytes,6,opt,name=profile,proto3" json:"profile,omitempty"`
+	Tenants []string `protobuf:"bytes,7,rep,name=tenants,proto3" json:"tenants,omitempty"`
+	DryRun  bool     `protobuf:"varint,8,opt,name=dry_run,json=dryRun,proto3" json:"dry_run,omitempty"`
+	// preflight_failures are the kernel checks that failed on startup when
+	// the agent runs degraded.
+	PreflightFailures []string        `protobuf:"bytes,9,rep,name=preflight_failures,json=preflightFailures,proto3" json:"preflight_failures,omitempty"`
+	UptimeSeconds     int64           `protobuf:"varint,10,opt,name=uptime_seconds,json=uptimeSeconds,proto3" json:"uptime_seconds,omitempty"`
+	TenantStatus      []*TenantStatus `protobuf:"bytes,11,rep,name=tenant_status,json=tenantStatus,proto3" json:"tenant_status,omitempty"`
+	// vrfs counts the routes of each attachment's VRF.
+	Vrfs []*VRFStatus `protobuf:"bytes,12,rep,name=vrfs,proto3" json:"vrfs,omitempty"`
+	// last_errors are the most recent failures, newest first.
+	LastErrors    []*ErrorStatus `protobuf:"bytes,13,rep,name=last_errors,json=lastErrors,proto3" json:"last_errors,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StatusReply) Reset() {
+	*x = StatusReply{}
+	mi := &file_local_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StatusReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatusReply) ProtoMessage() {}
+
+func (x *StatusReply) ProtoReflect() protoreflect.Message {
+	mi := &file_local_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StatusReply.ProtoReflect.Descriptor instead.
+func (*StatusReply) Descriptor() ([]byte, []int) {
+	return file_local_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *StatusReply) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+func (x *StatusReply) GetCommit() string {
+	if x != nil {
+		return x.Commit
+	}
+	return ""
+}
+
+func (x *StatusReply) GetBuildDate() string {
+	if x != nil {
+		return x.BuildDate
+	}
+	return ""
+}
+
+func (x *StatusReply) GetSchema() string {
+	if x != nil {
+		return x.Schema
+	}
+	return ""
+}
+
+func (x *StatusReply) GetConfigFile() string {
+	if x != nil {
+		return x.ConfigFile
+	}
+	return ""
+}
+
+func (x *StatusReply) GetProfile() string {
+	if x != nil {
+		return x.Profile
+	}
+	return ""
+}
+
+func (x *StatusReply) GetTenants() []string {
+	if x != nil {
+		return x.Tenants
+	}
+	return nil
+}
+
+func (x *StatusReply) GetDryRun() bool {
+	if x != nil {
+		return x.DryRun
+	}
+	return false
+}
+
+func (x *StatusReply) GetPreflightFailures() []string {
+	if x != nil {
+		return x.PreflightFailures
+	}
+	return nil
+}
+
+func (x *StatusReply) GetUptimeSeconds() int64 {
+	if x != nil {
+		return x.UptimeSeconds
+	}
+	return 0
+}
+
+func (x *StatusReply) GetTenantStatus() []*TenantStatus {
+	if x != nil {
+		return x.TenantStatus
+	}
+	return nil
+}
+
+func (x *StatusReply) GetVrfs() []*VRFStatus {
+	if x != nil {
+		return x.Vrfs
+	}
+	return nil
+}
+
+func (x *StatusReply) GetLastErrors() []*ErrorStatus {
+	if x != nil {
+		return x.LastErrors
+	}
+	return nil
+}
+
+type TenantStatus struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Name      string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Srv6Net   string                 `protobuf:"bytes,2,opt,name=srv6_net,json=srv6Net,proto3" json:"srv6_net,omitempty"`
+	Broker    string                 `protobuf:"bytes,3,opt,name=broker,proto3" json:"broker,omitempty"`
+	Connected bool                   `protobuf:"varint,4,opt,name=connected,proto3" json:"connected,omitempty"`
+	// broker_error is why the broker connection last went down or failed
+	// to come up, empty if it never did.
+	BrokerError   string `protobuf:"bytes,5,opt,name=broker_error,json=brokerError,proto3" json:"broker_error,omitempty"`
+	Attachments   uint32 `protobuf:"varint,6,opt,name=attachments,proto3" json:"attachments,omitempty"`
+	Routes        uint32 `protobuf:"varint,7,opt,name=routes,proto3" json:"routes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TenantStatus) Reset() {
+	*x = TenantStatus{}
+	mi := &file_local_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TenantStatus) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TenantStatus) ProtoMessage() {}
+
+func (x *TenantStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_local_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TenantStatus.ProtoReflect.Descriptor instead.
+func (*TenantStatus) Descriptor() ([]byte, []int) {
+	return file_local_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *TenantStatus) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *TenantStatus) GetSrv6Net() string {
+	if x != nil {
+		return x.Srv6Net
+	}
+	return ""
+}
+
+func (x *TenantStatus) GetBroker() string {
+	if x != nil {
+		return x.Broker
+	}
+	return ""
+}
+
+func (x *TenantStatus) GetConnected() bool {
+	if x != nil {
+		return x.Connected
+	}
+	return false
+}
+
+func (x *TenantStatus) GetBrokerError() string {
+	if x != nil {
+		return x.BrokerError
+	}
+	return ""
+}
+
+func (x *TenantStatus) GetAttachments() uint32 {
+	if x != nil {
+		return x.Attachments
+	}
+	return 0
+}
+
+func (x *TenantStatus) GetRoutes() uint32 {
+	if x != nil {
+		return x.Routes
+	}
+	return 0
+}
+
+type VRFStatus struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Vrf           string                 `protobuf:"bytes,1,opt,name=vrf,proto3" json:"vrf,omitempty"`
+	Vpc           string                 `protobuf:"bytes,2,opt,name=vpc,proto3" json:"vpc,omitempty"`
+	Vpcattachment string                 `protobuf:"bytes,3,opt,name=vpcattachment,proto3" json:"vpcattachment,omitempty"`
+	IngressRoutes uint32                 `protobuf:"varint,4,opt,name=ingress_routes,json=ingressRoutes,proto3" json:"ingress_routes,omitempty"`
+	EgressRoutes  uint32                 `protobuf:"varint,5,opt,name=egress_routes,json=egressRoutes,proto3" json:"egress_routes,omitempty"`
+	// neighbors counts the proxy neighbor entries of host routes.
+	Neighbors     uint32 `protobuf:"varint,6,opt,name=neighbors,proto3" json:"neighbors,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *VRFStatus) Reset() {
+	*x = VRFStatus{}
+	mi := &file_local_proto_msgTypes[29]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *VRFStatus) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VRFStatus) ProtoMessage() {}
+
+func (x *VRFStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_local_proto_msgTypes[29]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VRFStatus.ProtoReflect.Descriptor instead.
+func (*VRFStatus) Descriptor() ([]byte, []int) {
+	return file_local_proto_rawDescGZIP(), []int{29}
+}
+
+func (x *VRFStatus) GetVrf() string {
+	if x != nil {
+		return x.Vrf
+	}
+	return ""
+}
+
+func (x *VRFStatus) GetVpc() string {
+	if x != nil {
+		return x.Vpc
+	}
+	return ""
+}
+
+func (x *VRFStatus) GetVpcattachment() string {
+	if x != nil {
+		return x.Vpcattachment
+	}
+	return ""
+}
+
+func (x *VRFStatus) GetIngressRoutes() uint32 {
+	if x != nil {
+		return x.IngressRoutes
+	}
+	return 0
+}
+
+func (x *VRFStatus) GetEgressRoutes() uint32 {
+	if x != nil {
+		return x.EgressRoutes
+	}
+	return 0
+}
+
+func (x *VRFStatus) GetNeighbors() uint32 {
+	if x != nil {
+		return x.Neighbors
+	}
+	return 0
+}
+
+type ErrorStatus struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	TimeUnix int64                  `protobuf:"varint,1,opt,name=time_unix,json=timeUnix,proto3" json:"time_unix,omitempty"`
+	Tenant   string                 `protobuf:"bytes,2,opt,name=tenant,proto3" json:"tenant,omitempty"`
+	// operation is what failed, as in the ERROR events of WatchEvents.
+	Operation     string `protobuf:"bytes,3,opt,name=operation,proto3" json:"operation,omitempty"`
+	Error         string `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ErrorStatus) Reset() {
+	*x = ErrorStatus{}
+	mi := &file_local_proto_msgTypes[30]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ErrorStatus) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ErrorStatus) ProtoMessage() {}
+
+func (x *ErrorStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_local_proto_msgTypes[30]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ErrorStatus.ProtoReflect.Descriptor instead.
+func (*ErrorStatus) Descriptor() ([]byte, []int) {
+	return file_local_proto_rawDescGZIP(), []int{30}
+}
+
+func (x *ErrorStatus) GetTimeUnix() int64 {
+	if x != nil {
+		return x.TimeUnix
+	}
+	return 0
+}
+
+func (x *ErrorStatus) GetTenant() string {
+	if x != nil {
+		return x.Tenant
+	}
+	return ""
+}
+
+func (x *ErrorStatus) GetOperation() string {
+	if x != nil {
+		return x.Operation
+	}
+	return ""
+}
+
+func (x *ErrorStatus) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+var File_local_proto protoreflect.FileDescriptor
+
+const file_local_proto_rawDesc = "" +
+	"\n" +
+	"\vlocal.proto\x12\blocal.v1\"\xcb\x01\n" +
+	"\x0fRegisterRequest\x12\x10\n" +
+	"\x03vpc\x18\x01 \x01(\tR\x03vpc\x12$\n" +
+	"\rvpcattachment\x18\x02 \x01(\tR\rvpcattachment\x12\x1a\n" +
+	"\bnetworks\x18\x03 \x03(\tR\bnetworks\x12\x18\n" +
+	"\aanycast\x18\x04 \x01(\bR\aanycast\x12\x12\n" +
+	"\x04name\x18\x05 \x01(\tR\x04name\x12\x1e\n" +
+	"\n" +
+	"generation\x18\x06 \x01(\x04R\n" +
+	"generation\x12\x16\n" +
+	"\x06base62\x18\a \x01(\bR\x06base62\"-\n" +
+	"\rRegisterReply\x12\x1c\n" +
+	"\tconfirmed\x18\x01 \x01(\bR\tconfirmed\"\x9f\x01\n" +
+	"\x11DeregisterRequest\x12\x10\n" +
+	"\x03vpc\x18\x01 \x01(\tR\x03vpc\x12$\n" +
+	"\rvpcattachment\x18\x02 \x01(\tR\rvpcattachment\x12\x1a\n" +
+	"\bnetworks\x18\x03 \x03(\tR\bnetworks\x12\x1e\n" +
+	"\n" +
+	"generation\x18\x04 \x01(\x04R\n" +
+	"generation\x12\x16\n" +
+	"\x06base62\x18\x05 \x01(\bR\x06base62\"/\n" +
+	"\x0fDeregisterReply\x12\x1c\n" +
+	"\tconfirmed\x18\x01 \x01(\bR\tconfirmed\"G\n" +
+	"\x14RegisterBatchRequest\x12/\n" +
+	"\x05items\x18\x01 \x03(\v2\x19.local.v1.RegisterRequestR\x05items\"K\n" +
+	"\x16DeregisterBatchRequest\x121\n" +
+	"\x05items\x18\x01 \x03(\v2\x1b.local.v1.DeregisterRequestR\x05items\"=\n" +
+	"\n" +
+	"BatchReply\x12/\n" +
+	"\aresults\x18\x01 \x03(\v2\x15.local.v1.BatchResultR\aresults\"7\n" +
+	"\vBatchResult\x12\x12\n" +
+	"\x04code\x18\x01 \x01(\rR\x04code\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\"C\n" +
+	"\x19AllocateAttachmentRequest\x12\x10\n" +
+	"\x03vpc\x18\x01 \x01(\tR\x03vpc\x12\x14\n" +
+	"\x05owner\x18\x02 \x01(\tR\x05owner\"?\n" +
+	"\x17AllocateAttachmentReply\x12$\n" +
+	"\rvpcattachment\x18\x01 \x01(\tR\rvpcattachment\"R\n" +
+	"\x18ReleaseAttachmentRequest\x12\x10\n" +
+	"\x03vpc\x18\x01 \x01(\tR\x03vpc\x12$\n" +
+	"\rvpcattachment\x18\x02 \x01(\tR\rvpcattachment\"6\n" +
+	"\x16ReleaseAttachmentReply\x12\x1c\n" +
+	"\tconfirmed\x18\x01 \x01(\bR\tconfirmed\"\xb4\x03\n" +
+	"\n" +
+	"Attachment\x12\x10\n" +
+	"\x03vpc\x18\x01 \x01(\tR\x03vpc\x12$\n" +
+	"\rvpcattachment\x18\x02 \x01(\tR\rvpcattachment\x12\x10\n" +
+	"\x03vrf\x18\x03 \x01(\tR\x03vrf\x12\x14\n" +
+	"\x05table\x18\x04 \x01(\rR\x05table\x12%\n" +
+	"\x0ehost_interface\x18\x05 \x01(\tR\rhostInterface\x12'\n" +
+	"\x0fguest_interface\x18\x06 \x01(\tR\x0eguestInterface\x12#\n" +
+	"\rsrv6_endpoint\x18\a \x01(\tR\fsrv6Endpoint\x12\x1a\n" +
+	"\bnetworks\x18\b \x03(\tR\bnetworks\x12!\n" +
+	"\fcreated_unix\x18\t \x01(\x03R\vcreatedUnix\x12\x18\n" +
+	"\aanycast\x18\n" +
+	" \x03(\tR\aanycast\x12\x10\n" +
+	"\x03mtu\x18\v \x01(\rR\x03mtu\x12\x16\n" +
+	"\x06routes\x18\f \x01(\rR\x06routes\x12\x1a\n" +
+	"\bdelivery\x18\r \x01(\tR\bdelivery\x12\x12\n" +
+	"\x04name\x18\x0e \x01(\tR\x04name\x12\x1e\n" +
+	"\n" +
+	"generation\x18\x0f \x01(\x04R\n" +
+	"generation\"*\n" +
+	"\x16ListAttachmentsRequest\x12\x10\n" +
+	"\x03vpc\x18\x01 \x01(\tR\x03vpc\"N\n" +
+	"\x14ListAttachmentsReply\x126\n" +
+	"\vattachments\x18\x01 \x03(\v2\x14.local.v1.AttachmentR\vattachments\"N\n" +
+	"\x14GetAttachmentRequest\x12\x10\n" +
+	"\x03vpc\x18\x01 \x01(\tR\x03vpc\x12$\n" +
+	"\rvpcattachment\x18\x02 \x01(\tR\rvpcattachment\"J\n" +
+	"\x10GetRoutesRequest\x12\x10\n" +
+	"\x03vpc\x18\x01 \x01(\tR\x03vpc\x12$\n" +
+	"\rvpcattachment\x18\x02 \x01(\tR\rvpcattachment\"f\n" +
+	"\x0eGetRoutesReply\x121\n" +
+	"\x06routes\x18\x01 \x03(\v2\x19.local.v1.ProgrammedRouteR\x06routes\x12!\n" +
+	"\fkernel_error\x18\x02 \x01(\tR\vkernelError\"\xc2\x02\n" +
+	"\x0fProgrammedRoute\x12\x12\n" +
+	"\x04kind\x18\x01 \x01(\tR\x04kind\x12$\n" +
+	"\rvpcattachment\x18\x02 \x01(\tR\rvpcattachment\x12#\n" +
+	"\rsrv6_endpoint\x18\x03 \x01(\tR\fsrv6Endpoint\x12\x18\n" +
+	"\anetwork\x18\x04 \x01(\tR\anetwork\x12#\n" +
+	"\rsrv6_segments\x18\x05 \x03(\tR\fsrv6Segments\x12\x14\n" +
+	"\x05table\x18\x06 \x01(\rR\x05table\x12\x19\n" +
+	"\bin_state\x18\a \x01(\bR\ainState\x12\x1b\n" +
+	"\tin_kernel\x18\b \x01(\bR\binKernel\x12'\n" +
+	"\x0fkernel_segments\x18\t \x03(\tR\x0ekernelSegments\x12\x1a\n" +
+	"\bmismatch\x18\n" +
+	" \x01(\tR\bmismatch\" \n" +
+	"\fWatchRequest\x12\x10\n" +
+	"\x03vpc\x18\x01 \x01(\tR\x03vpc\"\xa9\x01\n" +
+	"\x0fAttachmentEvent\x122\n" +
+	"\x04type\x18\x01 \x01(\x0e2\x1e.local.v1.AttachmentEvent.TypeR\x04type\x124\n" +
+	"\n" +
+	"attachment\x18\x02 \x01(\v2\x14.local.v1.AttachmentR\n" +
+	"attachment\",\n" +
+	"\x04Type\x12\v\n" +
+	"\aUPDATED\x10\x00\x12\v\n" +
+	"\aREMOVED\x10\x01\x12\n" +
+	"\n" +
+	"\x06SYNCED\x10\x02\"&\n" +
+	"\x12WatchEventsRequest\x12\x10\n" +
+	"\x03vpc\x18\x01 \x01(\tR\x03vpc\"\xe5\x03\n" +
+	"\x05Event\x12(\n" +
+	"\x04type\x18\x01 \x01(\x0e2\x14.local.v1.Event.TypeR\x04type\x12$\n" +
+	"\x0etime_unix_nano\x18\x02 \x01(\x03R\ftimeUnixNano\x12\x16\n" +
+	"\x06tenant\x18\x03 \x01(\tR\x06tenant\x12\x10\n" +
+	"\x03vpc\x18\x04 \x01(\tR\x03vpc\x12$\n" +
+	"\rvpcattachment\x18\x05 \x01(\tR\rvpcattachment\x12\x18\n" +
+	"\anetwork\x18\x06 \x01(\tR\anetwork\x12#\n" +
+	"\rsrv6_endpoint\x18\a \x01(\tR\fsrv6Endpoint\x12#\n" +
+	"\rsrv6_segments\x18\b \x03(\tR\fsrv6Segments\x12\x1c\n" +
+	"\toperation\x18\t \x01(\tR\toperation\x12\x14\n" +
+	"\x05error\x18\n" +
+	" \x01(\tR\x05error\x12\x18\n" +
+	"\adropped\x18\v \x01(\rR\adropped\"\x89\x01\n" +
+	"\x04Type\x12\x0f\n" +
+	"\vUNSPECIFIED\x10\x00\x12\x0e\n" +
+	"\n" +
+	"REGISTERED\x10\x01\x12\x10\n" +
+	"\fDEREGISTERED\x10\x02\x12\x0f\n" +
+	"\vROUTE_ADDED\x10\x03\x12\x11\n" +
+	"\rROUTE_DELETED\x10\x04\x12\x12\n" +
+	"\x0eMQTT_CONNECTED\x10\x05\x12\t\n" +
+	"\x05ERROR\x10\x06\x12\v\n" +
+	"\aDROPPED\x10\a\"\xa2\x01\n" +
+	"\fProbeRequest\x12\x10\n" +
+	"\x03vpc\x18\x01 \x01(\tR\x03vpc\x12$\n" +
+	"\rvpcattachment\x18\x02 \x01(\tR\rvpcattachment\x12 \n" +
+	"\vdestination\x18\x03 \x01(\tR\vdestination\x12\x19\n" +
+	"\bmax_hops\x18\x04 \x01(\rR\amaxHops\x12\x1d\n" +
+	"\n" +
+	"timeout_ms\x18\x05 \x01(\rR\ttimeoutMs\"\x8f\x02\n" +
+	"\n" +
+	"ProbeReply\x12$\n" +
+	"\rvpcattachment\x18\x01 \x01(\tR\rvpcattachment\x12\x10\n" +
+	"\x03vrf\x18\x02 \x01(\tR\x03vrf\x12\x14\n" +
+	"\x05table\x18\x03 \x01(\rR\x05table\x12\x18\n" +
+	"\anetwork\x18\x04 \x01(\tR\anetwork\x12#\n" +
+	"\rsrv6_segments\x18\x05 \x03(\tR\fsrv6Segments\x12\x1c\n" +
+	"\tresponder\x18\x06 \x01(\tR\tresponder\x12\x14\n" +
+	"\x05local\x18\a \x01(\bR\x05local\x12\x18\n" +
+	"\areached\x18\b \x01(\bR\areached\x12&\n" +
+	"\x04hops\x18\t \x03(\v2\x12.local.v1.ProbeHopR\x04hops\"\x86\x02\n" +
+	"\bProbeHop\x12\x1b\n" +
+	"\thop_limit\x18\x01 \x01(\rR\bhopLimit\x12+\n" +
+	"\x04kind\x18\x02 \x01(\x0e2\x17.local.v1.ProbeHop.KindR\x04kind\x12\x18\n" +
+	"\aaddress\x18\x03 \x01(\tR\aaddress\x12\x1b\n" +
+	"\trtt_nanos\x18\x04 \x01(\x03R\brttNanos\x12\x1b\n" +
+	"\ticmp_code\x18\x05 \x01(\rR\bicmpCode\x12\x18\n" +
+	"\asegment\x18\x06 \x01(\bR\asegment\"B\n" +
+	"\x04Kind\x12\v\n" +
+	"\aTIMEOUT\x10\x00\x12\x11\n" +
+	"\rTIME_EXCEEDED\x10\x01\x12\x0f\n" +
+	"\vUNREACHABLE\x10\x02\x12\t\n" +
+	"\x05REPLY\x10\x03\"\x0f\n" +
+	"\rStatusRequest\"\xd8\x03\n" +
+	"\vStatusReply\x12\x18\n" +
+	"\aversion\x18\x01 \x01(\tR\aversion\x12\x16\n" +
+	"\x06commit\x18\x02 \x01(\tR\x06commit\x12\x1d\n" +
+	"\n" +
+	"build_date\x18\x03 \x01(\tR\tbuildDate\x12\x16\n" +
+	"\x06schema\x18\x04 \x01(\tR\x06schema\x12\x1f\n" +
+	"\vconfig_file\x18\x05 \x01(\tR\n" +
+	"configFile\x12\x18\n" +
+	"\aprofile\x18\x06 \x01(\tR\aprofile\x12\x18\n" +
+	"\atenants\x18\a \x03(\tR\atenants\x12\x17\n" +
+	"\adry_run\x18\b \x01(\bR\x06dryRun\x12-\n" +
+	"\x12preflight_failures\x18\t \x03(\tR\x11preflightFailures\x12%\n" +
+	"\x0euptime_seconds\x18\n" +
+	" \x01(\x03R\ruptimeSeconds\x12;\n" +
+	"\rtenant_status\x18\v \x03(\v2\x16.local.v1.TenantStatusR\ftenantStatus\x12'\n" +
+	"\x04vrfs\x18\f \x03(\v2\x13.local.v1.VRFStatusR\x04vrfs\x126\n" +
+	"\vlast_errors\x18\r \x03(\v2\x15.local.v1.ErrorStatusR\n" +
+	"lastErrors\"\xd0\x01\n" +
+	"\fTenantStatus\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x19\n" +
+	"\bsrv6_net\x18\x02 \x01(\tR\asrv6Net\x12\x16\n" +
+	"\x06broker\x18\x03 \x01(\tR\x06broker\x12\x1c\n" +
+	"\tconnected\x18\x04 \x01(\bR\tconnected\x12!\n" +
+	"\fbroker_error\x18\x05 \x01(\tR\vbrokerError\x12 \n" +
+	"\vattachments\x18\x06 \x01(\rR\vattachments\x12\x16\n" +
+	"\x06routes\x18\a \x01(\rR\x06routes\"\xbf\x01\n" +
+	"\tVRFStatus\x12\x10\n" +
+	"\x03vrf\x18\x01 \x01(\tR\x03vrf\x12\x10\n" +
+	"\x03vpc\x18\x02 \x01(\tR\x03vpc\x12$\n" +
+	"\rvpcattachment\x18\x03 \x01(\tR\rvpcattachment\x12%\n" +
+	"\x0eingress_routes\x18\x04 \x01(\rR\ringressRoutes\x12#\n" +
+	"\regress_routes\x18\x05 \x01(\rR\fegressRoutes\x12\x1c\n" +
+	"\tneighbors\x18\x06 \x01(\rR\tneighbors\"v\n" +
+	"\vErrorStatus\x12\x1b\n" +
+	"\ttime_unix\x18\x01 \x01(\x03R\btimeUnix\x12\x16\n" +
+	"\x06tenant\x18\x02 \x01(\tR\x06tenant\x12\x1c\n" +
+	"\toperation\x18\x03 \x01(\tR\toperation\x12\x14\n" +
+	"\x05error\x18\x04 \x01(\tR\x05error2\xa6\a\n" +
 	"\x05Local\x12>\n" +
 	"\bRegister\x12\x19.local.v1.RegisterRequest\x1a\x17.local.v1.RegisterReply\x12D\n" +
 	"\n" +
-	"Deregister\x12\x1b.local.v1.DeregisterRequest\x1a\x19.local.v1.DeregisterReplyB7Z5github.com/datum-cloud/galactic-agent/api/local;localb\x06proto3"
+	"Deregister\x12\x1b.local.v1.DeregisterRequest\x1a\x19.local.v1.DeregisterReply\x12E\n" +
+	"\rRegisterBatch\x12\x1e.local.v1.RegisterBatchRequest\x1a\x14.local.v1.BatchReply\x12I\n" +
+	"\x0fDeregisterBatch\x12 .local.v1.DeregisterBatchRequest\x1a\x14.local.v1.BatchReply\x12\\\n" +
+	"\x12AllocateAttachment\x12#.local.v1.AllocateAttachmentRequest\x1a!.local.v1.AllocateAttachmentReply\x12Y\n" +
+	"\x11ReleaseAttachment\x12\".local.v1.ReleaseAttachmentRequest\x1a .local.v1.ReleaseAttachmentReply\x12S\n" +
+	"\x0fListAttachments\x12 .local.v1.ListAttachmentsRequest\x1a\x1e.local.v1.ListAttachmentsReply\x12E\n" +
+	"\rGetAttachment\x12\x1e.local.v1.GetAttachmentRequest\x1a\x14.local.v1.Attachment\x12A\n" +
+	"\tGetRoutes\x12\x1a.local.v1.GetRoutesRequest\x1a\x18.local.v1.GetRoutesReply\x12<\n" +
+	"\x05Watch\x12\x16.local.v1.WatchRequest\x1a\x19.local.v1.AttachmentEvent0\x01\x12>\n" +
+	"\vWatchEvents\x12\x1c.local.v1.WatchEventsRequest\x1a\x0f.local.v1.Event0\x01\x125\n" +
+	"\x05Probe\x12\x16.local.v1.ProbeRequest\x1a\x14.local.v1.ProbeReply\x128\n" +
+	"\x06Status\x12\x17.local.v1.StatusRequest\x1a\x15.local.v1.StatusReplyB7Z5github.com/datum-cloud/galactic-agent/api/local;localb\x06proto3"
 
 var (
 	file_local_proto_rawDescOnce sync.Once
@@ -263,23 +2602,89 @@ func file_local_proto_rawDescGZIP() []byte {
 	return file_local_proto_rawDescData
 }
 
-var file_local_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_local_proto_enumTypes = make([]protoimpl.EnumInfo, 3)
+var file_local_proto_msgTypes = make([]protoimpl.MessageInfo, 31)
 var file_local_proto_goTypes = []any{
-	(*RegisterRequest)(nil),   // 0: local.v1.RegisterRequest
-	(*RegisterReply)(nil),     // 1: local.v1.RegisterReply
-	(*DeregisterRequest)(nil), // 2: local.v1.DeregisterRequest
-	(*DeregisterReply)(nil),   // 3: local.v1.DeregisterReply
+	(AttachmentEvent_Type)(0),         // 0: local.v1.AttachmentEvent.Type
+	(Event_Type)(0),                   // 1: local.v1.Event.Type
+	(ProbeHop_Kind)(0),                // 2: local.v1.ProbeHop.Kind
+	(*RegisterRequest)(nil),           // 3: local.v1.RegisterRequest
+	(*RegisterReply)(nil),             // 4: local.v1.RegisterReply
+	(*DeregisterRequest)(nil),         // 5: local.v1.DeregisterRequest
+	(*DeregisterReply)(nil),           // 6: local.v1.DeregisterReply
+	(*RegisterBatchRequest)(nil),      // 7: local.v1.RegisterBatchRequest
+	(*DeregisterBatchRequest)(nil),    // 8: local.v1.DeregisterBatchRequest
+	(*BatchReply)(nil),                // 9: local.v1.BatchReply
+	(*BatchResult)(nil),               // 10: local.v1.BatchResult
+	(*AllocateAttachmentRequest)(nil), // 11: local.v1.AllocateAttachmentRequest
+	(*AllocateAttachmentReply)(nil),   // 12: local.v1.AllocateAttachmentReply
+	(*ReleaseAttachmentRequest)(nil),  // 13: local.v1.ReleaseAttachmentRequest
+	(*ReleaseAttachmentReply)(nil),    // 14: local.v1.ReleaseAttachmentReply
+	(*Attachment)(nil),                // 15: local.v1.Attachment
+	(*ListAttachmentsRequest)(nil),    // 16: local.v1.ListAttachmentsRequest
+	(*ListAttachmentsReply)(nil),      // 17: local.v1.ListAttachmentsReply
+	(*GetAttachmentRequest)(nil),      // 18: local.v1.GetAttachmentRequest
+	(*GetRoutesRequest)(nil),          // 19: local.v1.GetRoutesRequest
+	(*GetRoutesReply)(nil),            // 20: local.v1.GetRoutesReply
+	(*ProgrammedRoute)(nil),           // 21: local.v1.ProgrammedRoute
+	(*WatchRequest)(nil),              // 22: local.v1.WatchRequest
+	(*AttachmentEvent)(nil),           // 23: local.v1.AttachmentEvent
+	(*WatchEventsRequest)(nil),        // 24: local.v1.WatchEventsRequest
+	(*Event)(nil),                     // 25: local.v1.Event
+	(*ProbeRequest)(nil),              // 26: local.v1.ProbeRequest
+	(*ProbeReply)(nil),                // 27: local.v1.ProbeReply
+	(*ProbeHop)(nil),                  // 28: local.v1.ProbeHop
+	(*StatusRequest)(nil),             // 29: local.v1.StatusRequest
+	(*StatusReply)(nil),               // 30: local.v1.StatusReply
+	(*TenantStatus)(nil),              // 31: local.v1.TenantStatus
+	(*VRFStatus)(nil),                 // 32: local.v1.VRFStatus
+	(*ErrorStatus)(nil),               // 33: local.v1.ErrorStatus
 }
 var file_local_proto_depIdxs = []int32{
-	0, // 0: local.v1.Local.Register:input_type -> local.v1.RegisterRequest
-	2, // 1: local.v1.Local.Deregister:input_type -> local.v1.DeregisterRequest
-	1, // 2: local.v1.Local.Register:output_type -> local.v1.RegisterReply
-	3, // 3: local.v1.Local.Deregister:output_type -> local.v1.DeregisterReply
-	2, // [2:4] is the sub-list for method output_type
-	0, // [0:2] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	3,  // 0: local.v1.RegisterBatchRequest.items:type_name -> local.v1.RegisterRequest
+	5,  // 1: local.v1.DeregisterBatchRequest.items:type_name -> local.v1.DeregisterRequest
+	10, // 2: local.v1.BatchReply.results:type_name -> local.v1.BatchResult
+	15, // 3: local.v1.ListAttachmentsReply.attachments:type_name -> local.v1.Attachment
+	21, // 4: local.v1.GetRoutesReply.routes:type_name -> local.v1.ProgrammedRoute
+	0,  // 5: local.v1.AttachmentEvent.type:type_name -> local.v1.AttachmentEvent.Type
+	15, // 6: local.v1.AttachmentEvent.attachment:type_name -> local.v1.Attachment
+	1,  // 7: local.v1.Event.type:type_name -> local.v1.Event.Type
+	28, // 8: local.v1.ProbeReply.hops:type_name -> local.v1.ProbeHop
+	2,  // 9: local.v1.ProbeHop.kind:type_name -> local.v1.ProbeHop.Kind
+	31, // 10: local.v1.StatusReply.tenant_status:type_name -> local.v1.TenantStatus
+	32, // 11: local.v1.StatusReply.vrfs:type_name -> local.v1.VRFStatus
+	33, // 12: local.v1.StatusReply.last_errors:type_name -> local.v1.ErrorStatus
+	3,  // 13: local.v1.Local.Register:input_type -> local.v1.RegisterRequest
+	5,  // 14: local.v1.Local.Deregister:input_type -> local.v1.DeregisterRequest
+	7,  // 15: local.v1.Local.RegisterBatch:input_type -> local.v1.RegisterBatchRequest
+	8,  // 16: local.v1.Local.DeregisterBatch:input_type -> local.v1.DeregisterBatchRequest
+	11, // 17: local.v1.Local.AllocateAttachment:input_type -> local.v1.AllocateAttachmentRequest
+	13, // 18: local.v1.Local.ReleaseAttachment:input_type -> local.v1.ReleaseAttachmentRequest
+	16, // 19: local.v1.Local.ListAttachments:input_type -> local.v1.ListAttachmentsRequest
+	18, // 20: local.v1.Local.GetAttachment:input_type -> local.v1.GetAttachmentRequest
+	19, // 21: local.v1.Local.GetRoutes:input_type -> local.v1.GetRoutesRequest
+	22, // 22: local.v1.Local.Watch:input_type -> local.v1.WatchRequest
+	24, // 23: local.v1.Local.WatchEvents:input_type -> local.v1.WatchEventsRequest
+	26, // 24: local.v1.Local.Probe:input_type -> local.v1.ProbeRequest
+	29, // 25: local.v1.Local.Status:input_type -> local.v1.StatusRequest
+	4,  // 26: local.v1.Local.Register:output_type -> local.v1.RegisterReply
+	6,  // 27: local.v1.Local.Deregister:output_type -> local.v1.DeregisterReply
+	9,  // 28: local.v1.Local.RegisterBatch:output_type -> local.v1.BatchReply
+	9,  // 29: local.v1.Local.DeregisterBatch:output_type -> local.v1.BatchReply
+	12, // 30: local.v1.Local.AllocateAttachment:output_type -> local.v1.AllocateAttachmentReply
+	14, // 31: local.v1.Local.ReleaseAttachment:output_type -> local.v1.ReleaseAttachmentReply
+	17, // 32: local.v1.Local.ListAttachments:output_type -> local.v1.ListAttachmentsReply
+	15, // 33: local.v1.Local.GetAttachment:output_type -> local.v1.Attachment
+	20, // 34: local.v1.Local.GetRoutes:output_type -> local.v1.GetRoutesReply
+	23, // 35: local.v1.Local.Watch:output_type -> local.v1.AttachmentEvent
+	25, // 36: local.v1.Local.WatchEvents:output_type -> local.v1.Event
+	27, // 37: local.v1.Local.Probe:output_type -> local.v1.ProbeReply
+	30, // 38: local.v1.Local.Status:output_type -> local.v1.StatusReply
+	26, // [26:39] is the sub-list for method output_type
+	13, // [13:26] is the sub-list for method input_type
+	13, // [13:13] is the sub-list for extension type_name
+	13, // [13:13] is the sub-list for extension extendee
+	0,  // [0:13] is the sub-list for field type_name
 }
 
 func init() { file_local_proto_init() }
@@ -292,13 +2697,14 @@ func file_local_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_local_proto_rawDesc), len(file_local_proto_rawDesc)),
-			NumEnums:      0,
-			NumMessages:   4,
+			NumEnums:      3,
+			NumMessages:   31,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
 		GoTypes:           file_local_proto_goTypes,
 		DependencyIndexes: file_local_proto_depIdxs,
+		EnumInfos:         file_local_proto_enumTypes,
 		MessageInfos:      file_local_proto_msgTypes,
 	}.Build()
 	File_local_proto = out.File
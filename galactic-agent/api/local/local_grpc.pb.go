@@ -19,8 +19,19 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	Local_Register_FullMethodName   = "/local.v1.Local/Register"
-	Local_Deregister_FullMethodName = "/local.v1.Local/Deregister"
+	Local_Register_FullMethodName           = "/local.v1.Local/Register"
+	Local_Deregister_FullMethodName         = "/local.v1.Local/Deregister"
+	Local_RegisterBatch_FullMethodName      = "/local.v1.Local/RegisterBatch"
+	Local_DeregisterBatch_FullMethodName    = "/local.v1.Local/DeregisterBatch"
+	Local_AllocateAttachment_FullMethodName = "/local.v1.Local/AllocateAttachment"
+	Local_ReleaseAttachment_FullMethodName  = "/local.v1.Local/ReleaseAttachment"
+	Local_ListAttachments_FullMethodName    = "/local.v1.Local/ListAttachments"
+	Local_GetAttachment_FullMethodName      = "/local.v1.Local/GetAttachment"
+	Local_GetRoutes_FullMethodName          = "/local.v1.Local/GetRoutes"
+	Local_Watch_FullMethodName              = "/local.v1.Local/Watch"
+	Local_WatchEvents_FullMethodName        = "/local.v1.Local/WatchEvents"
+	Local_Probe_FullMethodName              = "/local.v1.Local/Probe"
+	Local_Status_FullMethodName             = "/local.v1.Local/Status"
 )
 
 // LocalClient is the client API for Local service.
@@ -29,6 +40,41 @@ const (
 type LocalClient interface {
 	Register(ctx context.Context, in *RegisterRequest, opts ...grpc.CallOption) (*RegisterReply, error)
 	Deregister(ctx context.Context, in *DeregisterRequest, opts ...grpc.CallOption) (*DeregisterReply, error)
+	// RegisterBatch and DeregisterBatch handle many items in one call, e.g.
+	// the pods of a node on a CNI plugin's warm start. Each item is handled
+	// as Register or Deregister would handle it, in order, and one failing
+	// does not stop the rest: the reply holds the result of every item.
+	RegisterBatch(ctx context.Context, in *RegisterBatchRequest, opts ...grpc.CallOption) (*BatchReply, error)
+	DeregisterBatch(ctx context.Context, in *DeregisterBatchRequest, opts ...grpc.CallOption) (*BatchReply, error)
+	// AllocateAttachment assigns a free vpc attachment id. Repeating a call
+	// with the same owner returns the id already assigned to it.
+	AllocateAttachment(ctx context.Context, in *AllocateAttachmentRequest, opts ...grpc.CallOption) (*AllocateAttachmentReply, error)
+	ReleaseAttachment(ctx context.Context, in *ReleaseAttachmentRequest, opts ...grpc.CallOption) (*ReleaseAttachmentReply, error)
+	// ListAttachments and GetAttachment read the agent's registry of
+	// registered attachments.
+	ListAttachments(ctx context.Context, in *ListAttachmentsRequest, opts ...grpc.CallOption) (*ListAttachmentsReply, error)
+	GetAttachment(ctx context.Context, in *GetAttachmentRequest, opts ...grpc.CallOption) (*Attachment, error)
+	// GetRoutes lists the ingress and egress routes and proxy neighbors of a
+	// VPC, or of one attachment of it, as the agent's state holds them and as
+	// the kernel does, side by side, to find where the two differ.
+	GetRoutes(ctx context.Context, in *GetRoutesRequest, opts ...grpc.CallOption) (*GetRoutesReply, error)
+	// Watch streams the registry: every attachment once, then an event
+	// whenever one is registered, changes or is removed, including when the
+	// routes installed for it change.
+	Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[AttachmentEvent], error)
+	// WatchEvents streams what the agent does as it happens: registrations,
+	// routes programmed, broker connections and errors. Unlike Watch it
+	// sends no initial state, and a subscriber too slow to keep up misses
+	// events, which a DROPPED event tells it.
+	WatchEvents(ctx context.Context, in *WatchEventsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Event], error)
+	// Probe traces the way packets of an attachment to a destination take
+	// through the SRv6 datapath: the route the attachment's VRF holds for it
+	// and, hop by hop, the path along its segments to the node they lead to.
+	Probe(ctx context.Context, in *ProbeRequest, opts ...grpc.CallOption) (*ProbeReply, error)
+	// Status reports the build of the agent and the configuration it runs
+	// with, and a snapshot of its state: broker connections, registrations,
+	// routes per VRF and the last errors.
+	Status(ctx context.Context, in *StatusRequest, opts ...grpc.CallOption) (*StatusReply, error)
 }
 
 type localClient struct {
@@ -59,12 +105,175 @@ func (c *localClient) Deregister(ctx context.Context, in *DeregisterRequest, opt
 	return out, nil
 }
 
+func (c *localClient) RegisterBatch(ctx context.Context, in *RegisterBatchRequest, opts ...grpc.CallOption) (*BatchReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BatchReply)
+	err := c.cc.Invoke(ctx, Local_RegisterBatch_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *localClient) DeregisterBatch(ctx context.Context, in *DeregisterBatchRequest, opts ...grpc.CallOption) (*BatchReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BatchReply)
+	err := c.cc.Invoke(ctx, Local_DeregisterBatch_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *localClient) AllocateAttachment(ctx context.Context, in *AllocateAttachmentRequest, opts ...grpc.CallOption) (*AllocateAttachmentReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AllocateAttachmentReply)
+	err := c.cc.Invoke(ctx, Local_AllocateAttachment_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *localClient) ReleaseAttachment(ctx context.Context, in *ReleaseAttachmentRequest, opts ...grpc.CallOption) (*ReleaseAttachmentReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ReleaseAttachmentReply)
+	err := c.cc.Invoke(ctx, Local_ReleaseAttachment_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *localClient) ListAttachments(ctx context.Context, in *ListAttachmentsRequest, opts ...grpc.CallOption) (*ListAttachmentsReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListAttachmentsReply)
+	err := c.cc.Invoke(ctx, Local_ListAttachments_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *localClient) GetAttachment(ctx context.Context, in *GetAttachmentRequest, opts ...grpc.CallOption) (*Attachment, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Attachment)
+	err := c.cc.Invoke(ctx, Local_GetAttachment_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *localClient) GetRoutes(ctx context.Context, in *GetRoutesRequest, opts ...grpc.CallOption) (*GetRoutesReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetRoutesReply)
+	err := c.cc.Invoke(ctx, Local_GetRoutes_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *localClient) Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[AttachmentEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Local_ServiceDesc.Streams[0], Local_Watch_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchRequest, AttachmentEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Local_WatchClient = grpc.ServerStreamingClient[AttachmentEvent]
+
+func (c *localClient) WatchEvents(ctx context.Context, in *WatchEventsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Event], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Local_ServiceDesc.Streams[1], Local_WatchEvents_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchEventsRequest, Event]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Local_WatchEventsClient = grpc.ServerStreamingClient[Event]
+
+func (c *localClient) Probe(ctx context.Context, in *ProbeRequest, opts ...grpc.CallOption) (*ProbeReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ProbeReply)
+	err := c.cc.Invoke(ctx, Local_Probe_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *localClient) Status(ctx context.Context, in *StatusRequest, opts ...grpc.CallOption) (*StatusReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(StatusReply)
+	err := c.cc.Invoke(ctx, Local_Status_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // LocalServer is the server API for Local service.
 // All implementations must embed UnimplementedLocalServer
 // for forward compatibility.
 type LocalServer interface {
 	Register(context.Context, *RegisterRequest) (*RegisterReply, error)
 	Deregister(context.Context, *DeregisterRequest) (*DeregisterReply, error)
+	// RegisterBatch and DeregisterBatch handle many items in one call, e.g.
+	// the pods of a node on a CNI plugin's warm start. Each item is handled
+	// as Register or Deregister would handle it, in order, and one failing
+	// does not stop the rest: the reply holds the result of every item.
+	RegisterBatch(context.Context, *RegisterBatchRequest) (*BatchReply, error)
+	DeregisterBatch(context.Context, *DeregisterBatchRequest) (*BatchReply, error)
+	// AllocateAttachment assigns a free vpc attachment id. Repeating a call
+	// with the same owner returns the id already assigned to it.
+	AllocateAttachment(context.Context, *AllocateAttachmentRequest) (*AllocateAttachmentReply, error)
+	ReleaseAttachment(context.Context, *ReleaseAttachmentRequest) (*ReleaseAttachmentReply, error)
+	// ListAttachments and GetAttachment read the agent's registry of
+	// registered attachments.
+	ListAttachments(context.Context, *ListAttachmentsRequest) (*ListAttachmentsReply, error)
+	GetAttachment(context.Context, *GetAttachmentRequest) (*Attachment, error)
+	// GetRoutes lists the ingress and egress routes and proxy neighbors of a
+	// VPC, or of one attachment of it, as the agent's state holds them and as
+	// the kernel does, side by side, to find where the two differ.
+	GetRoutes(context.Context, *GetRoutesRequest) (*GetRoutesReply, error)
+	// Watch streams the registry: every attachment once, then an event
+	// whenever one is registered, changes or is removed, including when the
+	// routes installed for it change.
+	Watch(*WatchRequest, grpc.ServerStreamingServer[AttachmentEvent]) error
+	// WatchEvents streams what the agent does as it happens: registrations,
+	// routes programmed, broker connections and errors. Unlike Watch it
+	// sends no initial state, and a subscriber too slow to keep up misses
+	// events, which a DROPPED event tells it.
+	WatchEvents(*WatchEventsRequest, grpc.ServerStreamingServer[Event]) error
+	// Probe traces the way packets of an attachment to a destination take
+	// through the SRv6 datapath: the route the attachment's VRF holds for it
+	// and, hop by hop, the path along its segments to the node they lead to.
+	Probe(context.Context, *ProbeRequest) (*ProbeReply, error)
+	// Status reports the build of the agent and the configuration it runs
+	// with, and a snapshot of its state: broker connections, registrations,
+	// routes per VRF and the last errors.
+	Status(context.Context, *StatusRequest) (*StatusReply, error)
 	mustEmbedUnimplementedLocalServer()
 }
 
@@ -81,6 +290,39 @@ func (UnimplementedLocalServer) Register(context.Context, *RegisterRequest) (*Re
 func (UnimplementedLocalServer) Deregister(context.Context, *DeregisterRequest) (*DeregisterReply, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Deregister not implemented")
 }
+func (UnimplementedLocalServer) RegisterBatch(context.Context, *RegisterBatchRequest) (*BatchReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RegisterBatch not implemented")
+}
+func (UnimplementedLocalServer) DeregisterBatch(context.Context, *DeregisterBatchRequest) (*BatchReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeregisterBatch not implemented")
+}
+func (UnimplementedLocalServer) AllocateAttachment(context.Context, *AllocateAttachmentRequest) (*AllocateAttachmentReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AllocateAttachment not implemented")
+}
+func (UnimplementedLocalServer) ReleaseAttachment(context.Context, *ReleaseAttachmentRequest) (*ReleaseAttachmentReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReleaseAttachment not implemented")
+}
+func (UnimplementedLocalServer) ListAttachments(context.Context, *ListAttachmentsRequest) (*ListAttachmentsReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListAttachments not implemented")
+}
+func (UnimplementedLocalServer) GetAttachment(context.Context, *GetAttachmentRequest) (*Attachment, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAttachment not implemented")
+}
+func (UnimplementedLocalServer) GetRoutes(context.Context, *GetRoutesRequest) (*GetRoutesReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRoutes not implemented")
+}
+func (UnimplementedLocalServer) Watch(*WatchRequest, grpc.ServerStreamingServer[AttachmentEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method Watch not implemented")
+}
+func (UnimplementedLocalServer) WatchEvents(*WatchEventsRequest, grpc.ServerStreamingServer[Event]) error {
+	return status.Errorf(codes.Unimplemented, "method WatchEvents not implemented")
+}
+func (UnimplementedLocalServer) Probe(context.Context, *ProbeRequest) (*ProbeReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Probe not implemented")
+}
+func (UnimplementedLocalServer) Status(context.Context, *StatusRequest) (*StatusReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Status not implemented")
+}
 func (UnimplementedLocalServer) mustEmbedUnimplementedLocalServer() {}
 func (UnimplementedLocalServer) testEmbeddedByValue()               {}
 
@@ -138,6 +380,190 @@ func _Local_Deregister_Handler(srv interface{}, ctx context.Context, dec func(in
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Local_RegisterBatch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RegisterBatchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LocalServer).RegisterBatch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Local_RegisterBatch_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LocalServer).RegisterBatch(ctx, req.(*RegisterBatchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Local_DeregisterBatch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeregisterBatchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LocalServer).DeregisterBatch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Local_DeregisterBatch_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LocalServer).DeregisterBatch(ctx, req.(*DeregisterBatchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Local_AllocateAttachment_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AllocateAttachmentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LocalServer).AllocateAttachment(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Local_AllocateAttachment_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LocalServer).AllocateAttachment(ctx, req.(*AllocateAttachmentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Local_ReleaseAttachment_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReleaseAttachmentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LocalServer).ReleaseAttachment(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Local_ReleaseAttachment_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LocalServer).ReleaseAttachment(ctx, req.(*ReleaseAttachmentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Local_ListAttachments_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListAttachmentsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LocalServer).ListAttachments(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Local_ListAttachments_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LocalServer).ListAttachments(ctx, req.(*ListAttachmentsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Local_GetAttachment_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAttachmentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LocalServer).GetAttachment(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Local_GetAttachment_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LocalServer).GetAttachment(ctx, req.(*GetAttachmentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Local_GetRoutes_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRoutesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LocalServer).GetRoutes(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Local_GetRoutes_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LocalServer).GetRoutes(ctx, req.(*GetRoutesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Local_Watch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(LocalServer).Watch(m, &grpc.GenericServerStream[WatchRequest, AttachmentEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Local_WatchServer = grpc.ServerStreamingServer[AttachmentEvent]
+
+func _Local_WatchEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchEventsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(LocalServer).WatchEvents(m, &grpc.GenericServerStream[WatchEventsRequest, Event]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Local_WatchEventsServer = grpc.ServerStreamingServer[Event]
+
+func _Local_Probe_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ProbeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LocalServer).Probe(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Local_Probe_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LocalServer).Probe(ctx, req.(*ProbeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Local_Status_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LocalServer).Status(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Local_Status_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LocalServer).Status(ctx, req.(*StatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Local_ServiceDesc is the grpc.ServiceDesc for Local service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -153,7 +579,54 @@ var Local_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Deregister",
 			Handler:    _Local_Deregister_Handler,
 		},
+		{
+			MethodName: "RegisterBatch",
+			Handler:    _Local_RegisterBatch_Handler,
+		},
+		{
+			MethodName: "DeregisterBatch",
+			Handler:    _Local_DeregisterBatch_Handler,
+		},
+		{
+			MethodName: "AllocateAttachment",
+			Handler:    _Local_AllocateAttachment_Handler,
+		},
+		{
+			MethodName: "ReleaseAttachment",
+			Handler:    _Local_ReleaseAttachment_Handler,
+		},
+		{
+			MethodName: "ListAttachments",
+			Handler:    _Local_ListAttachments_Handler,
+		},
+		{
+			MethodName: "GetAttachment",
+			Handler:    _Local_GetAttachment_Handler,
+		},
+		{
+			MethodName: "GetRoutes",
+			Handler:    _Local_GetRoutes_Handler,
+		},
+		{
+			MethodName: "Probe",
+			Handler:    _Local_Probe_Handler,
+		},
+		{
+			MethodName: "Status",
+			Handler:    _Local_Status_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Watch",
+			Handler:       _Local_Watch_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "WatchEvents",
+			Handler:       _Local_WatchEvents_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "local.proto",
 }
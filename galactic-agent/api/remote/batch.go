@@ -0,0 +1,204 @@
+package remote
+
+import (
+	"errors"
+	"fmt"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	envelopeRoute      = 3
+	envelopeRouteBatch = 8
+	envelopeSignature  = 15
+
+	batchID     = 1
+	batchResync = 2
+	batchRoutes = 3
+)
+
+var errMalformed = errors.New("malformed envelope")
+
+// DecodeEnvelope decodes payload like proto.Unmarshal, except for the routes
+// of a RouteBatch: the envelope carries the batch without them, and the
+// returned reader decodes them on demand, so that a batch of any size
+// costs a chunk of routes in memory beyond the payload. The reader is nil
+// for other kinds.
+func DecodeEnvelope(payload []byte) (*Envelope, *BatchReader, error) {
+	envelope := &Envelope{}
+	batch, err := DecodeEnvelopeInto(envelope, payload)
+	if err != nil {
+		return nil, nil, err
+	}
+	return envelope, batch, nil
+}
+
+// DecodeEnvelopeInto is DecodeEnvelope decoding into envelope, which is
+// reset first. The Route of a Route envelope is decoded into the Route
+// envelope held, if any, so that a receiver recycling its envelopes
+// allocates no messages for the common kind.
+func DecodeEnvelopeInto(envelope *Envelope, payload []byte) (*BatchReader, error) {
+	reuse, _ := envelope.Kind.(*Envelope_Route)
+	proto.Reset(envelope)
+	if reuse != nil && reuse.Route != nil {
+		proto.Reset(reuse.Route)
+		envelope.Kind = reuse
+	} else {
+		reuse = nil
+	}
+	sawRoute := false
+	var batch *BatchReader
+	// the fields before and after a batch are decoded in one go each
+	merge := func(fields []byte) error {
+		if len(fields) == 0 {
+			return nil
+		}
+		return (proto.UnmarshalOptions{Merge: true}).Unmarshal(fields, envelope)
+	}
+	start := 0 // of the fields not yet decoded
+	for off := 0; off < len(payload); {
+		num, typ, n := protowire.ConsumeTag(payload[off:])
+		if n < 0 {
+			return nil, errMalformed
+		}
+		m := protowire.ConsumeFieldValue(num, typ, payload[off+n:])
+		if m < 0 {
+			return nil, errMalformed
+		}
+		field := payload[off : off+n+m]
+		off += n + m
+		if num == envelopeRoute {
+			sawRoute = true
+		}
+		if num != envelopeRouteBatch || typ != protowire.BytesType {
+			continue
+		}
+		if err := merge(payload[start : off-n-m]); err != nil {
+			return nil, err
+		}
+		start = off
+		value, _ := protowire.ConsumeBytes(field[n:])
+		r, err := readBatch(value)
+		if err != nil {
+			return nil, err
+		}
+		batch = r
+		envelope.Kind = &Envelope_RouteBatch{RouteBatch: &RouteBatch{Id: r.ID, Resync: r.Resync}}
+	}
+	if err := merge(payload[start:]); err != nil {
+		return nil, err
+	}
+	// the reused route stands for nothing unless the payload had one
+	if k, ok := envelope.Kind.(*Envelope_Route); ok && k == reuse && !sawRoute {
+		envelope.Kind = nil
+	}
+	if _, ok := envelope.Kind.(*Envelope_RouteBatch); !ok {
+		batch = nil
+	}
+	return batch, nil
+}
+
+// BatchReader decodes the routes of a RouteBatch.
+type BatchReader struct {
+	ID     string
+	Resync bool
+	b      []byte
+}
+
+func readBatch(b []byte) (*BatchReader, error) {
+	r := &BatchReader{b: b}
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return nil, fmt.Errorf("route batch: %w", errMalformed)
+		}
+		b = b[n:]
+		switch {
+		case num == batchID && typ == protowire.BytesType:
+			v, m := protowire.ConsumeString(b)
+			if m < 0 {
+				return nil, fmt.Errorf("route batch id: %w", errMalformed)
+			}
+			r.ID = v
+			n = m
+		case num == batchResync && typ == protowire.VarintType:
+			v, m := protowire.ConsumeVarint(b)
+			if m < 0 {
+				return nil, fmt.Errorf("route batch resync: %w", errMalformed)
+			}
+			r.Resync = protowire.DecodeBool(v)
+			n = m
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+			if n < 0 {
+				return nil, fmt.Errorf("route batch: %w", errMalformed)
+			}
+		}
+		b = b[n:]
+	}
+	return r, nil
+}
+
+// Next decodes up to len(routes) more routes into routes, which must be
+// non-nil and are reset first, and returns how many it decoded: 0 once all
+// were.
+func (r *BatchReader) Next(routes []*Route) (int, error) {
+	i := 0
+	for i < len(routes) && len(r.b) > 0 {
+		num, typ, n := protowire.ConsumeTag(r.b)
+		if n < 0 {
+			return i, fmt.Errorf("route batch: %w", errMalformed)
+		}
+		if num != batchRoutes || typ != protowire.BytesType {
+			m := protowire.ConsumeFieldValue(num, typ, r.b[n:])
+			if m < 0 {
+				return i, fmt.Errorf("route batch: %w", errMalformed)
+			}
+			r.b = r.b[n+m:]
+			continue
+		}
+		v, m := protowire.ConsumeBytes(r.b[n:])
+		if m < 0 {
+			return i, fmt.Errorf("route batch: %w", errMalformed)
+		}
+		r.b = r.b[n+m:]
+		if err := proto.Unmarshal(v, routes[i]); err != nil {
+			return i, fmt.Errorf("route batch: %w", err)
+		}
+		i++
+	}
+	return i, nil
+}
+
+// wireUnsigned is payload without its signature: what the signature of a
+// canonically encoded envelope covers. It copies payload only when the
+// signature is not its last field.
+func wireUnsigned(payload []byte) ([]byte, error) {
+	var out []byte
+	copied := false
+	start := 0 // of the part of payload not yet in out
+	for off := 0; off < len(payload); {
+		num, typ, n := protowire.ConsumeTag(payload[off:])
+		if n < 0 {
+			return nil, errMalformed
+		}
+		m := protowire.ConsumeFieldValue(num, typ, payload[off+n:])
+		if m < 0 {
+			return nil, errMalformed
+		}
+		if num == envelopeSignature {
+			if off+n+m == len(payload) && !copied {
+				return payload[:off], nil
+			}
+			out = append(out, payload[start:off]...)
+			copied = true
+			start = off + n + m
+		}
+		off += n + m
+	}
+	if !copied {
+		return payload, nil
+	}
+	return append(out, payload[start:]...), nil
+}
@@ -0,0 +1,288 @@
+// Package contract pins the wire format agents and controllers exchange.
+// The fixtures hold the canonical encoding of every envelope kind as it was
+// when the kind was introduced; a proto change that alters any of them breaks
+// mixed-version fleets and must be made backward compatible instead.
+package contract
+
+import (
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/datum-cloud/galactic-agent/api/remote"
+)
+
+type Role string
+
+const (
+	Agent      Role = "agent"
+	Controller Role = "controller"
+)
+
+// Fixture is an envelope together with its canonical encoding. Sender is the
+// role that produces it; the other role must be able to consume it.
+type Fixture struct {
+	Name     string
+	Sender   Role
+	Envelope *remote.Envelope
+	Wire     []byte
+}
+
+// Fixtures covers every envelope kind the current schema defines.
+var Fixtures = []Fixture{
+	{
+		Name:   "register",
+		Sender: Agent,
+		Envelope: &remote.Envelope{Kind: &remote.Envelope_Register{Register: &remote.Register{
+			Network:      "10.1.0.0/24",
+			Srv6Endpoint: "fc00::1:1",
+		}}},
+		Wire: wire("0a180a0b31302e312e302e302f32341209666330303a3a313a31"),
+	},
+	{
+		Name:   "deregister",
+		Sender: Agent,
+		Envelope: &remote.Envelope{Kind: &remote.Envelope_Deregister{Deregister: &remote.Deregister{
+			Network:      "10.1.0.0/24",
+			Srv6Endpoint: "fc00::1:1",
+		}}},
+		Wire: wire("12180a0b31302e312e302e302f32341209666330303a3a313a31"),
+	},
+	{
+		Name:   "route-add",
+		Sender: Controller,
+		Envelope: &remote.Envelope{Kind: &remote.Envelope_Route{Route: &remote.Route{
+			Status:       remote.Route_ADD,
+			Network:      "10.2.0.0/24",
+			Srv6Endpoint: "fc00::1:1",
+			Srv6Segments: []string{"fc00::2:1"},
+		}}},
+		Wire: wire("1a230a0b31302e322e302e302f32341209666330303a3a313a311a09666330303a3a323a31"),
+	},
+	{
+		Name:   "route-delete",
+		Sender: Controller,
+		Envelope: &remote.Envelope{Kind: &remote.Envelope_Route{Route: &remote.Route{
+			Status:       remote.Route_DELETE,
+			Network:      "10.2.0.0/24",
+			Srv6Endpoint: "fc00::1:1",
+			Srv6Segments: []string{"fc00::2:1", "fc00::3:1"},
+		}}},
+		Wire: wire("1a300a0b31302e322e302e302f32341209666330303a3a313a311a09666330303a3a323a311a09666330303a3a333a312001"),
+	},
+	{
+		Name:   "nack",
+		Sender: Agent,
+		Envelope: &remote.Envelope{Kind: &remote.Envelope_Nack{Nack: &remote.Nack{
+			Reason: remote.Nack_RATE_LIMITED,
+			Detail: "rate limit exceeded",
+			Route: &remote.Route{
+				Network:      "10.2.0.0/24",
+				Srv6Endpoint: "fc00::1:1",
+				Srv6Segments: []string{"fc00::2:1"},
+			},
+		}}},
+		Wire: wire("223c0801121372617465206c696d69742065786365656465641a230a0b31302e322e302e302f32341209666330303a3a313a311a09666330303a3a323a31"),
+	},
+	{
+		Name:   "credential-rotate",
+		Sender: Controller,
+		Envelope: &remote.Envelope{Kind: &remote.Envelope_CredentialRotate{CredentialRotate: &remote.CredentialRotate{
+			Sealed: []byte{0x01, 0x02, 0x03},
+		}}},
+		Wire: wire("2a050a03010203"),
+	},
+	{
+		Name:   "set-mtu",
+		Sender: Controller,
+		Envelope: &remote.Envelope{Kind: &remote.Envelope_SetMtu{SetMtu: &remote.SetMTU{
+			Srv6Endpoint: "fc00::1:1",
+			Mtu:          1400,
+		}}},
+		Wire: wire("320e0a09666330303a3a313a3110f80a"),
+	},
+	{
+		Name:   "heartbeat",
+		Sender: Agent,
+		Envelope: &remote.Envelope{Kind: &remote.Envelope_Heartbeat{Heartbeat: &remote.Heartbeat{
+			Usage: []*remote.VPCUsage{{
+				Vpc:                    "0000000000ab",
+				SentBytes:              1500,
+				SentPackets:            1,
+				ReceivedBytes:          3000,
+				ReceivedPackets:        2,
+				SentBytesPerSecond:     50,
+				ReceivedBytesPerSecond: 100,
+			}},
+		}}},
+		Wire: wire("3a2c0a2a0a0c30303030303030303030616210dc0b180120b8172802310000000000004940390000000000005940"),
+	},
+	{
+		Name:   "route-batch",
+		Sender: Controller,
+		Envelope: &remote.Envelope{Kind: &remote.Envelope_RouteBatch{RouteBatch: &remote.RouteBatch{
+			Id:     "b1",
+			Resync: true,
+			Routes: []*remote.Route{{
+				Status:       remote.Route_ADD,
+				Network:      "10.2.0.0/24",
+				Srv6Endpoint: "fc00::1:1",
+				Srv6Segments: []string{"fc00::2:1"},
+			}},
+		}}},
+		Wire: wire("422b0a02623110011a230a0b31302e322e302e302f32341209666330303a3a313a311a09666330303a3a323a31"),
+	},
+	{
+		Name:   "batch-progress",
+		Sender: Agent,
+		Envelope: &remote.Envelope{Kind: &remote.Envelope_BatchProgress{BatchProgress: &remote.BatchProgress{
+			Id:      "b1",
+			Applied: 1,
+			Done:    true,
+		}}},
+		Wire: wire("4a080a02623110012801"),
+	},
+	{
+		Name:   "route-changes",
+		Sender: Agent,
+		Envelope: &remote.Envelope{Kind: &remote.Envelope_RouteChanges{RouteChanges: &remote.RouteChanges{
+			Changes: []*remote.RouteChange{{
+				Op:               remote.RouteChange_DELETE,
+				Srv6Endpoint:     "fc00::1:1",
+				Table:            100,
+				Network:          "10.2.0.0/24",
+				Srv6Segments:     []string{"fc00::2:1"},
+				Protocol:         3,
+				Desired:          true,
+				ObservedUnixNano: 1700000000000000000,
+			}},
+		}}},
+		Wire: wire("52370a3508011209666330303a3a313a311864220b31302e322e302e302f32342a09666330303a3a323a3140034801508080a8b1e39fe7cb17"),
+	},
+	{
+		Name:   "agent-hello",
+		Sender: Agent,
+		Envelope: &remote.Envelope{Kind: &remote.Envelope_AgentHello{AgentHello: &remote.AgentHello{
+			AgentId:  "a1",
+			Hostname: "node1",
+			Srv6Net:  "fc00::/56",
+			Features: []string{"route-batch"},
+			Schema:   "0123456789ab",
+			Version:  "v1.0.0",
+		}}},
+		Wire: wire("5a390a02613112056e6f6465311a09666330303a3a2f3536220b726f7574652d62617463682a0c303132333435363738396162320676312e302e30"),
+	},
+}
+
+// Future is an envelope from a newer schema. Receivers must decode it without
+// error and report the listed skew instead of rejecting it.
+type Future struct {
+	Name     string
+	Receiver Role
+	Wire     []byte
+	Skew     []string
+}
+
+var Futures = []Future{
+	{
+		Name:     "register-unknown-field",
+		Receiver: Controller,
+		Wire:     wire("0a1b0a0b31302e312e302e302f32341209666330303a3a313a317a0178"),
+		Skew:     []string{remote.SkewUnknownField},
+	},
+	{
+		Name:     "unknown-kind",
+		Receiver: Agent,
+		Wire:     wire("720d0a0b31302e312e302e302f3234"),
+		Skew:     []string{remote.SkewUnknownField, remote.SkewUnknownKind},
+	},
+	{
+		Name:     "route-unknown-status",
+		Receiver: Agent,
+		Wire:     wire("1a250a0b31302e322e302e302f32341209666330303a3a313a311a09666330303a3a323a312007"),
+		Skew:     []string{remote.SkewUnknownEnumValue},
+	},
+}
+
+func wire(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// Test verifies the compiled schema against the fixtures from the point of
+// view of role, in a subtest per fixture: what role sends must encode to the
+// canonical bytes, and what it receives must decode from them. An empty role
+// checks both sides. Agent and controller builds both call it from their
+// tests, so a breaking proto change fails CI on either side.
+func Test(t *testing.T, role Role) {
+	for _, f := range Fixtures {
+		t.Run(f.Name, func(t *testing.T) {
+			if role == "" || f.Sender == role {
+				if err := checkEncode(f); err != nil {
+					t.Error(err)
+				}
+			}
+			if role == "" || f.Sender != role {
+				if err := checkDecode(f); err != nil {
+					t.Error(err)
+				}
+			}
+		})
+	}
+	for _, f := range Futures {
+		if role != "" && f.Receiver != role {
+			continue
+		}
+		t.Run(f.Name, func(t *testing.T) {
+			if err := checkFuture(f); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func checkEncode(f Fixture) error {
+	b, err := proto.MarshalOptions{Deterministic: true}.Marshal(f.Envelope)
+	if err != nil {
+		return fmt.Errorf("encode: %w", err)
+	}
+	if !slices.Equal(b, f.Wire) {
+		return fmt.Errorf("encode: got %x, want %x", b, f.Wire)
+	}
+	return nil
+}
+
+func checkDecode(f Fixture) error {
+	envelope := &remote.Envelope{}
+	if err := proto.Unmarshal(f.Wire, envelope); err != nil {
+		return fmt.Errorf("decode: %w", err)
+	}
+	if !proto.Equal(envelope, f.Envelope) {
+		return fmt.Errorf("decode: got %v, want %v", envelope, f.Envelope)
+	}
+	if skews := remote.CheckSkew(envelope); len(skews) > 0 {
+		return fmt.Errorf("decode: unexpected skew %v", skews)
+	}
+	return nil
+}
+
+func checkFuture(f Future) error {
+	envelope := &remote.Envelope{}
+	if err := proto.Unmarshal(f.Wire, envelope); err != nil {
+		return fmt.Errorf("decode: %w", err)
+	}
+	var reasons []string
+	for _, s := range remote.CheckSkew(envelope) {
+		reasons = append(reasons, s.Reason)
+	}
+	if !slices.Equal(reasons, f.Skew) {
+		return fmt.Errorf("skew: got %v, want %v", reasons, f.Skew)
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package contract
+
+import (
+	"testing"
+
+	"github.com/datum-cloud/galactic-agent/api/remote"
+)
+
+func TestAgent(t *testing.T) {
+	Test(t, Agent)
+}
+
+func TestController(t *testing.T) {
+	Test(t, Controller)
+}
+
+// TestCoverage fails when an envelope kind is added without a fixture.
+func TestCoverage(t *testing.T) {
+	covered := make(map[string]bool)
+	for _, f := range Fixtures {
+		covered[string(f.Envelope.ProtoReflect().WhichOneof(kinds).Name())] = true
+	}
+	for i := 0; i < kinds.Fields().Len(); i++ {
+		if name := string(kinds.Fields().Get(i).Name()); !covered[name] {
+			t.Errorf("no fixture for envelope kind %s", name)
+		}
+	}
+}
+
+var kinds = (&remote.Envelope{}).ProtoReflect().Descriptor().Oneofs().ByName("kind")
@@ -0,0 +1,66 @@
+package remote
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// CredentialKeySize is the length of the key CredentialRotate is sealed
+// with.
+const CredentialKeySize = 32
+
+// credentialAD binds sealed credentials to their purpose.
+var credentialAD = []byte("galactic credential rotate")
+
+func credentialAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != CredentialKeySize {
+		return nil, fmt.Errorf("credential key must be %d bytes, got %d", CredentialKeySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// SealCredentials encrypts creds for a CredentialRotate envelope.
+func SealCredentials(key []byte, creds *Credentials) ([]byte, error) {
+	gcm, err := credentialAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	b, err := proto.Marshal(creds)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, b, credentialAD), nil
+}
+
+// OpenCredentials decrypts what SealCredentials sealed.
+func OpenCredentials(key, sealed []byte) (*Credentials, error) {
+	gcm, err := credentialAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, errors.New("sealed credentials are truncated")
+	}
+	b, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], credentialAD)
+	if err != nil {
+		return nil, fmt.Errorf("sealed credentials: %w", err)
+	}
+	creds := &Credentials{}
+	if err := proto.Unmarshal(b, creds); err != nil {
+		return nil, err
+	}
+	return creds, nil
+}
@@ -0,0 +1,158 @@
+package remote
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// KeyIDHeader is the PEM header naming the key id of a trust bundle entry.
+// Entries without it are known by the KeyID of their key.
+const KeyIDHeader = "Key-Id"
+
+// KeyID derives a key id from the SHA-256 of the DER public key.
+func KeyID(pub crypto.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:8]), nil
+}
+
+// LoadSigningKey reads a PEM private key in PKCS #8, SEC 1 or PKCS #1 form.
+func LoadSigningKey(path string) (crypto.Signer, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM data", path)
+	}
+	var key any
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%s: unexpected PEM block %q", path, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("%s: unsupported key %T", path, key)
+	}
+	return signer, nil
+}
+
+// Keys is a trust bundle of public keys by key id.
+type Keys map[string]crypto.PublicKey
+
+// LoadKeys reads a trust bundle of PEM PUBLIC KEY and CERTIFICATE blocks.
+func LoadKeys(path string) (Keys, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	keys := make(Keys)
+	for {
+		var block *pem.Block
+		block, b = pem.Decode(b)
+		if block == nil {
+			break
+		}
+		var pub crypto.PublicKey
+		switch block.Type {
+		case "PUBLIC KEY":
+			pub, err = x509.ParsePKIXPublicKey(block.Bytes)
+		case "CERTIFICATE":
+			var cert *x509.Certificate
+			if cert, err = x509.ParseCertificate(block.Bytes); err == nil {
+				pub = cert.PublicKey
+			}
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		id := block.Headers[KeyIDHeader]
+		if id == "" {
+			if id, err = KeyID(pub); err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+		}
+		keys[id] = pub
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%s: no keys", path)
+	}
+	return keys, nil
+}
+
+// SignWithKey signs envelope with a bare key the receiver knows as keyID.
+func SignWithKey(envelope *Envelope, keyID string, key crypto.Signer) error {
+	value, err := sign(envelope, key)
+	if err != nil {
+		return err
+	}
+	envelope.Signature = &Signature{KeyId: keyID, Value: value}
+	return nil
+}
+
+// VerifyKey checks that envelope is signed by one of keys and returns the
+// key id.
+func (k Keys) VerifyKey(envelope *Envelope) (string, error) {
+	pub, err := k.signer(envelope)
+	if err != nil {
+		return "", err
+	}
+	if err := verify(envelope, pub, envelope.Signature.Value); err != nil {
+		return "", err
+	}
+	return envelope.Signature.KeyId, nil
+}
+
+// VerifyKeyWire is VerifyKey for an envelope decoded from payload by
+// DecodeEnvelope: the signature is checked against payload itself, which
+// must be canonically encoded, rather than a re-encoding of envelope.
+func (k Keys) VerifyKeyWire(envelope *Envelope, payload []byte) (string, error) {
+	pub, err := k.signer(envelope)
+	if err != nil {
+		return "", err
+	}
+	b, err := wireUnsigned(payload)
+	if err != nil {
+		return "", err
+	}
+	if err := verifyBytes(b, pub, envelope.Signature.Value); err != nil {
+		return "", err
+	}
+	return envelope.Signature.KeyId, nil
+}
+
+func (k Keys) signer(envelope *Envelope) (crypto.PublicKey, error) {
+	sig := envelope.GetSignature()
+	if sig == nil {
+		return nil, ErrUnsigned
+	}
+	if sig.KeyId == "" {
+		return nil, errors.New("signature has no key id")
+	}
+	pub, ok := k[sig.KeyId]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", sig.KeyId)
+	}
+	return pub, nil
+}
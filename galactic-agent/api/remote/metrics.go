@@ -0,0 +1,41 @@
+package remote
+
+import "github.com/datum-cloud/galactic-agent/metrics"
+
+var (
+	mqttConnected = metrics.NewGauge(
+		"galactic_agent_mqtt_connected",
+		"Whether the connection to each broker is up and subscribed.",
+		"broker",
+	)
+	mqttConnects = metrics.NewCounter(
+		"galactic_agent_mqtt_connects_total",
+		"Connections to each broker that came up and subscribed, the first one and every reconnect.",
+		"broker",
+	)
+	mqttConnectionsLost = metrics.NewCounter(
+		"galactic_agent_mqtt_connections_lost_total",
+		"Connections to each broker that dropped.",
+		"broker",
+	)
+	mqttReconnects = metrics.NewCounter(
+		"galactic_agent_mqtt_reconnect_attempts_total",
+		"Attempts to connect to each broker again after the connection dropped or was reset.",
+		"broker",
+	)
+	mqttDisconnected = metrics.NewCounter(
+		"galactic_agent_mqtt_disconnected_seconds_total",
+		"Time the connection to each broker spent down, counted when it comes back up.",
+		"broker",
+	)
+	mqttSubscribeFailures = metrics.NewCounter(
+		"galactic_agent_mqtt_subscribe_failures_total",
+		"Subscriptions to the receive topic of each broker that failed or timed out.",
+		"broker",
+	)
+	mqttPublishFailures = metrics.NewCounter(
+		"galactic_agent_mqtt_publish_failures_total",
+		"Envelopes each broker did not acknowledge.",
+		"broker",
+	)
+)
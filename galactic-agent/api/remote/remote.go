@@ -2,10 +2,15 @@ package remote
 
 import (
 	"context"
-	"log"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
 	"time"
 
 	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"google.golang.org/protobuf/proto"
 )
 
 type Remote struct {
@@ -18,59 +23,502 @@ type Remote struct {
 	TopicTX        string
 	ReceiveHandler func([]byte) error
 
-	client mqtt.Client
+	// TopicTelemetry is where SendTelemetry publishes; TopicTX if unset.
+	TopicTelemetry string
+
+	// ReconnectInterval caps the backoff between reconnect attempts; zero
+	// keeps the paho default of ten minutes.
+	ReconnectInterval time.Duration
+
+	// TLSConfig, if set, is used for ssl:// and tls:// broker URLs.
+	TLSConfig *tls.Config
+	// Signer, if set, signs every envelope before it is sent.
+	Signer func(*Envelope) error
+
+	// OnConnect, if set, is called on its own goroutine each time the
+	// connection comes up, once envelopes can be sent.
+	OnConnect func()
+	// OnConnectionLost, if set, is called on its own goroutine each time
+	// the connection drops, with why, and OnReconnecting before each
+	// attempt to bring it back.
+	OnConnectionLost func(err error)
+	OnReconnecting   func()
+
+	// DrainTimeout is how long Run waits, once ctx is done, for queued and
+	// unacknowledged envelopes to be acknowledged before it disconnects.
+	DrainTimeout time.Duration
+
+	mu        sync.Mutex
+	client    mqtt.Client
+	connected bool
+	downSince time.Time // when the connection went down, if it is
+	lastErr   error     // why it last went down or failed to come up
+	queue     []*outgoing
+	flushing  bool // setConnected is publishing the queue
+	inflight  int  // published, not acknowledged yet
+	handling  sync.WaitGroup
+	reconnect chan struct{}
+	rotate    chan rotation
 }
 
-func (r *Remote) Run(ctx context.Context) error {
-	log.Printf("MQTT connecting")
+// outgoing is an envelope waiting for a connection to be published on.
+type outgoing struct {
+	topic   string
+	payload []byte
+	done    chan error
+}
+
+// Settings are the broker and topics of a Remote, which Reconfigure may
+// change while it runs.
+type Settings struct {
+	URL            string
+	ClientID       string
+	Username       string
+	Password       string
+	QoS            byte
+	TopicRX        string
+	TopicTX        string
+	TopicTelemetry string
+
+	ReconnectInterval time.Duration
+}
+
+// rotation asks Run to switch to new settings or credentials, what.
+type rotation struct {
+	what      string
+	settings  Settings
+	tlsConfig *tls.Config
+	done      chan error
+}
+
+// settings returns the settings in use; the caller holds r.mu.
+func (r *Remote) settings() Settings {
+	return Settings{
+		URL:            r.URL,
+		ClientID:       r.ClientID,
+		Username:       r.Username,
+		Password:       r.Password,
+		QoS:            r.QoS,
+		TopicRX:        r.TopicRX,
+		TopicTX:        r.TopicTX,
+		TopicTelemetry: r.TopicTelemetry,
 
+		ReconnectInterval: r.ReconnectInterval,
+	}
+}
+
+// newClient builds a paho client for the given settings.
+func (r *Remote) newClient(s Settings, tlsConfig *tls.Config) mqtt.Client {
 	opts := mqtt.NewClientOptions().
-		AddBroker(r.URL)
-	if r.ClientID != "" {
-		opts.SetClientID(r.ClientID)
+		AddBroker(s.URL)
+	if s.ClientID != "" {
+		opts.SetClientID(s.ClientID)
 	}
-	if r.Username != "" {
-		opts.SetUsername(r.Username)
+	if s.Username != "" {
+		opts.SetUsername(s.Username)
 	}
-	if r.Password != "" {
-		opts.SetPassword(r.Password)
+	if s.Password != "" {
+		opts.SetPassword(s.Password)
+	}
+	opts.SetCleanSession(s.ClientID == "" || s.QoS == 0)
+	if s.ReconnectInterval > 0 {
+		opts.SetMaxReconnectInterval(s.ReconnectInterval)
+	}
+	if tlsConfig != nil {
+		opts.SetTLSConfig(tlsConfig)
 	}
-	opts.SetCleanSession(r.ClientID == "" || r.QoS == 0)
 
 	opts.OnConnect = func(c mqtt.Client) {
-		log.Println("MQTT connected")
+		slog.Info("MQTT connected", "broker", s.URL)
 		token := c.Subscribe(
-			r.TopicRX,
-			r.QoS,
+			s.TopicRX,
+			s.QoS,
 			func(_ mqtt.Client, msg mqtt.Message) {
+				r.handling.Add(1)
+				defer r.handling.Done()
 				payload := msg.Payload()
 				if err := r.ReceiveHandler(payload); err != nil {
-					log.Printf("MQTT ReceiveHandler failed: %v", err)
+					slog.Error("MQTT ReceiveHandler failed", "broker", s.URL, "topic", msg.Topic(), "err", err)
 				}
 			},
 		)
 		if !token.WaitTimeout(5*time.Second) || token.Error() != nil {
-			log.Printf("MQTT subscribe error: %v", token.Error())
+			err := token.Error()
+			if err == nil {
+				err = errors.New("subscribe timed out")
+			}
+			slog.Error("MQTT subscribe error", "broker", s.URL, "topic", s.TopicRX, "err", err)
+			mqttSubscribeFailures.Inc(s.URL)
+			r.failed(c, fmt.Errorf("subscribe %s: %w", s.TopicRX, err))
+			// connected but deaf: start over rather than wait for a
+			// drop that may never come
+			time.AfterFunc(5*time.Second, r.Reconnect)
 			return
 		}
-		log.Printf("MQTT subscribed: %s", r.TopicRX)
+		slog.Info("MQTT subscribed", "broker", s.URL, "topic", s.TopicRX)
+		r.setConnected(c, s.URL, true)
+		if r.OnConnect != nil {
+			go r.OnConnect()
+		}
 	}
+	opts.OnConnectionLost = func(c mqtt.Client, err error) {
+		slog.Warn("MQTT connection lost", "broker", s.URL, "err", err)
+		mqttConnectionsLost.Inc(s.URL)
+		r.failed(c, err)
+		r.setConnected(c, s.URL, false)
+		if r.OnConnectionLost != nil {
+			go r.OnConnectionLost(err)
+		}
+	}
+	opts.OnReconnecting = func(mqtt.Client, *mqtt.ClientOptions) {
+		slog.Info("MQTT reconnecting", "broker", s.URL)
+		r.reconnecting(s.URL)
+	}
+	return mqtt.NewClient(opts)
+}
 
-	r.client = mqtt.NewClient(opts)
-	if tok := r.client.Connect(); tok.Wait() && tok.Error() != nil {
+func (r *Remote) Run(ctx context.Context) error {
+	slog.Info("MQTT connecting", "broker", r.Settings().URL)
+
+	r.mu.Lock()
+	client := r.newClient(r.settings(), r.TLSConfig)
+	r.client = client
+	r.init()
+	r.downSince = time.Now()
+	reconnect, rotate := r.reconnect, r.rotate
+	r.mu.Unlock()
+	if tok := client.Connect(); tok.Wait() && tok.Error() != nil {
+		r.failed(client, tok.Error())
 		return tok.Error()
 	}
 
-	<-ctx.Done()
-	if r.client.IsConnected() {
-		r.client.Disconnect(250)
+	for ctx.Err() == nil {
+		select {
+		case <-ctx.Done():
+			r.drain()
+		case <-reconnect:
+			// envelopes sent meanwhile are queued and flushed by OnConnect
+			slog.Info("MQTT reconnecting", "broker", r.Settings().URL)
+			r.setConnected(client, r.Settings().URL, false)
+			client.Disconnect(250)
+			r.reconnecting(r.Settings().URL)
+			if tok := client.Connect(); tok.Wait() && tok.Error() != nil {
+				slog.Error("MQTT reconnect failed", "broker", r.Settings().URL, "err", tok.Error())
+				r.failed(client, tok.Error())
+				// paho does not retry a failed initial connect
+				time.AfterFunc(5*time.Second, r.Reconnect)
+			}
+		case rot := <-rotate:
+			next, err := r.switchTo(client, rot)
+			if err == nil {
+				client = next
+			}
+			rot.done <- err
+		}
 	}
-	log.Println("MQTT disconnected")
+	r.mu.Lock()
+	r.connected = false
+	queued := r.queue
+	r.queue = nil
+	r.mu.Unlock()
+	for _, o := range queued {
+		o.done <- ErrClosed
+	}
+	if client.IsConnected() {
+		client.Disconnect(250)
+	}
+	// a message being handled as the connection closed is handled in full
+	r.handling.Wait()
+	slog.Info("MQTT disconnected", "broker", r.Settings().URL)
 
 	return nil
 }
 
-func (r *Remote) Send(payload interface{}) {
-	token := r.client.Publish(r.TopicTX, r.QoS, false, payload)
-	token.Wait()
+// init creates the channels Run listens on; the caller holds r.mu.
+func (r *Remote) init() {
+	if r.reconnect == nil {
+		r.reconnect = make(chan struct{}, 1)
+		r.rotate = make(chan rotation)
+	}
+}
+
+// switchTo connects a client with rotated settings or credentials and, only
+// once it is up, drops the old one. Envelopes sent in between are queued for
+// the new client. If the new client can't connect the old one is kept.
+func (r *Remote) switchTo(old mqtt.Client, rot rotation) (mqtt.Client, error) {
+	slog.Info("MQTT rotating "+rot.what, "broker", rot.settings.URL)
+	next := r.newClient(rot.settings, rot.tlsConfig)
+	r.mu.Lock()
+	r.client = next
+	r.connected = false
+	r.mu.Unlock()
+
+	err := errors.New("connect timed out")
+	if tok := next.Connect(); tok.WaitTimeout(30 * time.Second) {
+		err = tok.Error()
+	}
+	if err != nil {
+		next.Disconnect(0)
+		r.mu.Lock()
+		r.client = old
+		r.mu.Unlock()
+		r.setConnected(old, r.Settings().URL, old.IsConnectionOpen())
+		return nil, fmt.Errorf("rotated %s: %w", rot.what, err)
+	}
+
+	r.mu.Lock()
+	s := rot.settings
+	if r.URL != s.URL {
+		mqttConnected.Set(0, r.URL)
+	}
+	r.URL, r.ClientID, r.Username, r.Password, r.QoS = s.URL, s.ClientID, s.Username, s.Password, s.QoS
+	r.TopicRX, r.TopicTX, r.TopicTelemetry = s.TopicRX, s.TopicTX, s.TopicTelemetry
+	r.ReconnectInterval = s.ReconnectInterval
+	r.TLSConfig = rot.tlsConfig
+	r.mu.Unlock()
+	old.Disconnect(250)
+	slog.Info("MQTT "+rot.what+" rotated", "broker", s.URL)
+	return next, nil
+}
+
+// Connected reports whether the client is connected and subscribed.
+func (r *Remote) Connected() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.connected
+}
+
+// LastError returns why the connection last went down or failed to come
+// up, nil if it never did.
+func (r *Remote) LastError() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.lastErr
+}
+
+// failed records err as why c is down, unless c is being replaced.
+func (r *Remote) failed(c mqtt.Client, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if c == r.client {
+		r.lastErr = err
+	}
+}
+
+// reconnecting counts an attempt to connect to broker again and tells
+// OnReconnecting.
+func (r *Remote) reconnecting(broker string) {
+	mqttReconnects.Inc(broker)
+	if r.OnReconnecting != nil {
+		r.OnReconnecting()
+	}
+}
+
+// Credentials returns the broker credentials currently in use.
+func (r *Remote) Credentials() (username, password string, tlsConfig *tls.Config) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.Username, r.Password, r.TLSConfig
+}
+
+// Rotate makes Run switch to new broker credentials without a window in
+// which the agent is disconnected, see switchTo. It returns once the new
+// connection is up or the switch failed.
+func (r *Remote) Rotate(ctx context.Context, username, password string, tlsConfig *tls.Config) error {
+	r.mu.Lock()
+	s := r.settings()
+	r.mu.Unlock()
+	s.Username, s.Password = username, password
+	return r.switchAll(ctx, rotation{what: "credentials", settings: s, tlsConfig: tlsConfig})
+}
+
+// Settings returns the settings currently in use.
+func (r *Remote) Settings() Settings {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.settings()
+}
+
+// Reconfigure makes Run switch to new settings the way Rotate switches
+// credentials, keeping the TLS configuration.
+func (r *Remote) Reconfigure(ctx context.Context, s Settings) error {
+	r.mu.Lock()
+	tlsConfig := r.TLSConfig
+	r.mu.Unlock()
+	return r.switchAll(ctx, rotation{what: "settings", settings: s, tlsConfig: tlsConfig})
+}
+
+// switchAll hands rot to Run and waits for the outcome.
+func (r *Remote) switchAll(ctx context.Context, rot rotation) error {
+	r.mu.Lock()
+	r.init()
+	rotate := r.rotate
+	r.mu.Unlock()
+	rot.done = make(chan error, 1)
+	select {
+	case rotate <- rot:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case err := <-rot.done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Reconnect makes Run close the broker connection and open a new one, e.g.
+// so that rotated TLS material is used before the old material expires.
+func (r *Remote) Reconnect() {
+	r.mu.Lock()
+	r.init()
+	reconnect := r.reconnect
+	r.mu.Unlock()
+	select {
+	case reconnect <- struct{}{}:
+	default:
+	}
+}
+
+// ErrClosed is returned for envelopes still queued when Run returns.
+var ErrClosed = errors.New("remote closed")
+
+// setConnected records the connection state of c to broker and, on
+// connect, publishes everything queued while the connection was down.
+func (r *Remote) setConnected(c mqtt.Client, broker string, connected bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// a client being replaced by switchTo no longer counts
+	if c != r.client {
+		return
+	}
+	switch {
+	case connected && !r.connected:
+		mqttConnects.Inc(broker)
+		mqttConnected.Set(1, broker)
+		if !r.downSince.IsZero() {
+			mqttDisconnected.Add(time.Since(r.downSince).Seconds(), broker)
+			r.downSince = time.Time{}
+		}
+	case !connected && r.connected:
+		mqttConnected.Set(0, broker)
+		r.downSince = time.Now()
+	}
+	r.connected = connected
+	if !connected || r.flushing {
+		return
+	}
+	// send queues behind the flush so queued envelopes go out before new
+	// ones, while the publishing itself happens without the lock
+	r.flushing = true
+	for r.connected && len(r.queue) > 0 {
+		c, qos, queued := r.client, r.QoS, r.queue
+		r.queue = nil
+		r.inflight += len(queued)
+		r.mu.Unlock()
+		for _, o := range queued {
+			r.publish(c, qos, o)
+		}
+		r.mu.Lock()
+	}
+	r.flushing = false
+}
+
+// publish hands o to paho and reports the broker's acknowledgement, or the
+// publish error, on o.done. The caller counted o in r.inflight and must not
+// hold r.mu.
+func (r *Remote) publish(c mqtt.Client, qos byte, o *outgoing) {
+	token := c.Publish(o.topic, qos, false, o.payload)
+	go func() {
+		<-token.Done()
+		if token.Error() != nil {
+			mqttPublishFailures.Inc(r.Settings().URL)
+		}
+		r.mu.Lock()
+		r.inflight--
+		r.mu.Unlock()
+		o.done <- token.Error()
+	}()
+}
+
+// drain waits up to DrainTimeout for the queued and unacknowledged
+// envelopes to be acknowledged.
+func (r *Remote) drain() {
+	deadline := time.Now().Add(r.DrainTimeout)
+	for {
+		r.mu.Lock()
+		pending := len(r.queue) + r.inflight
+		r.mu.Unlock()
+		if pending == 0 {
+			return
+		}
+		if !time.Now().Before(deadline) {
+			slog.Warn("MQTT envelopes not flushed", "broker", r.Settings().URL, "envelopes", pending)
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// SendEnvelope publishes an envelope on TopicTX and waits until the broker
+// has acknowledged it as required by QoS. It is safe for concurrent use and
+// may be called before Run connects: envelopes sent while disconnected are
+// queued and published once the connection is up. The wait, including any
+// time spent queued, is bounded by ctx.
+func (r *Remote) SendEnvelope(ctx context.Context, envelope *Envelope) error {
+	return r.send(ctx, false, envelope)
+}
+
+// SendTelemetry is SendEnvelope publishing on TopicTelemetry, for reports
+// the controller may consume apart from the control messages on TopicTX.
+func (r *Remote) SendTelemetry(ctx context.Context, envelope *Envelope) error {
+	return r.send(ctx, true, envelope)
+}
+
+func (r *Remote) send(ctx context.Context, telemetry bool, envelope *Envelope) error {
+	if err := Stamp(envelope); err != nil {
+		return err
+	}
+	if r.Signer != nil {
+		if err := r.Signer(envelope); err != nil {
+			return fmt.Errorf("sign envelope: %w", err)
+		}
+	}
+	payload, err := proto.Marshal(envelope)
+	if err != nil {
+		return err
+	}
+	o := &outgoing{payload: payload, done: make(chan error, 1)}
+
+	r.mu.Lock()
+	// the topics may change under Reconfigure
+	o.topic = r.TopicTX
+	if telemetry && r.TopicTelemetry != "" {
+		o.topic = r.TopicTelemetry
+	}
+	if r.connected && !r.flushing {
+		c, qos := r.client, r.QoS
+		r.inflight++
+		r.mu.Unlock()
+		r.publish(c, qos, o)
+	} else {
+		r.queue = append(r.queue, o)
+		r.mu.Unlock()
+	}
+
+	select {
+	case err := <-o.done:
+		return err
+	case <-ctx.Done():
+		r.mu.Lock()
+		for i, q := range r.queue {
+			if q == o {
+				r.queue = append(r.queue[:i], r.queue[i+1:]...)
+				break
+			}
+		}
+		r.mu.Unlock()
+		return ctx.Err()
+	}
 }
@@ -64,7 +64,111 @@ func (x Route_Status) Number() protoreflect.EnumNumber {
 
 // Deprecated: Use Route_Status.Descriptor instead.
 func (Route_Status) EnumDescriptor() ([]byte, []int) {
-	return file_remote_proto_rawDescGZIP(), []int{3, 0}
+	return file_remote_proto_rawDescGZIP(), []int{4, 0}
+}
+
+type RouteChange_Op int32
+
+const (
+	RouteChange_ADD    RouteChange_Op = 0
+	RouteChange_DELETE RouteChange_Op = 1
+)
+
+// Enum value maps for RouteChange_Op.
+var (
+	RouteChange_Op_name = map[int32]string{
+		0: "ADD",
+		1: "DELETE",
+	}
+	RouteChange_Op_value = map[string]int32{
+		"ADD":    0,
+		"DELETE": 1,
+	}
+)
+
+func (x RouteChange_Op) Enum() *RouteChange_Op {
+	p := new(RouteChange_Op)
+	*p = x
+	return p
+}
+
+func (x RouteChange_Op) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (RouteChange_Op) Descriptor() protoreflect.EnumDescriptor {
+	return file_remote_proto_enumTypes[1].Descriptor()
+}
+
+func (RouteChange_Op) Type() protoreflect.EnumType {
+	return &file_remote_proto_enumTypes[1]
+}
+
+func (x RouteChange_Op) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use RouteChange_Op.Descriptor instead.
+func (RouteChange_Op) EnumDescriptor() ([]byte, []int) {
+	return file_remote_proto_rawDescGZIP(), []int{8, 0}
+}
+
+type Nack_Reason int32
+
+const (
+	Nack_UNSPECIFIED       Nack_Reason = 0
+	Nack_RATE_LIMITED      Nack_Reason = 1
+	Nack_QUOTA_EXCEEDED    Nack_Reason = 2
+	Nack_PREFIX_REJECTED   Nack_Reason = 3
+	Nack_SEGMENT_REJECTED  Nack_Reason = 4
+	Nack_SEGMENTS_EXCEEDED Nack_Reason = 5
+)
+
+// Enum value maps for Nack_Reason.
+var (
+	Nack_Reason_name = map[int32]string{
+		0: "UNSPECIFIED",
+		1: "RATE_LIMITED",
+		2: "QUOTA_EXCEEDED",
+		3: "PREFIX_REJECTED",
+		4: "SEGMENT_REJECTED",
+		5: "SEGMENTS_EXCEEDED",
+	}
+	Nack_Reason_value = map[string]int32{
+		"UNSPECIFIED":       0,
+		"RATE_LIMITED":      1,
+		"QUOTA_EXCEEDED":    2,
+		"PREFIX_REJECTED":   3,
+		"SEGMENT_REJECTED":  4,
+		"SEGMENTS_EXCEEDED": 5,
+	}
+)
+
+func (x Nack_Reason) Enum() *Nack_Reason {
+	p := new(Nack_Reason)
+	*p = x
+	return p
+}
+
+func (x Nack_Reason) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Nack_Reason) Descriptor() protoreflect.EnumDescriptor {
+	return file_remote_proto_enumTypes[2].Descriptor()
+}
+
+func (Nack_Reason) Type() protoreflect.EnumType {
+	return &file_remote_proto_enumTypes[2]
+}
+
+func (x Nack_Reason) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Nack_Reason.Descriptor instead.
+func (Nack_Reason) EnumDescriptor() ([]byte, []int) {
+	return file_remote_proto_rawDescGZIP(), []int{15, 0}
 }
 
 type Envelope struct {
@@ -74,7 +178,22 @@ type Envelope struct {
 	//	*Envelope_Register
 	//	*Envelope_Deregister
 	//	*Envelope_Route
-	Kind          isEnvelope_Kind `protobuf_oneof:"kind"`
+	//	*Envelope_Nack
+	//	*Envelope_CredentialRotate
+	//	*Envelope_SetMtu
+	//	*Envelope_Heartbeat
+	//	*Envelope_RouteBatch
+	//	*Envelope_BatchProgress
+	//	*Envelope_RouteChanges
+	//	*Envelope_AgentHello
+	Kind isEnvelope_Kind `protobuf_oneof:"kind"`
+	// sent_unix_nano and nonce let receivers refuse replayed envelopes; both
+	// are covered by the signature.
+	SentUnixNano int64  `protobuf:"varint,12,opt,name=sent_unix_nano,json=sentUnixNano,proto3" json:"sent_unix_nano,omitempty"`
+	Nonce        []byte `protobuf:"bytes,13,opt,name=nonce,proto3" json:"nonce,omitempty"`
+	// signature is set by agents with a workload identity. It covers the
+	// deterministic encoding of the envelope with signature unset.
+	Signature     *Signature `protobuf:"bytes,15,opt,name=signature,proto3" json:"signature,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -143,6 +262,99 @@ func (x *Envelope) GetRoute() *Route {
 	return nil
 }
 
+func (x *Envelope) GetNack() *Nack {
+	if x != nil {
+		if x, ok := x.Kind.(*Envelope_Nack); ok {
+			return x.Nack
+		}
+	}
+	return nil
+}
+
+func (x *Envelope) GetCredentialRotate() *CredentialRotate {
+	if x != nil {
+		if x, ok := x.Kind.(*Envelope_CredentialRotate); ok {
+			return x.CredentialRotate
+		}
+	}
+	return nil
+}
+
+func (x *Envelope) GetSetMtu() *SetMTU {
+	if x != nil {
+		if x, ok := x.Kind.(*Envelope_SetMtu); ok {
+			return x.SetMtu
+		}
+	}
+	return nil
+}
+
+func (x *Envelope) GetHeartbeat() *Heartbeat {
+	if x != nil {
+		if x, ok := x.Kind.(*Envelope_Heartbeat); ok {
+			return x.Heartbeat
+		}
+	}
+	return nil
+}
+
+func (x *Envelope) GetRouteBatch() *RouteBatch {
+	if x != nil {
+		if x, ok := x.Kind.(*Envelope_RouteBatch); ok {
+			return x.RouteBatch
+		}
+	}
+	return nil
+}
+
+func (x *Envelope) GetBatchProgress() *BatchProgress {
+	if x != nil {
+		if x, ok := x.Kind.(*Envelope_BatchProgress); ok {
+			return x.BatchProgress
+		}
+	}
+	return nil
+}
+
+func (x *Envelope) GetRouteChanges() *RouteChanges {
+	if x != nil {
+		if x, ok := x.Kind.(*Envelope_RouteChanges); ok {
+			return x.RouteChanges
+		}
+	}
+	return nil
+}
+
+func (x *Envelope) GetAgentHello() *AgentHello {
+	if x != nil {
+		if x, ok := x.Kind.(*Envelope_AgentHello); ok {
+			return x.AgentHello
+		}
+	}
+	return nil
+}
+
+func (x *Envelope) GetSentUnixNano() int64 {
+	if x != nil {
+		return x.SentUnixNano
+	}
+	return 0
+}
+
+func (x *Envelope) GetNonce() []byte {
+	if x != nil {
+		return x.Nonce
+	}
+	return nil
+}
+
+func (x *Envelope) GetSignature() *Signature {
+	if x != nil {
+		return x.Signature
+	}
+	return nil
+}
+
 type isEnvelope_Kind interface {
 	isEnvelope_Kind()
 }
@@ -159,23 +371,145 @@ type Envelope_Route struct {
 	Route *Route `protobuf:"bytes,3,opt,name=route,proto3,oneof"`
 }
 
+type Envelope_Nack struct {
+	Nack *Nack `protobuf:"bytes,4,opt,name=nack,proto3,oneof"`
+}
+
+type Envelope_CredentialRotate struct {
+	CredentialRotate *CredentialRotate `protobuf:"bytes,5,opt,name=credential_rotate,json=credentialRotate,proto3,oneof"`
+}
+
+type Envelope_SetMtu struct {
+	SetMtu *SetMTU `protobuf:"bytes,6,opt,name=set_mtu,json=setMtu,proto3,oneof"`
+}
+
+type Envelope_Heartbeat struct {
+	Heartbeat *Heartbeat `protobuf:"bytes,7,opt,name=heartbeat,proto3,oneof"`
+}
+
+type Envelope_RouteBatch struct {
+	RouteBatch *RouteBatch `protobuf:"bytes,8,opt,name=route_batch,json=routeBatch,proto3,oneof"`
+}
+
+type Envelope_BatchProgress struct {
+	BatchProgress *BatchProgress `protobuf:"bytes,9,opt,name=batch_progress,json=batchProgress,proto3,oneof"`
+}
+
+type Envelope_RouteChanges struct {
+	RouteChanges *RouteChanges `protobuf:"bytes,10,opt,name=route_changes,json=routeChanges,proto3,oneof"`
+}
+
+type Envelope_AgentHello struct {
+	AgentHello *AgentHello `protobuf:"bytes,11,opt,name=agent_hello,json=agentHello,proto3,oneof"`
+}
+
 func (*Envelope_Register) isEnvelope_Kind() {}
 
 func (*Envelope_Deregister) isEnvelope_Kind() {}
 
 func (*Envelope_Route) isEnvelope_Kind() {}
 
+func (*Envelope_Nack) isEnvelope_Kind() {}
+
+func (*Envelope_CredentialRotate) isEnvelope_Kind() {}
+
+func (*Envelope_SetMtu) isEnvelope_Kind() {}
+
+func (*Envelope_Heartbeat) isEnvelope_Kind() {}
+
+func (*Envelope_RouteBatch) isEnvelope_Kind() {}
+
+func (*Envelope_BatchProgress) isEnvelope_Kind() {}
+
+func (*Envelope_RouteChanges) isEnvelope_Kind() {}
+
+func (*Envelope_AgentHello) isEnvelope_Kind() {}
+
+// Signature is made either by a SPIFFE identity, with spiffe_id and
+// x509_svid set, or by a bare key the receiver knows by key_id.
+type Signature struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	SpiffeId string                 `protobuf:"bytes,1,opt,name=spiffe_id,json=spiffeId,proto3" json:"spiffe_id,omitempty"`
+	// ASN.1 DER encoded certificate chain of the signer, leaf first
+	X509Svid      [][]byte `protobuf:"bytes,2,rep,name=x509_svid,json=x509Svid,proto3" json:"x509_svid,omitempty"`
+	Value         []byte   `protobuf:"bytes,3,opt,name=value,proto3" json:"value,omitempty"`
+	KeyId         string   `protobuf:"bytes,4,opt,name=key_id,json=keyId,proto3" json:"key_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Signature) Reset() {
+	*x = Signature{}
+	mi := &file_remote_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Signature) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Signature) ProtoMessage() {}
+
+func (x *Signature) ProtoReflect() protoreflect.Message {
+	mi := &file_remote_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Signature.ProtoReflect.Descriptor instead.
+func (*Signature) Descriptor() ([]byte, []int) {
+	return file_remote_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Signature) GetSpiffeId() string {
+	if x != nil {
+		return x.SpiffeId
+	}
+	return ""
+}
+
+func (x *Signature) GetX509Svid() [][]byte {
+	if x != nil {
+		return x.X509Svid
+	}
+	return nil
+}
+
+func (x *Signature) GetValue() []byte {
+	if x != nil {
+		return x.Value
+	}
+	return nil
+}
+
+func (x *Signature) GetKeyId() string {
+	if x != nil {
+		return x.KeyId
+	}
+	return ""
+}
+
 type Register struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Network       string                 `protobuf:"bytes,1,opt,name=network,proto3" json:"network,omitempty"`
-	Srv6Endpoint  string                 `protobuf:"bytes,2,opt,name=srv6_endpoint,json=srv6Endpoint,proto3" json:"srv6_endpoint,omitempty"`
+	state        protoimpl.MessageState `protogen:"open.v1"`
+	Network      string                 `protobuf:"bytes,1,opt,name=network,proto3" json:"network,omitempty"`
+	Srv6Endpoint string                 `protobuf:"bytes,2,opt,name=srv6_endpoint,json=srv6Endpoint,proto3" json:"srv6_endpoint,omitempty"`
+	// anycast is set when other attachments of the VPC may advertise the
+	// same network.
+	Anycast       bool `protobuf:"varint,3,opt,name=anycast,proto3" json:"anycast,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *Register) Reset() {
 	*x = Register{}
-	mi := &file_remote_proto_msgTypes[1]
+	mi := &file_remote_proto_msgTypes[2]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -187,7 +521,7 @@ func (x *Register) String() string {
 func (*Register) ProtoMessage() {}
 
 func (x *Register) ProtoReflect() protoreflect.Message {
-	mi := &file_remote_proto_msgTypes[1]
+	mi := &file_remote_proto_msgTypes[2]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -200,7 +534,7 @@ func (x *Register) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Register.ProtoReflect.Descriptor instead.
 func (*Register) Descriptor() ([]byte, []int) {
-	return file_remote_proto_rawDescGZIP(), []int{1}
+	return file_remote_proto_rawDescGZIP(), []int{2}
 }
 
 func (x *Register) GetNetwork() string {
@@ -217,6 +551,13 @@ func (x *Register) GetSrv6Endpoint() string {
 	return ""
 }
 
+func (x *Register) GetAnycast() bool {
+	if x != nil {
+		return x.Anycast
+	}
+	return false
+}
+
 type Deregister struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Network       string                 `protobuf:"bytes,1,opt,name=network,proto3" json:"network,omitempty"`
@@ -227,7 +568,7 @@ type Deregister struct {
 
 func (x *Deregister) Reset() {
 	*x = Deregister{}
-	mi := &file_remote_proto_msgTypes[2]
+	mi := &file_remote_proto_msgTypes[3]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -239,7 +580,7 @@ func (x *Deregister) String() string {
 func (*Deregister) ProtoMessage() {}
 
 func (x *Deregister) ProtoReflect() protoreflect.Message {
-	mi := &file_remote_proto_msgTypes[2]
+	mi := &file_remote_proto_msgTypes[3]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -252,7 +593,7 @@ func (x *Deregister) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Deregister.ProtoReflect.Descriptor instead.
 func (*Deregister) Descriptor() ([]byte, []int) {
-	return file_remote_proto_rawDescGZIP(), []int{2}
+	return file_remote_proto_rawDescGZIP(), []int{3}
 }
 
 func (x *Deregister) GetNetwork() string {
@@ -270,18 +611,21 @@ func (x *Deregister) GetSrv6Endpoint() string {
 }
 
 type Route struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Network       string                 `protobuf:"bytes,1,opt,name=network,proto3" json:"network,omitempty"`
-	Srv6Endpoint  string                 `protobuf:"bytes,2,opt,name=srv6_endpoint,json=srv6Endpoint,proto3" json:"srv6_endpoint,omitempty"`
-	Srv6Segments  []string               `protobuf:"bytes,3,rep,name=srv6_segments,json=srv6Segments,proto3" json:"srv6_segments,omitempty"`
-	Status        Route_Status           `protobuf:"varint,4,opt,name=status,proto3,enum=remote.v1.Route_Status" json:"status,omitempty"`
+	state        protoimpl.MessageState `protogen:"open.v1"`
+	Network      string                 `protobuf:"bytes,1,opt,name=network,proto3" json:"network,omitempty"`
+	Srv6Endpoint string                 `protobuf:"bytes,2,opt,name=srv6_endpoint,json=srv6Endpoint,proto3" json:"srv6_endpoint,omitempty"`
+	Srv6Segments []string               `protobuf:"bytes,3,rep,name=srv6_segments,json=srv6Segments,proto3" json:"srv6_segments,omitempty"`
+	Status       Route_Status           `protobuf:"varint,4,opt,name=status,proto3,enum=remote.v1.Route_Status" json:"status,omitempty"`
+	// dscp, if not 0, marks the traffic of the attachment to network,
+	// overriding the DSCP configured for the VPC.
+	Dscp          uint32 `protobuf:"varint,5,opt,name=dscp,proto3" json:"dscp,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *Route) Reset() {
 	*x = Route{}
-	mi := &file_remote_proto_msgTypes[3]
+	mi := &file_remote_proto_msgTypes[4]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -293,7 +637,7 @@ func (x *Route) String() string {
 func (*Route) ProtoMessage() {}
 
 func (x *Route) ProtoReflect() protoreflect.Message {
-	mi := &file_remote_proto_msgTypes[3]
+	mi := &file_remote_proto_msgTypes[4]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -306,7 +650,7 @@ func (x *Route) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Route.ProtoReflect.Descriptor instead.
 func (*Route) Descriptor() ([]byte, []int) {
-	return file_remote_proto_rawDescGZIP(), []int{3}
+	return file_remote_proto_rawDescGZIP(), []int{4}
 }
 
 func (x *Route) GetNetwork() string {
@@ -337,34 +681,936 @@ func (x *Route) GetStatus() Route_Status {
 	return Route_ADD
 }
 
-var File_remote_proto protoreflect.FileDescriptor
+func (x *Route) GetDscp() uint32 {
+	if x != nil {
+		return x.Dscp
+	}
+	return 0
+}
 
-const file_remote_proto_rawDesc = "" +
-	"\n" +
-	"\fremote.proto\x12\tremote.v1\"\xa8\x01\n" +
-	"\bEnvelope\x121\n" +
-	"\bregister\x18\x01 \x01(\v2\x13.remote.v1.RegisterH\x00R\bregister\x127\n" +
-	"\n" +
-	"deregister\x18\x02 \x01(\v2\x15.remote.v1.DeregisterH\x00R\n" +
-	"deregister\x12(\n" +
-	"\x05route\x18\x03 \x01(\v2\x10.remote.v1.RouteH\x00R\x05routeB\x06\n" +
-	"\x04kind\"I\n" +
-	"\bRegister\x12\x18\n" +
-	"\anetwork\x18\x01 \x01(\tR\anetwork\x12#\n" +
-	"\rsrv6_endpoint\x18\x02 \x01(\tR\fsrv6Endpoint\"K\n" +
-	"\n" +
-	"Deregister\x12\x18\n" +
-	"\anetwork\x18\x01 \x01(\tR\anetwork\x12#\n" +
-	"\rsrv6_endpoint\x18\x02 \x01(\tR\fsrv6Endpoint\"\xbb\x01\n" +
-	"\x05Route\x12\x18\n" +
-	"\anetwork\x18\x01 \x01(\tR\anetwork\x12#\n" +
-	"\rsrv6_endpoint\x18\x02 \x01(\tR\fsrv6Endpoint\x12#\n" +
-	"\rsrv6_segments\x18\x03 \x03(\tR\fsrv6Segments\x12/\n" +
-	"\x06status\x18\x04 \x01(\x0e2\x17.remote.v1.Route.StatusR\x06status\"\x1d\n" +
-	"\x06Status\x12\a\n" +
-	"\x03ADD\x10\x00\x12\n" +
+// RouteBatch carries many routes at once, typically a controller resyncing
+// an agent. Agents decode and apply it a chunk of routes at a time, and
+// report how far they got in BatchProgress envelopes. Agents verify a
+// signed batch over the bytes received rather than a re-encoding, so its
+// fields must be in field number order with the signature last, as proto
+// encoders write them.
+type RouteBatch struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// id names the batch in BatchProgress.
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// resync makes the batch the complete set of the tenant's routes: routes
+	// the agent holds that the batch does not list are deleted once it is
+	// applied.
+	Resync        bool     `protobuf:"varint,2,opt,name=resync,proto3" json:"resync,omitempty"`
+	Routes        []*Route `protobuf:"bytes,3,rep,name=routes,proto3" json:"routes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RouteBatch) Reset() {
+	*x = RouteBatch{}
+	mi := &file_remote_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RouteBatch) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RouteBatch) ProtoMessage() {}
+
+func (x *RouteBatch) ProtoReflect() protoreflect.Message {
+	mi := &file_remote_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RouteBatch.ProtoReflect.Descriptor instead.
+func (*RouteBatch) Descriptor() ([]byte, []int) {
+	return file_remote_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *RouteBatch) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *RouteBatch) GetResync() bool {
+	if x != nil {
+		return x.Resync
+	}
+	return false
+}
+
+func (x *RouteBatch) GetRoutes() []*Route {
+	if x != nil {
+		return x.Routes
+	}
+	return nil
+}
+
+// BatchProgress is sent by an agent applying a RouteBatch, after each
+// chunk of routes and once done. Counts are cumulative.
+type BatchProgress struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// applied and refused count routes of the batch; refused ones are also
+	// nacked.
+	Applied uint64 `protobuf:"varint,2,opt,name=applied,proto3" json:"applied,omitempty"`
+	Refused uint64 `protobuf:"varint,3,opt,name=refused,proto3" json:"refused,omitempty"`
+	// deleted counts the routes a resync removed.
+	Deleted       uint64 `protobuf:"varint,4,opt,name=deleted,proto3" json:"deleted,omitempty"`
+	Done          bool   `protobuf:"varint,5,opt,name=done,proto3" json:"done,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchProgress) Reset() {
+	*x = BatchProgress{}
+	mi := &file_remote_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchProgress) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchProgress) ProtoMessage() {}
+
+func (x *BatchProgress) ProtoReflect() protoreflect.Message {
+	mi := &file_remote_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchProgress.ProtoReflect.Descriptor instead.
+func (*BatchProgress) Descriptor() ([]byte, []int) {
+	return file_remote_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *BatchProgress) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *BatchProgress) GetApplied() uint64 {
+	if x != nil {
+		return x.Applied
+	}
+	return 0
+}
+
+func (x *BatchProgress) GetRefused() uint64 {
+	if x != nil {
+		return x.Refused
+	}
+	return 0
+}
+
+func (x *BatchProgress) GetDeleted() uint64 {
+	if x != nil {
+		return x.Deleted
+	}
+	return 0
+}
+
+func (x *BatchProgress) GetDone() bool {
+	if x != nil {
+		return x.Done
+	}
+	return false
+}
+
+// RouteChanges is sent by agents in route-monitor mode, on their telemetry
+// topic, with the kernel route changes seen in the VRFs of their
+// attachments since the last one. It lets controllers notice routes edited
+// behind the agent's back.
+type RouteChanges struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Changes       []*RouteChange         `protobuf:"bytes,1,rep,name=changes,proto3" json:"changes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RouteChanges) Reset() {
+	*x = RouteChanges{}
+	mi := &file_remote_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RouteChanges) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RouteChanges) ProtoMessage() {}
+
+func (x *RouteChanges) ProtoReflect() protoreflect.Message {
+	mi := &file_remote_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RouteChanges.ProtoReflect.Descriptor instead.
+func (*RouteChanges) Descriptor() ([]byte, []int) {
+	return file_remote_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *RouteChanges) GetChanges() []*RouteChange {
+	if x != nil {
+		return x.Changes
+	}
+	return nil
+}
+
+// RouteChange is a route added to, replaced in or deleted from the VRF
+// table of an attachment.
+type RouteChange struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Op    RouteChange_Op         `protobuf:"varint,1,opt,name=op,proto3,enum=remote.v1.RouteChange_Op" json:"op,omitempty"`
+	// srv6_endpoint is that of the attachment whose VRF holds the route.
+	Srv6Endpoint string `protobuf:"bytes,2,opt,name=srv6_endpoint,json=srv6Endpoint,proto3" json:"srv6_endpoint,omitempty"`
+	Table        uint32 `protobuf:"varint,3,opt,name=table,proto3" json:"table,omitempty"`
+	Network      string `protobuf:"bytes,4,opt,name=network,proto3" json:"network,omitempty"`
+	// srv6_segments are those of an SRv6 encap route, in Route order;
+	// device and gateway those of any other route.
+	Srv6Segments []string `protobuf:"bytes,5,rep,name=srv6_segments,json=srv6Segments,proto3" json:"srv6_segments,omitempty"`
+	Device       string   `protobuf:"bytes,6,opt,name=device,proto3" json:"device,omitempty"`
+	Gateway      string   `protobuf:"bytes,7,opt,name=gateway,proto3" json:"gateway,omitempty"`
+	// protocol is the kernel's route protocol: 3 for routes added by hand
+	// or by the agent, above 4 for routing daemons.
+	Protocol uint32 `protobuf:"varint,8,opt,name=protocol,proto3" json:"protocol,omitempty"`
+	// desired tells whether the agent's state held the route, with these
+	// segments, when the change was sent: an added route that is not
+	// desired, or a deleted one that is, was changed out of band.
+	Desired          bool  `protobuf:"varint,9,opt,name=desired,proto3" json:"desired,omitempty"`
+	ObservedUnixNano int64 `protobuf:"varint,10,opt,name=observed_unix_nano,json=observedUnixNano,proto3" json:"observed_unix_nano,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *RouteChange) Reset() {
+	*x = RouteChange{}
+	mi := &file_remote_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RouteChange) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RouteChange) ProtoMessage() {}
+
+func (x *RouteChange) ProtoReflect() protoreflect.Message {
+	mi := &file_remote_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RouteChange.ProtoReflect.Descriptor instead.
+func (*RouteChange) Descriptor() ([]byte, []int) {
+	return file_remote_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *RouteChange) GetOp() RouteChange_Op {
+	if x != nil {
+		return x.Op
+	}
+	return RouteChange_ADD
+}
+
+func (x *RouteChange) GetSrv6Endpoint() string {
+	if x != nil {
+		return x.Srv6Endpoint
+	}
+	return ""
+}
+
+func (x *RouteChange) GetTable() uint32 {
+	if x != nil {
+		return x.Table
+	}
+	return 0
+}
+
+func (x *RouteChange) GetNetwork() string {
+	if x != nil {
+		return x.Network
+	}
+	return ""
+}
+
+func (x *RouteChange) GetSrv6Segments() []string {
+	if x != nil {
+		return x.Srv6Segments
+	}
+	return nil
+}
+
+func (x *RouteChange) GetDevice() string {
+	if x != nil {
+		return x.Device
+	}
+	return ""
+}
+
+func (x *RouteChange) GetGateway() string {
+	if x != nil {
+		return x.Gateway
+	}
+	return ""
+}
+
+func (x *RouteChange) GetProtocol() uint32 {
+	if x != nil {
+		return x.Protocol
+	}
+	return 0
+}
+
+func (x *RouteChange) GetDesired() bool {
+	if x != nil {
+		return x.Desired
+	}
+	return false
+}
+
+func (x *RouteChange) GetObservedUnixNano() int64 {
+	if x != nil {
+		return x.ObservedUnixNano
+	}
+	return 0
+}
+
+// AgentHello is sent by agents each time they connect to the broker, so
+// controllers can track which agents exist and what they support.
+type AgentHello struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// agent_id stays the same across restarts and hostname changes.
+	AgentId  string `protobuf:"bytes,1,opt,name=agent_id,json=agentId,proto3" json:"agent_id,omitempty"`
+	Hostname string `protobuf:"bytes,2,opt,name=hostname,proto3" json:"hostname,omitempty"`
+	// srv6_net is the prefix the agent's SRv6 endpoints are taken from.
+	Srv6Net string `protobuf:"bytes,3,opt,name=srv6_net,json=srv6Net,proto3" json:"srv6_net,omitempty"`
+	// features are the optional capabilities the agent has turned on, such
+	// as "route-batch" or "route-monitor".
+	Features []string `protobuf:"bytes,4,rep,name=features,proto3" json:"features,omitempty"`
+	// schema is the agent's remote.proto fingerprint, see SchemaVersion.
+	Schema        string `protobuf:"bytes,5,opt,name=schema,proto3" json:"schema,omitempty"`
+	Version       string `protobuf:"bytes,6,opt,name=version,proto3" json:"version,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AgentHello) Reset() {
+	*x = AgentHello{}
+	mi := &file_remote_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AgentHello) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AgentHello) ProtoMessage() {}
+
+func (x *AgentHello) ProtoReflect() protoreflect.Message {
+	mi := &file_remote_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AgentHello.ProtoReflect.Descriptor instead.
+func (*AgentHello) Descriptor() ([]byte, []int) {
+	return file_remote_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *AgentHello) GetAgentId() string {
+	if x != nil {
+		return x.AgentId
+	}
+	return ""
+}
+
+func (x *AgentHello) GetHostname() string {
+	if x != nil {
+		return x.Hostname
+	}
+	return ""
+}
+
+func (x *AgentHello) GetSrv6Net() string {
+	if x != nil {
+		return x.Srv6Net
+	}
+	return ""
+}
+
+func (x *AgentHello) GetFeatures() []string {
+	if x != nil {
+		return x.Features
+	}
+	return nil
+}
+
+func (x *AgentHello) GetSchema() string {
+	if x != nil {
+		return x.Schema
+	}
+	return ""
+}
+
+func (x *AgentHello) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+// CredentialRotate pushes new broker credentials to an agent. Agents only
+// accept it signed by a trusted key.
+type CredentialRotate struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// sealed is a Credentials message encrypted with AES-256-GCM under a key
+	// shared with the agent: the nonce followed by the ciphertext.
+	Sealed        []byte `protobuf:"bytes,1,opt,name=sealed,proto3" json:"sealed,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CredentialRotate) Reset() {
+	*x = CredentialRotate{}
+	mi := &file_remote_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CredentialRotate) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CredentialRotate) ProtoMessage() {}
+
+func (x *CredentialRotate) ProtoReflect() protoreflect.Message {
+	mi := &file_remote_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CredentialRotate.ProtoReflect.Descriptor instead.
+func (*CredentialRotate) Descriptor() ([]byte, []int) {
+	return file_remote_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *CredentialRotate) GetSealed() []byte {
+	if x != nil {
+		return x.Sealed
+	}
+	return nil
+}
+
+// SetMTU sets the effective MTU of the attachment srv6_endpoint belongs
+// to: its host interface and the egress routes in its VRF, including routes
+// added later. Controllers push it when underlay MTUs differ between sites.
+type SetMTU struct {
+	state        protoimpl.MessageState `protogen:"open.v1"`
+	Srv6Endpoint string                 `protobuf:"bytes,1,opt,name=srv6_endpoint,json=srv6Endpoint,proto3" json:"srv6_endpoint,omitempty"`
+	// mtu is at least 1280, the IPv6 minimum.
+	Mtu           uint32 `protobuf:"varint,2,opt,name=mtu,proto3" json:"mtu,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetMTU) Reset() {
+	*x = SetMTU{}
+	mi := &file_remote_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetMTU) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetMTU) ProtoMessage() {}
+
+func (x *SetMTU) ProtoReflect() protoreflect.Message {
+	mi := &file_remote_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetMTU.ProtoReflect.Descriptor instead.
+func (*SetMTU) Descriptor() ([]byte, []int) {
+	return file_remote_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *SetMTU) GetSrv6Endpoint() string {
+	if x != nil {
+		return x.Srv6Endpoint
+	}
+	return ""
+}
+
+func (x *SetMTU) GetMtu() uint32 {
+	if x != nil {
+		return x.Mtu
+	}
+	return 0
+}
+
+// Heartbeat is sent by agents every usage_interval. It reports the traffic
+// of each VPC with attachments on the agent.
+type Heartbeat struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Usage         []*VPCUsage            `protobuf:"bytes,1,rep,name=usage,proto3" json:"usage,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Heartbeat) Reset() {
+	*x = Heartbeat{}
+	mi := &file_remote_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Heartbeat) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Heartbeat) ProtoMessage() {}
+
+func (x *Heartbeat) ProtoReflect() protoreflect.Message {
+	mi := &file_remote_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Heartbeat.ProtoReflect.Descriptor instead.
+func (*Heartbeat) Descriptor() ([]byte, []int) {
+	return file_remote_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *Heartbeat) GetUsage() []*VPCUsage {
+	if x != nil {
+		return x.Usage
+	}
+	return nil
+}
+
+// VPCUsage counts the traffic crossing the host interfaces of a VPC's
+// attachments since the agent started: sent is what workloads sent into the
+// VPC, received what the VPC delivered to them. The rates are per second
+// over the last usage_interval.
+type VPCUsage struct {
+	state                  protoimpl.MessageState `protogen:"open.v1"`
+	Vpc                    string                 `protobuf:"bytes,1,opt,name=vpc,proto3" json:"vpc,omitempty"`
+	SentBytes              uint64                 `protobuf:"varint,2,opt,name=sent_bytes,json=sentBytes,proto3" json:"sent_bytes,omitempty"`
+	SentPackets            uint64                 `protobuf:"varint,3,opt,name=sent_packets,json=sentPackets,proto3" json:"sent_packets,omitempty"`
+	ReceivedBytes          uint64                 `protobuf:"varint,4,opt,name=received_bytes,json=receivedBytes,proto3" json:"received_bytes,omitempty"`
+	ReceivedPackets        uint64                 `protobuf:"varint,5,opt,name=received_packets,json=receivedPackets,proto3" json:"received_packets,omitempty"`
+	SentBytesPerSecond     float64                `protobuf:"fixed64,6,opt,name=sent_bytes_per_second,json=sentBytesPerSecond,proto3" json:"sent_bytes_per_second,omitempty"`
+	ReceivedBytesPerSecond float64                `protobuf:"fixed64,7,opt,name=received_bytes_per_second,json=receivedBytesPerSecond,proto3" json:"received_bytes_per_second,omitempty"`
+	unknownFields          protoimpl.UnknownFields
+	sizeCache              protoimpl.SizeCache
+}
+
+func (x *VPCUsage) Reset() {
+	*x = VPCUsage{}
+	mi := &file_remote_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *VPCUsage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VPCUsage) ProtoMessage() {}
+
+func (x *VPCUsage) ProtoReflect() protoreflect.Message {
+	mi := &file_remote_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VPCUsage.ProtoReflect.Descriptor instead.
+func (*VPCUsage) Descriptor() ([]byte, []int) {
+	return file_remote_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *VPCUsage) GetVpc() string {
+	if x != nil {
+		return x.Vpc
+	}
+	return ""
+}
+
+func (x *VPCUsage) GetSentBytes() uint64 {
+	if x != nil {
+		return x.SentBytes
+	}
+	return 0
+}
+
+func (x *VPCUsage) GetSentPackets() uint64 {
+	if x != nil {
+		return x.SentPackets
+	}
+	return 0
+}
+
+func (x *VPCUsage) GetReceivedBytes() uint64 {
+	if x != nil {
+		return x.ReceivedBytes
+	}
+	return 0
+}
+
+func (x *VPCUsage) GetReceivedPackets() uint64 {
+	if x != nil {
+		return x.ReceivedPackets
+	}
+	return 0
+}
+
+func (x *VPCUsage) GetSentBytesPerSecond() float64 {
+	if x != nil {
+		return x.SentBytesPerSecond
+	}
+	return 0
+}
+
+func (x *VPCUsage) GetReceivedBytesPerSecond() float64 {
+	if x != nil {
+		return x.ReceivedBytesPerSecond
+	}
+	return 0
+}
+
+// Credentials replace the agent's broker credentials; empty fields keep
+// the current value.
+type Credentials struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Username string                 `protobuf:"bytes,1,opt,name=username,proto3" json:"username,omitempty"`
+	Password string                 `protobuf:"bytes,2,opt,name=password,proto3" json:"password,omitempty"`
+	// PEM client certificate chain and key, and the CA the broker's
+	// certificate must chain to.
+	CertPem       []byte `protobuf:"bytes,3,opt,name=cert_pem,json=certPem,proto3" json:"cert_pem,omitempty"`
+	KeyPem        []byte `protobuf:"bytes,4,opt,name=key_pem,json=keyPem,proto3" json:"key_pem,omitempty"`
+	CaPem         []byte `protobuf:"bytes,5,opt,name=ca_pem,json=caPem,proto3" json:"ca_pem,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Credentials) Reset() {
+	*x = Credentials{}
+	mi := &file_remote_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Credentials) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Credentials) ProtoMessage() {}
+
+func (x *Credentials) ProtoReflect() protoreflect.Message {
+	mi := &file_remote_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Credentials.ProtoReflect.Descriptor instead.
+func (*Credentials) Descriptor() ([]byte, []int) {
+	return file_remote_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *Credentials) GetUsername() string {
+	if x != nil {
+		return x.Username
+	}
+	return ""
+}
+
+func (x *Credentials) GetPassword() string {
+	if x != nil {
+		return x.Password
+	}
+	return ""
+}
+
+func (x *Credentials) GetCertPem() []byte {
+	if x != nil {
+		return x.CertPem
+	}
+	return nil
+}
+
+func (x *Credentials) GetKeyPem() []byte {
+	if x != nil {
+		return x.KeyPem
+	}
+	return nil
+}
+
+func (x *Credentials) GetCaPem() []byte {
+	if x != nil {
+		return x.CaPem
+	}
+	return nil
+}
+
+// Nack is sent by an agent for a received envelope it refused to apply.
+type Nack struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Reason Nack_Reason            `protobuf:"varint,1,opt,name=reason,proto3,enum=remote.v1.Nack_Reason" json:"reason,omitempty"`
+	Detail string                 `protobuf:"bytes,2,opt,name=detail,proto3" json:"detail,omitempty"`
+	// route is the refused route, if the envelope carried one
+	Route         *Route `protobuf:"bytes,3,opt,name=route,proto3" json:"route,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Nack) Reset() {
+	*x = Nack{}
+	mi := &file_remote_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Nack) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Nack) ProtoMessage() {}
+
+func (x *Nack) ProtoReflect() protoreflect.Message {
+	mi := &file_remote_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Nack.ProtoReflect.Descriptor instead.
+func (*Nack) Descriptor() ([]byte, []int) {
+	return file_remote_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *Nack) GetReason() Nack_Reason {
+	if x != nil {
+		return x.Reason
+	}
+	return Nack_UNSPECIFIED
+}
+
+func (x *Nack) GetDetail() string {
+	if x != nil {
+		return x.Detail
+	}
+	return ""
+}
+
+func (x *Nack) GetRoute() *Route {
+	if x != nil {
+		return x.Route
+	}
+	return nil
+}
+
+var File_remote_proto protoreflect.FileDescriptor
+
+const file_remote_proto_rawDesc = "" +
+	"\n" +
+	"\fremote.proto\x12\tremote.v1\"\xe6\x05\n" +
+	"\bEnvelope\x121\n" +
+	"\bregister\x18\x01 \x01(\v2\x13.remote.v1.RegisterH\x00R\bregister\x127\n" +
+	"\n" +
+	"deregister\x18\x02 \x01(\v2\x15.remote.v1.DeregisterH\x00R\n" +
+	"deregister\x12(\n" +
+	"\x05route\x18\x03 \x01(\v2\x10.remote.v1.RouteH\x00R\x05route\x12%\n" +
+	"\x04nack\x18\x04 \x01(\v2\x0f.remote.v1.NackH\x00R\x04nack\x12J\n" +
+	"\x11credential_rotate\x18\x05 \x01(\v2\x1b.remote.v1.CredentialRotateH\x00R\x10credentialRotate\x12,\n" +
+	"\aset_mtu\x18\x06 \x01(\v2\x11.remote.v1.SetMTUH\x00R\x06setMtu\x124\n" +
+	"\theartbeat\x18\a \x01(\v2\x14.remote.v1.HeartbeatH\x00R\theartbeat\x128\n" +
+	"\vroute_batch\x18\b \x01(\v2\x15.remote.v1.RouteBatchH\x00R\n" +
+	"routeBatch\x12A\n" +
+	"\x0ebatch_progress\x18\t \x01(\v2\x18.remote.v1.BatchProgressH\x00R\rbatchProgress\x12>\n" +
+	"\rroute_changes\x18\n" +
+	" \x01(\v2\x17.remote.v1.RouteChangesH\x00R\frouteChanges\x128\n" +
+	"\vagent_hello\x18\v \x01(\v2\x15.remote.v1.AgentHelloH\x00R\n" +
+	"agentHello\x12$\n" +
+	"\x0esent_unix_nano\x18\f \x01(\x03R\fsentUnixNano\x12\x14\n" +
+	"\x05nonce\x18\r \x01(\fR\x05nonce\x122\n" +
+	"\tsignature\x18\x0f \x01(\v2\x14.remote.v1.SignatureR\tsignatureB\x06\n" +
+	"\x04kind\"r\n" +
+	"\tSignature\x12\x1b\n" +
+	"\tspiffe_id\x18\x01 \x01(\tR\bspiffeId\x12\x1b\n" +
+	"\tx509_svid\x18\x02 \x03(\fR\bx509Svid\x12\x14\n" +
+	"\x05value\x18\x03 \x01(\fR\x05value\x12\x15\n" +
+	"\x06key_id\x18\x04 \x01(\tR\x05keyId\"c\n" +
+	"\bRegister\x12\x18\n" +
+	"\anetwork\x18\x01 \x01(\tR\anetwork\x12#\n" +
+	"\rsrv6_endpoint\x18\x02 \x01(\tR\fsrv6Endpoint\x12\x18\n" +
+	"\aanycast\x18\x03 \x01(\bR\aanycast\"K\n" +
+	"\n" +
+	"Deregister\x12\x18\n" +
+	"\anetwork\x18\x01 \x01(\tR\anetwork\x12#\n" +
+	"\rsrv6_endpoint\x18\x02 \x01(\tR\fsrv6Endpoint\"\xcf\x01\n" +
+	"\x05Route\x12\x18\n" +
+	"\anetwork\x18\x01 \x01(\tR\anetwork\x12#\n" +
+	"\rsrv6_endpoint\x18\x02 \x01(\tR\fsrv6Endpoint\x12#\n" +
+	"\rsrv6_segments\x18\x03 \x03(\tR\fsrv6Segments\x12/\n" +
+	"\x06status\x18\x04 \x01(\x0e2\x17.remote.v1.Route.StatusR\x06status\x12\x12\n" +
+	"\x04dscp\x18\x05 \x01(\rR\x04dscp\"\x1d\n" +
+	"\x06Status\x12\a\n" +
+	"\x03ADD\x10\x00\x12\n" +
+	"\n" +
+	"\x06DELETE\x10\x01\"^\n" +
+	"\n" +
+	"RouteBatch\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x16\n" +
+	"\x06resync\x18\x02 \x01(\bR\x06resync\x12(\n" +
+	"\x06routes\x18\x03 \x03(\v2\x10.remote.v1.RouteR\x06routes\"\x81\x01\n" +
+	"\rBatchProgress\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x18\n" +
+	"\aapplied\x18\x02 \x01(\x04R\aapplied\x12\x18\n" +
+	"\arefused\x18\x03 \x01(\x04R\arefused\x12\x18\n" +
+	"\adeleted\x18\x04 \x01(\x04R\adeleted\x12\x12\n" +
+	"\x04done\x18\x05 \x01(\bR\x04done\"@\n" +
+	"\fRouteChanges\x120\n" +
+	"\achanges\x18\x01 \x03(\v2\x16.remote.v1.RouteChangeR\achanges\"\xe3\x02\n" +
+	"\vRouteChange\x12)\n" +
+	"\x02op\x18\x01 \x01(\x0e2\x19.remote.v1.RouteChange.OpR\x02op\x12#\n" +
+	"\rsrv6_endpoint\x18\x02 \x01(\tR\fsrv6Endpoint\x12\x14\n" +
+	"\x05table\x18\x03 \x01(\rR\x05table\x12\x18\n" +
+	"\anetwork\x18\x04 \x01(\tR\anetwork\x12#\n" +
+	"\rsrv6_segments\x18\x05 \x03(\tR\fsrv6Segments\x12\x16\n" +
+	"\x06device\x18\x06 \x01(\tR\x06device\x12\x18\n" +
+	"\agateway\x18\a \x01(\tR\agateway\x12\x1a\n" +
+	"\bprotocol\x18\b \x01(\rR\bprotocol\x12\x18\n" +
+	"\adesired\x18\t \x01(\bR\adesired\x12,\n" +
+	"\x12observed_unix_nano\x18\n" +
+	" \x01(\x03R\x10observedUnixNano\"\x19\n" +
+	"\x02Op\x12\a\n" +
+	"\x03ADD\x10\x00\x12\n" +
+	"\n" +
+	"\x06DELETE\x10\x01\"\xac\x01\n" +
+	"\n" +
+	"AgentHello\x12\x19\n" +
+	"\bagent_id\x18\x01 \x01(\tR\aagentId\x12\x1a\n" +
+	"\bhostname\x18\x02 \x01(\tR\bhostname\x12\x19\n" +
+	"\bsrv6_net\x18\x03 \x01(\tR\asrv6Net\x12\x1a\n" +
+	"\bfeatures\x18\x04 \x03(\tR\bfeatures\x12\x16\n" +
+	"\x06schema\x18\x05 \x01(\tR\x06schema\x12\x18\n" +
+	"\aversion\x18\x06 \x01(\tR\aversion\"*\n" +
+	"\x10CredentialRotate\x12\x16\n" +
+	"\x06sealed\x18\x01 \x01(\fR\x06sealed\"?\n" +
+	"\x06SetMTU\x12#\n" +
+	"\rsrv6_endpoint\x18\x01 \x01(\tR\fsrv6Endpoint\x12\x10\n" +
+	"\x03mtu\x18\x02 \x01(\rR\x03mtu\"6\n" +
+	"\tHeartbeat\x12)\n" +
+	"\x05usage\x18\x01 \x03(\v2\x13.remote.v1.VPCUsageR\x05usage\"\x9e\x02\n" +
+	"\bVPCUsage\x12\x10\n" +
+	"\x03vpc\x18\x01 \x01(\tR\x03vpc\x12\x1d\n" +
 	"\n" +
-	"\x06DELETE\x10\x01B9Z7github.com/datum-cloud/galactic-agent/api/remote;remoteb\x06proto3"
+	"sent_bytes\x18\x02 \x01(\x04R\tsentBytes\x12!\n" +
+	"\fsent_packets\x18\x03 \x01(\x04R\vsentPackets\x12%\n" +
+	"\x0ereceived_bytes\x18\x04 \x01(\x04R\rreceivedBytes\x12)\n" +
+	"\x10received_packets\x18\x05 \x01(\x04R\x0freceivedPackets\x121\n" +
+	"\x15sent_bytes_per_second\x18\x06 \x01(\x01R\x12sentBytesPerSecond\x129\n" +
+	"\x19received_bytes_per_second\x18\a \x01(\x01R\x16receivedBytesPerSecond\"\x90\x01\n" +
+	"\vCredentials\x12\x1a\n" +
+	"\busername\x18\x01 \x01(\tR\busername\x12\x1a\n" +
+	"\bpassword\x18\x02 \x01(\tR\bpassword\x12\x19\n" +
+	"\bcert_pem\x18\x03 \x01(\fR\acertPem\x12\x17\n" +
+	"\akey_pem\x18\x04 \x01(\fR\x06keyPem\x12\x15\n" +
+	"\x06ca_pem\x18\x05 \x01(\fR\x05caPem\"\xfa\x01\n" +
+	"\x04Nack\x12.\n" +
+	"\x06reason\x18\x01 \x01(\x0e2\x16.remote.v1.Nack.ReasonR\x06reason\x12\x16\n" +
+	"\x06detail\x18\x02 \x01(\tR\x06detail\x12&\n" +
+	"\x05route\x18\x03 \x01(\v2\x10.remote.v1.RouteR\x05route\"\x81\x01\n" +
+	"\x06Reason\x12\x0f\n" +
+	"\vUNSPECIFIED\x10\x00\x12\x10\n" +
+	"\fRATE_LIMITED\x10\x01\x12\x12\n" +
+	"\x0eQUOTA_EXCEEDED\x10\x02\x12\x13\n" +
+	"\x0fPREFIX_REJECTED\x10\x03\x12\x14\n" +
+	"\x10SEGMENT_REJECTED\x10\x04\x12\x15\n" +
+	"\x11SEGMENTS_EXCEEDED\x10\x05B9Z7github.com/datum-cloud/galactic-agent/api/remote;remoteb\x06proto3"
 
 var (
 	file_remote_proto_rawDescOnce sync.Once
@@ -378,25 +1624,54 @@ func file_remote_proto_rawDescGZIP() []byte {
 	return file_remote_proto_rawDescData
 }
 
-var file_remote_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
-var file_remote_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_remote_proto_enumTypes = make([]protoimpl.EnumInfo, 3)
+var file_remote_proto_msgTypes = make([]protoimpl.MessageInfo, 16)
 var file_remote_proto_goTypes = []any{
-	(Route_Status)(0),  // 0: remote.v1.Route.Status
-	(*Envelope)(nil),   // 1: remote.v1.Envelope
-	(*Register)(nil),   // 2: remote.v1.Register
-	(*Deregister)(nil), // 3: remote.v1.Deregister
-	(*Route)(nil),      // 4: remote.v1.Route
+	(Route_Status)(0),        // 0: remote.v1.Route.Status
+	(RouteChange_Op)(0),      // 1: remote.v1.RouteChange.Op
+	(Nack_Reason)(0),         // 2: remote.v1.Nack.Reason
+	(*Envelope)(nil),         // 3: remote.v1.Envelope
+	(*Signature)(nil),        // 4: remote.v1.Signature
+	(*Register)(nil),         // 5: remote.v1.Register
+	(*Deregister)(nil),       // 6: remote.v1.Deregister
+	(*Route)(nil),            // 7: remote.v1.Route
+	(*RouteBatch)(nil),       // 8: remote.v1.RouteBatch
+	(*BatchProgress)(nil),    // 9: remote.v1.BatchProgress
+	(*RouteChanges)(nil),     // 10: remote.v1.RouteChanges
+	(*RouteChange)(nil),      // 11: remote.v1.RouteChange
+	(*AgentHello)(nil),       // 12: remote.v1.AgentHello
+	(*CredentialRotate)(nil), // 13: remote.v1.CredentialRotate
+	(*SetMTU)(nil),           // 14: remote.v1.SetMTU
+	(*Heartbeat)(nil),        // 15: remote.v1.Heartbeat
+	(*VPCUsage)(nil),         // 16: remote.v1.VPCUsage
+	(*Credentials)(nil),      // 17: remote.v1.Credentials
+	(*Nack)(nil),             // 18: remote.v1.Nack
 }
 var file_remote_proto_depIdxs = []int32{
-	2, // 0: remote.v1.Envelope.register:type_name -> remote.v1.Register
-	3, // 1: remote.v1.Envelope.deregister:type_name -> remote.v1.Deregister
-	4, // 2: remote.v1.Envelope.route:type_name -> remote.v1.Route
-	0, // 3: remote.v1.Route.status:type_name -> remote.v1.Route.Status
-	4, // [4:4] is the sub-list for method output_type
-	4, // [4:4] is the sub-list for method input_type
-	4, // [4:4] is the sub-list for extension type_name
-	4, // [4:4] is the sub-list for extension extendee
-	0, // [0:4] is the sub-list for field type_name
+	5,  // 0: remote.v1.Envelope.register:type_name -> remote.v1.Register
+	6,  // 1: remote.v1.Envelope.deregister:type_name -> remote.v1.Deregister
+	7,  // 2: remote.v1.Envelope.route:type_name -> remote.v1.Route
+	18, // 3: remote.v1.Envelope.nack:type_name -> remote.v1.Nack
+	13, // 4: remote.v1.Envelope.credential_rotate:type_name -> remote.v1.CredentialRotate
+	14, // 5: remote.v1.Envelope.set_mtu:type_name -> remote.v1.SetMTU
+	15, // 6: remote.v1.Envelope.heartbeat:type_name -> remote.v1.Heartbeat
+	8,  // 7: remote.v1.Envelope.route_batch:type_name -> remote.v1.RouteBatch
+	9,  // 8: remote.v1.Envelope.batch_progress:type_name -> remote.v1.BatchProgress
+	10, // 9: remote.v1.Envelope.route_changes:type_name -> remote.v1.RouteChanges
+	12, // 10: remote.v1.Envelope.agent_hello:type_name -> remote.v1.AgentHello
+	4,  // 11: remote.v1.Envelope.signature:type_name -> remote.v1.Signature
+	0,  // 12: remote.v1.Route.status:type_name -> remote.v1.Route.Status
+	7,  // 13: remote.v1.RouteBatch.routes:type_name -> remote.v1.Route
+	11, // 14: remote.v1.RouteChanges.changes:type_name -> remote.v1.RouteChange
+	1,  // 15: remote.v1.RouteChange.op:type_name -> remote.v1.RouteChange.Op
+	16, // 16: remote.v1.Heartbeat.usage:type_name -> remote.v1.VPCUsage
+	2,  // 17: remote.v1.Nack.reason:type_name -> remote.v1.Nack.Reason
+	7,  // 18: remote.v1.Nack.route:type_name -> remote.v1.Route
+	19, // [19:19] is the sub-list for method output_type
+	19, // [19:19] is the sub-list for method input_type
+	19, // [19:19] is the sub-list for extension type_name
+	19, // [19:19] is the sub-list for extension extendee
+	0,  // [0:19] is the sub-list for field type_name
 }
 
 func init() { file_remote_proto_init() }
@@ -408,14 +1683,22 @@ func file_remote_proto_init() {
 		(*Envelope_Register)(nil),
 		(*Envelope_Deregister)(nil),
 		(*Envelope_Route)(nil),
+		(*Envelope_Nack)(nil),
+		(*Envelope_CredentialRotate)(nil),
+		(*Envelope_SetMtu)(nil),
+		(*Envelope_Heartbeat)(nil),
+		(*Envelope_RouteBatch)(nil),
+		(*Envelope_BatchProgress)(nil),
+		(*Envelope_RouteChanges)(nil),
+		(*Envelope_AgentHello)(nil),
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_remote_proto_rawDesc), len(file_remote_proto_rawDesc)),
-			NumEnums:      1,
-			NumMessages:   4,
+			NumEnums:      3,
+			NumMessages:   16,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
@@ -0,0 +1,105 @@
+package remote
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// SchemaVersion is a short fingerprint of the remote.proto descriptor compiled
+// into this binary. Two builds speak the same envelope schema if and only if
+// their fingerprints match.
+var SchemaVersion = schemaFingerprint()
+
+func schemaFingerprint() string {
+	// package variables are initialized before the generated init functions
+	// run, so the descriptor has to be built here
+	file_remote_proto_init()
+	b, err := proto.MarshalOptions{Deterministic: true}.Marshal(protodesc.ToFileDescriptorProto(File_remote_proto))
+	if err != nil {
+		return "unknown"
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:6])
+}
+
+// Skew describes a part of a received message that this binary's schema does
+// not know about, typically because the sender was built from a newer proto.
+type Skew struct {
+	Path   string
+	Reason string
+}
+
+func (s Skew) String() string {
+	return fmt.Sprintf("%s: %s", s.Path, s.Reason)
+}
+
+const (
+	SkewUnknownField     = "unknown field"
+	SkewUnknownEnumValue = "unknown enum value"
+	SkewUnknownKind      = "unknown envelope kind"
+	SkewMalformed        = "malformed unknown fields"
+)
+
+// CheckSkew walks m and reports unknown fields and enum values. An Envelope
+// whose kind is not set but that carries unknown fields is reported as an
+// unknown kind: it is a oneof member added by a newer controller.
+func CheckSkew(m proto.Message) []Skew {
+	var skews []Skew
+	msg := m.ProtoReflect()
+	walk(msg, string(msg.Descriptor().Name()), &skews)
+	if env, ok := m.(*Envelope); ok && env.Kind == nil && len(msg.GetUnknown()) > 0 {
+		skews = append(skews, Skew{Path: "Envelope.kind", Reason: SkewUnknownKind})
+	}
+	return skews
+}
+
+func walk(m protoreflect.Message, path string, skews *[]Skew) {
+	for b := m.GetUnknown(); len(b) > 0; {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			*skews = append(*skews, Skew{Path: path, Reason: SkewMalformed})
+			return
+		}
+		b = b[n:]
+		n = protowire.ConsumeFieldValue(num, typ, b)
+		if n < 0 {
+			*skews = append(*skews, Skew{Path: path, Reason: SkewMalformed})
+			return
+		}
+		b = b[n:]
+		*skews = append(*skews, Skew{Path: fmt.Sprintf("%s.%d", path, num), Reason: SkewUnknownField})
+	}
+
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		p := path + "." + string(fd.Name())
+		switch {
+		case fd.IsMap():
+			// no maps in the envelope schema
+		case fd.IsList():
+			list := v.List()
+			for i := 0; i < list.Len(); i++ {
+				check(fd, list.Get(i), fmt.Sprintf("%s[%d]", p, i), skews)
+			}
+		default:
+			check(fd, v, p, skews)
+		}
+		return true
+	})
+}
+
+func check(fd protoreflect.FieldDescriptor, v protoreflect.Value, path string, skews *[]Skew) {
+	switch fd.Kind() {
+	case protoreflect.EnumKind:
+		if fd.Enum().Values().ByNumber(v.Enum()) == nil {
+			*skews = append(*skews, Skew{Path: fmt.Sprintf("%s=%d", path, v.Enum()), Reason: SkewUnknownEnumValue})
+		}
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		walk(v.Message(), path, skews)
+	}
+}
@@ -0,0 +1,115 @@
+package remote
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"errors"
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// ErrUnsigned is returned by Verify for envelopes without a signature.
+var ErrUnsigned = errors.New("envelope is not signed")
+
+// signedBytes is what a signature covers: the deterministic encoding of the
+// envelope with its signature unset.
+func signedBytes(envelope *Envelope) ([]byte, error) {
+	unsigned := proto.Clone(envelope).(*Envelope)
+	unsigned.Signature = nil
+	return proto.MarshalOptions{Deterministic: true}.Marshal(unsigned)
+}
+
+// Sign signs envelope as spiffeID. chain is the DER certificate chain of
+// the signer, leaf first, and key the private key of the leaf.
+func Sign(envelope *Envelope, spiffeID string, chain [][]byte, key crypto.Signer) error {
+	value, err := sign(envelope, key)
+	if err != nil {
+		return err
+	}
+	envelope.Signature = &Signature{SpiffeId: spiffeID, X509Svid: chain, Value: value}
+	return nil
+}
+
+func sign(envelope *Envelope, key crypto.Signer) ([]byte, error) {
+	b, err := signedBytes(envelope)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := key.Public().(ed25519.PublicKey); ok {
+		return key.Sign(rand.Reader, b, crypto.Hash(0))
+	}
+	digest := sha256.Sum256(b)
+	return key.Sign(rand.Reader, digest[:], crypto.SHA256)
+}
+
+// Verify checks that envelope is signed by a certificate chaining to roots
+// whose URI SAN is the claimed SPIFFE ID, and returns that ID.
+func Verify(envelope *Envelope, roots *x509.CertPool) (string, error) {
+	sig := envelope.GetSignature()
+	if sig == nil {
+		return "", ErrUnsigned
+	}
+	if len(sig.X509Svid) == 0 {
+		return "", errors.New("signature has no certificate")
+	}
+	leaf, err := x509.ParseCertificate(sig.X509Svid[0])
+	if err != nil {
+		return "", fmt.Errorf("signer certificate: %w", err)
+	}
+	intermediates := x509.NewCertPool()
+	for _, der := range sig.X509Svid[1:] {
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return "", fmt.Errorf("signer chain: %w", err)
+		}
+		intermediates.AddCert(cert)
+	}
+	if _, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}); err != nil {
+		return "", fmt.Errorf("signer certificate: %w", err)
+	}
+	if len(leaf.URIs) != 1 || leaf.URIs[0].String() != sig.SpiffeId {
+		return "", fmt.Errorf("signer certificate is not for %s", sig.SpiffeId)
+	}
+
+	if err := verify(envelope, leaf.PublicKey, sig.Value); err != nil {
+		return "", err
+	}
+	return sig.SpiffeId, nil
+}
+
+func verify(envelope *Envelope, key crypto.PublicKey, value []byte) error {
+	b, err := signedBytes(envelope)
+	if err != nil {
+		return err
+	}
+	return verifyBytes(b, key, value)
+}
+
+func verifyBytes(b []byte, key crypto.PublicKey, value []byte) error {
+	digest := sha256.Sum256(b)
+	var ok bool
+	switch pub := key.(type) {
+	case *ecdsa.PublicKey:
+		ok = ecdsa.VerifyASN1(pub, digest[:], value)
+	case *rsa.PublicKey:
+		ok = rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], value) == nil
+	case ed25519.PublicKey:
+		ok = ed25519.Verify(pub, b, value)
+	default:
+		return fmt.Errorf("unsupported signer key %T", pub)
+	}
+	if !ok {
+		return errors.New("signature mismatch")
+	}
+	return nil
+}
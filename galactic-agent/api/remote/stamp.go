@@ -0,0 +1,24 @@
+package remote
+
+import (
+	"crypto/rand"
+	"time"
+)
+
+// NonceSize is the length of the nonce Stamp sets.
+const NonceSize = 16
+
+// Stamp sets the send time and a random nonce of envelope, unless it
+// already has a nonce. It must happen before signing.
+func Stamp(envelope *Envelope) error {
+	if len(envelope.Nonce) > 0 {
+		return nil
+	}
+	nonce := make([]byte, NonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	envelope.SentUnixNano = time.Now().UnixNano()
+	envelope.Nonce = nonce
+	return nil
+}
@@ -0,0 +1,67 @@
+package remote
+
+import (
+	"context"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// Transport carries envelopes between an agent and the control plane.
+// Remote is the MQTT implementation; Loopback connects two in-process peers.
+type Transport interface {
+	Run(ctx context.Context) error
+	SendEnvelope(ctx context.Context, envelope *Envelope) error
+}
+
+var (
+	_ Transport = (*Remote)(nil)
+	_ Transport = (*Loopback)(nil)
+)
+
+// Loopback is one end of an in-memory Transport pair. Envelopes are
+// marshalled on send and handed to the peer's ReceiveHandler from the peer's
+// Run loop, so handlers see the same bytes they would over MQTT.
+type Loopback struct {
+	ReceiveHandler func([]byte) error
+
+	in  chan []byte
+	out chan []byte
+}
+
+// NewLoopback returns two connected ends. Up to buffer envelopes may be
+// in flight in each direction before SendEnvelope blocks.
+func NewLoopback(buffer int) (*Loopback, *Loopback) {
+	a := make(chan []byte, buffer)
+	b := make(chan []byte, buffer)
+	return &Loopback{in: a, out: b}, &Loopback{in: b, out: a}
+}
+
+// Run delivers received envelopes to ReceiveHandler until ctx is done.
+func (l *Loopback) Run(ctx context.Context) error {
+	for {
+		select {
+		case payload := <-l.in:
+			if l.ReceiveHandler == nil {
+				continue
+			}
+			if err := l.ReceiveHandler(payload); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func (l *Loopback) SendEnvelope(ctx context.Context, envelope *Envelope) error {
+	payload, err := proto.Marshal(envelope)
+	if err != nil {
+		return err
+	}
+	select {
+	case l.out <- payload:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/datum-cloud/galactic-agent/apiload"
+)
+
+func newAPILoadCmd() *cobra.Command {
+	var c apiload.Config
+	cmd := &cobra.Command{
+		Use:   "apiload",
+		Short: "Hammer the local API with concurrent calls for overlapping attachments",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+			defer stop()
+
+			if c.SocketPath == "" {
+				c.SocketPath = viper.GetString("socket_path")
+			}
+			res, err := apiload.Run(ctx, c)
+			if err != nil {
+				return err
+			}
+			res.Write(os.Stdout)
+			if n := res.Report.Errors(); n > 0 {
+				return fmt.Errorf("%d calls failed", n)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&c.SocketPath, "socket", "", "agent socket (default: socket_path)")
+	cmd.Flags().StringVar(&c.VPC, "vpc", "00000000f00d", "vpc id (hex) for the attachments")
+	cmd.Flags().IntVar(&c.Attachments, "attachments", 4, "number of attachments calls are spread over")
+	cmd.Flags().IntVar(&c.Networks, "networks", 8, "networks per attachment to choose from (max 256)")
+	cmd.Flags().IntVar(&c.Workers, "workers", 16, "concurrent callers")
+	cmd.Flags().IntVar(&c.Requests, "requests", 10000, "total calls to make (0 to run for --duration)")
+	cmd.Flags().DurationVar(&c.Duration, "duration", 0, "run for this long instead of a fixed number of calls")
+	cmd.Flags().Int64Var(&c.Seed, "seed", 1, "seed for the call sequence")
+	cmd.Flags().BoolVar(&c.Provision, "provision", true, "create and destroy VRFs and interfaces for the attachments")
+	return cmd
+}
@@ -0,0 +1,217 @@
+package apiload
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc/status"
+
+	"github.com/datum-cloud/galactic-agent/client"
+	"github.com/datum-cloud/galactic-agent/fixtures"
+	"github.com/datum-cloud/galactic-agent/latency"
+)
+
+// Config describes a contention run: Workers goroutines issue Requests calls
+// (or keep going for Duration) against a small pool of Attachments, so that
+// calls for the same attachment regularly overlap.
+type Config struct {
+	SocketPath  string
+	VPC         string
+	Attachments int
+	Networks    int // pool of networks per attachment
+	Workers     int
+	Requests    int
+	Duration    time.Duration
+	Seed        int64
+	Provision   bool
+}
+
+// Result holds the latency report and a tally of error codes returned by the
+// agent.
+type Result struct {
+	Report *latency.Report
+
+	mu    sync.Mutex
+	codes map[string]int
+}
+
+func (r *Result) record(op string, d time.Duration, err error) {
+	r.Report.Record(op, d, err)
+	if err == nil {
+		return
+	}
+	r.mu.Lock()
+	r.codes[status.Code(err).String()]++
+	r.mu.Unlock()
+}
+
+func (r *Result) Write(w io.Writer) {
+	r.Report.Write(w)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.codes) == 0 {
+		return
+	}
+	codes := make([]string, 0, len(r.codes))
+	for c := range r.codes {
+		codes = append(codes, c)
+	}
+	sort.Strings(codes)
+	fmt.Fprintln(w) //nolint:errcheck
+	for _, c := range codes {
+		fmt.Fprintf(w, "%-16s %8d\n", c, r.codes[c]) //nolint:errcheck
+	}
+}
+
+func attachmentID(i int) string {
+	return fmt.Sprintf("%04x", i+1)
+}
+
+func network(i, j int) string {
+	return fmt.Sprintf("172.%d.%d.%d/32", 16+i%16, (i>>4)&0xff, j&0xff)
+}
+
+const (
+	opRegister   = "register"
+	opDeregister = "deregister"
+	// opUpdate re-registers an attachment with a different network set,
+	// which is how a CNI changes an attachment's networks today.
+	opUpdate = "update"
+)
+
+var ops = []string{opRegister, opDeregister, opUpdate}
+
+// Run drives the local API until Requests calls have been made or Duration
+// has passed, then deregisters every network it may have registered.
+func Run(ctx context.Context, c Config) (*Result, error) {
+	if c.Attachments <= 0 {
+		c.Attachments = 4
+	}
+	if c.Networks <= 0 {
+		c.Networks = 8
+	}
+	if c.Networks > 256 {
+		return nil, fmt.Errorf("at most 256 networks per attachment are supported")
+	}
+	if c.Workers <= 0 {
+		c.Workers = 16
+	}
+	if c.Requests <= 0 && c.Duration <= 0 {
+		c.Requests = 10000
+	}
+
+	// no retries: contention has to show up in the results
+	cl, err := client.New(c.SocketPath, client.WithRetries(0, 0))
+	if err != nil {
+		return nil, err
+	}
+	defer cl.Close() //nolint:errcheck
+
+	attachments := make([]fixtures.Attachment, c.Attachments)
+	for i := range attachments {
+		attachments[i] = fixtures.Attachment{VPC: c.VPC, VPCAttachment: attachmentID(i)}
+	}
+	if c.Provision {
+		if err := fixtures.EnsureLoopback(); err != nil {
+			return nil, err
+		}
+		for _, a := range attachments {
+			if err := fixtures.Create(a); err != nil {
+				return nil, fmt.Errorf("provision %s: %w", a.VPCAttachment, err)
+			}
+			defer func() {
+				if err := fixtures.Destroy(a); err != nil {
+					slog.Warn("apiload: destroy failed", "vpc", a.VPC, "vpcattachment", a.VPCAttachment, "err", err)
+				}
+			}()
+		}
+	}
+
+	res := &Result{Report: latency.NewReport(), codes: make(map[string]int)}
+	runCtx := ctx
+	if c.Duration > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(ctx, c.Duration)
+		defer cancel()
+	}
+
+	var (
+		mu        sync.Mutex
+		remaining = c.Requests
+	)
+	next := func() bool {
+		if runCtx.Err() != nil {
+			return false
+		}
+		if c.Requests <= 0 {
+			return true
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if remaining == 0 {
+			return false
+		}
+		remaining--
+		return true
+	}
+
+	g := new(errgroup.Group)
+	for w := 0; w < c.Workers; w++ {
+		// each worker has its own source so runs are reproducible per seed
+		rng := rand.New(rand.NewSource(c.Seed + int64(w)))
+		g.Go(func() error {
+			for next() {
+				i := rng.Intn(len(attachments))
+				a := attachments[i]
+				op := ops[rng.Intn(len(ops))]
+				networks := pick(rng, i, c.Networks)
+
+				start := time.Now()
+				var err error
+				switch op {
+				case opRegister, opUpdate:
+					err = cl.Register(runCtx, a.VPC, a.VPCAttachment, networks...)
+				case opDeregister:
+					err = cl.Deregister(runCtx, a.VPC, a.VPCAttachment, networks...)
+				}
+				if runCtx.Err() != nil {
+					return nil
+				}
+				res.record(op, time.Since(start), err)
+			}
+			return nil
+		})
+	}
+	g.Wait() //nolint:errcheck
+
+	// clean up with a fresh context so an interrupted run leaves no state
+	cleanup, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	for i, a := range attachments {
+		all := make([]string, c.Networks)
+		for j := range all {
+			all[j] = network(i, j)
+		}
+		start := time.Now()
+		err := cl.Deregister(cleanup, a.VPC, a.VPCAttachment, all...)
+		res.record("cleanup", time.Since(start), err)
+	}
+	return res, nil
+}
+
+// pick returns a random non-empty subset of attachment i's network pool.
+func pick(rng *rand.Rand, i, pool int) []string {
+	n := 1 + rng.Intn(pool)
+	networks := make([]string, 0, n)
+	for _, j := range rng.Perm(pool)[:n] {
+		networks = append(networks, network(i, j))
+	}
+	return networks
+}
@@ -0,0 +1,107 @@
+// Package audit records every mutation of routing state for security teams
+// to ingest, independently of the agent's free-form log.
+package audit
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Actions recorded by the agent.
+const (
+	ActionRegister    = "register"
+	ActionDeregister  = "deregister"
+	ActionRouteAdd    = "route_add"
+	ActionRouteDelete = "route_delete"
+	ActionRefused     = "refused"
+	ActionSetMTU      = "set_mtu"
+	ActionNeighAdd    = "neigh_add"
+	ActionNeighDelete = "neigh_delete"
+)
+
+// ResultOK is the result field of an action that succeeded; that of one
+// that failed is its error.
+const ResultOK = "ok"
+
+// Event is one audited action. Field names are lower snake case.
+type Event struct {
+	Time   time.Time         `json:"time"`
+	Action string            `json:"action"`
+	Fields map[string]string `json:"fields"`
+}
+
+// Message renders e as one logfmt line, fields sorted by name.
+func (e Event) Message() string {
+	var b strings.Builder
+	b.WriteString("action=")
+	b.WriteString(e.Action)
+	for _, k := range e.keys() {
+		v := e.Fields[k]
+		if v == "" || strings.ContainsAny(v, " \t\"=") {
+			v = strconv.Quote(v)
+		}
+		fmt.Fprintf(&b, " %s=%s", k, v)
+	}
+	return b.String()
+}
+
+// Failed reports whether e was refused or failed, for sinks with a
+// severity.
+func (e Event) Failed() bool {
+	result, ok := e.Fields["result"]
+	return e.Action == ActionRefused || ok && result != ResultOK
+}
+
+func (e Event) keys() []string {
+	keys := make([]string, 0, len(e.Fields))
+	for k := range e.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Sink receives audit events.
+type Sink interface {
+	Emit(Event) error
+	Close() error
+}
+
+// Sinks fans events out to every sink. Its zero value discards events.
+type Sinks []Sink
+
+// Emit sends e to every sink; a failing sink doesn't stop the others.
+func (s Sinks) Emit(e Event) error {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	var errs []error
+	for _, sink := range s {
+		errs = append(errs, sink.Emit(e))
+	}
+	return errors.Join(errs...)
+}
+
+func (s Sinks) Close() error {
+	var errs []error
+	for _, sink := range s {
+		errs = append(errs, sink.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// Record emits an event and logs, rather than returns, a failure: auditing
+// must never block the dataplane.
+func (s Sinks) Record(action string, fields map[string]string) {
+	if len(s) == 0 {
+		return
+	}
+	if err := s.Emit(Event{Action: action, Fields: fields}); err != nil {
+		slog.Error("Audit", "err", err)
+	}
+}
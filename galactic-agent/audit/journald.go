@@ -0,0 +1,84 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// JournaldSocket is where journald accepts native protocol datagrams.
+const JournaldSocket = "/run/systemd/journal/socket"
+
+// Journald writes events with the native journal protocol, so each field
+// stays queryable, e.g. journalctl GALACTIC_ACTION=route_add. Fields are
+// prefixed GALACTIC_ and upper cased.
+type Journald struct {
+	conn       *net.UnixConn
+	identifier string
+}
+
+func NewJournald(identifier string) (*Journald, error) {
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: JournaldSocket, Net: "unixgram"})
+	if err != nil {
+		return nil, err
+	}
+	return &Journald{conn: conn, identifier: identifier}, nil
+}
+
+// journal priorities, as in syslog(3)
+const (
+	priorityWarning = 4
+	priorityNotice  = 5
+)
+
+func (j *Journald) Emit(e Event) error {
+	priority := priorityNotice
+	if e.Failed() {
+		priority = priorityWarning
+	}
+	var b bytes.Buffer
+	writeField(&b, "MESSAGE", e.Message())
+	writeField(&b, "PRIORITY", strconv.Itoa(priority))
+	writeField(&b, "SYSLOG_IDENTIFIER", j.identifier)
+	writeField(&b, "GALACTIC_ACTION", e.Action)
+	for _, k := range e.keys() {
+		writeField(&b, "GALACTIC_"+fieldName(k), e.Fields[k])
+	}
+	_, err := j.conn.Write(b.Bytes())
+	return err
+}
+
+func (j *Journald) Close() error {
+	return j.conn.Close()
+}
+
+// fieldName maps a field to the journal's [A-Z0-9_] alphabet.
+func fieldName(k string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+		return '_'
+	}, k)
+}
+
+// writeField appends one field; values containing a newline use the
+// length-prefixed form of the protocol.
+func writeField(b *bytes.Buffer, name, value string) {
+	b.WriteString(name)
+	if !strings.Contains(value, "\n") {
+		b.WriteByte('=')
+		b.WriteString(value)
+		b.WriteByte('\n')
+		return
+	}
+	b.WriteByte('\n')
+	_ = binary.Write(b, binary.LittleEndian, uint64(len(value)))
+	b.WriteString(value)
+	b.WriteByte('\n')
+}
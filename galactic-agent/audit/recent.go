@@ -0,0 +1,40 @@
+package audit
+
+import (
+	"slices"
+	"sync"
+)
+
+// Recent keeps the last events in memory, for the dashboard.
+type Recent struct {
+	mu     sync.Mutex
+	size   int
+	events []Event
+}
+
+func NewRecent(size int) *Recent {
+	return &Recent{size: size}
+}
+
+func (r *Recent) Emit(e Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.events) == r.size {
+		r.events = r.events[1:]
+	}
+	r.events = append(r.events, e)
+	return nil
+}
+
+func (r *Recent) Close() error {
+	return nil
+}
+
+// Events returns the kept events, newest first.
+func (r *Recent) Events() []Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	events := slices.Clone(r.events)
+	slices.Reverse(events)
+	return events
+}
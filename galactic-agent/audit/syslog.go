@@ -0,0 +1,30 @@
+package audit
+
+import (
+	"log/syslog"
+)
+
+// Syslog writes events as logfmt messages to a syslog daemon. An empty
+// network writes to the local daemon.
+type Syslog struct {
+	w *syslog.Writer
+}
+
+func NewSyslog(network, addr, tag string) (*Syslog, error) {
+	w, err := syslog.Dial(network, addr, syslog.LOG_NOTICE|syslog.LOG_AUTH, tag)
+	if err != nil {
+		return nil, err
+	}
+	return &Syslog{w: w}, nil
+}
+
+func (s *Syslog) Emit(e Event) error {
+	if e.Failed() {
+		return s.w.Warning(e.Message())
+	}
+	return s.w.Notice(e.Message())
+}
+
+func (s *Syslog) Close() error {
+	return s.w.Close()
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/viper"
+	"google.golang.org/grpc/peer"
+
+	"github.com/datum-cloud/galactic-agent/api/local"
+	"github.com/datum-cloud/galactic-agent/api/remote"
+	"github.com/datum-cloud/galactic-agent/audit"
+	"github.com/datum-cloud/galactic-agent/state"
+)
+
+// auditor receives every route mutation: the audit_sinks configured, and
+// the dashboard and WatchEvents feeds.
+var auditor audit.Sinks
+
+// openAudit opens the sinks named in audit_sinks.
+func openAudit() (audit.Sinks, error) {
+	var sinks audit.Sinks
+	for _, name := range viper.GetStringSlice("audit_sinks") {
+		var sink audit.Sink
+		var err error
+		switch name {
+		case "syslog":
+			sink, err = audit.NewSyslog(viper.GetString("audit_syslog_network"), viper.GetString("audit_syslog_address"), "galactic-agent")
+		case "journald":
+			sink, err = audit.NewJournald("galactic-agent")
+		case "file":
+			sink, err = audit.NewFile(viper.GetString("audit_file_path"), viper.GetInt64("audit_file_max_size_mb")<<20, viper.GetInt("audit_file_max_backups"))
+		default:
+			err = fmt.Errorf("unknown sink %q", name)
+		}
+		if err != nil {
+			_ = sinks.Close()
+			return nil, fmt.Errorf("audit sink %s: %w", name, err)
+		}
+		sinks = append(sinks, sink)
+	}
+	return sinks, nil
+}
+
+type auditSourceKey struct{}
+
+// withAuditSource returns ctx with source as where the changes made under
+// it came from.
+func withAuditSource(ctx context.Context, source string) context.Context {
+	return context.WithValue(ctx, auditSourceKey{}, source)
+}
+
+// auditSource names where the changes made under ctx came from: the MQTT
+// topic a route was received on, the local API caller, or the agent itself.
+func auditSource(ctx context.Context) string {
+	if source, ok := ctx.Value(auditSourceKey{}).(string); ok {
+		return source
+	}
+	if _, ok := peer.FromContext(ctx); ok {
+		return "grpc " + local.CallerFrom(ctx).String()
+	}
+	return "agent"
+}
+
+func auditResult(err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	return audit.ResultOK
+}
+
+// auditVRF returns the VRF of the attachment srv6Endpoint belongs to, empty
+// if it is not registered.
+func auditVRF(store *state.Store, srv6Endpoint string) string {
+	vpc, vpcAttachment, err := endpointAttachment(srv6Endpoint)
+	if err != nil {
+		return ""
+	}
+	a, _ := store.Attachment(vpc, vpcAttachment)
+	return a.VRF
+}
+
+func auditAttachment(ctx context.Context, action, vpc, vpcAttachment, srv6Endpoint, vrf string, networks []string, anycast bool, err error) {
+	for _, n := range networks {
+		auditor.Record(action, map[string]string{
+			"source":        auditSource(ctx),
+			"vpc":           vpc,
+			"vpcattachment": vpcAttachment,
+			"vrf":           vrf,
+			"network":       n,
+			"srv6_endpoint": srv6Endpoint,
+			"anycast":       strconv.FormatBool(anycast),
+			"result":        auditResult(err),
+		})
+	}
+}
+
+// auditRoute records a route change and, for a host route, the proxy
+// neighbor entry that goes with it.
+func auditRoute(ctx context.Context, d *domain, route *remote.Route, segments []string, err error) {
+	action, neigh := audit.ActionRouteAdd, audit.ActionNeighAdd
+	if route.Status == remote.Route_DELETE {
+		action, neigh = audit.ActionRouteDelete, audit.ActionNeighDelete
+	}
+	fields := map[string]string{
+		"source":        auditSource(ctx),
+		"tenant":        d.Name,
+		"vpc":           endpointVPC(route.Srv6Endpoint),
+		"vrf":           auditVRF(d.store, route.Srv6Endpoint),
+		"network":       route.Network,
+		"srv6_endpoint": route.Srv6Endpoint,
+		"srv6_segments": strings.Join(segments, ","),
+		"result":        auditResult(err),
+	}
+	auditor.Record(action, fields)
+	if state.IsHost(route.Network) {
+		delete(fields, "srv6_segments")
+		auditor.Record(neigh, fields)
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"runtime/debug"
+	"strconv"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/datum-cloud/galactic-agent/api/remote"
+	"github.com/datum-cloud/galactic-agent/metrics"
+)
+
+var (
+	routeBatches = metrics.NewCounter(
+		"galactic_agent_route_batches_total",
+		"RouteBatch envelopes applied, by tenant and whether they resynced the tenant's routes.",
+		"tenant", "resync",
+	)
+	routeBatchRoutes = metrics.NewCounter(
+		"galactic_agent_route_batch_routes_total",
+		"Routes of RouteBatch envelopes, by tenant and outcome: applied, refused, or deleted by a resync.",
+		"tenant", "outcome",
+	)
+)
+
+// applyBatch applies the routes of a RouteBatch a chunk at a time, so that
+// memory stays bounded whatever the size of the batch, and reports
+// progress to the controller after each chunk. accept runs the checks of a
+// route, counting the routes of the chunk accepted before it, and resolves
+// its segments. The accepted routes of a chunk are journaled together, then
+// applied and saved to the store at once. Refused routes are counted and the rest of the batch applied; a
+// batch that turns out malformed stops there.
+func applyBatch(ctx context.Context, d *domain, rb *remote.RouteBatch, batch *remote.BatchReader, chunk int, accept func(route *remote.Route, pending []*remote.Route) ([]string, error)) error {
+	// updates received before the batch must not land after it
+	if coalesce != nil {
+		coalesce.flush()
+	}
+	// a resync deletes what it does not list, so it has to remember what
+	// it listed
+	var listed map[string]bool
+	if rb.Resync {
+		listed = make(map[string]bool)
+	}
+	progress := &remote.BatchProgress{Id: rb.Id}
+	routes := make([]*remote.Route, chunk)
+	for i := range routes {
+		routes[i] = &remote.Route{}
+	}
+	accepted := make([]*remote.Route, 0, chunk)
+	segments := make([][]string, 0, chunk)
+	refuse := func(route *remote.Route, err error) {
+		slog.Warn("ROUTE BATCH: refused", append(endpointAttrs(route.Srv6Endpoint), "tenant", d.Name, "id", rb.Id, "status", route.Status.String(), "network", route.Network, "err", err)...)
+		progress.Refused++
+		routeBatchRoutes.Inc(d.Name, "refused")
+	}
+	for {
+		n, err := batch.Next(routes)
+		accepted, segments = accepted[:0], segments[:0]
+		for _, route := range routes[:n] {
+			for _, skew := range remote.CheckSkew(route) {
+				slog.Warn("SCHEMA SKEW", "tenant", d.Name, "skew", skew.String(), "local_schema", remote.SchemaVersion)
+				envelopeSkew.Inc(skew.Reason)
+			}
+			if listed != nil {
+				listed[route.Srv6Endpoint+" "+route.Network] = true
+			}
+			s, err := accept(route, accepted)
+			if err != nil {
+				refuse(route, err)
+				continue
+			}
+			accepted, segments = append(accepted, route), append(segments, s)
+		}
+		entries := make([]any, len(accepted))
+		for i, route := range accepted {
+			entries[i] = routeEntry(d, route, segments[i])
+		}
+		seqs := journalBeginAll(journalRoute, entries)
+		release := holdStores(d)
+		for i, route := range accepted {
+			if err := programRoute(ctx, d, route, segments[i]); err != nil {
+				refuse(route, err)
+				continue
+			}
+			progress.Applied++
+			routeBatchRoutes.Inc(d.Name, "applied")
+		}
+		release()
+		journalDoneAll(seqs)
+		if err != nil {
+			sendProgress(d, progress)
+			return err
+		}
+		if n == 0 {
+			break
+		}
+		sendProgress(d, progress)
+	}
+	if listed != nil {
+		var deletes []*remote.Route
+		var entries []any
+		for _, e := range d.store.Snapshot().Egress {
+			if listed[e.Endpoint+" "+e.Network] {
+				continue
+			}
+			route := &remote.Route{
+				Status:       remote.Route_DELETE,
+				Network:      e.Network,
+				Srv6Endpoint: e.Endpoint,
+				Srv6Segments: e.Segments,
+			}
+			deletes = append(deletes, route)
+			entries = append(entries, routeEntry(d, route, e.Segments))
+		}
+		seqs := journalBeginAll(journalRoute, entries)
+		release := holdStores(d)
+		for _, route := range deletes {
+			if err := programRoute(ctx, d, route, route.Srv6Segments); err != nil {
+				slog.Warn("ROUTE BATCH: resync delete", append(endpointAttrs(route.Srv6Endpoint), "tenant", d.Name, "id", rb.Id, "network", route.Network, "err", err)...)
+				continue
+			}
+			progress.Deleted++
+			routeBatchRoutes.Inc(d.Name, "deleted")
+		}
+		release()
+		journalDoneAll(seqs)
+	}
+	progress.Done = true
+	sendProgress(d, progress)
+	routeBatches.Inc(d.Name, strconv.FormatBool(rb.Resync))
+	slog.Info("ROUTE BATCH: done", "tenant", d.Name, "id", rb.Id, "applied", progress.Applied, "refused", progress.Refused, "deleted", progress.Deleted)
+	// hand the batch's garbage back to the OS rather than keep the peak
+	debug.FreeOSMemory()
+	return nil
+}
+
+// sendProgress sends a copy of p to d's controller in the background, like
+// nack; the counts are cumulative, so progress arriving out of order is
+// harmless.
+func sendProgress(d *domain, p *remote.BatchProgress) {
+	envelope := &remote.Envelope{Kind: &remote.Envelope_BatchProgress{BatchProgress: proto.Clone(p).(*remote.BatchProgress)}}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+		defer cancel()
+		tenantEnvelopes.Inc(d.Name, "send")
+		if err := d.remote.SendEnvelope(ctx, envelope); err != nil {
+			slog.Warn("BATCH PROGRESS", "tenant", d.Name, "id", p.Id, "err", err)
+		}
+	}()
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+
+	"github.com/datum-cloud/galactic-agent/bench"
+)
+
+// newBenchCmd runs the standardized scenarios. The throughput benchmarks
+// against a provisioned attachment are run with go test -bench, see the
+// bench package.
+func newBenchCmd() *cobra.Command {
+	var asJSON bool
+	cmd := &cobra.Command{
+		Use:   "bench",
+		Short: "Run the standardized route programming scenarios in a private namespace",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+			defer stop()
+
+			r, err := bench.Scenarios(ctx)
+			if err != nil {
+				return err
+			}
+			if asJSON {
+				if err := r.WriteJSON(os.Stdout); err != nil {
+					return err
+				}
+			} else {
+				r.Write(os.Stdout)
+			}
+			if r.Failed() {
+				return fmt.Errorf("one or more scenarios failed")
+			}
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&asJSON, "json", false, "print scenario timings as JSON")
+	return cmd
+}
@@ -0,0 +1,178 @@
+package bench
+
+import (
+	"flag"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/datum-cloud/galactic-agent/api/remote"
+	"github.com/datum-cloud/galactic-agent/srv6"
+	"github.com/datum-cloud/galactic-agent/srv6/endpoint"
+)
+
+// The route benchmarks run against a VPC attachment already provisioned
+// on the host (VRF, host interface and lo-galactic), the same as it would
+// be for a real workload, and are skipped unless one is given:
+//
+//	go test -bench . ./bench -args -srv6-net fc00::/64 -vpc 1 -vpcattachment 1
+//
+// BenchmarkReceiveLatency also needs a running agent subscribed to
+// -topic-rx on -mqtt-url.
+var (
+	srv6Net       = flag.String("srv6-net", "", "srv6_net of the agent")
+	vpc           = flag.String("vpc", "", "vpc id (hex) of a provisioned attachment")
+	vpcAttachment = flag.String("vpcattachment", "", "vpcattachment id (hex) of a provisioned attachment")
+	segments      = flag.String("segments", "fc00:0:0:1::1", "comma separated segment list for synthetic routes")
+	workers       = flag.Int("workers", 8, "workers for BenchmarkRouteEgressAddParallel")
+
+	mqttURL      = flag.String("mqtt-url", "", "broker of the agent under test")
+	mqttUsername = flag.String("mqtt-username", "", "broker username")
+	mqttPassword = flag.String("mqtt-password", "", "broker password")
+	mqttQoS      = flag.Int("mqtt-qos", 1, "QoS of the published routes")
+	topicRX      = flag.String("topic-rx", "", "receive topic of the agent under test")
+	timeout      = flag.Duration("route-timeout", 5*time.Second, "per-route timeout for BenchmarkReceiveLatency")
+)
+
+// attachment returns the endpoint and segments of the provisioned
+// attachment, skipping b when none is given.
+func attachment(b *testing.B) (string, []string) {
+	if *vpc == "" || *vpcAttachment == "" || *srv6Net == "" {
+		b.Skip("no provisioned attachment, see -srv6-net, -vpc and -vpcattachment")
+	}
+	ep, err := endpoint.Encode(*srv6Net, *vpc, *vpcAttachment)
+	if err != nil {
+		b.Fatalf("invalid endpoint: %v", err)
+	}
+	return ep, strings.Split(*segments, ",")
+}
+
+func cleanup(b *testing.B, ep string, segments []string, n int) {
+	b.StopTimer()
+	for i := 0; i < n && i < 1<<16; i++ {
+		_ = srv6.RouteEgressDel(prefix(i), ep, segments)
+	}
+}
+
+func reportRoutes(b *testing.B) {
+	b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "routes/s")
+}
+
+// BenchmarkRouteEgressAdd programs one route per iteration, serially.
+func BenchmarkRouteEgressAdd(b *testing.B) {
+	ep, segments := attachment(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := srv6.RouteEgressAdd(prefix(i%(1<<16)), ep, segments); err != nil {
+			b.Fatalf("route add failed: %v", err)
+		}
+	}
+	reportRoutes(b)
+	cleanup(b, ep, segments, b.N)
+}
+
+// BenchmarkRouteEgressAddParallel spreads the same work over -workers
+// workers. It is the baseline for batching and worker pool changes.
+func BenchmarkRouteEgressAddParallel(b *testing.B) {
+	ep, segments := attachment(b)
+	g := new(errgroup.Group)
+	g.SetLimit(max(1, *workers))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p := prefix(i % (1 << 16))
+		g.Go(func() error {
+			return srv6.RouteEgressAdd(p, ep, segments)
+		})
+	}
+	if err := g.Wait(); err != nil {
+		b.Fatalf("route add failed: %v", err)
+	}
+	reportRoutes(b)
+	cleanup(b, ep, segments, b.N)
+}
+
+// BenchmarkReceiveLatency publishes Route envelopes to the agent's receive
+// topic and measures the time until the route shows up in the kernel.
+func BenchmarkReceiveLatency(b *testing.B) {
+	ep, segments := attachment(b)
+	if *mqttURL == "" || *topicRX == "" {
+		b.Skip("no agent under test, see -mqtt-url and -topic-rx")
+	}
+
+	opts := mqtt.NewClientOptions().AddBroker(*mqttURL)
+	if *mqttUsername != "" {
+		opts.SetUsername(*mqttUsername)
+	}
+	if *mqttPassword != "" {
+		opts.SetPassword(*mqttPassword)
+	}
+	client := mqtt.NewClient(opts)
+	if tok := client.Connect(); tok.Wait() && tok.Error() != nil {
+		b.Fatalf("mqtt connect failed: %v", tok.Error())
+	}
+	defer client.Disconnect(250)
+
+	var mu sync.Mutex
+	waiting := make(map[string]chan struct{})
+	updates := make(chan netlink.RouteUpdate, 1024)
+	done := make(chan struct{})
+	defer close(done)
+	if err := netlink.RouteSubscribe(updates, done); err != nil {
+		b.Fatalf("route subscribe failed: %v", err)
+	}
+	go func() {
+		for u := range updates {
+			if u.Route.Dst == nil {
+				continue
+			}
+			mu.Lock()
+			if ch, ok := waiting[u.Route.Dst.String()]; ok {
+				close(ch)
+				delete(waiting, u.Route.Dst.String())
+			}
+			mu.Unlock()
+		}
+	}()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p := prefix(i % (1 << 16))
+		_, ipnet, _ := net.ParseCIDR(p)
+		ch := make(chan struct{})
+		mu.Lock()
+		waiting[ipnet.String()] = ch
+		mu.Unlock()
+
+		payload, err := proto.Marshal(&remote.Envelope{
+			Kind: &remote.Envelope_Route{
+				Route: &remote.Route{
+					Status:       remote.Route_ADD,
+					Network:      p,
+					Srv6Endpoint: ep,
+					Srv6Segments: segments,
+				},
+			},
+		})
+		if err != nil {
+			b.Fatalf("marshal failed: %v", err)
+		}
+		if tok := client.Publish(*topicRX, byte(*mqttQoS), false, payload); tok.Wait() && tok.Error() != nil {
+			b.Fatalf("publish failed: %v", tok.Error())
+		}
+
+		select {
+		case <-ch:
+		case <-time.After(*timeout):
+			b.Fatalf("route %s not programmed within %s", p, *timeout)
+		}
+	}
+	reportRoutes(b)
+	cleanup(b, ep, segments, b.N)
+}
@@ -0,0 +1,206 @@
+package bench
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"runtime"
+	"time"
+
+	"github.com/vishvananda/netns"
+	"golang.org/x/sys/unix"
+
+	"github.com/datum-cloud/galactic-agent/fixtures"
+	"github.com/datum-cloud/galactic-agent/nsutil"
+	"github.com/datum-cloud/galactic-agent/reconcile"
+	"github.com/datum-cloud/galactic-agent/srv6"
+	"github.com/datum-cloud/galactic-agent/srv6/endpoint"
+	"github.com/datum-cloud/galactic-agent/state"
+	"github.com/datum-cloud/galactic-agent/storm"
+)
+
+// ReportFormat is bumped whenever the meaning of a field in Report changes,
+// so stored results from different releases are only compared when equal.
+const ReportFormat = 1
+
+// The standardized scenarios run in a private namespace with a single
+// provisioned attachment, so results depend on the kernel and the agent only.
+const (
+	scenarioNS      = "galactic-bench"
+	scenarioSRv6Net = "fc00:bec::/64"
+	resyncCycles    = 20
+	resyncRoutes    = 500
+)
+
+var (
+	scenarioAttachment = fixtures.Attachment{VPC: "00000000bec0", VPCAttachment: "0001"}
+	scenarioSegments   = []string{"fc00:bec:0:2::1"}
+)
+
+// Timing is the result of one scenario. Ops are routes for the route
+// scenarios, delivered envelopes for resync and compared routes for the
+// reconciler sweep.
+type Timing struct {
+	Scenario  string  `json:"scenario"`
+	N         int     `json:"n"`
+	Nanos     int64   `json:"duration_ns"`
+	NsPerOp   int64   `json:"ns_per_op"`
+	OpsPerSec float64 `json:"ops_per_sec"`
+	Error     string  `json:"error,omitempty"`
+}
+
+func timing(name string, n int, d time.Duration, err error) Timing {
+	t := Timing{Scenario: name, N: n, Nanos: d.Nanoseconds()}
+	if err != nil {
+		t.Error = err.Error()
+		return t
+	}
+	if n > 0 {
+		t.NsPerOp = d.Nanoseconds() / int64(n)
+	}
+	if d > 0 {
+		t.OpsPerSec = float64(n) / d.Seconds()
+	}
+	return t
+}
+
+type Report struct {
+	Format    int       `json:"format"`
+	GoVersion string    `json:"go_version"`
+	Kernel    string    `json:"kernel"`
+	Started   time.Time `json:"started"`
+	Timings   []Timing  `json:"timings"`
+}
+
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+func (r *Report) Write(w io.Writer) {
+	for _, t := range r.Timings {
+		if t.Error != "" {
+			fmt.Fprintf(w, "%-20s FAILED: %s\n", t.Scenario, t.Error) //nolint:errcheck
+			continue
+		}
+		fmt.Fprintf(w, "%-20s %8d %12d ns/op %10.1f ops/s\n", t.Scenario, t.N, t.NsPerOp, t.OpsPerSec) //nolint:errcheck
+	}
+}
+
+// Failed reports whether any scenario failed.
+func (r *Report) Failed() bool {
+	for _, t := range r.Timings {
+		if t.Error != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func kernel() string {
+	var u unix.Utsname
+	if err := unix.Uname(&u); err != nil {
+		return "unknown"
+	}
+	return unix.ByteSliceToString(u.Release[:])
+}
+
+// Scenarios runs the standardized scenarios: 1k and 10k serial route adds,
+// a reconciler sweep over the 10k routes, and a reconnect resync through the
+// embedded broker. A failing scenario is recorded and the rest still run.
+func Scenarios(ctx context.Context) (*Report, error) {
+	r := &Report{
+		Format:    ReportFormat,
+		GoVersion: runtime.Version(),
+		Kernel:    kernel(),
+		Started:   time.Now().UTC(),
+	}
+
+	ns, err := nsutil.New(scenarioNS)
+	if err != nil {
+		return nil, fmt.Errorf("create namespace: %w", err)
+	}
+	defer func() {
+		ns.Close() //nolint:errcheck
+		_ = netns.DeleteNamed(scenarioNS)
+	}()
+
+	err = nsutil.Do(ns, func() error {
+		if err := fixtures.EnsureLoopback(); err != nil {
+			return err
+		}
+		if err := fixtures.Create(scenarioAttachment); err != nil {
+			return err
+		}
+		ep, err := endpoint.Encode(scenarioSRv6Net, scenarioAttachment.VPC, scenarioAttachment.VPCAttachment)
+		if err != nil {
+			return err
+		}
+		r.Timings = append(r.Timings, addRoutes("routes-add-1k", ep, 1000))
+		deleteRoutes(ep, 1000)
+		r.Timings = append(r.Timings, addRoutes("routes-add-10k", ep, 10000))
+		r.Timings = append(r.Timings, sweep(ep, 10000))
+		deleteRoutes(ep, 10000)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("scenario setup: %w", err)
+	}
+
+	r.Timings = append(r.Timings, resync(ctx))
+	return r, nil
+}
+
+// prefix returns the i-th synthetic /24 inside 10.0.0.0/8. Networks rather
+// than hosts are used so the neighbor proxy path is not exercised.
+func prefix(i int) string {
+	return fmt.Sprintf("10.%d.%d.0/24", (i>>8)&0xff, i&0xff)
+}
+
+func addRoutes(name, ep string, n int) Timing {
+	start := time.Now()
+	for i := 0; i < n; i++ {
+		if err := srv6.RouteEgressAdd(prefix(i), ep, scenarioSegments); err != nil {
+			return timing(name, i, time.Since(start), err)
+		}
+	}
+	return timing(name, n, time.Since(start), nil)
+}
+
+func deleteRoutes(ep string, n int) {
+	for i := 0; i < n; i++ {
+		_ = srv6.RouteEgressDel(prefix(i), ep, scenarioSegments)
+	}
+}
+
+// sweep times a full reconciler diff of n programmed routes against a
+// matching desired state. Any difference found is a failure.
+func sweep(ep string, n int) Timing {
+	st := state.State{Ingress: []string{}, Egress: make([]state.Egress, n)}
+	for i := range st.Egress {
+		st.Egress[i] = state.Egress{Network: prefix(i), Endpoint: ep, Segments: scenarioSegments}
+	}
+	start := time.Now()
+	changes, err := reconcile.Diff(st, scenarioSRv6Net)
+	d := time.Since(start)
+	if err == nil && len(changes) > 0 {
+		err = fmt.Errorf("%d unexpected differences, first: %s", len(changes), changes[0])
+	}
+	return timing("reconcile-sweep-10k", n, d, err)
+}
+
+// resync times reconnects followed by redelivery of a batch of routes.
+func resync(ctx context.Context) Timing {
+	res, err := storm.Run(ctx, storm.Config{
+		Cycles:         resyncCycles,
+		RoutesPerCycle: resyncRoutes,
+		Seed:           1,
+		Timeout:        10 * time.Second,
+	})
+	if err != nil {
+		return timing("reconnect-resync", 0, 0, err)
+	}
+	return timing("reconnect-resync", res.Routes, res.Duration, nil)
+}
@@ -0,0 +1,269 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/eclipse/paho.mqtt.golang/packets"
+)
+
+// Broker is a minimal in-process MQTT 3.1.1 broker. It supports enough of
+// the protocol for the agent and controller to talk to each other in tests
+// and demos: QoS 0/1/2 publish, wildcard subscriptions and keepalives. There
+// is no persistence, no retained messages and no authentication.
+type Broker struct {
+	mu      sync.Mutex
+	clients map[*client]struct{}
+}
+
+type client struct {
+	conn net.Conn
+	id   string
+
+	mu     sync.Mutex
+	subs   map[string]byte
+	nextID uint16
+	// requested records every subscribed filter in order, including
+	// repeats, so that clients subscribing twice can be detected
+	requested []string
+}
+
+func New() *Broker {
+	return &Broker{clients: make(map[*client]struct{})}
+}
+
+// Serve accepts connections on listener until ctx is done.
+func (b *Broker) Serve(ctx context.Context, listener net.Listener) error {
+	go func() {
+		<-ctx.Done()
+		listener.Close() //nolint:errcheck
+	}()
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				b.closeAll()
+				return nil
+			}
+			return err
+		}
+		go b.handle(conn)
+	}
+}
+
+func (b *Broker) closeAll() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for c := range b.clients {
+		c.conn.Close() //nolint:errcheck
+	}
+}
+
+func (b *Broker) handle(conn net.Conn) {
+	defer conn.Close() //nolint:errcheck
+
+	pkt, err := packets.ReadPacket(conn)
+	if err != nil {
+		return
+	}
+	connect, ok := pkt.(*packets.ConnectPacket)
+	if !ok {
+		return
+	}
+	c := &client{conn: conn, id: connect.ClientIdentifier, subs: make(map[string]byte)}
+	connack := packets.NewControlPacket(packets.Connack).(*packets.ConnackPacket)
+	connack.ReturnCode = connect.Validate()
+	if err := c.write(connack); err != nil || connack.ReturnCode != packets.Accepted {
+		return
+	}
+
+	b.mu.Lock()
+	// a reconnecting client takes over its previous session
+	for old := range b.clients {
+		if old.id != "" && old.id == c.id {
+			old.conn.Close() //nolint:errcheck
+			delete(b.clients, old)
+		}
+	}
+	b.clients[c] = struct{}{}
+	b.mu.Unlock()
+	defer func() {
+		b.mu.Lock()
+		delete(b.clients, c)
+		b.mu.Unlock()
+	}()
+
+	for {
+		pkt, err := packets.ReadPacket(conn)
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) && !strings.Contains(err.Error(), "EOF") {
+				slog.Warn("broker: client read failed", "client", c.id, "err", err)
+			}
+			return
+		}
+		switch p := pkt.(type) {
+		case *packets.PublishPacket:
+			switch p.Qos {
+			case 1:
+				ack := packets.NewControlPacket(packets.Puback).(*packets.PubackPacket)
+				ack.MessageID = p.MessageID
+				_ = c.write(ack)
+			case 2:
+				rec := packets.NewControlPacket(packets.Pubrec).(*packets.PubrecPacket)
+				rec.MessageID = p.MessageID
+				_ = c.write(rec)
+			}
+			b.publish(p.TopicName, p.Qos, p.Payload)
+		case *packets.PubrelPacket:
+			comp := packets.NewControlPacket(packets.Pubcomp).(*packets.PubcompPacket)
+			comp.MessageID = p.MessageID
+			_ = c.write(comp)
+		case *packets.PubrecPacket:
+			rel := packets.NewControlPacket(packets.Pubrel).(*packets.PubrelPacket)
+			rel.MessageID = p.MessageID
+			_ = c.write(rel)
+		case *packets.SubscribePacket:
+			ack := packets.NewControlPacket(packets.Suback).(*packets.SubackPacket)
+			ack.MessageID = p.MessageID
+			c.mu.Lock()
+			for i, topic := range p.Topics {
+				c.subs[topic] = p.Qoss[i]
+				c.requested = append(c.requested, topic)
+				ack.ReturnCodes = append(ack.ReturnCodes, p.Qoss[i])
+			}
+			c.mu.Unlock()
+			_ = c.write(ack)
+		case *packets.UnsubscribePacket:
+			c.mu.Lock()
+			for _, topic := range p.Topics {
+				delete(c.subs, topic)
+				c.requested = slices.DeleteFunc(c.requested, func(f string) bool { return f == topic })
+			}
+			c.mu.Unlock()
+			ack := packets.NewControlPacket(packets.Unsuback).(*packets.UnsubackPacket)
+			ack.MessageID = p.MessageID
+			_ = c.write(ack)
+		case *packets.PingreqPacket:
+			_ = c.write(packets.NewControlPacket(packets.Pingresp))
+		case *packets.DisconnectPacket:
+			return
+		}
+	}
+}
+
+// Subscriptions returns the filters subscribed by each live connection of the
+// client with the given id, one entry per connection. A filter subscribed
+// more than once on the same connection is listed once per request.
+func (b *Broker) Subscriptions(clientID string) [][]string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var subs [][]string
+	for c := range b.clients {
+		if c.id != clientID {
+			continue
+		}
+		c.mu.Lock()
+		filters := slices.Clone(c.requested)
+		c.mu.Unlock()
+		subs = append(subs, filters)
+	}
+	return subs
+}
+
+// Drop abruptly closes all connections of the client with the given id, as a
+// broker restart or network failure would, and returns how many were closed.
+func (b *Broker) Drop(clientID string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	n := 0
+	for c := range b.clients {
+		if c.id == clientID {
+			c.conn.Close() //nolint:errcheck
+			delete(b.clients, c)
+			n++
+		}
+	}
+	return n
+}
+
+func (b *Broker) publish(topic string, qos byte, payload []byte) {
+	b.mu.Lock()
+	clients := make([]*client, 0, len(b.clients))
+	for c := range b.clients {
+		clients = append(clients, c)
+	}
+	b.mu.Unlock()
+
+	for _, c := range clients {
+		granted, ok := c.match(topic)
+		if !ok {
+			continue
+		}
+		pub := packets.NewControlPacket(packets.Publish).(*packets.PublishPacket)
+		pub.TopicName = topic
+		pub.Payload = payload
+		pub.Qos = min(qos, granted)
+		if pub.Qos > 0 {
+			pub.MessageID = c.messageID()
+		}
+		if err := c.write(pub); err != nil {
+			slog.Warn("broker: delivery failed", "client", c.id, "err", err)
+		}
+	}
+}
+
+func (c *client) match(topic string) (byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var qos byte
+	found := false
+	for filter, q := range c.subs {
+		if Match(filter, topic) {
+			if !found || q > qos {
+				qos = q
+			}
+			found = true
+		}
+	}
+	return qos, found
+}
+
+func (c *client) messageID() uint16 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.nextID++
+	if c.nextID == 0 {
+		c.nextID = 1
+	}
+	return c.nextID
+}
+
+func (c *client) write(p packets.ControlPacket) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return p.Write(c.conn)
+}
+
+// Match reports whether topic matches the subscription filter, honouring the
+// single-level (+) and multi-level (#) wildcards.
+func Match(filter, topic string) bool {
+	f := strings.Split(filter, "/")
+	t := strings.Split(topic, "/")
+	for i, part := range f {
+		if part == "#" {
+			return true
+		}
+		if i >= len(t) {
+			return false
+		}
+		if part != "+" && part != t[i] {
+			return false
+		}
+	}
+	return len(f) == len(t)
+}
@@ -0,0 +1,384 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/datum-cloud/galactic-agent/api/local"
+)
+
+// SocketEnv overrides socket discovery when set.
+const SocketEnv = "GALACTIC_AGENT_SOCKET"
+
+// DefaultSocketPaths are probed in order by Discover.
+var DefaultSocketPaths = []string{
+	"/var/run/galactic/agent.sock",
+	"/run/galactic/agent.sock",
+}
+
+// Discover returns the path of the agent's local API socket: the value of
+// $GALACTIC_AGENT_SOCKET if set, otherwise the first default path that exists.
+func Discover() (string, error) {
+	if p := os.Getenv(SocketEnv); p != "" {
+		return p, nil
+	}
+	for _, p := range DefaultSocketPaths {
+		if _, err := os.Stat(p); err == nil {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("galactic agent socket not found (set %s)", SocketEnv)
+}
+
+// Client is a thin wrapper around the generated local API client that dials
+// the unix socket and retries calls while the agent is unavailable.
+type Client struct {
+	conn    *grpc.ClientConn
+	local   local.LocalClient
+	retries int
+	backoff time.Duration
+	token   string
+}
+
+const maxBackoff = 5 * time.Second
+
+type Option func(*Client)
+
+// WithRetries sets how many times a call is retried when the agent is
+// unreachable, and the initial backoff, which doubles between attempts up to
+// five seconds.
+func WithRetries(retries int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.retries = retries
+		c.backoff = backoff
+	}
+}
+
+// WithToken sends token as a bearer token on every call, for agents whose
+// local_authz rules grant VPCs by token.
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+// New connects to the agent listening on socketPath. An empty path is
+// resolved with Discover.
+func New(socketPath string, opts ...Option) (*Client, error) {
+	if socketPath == "" {
+		p, err := Discover()
+		if err != nil {
+			return nil, err
+		}
+		socketPath = p
+	}
+	c := &Client{
+		retries: 5,
+		backoff: 200 * time.Millisecond,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	dialOpts := []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
+	if c.token != "" {
+		dialOpts = append(dialOpts, grpc.WithUnaryInterceptor(func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+			ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+c.token)
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}))
+	}
+	conn, err := grpc.NewClient("unix://"+socketPath, dialOpts...)
+	if err != nil {
+		return nil, err
+	}
+	c.conn = conn
+	c.local = local.NewLocalClient(conn)
+	return c, nil
+}
+
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// Local exposes the generated client for RPCs not wrapped here.
+func (c *Client) Local() local.LocalClient {
+	return c.local
+}
+
+func (c *Client) Register(ctx context.Context, vpc, vpcAttachment string, networks ...string) error {
+	return c.register(ctx, vpc, vpcAttachment, "", networks, false)
+}
+
+// RegisterAnycast registers networks that other attachments of the VPC may
+// register too, also with RegisterAnycast.
+func (c *Client) RegisterAnycast(ctx context.Context, vpc, vpcAttachment string, networks ...string) error {
+	return c.register(ctx, vpc, vpcAttachment, "", networks, true)
+}
+
+// RegisterNamed is Register or, with anycast, RegisterAnycast, also
+// publishing the attachment's addresses under name when the agent
+// registers names in DNS.
+func (c *Client) RegisterNamed(ctx context.Context, vpc, vpcAttachment, name string, anycast bool, networks ...string) error {
+	return c.register(ctx, vpc, vpcAttachment, name, networks, anycast)
+}
+
+func (c *Client) register(ctx context.Context, vpc, vpcAttachment, name string, networks []string, anycast bool) error {
+	return c.retry(ctx, func() error {
+		reply, err := c.local.Register(ctx, &local.RegisterRequest{
+			Vpc:           vpc,
+			Vpcattachment: vpcAttachment,
+			Networks:      networks,
+			Anycast:       anycast,
+			Name:          name,
+		})
+		if err != nil {
+			return err
+		}
+		if !reply.GetConfirmed() {
+			return errors.New("registration not confirmed")
+		}
+		return nil
+	})
+}
+
+func (c *Client) Deregister(ctx context.Context, vpc, vpcAttachment string, networks ...string) error {
+	return c.retry(ctx, func() error {
+		reply, err := c.local.Deregister(ctx, &local.DeregisterRequest{
+			Vpc:           vpc,
+			Vpcattachment: vpcAttachment,
+			Networks:      networks,
+		})
+		if err != nil {
+			return err
+		}
+		if !reply.GetConfirmed() {
+			return errors.New("deregistration not confirmed")
+		}
+		return nil
+	})
+}
+
+// RegisterBatch registers items in one call. It returns the error of each
+// item, nil if it succeeded, unless the call as a whole failed.
+func (c *Client) RegisterBatch(ctx context.Context, items []*local.RegisterRequest) ([]error, error) {
+	var reply *local.BatchReply
+	err := c.retry(ctx, func() error {
+		var err error
+		reply, err = c.local.RegisterBatch(ctx, &local.RegisterBatchRequest{Items: items})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return batchErrors(reply, len(items))
+}
+
+// DeregisterBatch is RegisterBatch for deregistrations.
+func (c *Client) DeregisterBatch(ctx context.Context, items []*local.DeregisterRequest) ([]error, error) {
+	var reply *local.BatchReply
+	err := c.retry(ctx, func() error {
+		var err error
+		reply, err = c.local.DeregisterBatch(ctx, &local.DeregisterBatchRequest{Items: items})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return batchErrors(reply, len(items))
+}
+
+// batchErrors turns the results of a batch of n items into status errors.
+func batchErrors(reply *local.BatchReply, n int) ([]error, error) {
+	results := reply.GetResults()
+	if len(results) != n {
+		return nil, fmt.Errorf("%d results for %d items", len(results), n)
+	}
+	errs := make([]error, n)
+	for i, r := range results {
+		if codes.Code(r.GetCode()) != codes.OK {
+			errs[i] = status.Error(codes.Code(r.GetCode()), r.GetError())
+		}
+	}
+	return errs, nil
+}
+
+// AllocateAttachment returns a vpc attachment id assigned by the agent.
+// Calls with the same owner return the same id until it is released.
+func (c *Client) AllocateAttachment(ctx context.Context, vpc, owner string) (string, error) {
+	var vpcAttachment string
+	err := c.retry(ctx, func() error {
+		reply, err := c.local.AllocateAttachment(ctx, &local.AllocateAttachmentRequest{
+			Vpc:   vpc,
+			Owner: owner,
+		})
+		if err != nil {
+			return err
+		}
+		vpcAttachment = reply.GetVpcattachment()
+		return nil
+	})
+	return vpcAttachment, err
+}
+
+func (c *Client) ReleaseAttachment(ctx context.Context, vpc, vpcAttachment string) error {
+	return c.retry(ctx, func() error {
+		reply, err := c.local.ReleaseAttachment(ctx, &local.ReleaseAttachmentRequest{
+			Vpc:           vpc,
+			Vpcattachment: vpcAttachment,
+		})
+		if err != nil {
+			return err
+		}
+		if !reply.GetConfirmed() {
+			return errors.New("release not confirmed")
+		}
+		return nil
+	})
+}
+
+// ListAttachments returns the attachments registered with the agent in vpc,
+// or in all VPCs if vpc is empty, with their networks and SRv6 endpoints.
+func (c *Client) ListAttachments(ctx context.Context, vpc string) ([]*local.Attachment, error) {
+	var attachments []*local.Attachment
+	err := c.retry(ctx, func() error {
+		reply, err := c.local.ListAttachments(ctx, &local.ListAttachmentsRequest{Vpc: vpc})
+		if err != nil {
+			return err
+		}
+		attachments = reply.GetAttachments()
+		return nil
+	})
+	return attachments, err
+}
+
+// GetAttachment returns the registered attachment vpcAttachment of vpc; the
+// error has code NotFound if it is not registered.
+func (c *Client) GetAttachment(ctx context.Context, vpc, vpcAttachment string) (*local.Attachment, error) {
+	var attachment *local.Attachment
+	err := c.retry(ctx, func() error {
+		var err error
+		attachment, err = c.local.GetAttachment(ctx, &local.GetAttachmentRequest{Vpc: vpc, Vpcattachment: vpcAttachment})
+		return err
+	})
+	return attachment, err
+}
+
+// GetRoutes returns the routes of req's VPC, or of its attachment, as the
+// agent's state and the kernel hold them.
+func (c *Client) GetRoutes(ctx context.Context, req *local.GetRoutesRequest) (*local.GetRoutesReply, error) {
+	var reply *local.GetRoutesReply
+	err := c.retry(ctx, func() error {
+		var err error
+		reply, err = c.local.GetRoutes(ctx, req)
+		return err
+	})
+	return reply, err
+}
+
+// Probe traces req's destination through the route the agent programmed
+// for it in the attachment's VRF.
+func (c *Client) Probe(ctx context.Context, req *local.ProbeRequest) (*local.ProbeReply, error) {
+	var reply *local.ProbeReply
+	err := c.retry(ctx, func() error {
+		var err error
+		reply, err = c.local.Probe(ctx, req)
+		return err
+	})
+	return reply, err
+}
+
+// Status returns the build of the agent and the configuration it runs with.
+func (c *Client) Status(ctx context.Context) (*local.StatusReply, error) {
+	var reply *local.StatusReply
+	err := c.retry(ctx, func() error {
+		var err error
+		reply, err = c.local.Status(ctx, &local.StatusRequest{})
+		return err
+	})
+	return reply, err
+}
+
+// Watch calls fn with every event of the agent's registry of vpc, or of all
+// VPCs if vpc is empty, until ctx is done or fn returns an error. The stream
+// starts with the current attachments, followed by a SYNCED event.
+func (c *Client) Watch(ctx context.Context, vpc string, fn func(*local.AttachmentEvent) error) error {
+	var stream local.Local_WatchClient
+	err := c.retry(ctx, func() error {
+		var err error
+		stream, err = c.local.Watch(ctx, &local.WatchRequest{Vpc: vpc})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	for {
+		event, err := stream.Recv()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		if err := fn(event); err != nil {
+			return err
+		}
+	}
+}
+
+// WatchEvents calls fn with every event the agent publishes for vpc, or
+// for all VPCs if vpc is empty, until ctx is done or fn returns an error.
+func (c *Client) WatchEvents(ctx context.Context, vpc string, fn func(*local.Event) error) error {
+	var stream local.Local_WatchEventsClient
+	err := c.retry(ctx, func() error {
+		var err error
+		stream, err = c.local.WatchEvents(ctx, &local.WatchEventsRequest{Vpc: vpc})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	for {
+		event, err := stream.Recv()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		if err := fn(event); err != nil {
+			return err
+		}
+	}
+}
+
+func (c *Client) retry(ctx context.Context, call func() error) error {
+	backoff := c.backoff
+	for attempt := 0; ; attempt++ {
+		err := call()
+		if err == nil || attempt >= c.retries || !retryable(err) {
+			return err
+		}
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		backoff = min(2*backoff, maxBackoff)
+	}
+}
+
+func retryable(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.ResourceExhausted, codes.Aborted:
+		return true
+	}
+	return false
+}
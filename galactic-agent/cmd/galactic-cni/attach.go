@@ -0,0 +1,292 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netns"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/datum-cloud/galactic-agent/api/local"
+	"github.com/datum-cloud/galactic-agent/client"
+	"github.com/datum-cloud/galactic-agent/nsutil"
+	"github.com/datum-cloud/galactic-agent/wiring"
+)
+
+const callTimeout = 30 * time.Second
+
+func connect(conf *netConf) (*client.Client, error) {
+	var opts []client.Option
+	if conf.TokenFile != "" {
+		token, err := os.ReadFile(conf.TokenFile)
+		if err != nil {
+			return nil, newError(codeInvalidConfig, "reading tokenFile", err)
+		}
+		opts = append(opts, client.WithToken(strings.TrimSpace(string(token))))
+	}
+	c, err := client.New(conf.Socket, opts...)
+	if err != nil {
+		return nil, newError(codeTryAgainLater, "connecting to the galactic agent", err)
+	}
+	return c, nil
+}
+
+// resolve picks the VPC and attachment id of the container. Without an
+// explicit id one is allocated by the agent for the container and interface,
+// so repeating the call for the same container returns the same id.
+func resolve(ctx context.Context, c *client.Client, e env, conf *netConf) (wiring.Attachment, error) {
+	vpc, vpcAttachment := conf.VPC, e.args["GALACTIC_VPCATTACHMENT"]
+	if v := e.args["GALACTIC_VPC"]; v != "" {
+		vpc = v
+	}
+	if vpc == "" {
+		return wiring.Attachment{}, newError(codeInvalidConfig, "vpc is required", nil)
+	}
+	if vpcAttachment == "" {
+		id, err := c.AllocateAttachment(ctx, vpc, e.containerID+"/"+e.ifname)
+		if err != nil {
+			return wiring.Attachment{}, newError(codeTryAgainLater, "allocating a vpc attachment", err)
+		}
+		vpcAttachment = id
+	}
+	a, err := wiring.New(vpc, vpcAttachment)
+	if err != nil {
+		return a, newError(codeInvalidConfig, fmt.Sprintf("invalid vpc attachment %s/%s", vpc, vpcAttachment), err)
+	}
+	return a, nil
+}
+
+func cmdAdd(e env, conf *netConf, stdin []byte) (*result, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+	defer cancel()
+	c, err := connect(conf)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close() //nolint:errcheck
+	a, err := resolve(ctx, c, e, conf)
+	if err != nil {
+		return nil, err
+	}
+	ns, err := netns.GetFromPath(e.netns)
+	if err != nil {
+		return nil, newError(codeInvalidEnv, fmt.Sprintf("opening netns %s", e.netns), err)
+	}
+	defer ns.Close() //nolint:errcheck
+
+	res, err := delegateIPAM(e, conf, stdin)
+	if err != nil {
+		return nil, err
+	}
+	addrs, err := res.addresses()
+	if err != nil {
+		return nil, err
+	}
+	// release the addresses again if wiring the container fails
+	ok := false
+	defer func() {
+		if !ok {
+			del := e
+			del.command = "DEL"
+			os.Setenv("CNI_COMMAND", "DEL") //nolint:errcheck
+			delegateIPAM(del, conf, stdin)  //nolint:errcheck
+			a.Teardown()                    //nolint:errcheck
+		}
+	}()
+
+	err = a.SetupHost(addrs)
+	if err == nil {
+		err = moveGuest(a, ns)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("attachment %s/%s: %w", a.VPC, a.VPCAttachment, err)
+	}
+	mac, err := setupGuest(a, e.ifname, conf.MTU, addrs, ns)
+	if err != nil {
+		return nil, fmt.Errorf("attachment %s/%s: %w", a.VPC, a.VPCAttachment, err)
+	}
+
+	networks := make([]string, len(addrs))
+	for i, n := range addrs {
+		networks[i] = n.String()
+	}
+	err = c.RegisterNamed(ctx, a.VPC, a.VPCAttachment, e.args["GALACTIC_NAME"], conf.Anycast, networks...)
+	if err != nil {
+		return nil, newError(codeTryAgainLater, "registering with the galactic agent", err)
+	}
+	ok = true
+
+	res.Interfaces = []iface{
+		{Name: a.Host},
+		{Name: e.ifname, Mac: mac, Sandbox: e.netns},
+	}
+	guest := 1
+	for i := range res.IPs {
+		res.IPs[i].Interface = &guest
+		res.IPs[i].Gateway = wiring.Gateway(addrs[i].IP).String()
+	}
+	res.Routes = []route{{Dst: "0.0.0.0/0", GW: wiring.Gateway4.String()}, {Dst: "::/0", GW: wiring.Gateway6.String()}}
+	return res, nil
+}
+
+// moveGuest moves the guest side of a, left in the host namespace by
+// SetupHost, into ns.
+func moveGuest(a wiring.Attachment, ns netns.NsHandle) error {
+	guest, err := netlink.LinkByName(a.Guest)
+	if wiring.NotFound(err) {
+		// already moved by an earlier attempt
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return netlink.LinkSetNsFd(guest, int(ns))
+}
+
+// setupGuest renames the guest side to ifname inside ns, assigns the
+// container's addresses and routes everything via the host side.
+func setupGuest(a wiring.Attachment, ifname string, mtu int, addrs []*net.IPNet, ns netns.NsHandle) (string, error) {
+	var mac string
+	err := nsutil.Do(ns, func() error {
+		guest, err := netlink.LinkByName(a.Guest)
+		if wiring.NotFound(err) {
+			guest, err = netlink.LinkByName(ifname)
+		} else if err == nil {
+			if err := netlink.LinkSetName(guest, ifname); err != nil {
+				return err
+			}
+		}
+		if err != nil {
+			return err
+		}
+		if mtu > 0 {
+			if err := netlink.LinkSetMTU(guest, mtu); err != nil {
+				return err
+			}
+		}
+		for _, n := range addrs {
+			if err := netlink.AddrReplace(guest, &netlink.Addr{IPNet: n}); err != nil {
+				return err
+			}
+		}
+		if err := netlink.LinkSetUp(guest); err != nil {
+			return err
+		}
+		for _, n := range addrs {
+			if err := netlink.RouteReplace(&netlink.Route{
+				LinkIndex: guest.Attrs().Index,
+				Dst:       defaultRoute(n.IP),
+				Gw:        wiring.Gateway(n.IP),
+				Flags:     int(netlink.FLAG_ONLINK),
+			}); err != nil {
+				return err
+			}
+		}
+		mac = guest.Attrs().HardwareAddr.String()
+		return nil
+	})
+	return mac, err
+}
+
+func defaultRoute(ip net.IP) *net.IPNet {
+	if ip.To4() != nil {
+		return &net.IPNet{IP: net.IPv4zero, Mask: net.CIDRMask(0, 32)}
+	}
+	return &net.IPNet{IP: net.IPv6zero, Mask: net.CIDRMask(0, 128)}
+}
+
+// cmdDel undoes cmdAdd. It is idempotent: whatever is already gone, whether
+// the registration, the interfaces or the addresses, is skipped.
+func cmdDel(e env, conf *netConf, stdin []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+	defer cancel()
+	c, err := connect(conf)
+	if err != nil {
+		return err
+	}
+	defer c.Close() //nolint:errcheck
+	a, err := resolve(ctx, c, e, conf)
+	if err != nil {
+		return err
+	}
+
+	registered, err := c.Local().GetAttachment(ctx, &local.GetAttachmentRequest{Vpc: a.VPC, Vpcattachment: a.VPCAttachment})
+	switch {
+	case status.Code(err) == codes.NotFound:
+	case err != nil:
+		return newError(codeTryAgainLater, "looking up the attachment", err)
+	case len(registered.GetNetworks()) > 0:
+		if err := c.Deregister(ctx, a.VPC, a.VPCAttachment, registered.GetNetworks()...); err != nil {
+			return newError(codeTryAgainLater, "deregistering from the galactic agent", err)
+		}
+	}
+	if err := a.Teardown(); err != nil {
+		return fmt.Errorf("attachment %s/%s: %w", a.VPC, a.VPCAttachment, err)
+	}
+	if _, err := delegateIPAM(e, conf, stdin); err != nil {
+		return err
+	}
+	if e.args["GALACTIC_VPCATTACHMENT"] == "" {
+		if err := c.ReleaseAttachment(ctx, a.VPC, a.VPCAttachment); err != nil {
+			return newError(codeTryAgainLater, "releasing the vpc attachment", err)
+		}
+	}
+	return nil
+}
+
+// cmdCheck verifies that the interfaces of a previous ADD still exist and
+// that its addresses are still registered with the agent.
+func cmdCheck(e env, conf *netConf) error {
+	if conf.PrevResult == nil {
+		return newError(codeInvalidConfig, "prevResult is required", nil)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+	defer cancel()
+	c, err := connect(conf)
+	if err != nil {
+		return err
+	}
+	defer c.Close() //nolint:errcheck
+	a, err := resolve(ctx, c, e, conf)
+	if err != nil {
+		return err
+	}
+	for _, name := range []string{a.VRF, a.Host} {
+		if _, err := netlink.LinkByName(name); err != nil {
+			return fmt.Errorf("interface %s: %w", name, err)
+		}
+	}
+	ns, err := netns.GetFromPath(e.netns)
+	if err != nil {
+		return newError(codeInvalidEnv, fmt.Sprintf("opening netns %s", e.netns), err)
+	}
+	defer ns.Close() //nolint:errcheck
+	if err := nsutil.Do(ns, func() error {
+		_, err := netlink.LinkByName(e.ifname)
+		return err
+	}); err != nil {
+		return fmt.Errorf("interface %s in %s: %w", e.ifname, e.netns, err)
+	}
+
+	registered, err := c.Local().GetAttachment(ctx, &local.GetAttachmentRequest{Vpc: a.VPC, Vpcattachment: a.VPCAttachment})
+	if err != nil {
+		return fmt.Errorf("attachment %s/%s: %w", a.VPC, a.VPCAttachment, err)
+	}
+	addrs, err := conf.PrevResult.addresses()
+	if err != nil {
+		return err
+	}
+	for _, n := range addrs {
+		if !slices.Contains(registered.GetNetworks(), n.String()) {
+			return fmt.Errorf("attachment %s/%s: %s is not registered", a.VPC, a.VPCAttachment, n)
+		}
+	}
+	return nil
+}
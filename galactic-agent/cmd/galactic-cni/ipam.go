@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/datum-cloud/galactic-agent/wiring"
+)
+
+// result is the CNI result, in the 1.0.0 layout that 0.4.0 runtimes also
+// accept.
+type result struct {
+	CNIVersion string          `json:"cniVersion,omitempty"`
+	Interfaces []iface         `json:"interfaces,omitempty"`
+	IPs        []ipConfig      `json:"ips,omitempty"`
+	Routes     []route         `json:"routes,omitempty"`
+	DNS        json.RawMessage `json:"dns,omitempty"`
+}
+
+type iface struct {
+	Name    string `json:"name"`
+	Mac     string `json:"mac,omitempty"`
+	Sandbox string `json:"sandbox,omitempty"`
+}
+
+type ipConfig struct {
+	Interface *int   `json:"interface,omitempty"`
+	Address   string `json:"address"`
+	Gateway   string `json:"gateway,omitempty"`
+}
+
+type route struct {
+	Dst string `json:"dst"`
+	GW  string `json:"gw,omitempty"`
+}
+
+// addresses returns the addresses of r as host networks, the form they are
+// registered with the agent in.
+func (r *result) addresses() ([]*net.IPNet, error) {
+	ips := make([]net.IP, len(r.IPs))
+	for i, ip := range r.IPs {
+		addr, _, err := net.ParseCIDR(ip.Address)
+		if err != nil {
+			return nil, fmt.Errorf("ipam returned invalid address %q: %w", ip.Address, err)
+		}
+		ips[i] = addr
+	}
+	return wiring.HostNetworks(ips...), nil
+}
+
+// delegateIPAM runs the IPAM plugin of conf with the current environment and
+// stdin, as the spec requires, and decodes its result on ADD.
+func delegateIPAM(e env, conf *netConf, stdin []byte) (*result, error) {
+	plugin, err := findPlugin(e.path, conf.IPAM.Type)
+	if err != nil {
+		return nil, err
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(plugin)
+	cmd.Env = os.Environ()
+	cmd.Stdin = bytes.NewReader(stdin)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	runErr := cmd.Run()
+
+	if runErr != nil {
+		ce := &cniError{}
+		if json.Unmarshal(stdout.Bytes(), ce) == nil && ce.Msg != "" {
+			return nil, ce
+		}
+		return nil, newError(codeIO, fmt.Sprintf("ipam plugin %s failed", conf.IPAM.Type), fmt.Errorf("%w: %s", runErr, strings.TrimSpace(stderr.String())))
+	}
+	if e.command != "ADD" {
+		return nil, nil
+	}
+	res := &result{}
+	if err := json.Unmarshal(stdout.Bytes(), res); err != nil {
+		return nil, newError(codeDecode, fmt.Sprintf("invalid result from ipam plugin %s", conf.IPAM.Type), err)
+	}
+	if len(res.IPs) == 0 {
+		return nil, newError(codeInvalidConfig, fmt.Sprintf("ipam plugin %s returned no addresses", conf.IPAM.Type), nil)
+	}
+	return res, nil
+}
+
+func findPlugin(path, name string) (string, error) {
+	if strings.ContainsRune(name, os.PathSeparator) {
+		return "", newError(codeInvalidConfig, fmt.Sprintf("invalid ipam type %q", name), nil)
+	}
+	for _, dir := range filepath.SplitList(path) {
+		p := filepath.Join(dir, name)
+		if fi, err := os.Stat(p); err == nil && !fi.IsDir() {
+			return p, nil
+		}
+	}
+	return "", newError(codeInvalidEnv, fmt.Sprintf("ipam plugin %s not found in CNI_PATH", name), nil)
+}
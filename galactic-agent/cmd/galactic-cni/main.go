@@ -0,0 +1,178 @@
+// Command galactic-cni is a CNI plugin that attaches containers to a
+// Galactic VPC: it creates the attachment's VRF and veth pair with the names
+// the agent expects, moves the guest side into the container and registers
+// the container's addresses with the agent over its local API.
+//
+// Addresses come from the IPAM plugin named in the network configuration.
+// The VPC is taken from the configuration or the GALACTIC_VPC CNI_ARGS key;
+// the attachment id is allocated by the agent unless GALACTIC_VPCATTACHMENT
+// is passed. GALACTIC_NAME names the container in the VPC's DNS zone.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Supported CNI spec versions.
+var supportedVersions = []string{"0.4.0", "1.0.0"}
+
+// Error codes from the CNI spec.
+const (
+	codeIncompatibleVersion = 1
+	codeInvalidEnv          = 4
+	codeIO                  = 5
+	codeDecode              = 6
+	codeInvalidConfig       = 7
+	codeTryAgainLater       = 11
+)
+
+// netConf is the network configuration passed on stdin.
+type netConf struct {
+	CNIVersion string `json:"cniVersion"`
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+
+	// VPC is the hex id of the VPC containers of this network join.
+	VPC string `json:"vpc"`
+	// Socket is the agent's local API socket; empty discovers it.
+	Socket string `json:"socket"`
+	// TokenFile holds a bearer token for agents with local_authz.
+	TokenFile string `json:"tokenFile"`
+	// Anycast registers the container's addresses as anycast.
+	Anycast bool `json:"anycast"`
+	// MTU of the container interface; 0 keeps the kernel default.
+	MTU int `json:"mtu"`
+
+	IPAM struct {
+		Type string `json:"type"`
+	} `json:"ipam"`
+	PrevResult *result `json:"prevResult,omitempty"`
+}
+
+// env is the CNI_* environment of an invocation.
+type env struct {
+	command, containerID, netns, ifname, path string
+	args                                      map[string]string
+}
+
+type cniError struct {
+	CNIVersion string `json:"cniVersion"`
+	Code       int    `json:"code"`
+	Msg        string `json:"msg"`
+	Details    string `json:"details,omitempty"`
+}
+
+func (e *cniError) Error() string {
+	if e.Details != "" {
+		return e.Msg + ": " + e.Details
+	}
+	return e.Msg
+}
+
+func newError(code int, msg string, err error) *cniError {
+	e := &cniError{Code: code, Msg: msg}
+	if err != nil {
+		e.Details = err.Error()
+	}
+	return e
+}
+
+func readEnv() (env, error) {
+	e := env{
+		command:     os.Getenv("CNI_COMMAND"),
+		containerID: os.Getenv("CNI_CONTAINERID"),
+		netns:       os.Getenv("CNI_NETNS"),
+		ifname:      os.Getenv("CNI_IFNAME"),
+		path:        os.Getenv("CNI_PATH"),
+		args:        make(map[string]string),
+	}
+	for _, kv := range strings.Split(os.Getenv("CNI_ARGS"), ";") {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			e.args[k] = v
+		}
+	}
+	if e.command == "VERSION" {
+		return e, nil
+	}
+	if e.containerID == "" {
+		return e, newError(codeInvalidEnv, "CNI_CONTAINERID is required", nil)
+	}
+	if e.ifname == "" {
+		return e, newError(codeInvalidEnv, "CNI_IFNAME is required", nil)
+	}
+	if e.command == "ADD" && e.netns == "" {
+		return e, newError(codeInvalidEnv, "CNI_NETNS is required", nil)
+	}
+	return e, nil
+}
+
+func readConf(stdin []byte) (*netConf, error) {
+	conf := &netConf{}
+	if err := json.Unmarshal(stdin, conf); err != nil {
+		return nil, newError(codeDecode, "invalid network configuration", err)
+	}
+	supported := false
+	for _, v := range supportedVersions {
+		supported = supported || v == conf.CNIVersion
+	}
+	if !supported {
+		return nil, newError(codeIncompatibleVersion, fmt.Sprintf("unsupported cniVersion %q", conf.CNIVersion), nil)
+	}
+	if conf.IPAM.Type == "" {
+		return nil, newError(codeInvalidConfig, "ipam.type is required", nil)
+	}
+	return conf, nil
+}
+
+func run(stdin []byte, stdout io.Writer) error {
+	e, err := readEnv()
+	if err != nil {
+		return err
+	}
+	if e.command == "VERSION" {
+		return json.NewEncoder(stdout).Encode(map[string]any{
+			"cniVersion":        supportedVersions[len(supportedVersions)-1],
+			"supportedVersions": supportedVersions,
+		})
+	}
+	conf, err := readConf(stdin)
+	if err != nil {
+		return err
+	}
+	switch e.command {
+	case "ADD":
+		res, err := cmdAdd(e, conf, stdin)
+		if err != nil {
+			return err
+		}
+		res.CNIVersion = conf.CNIVersion
+		return json.NewEncoder(stdout).Encode(res)
+	case "DEL":
+		return cmdDel(e, conf, stdin)
+	case "CHECK":
+		return cmdCheck(e, conf)
+	}
+	return newError(codeInvalidEnv, fmt.Sprintf("unsupported CNI_COMMAND %q", e.command), nil)
+}
+
+func main() {
+	stdin, err := io.ReadAll(os.Stdin)
+	if err == nil {
+		err = run(stdin, os.Stdout)
+	}
+	if err == nil {
+		return
+	}
+	var ce *cniError
+	if !errors.As(err, &ce) {
+		ce = newError(codeIO, "galactic-cni failed", err)
+	}
+	ce.CNIVersion = supportedVersions[len(supportedVersions)-1]
+	json.NewEncoder(os.Stdout).Encode(ce) //nolint:errcheck
+	os.Exit(1)
+}
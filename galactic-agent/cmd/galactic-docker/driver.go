@@ -0,0 +1,378 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/datum-cloud/galactic-agent/client"
+	"github.com/datum-cloud/galactic-agent/srv6/endpoint"
+	"github.com/datum-cloud/galactic-agent/wiring"
+)
+
+const (
+	contentType = "application/vnd.docker.plugins.v1.2+json"
+	// genericOptions holds the -o options of docker network create.
+	genericOptions = "com.docker.network.generic"
+	callTimeout    = 30 * time.Second
+)
+
+// network is a Docker network of the driver and the VPC it joins.
+type network struct {
+	VPC     string `json:"vpc"`
+	Anycast bool   `json:"anycast,omitempty"`
+}
+
+// dockerEndpoint is a container's endpoint on a network and its attachment.
+type dockerEndpoint struct {
+	Network       string   `json:"network"`
+	VPCAttachment string   `json:"vpcattachment"`
+	Networks      []string `json:"networks"`
+}
+
+// driver implements the libnetwork remote driver protocol. Docker tells the
+// driver about a network only when it is created, so networks and endpoints
+// are kept in a file to survive restarts of the driver.
+type driver struct {
+	client *client.Client
+	path   string
+
+	mu        sync.Mutex
+	Networks  map[string]network        `json:"networks"`
+	Endpoints map[string]dockerEndpoint `json:"endpoints"`
+}
+
+func openDriver(c *client.Client, path string) (*driver, error) {
+	d := &driver{
+		client:    c,
+		path:      path,
+		Networks:  make(map[string]network),
+		Endpoints: make(map[string]dockerEndpoint),
+	}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return d, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, d); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return d, nil
+}
+
+// save writes the driver state atomically; the caller holds d.mu.
+func (d *driver) save() error {
+	b, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(d.path), 0o755); err != nil {
+		return err
+	}
+	tmp := d.path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, d.path)
+}
+
+func (d *driver) handler() http.Handler {
+	mux := http.NewServeMux()
+	handle := func(method string, fn func(context.Context, json.RawMessage) (any, error)) {
+		mux.HandleFunc("POST /"+method, func(w http.ResponseWriter, r *http.Request) {
+			var req json.RawMessage
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+				reply(w, nil, err)
+				return
+			}
+			ctx, cancel := context.WithTimeout(r.Context(), callTimeout)
+			defer cancel()
+			resp, err := fn(ctx, req)
+			if err != nil {
+				slog.Error("Driver call", "method", method, "err", err)
+			}
+			reply(w, resp, err)
+		})
+	}
+	empty := func(context.Context, json.RawMessage) (any, error) { return struct{}{}, nil }
+
+	handle("Plugin.Activate", func(context.Context, json.RawMessage) (any, error) {
+		return map[string][]string{"Implements": {"NetworkDriver"}}, nil
+	})
+	handle("NetworkDriver.GetCapabilities", func(context.Context, json.RawMessage) (any, error) {
+		return map[string]string{"Scope": "local", "ConnectivityScope": "global"}, nil
+	})
+	handle("NetworkDriver.CreateNetwork", d.createNetwork)
+	handle("NetworkDriver.DeleteNetwork", d.deleteNetwork)
+	handle("NetworkDriver.CreateEndpoint", d.createEndpoint)
+	handle("NetworkDriver.DeleteEndpoint", d.deleteEndpoint)
+	handle("NetworkDriver.EndpointOperInfo", func(context.Context, json.RawMessage) (any, error) {
+		return map[string]any{"Value": struct{}{}}, nil
+	})
+	handle("NetworkDriver.Join", d.join)
+	handle("NetworkDriver.Leave", d.leave)
+	for _, method := range []string{
+		"NetworkDriver.AllocateNetwork",
+		"NetworkDriver.FreeNetwork",
+		"NetworkDriver.DiscoverNew",
+		"NetworkDriver.DiscoverDelete",
+		"NetworkDriver.ProgramExternalConnectivity",
+		"NetworkDriver.RevokeExternalConnectivity",
+	} {
+		handle(method, empty)
+	}
+	return mux
+}
+
+func reply(w http.ResponseWriter, resp any, err error) {
+	w.Header().Set("Content-Type", contentType)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		resp = map[string]string{"Err": err.Error()}
+	}
+	json.NewEncoder(w).Encode(resp) //nolint:errcheck
+}
+
+func decode(raw json.RawMessage, v any) error {
+	if err := json.Unmarshal(raw, v); err != nil {
+		return fmt.Errorf("invalid request: %w", err)
+	}
+	return nil
+}
+
+func (d *driver) createNetwork(_ context.Context, raw json.RawMessage) (any, error) {
+	var req struct {
+		NetworkID string
+		Options   map[string]json.RawMessage
+	}
+	if err := decode(raw, &req); err != nil {
+		return nil, err
+	}
+	var opts map[string]string
+	if o, ok := req.Options[genericOptions]; ok {
+		if err := json.Unmarshal(o, &opts); err != nil {
+			return nil, fmt.Errorf("invalid driver options: %w", err)
+		}
+	}
+	if opts["vpc"] == "" {
+		return nil, errors.New("the vpc option is required, e.g. -o vpc=0000000000ab")
+	}
+	vpc, _, err := endpoint.PadHex(opts["vpc"], "0")
+	if err != nil {
+		return nil, fmt.Errorf("vpc option: %w", err)
+	}
+	n := network{VPC: vpc}
+	switch opts["anycast"] {
+	case "", "false":
+	case "true":
+		n.Anycast = true
+	default:
+		return nil, fmt.Errorf("anycast option %q is not true or false", opts["anycast"])
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.Networks[req.NetworkID] = n
+	slog.Info("CREATE NETWORK", "network", req.NetworkID, "vpc", vpc)
+	return struct{}{}, d.save()
+}
+
+func (d *driver) deleteNetwork(_ context.Context, raw json.RawMessage) (any, error) {
+	var req struct{ NetworkID string }
+	if err := decode(raw, &req); err != nil {
+		return nil, err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.Networks, req.NetworkID)
+	slog.Info("DELETE NETWORK", "network", req.NetworkID)
+	return struct{}{}, d.save()
+}
+
+func (d *driver) createEndpoint(ctx context.Context, raw json.RawMessage) (any, error) {
+	var req struct {
+		NetworkID, EndpointID string
+		Interface             *struct{ Address, AddressIPv6 string }
+	}
+	if err := decode(raw, &req); err != nil {
+		return nil, err
+	}
+	if req.Interface == nil || req.Interface.Address == "" && req.Interface.AddressIPv6 == "" {
+		return nil, errors.New("endpoint has no address: give the network a subnet")
+	}
+	var ips []net.IP
+	for _, addr := range []string{req.Interface.Address, req.Interface.AddressIPv6} {
+		if addr == "" {
+			continue
+		}
+		ip, _, err := net.ParseCIDR(addr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid address %q: %w", addr, err)
+		}
+		ips = append(ips, ip)
+	}
+	d.mu.Lock()
+	n, ok := d.Networks[req.NetworkID]
+	d.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown network %s", req.NetworkID)
+	}
+	vpcAttachment, err := d.client.AllocateAttachment(ctx, n.VPC, "docker/"+req.EndpointID)
+	if err != nil {
+		return nil, fmt.Errorf("allocating a vpc attachment: %w", err)
+	}
+	e := dockerEndpoint{Network: req.NetworkID, VPCAttachment: vpcAttachment}
+	for _, hn := range wiring.HostNetworks(ips...) {
+		e.Networks = append(e.Networks, hn.String())
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.Endpoints[req.EndpointID] = e
+	slog.Info("CREATE ENDPOINT", "endpoint", req.EndpointID, "vpc", n.VPC, "vpcattachment", vpcAttachment, "networks", e.Networks)
+	// Docker assigned the addresses, so the reply must not carry an interface
+	return struct{}{}, d.save()
+}
+
+func (d *driver) deleteEndpoint(ctx context.Context, raw json.RawMessage) (any, error) {
+	var req struct{ NetworkID, EndpointID string }
+	if err := decode(raw, &req); err != nil {
+		return nil, err
+	}
+	n, e, err := d.endpoint(req.NetworkID, req.EndpointID)
+	if err != nil {
+		// already gone
+		return struct{}{}, nil
+	}
+	if err := d.client.ReleaseAttachment(ctx, n.VPC, e.VPCAttachment); err != nil {
+		return nil, fmt.Errorf("releasing vpc attachment %s/%s: %w", n.VPC, e.VPCAttachment, err)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.Endpoints, req.EndpointID)
+	slog.Info("DELETE ENDPOINT", "endpoint", req.EndpointID)
+	return struct{}{}, d.save()
+}
+
+func (d *driver) endpoint(networkID, endpointID string) (network, dockerEndpoint, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	e, ok := d.Endpoints[endpointID]
+	if !ok {
+		return network{}, e, fmt.Errorf("unknown endpoint %s", endpointID)
+	}
+	n, ok := d.Networks[networkID]
+	if !ok {
+		return n, e, fmt.Errorf("unknown network %s", networkID)
+	}
+	return n, e, nil
+}
+
+type staticRoute struct {
+	Destination string
+	RouteType   int
+	NextHop     string `json:",omitempty"`
+}
+
+// routeConnected is libnetwork's types.CONNECTED route type.
+const routeConnected = 1
+
+type joinResponse struct {
+	InterfaceName struct {
+		SrcName, DstPrefix string
+	}
+	Gateway               string `json:",omitempty"`
+	GatewayIPv6           string `json:",omitempty"`
+	StaticRoutes          []staticRoute
+	DisableGatewayService bool
+}
+
+// join wires the endpoint's attachment and registers its addresses. Docker
+// moves the guest side into the container and names it eth<n>; the gateway
+// is on-link through static routes as it is outside the network's subnet.
+func (d *driver) join(ctx context.Context, raw json.RawMessage) (any, error) {
+	var req struct{ NetworkID, EndpointID string }
+	if err := decode(raw, &req); err != nil {
+		return nil, err
+	}
+	n, e, err := d.endpoint(req.NetworkID, req.EndpointID)
+	if err != nil {
+		return nil, err
+	}
+	a, err := wiring.New(n.VPC, e.VPCAttachment)
+	if err != nil {
+		return nil, err
+	}
+	var addrs []*net.IPNet
+	for _, network := range e.Networks {
+		_, hn, err := net.ParseCIDR(network)
+		if err != nil {
+			return nil, err
+		}
+		addrs = append(addrs, hn)
+	}
+	if err := a.SetupHost(addrs); err != nil {
+		return nil, fmt.Errorf("attachment %s/%s: %w", a.VPC, a.VPCAttachment, err)
+	}
+	if n.Anycast {
+		err = d.client.RegisterAnycast(ctx, a.VPC, a.VPCAttachment, e.Networks...)
+	} else {
+		err = d.client.Register(ctx, a.VPC, a.VPCAttachment, e.Networks...)
+	}
+	if err != nil {
+		a.Teardown() //nolint:errcheck
+		return nil, fmt.Errorf("registering with the galactic agent: %w", err)
+	}
+	slog.Info("JOIN", "endpoint", req.EndpointID, "vpc", a.VPC, "vpcattachment", a.VPCAttachment)
+
+	resp := joinResponse{DisableGatewayService: true}
+	resp.InterfaceName.SrcName = a.Guest
+	resp.InterfaceName.DstPrefix = "eth"
+	for _, hn := range addrs {
+		gw := wiring.Gateway(hn.IP)
+		if gw.To4() != nil {
+			resp.Gateway = gw.String()
+		} else {
+			resp.GatewayIPv6 = gw.String()
+		}
+		resp.StaticRoutes = append(resp.StaticRoutes, staticRoute{
+			Destination: wiring.HostNetworks(gw)[0].String(),
+			RouteType:   routeConnected,
+		})
+	}
+	return resp, nil
+}
+
+func (d *driver) leave(ctx context.Context, raw json.RawMessage) (any, error) {
+	var req struct{ NetworkID, EndpointID string }
+	if err := decode(raw, &req); err != nil {
+		return nil, err
+	}
+	n, e, err := d.endpoint(req.NetworkID, req.EndpointID)
+	if err != nil {
+		return nil, err
+	}
+	a, err := wiring.New(n.VPC, e.VPCAttachment)
+	if err != nil {
+		return nil, err
+	}
+	if err := d.client.Deregister(ctx, a.VPC, a.VPCAttachment, e.Networks...); err != nil {
+		return nil, fmt.Errorf("deregistering from the galactic agent: %w", err)
+	}
+	if err := a.Teardown(); err != nil {
+		return nil, fmt.Errorf("attachment %s/%s: %w", a.VPC, a.VPCAttachment, err)
+	}
+	slog.Info("LEAVE", "endpoint", req.EndpointID, "vpc", a.VPC, "vpcattachment", a.VPCAttachment)
+	return struct{}{}, nil
+}
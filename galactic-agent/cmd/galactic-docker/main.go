@@ -0,0 +1,98 @@
+// Command galactic-docker is a Docker network driver that attaches
+// containers to Galactic VPCs through the agent's local API:
+//
+//	docker network create -d galactic -o vpc=0000000000ab --subnet 10.1.0.0/24 vpc-ab
+//	docker run --network vpc-ab ...
+//
+// Every container endpoint gets its own VPC attachment, allocated from the
+// agent, and its addresses, assigned by Docker's IPAM, are registered as
+// host networks. Compose networks use it the same way, with the vpc in
+// driver_opts.
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/datum-cloud/galactic-agent/client"
+)
+
+func main() {
+	var (
+		pluginSocket, agentSocket, tokenFile, statePath string
+	)
+	cmd := &cobra.Command{
+		Use:   "galactic-docker",
+		Short: "Docker network driver attaching containers to Galactic VPCs",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+			defer stop()
+
+			var opts []client.Option
+			if tokenFile != "" {
+				token, err := os.ReadFile(tokenFile)
+				if err != nil {
+					return err
+				}
+				opts = append(opts, client.WithToken(strings.TrimSpace(string(token))))
+			}
+			c, err := client.New(agentSocket, opts...)
+			if err != nil {
+				return err
+			}
+			defer c.Close() //nolint:errcheck
+			d, err := openDriver(c, statePath)
+			if err != nil {
+				return err
+			}
+			return serve(ctx, pluginSocket, d)
+		},
+	}
+	cmd.Flags().StringVar(&pluginSocket, "plugin-socket", "/run/docker/plugins/galactic.sock", "socket Docker discovers the driver on; its name is the driver name")
+	cmd.Flags().StringVar(&agentSocket, "socket", "", "agent local API socket (default: discovered)")
+	cmd.Flags().StringVar(&tokenFile, "token-file", "", "file holding a bearer token for the local API")
+	cmd.Flags().StringVar(&statePath, "state", "/var/lib/galactic/docker.json", "file remembering networks and endpoints across restarts")
+	if err := cmd.Execute(); err != nil {
+		os.Exit(1)
+	}
+}
+
+func serve(ctx context.Context, path string, d *driver) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	err := os.Remove(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+	s := &http.Server{Handler: d.handler(), ReadHeaderTimeout: 5 * time.Second}
+
+	routineErr := make(chan error, 1)
+	go func() {
+		slog.Info("Docker network driver listening", "socket", path)
+		if err := s.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			routineErr <- err
+			return
+		}
+		routineErr <- nil
+	}()
+
+	<-ctx.Done()
+	s.Close() //nolint:errcheck
+	return <-routineErr
+}
@@ -0,0 +1,149 @@
+// Command galactic-emulator stands in for the control plane on a single host
+// or in CI. It answers Register envelopes from any number of agents with the
+// Route pushes a full mesh needs, optionally on a broker of its own.
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/datum-cloud/galactic-agent/api/remote"
+	"github.com/datum-cloud/galactic-agent/broker"
+	"github.com/datum-cloud/galactic-agent/controller"
+	"github.com/datum-cloud/galactic-agent/tlsreload"
+)
+
+func main() {
+	var (
+		c      controller.Controller
+		listen string
+		qos    int
+
+		certFile, keyFile, caFile string
+
+		signingKeyFile, trustBundle string
+	)
+	cmd := &cobra.Command{
+		Use:   "galactic-emulator",
+		Short: "Emulate the galactic control plane with a full mesh across all registered networks",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+			defer stop()
+
+			c.QoS = byte(qos)
+			if signingKeyFile != "" {
+				key, err := remote.LoadSigningKey(signingKeyFile)
+				if err != nil {
+					return err
+				}
+				c.SigningKey = key
+				if c.KeyID == "" {
+					if c.KeyID, err = remote.KeyID(key.Public()); err != nil {
+						return err
+					}
+				}
+			}
+			if trustBundle != "" {
+				keys, err := remote.LoadKeys(trustBundle)
+				if err != nil {
+					return err
+				}
+				c.Keys = keys
+			}
+			if certFile != "" && (listen == "" || strings.HasPrefix(listen, "/")) {
+				return fmt.Errorf("--tls-cert needs --listen on host:port")
+			}
+			g, ctx := errgroup.WithContext(ctx)
+			if listen != "" {
+				b := broker.New()
+				listener, url, err := listenBroker(listen)
+				if err != nil {
+					return err
+				}
+				c.URL = url
+				if certFile != "" {
+					certs, err := tlsreload.New(certFile, keyFile, caFile)
+					if err != nil {
+						return err
+					}
+					g.Go(func() error {
+						return certs.Run(ctx)
+					})
+					// new material applies to the next handshake, so the
+					// listener never has to be rebound
+					listener = tls.NewListener(listener, certs.ServerConfig())
+					url = "ssl" + strings.TrimPrefix(url, "tcp")
+
+					// the emulator itself connects over a private socket
+					dir, err := os.MkdirTemp("", "galactic-emulator-")
+					if err != nil {
+						return err
+					}
+					defer os.RemoveAll(dir) //nolint:errcheck
+					internal, internalURL, err := listenBroker(filepath.Join(dir, "broker.sock"))
+					if err != nil {
+						return err
+					}
+					g.Go(func() error {
+						return b.Serve(ctx, internal)
+					})
+					c.URL = internalURL
+				}
+				slog.Info("emulator: broker listening", "url", url)
+				g.Go(func() error {
+					return b.Serve(ctx, listener)
+				})
+			}
+			g.Go(func() error {
+				return c.Run(ctx)
+			})
+			return g.Wait()
+		},
+	}
+	cmd.Flags().StringVar(&c.URL, "mqtt-url", "tcp://localhost:1883", "broker the agents are connected to (ignored with --listen)")
+	cmd.Flags().StringVar(&listen, "listen", "", "run an embedded broker on host:port, or on a unix socket if the value is a path")
+	cmd.Flags().StringVar(&certFile, "tls-cert", "", "serve the embedded broker over TLS with this certificate, reloaded when it changes")
+	cmd.Flags().StringVar(&keyFile, "tls-key", "", "private key for --tls-cert")
+	cmd.Flags().StringVar(&caFile, "tls-ca", "", "require client certificates issued by this CA")
+	cmd.Flags().StringVar(&signingKeyFile, "signing-key", "", "sign route pushes with this PEM private key")
+	cmd.Flags().StringVar(&c.KeyID, "key-id", "", "key id of --signing-key; defaults to its fingerprint")
+	cmd.Flags().StringVar(&trustBundle, "trust-bundle", "", "drop envelopes not signed by one of the PEM public keys in this file")
+	cmd.Flags().StringVar(&c.ClientID, "client-id", "galactic-emulator", "mqtt client id")
+	cmd.Flags().StringVar(&c.Username, "username", "", "mqtt username")
+	cmd.Flags().StringVar(&c.Password, "password", "", "mqtt password")
+	cmd.Flags().IntVar(&qos, "qos", 1, "mqtt qos for subscriptions and route pushes")
+	cmd.Flags().StringVar(&c.Prefix, "prefix", "galactic", "topic prefix; agents use <prefix>/<agent>/send and <prefix>/<agent>/receive")
+	if err := cmd.Execute(); err != nil {
+		os.Exit(1)
+	}
+}
+
+// listenBroker opens the embedded broker's listener and returns the URL
+// clients connect to.
+func listenBroker(addr string) (net.Listener, string, error) {
+	if strings.HasPrefix(addr, "/") {
+		_ = os.Remove(addr)
+		listener, err := net.Listen("unix", addr)
+		return listener, "unix://" + addr, err
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, "", err
+	}
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	if ip := net.ParseIP(host); ip == nil || ip.IsUnspecified() {
+		host = "localhost"
+	}
+	return listener, "tcp://" + net.JoinHostPort(host, port), nil
+}
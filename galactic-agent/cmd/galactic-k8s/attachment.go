@@ -0,0 +1,327 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log/slog"
+	"net/netip"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	"github.com/datum-cloud/galactic-agent/api/local"
+	"github.com/datum-cloud/galactic-agent/client"
+	"github.com/datum-cloud/galactic-agent/srv6/endpoint"
+)
+
+const (
+	group      = "galactic.datumapis.com"
+	apiVersion = group + "/v1alpha"
+
+	// podAnnotation names the VPCAttachment of a pod, in its namespace.
+	podAnnotation = "k8s.v1alpha.galactic.datumapis.com/vpc-attachment"
+)
+
+// errNoIdentifier defers an attachment whose VPC was not given an id yet.
+var errNoIdentifier = errors.New("no identifier yet")
+
+func newAttachment() *unstructured.Unstructured {
+	a := &unstructured.Unstructured{}
+	a.SetAPIVersion(apiVersion)
+	a.SetKind("VPCAttachment")
+	return a
+}
+
+// reconciler registers the VPCAttachments of the pods of its node with the
+// agent. An attachment registered on the node holds the node's finalizer,
+// and an annotation of the same key records its ids, until it is
+// deregistered.
+type reconciler struct {
+	client ctrlclient.Client
+	agent  *client.Client
+	key    string // of the finalizer and annotation
+
+	// events reconciles the attachments the agent reports changes of
+	events chan event.GenericEvent
+
+	mu     sync.Mutex
+	owners map[string]types.NamespacedName // by padded vpc/vpcattachment
+}
+
+func newReconciler(c ctrlclient.Client, agent *client.Client, node string) *reconciler {
+	return &reconciler{
+		client: c,
+		agent:  agent,
+		key:    nodeKey(node),
+		events: make(chan event.GenericEvent, 64),
+		owners: make(map[string]types.NamespacedName),
+	}
+}
+
+// nodeKey is the finalizer of node, its name cut to the 63 characters a
+// qualified name may have.
+func nodeKey(node string) string {
+	if len(node) > 63 {
+		h := fnv.New32a()
+		h.Write([]byte(node)) //nolint:errcheck
+		node = fmt.Sprintf("%s-%08x", node[:54], h.Sum32())
+	}
+	return group + "/" + node
+}
+
+func (r *reconciler) setup(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("vpcattachment").
+		For(newAttachment()).
+		Watches(&corev1.Pod{}, handler.EnqueueRequestsFromMapFunc(podAttachment)).
+		WatchesRawSource(source.Channel(r.events, &handler.EnqueueRequestForObject{})).
+		Complete(r)
+}
+
+// podAttachment maps a pod to the attachment it refers to, if any.
+func podAttachment(_ context.Context, pod ctrlclient.Object) []reconcile.Request {
+	name := pod.GetAnnotations()[podAnnotation]
+	if name == "" {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: pod.GetNamespace(), Name: name}}}
+}
+
+func (r *reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	a := newAttachment()
+	if err := r.client.Get(ctx, req.NamespacedName, a); err != nil {
+		return ctrl.Result{}, ctrlclient.IgnoreNotFound(err)
+	}
+	here, err := r.scheduledHere(ctx, a)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if !here {
+		if !controllerutil.ContainsFinalizer(a, r.key) {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, r.deregister(ctx, a)
+	}
+	vpc, vpcAttachment, err := r.ids(ctx, a)
+	if errors.Is(err, errNoIdentifier) {
+		slog.Info("VPCAttachment deferred", "namespace", a.GetNamespace(), "name", a.GetName(), "err", err)
+		return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
+	}
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	ids := vpc + "/" + vpcAttachment
+	if old := a.GetAnnotations()[r.key]; old != ids {
+		if old != "" {
+			// the ids changed under a registration: undo it first
+			return ctrl.Result{Requeue: true}, r.deregister(ctx, a)
+		}
+		controllerutil.AddFinalizer(a, r.key)
+		a.SetAnnotations(withAnnotation(a.GetAnnotations(), r.key, ids))
+		if err := r.client.Update(ctx, a); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	return ctrl.Result{}, r.register(ctx, a, vpc, vpcAttachment)
+}
+
+// scheduledHere reports whether a pod of the node refers to a, which is not
+// being deleted.
+func (r *reconciler) scheduledHere(ctx context.Context, a *unstructured.Unstructured) (bool, error) {
+	if a.GetDeletionTimestamp() != nil {
+		return false, nil
+	}
+	// the cache holds the pods of the node only
+	var pods corev1.PodList
+	if err := r.client.List(ctx, &pods, ctrlclient.InNamespace(a.GetNamespace())); err != nil {
+		return false, err
+	}
+	return slices.ContainsFunc(pods.Items, func(p corev1.Pod) bool {
+		return p.Annotations[podAnnotation] == a.GetName() && p.DeletionTimestamp == nil &&
+			p.Status.Phase != corev1.PodSucceeded && p.Status.Phase != corev1.PodFailed
+	}), nil
+}
+
+// ids returns the VPC id in the status of the VPC a refers to and the
+// attachment id in a's, allocating one from the agent if it has none.
+func (r *reconciler) ids(ctx context.Context, a *unstructured.Unstructured) (string, string, error) {
+	ref, _, err := unstructured.NestedStringMap(a.Object, "spec", "vpc")
+	if err != nil {
+		return "", "", err
+	}
+	v := &unstructured.Unstructured{}
+	v.SetAPIVersion(cmp.Or(ref["apiVersion"], apiVersion))
+	v.SetKind(cmp.Or(ref["kind"], "VPC"))
+	key := types.NamespacedName{Namespace: cmp.Or(ref["namespace"], a.GetNamespace()), Name: ref["name"]}
+	if err := r.client.Get(ctx, key, v); err != nil {
+		return "", "", err
+	}
+	vpc, _, _ := unstructured.NestedString(v.Object, "status", "identifier")
+	if vpc == "" {
+		return "", "", fmt.Errorf("vpc %s: %w", key, errNoIdentifier)
+	}
+	vpcAttachment, _, _ := unstructured.NestedString(a.Object, "status", "identifier")
+	if vpcAttachment == "" {
+		vpcAttachment, err = r.agent.AllocateAttachment(ctx, vpc, owner(a))
+		if err != nil {
+			return "", "", err
+		}
+	}
+	return vpc, vpcAttachment, nil
+}
+
+// owner is the owner of the attachment ids the agent allocates for a.
+func owner(a *unstructured.Unstructured) string {
+	return "k8s/" + a.GetNamespace() + "/" + a.GetName()
+}
+
+// register registers the addresses of a's interface, once withdrawn those
+// it no longer has, and reports how the registration fares in a's status.
+// An attachment registered already is left as it is.
+func (r *reconciler) register(ctx context.Context, a *unstructured.Unstructured, vpc, vpcAttachment string) error {
+	networks, err := hostNetworks(a)
+	if err != nil {
+		return err
+	}
+	current, err := r.agent.GetAttachment(ctx, vpc, vpcAttachment)
+	if err != nil && status.Code(err) != codes.NotFound {
+		return err
+	}
+	stale := slices.DeleteFunc(slices.Clone(current.GetNetworks()), func(n string) bool {
+		return slices.Contains(networks, n)
+	})
+	if len(stale) > 0 {
+		if err := r.agent.Deregister(ctx, vpc, vpcAttachment, stale...); err != nil {
+			return err
+		}
+	}
+	if current == nil || len(stale) > 0 || !contains(current.GetNetworks(), networks) {
+		slog.Info("REGISTER", "namespace", a.GetNamespace(), "name", a.GetName(), "vpc", vpc, "vpcattachment", vpcAttachment, "networks", networks)
+		if err := r.agent.Register(ctx, vpc, vpcAttachment, networks...); err != nil {
+			return err
+		}
+		if current, err = r.agent.GetAttachment(ctx, vpc, vpcAttachment); err != nil {
+			return err
+		}
+	}
+	r.own(current, types.NamespacedName{Namespace: a.GetNamespace(), Name: a.GetName()})
+	return r.setStatus(ctx, a, current)
+}
+
+// deregister withdraws a from the agent, as registered under the ids of
+// its annotation, and releases a's hold on it.
+func (r *reconciler) deregister(ctx context.Context, a *unstructured.Unstructured) error {
+	if vpc, vpcAttachment, ok := strings.Cut(a.GetAnnotations()[r.key], "/"); ok {
+		current, err := r.agent.GetAttachment(ctx, vpc, vpcAttachment)
+		switch {
+		case status.Code(err) == codes.NotFound:
+		case err != nil:
+			return err
+		default:
+			slog.Info("DEREGISTER", "namespace", a.GetNamespace(), "name", a.GetName(), "vpc", vpc, "vpcattachment", vpcAttachment, "networks", current.GetNetworks())
+			if err := r.agent.Deregister(ctx, vpc, vpcAttachment, current.GetNetworks()...); err != nil {
+				return err
+			}
+			r.disown(current)
+		}
+		if id, _, _ := unstructured.NestedString(a.Object, "status", "identifier"); id == "" {
+			if err := r.agent.ReleaseAttachment(ctx, vpc, vpcAttachment); err != nil && status.Code(err) != codes.NotFound {
+				return err
+			}
+		}
+	}
+	controllerutil.RemoveFinalizer(a, r.key)
+	annotations := a.GetAnnotations()
+	delete(annotations, r.key)
+	a.SetAnnotations(annotations)
+	return ctrlclient.IgnoreNotFound(r.client.Update(ctx, a))
+}
+
+// own records that changes of registered concern the attachment key.
+func (r *reconciler) own(registered *local.Attachment, key types.NamespacedName) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.owners[registered.GetVpc()+"/"+registered.GetVpcattachment()] = key
+}
+
+func (r *reconciler) disown(registered *local.Attachment) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.owners, registered.GetVpc()+"/"+registered.GetVpcattachment())
+}
+
+// changed reconciles the attachment registered as vpc and vpcAttachment,
+// if it is one of the node's.
+func (r *reconciler) changed(ctx context.Context, vpc, vpcAttachment string) {
+	if padded, paddedAttachment, err := endpoint.PadHex(vpc, vpcAttachment); err == nil {
+		vpc, vpcAttachment = padded, paddedAttachment
+	}
+	r.mu.Lock()
+	key, ok := r.owners[vpc+"/"+vpcAttachment]
+	r.mu.Unlock()
+	if !ok {
+		return
+	}
+	a := newAttachment()
+	a.SetNamespace(key.Namespace)
+	a.SetName(key.Name)
+	select {
+	case r.events <- event.GenericEvent{Object: a}:
+	case <-ctx.Done():
+	}
+}
+
+// hostNetworks are the addresses of a's interface as host prefixes.
+func hostNetworks(a *unstructured.Unstructured) ([]string, error) {
+	addresses, _, err := unstructured.NestedStringSlice(a.Object, "spec", "interface", "addresses")
+	if err != nil {
+		return nil, err
+	}
+	networks := make([]string, 0, len(addresses))
+	for _, s := range addresses {
+		addr, err := netip.ParseAddr(s)
+		if err != nil {
+			prefix, perr := netip.ParsePrefix(s)
+			if perr != nil {
+				return nil, fmt.Errorf("interface address %q: %w", s, err)
+			}
+			addr = prefix.Addr()
+		}
+		networks = append(networks, netip.PrefixFrom(addr, addr.BitLen()).String())
+	}
+	return networks, nil
+}
+
+func contains(networks, subset []string) bool {
+	for _, n := range subset {
+		if !slices.Contains(networks, n) {
+			return false
+		}
+	}
+	return true
+}
+
+func withAnnotation(annotations map[string]string, key, value string) map[string]string {
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[key] = value
+	return annotations
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/datum-cloud/galactic-agent/api/local"
+)
+
+func TestHostNetworks(t *testing.T) {
+	a := newAttachment()
+	a.Object["spec"] = map[string]any{"interface": map[string]any{"addresses": []any{"10.1.1.100/24", "2001:db8:beef:1::100/64", "10.1.1.101"}}}
+	got, err := hostNetworks(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"10.1.1.100/32", "2001:db8:beef:1::100/128", "10.1.1.101/32"}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	a.Object["spec"] = map[string]any{"interface": map[string]any{"addresses": []any{"10.1.1"}}}
+	if _, err := hostNetworks(a); err == nil {
+		t.Error("malformed address accepted")
+	}
+}
+
+func TestConditions(t *testing.T) {
+	for _, tt := range []struct {
+		delivery   string
+		routes     uint32
+		registered metav1.ConditionStatus
+		converged  metav1.ConditionStatus
+	}{
+		{"", 0, metav1.ConditionUnknown, metav1.ConditionFalse},
+		{"pending", 3, metav1.ConditionUnknown, metav1.ConditionFalse},
+		{"failed: broker down", 0, metav1.ConditionFalse, metav1.ConditionFalse},
+		{"delivered", 0, metav1.ConditionTrue, metav1.ConditionFalse},
+		{"delivered", 3, metav1.ConditionTrue, metav1.ConditionTrue},
+	} {
+		c := conditions(&local.Attachment{Delivery: tt.delivery, Routes: tt.routes}, 7)
+		if c[0].Type != conditionRegistered || c[0].Status != tt.registered || c[1].Type != conditionRoutesConverged || c[1].Status != tt.converged {
+			t.Errorf("delivery %q, %d routes: got %s %s, %s %s", tt.delivery, tt.routes, c[0].Type, c[0].Status, c[1].Type, c[1].Status)
+		}
+		for _, c := range c {
+			if c.ObservedGeneration != 7 || c.Reason == "" {
+				t.Errorf("delivery %q, %d routes: %+v", tt.delivery, tt.routes, c)
+			}
+		}
+	}
+}
+
+func TestNodeKey(t *testing.T) {
+	for _, node := range []string{"node-1", "ip-10-0-0-1.ec2.internal", strings.Repeat("n", 253)} {
+		if errs := validation.IsQualifiedName(nodeKey(node)); len(errs) > 0 {
+			t.Errorf("nodeKey(%q) = %q: %v", node, nodeKey(node), errs)
+		}
+	}
+	if long := strings.Repeat("n", 100); nodeKey(long) == nodeKey(long+"x") {
+		t.Error("long node names collide")
+	}
+}
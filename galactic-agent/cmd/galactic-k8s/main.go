@@ -0,0 +1,127 @@
+// Command galactic-k8s runs on every node, next to the agent, and
+// registers the VPCAttachments of the pods scheduled on the node through
+// the agent's local API:
+//
+//	metadata:
+//	  annotations:
+//	    k8s.v1alpha.galactic.datumapis.com/vpc-attachment: vpc-attach-sjc
+//
+// An attachment is registered once a pod of its namespace on the node
+// refers to it, with the addresses of its interface as host networks, and
+// deregistered once none does or it is deleted. The VPC and attachment ids
+// are the identifiers in the status of the VPC the attachment refers to
+// and of the attachment; without one, the attachment id is allocated by
+// the agent. The Registered and RoutesConverged conditions of the
+// attachment's status follow what the agent's Watch stream reports of it.
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+
+	"github.com/datum-cloud/galactic-agent/api/local"
+	"github.com/datum-cloud/galactic-agent/client"
+)
+
+func main() {
+	var (
+		node, agentSocket, tokenFile, metricsAddr string
+	)
+	cmd := &cobra.Command{
+		Use:   "galactic-k8s",
+		Short: "Registers the VPCAttachments of a node's pods with its galactic agent",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if node == "" {
+				return errors.New("--node is required")
+			}
+			ctrl.SetLogger(logr.FromSlogHandler(slog.Default().Handler()))
+
+			var opts []client.Option
+			if tokenFile != "" {
+				token, err := os.ReadFile(tokenFile)
+				if err != nil {
+					return err
+				}
+				opts = append(opts, client.WithToken(strings.TrimSpace(string(token))))
+			}
+			c, err := client.New(agentSocket, opts...)
+			if err != nil {
+				return err
+			}
+			defer c.Close() //nolint:errcheck
+
+			scheme := runtime.NewScheme()
+			if err := clientgoscheme.AddToScheme(scheme); err != nil {
+				return err
+			}
+			mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
+				Scheme:  scheme,
+				Metrics: metricsserver.Options{BindAddress: metricsAddr},
+				// only the pods of this node matter
+				Cache: cache.Options{ByObject: map[ctrlclient.Object]cache.ByObject{
+					&corev1.Pod{}: {Field: fields.OneTermEqualSelector("spec.nodeName", node)},
+				}},
+				Client: ctrlclient.Options{Cache: &ctrlclient.CacheOptions{Unstructured: true}},
+			})
+			if err != nil {
+				return err
+			}
+			r := newReconciler(mgr.GetClient(), c, node)
+			if err := r.setup(mgr); err != nil {
+				return err
+			}
+			if err := mgr.Add(manager.RunnableFunc(func(ctx context.Context) error {
+				return watchAgent(ctx, c, r)
+			})); err != nil {
+				return err
+			}
+			return mgr.Start(ctrl.SetupSignalHandler())
+		},
+	}
+	cmd.Flags().StringVar(&node, "node", os.Getenv("NODE_NAME"), "name of the node the agent runs on (default: $NODE_NAME)")
+	cmd.Flags().StringVar(&agentSocket, "socket", "", "agent local API socket (default: discovered)")
+	cmd.Flags().StringVar(&tokenFile, "token-file", "", "file holding a bearer token for the local API")
+	cmd.Flags().StringVar(&metricsAddr, "metrics-addr", "0", "address to serve controller metrics on, 0 for none")
+	if err := cmd.Execute(); err != nil {
+		os.Exit(1)
+	}
+}
+
+// watchAgent follows the agent's registry, reconciling the attachment
+// behind every change, until ctx is done. It watches again after a
+// failure.
+func watchAgent(ctx context.Context, c *client.Client, r *reconciler) error {
+	for {
+		err := c.Watch(ctx, "", func(e *local.AttachmentEvent) error {
+			if a := e.GetAttachment(); a != nil {
+				r.changed(ctx, a.GetVpc(), a.GetVpcattachment())
+			}
+			return nil
+		})
+		if ctx.Err() != nil {
+			return nil
+		}
+		slog.Warn("Agent watch", "err", err)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(time.Second):
+		}
+	}
+}
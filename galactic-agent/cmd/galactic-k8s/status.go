@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/datum-cloud/galactic-agent/api/local"
+)
+
+// The conditions of a registered attachment's status.
+const (
+	// conditionRegistered is True once the agent delivered the
+	// registration to its controller.
+	conditionRegistered = "Registered"
+	// conditionRoutesConverged is True once, in addition, the agent
+	// installed routes from the controller for the attachment.
+	conditionRoutesConverged = "RoutesConverged"
+)
+
+// conditions are those of an attachment of the given generation as the
+// agent reports it registered.
+func conditions(registered *local.Attachment, generation int64) []metav1.Condition {
+	reg := metav1.Condition{Type: conditionRegistered, ObservedGeneration: generation}
+	routes := metav1.Condition{Type: conditionRoutesConverged, ObservedGeneration: generation, Status: metav1.ConditionFalse}
+	switch delivery := registered.GetDelivery(); {
+	case delivery == "delivered":
+		reg.Status, reg.Reason, reg.Message = metav1.ConditionTrue, "Delivered", "the controller received the registration"
+	case strings.HasPrefix(delivery, "failed: "):
+		reg.Status, reg.Reason, reg.Message = metav1.ConditionFalse, "DeliveryFailed", strings.TrimPrefix(delivery, "failed: ")
+	default:
+		reg.Status, reg.Reason, reg.Message = metav1.ConditionUnknown, "Pending", "the registration is on its way to the controller"
+	}
+	switch n := registered.GetRoutes(); {
+	case reg.Status != metav1.ConditionTrue:
+		routes.Reason, routes.Message = "NotRegistered", "the registration has not reached the controller"
+	case n == 0:
+		routes.Reason, routes.Message = "NoRoutes", "no routes received from the controller"
+	default:
+		routes.Status, routes.Reason, routes.Message = metav1.ConditionTrue, "RoutesInstalled", fmt.Sprintf("%d routes installed", n)
+	}
+	return []metav1.Condition{reg, routes}
+}
+
+// setStatus updates the conditions of a to those of registered, if they
+// changed.
+func (r *reconciler) setStatus(ctx context.Context, a *unstructured.Unstructured, registered *local.Attachment) error {
+	raw, _, err := unstructured.NestedSlice(a.Object, "status", "conditions")
+	if err != nil {
+		return err
+	}
+	current := make([]metav1.Condition, len(raw))
+	for i, c := range raw {
+		m, ok := c.(map[string]any)
+		if !ok {
+			return fmt.Errorf("status.conditions[%d] is not an object", i)
+		}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(m, &current[i]); err != nil {
+			return err
+		}
+	}
+	changed := false
+	for _, c := range conditions(registered, a.GetGeneration()) {
+		changed = meta.SetStatusCondition(&current, c) || changed
+	}
+	if !changed {
+		return nil
+	}
+	raw = make([]any, len(current))
+	for i := range current {
+		m, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&current[i])
+		if err != nil {
+			return err
+		}
+		raw[i] = m
+	}
+	if err := unstructured.SetNestedSlice(a.Object, raw, "status", "conditions"); err != nil {
+		return err
+	}
+	return r.client.Status().Update(ctx, a)
+}
@@ -0,0 +1,219 @@
+// Command galactic-testctl injects control messages into a running
+// galactic-agent through its MQTT broker, using the generated remote API and
+// the same SRv6 endpoint encoding as the agent itself.
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"os"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/datum-cloud/galactic-agent/api/remote"
+	"github.com/datum-cloud/galactic-agent/fixtures"
+	"github.com/datum-cloud/galactic-agent/srv6/endpoint"
+)
+
+var (
+	configFile string
+	dryRun     bool
+)
+
+func initConfig() {
+	viper.SetDefault("srv6_net", "fc00::/56")
+	viper.SetDefault("mqtt_url", "tcp://localhost:1883")
+	viper.SetDefault("mqtt_qos", 1)
+	viper.SetDefault("mqtt_topic_receive", "galactic/default/receive")
+	if configFile != "" {
+		viper.SetConfigFile(configFile)
+	}
+	viper.AutomaticEnv()
+	if err := viper.ReadInConfig(); err == nil {
+		slog.Info("Using config file", "path", viper.ConfigFileUsed())
+	}
+}
+
+func main() {
+	cmd := &cobra.Command{
+		Use:   "galactic-testctl",
+		Short: "Inject test routes into a galactic-agent",
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			initConfig()
+		},
+	}
+	cmd.PersistentFlags().StringVar(&configFile, "config", "", "agent config file to take the broker, topic and srv6_net from")
+	cmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "print the encoded envelopes instead of publishing them")
+	cmd.AddCommand(newRouteCmd(remote.Route_ADD))
+	cmd.AddCommand(newRouteCmd(remote.Route_DELETE))
+	cmd.AddCommand(newVRFCmd())
+	cmd.AddCommand(newDemoCmd())
+	if err := cmd.Execute(); err != nil {
+		os.Exit(1)
+	}
+}
+
+// route is one route as the controller would send it to this agent: traffic
+// from the local attachment to network is encapsulated towards segments.
+type route struct {
+	network       string
+	vpc           string
+	vpcAttachment string
+	segments      []string
+}
+
+func (r route) envelope(status remote.Route_Status) (*remote.Envelope, error) {
+	srv6Endpoint, err := endpoint.Encode(viper.GetString("srv6_net"), r.vpc, r.vpcAttachment)
+	if err != nil {
+		return nil, err
+	}
+	return &remote.Envelope{
+		Kind: &remote.Envelope_Route{
+			Route: &remote.Route{
+				Status:       status,
+				Network:      r.network,
+				Srv6Endpoint: srv6Endpoint,
+				Srv6Segments: r.segments,
+			},
+		},
+	}, nil
+}
+
+// publish sends the routes to the agent's receive topic, or prints them
+// when --dry-run is set.
+func publish(status remote.Route_Status, routes ...route) error {
+	topic := viper.GetString("mqtt_topic_receive")
+	var client mqtt.Client
+	if !dryRun {
+		opts := mqtt.NewClientOptions().AddBroker(viper.GetString("mqtt_url"))
+		if u := viper.GetString("mqtt_username"); u != "" {
+			opts.SetUsername(u)
+		}
+		if p := viper.GetString("mqtt_password"); p != "" {
+			opts.SetPassword(p)
+		}
+		client = mqtt.NewClient(opts)
+		if tok := client.Connect(); tok.Wait() && tok.Error() != nil {
+			return tok.Error()
+		}
+		defer client.Disconnect(250)
+	}
+
+	for _, r := range routes {
+		envelope, err := r.envelope(status)
+		if err != nil {
+			return err
+		}
+		payload, err := proto.Marshal(envelope)
+		if err != nil {
+			return err
+		}
+		kind := envelope.GetRoute()
+		fmt.Printf("%s %s: srv6_endpoint=%s srv6_segments=%v\n", kind.Status, kind.Network, kind.Srv6Endpoint, kind.Srv6Segments)
+		if dryRun {
+			fmt.Printf("  %s\n", hex.EncodeToString(payload))
+			continue
+		}
+		if tok := client.Publish(topic, byte(viper.GetInt("mqtt_qos")), false, payload); tok.Wait() && tok.Error() != nil {
+			return tok.Error()
+		}
+	}
+	if !dryRun {
+		fmt.Printf("Published %d route(s) to %s\n", len(routes), topic)
+	}
+	return nil
+}
+
+func newRouteCmd(status remote.Route_Status) *cobra.Command {
+	var r route
+	use := "route-add"
+	if status == remote.Route_DELETE {
+		use = "route-del"
+	}
+	cmd := &cobra.Command{
+		Use:   use + " <network>",
+		Short: fmt.Sprintf("Send a Route %s for network to the agent", status),
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			r.network = args[0]
+			return publish(status, r)
+		},
+	}
+	cmd.Flags().StringVar(&r.vpc, "vpc", "", "vpc id (hex) of the local attachment")
+	cmd.Flags().StringVar(&r.vpcAttachment, "vpcattachment", "", "vpc attachment id (hex) of the local attachment")
+	cmd.Flags().StringSliceVar(&r.segments, "segments", nil, "SRv6 segments towards the remote attachment")
+	cmd.MarkFlagRequired("vpc")           //nolint:errcheck
+	cmd.MarkFlagRequired("vpcattachment") //nolint:errcheck
+	cmd.MarkFlagRequired("segments")      //nolint:errcheck
+	return cmd
+}
+
+func newVRFCmd() *cobra.Command {
+	var a fixtures.Attachment
+	cmd := &cobra.Command{
+		Use:   "vrf-create",
+		Short: "Create the VRF and interfaces the CNI would for an attachment",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := fixtures.EnsureLoopback(); err != nil {
+				return err
+			}
+			if err := fixtures.Create(a); err != nil {
+				return err
+			}
+			table, err := fixtures.Table(a)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Attachment %s/%s ready in table %d\n", a.VPC, a.VPCAttachment, table)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&a.VPC, "vpc", "", "vpc id (hex)")
+	cmd.Flags().StringVar(&a.VPCAttachment, "vpcattachment", "", "vpc attachment id (hex)")
+	cmd.MarkFlagRequired("vpc")           //nolint:errcheck
+	cmd.MarkFlagRequired("vpcattachment") //nolint:errcheck
+	return cmd
+}
+
+// The WSL lab demo: one VPC attached at AMS and IAD, each with a route
+// towards the other POP's locator.
+var demoRoutes = []route{
+	{network: "192.168.2.0/24", vpc: "000000000001", vpcAttachment: "0001", segments: []string{"fc00:0:3::"}},
+	{network: "192.168.3.0/24", vpc: "000000000001", vpcAttachment: "0002", segments: []string{"fc00:0:2::"}},
+}
+
+func newDemoCmd() *cobra.Command {
+	var (
+		provision bool
+		remove    bool
+	)
+	cmd := &cobra.Command{
+		Use:   "demo",
+		Short: "Set up the lab attachments and inject the AMS and IAD demo routes",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if provision && !dryRun {
+				if err := fixtures.EnsureLoopback(); err != nil {
+					return err
+				}
+				for _, r := range demoRoutes {
+					a := fixtures.Attachment{VPC: r.vpc, VPCAttachment: r.vpcAttachment}
+					if err := fixtures.Create(a); err != nil {
+						return fmt.Errorf("attachment %s/%s: %w", r.vpc, r.vpcAttachment, err)
+					}
+				}
+			}
+			status := remote.Route_ADD
+			if remove {
+				status = remote.Route_DELETE
+			}
+			return publish(status, demoRoutes...)
+		},
+	}
+	cmd.Flags().BoolVar(&provision, "provision", true, "create the demo VRFs and interfaces first")
+	cmd.Flags().BoolVar(&remove, "delete", false, "withdraw the demo routes instead")
+	return cmd
+}
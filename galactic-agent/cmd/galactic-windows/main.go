@@ -0,0 +1,236 @@
+// Command galactic-windows is the Windows side of a WSL agent. It routes the
+// VPC prefixes the agent shares with Windows toward WSL, and reports the
+// Windows host's network interfaces to the agent so replies find their way
+// back, including after Windows renumbers the WSL network.
+//
+// It talks to the agent's companion listener, companion_addr, over TCP:
+//
+//	galactic-windows.exe --agent localhost:7310 --token-file token.txt
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/datum-cloud/galactic-agent/api/companion"
+)
+
+func main() {
+	var (
+		agent, tokenFile, host string
+		interval               time.Duration
+	)
+	cmd := &cobra.Command{
+		Use:   "galactic-windows",
+		Short: "Route Windows host traffic for Galactic VPCs through WSL",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			b, err := os.ReadFile(tokenFile)
+			if err != nil {
+				return err
+			}
+			token := strings.TrimSpace(string(b))
+			if host == "" {
+				host, _ = os.Hostname()
+			}
+			conn, err := grpc.NewClient(agent,
+				grpc.WithTransportCredentials(insecure.NewCredentials()),
+				grpc.WithUnaryInterceptor(func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+					return invoker(metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token), method, req, reply, cc, opts...)
+				}),
+				grpc.WithStreamInterceptor(func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+					return streamer(metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token), desc, cc, method, opts...)
+				}),
+			)
+			if err != nil {
+				return err
+			}
+			defer conn.Close() //nolint:errcheck
+			h := &helper{client: companion.NewCompanionClient(conn), host: host, installed: make(map[string]route)}
+			defer h.removeAll()
+
+			g, ctx := errgroup.WithContext(ctx)
+			g.Go(func() error {
+				return h.watchRoutes(ctx)
+			})
+			g.Go(func() error {
+				return h.reportInterfaces(ctx, interval)
+			})
+			return g.Wait()
+		},
+	}
+	cmd.Flags().StringVar(&agent, "agent", "localhost:7310", "companion_addr of the agent in WSL")
+	cmd.Flags().StringVar(&tokenFile, "token-file", "", "file holding the agent's companion_token")
+	cmd.Flags().StringVar(&host, "host", "", "name of this host in the agent's logs (default: hostname)")
+	cmd.Flags().DurationVar(&interval, "interval", 5*time.Second, "how often to look for interface changes")
+	cmd.MarkFlagRequired("token-file") //nolint:errcheck
+	if err := cmd.Execute(); err != nil {
+		os.Exit(1)
+	}
+}
+
+// route is a Windows host route toward WSL.
+type route struct {
+	prefix  *net.IPNet
+	nextHop net.IP
+}
+
+type helper struct {
+	client companion.CompanionClient
+	host   string
+
+	mu        sync.Mutex
+	installed map[string]route
+	// reported is the last report the agent accepted; reset when the agent
+	// reconnects so it hears about the interfaces again.
+	reported *companion.InterfaceReport
+}
+
+const retryInterval = 5 * time.Second
+
+// watchRoutes follows the agent's route set, reconnecting until ctx is done.
+func (h *helper) watchRoutes(ctx context.Context) error {
+	for ctx.Err() == nil {
+		stream, err := h.client.WatchRoutes(ctx, &companion.WatchRoutesRequest{Host: h.host})
+		for err == nil {
+			var set *companion.RouteSet
+			if set, err = stream.Recv(); err == nil {
+				h.apply(set)
+			}
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		slog.Warn("Agent unavailable", "err", err)
+		h.mu.Lock()
+		h.reported = nil
+		h.mu.Unlock()
+		select {
+		case <-ctx.Done():
+		case <-time.After(retryInterval):
+		}
+	}
+	return nil
+}
+
+// apply installs the routes of set that are missing and removes those no
+// longer in it.
+func (h *helper) apply(set *companion.RouteSet) {
+	want := make(map[string]route)
+	for _, p := range set.GetPrefixes() {
+		_, prefix, err := net.ParseCIDR(p)
+		if err != nil {
+			slog.Warn("Ignoring prefix", "prefix", p, "err", err)
+			continue
+		}
+		nextHop := net.ParseIP(set.GetNextHop6())
+		if prefix.IP.To4() != nil {
+			nextHop = net.ParseIP(set.GetNextHop4())
+		}
+		if nextHop == nil {
+			slog.Warn("Ignoring prefix: WSL has no address of its family", "prefix", prefix)
+			continue
+		}
+		want[prefix.String()+" via "+nextHop.String()] = route{prefix: prefix, nextHop: nextHop}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for key, r := range h.installed {
+		if _, ok := want[key]; ok {
+			continue
+		}
+		if err := delRoute(r); err != nil {
+			slog.Error("Remove route", "route", key, "err", err)
+			continue
+		}
+		slog.Info("Removed route", "route", key)
+		delete(h.installed, key)
+	}
+	for key, r := range want {
+		if _, ok := h.installed[key]; ok {
+			continue
+		}
+		if err := addRoute(r); err != nil {
+			slog.Error("Add route", "route", key, "err", err)
+			continue
+		}
+		slog.Info("Added route", "route", key)
+		h.installed[key] = r
+	}
+}
+
+func (h *helper) removeAll() {
+	h.apply(&companion.RouteSet{})
+}
+
+// reportInterfaces sends the interfaces to the agent whenever they change.
+func (h *helper) reportInterfaces(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		report, err := interfaces(h.host)
+		if err != nil {
+			slog.Warn("List interfaces", "err", err)
+		}
+		h.mu.Lock()
+		changed := report != nil && !proto.Equal(report, h.reported)
+		h.mu.Unlock()
+		if changed {
+			if reply, err := h.client.ReportInterfaces(ctx, report); err != nil {
+				slog.Warn("Report interfaces", "err", err)
+			} else {
+				slog.Info("Reported interfaces", "interfaces", len(report.Interfaces), "routing_back", reply.GetAccepted())
+				h.mu.Lock()
+				h.reported = report
+				h.mu.Unlock()
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func interfaces(host string) (*companion.InterfaceReport, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+	report := &companion.InterfaceReport{Host: host}
+	for _, i := range ifaces {
+		if i.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		addrs, err := i.Addrs()
+		if err != nil {
+			return nil, err
+		}
+		ci := &companion.Interface{Name: i.Name, Index: uint32(i.Index), Up: i.Flags&net.FlagUp != 0}
+		for _, a := range addrs {
+			ci.Addresses = append(ci.Addresses, a.String())
+		}
+		slices.Sort(ci.Addresses)
+		report.Interfaces = append(report.Interfaces, ci)
+	}
+	return report, nil
+}
@@ -0,0 +1,18 @@
+//go:build !windows
+
+package main
+
+import "log/slog"
+
+// Elsewhere the helper only logs the routes it would program, which is
+// enough to try the protocol against an agent.
+
+func addRoute(r route) error {
+	slog.Info("Would add route", "prefix", r.prefix, "next_hop", r.nextHop)
+	return nil
+}
+
+func delRoute(r route) error {
+	slog.Info("Would remove route", "prefix", r.prefix, "next_hop", r.nextHop)
+	return nil
+}
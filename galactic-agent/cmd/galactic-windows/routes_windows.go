@@ -0,0 +1,65 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"net"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// addRoute adds an active (not persistent) route with netsh, on the
+// interface the next hop is on-link for, the WSL virtual adapter.
+func addRoute(r route) error {
+	return netsh(r, "add")
+}
+
+func delRoute(r route) error {
+	return netsh(r, "delete")
+}
+
+func netsh(r route, op string) error {
+	index, err := interfaceFor(r.nextHop)
+	if err != nil {
+		return err
+	}
+	family := "ipv6"
+	if r.prefix.IP.To4() != nil {
+		family = "ipv4"
+	}
+	args := []string{"interface", family, op, "route",
+		"prefix=" + r.prefix.String(),
+		"interface=" + strconv.Itoa(index),
+		"nexthop=" + r.nextHop.String()}
+	if op == "add" {
+		args = append(args, "store=active")
+	}
+	out, err := exec.Command("netsh", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("netsh %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// interfaceFor returns the index of the interface with a subnet containing
+// ip.
+func interfaceFor(ip net.IP) (int, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return 0, err
+	}
+	for _, i := range ifaces {
+		addrs, err := i.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, a := range addrs {
+			if n, ok := a.(*net.IPNet); ok && n.Contains(ip) {
+				return i.Index, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("no interface is on-link for %s", ip)
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/datum-cloud/galactic-agent/api/remote"
+	"github.com/datum-cloud/galactic-agent/metrics"
+	"github.com/datum-cloud/galactic-agent/state"
+	"github.com/datum-cloud/galactic-agent/tracing"
+)
+
+var routesCoalesced = metrics.NewCounter(
+	"galactic_agent_routes_coalesced_total",
+	"Route updates superseded within route_coalesce_window by a later update for the same network and endpoint.",
+	"vpc",
+)
+
+// coalesce holds received routes for route_coalesce_window and programs
+// only the last update of each network and endpoint, so that ADD, DELETE,
+// ADD in quick succession costs the kernel one change; nil unless the
+// window is set.
+var coalesce *coalescer
+
+type pendingRoute struct {
+	d        *domain
+	route    *remote.Route
+	segments []string
+	trace    tracing.SpanContext
+	source   string
+	received time.Time
+}
+
+type coalescer struct {
+	window time.Duration
+
+	mu      sync.Mutex
+	pending map[string]pendingRoute // by endpoint and network
+	order   []string
+	timer   *time.Timer
+
+	// applying keeps the batches in order when one takes longer than the
+	// window
+	applying sync.Mutex
+}
+
+func loadCoalescer(window time.Duration) *coalescer {
+	if window <= 0 {
+		return nil
+	}
+	return &coalescer{window: window, pending: make(map[string]pendingRoute)}
+}
+
+// add queues a copy of route, which passed the checks of its ADD already,
+// replacing any update of the same network and endpoint queued before it.
+func (c *coalescer) add(ctx context.Context, d *domain, route *remote.Route, segments []string) {
+	route = proto.Clone(route).(*remote.Route)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := route.Srv6Endpoint + " " + route.Network
+	if _, ok := c.pending[key]; ok {
+		routesCoalesced.Inc(endpointVPC(route.Srv6Endpoint))
+	} else {
+		c.order = append(c.order, key)
+	}
+	c.pending[key] = pendingRoute{d: d, route: route, segments: segments, trace: tracing.FromContext(ctx), source: auditSource(ctx), received: received(ctx)}
+	if c.timer == nil {
+		c.timer = time.AfterFunc(c.window, c.flush)
+	}
+}
+
+// usage counts the ADDs queued for endpoint, other than of network, of
+// routes store does not hold yet, as state.Store.EgressUsage does, so that
+// a burst within the window can't take an attachment over its quota.
+func (c *coalescer) usage(store *state.Store, endpoint, network string) (routes, hosts int) {
+	if c == nil {
+		return 0, 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, p := range c.pending {
+		r, h := pendingUsage(store, endpoint, network, p.route)
+		routes, hosts = routes+r, hosts+h
+	}
+	return routes, hosts
+}
+
+// pendingUsage is 1 route, and 1 host if it is one, for route if it is an
+// ADD for endpoint, other than of network, of a route store does not hold
+// yet: one accepted but not applied yet.
+func pendingUsage(store *state.Store, endpoint, network string, route *remote.Route) (routes, hosts int) {
+	if route.Status != remote.Route_ADD || route.Srv6Endpoint != endpoint || route.Network == network {
+		return 0, 0
+	}
+	if _, ok := store.Egress(route.Network, endpoint); ok {
+		return 0, 0
+	}
+	if state.IsHost(route.Network) {
+		return 1, 1
+	}
+	return 1, 0
+}
+
+// flush programs the queued routes in the order they first arrived,
+// journaled together and saved to the stores at once.
+func (c *coalescer) flush() {
+	c.applying.Lock()
+	defer c.applying.Unlock()
+	c.mu.Lock()
+	pending, order := c.pending, c.order
+	c.pending, c.order = make(map[string]pendingRoute), nil
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+	c.mu.Unlock()
+	apply := make([]pendingRoute, 0, len(order))
+	domains := make([]*domain, 0, len(order))
+	entries := make([]any, 0, len(order))
+	for _, key := range order {
+		p := pending[key]
+		// a route added and deleted within the window never reached the
+		// kernel
+		if p.route.Status == remote.Route_DELETE {
+			if _, ok := p.d.store.Egress(p.route.Network, p.route.Srv6Endpoint); !ok {
+				continue
+			}
+		}
+		apply = append(apply, p)
+		domains = append(domains, p.d)
+		entries = append(entries, routeEntry(p.d, p.route, p.segments))
+	}
+	seqs := journalBeginAll(journalRoute, entries)
+	release := holdStores(domains...)
+	for _, p := range apply {
+		if err := programRoute(tracing.WithSpanContext(withReceived(withAuditSource(context.Background(), p.source), p.received), p.trace), p.d, p.route, p.segments); err != nil {
+			slog.Error("ROUTE", append(endpointAttrs(p.route.Srv6Endpoint), "status", p.route.Status.String(), "network", p.route.Network, "err", err)...)
+		}
+	}
+	release()
+	journalDoneAll(seqs)
+}
+
+// holdStores holds the stores of ds, see state.Store.Hold, until the
+// returned func releases them.
+func holdStores(ds ...*domain) (release func()) {
+	held := make(map[*state.Store]bool)
+	for _, d := range ds {
+		if !held[d.store] {
+			d.store.Hold()
+			held[d.store] = true
+		}
+	}
+	return func() {
+		for store := range held {
+			if err := store.Release(); err != nil {
+				slog.Error("State store", "err", err)
+			}
+		}
+	}
+}
+
+// applyRoute programs a received route that passed its checks and records
+// it in the store, journaled until both are done, traced as part of the
+// span in ctx and audited as coming from its source.
+func applyRoute(ctx context.Context, d *domain, route *remote.Route, segments []string) error {
+	seq := journalBegin(journalRoute, routeEntry(d, route, segments))
+	defer journalDone(seq)
+	return programRoute(ctx, d, route, segments)
+}
+
+// routeEntry is the journal entry of route, for d, with segments.
+func routeEntry(d *domain, route *remote.Route, segments []string) journaledRoute {
+	return journaledRoute{
+		Tenant:   d.Name,
+		Status:   route.Status.String(),
+		Network:  route.Network,
+		Endpoint: route.Srv6Endpoint,
+		Segments: segments,
+		DSCP:     route.Dscp,
+	}
+}
+
+// programRoute is applyRoute for a route journaled already, to be marked
+// done by the caller once the store saved it.
+func programRoute(ctx context.Context, d *domain, route *remote.Route, segments []string) (err error) {
+	ctx, span := tracing.Start(ctx, "Route", tracing.Internal, "tenant", d.Name, "status", route.Status.String(), "network", route.Network, "srv6_endpoint", route.Srv6Endpoint)
+	defer func() {
+		auditRoute(ctx, d, route, segments, err)
+		span.End(err)
+	}()
+	switch route.Status {
+	case remote.Route_ADD:
+		mtu := attachmentMTU(d.store, route.Srv6Endpoint)
+		_, egress := tracing.Start(ctx, "EgressAdd", tracing.Internal, "segments", len(segments), "mtu", mtu)
+		err := dp.EgressAdd(route.Network, route.Srv6Endpoint, segments, mtu)
+		endDataplane(egress, err)
+		if err != nil {
+			return err
+		}
+		observeProgrammed(ctx, "route_add")
+		if err := markRoute(d.store, route); err != nil {
+			return err
+		}
+		if err := d.store.AddEgress(route.Network, route.Srv6Endpoint, segments, int(route.Dscp)); err != nil {
+			slog.Error("State store", append(endpointAttrs(route.Srv6Endpoint), "network", route.Network, "err", err)...)
+		}
+		trackNetwork(endpointVPC(route.Srv6Endpoint), route.Network, "route:"+route.Srv6Endpoint)
+	case remote.Route_DELETE:
+		_, egress := tracing.Start(ctx, "EgressDel", tracing.Internal)
+		err := dp.EgressDel(route.Network, route.Srv6Endpoint, segments)
+		endDataplane(egress, err)
+		if err != nil {
+			return err
+		}
+		observeProgrammed(ctx, "route_delete")
+		if err := unmarkRoute(d.store, route); err != nil {
+			slog.Warn("DSCP marking", append(endpointAttrs(route.Srv6Endpoint), "network", route.Network, "err", err)...)
+		}
+		if err := d.store.DelEgress(route.Network, route.Srv6Endpoint); err != nil {
+			slog.Error("State store", append(endpointAttrs(route.Srv6Endpoint), "network", route.Network, "err", err)...)
+		}
+		untrackNetwork(endpointVPC(route.Srv6Endpoint), route.Network, "route:"+route.Srv6Endpoint)
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"slices"
+	"sort"
+	"sync"
+
+	"github.com/spf13/viper"
+	"github.com/vishvananda/netlink"
+
+	"github.com/datum-cloud/galactic-agent/api/companion"
+	"github.com/datum-cloud/galactic-agent/metrics"
+	"github.com/datum-cloud/galactic-agent/routeleak"
+	"github.com/datum-cloud/galactic-agent/srv6/endpoint"
+)
+
+var companionReports = metrics.NewCounter(
+	"galactic_agent_companion_interface_reports_total",
+	"Interface reports received from the Windows helper.",
+)
+
+// windows connects the Windows side of WSL to the VPCs in companion_vpcs;
+// nil unless companion_addr is set.
+var windows *windowsHost
+
+type windowsHost struct {
+	iface string
+	// vpcs maps each shared VPC to the attachment whose VRF its traffic
+	// from Windows enters.
+	vpcs map[string]string
+	wake chan struct{}
+
+	mu    sync.Mutex
+	hosts []net.IP
+}
+
+func loadCompanion() (*windowsHost, error) {
+	if viper.GetString("companion_addr") == "" {
+		return nil, nil
+	}
+	if viper.GetString("companion_token") == "" {
+		return nil, errors.New("companion_token is required with companion_addr")
+	}
+	w := &windowsHost{
+		iface: viper.GetString("companion_interface"),
+		vpcs:  make(map[string]string),
+		wake:  make(chan struct{}, 1),
+	}
+	for vpc, vpcAttachment := range viper.GetStringMapString("companion_vpcs") {
+		vpc, vpcAttachment, err := endpoint.PadHex(vpc, vpcAttachment)
+		if err != nil {
+			return nil, fmt.Errorf("companion_vpcs: %w", err)
+		}
+		w.vpcs[vpc] = vpcAttachment
+	}
+	return w, nil
+}
+
+// notify asks run to resync; it never blocks, so stores may call it with
+// their lock held.
+func (w *windowsHost) notify() {
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+// leaks returns, per shared VPC with a registered attachment, the networks
+// of the attachment and those routed to it from the rest of the VPC.
+func (w *windowsHost) leaks() []routeleak.Leak {
+	var leaks []routeleak.Leak
+	for vpc, vpcAttachment := range w.vpcs {
+		store := domainFor(vpc).store
+		a, ok := store.Attachment(vpc, vpcAttachment)
+		if !ok {
+			continue
+		}
+		networks := slices.Clone(a.Networks)
+		for _, e := range store.Snapshot().Egress {
+			if e.Endpoint == a.Endpoint {
+				networks = append(networks, e.Network)
+			}
+		}
+		l := routeleak.Leak{Table: a.Table}
+		for _, n := range networks {
+			if _, p, err := net.ParseCIDR(n); err == nil {
+				l.Prefixes = append(l.Prefixes, p)
+			}
+		}
+		leaks = append(leaks, l)
+	}
+	return leaks
+}
+
+func (w *windowsHost) routeSet() (*companion.RouteSet, error) {
+	set := &companion.RouteSet{}
+	seen := make(map[string]bool)
+	for _, l := range w.leaks() {
+		for _, p := range l.Prefixes {
+			if !seen[p.String()] {
+				seen[p.String()] = true
+				set.Prefixes = append(set.Prefixes, p.String())
+			}
+		}
+	}
+	sort.Strings(set.Prefixes)
+	addrs, err := w.addrs()
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range addrs {
+		switch {
+		case a.IP.To4() != nil && set.NextHop4 == "":
+			set.NextHop4 = a.IP.String()
+		case a.IP.To4() == nil && set.NextHop6 == "":
+			set.NextHop6 = a.IP.String()
+		}
+	}
+	return set, nil
+}
+
+// addrs returns the global addresses of the WSL interface.
+func (w *windowsHost) addrs() ([]netlink.Addr, error) {
+	link, err := netlink.LinkByName(w.iface)
+	if err != nil {
+		return nil, err
+	}
+	addrs, err := netlink.AddrList(link, netlink.FAMILY_ALL)
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(addrs, func(a netlink.Addr) bool {
+		return !a.IP.IsGlobalUnicast()
+	}), nil
+}
+
+// report takes the addresses of the Windows host that are on the WSL
+// network as the destinations of VPC replies.
+func (w *windowsHost) report(r *companion.InterfaceReport) ([]string, error) {
+	companionReports.Inc()
+	addrs, err := w.addrs()
+	if err != nil {
+		return nil, err
+	}
+	var hosts []net.IP
+	var accepted []string
+	for _, i := range r.GetInterfaces() {
+		if !i.GetUp() {
+			continue
+		}
+		for _, s := range i.GetAddresses() {
+			ip, _, err := net.ParseCIDR(s)
+			if err != nil {
+				continue
+			}
+			if slices.ContainsFunc(addrs, func(a netlink.Addr) bool { return a.Contains(ip) }) {
+				hosts = append(hosts, ip)
+				accepted = append(accepted, ip.String())
+			}
+		}
+	}
+	slog.Info("Companion", "host", r.GetHost(), "interfaces", len(r.GetInterfaces()), "routing_back", accepted)
+	w.mu.Lock()
+	w.hosts = hosts
+	w.mu.Unlock()
+	w.notify()
+	return accepted, nil
+}
+
+// run programs the route leaks and tells the helper about new routes
+// whenever the registry or the Windows host change.
+func (w *windowsHost) run(ctx context.Context, s *companion.Server) error {
+	w.notify()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-w.wake:
+		}
+		w.mu.Lock()
+		hosts := slices.Clone(w.hosts)
+		w.mu.Unlock()
+		if err := routeleak.Sync(w.iface, w.leaks(), hosts); err != nil {
+			slog.Error("Companion route leaks", "err", err)
+		}
+		s.Notify()
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/datum-cloud/galactic-agent/conformance"
+)
+
+func newConformanceCmd() *cobra.Command {
+	var (
+		timeout time.Duration
+		keep    bool
+		strict  bool
+	)
+	cmd := &cobra.Command{
+		Use:   "conformance",
+		Short: "Report which SRv6 behaviors this kernel programs and decapsulates correctly",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			results, err := conformance.Run(timeout, keep)
+			if err != nil {
+				return err
+			}
+			conformance.Write(os.Stdout, results)
+			if !strict {
+				return nil
+			}
+			for _, r := range results {
+				if !r.Passed() {
+					return fmt.Errorf("%s failed", r.Case)
+				}
+			}
+			return nil
+		},
+	}
+	cmd.Flags().DurationVar(&timeout, "timeout", 2*time.Second, "time to wait for each probe to be delivered")
+	cmd.Flags().BoolVar(&keep, "keep", false, "leave the test namespaces in place for inspection")
+	cmd.Flags().BoolVar(&strict, "strict", false, "exit non-zero unless every behavior passes")
+	return cmd
+}
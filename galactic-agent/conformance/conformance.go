@@ -0,0 +1,488 @@
+package conformance
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netlink/nl"
+	"github.com/vishvananda/netns"
+
+	"github.com/datum-cloud/galactic-agent/nsutil"
+)
+
+// The suite builds three namespaces:
+//
+//	src --c0-- dut --d0 (in vrf, table 100)-- dst
+//	                --x0 (main table)--------- dst
+//
+// For every case an SRv6 behavior is programmed on dut, src encapsulates UDP
+// datagrams towards the case's SID and the case passes for a family when
+// the datagram arrives at dst.
+const (
+	nsSrc = "galactic-conf-src"
+	nsDUT = "galactic-conf-dut"
+	nsDst = "galactic-conf-dst"
+
+	vrfName  = "conf-vrf"
+	vrfTable = 100
+	udpPort  = 4789
+)
+
+var (
+	underlaySrc = mustCIDR("fd00:c0::1/64")
+	underlayDUT = mustCIDR("fd00:c0::2/64")
+	locator     = mustCIDR("fc00:c0f::/48")
+
+	innerSrc4 = mustCIDR("192.0.2.1/32")
+	innerSrc6 = mustCIDR("2001:db8:5::1/128")
+
+	vrfDUT4 = mustCIDR("198.51.100.1/24")
+	vrfDst4 = mustCIDR("198.51.100.2/24")
+	vrfDUT6 = mustCIDR("2001:db8:d::1/64")
+	vrfDst6 = mustCIDR("2001:db8:d::2/64")
+
+	mainDUT4 = mustCIDR("203.0.113.1/24")
+	mainDst4 = mustCIDR("203.0.113.2/24")
+	mainDUT6 = mustCIDR("2001:db8:e::1/64")
+	mainDst6 = mustCIDR("2001:db8:e::2/64")
+)
+
+func mustCIDR(s string) *net.IPNet {
+	ip, n, err := net.ParseCIDR(s)
+	if err != nil {
+		panic(err)
+	}
+	n.IP = ip
+	return n
+}
+
+// Case is one SRv6 behavior and the inner destinations it must deliver to.
+type Case struct {
+	Name   string
+	Action int
+	SID    net.IP
+	// encap completes the behavior's attributes given dut's links
+	encap   func(e *netlink.SEG6LocalEncap, l links)
+	Targets map[string]net.IP // family -> inner destination
+}
+
+type links struct {
+	vrf, d0, x0 netlink.Link
+}
+
+// Cases covers the decapsulation behaviors the agent can use.
+var Cases = []Case{
+	{
+		Name: "End.DT4", Action: nl.SEG6_LOCAL_ACTION_END_DT4, SID: net.ParseIP("fc00:c0f::4"),
+		encap: func(e *netlink.SEG6LocalEncap, _ links) {
+			e.Flags[nl.SEG6_LOCAL_VRFTABLE] = true
+			e.VrfTable = vrfTable
+		},
+		Targets: map[string]net.IP{"ipv4": vrfDst4.IP},
+	},
+	{
+		Name: "End.DT6", Action: nl.SEG6_LOCAL_ACTION_END_DT6, SID: net.ParseIP("fc00:c0f::6"),
+		encap: func(e *netlink.SEG6LocalEncap, _ links) {
+			e.Flags[nl.SEG6_LOCAL_VRFTABLE] = true
+			e.VrfTable = vrfTable
+		},
+		Targets: map[string]net.IP{"ipv6": vrfDst6.IP},
+	},
+	{
+		Name: "End.DT46", Action: nl.SEG6_LOCAL_ACTION_END_DT46, SID: net.ParseIP("fc00:c0f::46"),
+		encap: func(e *netlink.SEG6LocalEncap, _ links) {
+			e.Flags[nl.SEG6_LOCAL_VRFTABLE] = true
+			e.VrfTable = vrfTable
+		},
+		Targets: map[string]net.IP{"ipv4": vrfDst4.IP, "ipv6": vrfDst6.IP},
+	},
+	{
+		Name: "End.DX4", Action: nl.SEG6_LOCAL_ACTION_END_DX4, SID: net.ParseIP("fc00:c0f::d4"),
+		encap: func(e *netlink.SEG6LocalEncap, l links) {
+			e.Flags[nl.SEG6_LOCAL_NH4] = true
+			e.InAddr = mainDst4.IP
+			e.Flags[nl.SEG6_LOCAL_OIF] = true
+			e.Oif = l.x0.Attrs().Index
+		},
+		Targets: map[string]net.IP{"ipv4": mainDst4.IP},
+	},
+	{
+		Name: "End.DX6", Action: nl.SEG6_LOCAL_ACTION_END_DX6, SID: net.ParseIP("fc00:c0f::d6"),
+		encap: func(e *netlink.SEG6LocalEncap, l links) {
+			e.Flags[nl.SEG6_LOCAL_NH6] = true
+			e.In6Addr = mainDst6.IP
+			e.Flags[nl.SEG6_LOCAL_OIF] = true
+			e.Oif = l.x0.Attrs().Index
+		},
+		Targets: map[string]net.IP{"ipv6": mainDst6.IP},
+	},
+}
+
+// Result reports a case. Program is empty when the behavior was accepted
+// and read back correctly; Decap holds per family whether traffic arrived.
+type Result struct {
+	Case    string
+	Program string
+	Decap   map[string]error
+}
+
+func (r Result) Passed() bool {
+	if r.Program != "" {
+		return false
+	}
+	for _, err := range r.Decap {
+		if err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func Write(w io.Writer, results []Result) {
+	fmt.Fprintf(w, "%-10s %-8s %-8s %-8s %s\n", "BEHAVIOR", "PROGRAM", "IPV4", "IPV6", "DETAIL") //nolint:errcheck
+	for _, r := range results {
+		program, detail := "PASS", ""
+		if r.Program != "" {
+			program, detail = "FAIL", r.Program
+		}
+		cols := map[string]string{"ipv4": "n/a", "ipv6": "n/a"}
+		for family, err := range r.Decap {
+			cols[family] = "PASS"
+			if err != nil {
+				cols[family] = "FAIL"
+				if detail == "" {
+					detail = fmt.Sprintf("%s: %v", family, err)
+				}
+			}
+		}
+		if r.Program != "" {
+			cols["ipv4"], cols["ipv6"] = "skip", "skip"
+		}
+		fmt.Fprintf(w, "%-10s %-8s %-8s %-8s %s\n", r.Case, program, cols["ipv4"], cols["ipv6"], detail) //nolint:errcheck
+	}
+}
+
+type suite struct {
+	src, dut, dst netns.NsHandle
+	links         links
+	timeout       time.Duration
+}
+
+// Run builds the namespaces, runs every case and tears everything down
+// again unless keep is set.
+func Run(timeout time.Duration, keep bool) ([]Result, error) {
+	if timeout == 0 {
+		timeout = 2 * time.Second
+	}
+	s := &suite{timeout: timeout}
+	if !keep {
+		defer s.teardown()
+	}
+	if err := s.setup(); err != nil {
+		return nil, fmt.Errorf("setup: %w", err)
+	}
+	results := make([]Result, 0, len(Cases))
+	for _, c := range Cases {
+		results = append(results, s.run(c))
+	}
+	return results, nil
+}
+
+func (s *suite) teardown() {
+	for _, name := range []string{nsSrc, nsDUT, nsDst} {
+		_ = netns.DeleteNamed(name)
+	}
+}
+
+func addAddr(link netlink.Link, addrs ...*net.IPNet) error {
+	for _, a := range addrs {
+		if err := netlink.AddrAdd(link, &netlink.Addr{IPNet: a, Flags: nl.IFA_F_NODAD}); err != nil {
+			return fmt.Errorf("%s %s: %w", link.Attrs().Name, a, err)
+		}
+	}
+	return netlink.LinkSetUp(link)
+}
+
+// veth creates a pair in the current namespace and moves the peer to ns.
+func veth(name string, ns netns.NsHandle) (netlink.Link, error) {
+	v := &netlink.Veth{LinkAttrs: netlink.LinkAttrs{Name: name}, PeerName: name + "p"}
+	if err := netlink.LinkAdd(v); err != nil {
+		return nil, err
+	}
+	peer, err := netlink.LinkByName(name + "p")
+	if err != nil {
+		return nil, err
+	}
+	if err := netlink.LinkSetNsFd(peer, int(ns)); err != nil {
+		return nil, err
+	}
+	return netlink.LinkByName(name)
+}
+
+func (s *suite) setup() error {
+	var err error
+	if s.src, err = nsutil.New(nsSrc); err != nil {
+		return err
+	}
+	if s.dut, err = nsutil.New(nsDUT); err != nil {
+		return err
+	}
+	if s.dst, err = nsutil.New(nsDst); err != nil {
+		return err
+	}
+
+	if err := nsutil.Do(s.dut, s.setupDUT); err != nil {
+		return fmt.Errorf("dut: %w", err)
+	}
+	if err := nsutil.Do(s.src, s.setupSrc); err != nil {
+		return fmt.Errorf("src: %w", err)
+	}
+	if err := nsutil.Do(s.dst, s.setupDst); err != nil {
+		return fmt.Errorf("dst: %w", err)
+	}
+	return nil
+}
+
+func (s *suite) setupDUT() error {
+	for key, value := range map[string]string{
+		"net/ipv4/conf/all/forwarding":   "1",
+		"net/ipv6/conf/all/forwarding":   "1",
+		"net/ipv6/conf/all/seg6_enabled": "1",
+		"net/vrf/strict_mode":            "1",
+	} {
+		if err := nsutil.SetSysctl(key, value); err != nil {
+			return err
+		}
+	}
+	lo, err := netlink.LinkByName("lo")
+	if err != nil {
+		return err
+	}
+	if err := netlink.LinkSetUp(lo); err != nil {
+		return err
+	}
+
+	vrf := &netlink.Vrf{LinkAttrs: netlink.LinkAttrs{Name: vrfName}, Table: vrfTable}
+	if err := netlink.LinkAdd(vrf); err != nil {
+		return err
+	}
+	if err := netlink.LinkSetUp(vrf); err != nil {
+		return err
+	}
+	s.links.vrf = vrf
+
+	c0, err := veth("c0", s.src)
+	if err != nil {
+		return err
+	}
+	if err := nsutil.SetSysctl("net/ipv6/conf/c0/seg6_enabled", "1"); err != nil {
+		return err
+	}
+	if err := addAddr(c0, underlayDUT); err != nil {
+		return err
+	}
+
+	if s.links.d0, err = veth("d0", s.dst); err != nil {
+		return err
+	}
+	if err := netlink.LinkSetMaster(s.links.d0, vrf); err != nil {
+		return err
+	}
+	if err := addAddr(s.links.d0, vrfDUT4, vrfDUT6); err != nil {
+		return err
+	}
+
+	if s.links.x0, err = veth("x0", s.dst); err != nil {
+		return err
+	}
+	return addAddr(s.links.x0, mainDUT4, mainDUT6)
+}
+
+func (s *suite) setupSrc() error {
+	lo, err := netlink.LinkByName("lo")
+	if err != nil {
+		return err
+	}
+	if err := addAddr(lo, innerSrc4, innerSrc6); err != nil {
+		return err
+	}
+	c0, err := netlink.LinkByName("c0p")
+	if err != nil {
+		return err
+	}
+	if err := addAddr(c0, underlaySrc); err != nil {
+		return err
+	}
+	return netlink.RouteAdd(&netlink.Route{Dst: locator, Gw: underlayDUT.IP, LinkIndex: c0.Attrs().Index})
+}
+
+func (s *suite) setupDst() error {
+	for key, value := range map[string]string{
+		"net/ipv4/conf/all/rp_filter":     "0",
+		"net/ipv4/conf/default/rp_filter": "0",
+	} {
+		if err := nsutil.SetSysctl(key, value); err != nil {
+			return err
+		}
+	}
+	lo, err := netlink.LinkByName("lo")
+	if err != nil {
+		return err
+	}
+	if err := netlink.LinkSetUp(lo); err != nil {
+		return err
+	}
+	d0, err := netlink.LinkByName("d0p")
+	if err != nil {
+		return err
+	}
+	if err := addAddr(d0, vrfDst4, vrfDst6); err != nil {
+		return err
+	}
+	x0, err := netlink.LinkByName("x0p")
+	if err != nil {
+		return err
+	}
+	return addAddr(x0, mainDst4, mainDst6)
+}
+
+func (s *suite) run(c Case) Result {
+	res := Result{Case: c.Name, Decap: make(map[string]error)}
+	sid := &net.IPNet{IP: c.SID, Mask: net.CIDRMask(128, 128)}
+
+	var flags [nl.SEG6_LOCAL_MAX]bool
+	flags[nl.SEG6_LOCAL_ACTION] = true
+	encap := &netlink.SEG6LocalEncap{Action: c.Action, Flags: flags}
+	if err := nsutil.Do(s.dut, func() error {
+		c.encap(encap, s.links)
+		c0, err := netlink.LinkByName("c0")
+		if err != nil {
+			return err
+		}
+		if err := netlink.RouteReplace(&netlink.Route{Dst: sid, LinkIndex: c0.Attrs().Index, Encap: encap}); err != nil {
+			return err
+		}
+		return s.readBack(sid, encap)
+	}); err != nil {
+		res.Program = err.Error()
+		return res
+	}
+	defer nsutil.Do(s.dut, func() error { //nolint:errcheck
+		return netlink.RouteDel(&netlink.Route{Dst: sid, Encap: &netlink.SEG6LocalEncap{}})
+	})
+
+	for family, target := range c.Targets {
+		res.Decap[family] = s.probe(c.SID, family, target)
+	}
+	return res
+}
+
+// readBack checks that the kernel kept the behavior's attributes.
+func (s *suite) readBack(sid *net.IPNet, want *netlink.SEG6LocalEncap) error {
+	routes, err := netlink.RouteListFiltered(netlink.FAMILY_V6, &netlink.Route{Dst: sid}, netlink.RT_FILTER_DST)
+	if err != nil {
+		return err
+	}
+	for _, r := range routes {
+		got, ok := r.Encap.(*netlink.SEG6LocalEncap)
+		if !ok {
+			continue
+		}
+		switch {
+		case got.Action != want.Action:
+			return fmt.Errorf("read back action %s", nl.SEG6LocalActionString(got.Action))
+		case want.Flags[nl.SEG6_LOCAL_VRFTABLE] && got.VrfTable != want.VrfTable:
+			return fmt.Errorf("read back vrftable %d", got.VrfTable)
+		case want.Flags[nl.SEG6_LOCAL_NH4] && !got.InAddr.Equal(want.InAddr):
+			return fmt.Errorf("read back nh4 %s", got.InAddr)
+		case want.Flags[nl.SEG6_LOCAL_NH6] && !got.In6Addr.Equal(want.In6Addr):
+			return fmt.Errorf("read back nh6 %s", got.In6Addr)
+		}
+		return nil
+	}
+	return fmt.Errorf("route for %s not found after programming", sid)
+}
+
+// probe encapsulates a datagram from src towards sid and waits for it at
+// target in dst.
+func (s *suite) probe(sid net.IP, family string, target net.IP) error {
+	network, source, bits := "udp4", innerSrc4.IP, 32
+	if family == "ipv6" {
+		network, source, bits = "udp6", innerSrc6.IP, 128
+	}
+
+	var listener *net.UDPConn
+	if err := nsutil.Do(s.dst, func() error {
+		var err error
+		listener, err = net.ListenUDP(network, &net.UDPAddr{IP: target, Port: udpPort})
+		return err
+	}); err != nil {
+		return err
+	}
+	defer listener.Close() //nolint:errcheck
+
+	dst := &net.IPNet{IP: target, Mask: net.CIDRMask(bits, bits)}
+	var conn *net.UDPConn
+	if err := nsutil.Do(s.src, func() error {
+		c0, err := netlink.LinkByName("c0p")
+		if err != nil {
+			return err
+		}
+		if err := netlink.RouteReplace(&netlink.Route{
+			Dst:       dst,
+			LinkIndex: c0.Attrs().Index,
+			Encap:     &netlink.SEG6Encap{Mode: nl.SEG6_IPTUN_MODE_ENCAP, Segments: []net.IP{sid}},
+		}); err != nil {
+			return fmt.Errorf("encap route: %w", err)
+		}
+		conn, err = net.DialUDP(network, &net.UDPAddr{IP: source}, &net.UDPAddr{IP: target, Port: udpPort})
+		return err
+	}); err != nil {
+		return err
+	}
+	defer func() {
+		conn.Close() //nolint:errcheck
+
+		// remove the encap route so the next case can reuse the target
+		nsutil.Do(s.src, func() error { //nolint:errcheck
+			return netlink.RouteDel(&netlink.Route{Dst: dst})
+		})
+	}()
+
+	want := fmt.Sprintf("galactic-conformance %s %s", sid, family)
+	received := make(chan error, 1)
+	go func() {
+		buf := make([]byte, 256)
+		for {
+			n, _, err := listener.ReadFromUDP(buf)
+			if err != nil {
+				received <- err
+				return
+			}
+			if string(buf[:n]) == want {
+				received <- nil
+				return
+			}
+		}
+	}()
+
+	// a few packets in case the first is lost to neighbor resolution
+	deadline := time.After(s.timeout)
+	tick := time.NewTicker(100 * time.Millisecond)
+	defer tick.Stop()
+	for {
+		if _, err := conn.Write([]byte(want)); err != nil {
+			return fmt.Errorf("send: %w", err)
+		}
+		select {
+		case err := <-received:
+			return err
+		case <-deadline:
+			return fmt.Errorf("not delivered within %s", s.timeout)
+		case <-tick.C:
+		}
+	}
+}
@@ -0,0 +1,377 @@
+package controller
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"fmt"
+	"log/slog"
+	"maps"
+	"strings"
+	"sync"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/datum-cloud/galactic-agent/api/remote"
+	"github.com/datum-cloud/galactic-common/util"
+)
+
+// Controller is a reference implementation of the control plane. Agents
+// publish Register/Deregister envelopes on <prefix>/<agent>/send and receive
+// Route envelopes on <prefix>/<agent>/receive. Every network registered in a
+// VPC is routed to every other attachment of the same VPC (full mesh).
+// Route changes agents in route-monitor mode publish on
+// <prefix>/<agent>/telemetry are logged when made out of band.
+type Controller struct {
+	URL      string
+	ClientID string
+	Username string
+	Password string
+	QoS      byte
+	Prefix   string
+
+	// Roots, if set, requires envelopes to be signed by a SPIFFE identity
+	// chaining to it; others are dropped.
+	Roots *x509.CertPool
+	// Keys, if set, requires envelopes to be signed by one of its keys.
+	Keys remote.Keys
+	// SigningKey, if set, signs every route pushed as KeyID.
+	SigningKey crypto.Signer
+	KeyID      string
+
+	mu            sync.Mutex
+	registrations map[registration]struct{}
+	agents        map[string]*remote.AgentHello // last hello, by agent
+	client        mqtt.Client
+	transports    map[string]remote.Transport
+}
+
+type registration struct {
+	agent    string
+	vpc      string
+	network  string
+	endpoint string
+}
+
+// Connect attaches agent over an in-memory transport instead of MQTT:
+// envelopes received on t are handled as if published on the agent's send
+// topic, and routes for agent are sent on t. The caller runs t.
+func (c *Controller) Connect(agent string, t *remote.Loopback) {
+	t.ReceiveHandler = func(payload []byte) error {
+		return c.Handle(agent, payload)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.transports == nil {
+		c.transports = make(map[string]remote.Transport)
+	}
+	c.transports[agent] = t
+}
+
+// Run connects to the broker and serves agents until ctx is done. With no
+// URL only agents attached through Connect are served.
+func (c *Controller) Run(ctx context.Context) error {
+	if c.Prefix == "" {
+		c.Prefix = "galactic"
+	}
+	if c.URL == "" {
+		<-ctx.Done()
+		return nil
+	}
+
+	opts := mqtt.NewClientOptions().
+		AddBroker(c.URL)
+	if c.ClientID != "" {
+		opts.SetClientID(c.ClientID)
+	}
+	if c.Username != "" {
+		opts.SetUsername(c.Username)
+	}
+	if c.Password != "" {
+		opts.SetPassword(c.Password)
+	}
+
+	filters := map[string]byte{
+		c.Prefix + "/+/send":      c.QoS,
+		c.Prefix + "/+/telemetry": c.QoS,
+	}
+	opts.OnConnect = func(client mqtt.Client) {
+		token := client.SubscribeMultiple(filters, func(_ mqtt.Client, msg mqtt.Message) {
+			if err := c.receive(msg.Topic(), msg.Payload()); err != nil {
+				slog.Warn("Controller: receive", "topic", msg.Topic(), "err", err)
+			}
+		})
+		if !token.WaitTimeout(5*time.Second) || token.Error() != nil {
+			slog.Error("Controller: subscribe", "err", token.Error())
+			return
+		}
+		slog.Info("Controller: subscribed", "send", c.Prefix+"/+/send", "telemetry", c.Prefix+"/+/telemetry")
+	}
+
+	c.client = mqtt.NewClient(opts)
+	if tok := c.client.Connect(); tok.Wait() && tok.Error() != nil {
+		return tok.Error()
+	}
+	<-ctx.Done()
+	c.client.Disconnect(250)
+	return nil
+}
+
+func (c *Controller) receive(topic string, payload []byte) error {
+	agent, _, _ := strings.Cut(strings.TrimPrefix(topic, c.Prefix+"/"), "/")
+	return c.Handle(agent, payload)
+}
+
+// Handle processes an envelope sent by agent.
+func (c *Controller) Handle(agent string, payload []byte) error {
+	envelope := &remote.Envelope{}
+	if err := proto.Unmarshal(payload, envelope); err != nil {
+		return err
+	}
+	if c.Roots != nil {
+		id, err := remote.Verify(envelope, c.Roots)
+		if err != nil {
+			return fmt.Errorf("agent %s: %w", agent, err)
+		}
+		slog.Info("Controller: envelope signed", "agent", agent, "signer", id)
+	}
+	if c.Keys != nil {
+		if _, err := c.Keys.VerifyKey(envelope); err != nil {
+			return fmt.Errorf("agent %s: %w", agent, err)
+		}
+	}
+	switch kind := envelope.Kind.(type) {
+	case *remote.Envelope_Register:
+		return c.register(agent, kind.Register.Network, kind.Register.Srv6Endpoint)
+	case *remote.Envelope_Deregister:
+		return c.deregister(agent, kind.Deregister.Network, kind.Deregister.Srv6Endpoint)
+	case *remote.Envelope_AgentHello:
+		h := kind.AgentHello
+		slog.Info("Controller: HELLO", "agent", agent, "agent_id", h.AgentId, "hostname", h.Hostname, "srv6_net", h.Srv6Net, "version", h.Version, "schema", h.Schema, "features", h.Features)
+		c.mu.Lock()
+		if c.agents == nil {
+			c.agents = make(map[string]*remote.AgentHello)
+		}
+		c.agents[agent] = h
+		c.mu.Unlock()
+	case *remote.Envelope_Nack:
+		slog.Warn("Controller: NACK", "agent", agent, "network", kind.Nack.GetRoute().GetNetwork(), "srv6_endpoint", kind.Nack.GetRoute().GetSrv6Endpoint(), "reason", kind.Nack.Reason.String(), "detail", kind.Nack.Detail)
+	case *remote.Envelope_BatchProgress:
+		p := kind.BatchProgress
+		slog.Info("Controller: BATCH PROGRESS", "agent", agent, "id", p.Id, "applied", p.Applied, "refused", p.Refused, "deleted", p.Deleted, "done", p.Done)
+	case *remote.Envelope_Heartbeat:
+		for _, u := range kind.Heartbeat.Usage {
+			slog.Info("Controller: USAGE", "agent", agent, "vpc", u.Vpc, "sent_bytes", u.SentBytes, "sent_bytes_per_second", u.SentBytesPerSecond, "received_bytes", u.ReceivedBytes, "received_bytes_per_second", u.ReceivedBytesPerSecond)
+		}
+	case *remote.Envelope_RouteChanges:
+		for _, rc := range kind.RouteChanges.Changes {
+			// the agent's own changes match its state
+			if rc.Desired == (rc.Op == remote.RouteChange_ADD) {
+				continue
+			}
+			slog.Warn("Controller: OUT OF BAND route", "agent", agent, "op", rc.Op.String(), "network", rc.Network, "table", rc.Table, "srv6_endpoint", rc.Srv6Endpoint, "protocol", rc.Protocol, "srv6_segments", rc.Srv6Segments, "device", rc.Device, "gateway", rc.Gateway)
+		}
+	}
+	return nil
+}
+
+// Agents returns the last AgentHello of each agent that sent one, by
+// agent.
+func (c *Controller) Agents() map[string]*remote.AgentHello {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return maps.Clone(c.agents)
+}
+
+func vpcOf(endpoint string) (string, error) {
+	ip, err := util.ParseIP(endpoint)
+	if err != nil {
+		return "", err
+	}
+	vpc, _, err := util.DecodeSRv6Endpoint(ip)
+	return vpc, err
+}
+
+func (c *Controller) register(agent, network, endpoint string) error {
+	vpc, err := vpcOf(endpoint)
+	if err != nil {
+		return fmt.Errorf("invalid endpoint %q: %w", endpoint, err)
+	}
+	reg := registration{agent: agent, vpc: vpc, network: network, endpoint: endpoint}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.registrations[reg]; ok {
+		return nil
+	}
+	if c.registrations == nil {
+		c.registrations = make(map[registration]struct{})
+	}
+	newEndpoint := !c.hasEndpoint(endpoint)
+	c.registrations[reg] = struct{}{}
+	slog.Info("Controller: REGISTER", "agent", agent, "network", network, "srv6_endpoint", endpoint)
+
+	for o := range c.registrations {
+		if o.vpc != vpc || o.endpoint == endpoint {
+			continue
+		}
+		c.send(o.agent, remote.Route_ADD, network, o.endpoint, endpoint)
+		if newEndpoint {
+			c.send(agent, remote.Route_ADD, o.network, endpoint, o.endpoint)
+		}
+	}
+	return nil
+}
+
+func (c *Controller) deregister(agent, network, endpoint string) error {
+	vpc, err := vpcOf(endpoint)
+	if err != nil {
+		return fmt.Errorf("invalid endpoint %q: %w", endpoint, err)
+	}
+	reg := registration{agent: agent, vpc: vpc, network: network, endpoint: endpoint}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.registrations[reg]; !ok {
+		return nil
+	}
+	delete(c.registrations, reg)
+	goneEndpoint := !c.hasEndpoint(endpoint)
+	slog.Info("Controller: DEREGISTER", "agent", agent, "network", network, "srv6_endpoint", endpoint)
+
+	for o := range c.registrations {
+		if o.vpc != vpc || o.endpoint == endpoint {
+			continue
+		}
+		c.send(o.agent, remote.Route_DELETE, network, o.endpoint, endpoint)
+		if goneEndpoint {
+			c.send(agent, remote.Route_DELETE, o.network, endpoint, o.endpoint)
+		}
+	}
+	return nil
+}
+
+func (c *Controller) hasEndpoint(endpoint string) bool {
+	for r := range c.registrations {
+		if r.endpoint == endpoint {
+			return true
+		}
+	}
+	return false
+}
+
+// send tells agent to route network, inside the VRF of endpoint, via segment.
+func (c *Controller) send(agent string, status remote.Route_Status, network, endpoint, segment string) {
+	c.sendEnvelope(agent, &remote.Envelope{
+		Kind: &remote.Envelope_Route{
+			Route: &remote.Route{
+				Status:       status,
+				Network:      network,
+				Srv6Endpoint: endpoint,
+				Srv6Segments: []string{segment},
+			},
+		},
+	})
+}
+
+// Resync sends agent every route it should hold, in one RouteBatch that
+// replaces the routes it has: for each of its endpoints, the networks of
+// the other attachments of the endpoint's VPC.
+func (c *Controller) Resync(agent string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	endpoints := make(map[string]string) // of agent, to their VPC
+	for r := range c.registrations {
+		if r.agent == agent {
+			endpoints[r.endpoint] = r.vpc
+		}
+	}
+	batch := &remote.RouteBatch{Id: fmt.Sprintf("%s-%d", agent, time.Now().UnixNano()), Resync: true}
+	for endpoint, vpc := range endpoints {
+		for o := range c.registrations {
+			if o.vpc != vpc || o.endpoint == endpoint {
+				continue
+			}
+			batch.Routes = append(batch.Routes, &remote.Route{
+				Status:       remote.Route_ADD,
+				Network:      o.network,
+				Srv6Endpoint: endpoint,
+				Srv6Segments: []string{o.endpoint},
+			})
+		}
+	}
+	slog.Info("Controller: RESYNC", "agent", agent, "routes", len(batch.Routes))
+	c.sendEnvelope(agent, &remote.Envelope{Kind: &remote.Envelope_RouteBatch{RouteBatch: batch}})
+}
+
+// RotateCredentials pushes new broker credentials to agent, sealed with
+// the key the agent was given as credential_rotate_key. Agents only accept
+// them from a controller with a SigningKey they trust.
+func (c *Controller) RotateCredentials(agent string, key []byte, creds *remote.Credentials) error {
+	sealed, err := remote.SealCredentials(key, creds)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sendEnvelope(agent, &remote.Envelope{
+		Kind: &remote.Envelope_CredentialRotate{
+			CredentialRotate: &remote.CredentialRotate{Sealed: sealed},
+		},
+	})
+	return nil
+}
+
+// SetMTU tells the agent serving endpoint to use mtu for its attachment.
+func (c *Controller) SetMTU(agent, endpoint string, mtu uint32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sendEnvelope(agent, &remote.Envelope{
+		Kind: &remote.Envelope_SetMtu{
+			SetMtu: &remote.SetMTU{Srv6Endpoint: endpoint, Mtu: mtu},
+		},
+	})
+}
+
+// sendEnvelope stamps, signs and sends envelope to agent; the caller holds
+// c.mu.
+func (c *Controller) sendEnvelope(agent string, envelope *remote.Envelope) {
+	if err := remote.Stamp(envelope); err != nil {
+		slog.Error("Controller: stamp", "agent", agent, "err", err)
+		return
+	}
+	if c.SigningKey != nil {
+		if err := remote.SignWithKey(envelope, c.KeyID, c.SigningKey); err != nil {
+			slog.Error("Controller: sign", "agent", agent, "err", err)
+			return
+		}
+	}
+	if t, ok := c.transports[agent]; ok {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := t.SendEnvelope(ctx, envelope); err != nil {
+			slog.Warn("Controller: send", "agent", agent, "err", err)
+		}
+		return
+	}
+	if c.client == nil {
+		slog.Warn("Controller: agent not connected", "agent", agent)
+		return
+	}
+	payload, err := proto.Marshal(envelope)
+	if err != nil {
+		slog.Error("Controller: marshal", "agent", agent, "err", err)
+		return
+	}
+	topic := c.Prefix + "/" + agent + "/receive"
+	// Publishing is asynchronous: waiting on the token from inside the
+	// subscription callback would stall paho's ordered delivery.
+	token := c.client.Publish(topic, c.QoS, false, payload)
+	go func() {
+		if token.Wait() && token.Error() != nil {
+			slog.Warn("Controller: publish", "topic", topic, "err", token.Error())
+		}
+	}()
+}
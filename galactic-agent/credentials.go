@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/datum-cloud/galactic-agent/api/remote"
+	"github.com/datum-cloud/galactic-agent/fips"
+)
+
+// credentialKey returns credential_rotate_key, or nil if rotation via
+// control message is not enabled.
+func credentialKey() ([]byte, error) {
+	v := viper.GetString("credential_rotate_key")
+	if v == "" {
+		return nil, nil
+	}
+	key, err := decodeStateKey([]byte(v))
+	if err != nil {
+		return nil, fmt.Errorf("credential_rotate_key: %w", err)
+	}
+	return key, nil
+}
+
+// applyCredentials returns the username, password and TLS config that
+// result from applying creds on top of the current ones.
+func applyCredentials(username, password string, tlsConfig *tls.Config, creds *remote.Credentials, fipsMode bool) (string, string, *tls.Config, error) {
+	if creds.Username != "" {
+		username = creds.Username
+	}
+	if creds.Password != "" {
+		password = creds.Password
+	}
+	if len(creds.CertPem) == 0 && len(creds.CaPem) == 0 {
+		return username, password, tlsConfig, nil
+	}
+	c := &tls.Config{MinVersion: tls.VersionTLS12}
+	if tlsConfig != nil {
+		c = tlsConfig.Clone()
+	}
+	if len(creds.CertPem) > 0 {
+		cert, err := tls.X509KeyPair(creds.CertPem, creds.KeyPem)
+		if err != nil {
+			return "", "", nil, fmt.Errorf("rotated certificate: %w", err)
+		}
+		c.Certificates = []tls.Certificate{cert}
+		c.GetClientCertificate = nil
+	}
+	if len(creds.CaPem) > 0 {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(creds.CaPem) {
+			return "", "", nil, errors.New("rotated CA has no certificates")
+		}
+		// the pushed CA replaces whatever custom verification was set up
+		c.RootCAs = pool
+		c.InsecureSkipVerify = false
+		c.VerifyConnection = nil
+	}
+	if fipsMode {
+		c = fips.RestrictTLS(c)
+	}
+	return username, password, c, nil
+}
+
+// rotateCredentials switches m to creds and, once the new connection is
+// up, persists sealed at path so the credentials survive a restart.
+func rotateCredentials(m *remote.Remote, path string, creds *remote.Credentials, sealed []byte, fipsMode bool) {
+	username, password, tlsConfig := m.Credentials()
+	username, password, tlsConfig, err := applyCredentials(username, password, tlsConfig, creds, fipsMode)
+	if err != nil {
+		slog.Error("CREDENTIAL ROTATE", "err", err)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if err := m.Rotate(ctx, username, password, tlsConfig); err != nil {
+		slog.Warn("CREDENTIAL ROTATE: keeping current credentials", "err", err)
+		return
+	}
+	slog.Info("CREDENTIAL ROTATE: applied")
+	if err := writeFileAtomic(path, sealed); err != nil {
+		slog.Warn("CREDENTIAL ROTATE: not persisted", "path", path, "err", err)
+	}
+}
+
+// loadCredentials applies credentials persisted at path by an earlier
+// rotation.
+func loadCredentials(m *remote.Remote, path string, key []byte, fipsMode bool) error {
+	sealed, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	creds, err := remote.OpenCredentials(key, sealed)
+	if err != nil {
+		return err
+	}
+	m.Username, m.Password, m.TLSConfig, err = applyCredentials(m.Username, m.Password, m.TLSConfig, creds, fipsMode)
+	return err
+}
+
+func writeFileAtomic(path string, b []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) //nolint:errcheck
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close() //nolint:errcheck
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"time"
+
+	"github.com/datum-cloud/galactic-agent/audit"
+	"github.com/datum-cloud/galactic-agent/dashboard"
+	"github.com/datum-cloud/galactic-agent/reconcile"
+)
+
+// recentEvents keeps the audit events shown on the dashboard; it joins
+// the audit sinks when dashboard_addr is set.
+var recentEvents = audit.NewRecent(100)
+
+// dashboardSnapshot gathers the dashboard from the stores, the remotes, the
+// probes and a dry run of reconcile.
+func dashboardSnapshot() (*dashboard.Snapshot, error) {
+	s := &dashboard.Snapshot{Time: time.Now(), Events: recentEvents.Events()}
+	if probes != nil {
+		s.Probes = probes.results()
+	}
+	for _, t := range tenantMap.All() {
+		d := domains[t]
+		st := d.store.Snapshot()
+		s.Tenants = append(s.Tenants, dashboard.Tenant{
+			Name:        t.Name,
+			Broker:      d.remote.Settings().URL,
+			Connected:   d.remote.Connected(),
+			Attachments: st.Attachments,
+			Routes:      st.Egress,
+		})
+		host, err := reconcile.Host(st)
+		if err != nil {
+			s.Reconcile = err.Error()
+			continue
+		}
+		routes, err := reconcile.Diff(st, t.SRv6Net)
+		if err != nil {
+			s.Reconcile = err.Error()
+			continue
+		}
+		for _, c := range append(host, routes...) {
+			s.Differences = append(s.Differences, c.String())
+		}
+	}
+	return s, nil
+}
@@ -0,0 +1,156 @@
+// Package dashboard serves a read-only dashboard of the agent: broker
+// connections, registered attachments, programmed routes, recent events and
+// the differences between the desired state and the kernel. It is meant for
+// developers debugging an agent on their machine and has no authentication,
+// so it should only listen on localhost.
+package dashboard
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/datum-cloud/galactic-agent/audit"
+	"github.com/datum-cloud/galactic-agent/probe"
+	"github.com/datum-cloud/galactic-agent/state"
+)
+
+// Snapshot is everything the dashboard shows, taken at Time.
+type Snapshot struct {
+	Time    time.Time     `json:"time"`
+	Tenants []Tenant      `json:"tenants"`
+	Events  []audit.Event `json:"events"`
+	// Probes are the results of probing other nodes, if enabled.
+	Probes []probe.Result `json:"probes,omitempty"`
+	// Differences are what routes diff and hook would report; Reconcile
+	// holds the error that kept them from being computed.
+	Differences []string `json:"differences"`
+	Reconcile   string   `json:"reconcile_error,omitempty"`
+}
+
+// Tenant is the state of one control domain.
+type Tenant struct {
+	Name        string             `json:"name"`
+	Broker      string             `json:"broker"`
+	Connected   bool               `json:"connected"`
+	Attachments []state.Attachment `json:"attachments"`
+	Routes      []state.Egress     `json:"routes"`
+}
+
+// Handler serves the dashboard at / and the snapshot it renders at
+// /status.json.
+func Handler(snapshot func() (*Snapshot, error)) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, _ *http.Request) {
+		s, err := snapshot()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := page.Execute(w, s); err != nil {
+			slog.Warn("Dashboard", "err", err)
+		}
+	})
+	mux.HandleFunc("GET /status.json", func(w http.ResponseWriter, _ *http.Request) {
+		s, err := snapshot()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(s); err != nil {
+			slog.Warn("Dashboard json", "err", err)
+		}
+	})
+	return mux
+}
+
+// Serve exposes the dashboard on addr until ctx is done.
+func Serve(ctx context.Context, addr string, snapshot func() (*Snapshot, error)) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	s := &http.Server{Handler: Handler(snapshot), ReadHeaderTimeout: 5 * time.Second}
+
+	routineErr := make(chan error, 1)
+	go func() {
+		slog.Info("Dashboard listening", "address", "http://"+listener.Addr().String()+"/")
+		if err := s.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			routineErr <- err
+			return
+		}
+		routineErr <- nil
+	}()
+
+	<-ctx.Done()
+	s.Close() //nolint:errcheck
+	return <-routineErr
+}
+
+var page = template.Must(template.New("dashboard").Funcs(template.FuncMap{
+	"time":    func(t time.Time) string { return t.Format("15:04:05") },
+	"percent": func(f float64) float64 { return 100 * f },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta http-equiv="refresh" content="5">
+<title>Galactic Agent</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 1.5em; }
+th, td { border: 1px solid #ccc; padding: 0.2em 0.6em; text-align: left; font-family: monospace; }
+th { background: #eee; font-family: sans-serif; }
+.up { color: green; } .down { color: red; }
+</style>
+</head>
+<body>
+<h1>Galactic Agent</h1>
+<p>As of {{time .Time}}, refreshed every 5s. <a href="status.json">JSON</a></p>
+{{range .Tenants}}
+<h2>Tenant {{.Name}}</h2>
+<p>Broker {{.Broker}}: {{if .Connected}}<span class="up">connected</span>{{else}}<span class="down">disconnected</span>{{end}}</p>
+<h3>Attachments</h3>
+<table>
+<tr><th>VPC</th><th>Attachment</th><th>VRF</th><th>Table</th><th>Host</th><th>SRv6 endpoint</th><th>Networks</th><th>MTU</th></tr>
+{{range .Attachments}}<tr><td>{{.VPC}}</td><td>{{.VPCAttachment}}</td><td>{{.VRF}}</td><td>{{.Table}}</td><td>{{.Host}}</td><td>{{.Endpoint}}</td><td>{{range .Networks}}{{.}} {{end}}</td><td>{{if .MTU}}{{.MTU}}{{end}}</td></tr>
+{{else}}<tr><td colspan="8">none</td></tr>
+{{end}}</table>
+<h3>Routes</h3>
+<table>
+<tr><th>Network</th><th>SRv6 endpoint</th><th>Segments</th><th>DSCP</th></tr>
+{{range .Routes}}<tr><td>{{.Network}}</td><td>{{.Endpoint}}</td><td>{{range .Segments}}{{.}} {{end}}</td><td>{{if .DSCP}}{{.DSCP}}{{end}}</td></tr>
+{{else}}<tr><td colspan="4">none</td></tr>
+{{end}}</table>
+{{end}}
+{{if .Probes}}<h2>Probes</h2>
+<table>
+<tr><th>Responder</th><th>Segments</th><th>State</th><th>Sent</th><th>Received</th><th>Loss</th><th>RTT</th></tr>
+{{range .Probes}}<tr><td>{{.Responder}}</td><td>{{range .Segments}}{{.}} {{end}}</td><td class="{{if eq .State.String "up"}}up{{else if eq .State.String "down"}}down{{end}}">{{.State}}</td><td>{{.Sent}}</td><td>{{.Received}}</td><td>{{printf "%.0f%%" (percent .Loss)}}</td><td>{{.RTT}}</td></tr>
+{{end}}</table>
+{{end}}
+<h2>Reconcile</h2>
+{{if .Reconcile}}<p class="down">{{.Reconcile}}</p>
+{{else if .Differences}}<table>
+{{range .Differences}}<tr><td>{{.}}</td></tr>
+{{end}}</table>
+{{else}}<p class="up">The kernel matches the desired state.</p>
+{{end}}
+<h2>Recent events</h2>
+<table>
+<tr><th>Time</th><th>Action</th><th>Details</th></tr>
+{{range .Events}}<tr><td>{{time .Time}}</td><td>{{.Action}}</td><td>{{range $k, $v := .Fields}}{{$k}}={{$v}} {{end}}</td></tr>
+{{else}}<tr><td colspan="3">none</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
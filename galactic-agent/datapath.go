@@ -0,0 +1,45 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/viper"
+
+	"github.com/datum-cloud/galactic-agent/datapath"
+	"github.com/datum-cloud/galactic-agent/ovs"
+)
+
+// dp realizes attachments and routes on this host: the kernel's VRFs and
+// seg6 routes unless datapath is ovs.
+var dp datapath.Datapath = datapath.Kernel{}
+
+func loadDatapath() (datapath.Datapath, error) {
+	switch name := viper.GetString("datapath"); name {
+	case "kernel":
+		return datapath.Kernel{}, nil
+	case "ovs":
+		o := &ovs.Datapath{
+			Bridge: viper.GetString("ovs_bridge"),
+			Encap:  viper.GetString("ovs_encap"),
+			Vsctl:  viper.GetStringSlice("ovs_vsctl"),
+			Ofctl:  viper.GetStringSlice("ovs_ofctl"),
+		}
+		if o.Bridge == "" || len(o.Vsctl) == 0 || len(o.Ofctl) == 0 {
+			return nil, errors.New("ovs_bridge, ovs_vsctl and ovs_ofctl must be set for the ovs datapath")
+		}
+		if err := o.Setup(); err != nil {
+			return nil, fmt.Errorf("ovs datapath: %w", err)
+		}
+		return o, nil
+	default:
+		return nil, fmt.Errorf("datapath %q: want kernel or ovs", name)
+	}
+}
+
+// kernelDatapath reports whether dp is the kernel's, which reconcile can
+// read back.
+func kernelDatapath() bool {
+	_, ok := dp.(datapath.Kernel)
+	return ok
+}
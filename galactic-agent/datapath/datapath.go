@@ -0,0 +1,57 @@
+// Package datapath is how the agent realizes attachments and routes on
+// the host. The kernel datapath programs VRF routes with seg6 encap; the
+// ovs package provides one built on an Open vSwitch bridge.
+package datapath
+
+import (
+	"github.com/datum-cloud/galactic-agent/srv6"
+)
+
+// Datapath programs the ingress of attachments and the routes out of them.
+// Attachments and routes are named by SRv6 endpoint, prefixes and
+// segments as the controller sends them. Every method is idempotent.
+type Datapath interface {
+	IngressAdd(srv6Endpoint string) error
+	IngressDel(srv6Endpoint string) error
+	// EgressAdd routes prefix out of the attachment of srv6Endpoint
+	// through segments; a non-zero mtu applies to the route.
+	EgressAdd(prefix, srv6Endpoint string, segments []string, mtu int) error
+	EgressDel(prefix, srv6Endpoint string, segments []string) error
+	SetMTU(srv6Endpoint string, mtu int) error
+	// SetDSCP marks the traffic of the attachment to prefix, or all its
+	// traffic if prefix is empty.
+	SetDSCP(srv6Endpoint, prefix string, dscp uint8) error
+	ClearDSCP(srv6Endpoint, prefix string) error
+}
+
+// Kernel is the kernel datapath: a VRF per attachment, seg6 encap routes in
+// it and seg6local routes into it, see package srv6.
+type Kernel struct{}
+
+func (Kernel) IngressAdd(srv6Endpoint string) error {
+	return srv6.RouteIngressAdd(srv6Endpoint)
+}
+
+func (Kernel) IngressDel(srv6Endpoint string) error {
+	return srv6.RouteIngressDel(srv6Endpoint)
+}
+
+func (Kernel) EgressAdd(prefix, srv6Endpoint string, segments []string, mtu int) error {
+	return srv6.RouteEgressAddMTU(prefix, srv6Endpoint, segments, mtu)
+}
+
+func (Kernel) EgressDel(prefix, srv6Endpoint string, segments []string) error {
+	return srv6.RouteEgressDel(prefix, srv6Endpoint, segments)
+}
+
+func (Kernel) SetMTU(srv6Endpoint string, mtu int) error {
+	return srv6.SetMTU(srv6Endpoint, mtu)
+}
+
+func (Kernel) SetDSCP(srv6Endpoint, prefix string, dscp uint8) error {
+	return srv6.SetDSCP(srv6Endpoint, prefix, dscp)
+}
+
+func (Kernel) ClearDSCP(srv6Endpoint, prefix string) error {
+	return srv6.ClearDSCP(srv6Endpoint, prefix)
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/datum-cloud/galactic-agent/dnsreg"
+	"github.com/datum-cloud/galactic-agent/state"
+	"github.com/datum-cloud/galactic-common/util"
+)
+
+// names publishes the addresses of the registered attachments in DNS; nil
+// unless dns_backend is set.
+var names *dnsHost
+
+type dnsHost struct {
+	backend dnsreg.Backend
+	domain  string
+	retry   time.Duration
+	wake    chan struct{}
+}
+
+func loadDNS() (*dnsHost, error) {
+	domain := strings.Trim(viper.GetString("dns_domain"), ".")
+	ttl := viper.GetUint32("dns_ttl")
+	var backend dnsreg.Backend
+	switch name := viper.GetString("dns_backend"); name {
+	case "":
+		return nil, nil
+	case "file":
+		dir := viper.GetString("dns_zone_dir")
+		if dir == "" {
+			return nil, errors.New("dns_zone_dir is required for the file dns backend")
+		}
+		backend = &dnsreg.Files{Dir: dir, Domain: domain, TTL: ttl}
+	case "rfc2136":
+		u := &dnsreg.RFC2136{
+			Server:  viper.GetString("dns_server"),
+			KeyName: viper.GetString("dns_tsig_key_name"),
+			TTL:     ttl,
+		}
+		if u.Server == "" {
+			return nil, errors.New("dns_server is required for the rfc2136 dns backend")
+		}
+		if secret := viper.GetString("dns_tsig_secret"); secret != "" {
+			key, err := base64.StdEncoding.DecodeString(secret)
+			if err != nil {
+				return nil, fmt.Errorf("dns_tsig_secret: %w", err)
+			}
+			if u.KeyName == "" {
+				return nil, errors.New("dns_tsig_secret needs dns_tsig_key_name")
+			}
+			u.Key = key
+		}
+		backend = u
+	default:
+		return nil, fmt.Errorf("dns_backend %q: want file or rfc2136", name)
+	}
+	if domain == "" {
+		return nil, errors.New("dns_domain is empty")
+	}
+	if viper.GetDuration("dns_retry_interval") <= 0 {
+		return nil, errors.New("dns_retry_interval must be positive")
+	}
+	return &dnsHost{
+		backend: backend,
+		domain:  domain,
+		retry:   viper.GetDuration("dns_retry_interval"),
+		wake:    make(chan struct{}, 1),
+	}, nil
+}
+
+// notify asks run to sync; it never blocks, so stores may call it with
+// their lock held.
+func (n *dnsHost) notify() {
+	select {
+	case n.wake <- struct{}{}:
+	default:
+	}
+}
+
+// zones are the zones of the registered attachments: <vpc>.<domain>, with
+// the addresses of each attachment's host networks under its id and, if it
+// has one, its name.
+func (n *dnsHost) zones() []dnsreg.Zone {
+	byVPC := make(map[string]*dnsreg.Zone)
+	var zones []*dnsreg.Zone
+	for _, t := range tenantMap.All() {
+		for _, a := range domains[t].store.Snapshot().Attachments {
+			z, ok := byVPC[a.VPC]
+			if !ok {
+				z = &dnsreg.Zone{Name: a.VPC + "." + n.domain}
+				byVPC[a.VPC] = z
+				zones = append(zones, z)
+			}
+			for _, network := range a.Networks {
+				if !state.IsHost(network) {
+					continue
+				}
+				ip, err := util.ParseIP(strings.Split(network, "/")[0])
+				if err != nil {
+					continue
+				}
+				z.Add(a.VPCAttachment, ip)
+				if a.Name != "" {
+					z.Add(a.Name, ip)
+				}
+			}
+		}
+	}
+	out := make([]dnsreg.Zone, len(zones))
+	for i, z := range zones {
+		out[i] = *z
+	}
+	return out
+}
+
+// run syncs whenever the registry changes, and again retry after a sync
+// failed.
+func (n *dnsHost) run(ctx context.Context) error {
+	var retry <-chan time.Time
+	n.notify()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-n.wake:
+		case <-retry:
+		}
+		retry = nil
+		if err := n.backend.Sync(ctx, n.zones()); err != nil {
+			slog.Error("DNS", "err", err)
+			retry = time.After(n.retry)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+// Package dnsreg publishes the addresses of VPC attachments in DNS, a zone
+// per VPC, either as zone files for CoreDNS's file or auto plugin or as
+// RFC 2136 dynamic updates to a primary server.
+package dnsreg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+)
+
+// Zone is the records of a zone: addresses by name, relative to the zone.
+type Zone struct {
+	Name    string
+	Records map[string][]net.IP
+}
+
+// Backend makes the zones it publishes those given: zones and names not
+// given are removed.
+type Backend interface {
+	Sync(ctx context.Context, zones []Zone) error
+}
+
+// ErrName is returned for names that are not DNS labels.
+var ErrName = errors.New("invalid dns name")
+
+// CheckLabel refuses names that are not a single DNS label of letters,
+// digits and hyphens.
+func CheckLabel(name string) error {
+	if name == "" || len(name) > 63 || name[0] == '-' || name[len(name)-1] == '-' {
+		return fmt.Errorf("%w: %q", ErrName, name)
+	}
+	for _, c := range name {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-') {
+			return fmt.Errorf("%w: %q", ErrName, name)
+		}
+	}
+	return nil
+}
+
+// Add adds ip to the records of name, keeping them sorted and unique.
+func (z *Zone) Add(name string, ip net.IP) {
+	name = strings.ToLower(name)
+	if z.Records == nil {
+		z.Records = make(map[string][]net.IP)
+	}
+	ips := z.Records[name]
+	i, found := slices.BinarySearchFunc(ips, ip, compareIP)
+	if !found {
+		z.Records[name] = slices.Insert(ips, i, ip)
+	}
+}
+
+func compareIP(a, b net.IP) int {
+	return strings.Compare(string(a.To16()), string(b.To16()))
+}
+
+func sameIPs(a, b []net.IP) bool {
+	return slices.EqualFunc(a, b, net.IP.Equal)
+}
+
+// fqdn is name in zone, with the trailing dot.
+func fqdn(name, zone string) string {
+	if name == "" || name == "@" {
+		return zone + "."
+	}
+	return name + "." + zone + "."
+}
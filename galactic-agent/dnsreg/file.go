@@ -0,0 +1,119 @@
+package dnsreg
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Files writes a zone file db.<zone> per zone into Dir, the naming
+// CoreDNS's auto plugin loads by default, and removes those of zones
+// under Domain no longer given. A file is rewritten, with a new serial,
+// only when its records change.
+type Files struct {
+	Dir    string
+	Domain string
+	TTL    uint32
+
+	written map[string]string // records last written, by zone
+	serials map[string]uint32
+}
+
+func (f *Files) Sync(ctx context.Context, zones []Zone) error {
+	if f.written == nil {
+		f.written = make(map[string]string)
+		f.serials = make(map[string]uint32)
+	}
+	var errs []error
+	keep := make(map[string]bool, len(zones))
+	for _, z := range zones {
+		keep["db."+z.Name] = true
+		records := f.records(z)
+		if f.written[z.Name] == records {
+			continue
+		}
+		if err := f.write(z.Name, records); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		f.written[z.Name] = records
+	}
+	// zone files of the domain no zone is left in
+	old, err := filepath.Glob(filepath.Join(f.Dir, "db.*."+f.Domain))
+	if err != nil {
+		return err
+	}
+	for _, path := range old {
+		name := filepath.Base(path)
+		if keep[name] {
+			continue
+		}
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, err)
+			continue
+		}
+		delete(f.written, strings.TrimPrefix(name, "db."))
+	}
+	return errors.Join(errs...)
+}
+
+func (f *Files) records(z Zone) string {
+	names := make([]string, 0, len(z.Records))
+	for name := range z.Records {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b bytes.Buffer
+	for _, name := range names {
+		for _, ip := range z.Records[name] {
+			typ := "AAAA"
+			if ip.To4() != nil {
+				typ = "A"
+			}
+			fmt.Fprintf(&b, "%s\tIN\t%s\t%s\n", name, typ, ip)
+		}
+	}
+	return b.String()
+}
+
+// write replaces the zone file atomically, with a serial above the last.
+func (f *Files) write(zone, records string) error {
+	serial := uint32(time.Now().Unix())
+	if last := f.serials[zone]; serial <= last {
+		serial = last + 1
+	}
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "$ORIGIN %s.\n$TTL %d\n", zone, f.TTL)
+	fmt.Fprintf(&b, "@\tIN\tSOA\tns.%s. hostmaster.%s. %d 3600 600 86400 %d\n", zone, zone, serial, f.TTL)
+	fmt.Fprintf(&b, "@\tIN\tNS\tns.%s.\n", zone)
+	b.WriteString(records)
+
+	path := filepath.Join(f.Dir, "db."+zone)
+	tmp, err := os.CreateTemp(f.Dir, ".db."+zone+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) //nolint:errcheck
+	if _, err := tmp.Write(b.Bytes()); err != nil {
+		tmp.Close() //nolint:errcheck
+		return err
+	}
+	if err := tmp.Chmod(0o644); err != nil {
+		tmp.Close() //nolint:errcheck
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	f.serials[zone] = serial
+	return nil
+}
@@ -0,0 +1,236 @@
+package dnsreg
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+const (
+	typeA    = 1
+	typeSOA  = 6
+	typeAAAA = 28
+	typeTSIG = 250
+
+	classIN  = 1
+	classANY = 255
+
+	opcodeUpdate = 5
+
+	tsigAlgorithm = "hmac-sha256."
+	tsigFudge     = 300
+)
+
+var rcodes = map[uint16]string{
+	1: "FORMERR", 2: "SERVFAIL", 3: "NXDOMAIN", 4: "NOTIMP", 5: "REFUSED",
+	6: "YXDOMAIN", 7: "YXRRSET", 8: "NXRRSET", 9: "NOTAUTH", 10: "NOTZONE",
+}
+
+// RFC2136 sends dynamic updates over TCP to Server, a primary for the
+// zones, signed with TSIG (HMAC-SHA256) when Key is set. It replaces the
+// records of the names that changed since the last update; at the first
+// one every name given is replaced, but names published before the agent
+// started and gone since are left to expire.
+type RFC2136 struct {
+	Server  string
+	KeyName string
+	Key     []byte
+	TTL     uint32
+	Timeout time.Duration
+
+	published map[string]map[string][]net.IP // by zone and name
+}
+
+func (u *RFC2136) Sync(ctx context.Context, zones []Zone) error {
+	if u.published == nil {
+		u.published = make(map[string]map[string][]net.IP)
+	}
+	var errs []error
+	given := make(map[string]bool, len(zones))
+	for _, z := range zones {
+		given[z.Name] = true
+		if err := u.update(ctx, z.Name, z.Records); err != nil {
+			errs = append(errs, fmt.Errorf("zone %s: %w", z.Name, err))
+		}
+	}
+	for zone := range u.published {
+		if !given[zone] {
+			if err := u.update(ctx, zone, nil); err != nil {
+				errs = append(errs, fmt.Errorf("zone %s: %w", zone, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// update makes the names of zone published records, sending what changed.
+func (u *RFC2136) update(ctx context.Context, zone string, records map[string][]net.IP) error {
+	published := u.published[zone]
+	var rrs []byte
+	n := 0
+	for name, ips := range records {
+		if old, ok := published[name]; ok && sameIPs(old, ips) {
+			continue
+		}
+		// delete the name's addresses, then add those it has now
+		rrs = appendRR(rrs, fqdn(name, zone), typeA, classANY, 0, nil)
+		rrs = appendRR(rrs, fqdn(name, zone), typeAAAA, classANY, 0, nil)
+		n += 2
+		for _, ip := range ips {
+			if ip4 := ip.To4(); ip4 != nil {
+				rrs = appendRR(rrs, fqdn(name, zone), typeA, classIN, u.TTL, ip4)
+			} else {
+				rrs = appendRR(rrs, fqdn(name, zone), typeAAAA, classIN, u.TTL, ip.To16())
+			}
+			n++
+		}
+	}
+	for name := range published {
+		if _, ok := records[name]; !ok {
+			rrs = appendRR(rrs, fqdn(name, zone), typeA, classANY, 0, nil)
+			rrs = appendRR(rrs, fqdn(name, zone), typeAAAA, classANY, 0, nil)
+			n += 2
+		}
+	}
+	if n == 0 {
+		return nil
+	}
+
+	var id [2]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return err
+	}
+	msg := binary.BigEndian.AppendUint16(nil, binary.BigEndian.Uint16(id[:]))
+	msg = binary.BigEndian.AppendUint16(msg, opcodeUpdate<<11)
+	msg = binary.BigEndian.AppendUint16(msg, 1) // zone
+	msg = binary.BigEndian.AppendUint16(msg, 0) // prerequisites
+	msg = binary.BigEndian.AppendUint16(msg, uint16(n))
+	msg = binary.BigEndian.AppendUint16(msg, 0) // additional
+	msg = appendName(msg, zone+".")
+	msg = binary.BigEndian.AppendUint16(msg, typeSOA)
+	msg = binary.BigEndian.AppendUint16(msg, classIN)
+	msg = append(msg, rrs...)
+	if u.Key != nil {
+		msg = u.sign(msg, time.Now())
+	}
+	if err := u.exchange(ctx, msg); err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		delete(u.published, zone)
+		return nil
+	}
+	published = make(map[string][]net.IP, len(records))
+	for name, ips := range records {
+		published[name] = ips
+	}
+	u.published[zone] = published
+	return nil
+}
+
+// exchange sends msg and checks the response.
+func (u *RFC2136) exchange(ctx context.Context, msg []byte) error {
+	timeout := u.Timeout
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", u.Server)
+	if err != nil {
+		return err
+	}
+	defer conn.Close() //nolint:errcheck
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline) //nolint:errcheck
+	}
+	if _, err := conn.Write(binary.BigEndian.AppendUint16(nil, uint16(len(msg)))); err != nil {
+		return err
+	}
+	if _, err := conn.Write(msg); err != nil {
+		return err
+	}
+	var length [2]byte
+	if _, err := io.ReadFull(conn, length[:]); err != nil {
+		return err
+	}
+	resp := make([]byte, binary.BigEndian.Uint16(length[:]))
+	if _, err := io.ReadFull(conn, resp); err != nil {
+		return err
+	}
+	if len(resp) < 12 || resp[0] != msg[0] || resp[1] != msg[1] {
+		return errors.New("malformed response")
+	}
+	if rcode := binary.BigEndian.Uint16(resp[2:]) & 0xf; rcode != 0 {
+		if name, ok := rcodes[rcode]; ok {
+			return fmt.Errorf("update refused: %s", name)
+		}
+		return fmt.Errorf("update refused: rcode %d", rcode)
+	}
+	return nil
+}
+
+// sign appends a TSIG record to msg, as RFC 8945 specifies.
+func (u *RFC2136) sign(msg []byte, now time.Time) []byte {
+	keyName := strings.ToLower(strings.TrimSuffix(u.KeyName, ".") + ".")
+	signed := uint64(now.Unix())
+	timers := func(b []byte) []byte {
+		b = append(b, byte(signed>>40), byte(signed>>32), byte(signed>>24), byte(signed>>16), byte(signed>>8), byte(signed))
+		return binary.BigEndian.AppendUint16(b, tsigFudge)
+	}
+
+	mac := hmac.New(sha256.New, u.Key)
+	mac.Write(msg) //nolint:errcheck
+	vars := appendName(nil, keyName)
+	vars = binary.BigEndian.AppendUint16(vars, classANY)
+	vars = binary.BigEndian.AppendUint32(vars, 0)
+	vars = appendName(vars, tsigAlgorithm)
+	vars = timers(vars)
+	vars = binary.BigEndian.AppendUint16(vars, 0) // error
+	vars = binary.BigEndian.AppendUint16(vars, 0) // other len
+
+	mac.Write(vars) //nolint:errcheck
+	sum := mac.Sum(nil)
+
+	rdata := appendName(nil, tsigAlgorithm)
+	rdata = timers(rdata)
+	rdata = binary.BigEndian.AppendUint16(rdata, uint16(len(sum)))
+	rdata = append(rdata, sum...)
+	rdata = append(rdata, msg[0], msg[1]) // original id
+	rdata = binary.BigEndian.AppendUint16(rdata, 0)
+	rdata = binary.BigEndian.AppendUint16(rdata, 0)
+
+	msg = appendRR(msg, keyName, typeTSIG, classANY, 0, rdata)
+	binary.BigEndian.PutUint16(msg[10:], binary.BigEndian.Uint16(msg[10:])+1)
+	return msg
+}
+
+func appendRR(b []byte, name string, typ, class uint16, ttl uint32, rdata []byte) []byte {
+	b = appendName(b, name)
+	b = binary.BigEndian.AppendUint16(b, typ)
+	b = binary.BigEndian.AppendUint16(b, class)
+	b = binary.BigEndian.AppendUint32(b, ttl)
+	b = binary.BigEndian.AppendUint16(b, uint16(len(rdata)))
+	return append(b, rdata...)
+}
+
+// appendName appends name, fully qualified, in uncompressed wire format.
+func appendName(b []byte, name string) []byte {
+	for _, label := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		if label == "" {
+			continue
+		}
+		b = append(b, byte(len(label)))
+		b = append(b, label...)
+	}
+	return append(b, 0)
+}
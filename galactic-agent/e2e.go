@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/datum-cloud/galactic-agent/e2e"
+)
+
+func newE2ETestCmd() *cobra.Command {
+	var c e2e.Config
+	cmd := &cobra.Command{
+		Use:   "e2e-test",
+		Short: "Run a two-agent SRv6 connectivity test in local network namespaces",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+			defer stop()
+
+			if c.Binary == "" {
+				binary, err := os.Executable()
+				if err != nil {
+					return err
+				}
+				c.Binary = binary
+			}
+			return e2e.Run(ctx, c)
+		},
+	}
+	cmd.Flags().StringVar(&c.Binary, "agent-binary", "", "galactic-agent binary to run in each namespace (default: this binary)")
+	cmd.Flags().StringVar(&c.WorkDir, "workdir", "", "directory for sockets, configs and agent logs (default: a temporary directory)")
+	cmd.Flags().StringVar(&c.VPC, "vpc", "000000000e2e", "vpc id (hex) used for the test attachments")
+	cmd.Flags().DurationVar(&c.Timeout, "timeout", 30*time.Second, "time allowed for routes to converge")
+	cmd.Flags().BoolVar(&c.Keep, "keep", false, "leave namespaces and workdir in place for inspection")
+	return cmd
+}
@@ -0,0 +1,398 @@
+package e2e
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netns"
+
+	"github.com/datum-cloud/galactic-agent/broker"
+	"github.com/datum-cloud/galactic-agent/client"
+	"github.com/datum-cloud/galactic-agent/controller"
+	"github.com/datum-cloud/galactic-agent/fixtures"
+	"github.com/datum-cloud/galactic-agent/nsutil"
+	"github.com/datum-cloud/galactic-common/sysctl"
+	"github.com/datum-cloud/galactic-common/util"
+	"github.com/datum-cloud/galactic-common/vrf"
+)
+
+// Config controls the two-agent connectivity test. Binary is the
+// galactic-agent executable started inside each namespace.
+type Config struct {
+	Binary  string
+	WorkDir string
+	VPC     string
+	Timeout time.Duration
+	Keep    bool
+}
+
+// node is one simulated host: an agent namespace joined to the underlay and a
+// workload namespace attached to the agent through a VPC attachment.
+type node struct {
+	name          string
+	underlay      string
+	locator       string
+	vpcAttachment string
+	workload      string // workload address, registered as a /32
+	peerUnderlay  string
+	peerLocator   string
+
+	ns, wl netns.NsHandle
+	agent  *exec.Cmd
+}
+
+const (
+	underlayLink = "e2e0"
+	gatewayIP    = "169.254.1.1"
+)
+
+func (n *node) nsName() string { return "galactic-e2e-" + n.name }
+func (n *node) wlName() string { return "galactic-e2e-" + n.name + "-wl" }
+
+// Run builds two agents A and B in separate network namespaces, connects
+// them to an embedded broker and the reference controller, registers one
+// workload on each and verifies they can ping each other over SRv6.
+func Run(ctx context.Context, c Config) error {
+	if c.Timeout == 0 {
+		c.Timeout = 30 * time.Second
+	}
+	if c.VPC == "" {
+		c.VPC = "000000000e2e"
+	}
+	dir := c.WorkDir
+	if dir == "" {
+		d, err := os.MkdirTemp("", "galactic-e2e-")
+		if err != nil {
+			return err
+		}
+		dir = d
+	}
+	if !c.Keep {
+		defer os.RemoveAll(dir) //nolint:errcheck
+	}
+
+	a := &node{name: "a", underlay: "fd00:e2e::1", locator: "fc00:0:0:a::/64", vpcAttachment: "0001", workload: "10.1.0.1"}
+	b := &node{name: "b", underlay: "fd00:e2e::2", locator: "fc00:0:0:b::/64", vpcAttachment: "0002", workload: "10.2.0.1"}
+	a.peerUnderlay, a.peerLocator = b.underlay, b.locator
+	b.peerUnderlay, b.peerLocator = a.underlay, a.locator
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	brokerSock := filepath.Join(dir, "broker.sock")
+	listener, err := net.Listen("unix", brokerSock)
+	if err != nil {
+		return fmt.Errorf("broker listen failed: %w", err)
+	}
+	go func() {
+		if err := broker.New().Serve(ctx, listener); err != nil {
+			slog.Warn("e2e: broker stopped", "err", err)
+		}
+	}()
+	mqttURL := "unix://" + brokerSock
+
+	ctrl := &controller.Controller{URL: mqttURL, ClientID: "galactic-e2e-controller", QoS: 1}
+	go func() {
+		if err := ctrl.Run(ctx); err != nil {
+			slog.Warn("e2e: controller stopped", "err", err)
+		}
+	}()
+
+	nodes := []*node{a, b}
+	defer func() {
+		for _, n := range nodes {
+			n.teardown(c.Keep)
+		}
+	}()
+	for _, n := range nodes {
+		if err := n.setupNamespaces(); err != nil {
+			return fmt.Errorf("node %s: %w", n.name, err)
+		}
+	}
+	if err := connectUnderlay(a, b); err != nil {
+		return fmt.Errorf("underlay: %w", err)
+	}
+	for _, n := range nodes {
+		if err := n.setupAttachment(c.VPC); err != nil {
+			return fmt.Errorf("node %s: %w", n.name, err)
+		}
+		if err := n.startAgent(c.Binary, dir, mqttURL); err != nil {
+			return fmt.Errorf("node %s: %w", n.name, err)
+		}
+	}
+	for _, n := range nodes {
+		if err := n.register(ctx, dir, c.VPC); err != nil {
+			return fmt.Errorf("node %s: %w", n.name, err)
+		}
+	}
+
+	deadline := time.Now().Add(c.Timeout)
+	for _, pair := range [][2]*node{{a, b}, {b, a}} {
+		if err := ping(pair[0], pair[1].workload, deadline); err != nil {
+			return fmt.Errorf("ping %s -> %s failed: %w", pair[0].name, pair[1].name, err)
+		}
+		slog.Info("e2e: ping ok", "from", pair[0].name, "from_workload", pair[0].workload, "to", pair[1].name, "to_workload", pair[1].workload)
+	}
+	return nil
+}
+
+func (n *node) setupNamespaces() error {
+	var err error
+	if n.ns, err = nsutil.New(n.nsName()); err != nil {
+		return err
+	}
+	if n.wl, err = nsutil.New(n.wlName()); err != nil {
+		return err
+	}
+	return nsutil.Do(n.ns, func() error {
+		if err := fixtures.ConfigureHost(); err != nil {
+			return err
+		}
+		lo, err := netlink.LinkByName("lo")
+		if err != nil {
+			return err
+		}
+		if err := netlink.LinkSetUp(lo); err != nil {
+			return err
+		}
+		dummy := &netlink.Dummy{LinkAttrs: netlink.LinkAttrs{Name: "lo-galactic"}}
+		if err := netlink.LinkAdd(dummy); err != nil {
+			return err
+		}
+		return netlink.LinkSetUp(dummy)
+	})
+}
+
+func connectUnderlay(a, b *node) error {
+	if err := nsutil.Do(a.ns, func() error {
+		veth := &netlink.Veth{LinkAttrs: netlink.LinkAttrs{Name: underlayLink}, PeerName: underlayLink + "p"}
+		if err := netlink.LinkAdd(veth); err != nil {
+			return err
+		}
+		peer, err := netlink.LinkByName(underlayLink + "p")
+		if err != nil {
+			return err
+		}
+		if err := netlink.LinkSetNsFd(peer, int(b.ns)); err != nil {
+			return err
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	if err := nsutil.Do(b.ns, func() error {
+		peer, err := netlink.LinkByName(underlayLink + "p")
+		if err != nil {
+			return err
+		}
+		return netlink.LinkSetName(peer, underlayLink)
+	}); err != nil {
+		return err
+	}
+	for _, n := range []*node{a, b} {
+		if err := nsutil.Do(n.ns, n.configureUnderlay); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (n *node) configureUnderlay() error {
+	link, err := netlink.LinkByName(underlayLink)
+	if err != nil {
+		return err
+	}
+	addr, err := netlink.ParseAddr(n.underlay + "/64")
+	if err != nil {
+		return err
+	}
+	addr.Flags = syscall.IFA_F_NODAD
+	if err := netlink.AddrAdd(link, addr); err != nil {
+		return err
+	}
+	if err := nsutil.SetSysctl("net/ipv6/conf/"+underlayLink+"/seg6_enabled", "1"); err != nil {
+		return err
+	}
+	if err := netlink.LinkSetUp(link); err != nil {
+		return err
+	}
+	dst, err := netlink.ParseIPNet(n.peerLocator)
+	if err != nil {
+		return err
+	}
+	return netlink.RouteReplace(&netlink.Route{
+		Dst:       dst,
+		Gw:        net.ParseIP(n.peerUnderlay),
+		LinkIndex: link.Attrs().Index,
+	})
+}
+
+// setupAttachment does what the CNI would do: create the VRF, the host and
+// guest interfaces, and move the guest side into the workload namespace.
+func (n *node) setupAttachment(vpcHex string) error {
+	vpc, err := util.HexToBase62(vpcHex)
+	if err != nil {
+		return err
+	}
+	vpcAttachment, err := util.HexToBase62(n.vpcAttachment)
+	if err != nil {
+		return err
+	}
+	hostName := util.GenerateInterfaceNameHost(vpc, vpcAttachment)
+	guestName := util.GenerateInterfaceNameGuest(vpc, vpcAttachment)
+	workload := net.ParseIP(n.workload)
+
+	if err := nsutil.Do(n.ns, func() error {
+		if err := vrf.Add(vpc, vpcAttachment); err != nil {
+			return err
+		}
+		vrfLink, err := netlink.LinkByName(util.GenerateInterfaceNameVRF(vpc, vpcAttachment))
+		if err != nil {
+			return err
+		}
+		veth := &netlink.Veth{LinkAttrs: netlink.LinkAttrs{Name: hostName}, PeerName: guestName}
+		if err := netlink.LinkAdd(veth); err != nil {
+			return err
+		}
+		host, err := netlink.LinkByName(hostName)
+		if err != nil {
+			return err
+		}
+		if err := netlink.LinkSetMaster(host, vrfLink); err != nil {
+			return err
+		}
+		if err := netlink.AddrAdd(host, &netlink.Addr{IPNet: netlink.NewIPNet(net.ParseIP(gatewayIP))}); err != nil {
+			return err
+		}
+		if err := sysctl.ConfigureInterfaceSysctls(hostName); err != nil {
+			return err
+		}
+		if err := netlink.LinkSetUp(host); err != nil {
+			return err
+		}
+		table, err := vrf.GetVRFIdForVPC(vpc, vpcAttachment)
+		if err != nil {
+			return err
+		}
+		if err := netlink.RouteReplace(&netlink.Route{
+			Dst:       netlink.NewIPNet(workload),
+			LinkIndex: host.Attrs().Index,
+			Table:     int(table),
+			Scope:     netlink.SCOPE_LINK,
+		}); err != nil {
+			return err
+		}
+		guest, err := netlink.LinkByName(guestName)
+		if err != nil {
+			return err
+		}
+		return netlink.LinkSetNsFd(guest, int(n.wl))
+	}); err != nil {
+		return err
+	}
+
+	return nsutil.Do(n.wl, func() error {
+		guest, err := netlink.LinkByName(guestName)
+		if err != nil {
+			return err
+		}
+		if err := netlink.AddrAdd(guest, &netlink.Addr{IPNet: netlink.NewIPNet(workload)}); err != nil {
+			return err
+		}
+		if err := netlink.LinkSetUp(guest); err != nil {
+			return err
+		}
+		return netlink.RouteReplace(&netlink.Route{
+			LinkIndex: guest.Attrs().Index,
+			Gw:        net.ParseIP(gatewayIP),
+			Flags:     int(netlink.FLAG_ONLINK),
+		})
+	})
+}
+
+func (n *node) socketPath(dir string) string {
+	return filepath.Join(dir, n.name+".sock")
+}
+
+func (n *node) startAgent(binary, dir, mqttURL string) error {
+	configPath := filepath.Join(dir, n.name+".yaml")
+	// each agent keeps its state, journal and agent_id apart from the
+	// other's and the host agent's
+	config := fmt.Sprintf(`srv6_net: %q
+socket_path: %q
+state_dir: %q
+mqtt_url: %q
+mqtt_clientid: %q
+mqtt_qos: 1
+mqtt_topic_receive: "galactic/%s/receive"
+mqtt_topic_send: "galactic/%s/send"
+`, n.locator, n.socketPath(dir), filepath.Join(dir, n.name), mqttURL, "galactic-e2e-"+n.name, n.name, n.name)
+	if err := os.WriteFile(configPath, []byte(config), 0o644); err != nil {
+		return err
+	}
+
+	logFile, err := os.Create(filepath.Join(dir, n.name+".log"))
+	if err != nil {
+		return err
+	}
+	n.agent = exec.Command(binary, "--config", configPath)
+	n.agent.Stdout = logFile
+	n.agent.Stderr = logFile
+	// the child inherits the network namespace of the thread that forks it
+	return nsutil.Do(n.ns, n.agent.Start)
+}
+
+func (n *node) register(ctx context.Context, dir, vpc string) error {
+	// the client retries while the agent is still starting up
+	c, err := client.New(n.socketPath(dir), client.WithRetries(20, 100*time.Millisecond))
+	if err != nil {
+		return err
+	}
+	defer c.Close() //nolint:errcheck
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	return c.Register(ctx, vpc, n.vpcAttachment, n.workload+"/32")
+}
+
+func ping(from *node, dst string, deadline time.Time) error {
+	var lastErr error
+	for time.Now().Before(deadline) {
+		cmd := exec.Command("ping", "-c", "1", "-W", "1", dst)
+		out := []byte{}
+		lastErr = nsutil.Do(from.wl, func() error {
+			var err error
+			out, err = cmd.CombinedOutput()
+			return err
+		})
+		if lastErr == nil {
+			return nil
+		}
+		lastErr = fmt.Errorf("%w: %s", lastErr, out)
+		time.Sleep(500 * time.Millisecond)
+	}
+	return lastErr
+}
+
+func (n *node) teardown(keep bool) {
+	if n.agent != nil && n.agent.Process != nil {
+		_ = n.agent.Process.Signal(syscall.SIGTERM)
+		_ = n.agent.Wait()
+	}
+	_ = n.wl.Close()
+	_ = n.ns.Close()
+	if keep {
+		return
+	}
+	for _, name := range []string{n.wlName(), n.nsName()} {
+		_ = netns.DeleteNamed(name)
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/datum-cloud/galactic-agent/enroll"
+)
+
+func newEnrollCmd() *cobra.Command {
+	var (
+		url, token, tokenFile, caFile string
+		dir, out                      string
+		force                         bool
+		timeout                       time.Duration
+	)
+	cmd := &cobra.Command{
+		Use:   "enroll",
+		Short: "Obtain this host's identity and config from a bootstrap endpoint",
+		Long: `Trade a one-time enrollment token for this agent's long-term identity,
+broker credentials, topics and srv6_net, and write them out as a config
+file the agent can then be started with.
+
+A P-256 key is generated locally and only a certificate request for it is
+sent; the issued certificate, the key and any CA and controller trust
+bundle are written to --dir.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if tokenFile != "" {
+				b, err := os.ReadFile(tokenFile)
+				if err != nil {
+					return err
+				}
+				token = string(trimNewline(b))
+			}
+			var roots *x509.CertPool
+			if caFile != "" {
+				b, err := os.ReadFile(caFile)
+				if err != nil {
+					return err
+				}
+				roots = x509.NewCertPool()
+				if !roots.AppendCertsFromPEM(b) {
+					return fmt.Errorf("%s: no certificates", caFile)
+				}
+			}
+			ctx, cancel := context.WithTimeout(cmd.Context(), timeout)
+			defer cancel()
+			result, err := enroll.Enroll(ctx, url, token, roots)
+			if err != nil {
+				return err
+			}
+			if err := result.Write(dir, out, force); err != nil {
+				return err
+			}
+			slog.Info("Enrolled", "config", out, "srv6_net", result.SRv6Net)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&url, "url", "", "bootstrap endpoint, e.g. https://bootstrap.example.com/v1/enroll")
+	cmd.Flags().StringVar(&token, "token", "", "one-time enrollment token")
+	cmd.Flags().StringVar(&tokenFile, "token-file", "", "read the enrollment token from this file instead")
+	cmd.Flags().StringVar(&caFile, "bootstrap-ca", "", "CA that signed the bootstrap endpoint's certificate; default system roots")
+	cmd.Flags().StringVar(&dir, "dir", "/etc/galactic/identity", "directory for the key, certificates and trust bundle")
+	cmd.Flags().StringVar(&out, "out", "/etc/galactic/galactic-agent.yaml", "config file to write")
+	cmd.Flags().BoolVar(&force, "force", false, "overwrite an existing config file")
+	cmd.Flags().DurationVar(&timeout, "timeout", time.Minute, "time allowed for enrollment")
+	_ = cmd.MarkFlagRequired("url")
+	return cmd
+}
+
+func trimNewline(b []byte) []byte {
+	for len(b) > 0 && (b[len(b)-1] == '\n' || b[len(b)-1] == '\r') {
+		b = b[:len(b)-1]
+	}
+	return b
+}
@@ -0,0 +1,198 @@
+// Package enroll provisions a fresh agent: it trades a one-time token for
+// the agent's long-term identity, broker credentials, topics and srv6_net
+// at a bootstrap endpoint, and writes them out as the agent's config.
+package enroll
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/viper"
+)
+
+// Request is posted to the bootstrap endpoint, with the token also sent as
+// a bearer token.
+type Request struct {
+	Token    string `json:"token"`
+	Hostname string `json:"hostname"`
+	// CSR is a PEM certificate request for the key generated on the host;
+	// the private key never leaves it.
+	CSR string `json:"csr"`
+}
+
+// Response is what the bootstrap endpoint answers.
+type Response struct {
+	MQTTURL          string `json:"mqtt_url"`
+	MQTTClientID     string `json:"mqtt_clientid"`
+	MQTTUsername     string `json:"mqtt_username"`
+	MQTTPassword     string `json:"mqtt_password"`
+	MQTTTopicReceive string `json:"mqtt_topic_receive"`
+	MQTTTopicSend    string `json:"mqtt_topic_send"`
+	SRv6Net          string `json:"srv6_net"`
+	// Certificate is the PEM chain issued for the CSR, leaf first.
+	Certificate string `json:"certificate"`
+	// CA is the PEM bundle the broker's certificate chains to.
+	CA string `json:"ca"`
+	// TrustBundle optionally holds PEM keys controller envelopes must be
+	// signed with, see route_trust_bundle.
+	TrustBundle string `json:"route_trust_bundle,omitempty"`
+}
+
+// Result is an enrollment ready to be written.
+type Result struct {
+	Response
+	KeyPEM []byte
+}
+
+// Enroll generates a P-256 key, posts a CSR for it with token to url and
+// returns the issued identity and settings. roots verifies the endpoint;
+// nil uses the system roots.
+func Enroll(ctx context.Context, url, token string, roots *x509.CertPool) (*Result, error) {
+	if token == "" {
+		return nil, errors.New("enrollment token is required")
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, err
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: hostname},
+	}, key)
+	if err != nil {
+		return nil, err
+	}
+	body, err := json.Marshal(Request{
+		Token:    token,
+		Hostname: hostname,
+		CSR:      string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csr})),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig: &tls.Config{RootCAs: roots, MinVersion: tls.VersionTLS12},
+	}}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close() //nolint:errcheck
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("enrollment refused: %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	var r Result
+	if err := json.NewDecoder(resp.Body).Decode(&r.Response); err != nil {
+		return nil, fmt.Errorf("enrollment response: %w", err)
+	}
+	if err := r.check(key); err != nil {
+		return nil, err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	r.KeyPEM = pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	return &r, nil
+}
+
+// check makes sure the response is usable before anything is written.
+func (r *Result) check(key *ecdsa.PrivateKey) error {
+	if r.MQTTURL == "" || r.SRv6Net == "" {
+		return errors.New("enrollment response lacks mqtt_url or srv6_net")
+	}
+	block, _ := pem.Decode([]byte(r.Certificate))
+	if block == nil {
+		return errors.New("enrollment response has no certificate")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return fmt.Errorf("issued certificate: %w", err)
+	}
+	if pub, ok := cert.PublicKey.(*ecdsa.PublicKey); !ok || !pub.Equal(&key.PublicKey) {
+		return errors.New("issued certificate is not for the enrolled key")
+	}
+	if r.CA != "" && !x509.NewCertPool().AppendCertsFromPEM([]byte(r.CA)) {
+		return errors.New("enrollment response CA has no certificates")
+	}
+	return nil
+}
+
+// Write stores the key material in dir and the settings in a new config
+// file at configPath, which must not exist unless force is set. Everything
+// is written readable by the owner only.
+func (r *Result) Write(dir, configPath string, force bool) error {
+	if _, err := os.Stat(configPath); err == nil && !force {
+		return fmt.Errorf("%s exists; this host is already enrolled", configPath)
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	v := viper.New()
+	v.SetConfigPermissions(0o600)
+	files := []struct {
+		name, key string
+		data      []byte
+	}{
+		{"agent.key", "mqtt_tls_key_file", r.KeyPEM},
+		{"agent.crt", "mqtt_tls_cert_file", []byte(r.Certificate)},
+		{"ca.crt", "mqtt_tls_ca_file", []byte(r.CA)},
+		{"controllers.pem", "route_trust_bundle", []byte(r.TrustBundle)},
+	}
+	for _, f := range files {
+		if len(f.data) == 0 {
+			continue
+		}
+		path := filepath.Join(dir, f.name)
+		if err := os.WriteFile(path, f.data, 0o600); err != nil {
+			return err
+		}
+		v.Set(f.key, path)
+	}
+	// the identity key also signs envelopes, so controllers can tell
+	// agents apart independently of the broker
+	v.Set("signing_key_file", filepath.Join(dir, "agent.key"))
+	settings := map[string]string{
+		"mqtt_url":           r.MQTTURL,
+		"mqtt_clientid":      r.MQTTClientID,
+		"mqtt_username":      r.MQTTUsername,
+		"mqtt_password":      r.MQTTPassword,
+		"mqtt_topic_receive": r.MQTTTopicReceive,
+		"mqtt_topic_send":    r.MQTTTopicSend,
+		"srv6_net":           r.SRv6Net,
+	}
+	for k, val := range settings {
+		if val != "" {
+			v.Set(k, val)
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(configPath), 0o755); err != nil {
+		return err
+	}
+	return v.WriteConfigAs(configPath)
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/datum-cloud/galactic-agent/api/local"
+	"github.com/datum-cloud/galactic-agent/audit"
+)
+
+// eventFeed is the audit sink behind the local API's WatchEvents: it
+// publishes registrations and routes as they are audited, and failures
+// and refused routes as errors.
+type eventFeed struct{}
+
+// eventTypes are the event types of the audited actions published.
+var eventTypes = map[string]local.Event_Type{
+	audit.ActionRegister:    local.Event_REGISTERED,
+	audit.ActionDeregister:  local.Event_DEREGISTERED,
+	audit.ActionRouteAdd:    local.Event_ROUTE_ADDED,
+	audit.ActionRouteDelete: local.Event_ROUTE_DELETED,
+}
+
+func (eventFeed) Emit(e audit.Event) error {
+	typ, ok := eventTypes[e.Action]
+	if !ok && !e.Failed() {
+		return nil
+	}
+	ev := &local.Event{
+		Type:          typ,
+		TimeUnixNano:  e.Time.UnixNano(),
+		Tenant:        e.Fields["tenant"],
+		Vpc:           e.Fields["vpc"],
+		Vpcattachment: e.Fields["vpcattachment"],
+		Network:       e.Fields["network"],
+		Srv6Endpoint:  e.Fields["srv6_endpoint"],
+	}
+	if segments := e.Fields["srv6_segments"]; segments != "" {
+		ev.Srv6Segments = strings.Split(segments, ",")
+	}
+	if e.Failed() {
+		ev.Type, ev.Operation = local.Event_ERROR, e.Action
+		ev.Error = e.Fields["result"]
+		if e.Action == audit.ActionRefused {
+			ev.Error = e.Fields["reason"] + ": " + e.Fields["detail"]
+		}
+	}
+	publish(ev)
+	return nil
+}
+
+func (eventFeed) Close() error { return nil }
+
+// publishConnected tells the WatchEvents subscribers the broker of d
+// connected.
+func publishConnected(d *domain) {
+	publish(&local.Event{Type: local.Event_MQTT_CONNECTED, TimeUnixNano: time.Now().UnixNano(), Tenant: d.Name})
+}
+
+// publishError tells the WatchEvents subscribers operation failed in
+// tenant with err.
+func publishError(tenant, operation string, err error) {
+	publish(&local.Event{Type: local.Event_ERROR, TimeUnixNano: time.Now().UnixNano(), Tenant: tenant, Operation: operation, Error: err.Error()})
+}
+
+// keptErrors is how many of the last errors Status reports.
+const keptErrors = 20
+
+// lastErrors are the last ERROR events published, oldest first.
+var lastErrors struct {
+	mu     sync.Mutex
+	events []*local.Event
+}
+
+// publish sends e to the WatchEvents subscribers and, if it is an error,
+// keeps it for Status.
+func publish(e *local.Event) {
+	if e.Type == local.Event_ERROR {
+		lastErrors.mu.Lock()
+		if len(lastErrors.events) == keptErrors {
+			lastErrors.events = lastErrors.events[1:]
+		}
+		lastErrors.events = append(lastErrors.events, e)
+		lastErrors.mu.Unlock()
+	}
+	l.Publish(e)
+}
+
+// recentErrors returns the kept errors, newest first.
+func recentErrors() []*local.ErrorStatus {
+	lastErrors.mu.Lock()
+	events := slices.Clone(lastErrors.events)
+	lastErrors.mu.Unlock()
+	slices.Reverse(events)
+	out := make([]*local.ErrorStatus, len(events))
+	for i, e := range events {
+		out[i] = &local.ErrorStatus{
+			TimeUnix:  e.GetTimeUnixNano() / int64(time.Second),
+			Tenant:    e.GetTenant(),
+			Operation: e.GetOperation(),
+			Error:     e.GetError(),
+		}
+	}
+	return out
+}
@@ -0,0 +1,69 @@
+// Package fips restricts the agent to FIPS 140-3 approved algorithms. The
+// mode relies on the Go Cryptographic Module: build with GOFIPS140 set, or
+// run with GODEBUG=fips140=on, and the agent refuses to start otherwise.
+package fips
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/fips140"
+	"crypto/rsa"
+	"crypto/tls"
+	"errors"
+	"fmt"
+)
+
+// ErrNotEnabled is returned by Require outside FIPS 140-3 mode.
+var ErrNotEnabled = errors.New("FIPS 140-3 mode is not enabled; build with GOFIPS140 or run with GODEBUG=fips140=on")
+
+// MinRSABits is the smallest RSA modulus accepted.
+const MinRSABits = 2048
+
+// Require fails unless the Go Cryptographic Module runs in FIPS mode.
+func Require() error {
+	if !fips140.Enabled() {
+		return ErrNotEnabled
+	}
+	return nil
+}
+
+// RestrictTLS returns a copy of config limited to TLS 1.2+ with ECDHE
+// AES-GCM suites and NIST curves. A nil config stays nil.
+func RestrictTLS(config *tls.Config) *tls.Config {
+	if config == nil {
+		return nil
+	}
+	c := config.Clone()
+	c.MinVersion = max(c.MinVersion, tls.VersionTLS12)
+	c.CipherSuites = []uint16{
+		tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+		tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+		tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+		tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	}
+	c.CurvePreferences = []tls.CurveID{tls.CurveP256, tls.CurveP384}
+	return c
+}
+
+// CheckKey rejects signing and verification keys FIPS 186-5 doesn't
+// approve.
+func CheckKey(key crypto.PublicKey) error {
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		switch k.Curve {
+		case elliptic.P256(), elliptic.P384(), elliptic.P521():
+			return nil
+		}
+		return fmt.Errorf("ECDSA curve %s is not approved", k.Curve.Params().Name)
+	case *rsa.PublicKey:
+		if k.N.BitLen() < MinRSABits {
+			return fmt.Errorf("RSA key of %d bits is below %d", k.N.BitLen(), MinRSABits)
+		}
+		return nil
+	case ed25519.PublicKey:
+		return nil
+	}
+	return fmt.Errorf("key type %T is not approved", key)
+}
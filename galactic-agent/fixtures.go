@@ -0,0 +1,72 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/datum-cloud/galactic-agent/fixtures"
+)
+
+func newFixturesCmd() *cobra.Command {
+	var (
+		vpc          string
+		first, count int
+	)
+	cmd := &cobra.Command{
+		Use:   "fixtures",
+		Short: "Provision or tear down VPC attachments for manual testing",
+	}
+	cmd.PersistentFlags().StringVar(&vpc, "vpc", "000000000001", "vpc id (hex)")
+	cmd.PersistentFlags().IntVar(&first, "first", 1, "id of the first vpc attachment")
+	cmd.PersistentFlags().IntVarP(&count, "count", "n", 1, "number of vpc attachments")
+
+	create := &cobra.Command{
+		Use:   "create",
+		Short: "Create the loopback, host sysctls and the VRF and veth pair of each attachment",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := fixtures.ConfigureHost(); err != nil {
+				return err
+			}
+			if err := fixtures.EnsureLoopback(); err != nil {
+				return err
+			}
+			for _, a := range fixtures.Range(vpc, first, count) {
+				if err := fixtures.Create(a); err != nil {
+					return fmt.Errorf("create %s/%s: %w", a.VPC, a.VPCAttachment, err)
+				}
+				table, err := fixtures.Table(a)
+				if err != nil {
+					return err
+				}
+				fmt.Printf("Attachment %s/%s ready in table %d\n", a.VPC, a.VPCAttachment, table)
+			}
+			return nil
+		},
+	}
+
+	var loopback bool
+	destroy := &cobra.Command{
+		Use:   "destroy",
+		Short: "Remove the attachments; pieces that are already gone are skipped",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var errs []error
+			for _, a := range fixtures.Range(vpc, first, count) {
+				if err := fixtures.Destroy(a); err != nil {
+					errs = append(errs, fmt.Errorf("destroy %s/%s: %w", a.VPC, a.VPCAttachment, err))
+				}
+			}
+			if loopback {
+				if err := fixtures.DestroyLoopback(); err != nil {
+					errs = append(errs, err)
+				}
+			}
+			return errors.Join(errs...)
+		},
+	}
+	destroy.Flags().BoolVar(&loopback, "loopback", false, "also remove the egress loopback device")
+
+	cmd.AddCommand(create, destroy)
+	return cmd
+}
@@ -0,0 +1,176 @@
+package fixtures
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/vishvananda/netlink"
+
+	"github.com/datum-cloud/galactic-agent/nsutil"
+	"github.com/datum-cloud/galactic-agent/reconcile"
+	"github.com/datum-cloud/galactic-agent/srv6/routeegress"
+	"github.com/datum-cloud/galactic-common/sysctl"
+	"github.com/datum-cloud/galactic-common/util"
+	"github.com/datum-cloud/galactic-common/vrf"
+)
+
+// Attachment identifies a VPC attachment by its hex ids, as used on the
+// local API.
+type Attachment struct {
+	VPC           string
+	VPCAttachment string
+}
+
+type names struct {
+	vrf, host, guest string
+}
+
+func (a Attachment) names() (names, error) {
+	vpc, err := util.HexToBase62(a.VPC)
+	if err != nil {
+		return names{}, err
+	}
+	vpcAttachment, err := util.HexToBase62(a.VPCAttachment)
+	if err != nil {
+		return names{}, err
+	}
+	return names{
+		vrf:   util.GenerateInterfaceNameVRF(vpc, vpcAttachment),
+		host:  util.GenerateInterfaceNameHost(vpc, vpcAttachment),
+		guest: util.GenerateInterfaceNameGuest(vpc, vpcAttachment),
+	}, nil
+}
+
+func notFound(err error) bool {
+	var lnf netlink.LinkNotFoundError
+	return errors.As(err, &lnf)
+}
+
+// Range returns n attachments of vpc with consecutive ids starting at first.
+func Range(vpc string, first, n int) []Attachment {
+	atts := make([]Attachment, n)
+	for i := range atts {
+		atts[i] = Attachment{VPC: vpc, VPCAttachment: fmt.Sprintf("%04x", first+i)}
+	}
+	return atts
+}
+
+// ConfigureHost applies reconcile.HostSysctls to the current namespace.
+func ConfigureHost() error {
+	for key, value := range reconcile.HostSysctls {
+		if err := nsutil.SetSysctl(key, value); err != nil {
+			return fmt.Errorf("sysctl %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// EnsureLoopback creates the device egress routes are attached to.
+func EnsureLoopback() error {
+	if _, err := netlink.LinkByName(routeegress.LoopbackDevice); err == nil {
+		return nil
+	} else if !notFound(err) {
+		return err
+	}
+	dummy := &netlink.Dummy{LinkAttrs: netlink.LinkAttrs{Name: routeegress.LoopbackDevice}}
+	if err := netlink.LinkAdd(dummy); err != nil {
+		return err
+	}
+	return netlink.LinkSetUp(dummy)
+}
+
+// DestroyLoopback removes the egress device, and with it every egress route.
+func DestroyLoopback() error {
+	link, err := netlink.LinkByName(routeegress.LoopbackDevice)
+	if notFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return netlink.LinkDel(link)
+}
+
+// Create provisions what the CNI would for an attachment: the VRF and a
+// veth pair whose host side is enslaved to it. Existing pieces are reused.
+func Create(a Attachment) error {
+	n, err := a.names()
+	if err != nil {
+		return err
+	}
+	vpc, _ := util.HexToBase62(a.VPC)
+	vpcAttachment, _ := util.HexToBase62(a.VPCAttachment)
+
+	vrfLink, err := netlink.LinkByName(n.vrf)
+	if notFound(err) {
+		if err := vrf.Add(vpc, vpcAttachment); err != nil {
+			return err
+		}
+		vrfLink, err = netlink.LinkByName(n.vrf)
+	}
+	if err != nil {
+		return err
+	}
+
+	host, err := netlink.LinkByName(n.host)
+	if notFound(err) {
+		veth := &netlink.Veth{LinkAttrs: netlink.LinkAttrs{Name: n.host}, PeerName: n.guest}
+		if err := netlink.LinkAdd(veth); err != nil {
+			return err
+		}
+		host, err = netlink.LinkByName(n.host)
+	}
+	if err != nil {
+		return err
+	}
+	if err := netlink.LinkSetMaster(host, vrfLink); err != nil {
+		return err
+	}
+	if err := sysctl.ConfigureInterfaceSysctls(n.host); err != nil {
+		return err
+	}
+	if guest, err := netlink.LinkByName(n.guest); err == nil {
+		if err := netlink.LinkSetUp(guest); err != nil {
+			return err
+		}
+	}
+	return netlink.LinkSetUp(host)
+}
+
+// Destroy removes the attachment's interfaces and VRF, ignoring pieces that
+// are already gone.
+func Destroy(a Attachment) error {
+	n, err := a.names()
+	if err != nil {
+		return err
+	}
+	if host, err := netlink.LinkByName(n.host); err == nil {
+		if err := netlink.LinkDel(host); err != nil {
+			return err
+		}
+	} else if !notFound(err) {
+		return err
+	}
+	if _, err := netlink.LinkByName(n.vrf); err == nil {
+		vpc, _ := util.HexToBase62(a.VPC)
+		vpcAttachment, _ := util.HexToBase62(a.VPCAttachment)
+		return vrf.Delete(vpc, vpcAttachment)
+	} else if !notFound(err) {
+		return err
+	}
+	return nil
+}
+
+// Table returns the routing table of the attachment's VRF.
+func Table(a Attachment) (int, error) {
+	vpc, err := util.HexToBase62(a.VPC)
+	if err != nil {
+		return 0, err
+	}
+	vpcAttachment, err := util.HexToBase62(a.VPCAttachment)
+	if err != nil {
+		return 0, err
+	}
+	table, err := vrf.GetVRFIdForVPC(vpc, vpcAttachment)
+	return int(table), err
+}
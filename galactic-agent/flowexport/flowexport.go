@@ -0,0 +1,176 @@
+// Package flowexport samples the traffic of VPC attachments with netfilter
+// and exports it as IPFIX flow records, annotated with the VPC and
+// attachment each flow belongs to, to a collector.
+package flowexport
+
+import (
+	"encoding/binary"
+	"errors"
+	"net/netip"
+	"time"
+)
+
+// Direction is that of a flow relative to the attachment, as IPFIX
+// flowDirection has it at the host interface.
+type Direction uint8
+
+const (
+	// Sent flows enter the host from the attachment, into the VPC.
+	Sent Direction = 0
+	// Received flows leave the host to the attachment.
+	Received Direction = 1
+)
+
+// Attachment is what a flow is annotated with.
+type Attachment struct {
+	VPC           string
+	VPCAttachment string
+	Table         uint32
+}
+
+// Key identifies a flow.
+type Key struct {
+	Attachment
+	Direction Direction
+	Src, Dst  netip.Addr
+	Protocol  uint8
+	// SrcPort and DstPort are the transport ports; for ICMP DstPort is
+	// type << 8 | code, as NetFlow has it.
+	SrcPort, DstPort uint16
+}
+
+// Flow is the sampled traffic of a key, scaled by the sampling rate.
+type Flow struct {
+	Key
+	Packets, Octets uint64
+	Start, End      time.Time
+}
+
+var (
+	ErrNotIP = errors.New("not an IP packet")
+	ErrFull  = errors.New("flow cache full")
+)
+
+// Cache accumulates samples into flows until drained. Samples of new
+// flows beyond Max, if set, are refused.
+type Cache struct {
+	Rate uint32
+	Max  int
+
+	flows map[Key]*Flow
+}
+
+// Add accounts a sampled packet of a, seen at t.
+func (c *Cache) Add(a Attachment, dir Direction, packet []byte, t time.Time) error {
+	k, length, ok := parse(packet)
+	if !ok {
+		return ErrNotIP
+	}
+	k.Attachment = a
+	k.Direction = dir
+	if c.flows == nil {
+		c.flows = make(map[Key]*Flow)
+	}
+	f, ok := c.flows[k]
+	if !ok {
+		if c.Max > 0 && len(c.flows) >= c.Max {
+			return ErrFull
+		}
+		f = &Flow{Key: k, Start: t}
+		c.flows[k] = f
+	}
+	rate := uint64(max(c.Rate, 1))
+	f.Packets += rate
+	f.Octets += uint64(length) * rate
+	f.End = t
+	return nil
+}
+
+// Drain returns the flows accumulated since the last drain.
+func (c *Cache) Drain() []Flow {
+	flows := make([]Flow, 0, len(c.flows))
+	for _, f := range c.flows {
+		flows = append(flows, *f)
+	}
+	c.flows = nil
+	return flows
+}
+
+const (
+	protoICMP   = 1
+	protoTCP    = 6
+	protoUDP    = 17
+	protoSCTP   = 132
+	protoICMPv6 = 58
+)
+
+// parse reads the flow key of an IP packet and its length, from the IP
+// header rather than the packet, which may be truncated.
+func parse(p []byte) (Key, int, bool) {
+	var k Key
+	if len(p) < 1 {
+		return k, 0, false
+	}
+	var length int
+	var l4 []byte
+	switch p[0] >> 4 {
+	case 4:
+		ihl := int(p[0]&0xf) * 4
+		if len(p) < 20 || ihl < 20 {
+			return k, 0, false
+		}
+		length = int(binary.BigEndian.Uint16(p[2:]))
+		k.Protocol = p[9]
+		k.Src = netip.AddrFrom4([4]byte(p[12:16]))
+		k.Dst = netip.AddrFrom4([4]byte(p[16:20]))
+		// only the first fragment has the transport header
+		if binary.BigEndian.Uint16(p[6:])&0x1fff == 0 && len(p) > ihl {
+			l4 = p[ihl:]
+		}
+	case 6:
+		if len(p) < 40 {
+			return k, 0, false
+		}
+		length = 40 + int(binary.BigEndian.Uint16(p[4:]))
+		k.Src = netip.AddrFrom16([16]byte(p[8:24]))
+		k.Dst = netip.AddrFrom16([16]byte(p[24:40]))
+		next, rest := p[6], p[40:]
+		// skip the extension headers
+	headers:
+		for len(rest) >= 8 {
+			switch next {
+			case 0, 43, 60: // hop-by-hop, routing, destination options
+				n := 8 + int(rest[1])*8
+				if len(rest) < n {
+					rest = nil
+					break headers
+				}
+				next, rest = rest[0], rest[n:]
+			case 44: // fragment
+				if binary.BigEndian.Uint16(rest[2:])&0xfff8 != 0 {
+					next, rest = rest[0], nil
+					break headers
+				}
+				next, rest = rest[0], rest[8:]
+			default:
+				break headers
+			}
+		}
+		k.Protocol = next
+		l4 = rest
+	default:
+		return k, 0, false
+	}
+	switch k.Protocol {
+	case protoTCP, protoUDP, protoSCTP:
+		if len(l4) >= 4 {
+			k.SrcPort = binary.BigEndian.Uint16(l4)
+			k.DstPort = binary.BigEndian.Uint16(l4[2:])
+		}
+	case protoICMP, protoICMPv6:
+		if len(l4) >= 2 {
+			k.DstPort = uint16(l4[0])<<8 | uint16(l4[1])
+		}
+	}
+	return k, length, true
+}
@@ -0,0 +1,186 @@
+package flowexport
+
+import (
+	"encoding/binary"
+	"net"
+	"sort"
+	"time"
+)
+
+// IPFIX information elements, see the IANA registry
+const (
+	ieOctetDeltaCount          = 1
+	iePacketDeltaCount         = 2
+	ieProtocolIdentifier       = 4
+	ieSourceTransportPort      = 7
+	ieSourceIPv4Address        = 8
+	ieDestinationTransportPort = 11
+	ieDestinationIPv4Address   = 12
+	ieSourceIPv6Address        = 27
+	ieDestinationIPv6Address   = 28
+	ieFlowDirection            = 61
+	ieFlowStartMilliseconds    = 152
+	ieFlowEndMilliseconds      = 153
+	ieIngressVRFID             = 234
+	ieVRFname                  = 236
+
+	variableLength = 0xffff
+
+	templateSetID = 2
+	templateIPv4  = 256
+	templateIPv6  = 257
+
+	// maxMessage keeps messages in one packet over a 1500 byte underlay
+	maxMessage = 1400
+)
+
+type field struct{ id, length uint16 }
+
+func template(id uint16, addrLength uint16) []byte {
+	src, dst := uint16(ieSourceIPv4Address), uint16(ieDestinationIPv4Address)
+	if addrLength == net.IPv6len {
+		src, dst = ieSourceIPv6Address, ieDestinationIPv6Address
+	}
+	fields := []field{
+		{src, addrLength},
+		{dst, addrLength},
+		{ieProtocolIdentifier, 1},
+		{ieSourceTransportPort, 2},
+		{ieDestinationTransportPort, 2},
+		{ieFlowDirection, 1},
+		{ieOctetDeltaCount, 8},
+		{iePacketDeltaCount, 8},
+		{ieFlowStartMilliseconds, 8},
+		{ieFlowEndMilliseconds, 8},
+		{ieIngressVRFID, 4},
+		{ieVRFname, variableLength},
+	}
+	b := binary.BigEndian.AppendUint16(nil, id)
+	b = binary.BigEndian.AppendUint16(b, uint16(len(fields)))
+	for _, f := range fields {
+		b = binary.BigEndian.AppendUint16(b, f.id)
+		b = binary.BigEndian.AppendUint16(b, f.length)
+	}
+	return b
+}
+
+// templates is the template set sent at the start of every message, so a
+// collector that restarts or loses a packet learns them again at once.
+var templates = func() []byte {
+	records := append(template(templateIPv4, net.IPv4len), template(templateIPv6, net.IPv6len)...)
+	b := binary.BigEndian.AppendUint16(nil, templateSetID)
+	b = binary.BigEndian.AppendUint16(b, uint16(4+len(records)))
+	return append(b, records...)
+}()
+
+// Exporter sends flows over UDP to an IPFIX Collector, as Observation
+// Domain DomainID. The VRFname of a flow is <vpc>/<vpcattachment> and its
+// ingressVRFID the attachment's VRF table.
+type Exporter struct {
+	Collector string
+	DomainID  uint32
+
+	conn net.Conn
+	seq  uint32 // data records sent
+}
+
+// Export sends flows in as few messages as fit.
+func (e *Exporter) Export(flows []Flow, now time.Time) error {
+	if e.conn == nil {
+		conn, err := net.Dial("udp", e.Collector)
+		if err != nil {
+			return err
+		}
+		e.conn = conn
+	}
+	// IPv4 first, so each message has at most one set per template
+	sort.SliceStable(flows, func(i, j int) bool { return flows[i].Src.Is4() && !flows[j].Src.Is4() })
+
+	var msg []byte
+	var records uint32
+	set, setID := -1, uint16(0)
+	closeSet := func() {
+		if set >= 0 {
+			binary.BigEndian.PutUint16(msg[set+2:], uint16(len(msg)-set))
+			set = -1
+		}
+	}
+	start := func() {
+		msg = binary.BigEndian.AppendUint16(msg[:0], 10) // version
+		msg = binary.BigEndian.AppendUint16(msg, 0)      // length, set by send
+		msg = binary.BigEndian.AppendUint32(msg, uint32(now.Unix()))
+		msg = binary.BigEndian.AppendUint32(msg, e.seq)
+		msg = binary.BigEndian.AppendUint32(msg, e.DomainID)
+		msg = append(msg, templates...)
+		records = 0
+	}
+	send := func() error {
+		closeSet()
+		binary.BigEndian.PutUint16(msg[2:], uint16(len(msg)))
+		if _, err := e.conn.Write(msg); err != nil {
+			return err
+		}
+		e.seq += records
+		return nil
+	}
+
+	start()
+	for _, f := range flows {
+		id := uint16(templateIPv6)
+		if f.Src.Is4() {
+			id = templateIPv4
+		}
+		rec := record(f)
+		need := len(rec)
+		if set < 0 || id != setID {
+			need += 4
+		}
+		if records > 0 && len(msg)+need > maxMessage {
+			if err := send(); err != nil {
+				return err
+			}
+			start()
+		}
+		if set < 0 || id != setID {
+			closeSet()
+			set, setID = len(msg), id
+			msg = binary.BigEndian.AppendUint16(msg, id)
+			msg = binary.BigEndian.AppendUint16(msg, 0) // length, set by closeSet
+		}
+		msg = append(msg, rec...)
+		records++
+	}
+	if records == 0 {
+		return nil
+	}
+	return send()
+}
+
+// record encodes f as a data record of its template.
+func record(f Flow) []byte {
+	b := f.Src.AsSlice()
+	b = append(b, f.Dst.AsSlice()...)
+	b = append(b, f.Protocol)
+	b = binary.BigEndian.AppendUint16(b, f.SrcPort)
+	b = binary.BigEndian.AppendUint16(b, f.DstPort)
+	b = append(b, byte(f.Direction))
+	b = binary.BigEndian.AppendUint64(b, f.Octets)
+	b = binary.BigEndian.AppendUint64(b, f.Packets)
+	b = binary.BigEndian.AppendUint64(b, uint64(f.Start.UnixMilli()))
+	b = binary.BigEndian.AppendUint64(b, uint64(f.End.UnixMilli()))
+	b = binary.BigEndian.AppendUint32(b, f.Table)
+	name := f.VPC + "/" + f.VPCAttachment
+	if len(name) > 254 {
+		name = name[:254]
+	}
+	b = append(b, byte(len(name)))
+	return append(b, name...)
+}
+
+// Close closes the connection to the collector.
+func (e *Exporter) Close() error {
+	if e.conn == nil {
+		return nil
+	}
+	return e.conn.Close()
+}
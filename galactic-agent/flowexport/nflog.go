@@ -0,0 +1,156 @@
+package flowexport
+
+import (
+	"encoding/binary"
+	"fmt"
+	"syscall"
+	"time"
+
+	"github.com/vishvananda/netlink/nl"
+	"golang.org/x/sys/unix"
+)
+
+// nfnetlink_log, see linux/netfilter/nfnetlink_log.h
+const (
+	subsysULOG = 4
+
+	msgPacket = 0
+	msgConfig = 1
+
+	cfgCmd  = 1
+	cfgMode = 2
+
+	cmdBind = 1
+
+	copyPacket = 2
+
+	attrPacketHdr = 1
+	attrInDev     = 4
+	attrOutDev    = 5
+	attrPayload   = 9
+
+	// netfilter hooks
+	hookPreRouting  = 0
+	hookPostRouting = 4
+)
+
+// Sample is a packet netfilter logged as it entered the host on the
+// interface of Index, or left it there: the network header on, up to the
+// snap length.
+type Sample struct {
+	Direction Direction
+	Index     int
+	Payload   []byte
+	Time      time.Time
+}
+
+// Log receives the packets netfilter logs to an nflog group.
+type Log struct {
+	s     *nl.NetlinkSocket
+	group uint16
+}
+
+// Listen binds nflog group, copying snaplen bytes of each packet.
+func Listen(group uint16, snaplen uint32) (*Log, error) {
+	s, err := nl.Subscribe(unix.NETLINK_NETFILTER)
+	if err != nil {
+		return nil, err
+	}
+	l := &Log{s: s, group: group}
+	if err := l.config(nl.NewRtAttr(cfgCmd, []byte{cmdBind})); err != nil {
+		s.Close()
+		return nil, fmt.Errorf("nflog group %d: %w", group, err)
+	}
+	mode := binary.BigEndian.AppendUint32(nil, snaplen)
+	mode = append(mode, copyPacket, 0)
+	if err := l.config(nl.NewRtAttr(cfgMode, mode)); err != nil {
+		s.Close()
+		return nil, fmt.Errorf("nflog group %d: %w", group, err)
+	}
+	// samples come in bursts; better to queue them than to lose them
+	s.SetReceiveBufferSize(4<<20, false) //nolint:errcheck
+	return l, nil
+}
+
+// config sends a config message for the group and waits for its ack.
+func (l *Log) config(attr *nl.RtAttr) error {
+	req := nl.NewNetlinkRequest(subsysULOG<<8|msgConfig, unix.NLM_F_ACK)
+	req.AddData(&nl.Nfgenmsg{NfgenFamily: unix.AF_UNSPEC, Version: nl.NFNETLINK_V0, ResId: nl.Swap16(l.group)})
+	req.AddData(attr)
+	if err := l.s.Send(req); err != nil {
+		return err
+	}
+	for {
+		msgs, _, err := l.s.Receive()
+		if err != nil {
+			return err
+		}
+		for _, m := range msgs {
+			if m.Header.Seq != req.Seq || m.Header.Type != unix.NLMSG_ERROR {
+				continue
+			}
+			if errno := int32(nl.NativeEndian().Uint32(m.Data[0:4])); errno != 0 {
+				return syscall.Errno(-errno)
+			}
+			return nil
+		}
+	}
+}
+
+// Read returns the next samples, blocking until there are some. It fails
+// once Close is called.
+func (l *Log) Read() ([]Sample, error) {
+	msgs, _, err := l.s.Receive()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	samples := make([]Sample, 0, len(msgs))
+	for _, m := range msgs {
+		if m.Header.Type != subsysULOG<<8|msgPacket || len(m.Data) < nl.SizeofNfgenmsg {
+			continue
+		}
+		attrs, err := nl.ParseRouteAttr(m.Data[nl.SizeofNfgenmsg:])
+		if err != nil {
+			continue
+		}
+		hook := -1
+		var in, out int
+		s := Sample{Time: now}
+		for _, a := range attrs {
+			switch a.Attr.Type & nl.NLA_TYPE_MASK {
+			case attrPacketHdr:
+				if len(a.Value) >= 3 {
+					hook = int(a.Value[2])
+				}
+			case attrInDev:
+				if len(a.Value) >= 4 {
+					in = int(binary.BigEndian.Uint32(a.Value))
+				}
+			case attrOutDev:
+				if len(a.Value) >= 4 {
+					out = int(binary.BigEndian.Uint32(a.Value))
+				}
+			case attrPayload:
+				s.Payload = a.Value
+			}
+		}
+		switch hook {
+		case hookPreRouting:
+			s.Direction, s.Index = Sent, in
+		case hookPostRouting:
+			s.Direction, s.Index = Received, out
+		default:
+			continue
+		}
+		if s.Payload != nil {
+			samples = append(samples, s)
+		}
+	}
+	return samples, nil
+}
+
+// Close stops Read. The kernel unbinds the group with the socket.
+func (l *Log) Close() {
+	l.s.Close()
+}
@@ -0,0 +1,77 @@
+package flowexport
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Table is the nftables table sampling the traffic of host interfaces.
+const Table = "galactic-flows"
+
+// Rules samples one in Rate packets received or sent on the host
+// interfaces given to Apply, logging Snaplen bytes of each to nflog Group.
+// Command is how to start nft, such as ["nft"] or
+// ["nsenter", "-t", "1", "-n", "nft"].
+type Rules struct {
+	Command []string
+	Group   uint16
+	Rate    uint32
+	Snaplen uint32
+	Timeout time.Duration
+}
+
+// Apply replaces the table with one sampling interfaces, atomically.
+func (r *Rules) Apply(ctx context.Context, interfaces []string) error {
+	var b strings.Builder
+	// declaring the table first lets the delete succeed on the first apply
+	fmt.Fprintf(&b, "table inet %s\ndelete table inet %s\n", Table, Table)
+	fmt.Fprintf(&b, "table inet %s {\n", Table)
+	b.WriteString("\tset hosts {\n\t\ttype ifname\n")
+	if len(interfaces) > 0 {
+		quoted := make([]string, len(interfaces))
+		for i, name := range interfaces {
+			quoted[i] = `"` + name + `"`
+		}
+		slices.Sort(quoted)
+		fmt.Fprintf(&b, "\t\telements = { %s }\n", strings.Join(quoted, ", "))
+	}
+	b.WriteString("\t}\n")
+	sample := fmt.Sprintf("log group %d snaplen %d", r.Group, r.Snaplen)
+	if r.Rate > 1 {
+		sample = fmt.Sprintf("numgen random mod %d == 0 %s", r.Rate, sample)
+	}
+	// before conntrack and NAT on the way in, after them on the way out,
+	// so the addresses are those of the attachment
+	fmt.Fprintf(&b, "\tchain prerouting {\n\t\ttype filter hook prerouting priority -300; policy accept;\n\t\tiifname @hosts %s\n\t}\n", sample)
+	fmt.Fprintf(&b, "\tchain postrouting {\n\t\ttype filter hook postrouting priority 300; policy accept;\n\t\toifname @hosts %s\n\t}\n", sample)
+	b.WriteString("}\n")
+	return r.run(ctx, b.String())
+}
+
+// Remove deletes the table.
+func (r *Rules) Remove(ctx context.Context) error {
+	return r.run(ctx, fmt.Sprintf("table inet %s\ndelete table inet %s\n", Table, Table))
+}
+
+func (r *Rules) run(ctx context.Context, script string) error {
+	timeout := r.Timeout
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, r.Command[0], append(slices.Clone(r.Command[1:]), "-f", "-")...)
+	cmd.Stdin = strings.NewReader(script)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("nft: %w: %s", err, bytes.TrimSpace(out.Bytes()))
+	}
+	return nil
+}
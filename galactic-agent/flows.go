@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"slices"
+	"time"
+
+	"github.com/spf13/viper"
+	"golang.org/x/sys/unix"
+
+	"github.com/datum-cloud/galactic-agent/flowexport"
+	"github.com/datum-cloud/galactic-agent/metrics"
+	"github.com/datum-cloud/galactic-agent/srv6/linkcache"
+)
+
+// flowSnaplen is enough of a packet for its IP and transport headers.
+const flowSnaplen = 128
+
+var (
+	flowSamples = metrics.NewCounter(
+		"galactic_agent_flow_samples_total",
+		"Packets sampled for flow export, by result: accounted to a flow, unknown (not IP, or of no attachment) or dropped (flow cache full or nflog overrun).",
+		"result",
+	)
+	flowRecords = metrics.NewCounter(
+		"galactic_agent_flow_records_exported_total",
+		"IPFIX flow records sent to the collector.",
+	)
+)
+
+// flows samples the traffic of attachments and exports it as IPFIX; nil
+// unless flow_export_collector is set.
+var flows *flowHost
+
+type flowHost struct {
+	rules    *flowexport.Rules
+	nflog    *flowexport.Log
+	exporter *flowexport.Exporter
+	interval time.Duration
+	maxFlows int
+	wake     chan struct{}
+}
+
+func loadFlowExport() (*flowHost, error) {
+	collector := viper.GetString("flow_export_collector")
+	if collector == "" {
+		return nil, nil
+	}
+	if !kernelDatapath() {
+		return nil, errors.New("flow_export_collector needs the kernel datapath")
+	}
+	if _, _, err := net.SplitHostPort(collector); err != nil {
+		return nil, fmt.Errorf("flow_export_collector: %w", err)
+	}
+	rate := viper.GetInt("flow_export_sample_rate")
+	if rate <= 0 {
+		return nil, errors.New("flow_export_sample_rate must be positive")
+	}
+	group := viper.GetInt("flow_export_nflog_group")
+	if group < 0 || group > 0xffff {
+		return nil, fmt.Errorf("flow_export_nflog_group %d out of range 0-65535", group)
+	}
+	nft := viper.GetStringSlice("flow_export_nft")
+	if len(nft) == 0 {
+		return nil, errors.New("flow_export_nft is empty")
+	}
+	interval := viper.GetDuration("flow_export_interval")
+	if interval <= 0 {
+		return nil, errors.New("flow_export_interval must be positive")
+	}
+	nflog, err := flowexport.Listen(uint16(group), flowSnaplen)
+	if err != nil {
+		return nil, err
+	}
+	return &flowHost{
+		rules: &flowexport.Rules{
+			Command: nft,
+			Group:   uint16(group),
+			Rate:    uint32(rate),
+			Snaplen: flowSnaplen,
+		},
+		nflog:    nflog,
+		exporter: &flowexport.Exporter{Collector: collector, DomainID: viper.GetUint32("flow_export_domain_id")},
+		interval: interval,
+		maxFlows: viper.GetInt("flow_export_max_flows"),
+		wake:     make(chan struct{}, 1),
+	}, nil
+}
+
+// notify asks run to sample the current attachments; it never blocks, so
+// stores may call it with their lock held.
+func (f *flowHost) notify() {
+	select {
+	case f.wake <- struct{}{}:
+	default:
+	}
+}
+
+// attachments indexes the registered attachments by the index of their
+// host interface, and lists the names of those interfaces.
+func (f *flowHost) attachments() (map[int]flowexport.Attachment, []string) {
+	byIndex := make(map[int]flowexport.Attachment)
+	var hosts []string
+	for _, t := range tenantMap.All() {
+		for _, a := range domains[t].store.Snapshot().Attachments {
+			link, err := linkcache.Links.LinkByName(a.Host)
+			if err != nil {
+				continue
+			}
+			byIndex[link.Attrs().Index] = flowexport.Attachment{VPC: a.VPC, VPCAttachment: a.VPCAttachment, Table: uint32(a.Table)}
+			hosts = append(hosts, a.Host)
+		}
+	}
+	slices.Sort(hosts)
+	return byIndex, hosts
+}
+
+// read passes the samples logged to out until the log is closed.
+func (f *flowHost) read(ctx context.Context, out chan<- []flowexport.Sample) {
+	defer close(out)
+	for {
+		samples, err := f.nflog.Read()
+		if errors.Is(err, unix.ENOBUFS) {
+			// the kernel dropped samples it could not queue
+			flowSamples.Inc("dropped")
+			continue
+		}
+		if err != nil {
+			if ctx.Err() == nil {
+				slog.Error("Flow export", "err", err)
+			}
+			return
+		}
+		select {
+		case out <- samples:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// run samples the host interfaces of the attachments as they come and go,
+// and exports the flows seen every interval, until ctx is done. It removes
+// the sampling rules on the way out.
+func (f *flowHost) run(ctx context.Context) error {
+	samples := make(chan []flowexport.Sample, 64)
+	go f.read(ctx, samples)
+	defer f.exporter.Close() //nolint:errcheck
+	defer f.nflog.Close()
+	defer func() {
+		if err := f.rules.Remove(context.Background()); err != nil {
+			slog.Error("Flow export", "err", err)
+		}
+	}()
+
+	cache := &flowexport.Cache{Rate: f.rules.Rate, Max: f.maxFlows}
+	var (
+		byIndex map[int]flowexport.Attachment
+		hosts   []string
+		applied []string
+		dirty   = true // hosts differ from those applied
+	)
+	ticker := time.NewTicker(f.interval)
+	defer ticker.Stop()
+	f.notify()
+	for {
+		select {
+		case <-ctx.Done():
+			f.export(cache, time.Now())
+			return nil
+		case <-f.wake:
+			byIndex, hosts = f.attachments()
+			dirty = dirty || !slices.Equal(hosts, applied)
+		case batch, ok := <-samples:
+			if !ok {
+				return errors.New("flow export: nflog closed")
+			}
+			for _, s := range batch {
+				a, ok := byIndex[s.Index]
+				if !ok {
+					flowSamples.Inc("unknown")
+					continue
+				}
+				switch err := cache.Add(a, s.Direction, s.Payload, s.Time); {
+				case err == nil:
+					flowSamples.Inc("accounted")
+				case errors.Is(err, flowexport.ErrFull):
+					flowSamples.Inc("dropped")
+				default:
+					flowSamples.Inc("unknown")
+				}
+			}
+			continue
+		case now := <-ticker.C:
+			f.export(cache, now)
+		}
+		// applied after samples were drained, and retried every tick
+		if dirty {
+			if err := f.rules.Apply(ctx, hosts); err != nil {
+				slog.Error("Flow export", "err", err)
+				continue
+			}
+			applied, dirty = hosts, false
+		}
+	}
+}
+
+func (f *flowHost) export(cache *flowexport.Cache, now time.Time) {
+	records := cache.Drain()
+	if len(records) == 0 {
+		return
+	}
+	if err := f.exporter.Export(records, now); err != nil {
+		slog.Warn("Flow export", "collector", f.exporter.Collector, "err", err)
+		return
+	}
+	flowRecords.Add(float64(len(records)))
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"reflect"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/datum-cloud/galactic-agent/frr"
+)
+
+// routing mirrors the agent's prefixes into a local FRR and imports
+// routes from it; nil unless frr_prefix_lists or frr_import is set.
+var routing *frrHost
+
+type frrHost struct {
+	vtysh *frr.Vtysh
+	// owner starts the names of the prefix lists the agent keeps; empty
+	// mirrors nothing.
+	owner     string
+	imports   []*net.IPNet
+	protocols []string
+	interval  time.Duration
+	wake      chan struct{}
+
+	mu       sync.Mutex
+	imported []frr.Route
+}
+
+func loadFRR() (*frrHost, error) {
+	owner := viper.GetString("frr_prefix_lists")
+	importPrefixes := viper.GetStringSlice("frr_import")
+	if owner == "" && len(importPrefixes) == 0 {
+		return nil, nil
+	}
+	command := viper.GetStringSlice("frr_vtysh")
+	if len(command) == 0 {
+		return nil, errors.New("frr_vtysh is empty")
+	}
+	f := &frrHost{
+		vtysh:     &frr.Vtysh{Command: command},
+		owner:     owner,
+		protocols: viper.GetStringSlice("frr_import_protocols"),
+		interval:  viper.GetDuration("frr_sync_interval"),
+		wake:      make(chan struct{}, 1),
+	}
+	for _, p := range importPrefixes {
+		_, n, err := net.ParseCIDR(p)
+		if err != nil {
+			return nil, fmt.Errorf("frr_import: %w", err)
+		}
+		f.imports = append(f.imports, n)
+	}
+	if f.interval <= 0 {
+		return nil, errors.New("frr_sync_interval must be positive")
+	}
+	return f, nil
+}
+
+// notify asks run to resync; it never blocks, so stores may call it with
+// their lock held.
+func (f *frrHost) notify() {
+	select {
+	case f.wake <- struct{}{}:
+	default:
+	}
+}
+
+// prefixLists are the lists the agent keeps in FRR: <owner>-INGRESS with
+// the SRv6 endpoints the host terminates, and <owner>-EGRESS-<vrf> with
+// the networks routed out of each VRF.
+func (f *frrHost) prefixLists() []frr.PrefixList {
+	ingress := frr.PrefixList{Name: f.owner + "-INGRESS"}
+	var lists []frr.PrefixList
+	for _, t := range tenantMap.All() {
+		st := domains[t].store.Snapshot()
+		for _, ep := range st.Ingress {
+			ingress.Prefixes = append(ingress.Prefixes, ep+"/128")
+		}
+		vrfs := make(map[string]string, len(st.Attachments))
+		for _, a := range st.Attachments {
+			vrfs[a.Endpoint] = a.VRF
+		}
+		egress := make(map[string]*frr.PrefixList)
+		for _, e := range st.Egress {
+			vrf, ok := vrfs[e.Endpoint]
+			if !ok {
+				continue
+			}
+			l := egress[vrf]
+			if l == nil {
+				l = &frr.PrefixList{Name: f.owner + "-EGRESS-" + vrf}
+				egress[vrf] = l
+			}
+			l.Prefixes = append(l.Prefixes, e.Network)
+		}
+		for _, l := range egress {
+			lists = append(lists, *l)
+		}
+	}
+	sort.Slice(lists, func(i, j int) bool { return lists[i].Name < lists[j].Name })
+	return append([]frr.PrefixList{ingress}, lists...)
+}
+
+// vrfs are the VRFs routes are imported from: the default one and those
+// of the registered attachments.
+func (f *frrHost) vrfs() []string {
+	vrfs := []string{"default"}
+	for _, t := range tenantMap.All() {
+		for _, a := range domains[t].store.Snapshot().Attachments {
+			if !slices.Contains(vrfs, a.VRF) {
+				vrfs = append(vrfs, a.VRF)
+			}
+		}
+	}
+	return vrfs
+}
+
+func (f *frrHost) selected(r frr.Route) bool {
+	if !slices.Contains(f.protocols, r.Protocol) {
+		return false
+	}
+	_, n, err := net.ParseCIDR(r.Prefix)
+	if err != nil {
+		return false
+	}
+	ones, _ := n.Mask.Size()
+	for _, i := range f.imports {
+		if inOnes, _ := i.Mask.Size(); i.Contains(n.IP) && ones >= inOnes {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *frrHost) sync(ctx context.Context) {
+	if f.owner != "" {
+		if err := f.vtysh.SyncPrefixLists(ctx, f.owner+"-", f.prefixLists()); err != nil {
+			slog.Warn("FRR prefix lists", "err", err)
+		}
+	}
+	if len(f.imports) == 0 {
+		return
+	}
+	var imported []frr.Route
+	for _, vrf := range f.vrfs() {
+		routes, err := f.vtysh.Routes(ctx, vrf)
+		if err != nil {
+			slog.Warn("FRR routes", "vrf", vrf, "err", err)
+			continue
+		}
+		for _, r := range routes {
+			if f.selected(r) {
+				imported = append(imported, r)
+			}
+		}
+	}
+	f.mu.Lock()
+	changed := !reflect.DeepEqual(imported, f.imported)
+	f.imported = imported
+	f.mu.Unlock()
+	if !changed {
+		return
+	}
+	slog.Info("FRR routes imported", "routes", len(imported))
+	if telemetry != nil {
+		telemetry.Notify()
+	}
+}
+
+// routes returns the routes last imported.
+func (f *frrHost) routes() []frr.Route {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.imported)
+}
+
+// run syncs whenever the registry changes, and every interval for the
+// changes FRR makes.
+func (f *frrHost) run(ctx context.Context) error {
+	ticker := time.NewTicker(f.interval)
+	defer ticker.Stop()
+	f.notify()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-f.wake:
+		case <-ticker.C:
+		}
+		f.sync(ctx)
+	}
+}
+
+// nexthopStrings renders the next hops of an imported route as its YANG
+// leaf-list holds them: the address, or the interface of direct ones.
+func nexthopStrings(r frr.Route) (nexthops, segments []string) {
+	for _, nh := range r.Nexthops {
+		switch {
+		case nh.IP != "" && nh.Interface != "":
+			nexthops = append(nexthops, nh.IP+"%"+nh.Interface)
+		case nh.IP != "":
+			nexthops = append(nexthops, nh.IP)
+		default:
+			nexthops = append(nexthops, nh.Interface)
+		}
+		if segments == nil {
+			segments = nh.Segments
+		}
+	}
+	return nexthops, segments
+}
@@ -0,0 +1,210 @@
+// Package frr keeps a local FRR instance and the agent consistent through
+// vtysh: it maintains prefix lists holding the prefixes the agent
+// programs, for FRR's route-maps to match, and reads FRR's RIB.
+package frr
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os/exec"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Vtysh runs FRR's shell. Command is how to start it, such as
+// ["vtysh"] or ["ip", "netns", "exec", "frr", "vtysh"].
+type Vtysh struct {
+	Command []string
+	Timeout time.Duration
+}
+
+const defaultTimeout = 30 * time.Second
+
+func (v *Vtysh) run(ctx context.Context, lines ...string) ([]byte, error) {
+	timeout := v.Timeout
+	if timeout == 0 {
+		timeout = defaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	args := slices.Clone(v.Command[1:])
+	for _, l := range lines {
+		args = append(args, "-c", l)
+	}
+	cmd := exec.CommandContext(ctx, v.Command[0], args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("vtysh: %w: %s", err, bytes.TrimSpace(append(stderr.Bytes(), out...)))
+	}
+	// older vtysh exit 0 on errors, which they print prefixed with %
+	for _, line := range strings.Split(string(out), "\n") {
+		if strings.HasPrefix(line, "% ") {
+			return nil, fmt.Errorf("vtysh: %s", line)
+		}
+	}
+	return out, nil
+}
+
+// Configure applies lines in configuration mode.
+func (v *Vtysh) Configure(ctx context.Context, lines []string) error {
+	_, err := v.run(ctx, append(append([]string{"configure terminal"}, lines...), "end")...)
+	return err
+}
+
+// PrefixList is a prefix list permitting exactly Prefixes, IPv4 ones in
+// the ip prefix list and IPv6 ones in the ipv6 prefix list of Name.
+type PrefixList struct {
+	Name     string
+	Prefixes []string
+}
+
+var prefixListLine = regexp.MustCompile(`^(ip|ipv6) prefix-list (\S+) seq (\d+) (permit|deny) (\S+)(.*)$`)
+
+type entry struct {
+	afi    string
+	name   string
+	seq    int
+	prefix string
+	exact  bool // a plain permit of prefix
+}
+
+// SyncPrefixLists makes the prefix lists whose names start with owner
+// the lists given, which should all start with it: entries the lists do
+// not hold are removed, and lists not given are emptied, which in FRR
+// removes them.
+func (v *Vtysh) SyncPrefixLists(ctx context.Context, owner string, lists []PrefixList) error {
+	out, err := v.run(ctx, "show running-config")
+	if err != nil {
+		return err
+	}
+	have := make(map[string][]entry) // by afi and name
+	s := bufio.NewScanner(bytes.NewReader(out))
+	for s.Scan() {
+		m := prefixListLine.FindStringSubmatch(strings.TrimSpace(s.Text()))
+		if m == nil || !strings.HasPrefix(m[2], owner) {
+			continue
+		}
+		seq, _ := strconv.Atoi(m[3])
+		key := m[1] + " " + m[2]
+		have[key] = append(have[key], entry{afi: m[1], name: m[2], seq: seq, prefix: m[5], exact: m[4] == "permit" && strings.TrimSpace(m[6]) == ""})
+	}
+
+	want := make(map[string][]string)
+	for _, l := range lists {
+		for _, p := range l.Prefixes {
+			_, n, err := net.ParseCIDR(p)
+			if err != nil {
+				return fmt.Errorf("prefix list %s: %w", l.Name, err)
+			}
+			afi := "ipv6"
+			if n.IP.To4() != nil {
+				afi = "ip"
+			}
+			key := afi + " " + l.Name
+			if !slices.Contains(want[key], n.String()) {
+				want[key] = append(want[key], n.String())
+			}
+		}
+	}
+
+	var lines []string
+	for key, entries := range have {
+		for _, e := range entries {
+			if !e.exact || !slices.Contains(want[key], e.prefix) {
+				lines = append(lines, fmt.Sprintf("no %s prefix-list %s seq %d", e.afi, e.name, e.seq))
+			}
+		}
+	}
+	for key, prefixes := range want {
+		for _, p := range prefixes {
+			if !slices.ContainsFunc(have[key], func(e entry) bool { return e.exact && e.prefix == p }) {
+				lines = append(lines, fmt.Sprintf("%s prefix-list %s permit %s", strings.Fields(key)[0], strings.Fields(key)[1], p))
+			}
+		}
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+	// removals first, so that a prefix moving between entries is never
+	// permitted twice
+	sort.SliceStable(lines, func(i, j int) bool {
+		return strings.HasPrefix(lines[i], "no ") && !strings.HasPrefix(lines[j], "no ")
+	})
+	return v.Configure(ctx, lines)
+}
+
+// Route is a route of FRR's RIB.
+type Route struct {
+	VRF      string
+	Prefix   string
+	Protocol string
+	Nexthops []Nexthop
+}
+
+type Nexthop struct {
+	IP        string
+	Interface string
+	// Segments are the SRv6 segments the route encapsulates in, if any.
+	Segments []string
+}
+
+type ribEntry struct {
+	Prefix   string `json:"prefix"`
+	Protocol string `json:"protocol"`
+	Selected bool   `json:"selected"`
+	Nexthops []struct {
+		IP            string `json:"ip"`
+		InterfaceName string `json:"interfaceName"`
+		Active        bool   `json:"active"`
+		Seg6          *struct {
+			Segs string `json:"segs"`
+		} `json:"seg6"`
+	} `json:"nexthops"`
+}
+
+// Routes returns the selected routes of vrf, "default" for the default
+// VRF, of both address families.
+func (v *Vtysh) Routes(ctx context.Context, vrf string) ([]Route, error) {
+	var routes []Route
+	for _, afi := range []string{"ip", "ipv6"} {
+		out, err := v.run(ctx, fmt.Sprintf("show %s route vrf %s json", afi, vrf))
+		if err != nil {
+			return nil, err
+		}
+		var rib map[string][]ribEntry
+		if err := json.Unmarshal(out, &rib); err != nil {
+			return nil, fmt.Errorf("show %s route vrf %s: %w", afi, vrf, err)
+		}
+		for prefix, entries := range rib {
+			for _, e := range entries {
+				if !e.Selected {
+					continue
+				}
+				r := Route{VRF: vrf, Prefix: prefix, Protocol: e.Protocol}
+				for _, nh := range e.Nexthops {
+					if !nh.Active {
+						continue
+					}
+					n := Nexthop{IP: nh.IP, Interface: nh.InterfaceName}
+					if nh.Seg6 != nil {
+						n.Segments = strings.FieldsFunc(nh.Seg6.Segs, func(r rune) bool { return r == ',' || r == '/' || r == ' ' })
+					}
+					r.Nexthops = append(r.Nexthops, n)
+				}
+				routes = append(routes, r)
+			}
+		}
+	}
+	sort.Slice(routes, func(i, j int) bool { return routes[i].Prefix < routes[j].Prefix })
+	return routes, nil
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/viper"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/datum-cloud/galactic-agent/api/gnmi"
+	"github.com/datum-cloud/galactic-agent/ratelimit"
+	"github.com/datum-cloud/galactic-agent/tlsreload"
+	"github.com/datum-cloud/galactic-agent/yang"
+)
+
+// telemetry serves the agent's state over gNMI; nil unless gnmi_addr is
+// set.
+var telemetry *gnmi.Server
+
+// gnmiModel is yang/galactic-agent.yang; only the rate limits can be set.
+var gnmiModel = &gnmi.ModelData{Name: yang.Name, Organization: "Datum", Version: yang.Revision}
+
+// loadGNMI builds the gNMI server from gnmi_addr. It refuses to serve
+// plaintext beyond localhost. The returned watcher, if any, has to run.
+func loadGNMI(limiters map[string]*ratelimit.Limiter) (*gnmi.Server, *tlsreload.Watcher, error) {
+	addr := viper.GetString("gnmi_addr")
+	if addr == "" {
+		return nil, nil, nil
+	}
+	s := &gnmi.Server{
+		Addr:         addr,
+		Model:        gnmiModel,
+		StateHandler: func() ([]gnmi.Leaf, error) { return gnmiLeaves(limiters) },
+		SetHandler:   func(updates []gnmi.Leaf) error { return gnmiSet(limiters, updates) },
+	}
+	certFile := viper.GetString("gnmi_tls_cert_file")
+	if certFile == "" {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, nil, fmt.Errorf("gnmi_addr invalid: %w", err)
+		}
+		if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+			return nil, nil, errors.New("gnmi_tls_cert_file is required unless gnmi_addr is on localhost")
+		}
+		return s, nil, nil
+	}
+	certs, err := tlsreload.New(certFile, viper.GetString("gnmi_tls_key_file"), viper.GetString("gnmi_tls_ca_file"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("gnmi tls: %w", err)
+	}
+	s.TLSConfig = certs.ServerConfig()
+	return s, certs, nil
+}
+
+// typedValue encodes the value of a yang.Leaf.
+func typedValue(v any) *gnmi.TypedValue {
+	switch v := v.(type) {
+	case bool:
+		return &gnmi.TypedValue{Value: &gnmi.TypedValue_BoolVal{BoolVal: v}}
+	case uint32:
+		return &gnmi.TypedValue{Value: &gnmi.TypedValue_UintVal{UintVal: uint64(v)}}
+	case uint64:
+		return &gnmi.TypedValue{Value: &gnmi.TypedValue_UintVal{UintVal: v}}
+	case float64:
+		return &gnmi.TypedValue{Value: &gnmi.TypedValue_DoubleVal{DoubleVal: v}}
+	case []string:
+		a := &gnmi.ScalarArray{}
+		for _, s := range v {
+			a.Element = append(a.Element, typedValue(s))
+		}
+		return &gnmi.TypedValue{Value: &gnmi.TypedValue_LeaflistVal{LeaflistVal: a}}
+	}
+	return &gnmi.TypedValue{Value: &gnmi.TypedValue_StringVal{StringVal: yang.Text(v)}}
+}
+
+// gnmiLeaves serves the agent's tree.
+func gnmiLeaves(limiters map[string]*ratelimit.Limiter) ([]gnmi.Leaf, error) {
+	var leaves []gnmi.Leaf
+	for _, l := range agentLeaves(limiters) {
+		p, err := gnmi.ParsePath(l.Path)
+		if err != nil {
+			return nil, err
+		}
+		leaves = append(leaves, gnmi.Leaf{Path: p, Value: typedValue(l.Value), Config: l.Config})
+	}
+	return leaves, nil
+}
+
+// gnmiSet applies updates through setConfig, mapping its error tags to
+// gRPC codes.
+func gnmiSet(limiters map[string]*ratelimit.Limiter, updates []gnmi.Leaf) error {
+	leaves := make([]yang.Leaf, 0, len(updates))
+	for _, u := range updates {
+		v, err := numberVal(u.Value)
+		if err != nil {
+			return status.Errorf(codes.InvalidArgument, "%s: %v", gnmi.PathString(u.Path), err)
+		}
+		leaves = append(leaves, yang.Leaf{Path: gnmi.PathString(u.Path), Value: v})
+	}
+	err := setConfig(limiters, leaves, "GNMI SET")
+	var e *yang.Error
+	if !errors.As(err, &e) {
+		return err
+	}
+	if e.Tag == yang.TagDataMissing {
+		return status.Error(codes.NotFound, e.Error())
+	}
+	return status.Error(codes.InvalidArgument, e.Error())
+}
+
+// numberVal accepts numbers however a client typed them. JSON_IETF quotes
+// 64 bit integers.
+func numberVal(v *gnmi.TypedValue) (float64, error) {
+	switch x := v.GetValue().(type) {
+	case *gnmi.TypedValue_DoubleVal:
+		return x.DoubleVal, nil
+	case *gnmi.TypedValue_IntVal:
+		return float64(x.IntVal), nil
+	case *gnmi.TypedValue_UintVal:
+		return float64(x.UintVal), nil
+	case *gnmi.TypedValue_JsonVal:
+		return strconv.ParseFloat(strings.Trim(string(x.JsonVal), `"`), 64)
+	case *gnmi.TypedValue_JsonIetfVal:
+		return strconv.ParseFloat(strings.Trim(string(x.JsonIetfVal), `"`), 64)
+	case *gnmi.TypedValue_StringVal:
+		return strconv.ParseFloat(x.StringVal, 64)
+	}
+	return 0, fmt.Errorf("not a number: %v", v)
+}
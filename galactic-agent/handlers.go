@@ -0,0 +1,354 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/datum-cloud/galactic-agent/api/remote"
+	"github.com/datum-cloud/galactic-agent/audit"
+	"github.com/datum-cloud/galactic-agent/dnsreg"
+	"github.com/datum-cloud/galactic-agent/prefixpolicy"
+	"github.com/datum-cloud/galactic-agent/quota"
+	"github.com/datum-cloud/galactic-agent/ratelimit"
+	"github.com/datum-cloud/galactic-agent/replay"
+	"github.com/datum-cloud/galactic-agent/srv6/alias"
+	"github.com/datum-cloud/galactic-agent/srv6/allowlist"
+	"github.com/datum-cloud/galactic-agent/srv6/endpoint"
+	"github.com/datum-cloud/galactic-agent/tracing"
+)
+
+// handlers serve the local API and the envelopes the controllers send,
+// with the settings the agent started with.
+type handlers struct {
+	registerLimit *ratelimit.Limiter
+	routeLimit    *ratelimit.Limiter
+	// duplicates is the duplicate_networks policy
+	duplicates string
+	vpcDSCP    map[string]uint8
+
+	aliases          alias.Table
+	prefixPolicies   prefixpolicy.Policies
+	segmentAllowlist allowlist.List
+	underlayMTU      int
+	routeQuota       quota.Limits
+	batchChunk       int
+
+	// trusted, if set, are the keys received envelopes must be signed
+	// with
+	trusted  remote.Keys
+	replays  *replay.Guard
+	credKey  []byte
+	fipsMode bool
+
+	// envelopes recycles received envelopes and their routes; nothing may
+	// keep either past receive
+	envelopes sync.Pool
+}
+
+// register is the RegisterHandler of the local API.
+func (h *handlers) register(ctx context.Context, vpc, vpcAttachment string, networks []string, anycast bool, name string, generation uint64) (err error) {
+	if err := limitLocal(h.registerLimit, vpc); err != nil {
+		return err
+	}
+	unlock := lockAttachment(vpc, vpcAttachment)
+	defer unlock()
+	if repeat, err := checkGeneration(domainFor(vpc).store, vpc, vpcAttachment, generation, true); repeat || err != nil {
+		if repeat {
+			slog.Debug("REGISTER repeated", "vpc", vpc, "vpcattachment", vpcAttachment, "generation", generation)
+		}
+		return err
+	}
+	// a failed registration is the caller's to retry;
+	// only one a crash interrupted is made again
+	seq := journalBegin(journalRegister, registration{VPC: vpc, VPCAttachment: vpcAttachment, Networks: networks, Anycast: anycast, Name: name})
+	// the envelopes outlive the call; its caller is kept
+	// for the audit
+	spanCtx, span := tracing.Start(withReceived(context.WithoutCancel(ctx), time.Now()), "Register", tracing.Server, "vpc", vpc, "vpcattachment", vpcAttachment, "networks", len(networks), "anycast", anycast)
+	var srv6_endpoint, vrf string
+	defer func() {
+		if err != nil {
+			journalDone(seq)
+		}
+		auditAttachment(spanCtx, audit.ActionRegister, vpc, vpcAttachment, srv6_endpoint, vrf, networks, anycast, err)
+		span.End(err)
+	}()
+	if endpoint.Reserved(vpc, vpcAttachment) {
+		return status.Errorf(codes.InvalidArgument, "vpc %s attachment %s is reserved for probing", vpc, vpcAttachment)
+	}
+	if name != "" {
+		if err := dnsreg.CheckLabel(name); err != nil {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+	d := domainFor(vpc)
+	withdrawals, err := checkDuplicates(d.store, h.duplicates, vpc, vpcAttachment, networks, anycast)
+	if err != nil {
+		if errors.Is(err, endpoint.ErrMalformedID) {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+		return err
+	}
+	_, encode := tracing.Start(spanCtx, "EncodeEndpoint", tracing.Internal, "srv6_net", d.SRv6Net)
+	srv6_endpoint, err = endpoint.Encode(d.SRv6Net, vpc, vpcAttachment)
+	encode.End(err)
+	if err != nil {
+		return err
+	}
+	span.SetAttr("srv6_endpoint", srv6_endpoint)
+	_, ingress := tracing.Start(spanCtx, "IngressAdd", tracing.Internal)
+	err = dp.IngressAdd(srv6_endpoint)
+	endDataplane(ingress, err)
+	if err != nil {
+		return err
+	}
+	observeProgrammed(spanCtx, "register")
+	if err := markAttachment(h.vpcDSCP, vpc, srv6_endpoint); err != nil {
+		return err
+	}
+	if err := d.store.AddIngress(srv6_endpoint); err != nil {
+		slog.Error("State store", "vpc", vpc, "vpcattachment", vpcAttachment, "err", err)
+	}
+	if a, err := describeAttachment(vpc, vpcAttachment, srv6_endpoint, networks); err != nil {
+		slog.Error("Registry", "vpc", vpc, "vpcattachment", vpcAttachment, "err", err)
+	} else {
+		if anycast {
+			a.Anycast = networks
+		}
+		a.Name = name
+		vrf = a.VRF
+		if err := d.store.RegisterAttachment(a); err != nil {
+			slog.Error("State store", "vpc", vpc, "vpcattachment", vpcAttachment, "err", err)
+		}
+		countAttachments(d)
+	}
+	for _, n := range networks {
+		trackNetwork(vpc, n, "attachment:"+srv6_endpoint)
+	}
+	var envelopes []*remote.Envelope
+	for _, n := range networks {
+		slog.Info("REGISTER", "vpc", vpc, "vpcattachment", vpcAttachment, "network", n, "srv6_endpoint", srv6_endpoint, "anycast", anycast)
+		envelopes = append(envelopes, &remote.Envelope{
+			Kind: &remote.Envelope_Register{
+				Register: &remote.Register{
+					Network:      n,
+					Srv6Endpoint: srv6_endpoint,
+					Anycast:      anycast,
+				},
+			},
+		})
+	}
+	if err := sendJournaled(spanCtx, seq, srv6_endpoint, envelopes...); err != nil {
+		return err
+	}
+	setGeneration(d.store, vpc, vpcAttachment, generation)
+	for _, w := range withdrawals {
+		if err := withdraw(d.store, w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deregister is the DeregisterHandler of the local API.
+func (h *handlers) deregister(ctx context.Context, vpc, vpcAttachment string, networks []string, generation uint64) (err error) {
+	if err := limitLocal(h.registerLimit, vpc); err != nil {
+		return err
+	}
+	unlock := lockAttachment(vpc, vpcAttachment)
+	defer unlock()
+	if repeat, err := checkGeneration(domainFor(vpc).store, vpc, vpcAttachment, generation, false); repeat || err != nil {
+		if repeat {
+			slog.Debug("DEREGISTER repeated", "vpc", vpc, "vpcattachment", vpcAttachment, "generation", generation)
+		}
+		return err
+	}
+	seq := journalBegin(journalDeregister, registration{VPC: vpc, VPCAttachment: vpcAttachment, Networks: networks})
+	spanCtx, span := tracing.Start(withReceived(context.WithoutCancel(ctx), time.Now()), "Deregister", tracing.Server, "vpc", vpc, "vpcattachment", vpcAttachment, "networks", len(networks))
+	var srv6_endpoint, vrf string
+	defer func() {
+		if err != nil {
+			journalDone(seq)
+		}
+		auditAttachment(spanCtx, audit.ActionDeregister, vpc, vpcAttachment, srv6_endpoint, vrf, networks, false, err)
+		span.End(err)
+	}()
+	d := domainFor(vpc)
+	srv6_endpoint, err = endpoint.Encode(d.SRv6Net, vpc, vpcAttachment)
+	if err != nil {
+		return err
+	}
+	vrf = auditVRF(d.store, srv6_endpoint)
+	span.SetAttr("srv6_endpoint", srv6_endpoint)
+	_, ingress := tracing.Start(spanCtx, "IngressDel", tracing.Internal)
+	err = dp.IngressDel(srv6_endpoint)
+	endDataplane(ingress, err)
+	if err != nil {
+		return err
+	}
+	observeProgrammed(spanCtx, "deregister")
+	if err := d.store.DelIngress(srv6_endpoint); err != nil {
+		slog.Error("State store", "vpc", vpc, "vpcattachment", vpcAttachment, "err", err)
+	}
+	if vpc, vpcAttachment, err := endpoint.PadHex(vpc, vpcAttachment); err == nil {
+		if err := d.store.DeregisterAttachment(vpc, vpcAttachment, networks); err != nil {
+			slog.Error("State store", "vpc", vpc, "vpcattachment", vpcAttachment, "err", err)
+		}
+		countAttachments(d)
+	}
+	for _, n := range networks {
+		untrackNetwork(vpc, n, "attachment:"+srv6_endpoint)
+	}
+	var envelopes []*remote.Envelope
+	for _, n := range networks {
+		slog.Info("DEREGISTER", "vpc", vpc, "vpcattachment", vpcAttachment, "network", n, "srv6_endpoint", srv6_endpoint)
+		envelopes = append(envelopes, &remote.Envelope{
+			Kind: &remote.Envelope_Deregister{
+				Deregister: &remote.Deregister{
+					Network:      n,
+					Srv6Endpoint: srv6_endpoint,
+				},
+			},
+		})
+	}
+	if err := sendJournaled(spanCtx, seq, srv6_endpoint, envelopes...); err != nil {
+		return err
+	}
+	setGeneration(d.store, vpc, vpcAttachment, generation)
+	return nil
+}
+
+// accept runs the checks of a route d's controller sent, nacking refused
+// ones, and resolves its segments; limit is the rate limiter it takes a
+// token from, nil for none.
+func (h *handlers) accept(d *domain, route *remote.Route, limit *ratelimit.Limiter, pending []*remote.Route) ([]string, error) {
+	if err := checkTenant(d, route.Srv6Endpoint); err != nil {
+		return nil, err
+	}
+	if err := limitRoute(limit, route); err != nil {
+		nack(remote.Nack_RATE_LIMITED, err, route)
+		return nil, err
+	}
+	segments, err := h.aliases.Resolve(route.Srv6Segments)
+	if err != nil {
+		return nil, err
+	}
+	if route.Status != remote.Route_ADD {
+		return segments, nil
+	}
+	if err := checkPrefix(h.prefixPolicies, route); err != nil {
+		nack(remote.Nack_PREFIX_REJECTED, err, route)
+		return nil, err
+	}
+	if err := checkSegments(h.segmentAllowlist, route, segments); err != nil {
+		nack(remote.Nack_SEGMENT_REJECTED, err, route)
+		return nil, err
+	}
+	if err := checkDepth(h.underlayMTU, d.store, route, segments); err != nil {
+		nack(remote.Nack_SEGMENTS_EXCEEDED, err, route)
+		return nil, err
+	}
+	if err := checkQuota(h.routeQuota, d.store, route, pending); err != nil {
+		nack(remote.Nack_QUOTA_EXCEEDED, err, route)
+		return nil, err
+	}
+	if err := checkDSCP(route); err != nil {
+		return nil, err
+	}
+	return segments, nil
+}
+
+// receive handles the envelopes d's controller sends.
+func (h *handlers) receive(d *domain) func(payload []byte) error {
+	return func(payload []byte) (err error) {
+		spanCtx, span := tracing.Start(withReceived(withAuditSource(context.Background(), "mqtt "+d.remote.Settings().TopicRX), time.Now()), "Receive", tracing.Consumer, "tenant", d.Name, "bytes", len(payload))
+		defer func() {
+			if err != nil {
+				publishError(d.Name, "receive", err)
+			}
+			span.End(err)
+		}()
+		envelope := h.envelopes.Get().(*remote.Envelope)
+		defer h.envelopes.Put(envelope)
+		// the routes of a batch are left to decode as they
+		// are applied
+		_, decode := tracing.Start(spanCtx, "Decode", tracing.Internal)
+		batch, err := remote.DecodeEnvelopeInto(envelope, payload)
+		decode.End(err)
+		if err != nil {
+			return err
+		}
+		tenantEnvelopes.Inc(d.Name, "receive")
+		if h.trusted != nil {
+			if batch != nil {
+				_, err = h.trusted.VerifyKeyWire(envelope, payload)
+			} else {
+				_, err = h.trusted.VerifyKey(envelope)
+			}
+			if err != nil {
+				envelopeUnverified.Inc()
+				return fmt.Errorf("unverified envelope: %w", err)
+			}
+		}
+		if err := h.replays.Check(envelope); err != nil {
+			envelopeReplayed.Inc()
+			return err
+		}
+		for _, skew := range remote.CheckSkew(envelope) {
+			slog.Warn("SCHEMA SKEW", "tenant", d.Name, "skew", skew.String(), "local_schema", remote.SchemaVersion)
+			envelopeSkew.Inc(skew.Reason)
+		}
+		switch kind := envelope.Kind.(type) {
+		case *remote.Envelope_Route:
+			slog.Info("ROUTE", append(endpointAttrs(kind.Route.Srv6Endpoint), "status", kind.Route.Status.String(), "network", kind.Route.Network, "srv6_segments", kind.Route.Srv6Segments)...)
+			segments, err := h.accept(d, kind.Route, h.routeLimit, nil)
+			if err != nil {
+				return err
+			}
+			if coalesce != nil {
+				coalesce.add(spanCtx, d, kind.Route, segments)
+				return nil
+			}
+			return applyRoute(spanCtx, d, kind.Route, segments)
+		case *remote.Envelope_RouteBatch:
+			slog.Info("ROUTE BATCH", "tenant", d.Name, "id", kind.RouteBatch.Id, "resync", kind.RouteBatch.Resync)
+			// routes of a batch are not rate limited: a resync
+			// has to go through whole
+			span.SetAttr("kind", "route_batch", "batch", kind.RouteBatch.Id)
+			return applyBatch(spanCtx, d, kind.RouteBatch, batch, h.batchChunk, func(route *remote.Route, pending []*remote.Route) ([]string, error) {
+				return h.accept(d, route, nil, pending)
+			})
+		case *remote.Envelope_SetMtu:
+			slog.Info("SET MTU", append(endpointAttrs(kind.SetMtu.Srv6Endpoint), "mtu", kind.SetMtu.Mtu)...)
+			if err := checkTenant(d, kind.SetMtu.Srv6Endpoint); err != nil {
+				return err
+			}
+			if err := setMTU(d.store, kind.SetMtu); err != nil {
+				return err
+			}
+		case *remote.Envelope_CredentialRotate:
+			// only a trusted controller may hand out broker
+			// credentials
+			if h.trusted == nil || h.credKey == nil {
+				return errors.New("credential rotation needs route_trust_bundle and credential_rotate_key")
+			}
+			sealed := kind.CredentialRotate.Sealed
+			creds, err := remote.OpenCredentials(h.credKey, sealed)
+			if err != nil {
+				return err
+			}
+			slog.Info("CREDENTIAL ROTATE: received", "tenant", d.Name)
+			go rotateCredentials(d.remote, d.CredentialsPath, creds, sealed, h.fipsMode)
+		case *remote.Envelope_Register, *remote.Envelope_Deregister, *remote.Envelope_Nack, *remote.Envelope_Heartbeat, *remote.Envelope_BatchProgress, *remote.Envelope_RouteChanges, *remote.Envelope_AgentHello:
+			slog.Warn("SCHEMA SKEW: unexpected envelope kind on receive topic", "tenant", d.Name, "kind", fmt.Sprintf("%T", kind))
+			envelopeSkew.Inc(skewUnexpectedKind)
+		}
+		return nil
+	}
+}
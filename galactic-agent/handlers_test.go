@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/datum-cloud/galactic-agent/api/remote"
+	"github.com/datum-cloud/galactic-agent/controller"
+	"github.com/datum-cloud/galactic-agent/replay"
+	"github.com/datum-cloud/galactic-agent/srv6/endpoint"
+	"github.com/datum-cloud/galactic-agent/state"
+	"github.com/datum-cloud/galactic-agent/tenant"
+)
+
+// fakeDatapath records the egress routes programmed, as "add" or "del",
+// the network, the endpoint and the segments.
+type fakeDatapath struct {
+	egress chan string
+}
+
+func (fakeDatapath) IngressAdd(string) error { return nil }
+func (fakeDatapath) IngressDel(string) error { return nil }
+
+func (f fakeDatapath) EgressAdd(prefix, srv6Endpoint string, segments []string, _ int) error {
+	f.egress <- fmt.Sprintf("add %s %s %v", prefix, srv6Endpoint, segments)
+	return nil
+}
+
+func (f fakeDatapath) EgressDel(prefix, srv6Endpoint string, segments []string) error {
+	f.egress <- fmt.Sprintf("del %s %s %v", prefix, srv6Endpoint, segments)
+	return nil
+}
+
+func (fakeDatapath) SetMTU(string, int) error            { return nil }
+func (fakeDatapath) SetDSCP(string, string, uint8) error { return nil }
+func (fakeDatapath) ClearDSCP(string, string) error      { return nil }
+
+// TestLoopback runs Register and Deregister through the handlers main wires
+// into the local API, to the reference controller over a Loopback, and
+// checks the routes it sends back are applied.
+func TestLoopback(t *testing.T) {
+	const srv6Net = "fc00:0:0:1::/64"
+	var err error
+	tenantMap, err = tenant.New(tenant.Tenant{SRv6Net: srv6Net}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := state.Open("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	def := tenantMap.All()[0]
+	d := &domain{Tenant: def, store: store, remote: &remote.Remote{TopicRX: "galactic/agent/receive"}}
+	domains = map[*tenant.Tenant]*domain{def: d}
+	fake := fakeDatapath{egress: make(chan string, 16)}
+	dp = fake
+	outbox = newOutbox(16)
+
+	h := &handlers{
+		replays:   &replay.Guard{Window: time.Minute},
+		envelopes: sync.Pool{New: func() any { return &remote.Envelope{} }},
+	}
+	l.RegisterHandler, l.DeregisterHandler = h.register, h.deregister
+
+	agent, ctrl := remote.NewLoopback(16)
+	agent.ReceiveHandler = h.receive(d)
+	r = agent
+	c := &controller.Controller{}
+	c.Connect("agent", ctrl)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan struct{})
+	close(served)
+	var wg sync.WaitGroup
+	for _, run := range []func(context.Context) error{agent.Run, ctrl.Run, c.Run, func(ctx context.Context) error {
+		return outbox.run(ctx, served, time.Second)
+	}} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := run(ctx); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	defer wg.Wait()
+	defer cancel()
+
+	a, err := endpoint.Encode(srv6Net, "1", "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := endpoint.Encode(srv6Net, "1", "2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := func(want ...string) {
+		t.Helper()
+		got := make(map[string]bool)
+		for range want {
+			select {
+			case e := <-fake.egress:
+				got[e] = true
+			case <-time.After(5 * time.Second):
+				t.Fatalf("got %v, want %v", got, want)
+			}
+		}
+		for _, w := range want {
+			if !got[w] {
+				t.Fatalf("got %v, want %v", got, want)
+			}
+		}
+	}
+
+	if err := l.RegisterHandler(ctx, "1", "1", []string{"10.0.1.0/24"}, false, "", 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.RegisterHandler(ctx, "1", "2", []string{"10.0.2.0/24"}, false, "", 0); err != nil {
+		t.Fatal(err)
+	}
+	// each attachment routes the other's network through its endpoint
+	expect(
+		fmt.Sprintf("add 10.0.2.0/24 %s [%s]", a, b),
+		fmt.Sprintf("add 10.0.1.0/24 %s [%s]", b, a),
+	)
+
+	if err := l.DeregisterHandler(ctx, "1", "2", []string{"10.0.2.0/24"}, 0); err != nil {
+		t.Fatal(err)
+	}
+	expect(
+		fmt.Sprintf("del 10.0.2.0/24 %s [%s]", a, b),
+		fmt.Sprintf("del 10.0.1.0/24 %s [%s]", b, a),
+	)
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/datum-cloud/galactic-agent/api/remote"
+	"github.com/datum-cloud/galactic-agent/metrics"
+	"github.com/datum-cloud/galactic-agent/state"
+	"github.com/datum-cloud/galactic-agent/usage"
+)
+
+var (
+	vpcBytes = metrics.NewCounter(
+		"galactic_agent_vpc_bytes_total",
+		"Bytes crossing the host interfaces of attachments, by VPC and direction: sent into the VPC or received from it.",
+		"vpc", "direction",
+	)
+	vpcPackets = metrics.NewCounter(
+		"galactic_agent_vpc_packets_total",
+		"Packets crossing the host interfaces of attachments, by VPC and direction.",
+		"vpc", "direction",
+	)
+	vpcRate = metrics.NewGauge(
+		"galactic_agent_vpc_bytes_per_second",
+		"Bytes per second over the last usage_interval, by VPC and direction.",
+		"vpc", "direction",
+	)
+)
+
+// heartbeat meters the traffic of every VPC each interval, exports it as
+// metrics and reports it to each tenant's controller in a Heartbeat, until
+// ctx is done.
+func heartbeat(ctx context.Context, interval time.Duration) error {
+	meter := &usage.Meter{}
+	// the first sample picks up what interfaces counted before the agent
+	// started; it goes into the totals but not the rates
+	sampleUsage(meter, 0)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	last := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-ticker.C:
+			sampleUsage(meter, now.Sub(last))
+			last = now
+		}
+		totals := meter.Totals()
+		for _, t := range tenantMap.All() {
+			sendHeartbeat(ctx, domains[t], totals)
+		}
+	}
+}
+
+func sampleUsage(meter *usage.Meter, elapsed time.Duration) {
+	var attachments []state.Attachment
+	for _, d := range domains {
+		attachments = append(attachments, d.store.Snapshot().Attachments...)
+	}
+	deltas, err := meter.Sample(attachments)
+	if err != nil {
+		slog.Error("Usage", "err", err)
+		return
+	}
+	for vpc, d := range deltas {
+		vpcBytes.Add(float64(d.SentBytes), vpc, "sent")
+		vpcBytes.Add(float64(d.ReceivedBytes), vpc, "received")
+		vpcPackets.Add(float64(d.SentPackets), vpc, "sent")
+		vpcPackets.Add(float64(d.ReceivedPackets), vpc, "received")
+		if elapsed > 0 {
+			vpcRate.Set(float64(d.SentBytes)/elapsed.Seconds(), vpc, "sent")
+			vpcRate.Set(float64(d.ReceivedBytes)/elapsed.Seconds(), vpc, "received")
+		}
+	}
+	// VPCs whose last attachment went away are idle
+	for vpc := range meter.Totals() {
+		if _, ok := deltas[vpc]; !ok && elapsed > 0 {
+			vpcRate.Set(0, vpc, "sent")
+			vpcRate.Set(0, vpc, "received")
+		}
+	}
+}
+
+// sendHeartbeat reports the usage of d's VPCs to d's controller. Rates are
+// read back from the gauges set by the last sample.
+func sendHeartbeat(ctx context.Context, d *domain, totals map[string]usage.Counters) {
+	hb := &remote.Heartbeat{}
+	for vpc, t := range totals {
+		if tenantMap.For(vpc) != d.Tenant {
+			continue
+		}
+		hb.Usage = append(hb.Usage, &remote.VPCUsage{
+			Vpc:                    vpc,
+			SentBytes:              t.SentBytes,
+			SentPackets:            t.SentPackets,
+			ReceivedBytes:          t.ReceivedBytes,
+			ReceivedPackets:        t.ReceivedPackets,
+			SentBytesPerSecond:     vpcRate.Get(vpc, "sent"),
+			ReceivedBytesPerSecond: vpcRate.Get(vpc, "received"),
+		})
+	}
+	sort.Slice(hb.Usage, func(i, j int) bool { return hb.Usage[i].Vpc < hb.Usage[j].Vpc })
+
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+	tenantEnvelopes.Inc(d.Name, "send")
+	if err := d.remote.SendEnvelope(ctx, &remote.Envelope{Kind: &remote.Envelope_Heartbeat{Heartbeat: hb}}); err != nil {
+		slog.Warn("Send heartbeat", "tenant", d.Name, "err", err)
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/viper"
+
+	"github.com/datum-cloud/galactic-agent/api/remote"
+)
+
+// agentID returns agent_id or, without it, the ID kept in agent_id in
+// state_dir, generated on the first start. Either way it names the agent
+// to controllers across restarts and hostname changes.
+func agentID() (string, error) {
+	if id := viper.GetString("agent_id"); id != "" {
+		return id, nil
+	}
+	path := filepath.Join(viper.GetString("state_dir"), "agent_id")
+	b, err := os.ReadFile(path)
+	if err == nil {
+		return strings.TrimSpace(string(b)), nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return "", err
+	}
+	id := make([]byte, 16)
+	rand.Read(id) //nolint:errcheck
+	s := hex.EncodeToString(id)
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, []byte(s+"\n"), 0o600); err != nil {
+		return "", err
+	}
+	return s, nil
+}
+
+// agentFeatures are the optional capabilities of the agent as configured,
+// for AgentHello. credentialRotate tells whether it accepts
+// CredentialRotate.
+func agentFeatures(credentialRotate bool) []string {
+	features := []string{"route-batch", "set-mtu", "dscp", "anycast"}
+	if credentialRotate {
+		features = append(features, "credential-rotate")
+	}
+	if viper.GetDuration("usage_interval") > 0 {
+		features = append(features, "heartbeat")
+	}
+	if monitor != nil {
+		features = append(features, "route-monitor")
+	}
+	if flows != nil {
+		features = append(features, "flow-export")
+	}
+	if probes != nil {
+		features = append(features, "probes")
+	}
+	return features
+}
+
+// helloSender returns the OnConnect of d's remote: it introduces the agent
+// to d's controller with an AgentHello.
+func helloSender(ctx context.Context, d *domain, id string, features []string) func() {
+	hostname, _ := os.Hostname()
+	return func() {
+		hello := &remote.AgentHello{
+			AgentId:  id,
+			Hostname: hostname,
+			Srv6Net:  d.SRv6Net,
+			Features: features,
+			Schema:   remote.SchemaVersion,
+			Version:  currentBuild().Version,
+		}
+		ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+		defer cancel()
+		tenantEnvelopes.Inc(d.Name, "send")
+		if err := d.remote.SendEnvelope(ctx, &remote.Envelope{Kind: &remote.Envelope_AgentHello{AgentHello: hello}}); err != nil {
+			slog.Error("Send hello", "tenant", d.Name, "err", err)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"golang.org/x/sys/unix"
+
+	"github.com/datum-cloud/galactic-agent/reconcile"
+	"github.com/datum-cloud/galactic-agent/state"
+)
+
+func newHookCmd() *cobra.Command {
+	var check bool
+	cmd := &cobra.Command{
+		Use:   "hook [interface] [action]",
+		Short: "Restore the agent's host state after a host network event",
+		Long: `Restore what the agent needs of the host after a network event: the
+host-wide sysctls, the VRF and host interface of every registered
+attachment, and the ingress and egress routes. Differences are printed
+like routes diff and fixed unless --check is given. The command exits
+non-zero when differences remain.
+
+Network resets, such as WSL's, wipe this state without the agent noticing.
+Run the command from a dispatcher so the host repairs itself. For
+NetworkManager, as /etc/NetworkManager/dispatcher.d/90-galactic:
+
+   #!/bin/sh
+   exec /usr/local/bin/galactic-agent --config /etc/galactic/galactic-agent.yaml hook "$@"
+
+For networkd-dispatcher, the same script in
+/etc/networkd-dispatcher/routable.d/, which passes the interface and state
+as $IFACE and $STATE. Host interfaces removed by the reset can't be
+restored: their workloads have to be reattached.`,
+		Args: cobra.MaximumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			iface, action := os.Getenv("IFACE"), os.Getenv("STATE")
+			if len(args) > 0 {
+				iface = args[0]
+			}
+			if len(args) > 1 {
+				action = args[1]
+			}
+			switch action {
+			case "pre-up", "pre-down":
+				// nothing has changed yet
+				return nil
+			}
+			fmt.Printf("Network event: interface %q, action %q\n", iface, action)
+
+			unlock, err := lockHook()
+			if err != nil {
+				return err
+			}
+			defer unlock()
+
+			key, err := stateKey()
+			if err != nil {
+				return fmt.Errorf("state key: %w", err)
+			}
+			tenants, err := loadTenants()
+			if err != nil {
+				return err
+			}
+			states := make([]state.State, 0, len(tenants.All()))
+			for _, t := range tenants.All() {
+				st, err := state.LoadSealed(t.StatePath, key)
+				if err != nil {
+					return fmt.Errorf("state store of tenant %s: %w", t.Name, err)
+				}
+				states = append(states, st)
+			}
+
+			// routes go into the VRFs, so the host is fixed before they are
+			// compared
+			var host []reconcile.Change
+			for _, st := range states {
+				c, err := reconcile.Host(st)
+				if err != nil {
+					return err
+				}
+				host = append(host, c...)
+			}
+			reconcile.Write(os.Stdout, host)
+			failed := 0
+			if !check {
+				failed += fixChanges(host)
+			}
+			var routes []reconcile.Change
+			for i, t := range tenants.All() {
+				c, err := reconcile.Diff(states[i], t.SRv6Net)
+				if err != nil {
+					return err
+				}
+				routes = append(routes, c...)
+			}
+			reconcile.Write(os.Stdout, routes)
+
+			total := len(host) + len(routes)
+			switch {
+			case total == 0:
+				return nil
+			case check:
+				return fmt.Errorf("%d differences", total)
+			}
+			if failed += fixChanges(routes); failed > 0 {
+				return fmt.Errorf("%d of %d differences could not be fixed", failed, total)
+			}
+			fmt.Printf("Fixed %d differences\n", total)
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&check, "check", false, "only report differences")
+	return cmd
+}
+
+// lockHook serializes hook runs, as dispatchers start one per event and a
+// reset produces several at once.
+func lockHook() (func(), error) {
+	dir := filepath.Dir(viper.GetString("socket_path"))
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(filepath.Join(dir, "hook.lock"), os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	if err := unix.Flock(int(f.Fd()), unix.LOCK_EX); err != nil {
+		f.Close() //nolint:errcheck
+		return nil, err
+	}
+	return func() {
+		f.Close() //nolint:errcheck
+	}, nil
+}
@@ -0,0 +1,215 @@
+// Package identity obtains the agent's X.509 SVID from a SPIFFE Workload
+// API, keeps it current as the API rotates it, and exposes it for MQTT mTLS
+// and for signing envelopes.
+package identity
+
+import (
+	"context"
+	"crypto"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/datum-cloud/galactic-agent/api/remote"
+	"github.com/datum-cloud/galactic-agent/api/spiffe"
+)
+
+// SocketEnv is the standard variable naming the Workload API endpoint.
+const SocketEnv = "SPIFFE_ENDPOINT_SOCKET"
+
+// SVID is one X.509 identity with the trust bundle it chains to.
+type SVID struct {
+	ID          string
+	Certificate tls.Certificate
+	Chain       [][]byte
+	Roots       *x509.CertPool
+}
+
+// Source streams SVIDs from the Workload API at Socket, a unix:// or bare
+// path; empty means $SPIFFE_ENDPOINT_SOCKET.
+type Source struct {
+	Socket string
+	// OnRotate is called after an SVID has been replaced by a new one.
+	OnRotate func()
+
+	mu    sync.Mutex
+	svid  *SVID
+	ready chan struct{}
+	once  sync.Once
+}
+
+func (s *Source) init() {
+	s.once.Do(func() { s.ready = make(chan struct{}) })
+}
+
+// Run keeps the SVID current until ctx is done, reconnecting to the
+// Workload API when the stream ends.
+func (s *Source) Run(ctx context.Context) error {
+	s.init()
+	target := s.Socket
+	if target == "" {
+		target = os.Getenv(SocketEnv)
+	}
+	if target == "" {
+		return fmt.Errorf("no workload API socket configured (set %s)", SocketEnv)
+	}
+	if !strings.HasPrefix(target, "unix:") {
+		target = "unix://" + target
+	}
+	conn, err := grpc.NewClient(target, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return err
+	}
+	defer conn.Close() //nolint:errcheck
+	client := spiffe.NewSpiffeWorkloadAPIClient(conn)
+
+	for {
+		if err := s.watch(ctx, client); err != nil && ctx.Err() == nil {
+			slog.Warn("identity: workload API", "err", err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+func (s *Source) watch(ctx context.Context, client spiffe.SpiffeWorkloadAPIClient) error {
+	// the Workload API rejects calls without this header
+	ctx = metadata.AppendToOutgoingContext(ctx, "workload.spiffe.io", "true")
+	stream, err := client.FetchX509SVID(ctx, &spiffe.X509SVIDRequest{})
+	if err != nil {
+		return err
+	}
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		if len(resp.GetSvids()) == 0 {
+			return errors.New("no SVID issued to this workload")
+		}
+		// the first SVID is the default one
+		svid, err := parse(resp.GetSvids()[0])
+		if err != nil {
+			return err
+		}
+		s.mu.Lock()
+		first := s.svid == nil
+		s.svid = svid
+		s.mu.Unlock()
+		slog.Info("identity: SVID", "id", svid.ID, "not_after", svid.Certificate.Leaf.NotAfter.Format(time.RFC3339))
+		if first {
+			close(s.ready)
+		} else if s.OnRotate != nil {
+			s.OnRotate()
+		}
+	}
+}
+
+func parse(v *spiffe.X509SVID) (*SVID, error) {
+	certs, err := x509.ParseCertificates(v.GetX509Svid())
+	if err != nil || len(certs) == 0 {
+		return nil, fmt.Errorf("SVID %s: invalid certificate chain: %v", v.GetSpiffeId(), err)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(v.GetX509SvidKey())
+	if err != nil {
+		return nil, fmt.Errorf("SVID %s: invalid key: %w", v.GetSpiffeId(), err)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("SVID %s: key %T cannot sign", v.GetSpiffeId(), key)
+	}
+	roots, err := x509.ParseCertificates(v.GetBundle())
+	if err != nil {
+		return nil, fmt.Errorf("SVID %s: invalid bundle: %w", v.GetSpiffeId(), err)
+	}
+	pool := x509.NewCertPool()
+	for _, c := range roots {
+		pool.AddCert(c)
+	}
+	chain := make([][]byte, len(certs))
+	for i, c := range certs {
+		chain[i] = c.Raw
+	}
+	return &SVID{
+		ID:          v.GetSpiffeId(),
+		Certificate: tls.Certificate{Certificate: chain, PrivateKey: signer, Leaf: certs[0]},
+		Chain:       chain,
+		Roots:       pool,
+	}, nil
+}
+
+// Wait blocks until the first SVID has been received.
+func (s *Source) Wait(ctx context.Context) error {
+	s.init()
+	select {
+	case <-s.ready:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// SVID returns the current SVID, or nil before the first one arrived.
+func (s *Source) SVID() *SVID {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.svid
+}
+
+// TLSConfig returns a client configuration presenting the current SVID and
+// trusting the current bundle, both looked up on every handshake so
+// rotations apply to the next reconnect.
+func (s *Source) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			svid := s.SVID()
+			if svid == nil {
+				return nil, errors.New("no SVID yet")
+			}
+			return &svid.Certificate, nil
+		},
+		// verification against the rotating bundle is done below
+		InsecureSkipVerify: true, //nolint:gosec
+		VerifyConnection: func(cs tls.ConnectionState) error {
+			svid := s.SVID()
+			if svid == nil {
+				return errors.New("no trust bundle yet")
+			}
+			if len(cs.PeerCertificates) == 0 {
+				return errors.New("broker presented no certificate")
+			}
+			intermediates := x509.NewCertPool()
+			for _, c := range cs.PeerCertificates[1:] {
+				intermediates.AddCert(c)
+			}
+			_, err := cs.PeerCertificates[0].Verify(x509.VerifyOptions{
+				Roots:         svid.Roots,
+				Intermediates: intermediates,
+			})
+			return err
+		},
+	}
+}
+
+// Sign signs envelope with the current SVID.
+func (s *Source) Sign(envelope *remote.Envelope) error {
+	svid := s.SVID()
+	if svid == nil {
+		return errors.New("no SVID yet")
+	}
+	return remote.Sign(envelope, svid.ID, svid.Chain, svid.Certificate.PrivateKey.(crypto.Signer))
+}
@@ -0,0 +1,166 @@
+// Package ipam hands out vpc attachment ids for small deployments that have
+// no external coordinator. Assignments are persisted per locator, so two
+// agents sharing a locator must not both allocate.
+package ipam
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/datum-cloud/galactic-agent/srv6/endpoint"
+)
+
+var (
+	// ErrExhausted is returned when every attachment id of a VPC is taken.
+	ErrExhausted = errors.New("no free vpc attachment id")
+	ErrNoOwner   = errors.New("owner is required")
+)
+
+// first and last bound the ids handed out; 0 is never allocated so that a
+// zero value is never mistaken for an assignment.
+const (
+	first = 0x0001
+	last  = 0xffff
+)
+
+// file is the on-disk format: locator -> vpc -> vpcattachment -> owner.
+type file struct {
+	Locators map[string]map[string]map[string]string `json:"locators"`
+}
+
+type Allocator struct {
+	path    string
+	locator string
+
+	mu   sync.Mutex
+	data file
+}
+
+// Open loads the allocations at path for locator. An empty path keeps them
+// in memory only.
+func Open(path, locator string) (*Allocator, error) {
+	a := &Allocator{path: path, locator: locator}
+	a.data.Locators = make(map[string]map[string]map[string]string)
+	if path == "" {
+		return a, nil
+	}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return a, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &a.data); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if a.data.Locators == nil {
+		a.data.Locators = make(map[string]map[string]map[string]string)
+	}
+	return a, nil
+}
+
+// vpc returns the assignments of vpc, creating them if needed. The caller
+// holds a.mu.
+func (a *Allocator) vpc(vpc string) map[string]string {
+	vpcs, ok := a.data.Locators[a.locator]
+	if !ok {
+		vpcs = make(map[string]map[string]string)
+		a.data.Locators[a.locator] = vpcs
+	}
+	atts, ok := vpcs[vpc]
+	if !ok {
+		atts = make(map[string]string)
+		vpcs[vpc] = atts
+	}
+	return atts
+}
+
+// Allocate assigns the lowest free attachment id of vpc to owner. An owner
+// that already holds an id gets the same one back, so retried calls do not
+// leak ids.
+func (a *Allocator) Allocate(vpc, owner string) (string, error) {
+	vpc, _, err := endpoint.PadHex(vpc, "0")
+	if err != nil {
+		return "", err
+	}
+	if owner == "" {
+		return "", ErrNoOwner
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	atts := a.vpc(vpc)
+	for id, o := range atts {
+		if o == owner {
+			return id, nil
+		}
+	}
+	for n := first; n <= last; n++ {
+		id := fmt.Sprintf("%04x", n)
+		if _, ok := atts[id]; ok {
+			continue
+		}
+		atts[id] = owner
+		if err := a.save(); err != nil {
+			delete(atts, id)
+			return "", err
+		}
+		return id, nil
+	}
+	return "", ErrExhausted
+}
+
+// Release frees an attachment id. Releasing an id that is not allocated is
+// not an error.
+func (a *Allocator) Release(vpc, vpcAttachment string) error {
+	vpc, vpcAttachment, err := endpoint.PadHex(vpc, vpcAttachment)
+	if err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	atts := a.vpc(vpc)
+	owner, ok := atts[vpcAttachment]
+	if !ok {
+		return nil
+	}
+	delete(atts, vpcAttachment)
+	if err := a.save(); err != nil {
+		atts[vpcAttachment] = owner
+		return err
+	}
+	return nil
+}
+
+// save writes the allocations atomically; the caller holds a.mu.
+func (a *Allocator) save() error {
+	if a.path == "" {
+		return nil
+	}
+	b, err := json.MarshalIndent(a.data, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(a.path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(a.path), filepath.Base(a.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) //nolint:errcheck
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close() //nolint:errcheck
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), a.path)
+}
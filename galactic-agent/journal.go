@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+
+	"github.com/spf13/viper"
+
+	"github.com/datum-cloud/galactic-agent/api/remote"
+	"github.com/datum-cloud/galactic-agent/journal"
+	"github.com/datum-cloud/galactic-agent/srv6/dryrun"
+)
+
+// wal is the write-ahead journal of registrations and received routes,
+// nil without journal_path. A registration is done once its envelopes
+// reached the controller, a route once it is programmed and stored.
+var wal *journal.Journal
+
+// The ops of journal entries.
+const (
+	journalRegister   = "register"
+	journalDeregister = "deregister"
+	journalRoute      = "route"
+)
+
+// registration is the data of register and deregister entries, the
+// arguments of the local API call.
+type registration struct {
+	VPC           string   `json:"vpc"`
+	VPCAttachment string   `json:"vpcattachment"`
+	Networks      []string `json:"networks"`
+	Anycast       bool     `json:"anycast,omitempty"`
+	Name          string   `json:"name,omitempty"`
+}
+
+// journaledRoute is the data of route entries: a route that passed its
+// checks, with its segments resolved.
+type journaledRoute struct {
+	Tenant   string   `json:"tenant"`
+	Status   string   `json:"status"`
+	Network  string   `json:"network"`
+	Endpoint string   `json:"srv6_endpoint"`
+	Segments []string `json:"srv6_segments"`
+	DSCP     uint32   `json:"dscp,omitempty"`
+}
+
+// openJournal opens the journal at journal_path, its entries sealed with
+// key, the state key, if set. In dry run nothing is changed, so nothing is
+// journaled.
+func openJournal(key []byte) error {
+	path := viper.GetString("journal_path")
+	if path == "" || dryrun.Enabled() {
+		return nil
+	}
+	j, err := journal.OpenSealed(path, key)
+	if err != nil {
+		return err
+	}
+	wal = j
+	return nil
+}
+
+// journalBegin records the change op before it is made. It returns 0,
+// which journalDone ignores, without a journal or when the entry could not
+// be written: the change is made all the same, as without a journal.
+func journalBegin(op string, data any) uint64 {
+	if wal == nil {
+		return 0
+	}
+	seq, err := wal.Begin(op, data)
+	if err != nil {
+		slog.Error("Journal", "op", op, "err", err)
+		return 0
+	}
+	return seq
+}
+
+// journalBeginAll is journalBegin for a change op of each of data, synced
+// together, for the bulk paths that would otherwise sync once a change. Its
+// sequence numbers are all 0 when they could not be written.
+func journalBeginAll(op string, data []any) []uint64 {
+	seqs := make([]uint64, len(data))
+	if wal == nil || len(data) == 0 {
+		return seqs
+	}
+	s, err := wal.BeginAll(op, data...)
+	if err != nil {
+		slog.Error("Journal", "op", op, "entries", len(data), "err", err)
+		return seqs
+	}
+	return s
+}
+
+// journalDone marks the change seq complete.
+func journalDone(seq uint64) {
+	if wal == nil || seq == 0 {
+		return
+	}
+	if err := wal.Done(seq); err != nil {
+		slog.Error("Journal", "seq", seq, "err", err)
+	}
+}
+
+// journalDoneAll marks the changes seqs complete.
+func journalDoneAll(seqs []uint64) {
+	for _, seq := range seqs {
+		journalDone(seq)
+	}
+}
+
+// sendJournaled is sendLocalThen for the change seq, which is done once
+// the controller has all of envelopes. The outbox only gives up on one as
+// it is drained; seq then stays pending and is made again on the next
+// start.
+func sendJournaled(ctx context.Context, seq uint64, srv6Endpoint string, envelopes ...*remote.Envelope) error {
+	if len(envelopes) == 0 {
+		journalDone(seq)
+		return nil
+	}
+	var failed bool
+	left := len(envelopes)
+	return sendLocalThen(ctx, srv6Endpoint, func(err error) {
+		failed = failed || err != nil
+		if left--; left == 0 && !failed {
+			journalDone(seq)
+		}
+	}, envelopes...)
+}
+
+// replayJournal makes the changes a crash interrupted again, oldest
+// first, after the startup replay restored the persisted state. Routes are
+// made again under their own entries rather than journaled anew, and
+// saved to the stores at once; registrations go through the local API
+// handlers, which journal them again.
+func replayJournal() {
+	if wal == nil {
+		return
+	}
+	pending := wal.Pending()
+	if len(pending) == 0 {
+		return
+	}
+	slog.Info("Replaying journal", "entries", len(pending))
+	ctx := withAuditSource(context.Background(), "journal")
+	// the routes replayed since the last registration, done once saved
+	var routes []uint64
+	var release func()
+	save := func() {
+		if release != nil {
+			release()
+			journalDoneAll(routes)
+			routes, release = nil, nil
+		}
+	}
+	for _, e := range pending {
+		if e.Op == journalRoute && release == nil {
+			release = holdStores(slices.Collect(maps.Values(domains))...)
+		} else if e.Op != journalRoute {
+			save()
+		}
+		if err := replayEntry(ctx, e); err != nil {
+			slog.Error("Journal replay", "seq", e.Seq, "op", e.Op, "err", err)
+		}
+		if e.Op == journalRoute {
+			routes = append(routes, e.Seq)
+		} else {
+			journalDone(e.Seq)
+		}
+	}
+	save()
+}
+
+func replayEntry(ctx context.Context, e journal.Entry) error {
+	switch e.Op {
+	case journalRegister, journalDeregister:
+		var reg registration
+		if err := json.Unmarshal(e.Data, &reg); err != nil {
+			return err
+		}
+		// replayed without a generation: the store may hold the call's
+		// already, which would make it a repeat sending nothing
+		if e.Op == journalRegister {
+			return l.RegisterHandler(ctx, reg.VPC, reg.VPCAttachment, reg.Networks, reg.Anycast, reg.Name, 0)
+		}
+		return l.DeregisterHandler(ctx, reg.VPC, reg.VPCAttachment, reg.Networks, 0)
+	case journalRoute:
+		var jr journaledRoute
+		if err := json.Unmarshal(e.Data, &jr); err != nil {
+			return err
+		}
+		t := tenantMap.Get(jr.Tenant)
+		if t == nil {
+			return fmt.Errorf("tenant %s is not configured", jr.Tenant)
+		}
+		status, ok := remote.Route_Status_value[jr.Status]
+		if !ok {
+			return fmt.Errorf("route status %s unknown", jr.Status)
+		}
+		route := &remote.Route{
+			Status:       remote.Route_Status(status),
+			Network:      jr.Network,
+			Srv6Endpoint: jr.Endpoint,
+			Srv6Segments: jr.Segments,
+			Dscp:         jr.DSCP,
+		}
+		return programRoute(ctx, domains[t], route, jr.Segments)
+	}
+	return fmt.Errorf("unknown op %q", e.Op)
+}
@@ -0,0 +1,255 @@
+// Package journal is a write-ahead log of the changes the agent is about
+// to make. An entry is appended, and synced, before a change starts and
+// marked done once it is complete; the entries not done when the agent
+// stopped are the changes a crash interrupted, to be made again.
+package journal
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"crypto/cipher"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+)
+
+// compactSize is the size past which the file is rewritten with only the
+// pending entries, once it also doubled since it last was.
+const compactSize = 1 << 20
+
+// Entry is a change: Op names what it is, Data holds its arguments.
+type Entry struct {
+	Seq  uint64          `json:"seq"`
+	Op   string          `json:"op,omitempty"`
+	Data json.RawMessage `json:"data,omitempty"`
+	// Done marks the change of Seq complete, in a line of its own.
+	Done bool `json:"done,omitempty"`
+}
+
+// Journal appends entries as JSON lines to a file, each sealed when it
+// has a key.
+type Journal struct {
+	path string
+	gcm  cipher.AEAD
+
+	mu        sync.Mutex
+	f         *os.File
+	size      int64
+	compactAt int64
+	seq       uint64
+	pending   map[uint64]Entry
+}
+
+// Open opens the journal at path, creating it if need be, and keeps the
+// entries of the file not done for Pending. The file is rewritten with
+// only those. A line cut short by a crash is dropped: its change had not
+// started.
+func Open(path string) (*Journal, error) {
+	return OpenSealed(path, nil)
+}
+
+// OpenSealed is Open for a journal whose entries are encrypted with key,
+// the state key. A nil key writes plain JSON.
+func OpenSealed(path string, key []byte) (*Journal, error) {
+	j := &Journal{path: path, pending: make(map[uint64]Entry)}
+	if key != nil {
+		gcm, err := aead(key)
+		if err != nil {
+			return nil, err
+		}
+		j.gcm = gcm
+	}
+	b, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	for line := range bytes.Lines(b) {
+		plain, err := unseal(j.gcm, bytes.TrimSuffix(line, []byte("\n")))
+		if err != nil {
+			// only the last line may be cut short
+			if errors.Is(err, ErrSealed) || bytes.HasSuffix(line, []byte("\n")) {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(plain, &e); err != nil {
+			continue
+		}
+		j.seq = max(j.seq, e.Seq)
+		if e.Done {
+			delete(j.pending, e.Seq)
+		} else {
+			j.pending[e.Seq] = e
+		}
+	}
+	if err := j.rewrite(); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// rewrite replaces the file with the pending entries and opens it for
+// appending, closing the file it had open.
+func (j *Journal) rewrite() error {
+	tmp, err := os.CreateTemp(filepath.Dir(j.path), filepath.Base(j.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) //nolint:errcheck
+	w := bufio.NewWriter(tmp)
+	for _, e := range j.pendingEntries() {
+		line, err := j.line(e)
+		if err == nil {
+			_, err = w.Write(line)
+		}
+		if err != nil {
+			tmp.Close() //nolint:errcheck
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close() //nolint:errcheck
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close() //nolint:errcheck
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), j.path); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(j.path, os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close() //nolint:errcheck
+		return err
+	}
+	if j.f != nil {
+		j.f.Close() //nolint:errcheck
+	}
+	j.f, j.size = f, info.Size()
+	j.compactAt = max(compactSize, 2*j.size)
+	return nil
+}
+
+// Pending returns the entries not done, oldest first.
+func (j *Journal) Pending() []Entry {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.pendingEntries()
+}
+
+func (j *Journal) pendingEntries() []Entry {
+	entries := make([]Entry, 0, len(j.pending))
+	for _, e := range j.pending {
+		entries = append(entries, e)
+	}
+	slices.SortFunc(entries, func(a, b Entry) int {
+		return cmp.Compare(a.Seq, b.Seq)
+	})
+	return entries
+}
+
+// Begin records the change op with the arguments data, once synced to
+// disk, and returns its sequence number for Done.
+func (j *Journal) Begin(op string, data any) (uint64, error) {
+	seqs, err := j.BeginAll(op, data)
+	if err != nil {
+		return 0, err
+	}
+	return seqs[0], nil
+}
+
+// BeginAll is Begin for a change op of each of data, synced to disk
+// together.
+func (j *Journal) BeginAll(op string, data ...any) ([]uint64, error) {
+	entries := make([]Entry, len(data))
+	for i, d := range data {
+		raw, err := json.Marshal(d)
+		if err != nil {
+			return nil, err
+		}
+		entries[i] = Entry{Op: op, Data: raw}
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	seqs := make([]uint64, len(entries))
+	for i := range entries {
+		j.seq++
+		entries[i].Seq, seqs[i] = j.seq, j.seq
+		if err := j.append(entries[i]); err != nil {
+			return nil, err
+		}
+	}
+	if err := j.f.Sync(); err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		j.pending[e.Seq] = e
+	}
+	return seqs, nil
+}
+
+// Done marks the change seq complete. It is not synced: should it be
+// lost, the change is made again, which changes nothing.
+func (j *Journal) Done(seq uint64) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if _, ok := j.pending[seq]; !ok {
+		return nil
+	}
+	delete(j.pending, seq)
+	if err := j.append(Entry{Seq: seq, Done: true}); err != nil {
+		return err
+	}
+	// entries that stay pending, as of envelopes that failed, must not
+	// keep the file growing
+	if j.size > j.compactAt {
+		return j.rewrite()
+	}
+	return nil
+}
+
+// append writes e as a line; the caller holds j.mu.
+func (j *Journal) append(e Entry) error {
+	line, err := j.line(e)
+	if err != nil {
+		return err
+	}
+	n, err := j.f.Write(line)
+	j.size += int64(n)
+	return err
+}
+
+// line is e as written to the file, sealed if j has a key.
+func (j *Journal) line(e Entry) ([]byte, error) {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+	if j.gcm != nil {
+		if b, err = seal(j.gcm, b); err != nil {
+			return nil, err
+		}
+	}
+	return append(b, '\n'), nil
+}
+
+// Close closes the file.
+func (j *Journal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.f.Close()
+}
@@ -0,0 +1,63 @@
+package journal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestCompactPending checks an entry left pending does not keep the file
+// from being compacted, and survives it.
+func TestCompactPending(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.log")
+	j, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stuck, err := j.Begin("route", map[string]string{"network": "10.0.0.1/32"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := make([]any, 64)
+	for i := range data {
+		data[i] = map[string]string{"network": strings.Repeat("x", 256)}
+	}
+	for range 2 * compactSize / (64 * 300) {
+		seqs, err := j.BeginAll("route", data...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, seq := range seqs {
+			if err := j.Done(seq); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := j.Close(); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() > compactSize {
+		t.Errorf("journal of %d bytes not compacted", info.Size())
+	}
+
+	j, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j.Close() //nolint:errcheck
+	if pending := j.Pending(); len(pending) != 1 || pending[0].Seq != stuck {
+		t.Errorf("pending %+v, want only %d", pending, stuck)
+	}
+	seq, err := j.Begin("route", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seq <= stuck {
+		t.Errorf("sequence number %d reused", seq)
+	}
+}
@@ -0,0 +1,55 @@
+package journal
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// ErrSealed is returned when a journal with encrypted entries is opened
+// without its key.
+var ErrSealed = errors.New("journal is encrypted")
+
+// sealedPrefix starts every encrypted line, followed by the nonce and the
+// sealed JSON in base64. It is also the additional data of the seal.
+var sealedPrefix = []byte("aes256gcm:")
+
+func aead(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("journal key: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+func seal(gcm cipher.AEAD, plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	sealed := gcm.Seal(nonce, nonce, plaintext, sealedPrefix)
+	return base64.StdEncoding.AppendEncode(bytes.Clone(sealedPrefix), sealed), nil
+}
+
+// unseal returns the JSON of line. Plain lines are passed through so that
+// an existing journal can be encrypted by configuring a key.
+func unseal(gcm cipher.AEAD, line []byte) ([]byte, error) {
+	if !bytes.HasPrefix(line, sealedPrefix) {
+		return line, nil
+	}
+	if gcm == nil {
+		return nil, ErrSealed
+	}
+	b, err := base64.StdEncoding.AppendDecode(nil, line[len(sealedPrefix):])
+	if err != nil {
+		return nil, err
+	}
+	if len(b) < gcm.NonceSize() {
+		return nil, errors.New("journal entry is truncated")
+	}
+	return gcm.Open(nil, b[:gcm.NonceSize()], b[gcm.NonceSize():], sealedPrefix)
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/datum-cloud/galactic-agent/metrics"
+)
+
+var programLatency = metrics.NewHistogram(
+	"galactic_agent_programming_seconds",
+	"Time from receiving a Route, or a Register or Deregister call, to its route being programmed in the kernel, by operation: route_add, route_delete, register or deregister. Routes held by route_coalesce_window include the wait.",
+	metrics.LatencyBuckets,
+	"operation",
+)
+
+type receivedKey struct{}
+
+// withReceived returns ctx with when the message or call it handles was
+// received.
+func withReceived(ctx context.Context, t time.Time) context.Context {
+	return context.WithValue(ctx, receivedKey{}, t)
+}
+
+// received returns when the message or call of ctx was received, zero for
+// changes the agent makes of itself, such as the journal replay.
+func received(ctx context.Context) time.Time {
+	t, _ := ctx.Value(receivedKey{}).(time.Time)
+	return t
+}
+
+// observeProgrammed records how long operation took to reach the kernel
+// since the message or call of ctx was received.
+func observeProgrammed(ctx context.Context, operation string) {
+	if t := received(ctx); !t.IsZero() {
+		programLatency.Observe(time.Since(t).Seconds(), operation)
+	}
+}
@@ -0,0 +1,77 @@
+package latency
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Report collects latency samples and error counts per operation. It is safe
+// for concurrent use.
+type Report struct {
+	mu      sync.Mutex
+	samples map[string][]time.Duration
+	errors  map[string]int
+}
+
+func NewReport() *Report {
+	return &Report{samples: make(map[string][]time.Duration), errors: make(map[string]int)}
+}
+
+// Record adds a sample for op, or counts an error if err is set.
+func (r *Report) Record(op string, d time.Duration, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err != nil {
+		r.errors[op]++
+		return
+	}
+	r.samples[op] = append(r.samples[op], d)
+}
+
+// Errors returns the total number of errors recorded.
+func (r *Report) Errors() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := 0
+	for _, c := range r.errors {
+		n += c
+	}
+	return n
+}
+
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(float64(len(sorted)-1) * p)
+	return sorted[i]
+}
+
+// Write prints one line per operation with count, errors and percentiles.
+func (r *Report) Write(w io.Writer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ops := make([]string, 0, len(r.samples))
+	for op := range r.samples {
+		ops = append(ops, op)
+	}
+	for op := range r.errors {
+		if _, ok := r.samples[op]; !ok {
+			ops = append(ops, op)
+		}
+	}
+	sort.Strings(ops)
+	fmt.Fprintf(w, "%-16s %8s %8s %10s %10s %10s %10s\n", "OPERATION", "COUNT", "ERRORS", "P50", "P90", "P99", "MAX") //nolint:errcheck
+	for _, op := range ops {
+		s := append([]time.Duration(nil), r.samples[op]...)
+		sort.Slice(s, func(i, j int) bool { return s[i] < s[j] })
+		fmt.Fprintf(w, "%-16s %8d %8d %10s %10s %10s %10s\n", op, len(s), r.errors[op], //nolint:errcheck
+			percentile(s, 0.5).Round(time.Microsecond),
+			percentile(s, 0.9).Round(time.Microsecond),
+			percentile(s, 0.99).Round(time.Microsecond),
+			percentile(s, 1).Round(time.Microsecond))
+	}
+}
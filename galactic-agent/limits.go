@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/datum-cloud/galactic-agent/api/remote"
+	"github.com/datum-cloud/galactic-agent/audit"
+	"github.com/datum-cloud/galactic-agent/metrics"
+	"github.com/datum-cloud/galactic-agent/prefixpolicy"
+	"github.com/datum-cloud/galactic-agent/quota"
+	"github.com/datum-cloud/galactic-agent/ratelimit"
+	"github.com/datum-cloud/galactic-agent/srv6"
+	"github.com/datum-cloud/galactic-agent/srv6/allowlist"
+	"github.com/datum-cloud/galactic-agent/srv6/depth"
+	"github.com/datum-cloud/galactic-agent/srv6/endpoint"
+	"github.com/datum-cloud/galactic-agent/state"
+	"github.com/datum-cloud/galactic-common/util"
+)
+
+var rateLimited = metrics.NewCounter(
+	"galactic_agent_rate_limited_total",
+	"Control operations refused because their VPC was over its rate limit.",
+	"operation", "vpc",
+)
+
+// limitLocal takes a token for a local API call, turning a refusal into
+// ResourceExhausted so clients back off.
+func limitLocal(l *ratelimit.Limiter, vpc string) error {
+	if padded, _, err := endpoint.PadHex(vpc, "0"); err == nil {
+		vpc = padded
+	}
+	if err := l.Take(vpc); err != nil {
+		rateLimited.Inc(l.Operation, vpc)
+		return status.Error(codes.ResourceExhausted, err.Error())
+	}
+	return nil
+}
+
+// limitRoute takes a token for a received route, keyed by the VPC encoded
+// in its endpoint. Routes whose endpoint doesn't decode share one bucket.
+func limitRoute(l *ratelimit.Limiter, route *remote.Route) error {
+	vpc := endpointVPC(route.Srv6Endpoint)
+	if err := l.Take(vpc); err != nil {
+		rateLimited.Inc(l.Operation, vpc)
+		return err
+	}
+	return nil
+}
+
+var quotaExceeded = metrics.NewCounter(
+	"galactic_agent_quota_exceeded_total",
+	"Routes refused because their attachment reached its route or neighbor proxy quota.",
+	"resource", "vpc",
+)
+
+// checkQuota refuses a route that would take its attachment over limits,
+// counting the routes the coalescer holds still and those of pending,
+// accepted but not applied yet. Replacing an existing route is always
+// allowed.
+func checkQuota(limits quota.Limits, store *state.Store, route *remote.Route, pending []*remote.Route) error {
+	routes, hosts := store.EgressUsage(route.Srv6Endpoint, route.Network)
+	pendingRoutes, pendingHosts := coalesce.usage(store, route.Srv6Endpoint, route.Network)
+	routes, hosts = routes+pendingRoutes, hosts+pendingHosts
+	for _, p := range pending {
+		r, h := pendingUsage(store, route.Srv6Endpoint, route.Network, p)
+		routes, hosts = routes+r, hosts+h
+	}
+	err := limits.Check(route.Srv6Endpoint, routes, hosts, state.IsHost(route.Network))
+	var exceeded *quota.ExceededError
+	if errors.As(err, &exceeded) {
+		quotaExceeded.Inc(exceeded.Resource, endpointVPC(route.Srv6Endpoint))
+	}
+	return err
+}
+
+var prefixRejected = metrics.NewCounter(
+	"galactic_agent_prefix_rejected_total",
+	"Routes refused by the prefix policy of their VPC.",
+	"reason", "vpc",
+)
+
+// checkPrefix applies the prefix policy of the route's VPC.
+func checkPrefix(policies prefixpolicy.Policies, route *remote.Route) error {
+	vpc := endpointVPC(route.Srv6Endpoint)
+	err := policies.For(vpc).Check(route.Network)
+	var rejected *prefixpolicy.RejectedError
+	if errors.As(err, &rejected) {
+		prefixRejected.Inc(rejected.Reason, vpc)
+	}
+	return err
+}
+
+var segmentRejected = metrics.NewCounter(
+	"galactic_agent_segment_rejected_total",
+	"Routes refused because a segment was outside segment_allowlist.",
+	"vpc",
+)
+
+// checkSegments refuses a route whose resolved segments leave the allowlist.
+func checkSegments(allowed allowlist.List, route *remote.Route, segments []string) error {
+	err := allowed.Check(segments)
+	if err != nil {
+		segmentRejected.Inc(endpointVPC(route.Srv6Endpoint))
+	}
+	return err
+}
+
+var segmentsExceeded = metrics.NewCounter(
+	"galactic_agent_segments_exceeded_total",
+	"Routes refused because their segment list exceeded the SRH maximum or the underlay MTU budget.",
+	"reason", "vpc",
+)
+
+// checkDepth refuses a route whose resolved segments the kernel can't
+// encapsulate, or that leave no room for packets of the attachment's MTU
+// within underlayMTU. The attachment's MTU is the one pushed by the
+// controller, else that of its host interface, else the IPv6 minimum.
+func checkDepth(underlayMTU int, store *state.Store, route *remote.Route, segments []string) error {
+	inner := attachmentMTU(store, route.Srv6Endpoint)
+	if inner == 0 && underlayMTU != 0 {
+		var err error
+		if inner, err = srv6.AttachmentMTU(route.Srv6Endpoint); err != nil {
+			inner = minMTU
+		}
+	}
+	err := depth.Check(len(segments), inner, underlayMTU)
+	var exceeded *depth.ExceededError
+	if errors.As(err, &exceeded) {
+		segmentsExceeded.Inc(exceeded.Reason, endpointVPC(route.Srv6Endpoint))
+	}
+	return err
+}
+
+// nack tells the controller a route was refused. It runs in the background
+// since it's called from the receive callback, which must not block on the
+// broker.
+func nack(reason remote.Nack_Reason, cause error, route *remote.Route) {
+	auditor.Record(audit.ActionRefused, map[string]string{
+		"vpc":           endpointVPC(route.Srv6Endpoint),
+		"network":       route.Network,
+		"srv6_endpoint": route.Srv6Endpoint,
+		"reason":        reason.String(),
+		"detail":        cause.Error(),
+	})
+	// route belongs to the receive path, which reuses it
+	route = proto.Clone(route).(*remote.Route)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+		defer cancel()
+		err := r.SendEnvelope(ctx, &remote.Envelope{
+			Kind: &remote.Envelope_Nack{
+				Nack: &remote.Nack{Reason: reason, Detail: cause.Error(), Route: route},
+			},
+		})
+		if err != nil {
+			slog.Warn("NACK", append(endpointAttrs(route.Srv6Endpoint), "network", route.Network, "reason", reason.String(), "err", err)...)
+		}
+	}()
+}
+
+// endpointVPCs caches endpointVPC, which every route asks several times.
+var endpointVPCs = struct {
+	sync.RWMutex
+	m map[string]string
+}{m: make(map[string]string)}
+
+// endpointVPC is the hex VPC encoded in an SRv6 endpoint, or "unknown".
+func endpointVPC(srv6Endpoint string) string {
+	endpointVPCs.RLock()
+	vpc, ok := endpointVPCs.m[srv6Endpoint]
+	endpointVPCs.RUnlock()
+	if ok {
+		return vpc
+	}
+	ip, err := util.ParseIP(srv6Endpoint)
+	if err != nil {
+		return "unknown"
+	}
+	vpc, _, err = util.DecodeSRv6Endpoint(ip)
+	if err != nil {
+		return "unknown"
+	}
+	endpointVPCs.Lock()
+	// the endpoints of a node are few; a flood of bogus ones only costs
+	// the cache
+	if len(endpointVPCs.m) >= 65536 {
+		clear(endpointVPCs.m)
+	}
+	endpointVPCs.m[srv6Endpoint] = vpc
+	endpointVPCs.Unlock()
+	return vpc
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/datum-cloud/galactic-agent/loadgen"
+)
+
+func newLoadgenCmd() *cobra.Command {
+	var (
+		c      loadgen.Config
+		noMQTT bool
+	)
+	cmd := &cobra.Command{
+		Use:   "loadgen",
+		Short: "Ramp synthetic attachments and routes against a running agent",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+			defer stop()
+
+			c.SRv6Net = viper.GetString("srv6_net")
+			c.SocketPath = viper.GetString("socket_path")
+			if !noMQTT {
+				c.MQTTURL = viper.GetString("mqtt_url")
+				c.Username = viper.GetString("mqtt_username")
+				c.Password = viper.GetString("mqtt_password")
+				c.QoS = byte(viper.GetInt("mqtt_qos"))
+				c.TopicRX = viper.GetString("mqtt_topic_receive")
+			}
+			report, err := loadgen.Run(ctx, c)
+			if err != nil {
+				return err
+			}
+			report.Write(os.Stdout)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&c.VPC, "vpc", "00000000f00d", "vpc id (hex) for synthetic attachments")
+	cmd.Flags().IntVarP(&c.Attachments, "attachments", "n", 10, "number of synthetic attachments")
+	cmd.Flags().IntVarP(&c.RoutesPerAttachment, "routes", "m", 10, "routes pushed to each attachment (max 256)")
+	cmd.Flags().StringVar(&c.Segment, "segment", "fc00:0:0:ffff::1", "segment used for synthetic routes")
+	cmd.Flags().Float64Var(&c.Rate, "rate", 10, "attachments brought up (and down) per second")
+	cmd.Flags().DurationVar(&c.Hold, "hold", 30*time.Second, "time to hold full load before ramping down")
+	cmd.Flags().DurationVar(&c.Timeout, "timeout", 10*time.Second, "time to wait for each route to reach the kernel")
+	cmd.Flags().BoolVar(&c.Provision, "provision", true, "create and destroy VRFs and interfaces for the synthetic attachments")
+	cmd.Flags().BoolVar(&noMQTT, "no-mqtt", false, "only exercise the local API")
+	return cmd
+}
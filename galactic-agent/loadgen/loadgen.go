@@ -0,0 +1,263 @@
+package loadgen
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/datum-cloud/galactic-agent/api/remote"
+	"github.com/datum-cloud/galactic-agent/client"
+	"github.com/datum-cloud/galactic-agent/fixtures"
+	"github.com/datum-cloud/galactic-agent/latency"
+	"github.com/datum-cloud/galactic-agent/srv6/endpoint"
+)
+
+// Config describes a soak run: Attachments synthetic VPC attachments are
+// brought up one by one at Rate per second, each registering one network via
+// the local API and receiving RoutesPerAttachment routes via MQTT. After Hold
+// the load is ramped down again in reverse.
+type Config struct {
+	VPC                 string
+	Attachments         int
+	RoutesPerAttachment int
+	Segment             string
+	Rate                float64
+	Hold                time.Duration
+	Timeout             time.Duration
+	Provision           bool
+
+	SRv6Net    string
+	SocketPath string
+
+	// MQTT settings; routes are skipped when MQTTURL is empty.
+	MQTTURL  string
+	Username string
+	Password string
+	QoS      byte
+	TopicRX  string
+}
+
+type generator struct {
+	c      Config
+	report *latency.Report
+	local  *client.Client
+	mqtt   mqtt.Client
+
+	mu      sync.Mutex
+	waiting map[string]chan struct{}
+}
+
+func attachmentID(i int) string {
+	return fmt.Sprintf("%04x", i+1)
+}
+
+func network(i int) string {
+	return fmt.Sprintf("172.%d.%d.1/32", 16+(i>>8)%16, i&0xff)
+}
+
+func route(i, j int) string {
+	return fmt.Sprintf("%d.%d.%d.0/24", 10+(i>>8), i&0xff, j&0xff)
+}
+
+// Run executes the soak test and returns the latency report.
+func Run(ctx context.Context, c Config) (*latency.Report, error) {
+	if c.Rate <= 0 {
+		c.Rate = 10
+	}
+	if c.Timeout == 0 {
+		c.Timeout = 10 * time.Second
+	}
+	if c.RoutesPerAttachment > 256 {
+		return nil, fmt.Errorf("at most 256 routes per attachment are supported")
+	}
+	g := &generator{
+		c:       c,
+		report:  latency.NewReport(),
+		waiting: make(map[string]chan struct{}),
+	}
+
+	local, err := client.New(c.SocketPath)
+	if err != nil {
+		return nil, err
+	}
+	defer local.Close() //nolint:errcheck
+	g.local = local
+
+	if c.MQTTURL != "" && c.RoutesPerAttachment > 0 {
+		opts := mqtt.NewClientOptions().AddBroker(c.MQTTURL)
+		if c.Username != "" {
+			opts.SetUsername(c.Username)
+		}
+		if c.Password != "" {
+			opts.SetPassword(c.Password)
+		}
+		g.mqtt = mqtt.NewClient(opts)
+		if tok := g.mqtt.Connect(); tok.Wait() && tok.Error() != nil {
+			return nil, tok.Error()
+		}
+		defer g.mqtt.Disconnect(250)
+
+		updates := make(chan netlink.RouteUpdate, 4096)
+		done := make(chan struct{})
+		defer close(done)
+		if err := netlink.RouteSubscribe(updates, done); err != nil {
+			return nil, err
+		}
+		go g.watch(updates)
+	}
+
+	if c.Provision {
+		if err := fixtures.EnsureLoopback(); err != nil {
+			return nil, err
+		}
+	}
+
+	interval := time.Duration(float64(time.Second) / c.Rate)
+	up := 0
+	for ; up < c.Attachments && ctx.Err() == nil; up++ {
+		g.up(ctx, up)
+		sleep(ctx, interval)
+	}
+	slog.Info("loadgen: attachments up", "attachments", up, "hold", c.Hold)
+	sleep(ctx, c.Hold)
+
+	// ramp down even when interrupted so the host is left clean
+	down := context.Background()
+	for i := up - 1; i >= 0; i-- {
+		g.down(down, i)
+		if ctx.Err() == nil {
+			sleep(ctx, interval)
+		}
+	}
+	return g.report, nil
+}
+
+func sleep(ctx context.Context, d time.Duration) {
+	select {
+	case <-time.After(d):
+	case <-ctx.Done():
+	}
+}
+
+func (g *generator) watch(updates <-chan netlink.RouteUpdate) {
+	for u := range updates {
+		if u.Route.Dst == nil {
+			continue
+		}
+		key := fmt.Sprintf("%d/%d/%s", u.Type, u.Route.Table, u.Route.Dst)
+		g.mu.Lock()
+		if ch, ok := g.waiting[key]; ok {
+			close(ch)
+			delete(g.waiting, key)
+		}
+		g.mu.Unlock()
+	}
+}
+
+func (g *generator) up(ctx context.Context, i int) {
+	a := fixtures.Attachment{VPC: g.c.VPC, VPCAttachment: attachmentID(i)}
+	if g.c.Provision {
+		if err := fixtures.Create(a); err != nil {
+			slog.Warn("loadgen: provision failed", "vpc", a.VPC, "vpcattachment", a.VPCAttachment, "err", err)
+			g.report.Record("provision", 0, err)
+			return
+		}
+	}
+
+	start := time.Now()
+	err := g.local.Register(ctx, a.VPC, a.VPCAttachment, network(i))
+	g.report.Record("register", time.Since(start), err)
+	if err != nil {
+		slog.Warn("loadgen: register failed", "vpc", a.VPC, "vpcattachment", a.VPCAttachment, "err", err)
+		return
+	}
+	g.routes(i, remote.Route_ADD)
+}
+
+func (g *generator) down(ctx context.Context, i int) {
+	a := fixtures.Attachment{VPC: g.c.VPC, VPCAttachment: attachmentID(i)}
+	g.routes(i, remote.Route_DELETE)
+
+	start := time.Now()
+	err := g.local.Deregister(ctx, a.VPC, a.VPCAttachment, network(i))
+	g.report.Record("deregister", time.Since(start), err)
+
+	if g.c.Provision {
+		if err := fixtures.Destroy(a); err != nil {
+			slog.Warn("loadgen: destroy failed", "vpc", a.VPC, "vpcattachment", a.VPCAttachment, "err", err)
+		}
+	}
+}
+
+// routes publishes the attachment's routes and waits for all of them to be
+// reflected in the kernel, recording the latency of each.
+func (g *generator) routes(i int, status remote.Route_Status) {
+	if g.mqtt == nil || g.c.RoutesPerAttachment == 0 {
+		return
+	}
+	srv6Endpoint, err := endpoint.Encode(g.c.SRv6Net, g.c.VPC, attachmentID(i))
+	if err != nil {
+		g.report.Record(status.String(), 0, err)
+		return
+	}
+	table, err := g.table(i)
+	if err != nil {
+		g.report.Record(status.String(), 0, err)
+		return
+	}
+	msgType := uint16(unix.RTM_NEWROUTE)
+	if status == remote.Route_DELETE {
+		msgType = unix.RTM_DELROUTE
+	}
+
+	var wg sync.WaitGroup
+	for j := 0; j < g.c.RoutesPerAttachment; j++ {
+		p := route(i, j)
+		_, dst, _ := net.ParseCIDR(p)
+		ch := make(chan struct{})
+		g.mu.Lock()
+		g.waiting[fmt.Sprintf("%d/%d/%s", msgType, table, dst)] = ch
+		g.mu.Unlock()
+
+		payload, err := proto.Marshal(&remote.Envelope{
+			Kind: &remote.Envelope_Route{
+				Route: &remote.Route{
+					Status:       status,
+					Network:      p,
+					Srv6Endpoint: srv6Endpoint,
+					Srv6Segments: []string{g.c.Segment},
+				},
+			},
+		})
+		if err != nil {
+			g.report.Record(status.String(), 0, err)
+			continue
+		}
+		start := time.Now()
+		g.mqtt.Publish(g.c.TopicRX, g.c.QoS, false, payload)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case <-ch:
+				g.report.Record(status.String(), time.Since(start), nil)
+			case <-time.After(g.c.Timeout):
+				g.report.Record(status.String(), 0, fmt.Errorf("timeout"))
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func (g *generator) table(i int) (int, error) {
+	a := fixtures.Attachment{VPC: g.c.VPC, VPCAttachment: attachmentID(i)}
+	return fixtures.Table(a)
+}
@@ -0,0 +1,103 @@
+// Package logging sets up the agent's structured log. Events carry the
+// vpc, vpcattachment, network and srv6_endpoint they concern as fields, so
+// they can be searched for once ingested.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Formats of the log.
+const (
+	Text = "text"
+	JSON = "json"
+)
+
+// Outputs of the log.
+const (
+	Stderr   = "stderr"
+	Journald = "journald"
+	Syslog   = "syslog"
+)
+
+// Options configure the log.
+type Options struct {
+	// Level is debug, info, warn or error.
+	Level string
+	// Format is Text or JSON, for the Stderr output.
+	Format string
+	// Output is Stderr, the writer given to Setup; Journald, with each
+	// field of an event a journal field; or Syslog, as logfmt messages to
+	// the daemon at SyslogNetwork and SyslogAddress, local if empty.
+	Output        string
+	SyslogNetwork string
+	SyslogAddress string
+	// RepeatWindow collapses warnings and errors repeated within it into
+	// one line with their count; 0 logs every one.
+	RepeatWindow time.Duration
+}
+
+// Identifier names the agent to journald and syslog.
+const Identifier = "galactic-agent"
+
+var (
+	outputMu sync.Mutex
+	output   io.Closer // the journald or syslog connection in use
+)
+
+// Setup makes the default slog logger write events of opts.Level and above
+// to opts.Output. The standard logger writes through it too, at level
+// info. Setting up again, as on reload, closes the connection of the
+// output replaced.
+func Setup(w io.Writer, opts Options) error {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(opts.Level)); err != nil {
+		return fmt.Errorf("log_level invalid: %q", opts.Level)
+	}
+	var h slog.Handler
+	var conn io.Closer
+	switch strings.ToLower(opts.Output) {
+	case Stderr, "":
+		handlerOpts := &slog.HandlerOptions{Level: l}
+		switch strings.ToLower(opts.Format) {
+		case Text:
+			h = slog.NewTextHandler(w, handlerOpts)
+		case JSON:
+			h = slog.NewJSONHandler(w, handlerOpts)
+		default:
+			return fmt.Errorf("log_format invalid: %q", opts.Format)
+		}
+	case Journald:
+		j, err := newJournal()
+		if err != nil {
+			return fmt.Errorf("log_output journald: %w", err)
+		}
+		h, conn = newSinkHandler(l, j.write), j
+	case Syslog:
+		s, err := newSyslog(opts.SyslogNetwork, opts.SyslogAddress)
+		if err != nil {
+			return fmt.Errorf("log_output syslog: %w", err)
+		}
+		h, conn = newSinkHandler(l, s.write), s
+	default:
+		return fmt.Errorf("log_output invalid: %q", opts.Output)
+	}
+	if opts.RepeatWindow > 0 {
+		h = newRepeatHandler(h, opts.RepeatWindow)
+	}
+	slog.SetDefault(slog.New(h))
+
+	outputMu.Lock()
+	old := output
+	output = conn
+	outputMu.Unlock()
+	if old != nil {
+		old.Close() //nolint:errcheck
+	}
+	return nil
+}
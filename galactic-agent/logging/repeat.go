@@ -0,0 +1,119 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// repeatHandler collapses repeats of the same warning or error: the first
+// is logged, those within window after it are counted, and once window is
+// over the last of them is logged once with how many there were. Records
+// are the same when their level, message and attributes are, e.g. a
+// missing link failing every reconnect. Metrics are counted by the callers
+// regardless.
+type repeatHandler struct {
+	next   slog.Handler
+	window time.Duration
+	// prefix keys the attributes and groups added with WithAttrs and
+	// WithGroup
+	prefix string
+	state  *repeatState
+}
+
+type repeatState struct {
+	mu   sync.Mutex
+	seen map[string]*repeat
+}
+
+// repeat is a record logged within the window, and how many times it
+// repeated since.
+type repeat struct {
+	count int
+	last  slog.Record
+	next  slog.Handler
+}
+
+func newRepeatHandler(next slog.Handler, window time.Duration) *repeatHandler {
+	return &repeatHandler{next: next, window: window, state: &repeatState{seen: make(map[string]*repeat)}}
+}
+
+func (h *repeatHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *repeatHandler) Handle(ctx context.Context, r slog.Record) error {
+	if !collapsible(r) {
+		return h.next.Handle(ctx, r)
+	}
+	key := h.key(r)
+	s := h.state
+	s.mu.Lock()
+	if rep, ok := s.seen[key]; ok {
+		rep.count++
+		rep.last = r.Clone()
+		s.mu.Unlock()
+		return nil
+	}
+	s.seen[key] = &repeat{next: h.next}
+	s.mu.Unlock()
+	time.AfterFunc(h.window, func() { h.flush(key) })
+	return h.next.Handle(ctx, r)
+}
+
+// flush ends the window of key, logging the last repeat if there was one.
+func (h *repeatHandler) flush(key string) {
+	s := h.state
+	s.mu.Lock()
+	rep := s.seen[key]
+	delete(s.seen, key)
+	s.mu.Unlock()
+	if rep == nil || rep.count == 0 {
+		return
+	}
+	r := rep.last
+	r.AddAttrs(slog.Int("repeated", rep.count), slog.Duration("within", h.window))
+	rep.next.Handle(context.Background(), r) //nolint:errcheck
+}
+
+// collapsible reports whether r is a warning or an error, or carries one.
+func collapsible(r slog.Record) bool {
+	if r.Level >= slog.LevelWarn {
+		return true
+	}
+	found := false
+	r.Attrs(func(a slog.Attr) bool {
+		found = a.Key == "err"
+		return !found
+	})
+	return found
+}
+
+func (h *repeatHandler) key(r slog.Record) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\x00%s\x00%s", h.prefix, r.Level, r.Message)
+	r.Attrs(func(a slog.Attr) bool {
+		fmt.Fprintf(&b, "\x00%s", a)
+		return true
+	})
+	return b.String()
+}
+
+func (h *repeatHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	c := *h
+	c.next = h.next.WithAttrs(attrs)
+	for _, a := range attrs {
+		c.prefix += "\x00" + a.String()
+	}
+	return &c
+}
+
+func (h *repeatHandler) WithGroup(name string) slog.Handler {
+	c := *h
+	c.next = h.next.WithGroup(name)
+	c.prefix += "\x00group=" + name
+	return &c
+}
@@ -0,0 +1,196 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"log/slog"
+	"log/syslog"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// field is an attribute of an event, its key qualified by its groups.
+type field struct {
+	key, value string
+}
+
+// sinkHandler hands events to an output that takes a message and its
+// fields rather than a stream of lines.
+type sinkHandler struct {
+	level  slog.Leveler
+	write  func(level slog.Level, msg string, fields []field) error
+	fields []field
+	group  string
+}
+
+func newSinkHandler(level slog.Leveler, write func(slog.Level, string, []field) error) *sinkHandler {
+	return &sinkHandler{level: level, write: write}
+}
+
+func (h *sinkHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *sinkHandler) Handle(_ context.Context, r slog.Record) error {
+	fields := h.fields[:len(h.fields):len(h.fields)]
+	r.Attrs(func(a slog.Attr) bool {
+		fields = appendFields(fields, h.group, a)
+		return true
+	})
+	return h.write(r.Level, r.Message, fields)
+}
+
+func (h *sinkHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	c := *h
+	c.fields = h.fields[:len(h.fields):len(h.fields)]
+	for _, a := range attrs {
+		c.fields = appendFields(c.fields, h.group, a)
+	}
+	return &c
+}
+
+func (h *sinkHandler) WithGroup(name string) slog.Handler {
+	c := *h
+	c.group = h.group + name + "."
+	return &c
+}
+
+func appendFields(fields []field, group string, a slog.Attr) []field {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return fields
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		prefix := group
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, g := range a.Value.Group() {
+			fields = appendFields(fields, prefix, g)
+		}
+		return fields
+	}
+	return append(fields, field{group + a.Key, a.Value.String()})
+}
+
+// logfmt renders msg and fields as one line, the way the text format does.
+func logfmt(msg string, fields []field) string {
+	var b strings.Builder
+	b.WriteString(msg)
+	for _, f := range fields {
+		v := f.value
+		if v == "" || strings.ContainsAny(v, " \t\"=") {
+			v = strconv.Quote(v)
+		}
+		b.WriteString(" " + f.key + "=" + v)
+	}
+	return b.String()
+}
+
+// journalSocket is where journald accepts native protocol datagrams.
+const journalSocket = "/run/systemd/journal/socket"
+
+// journal writes events with the native journal protocol: MESSAGE holds
+// them as a line, and each field is a journal field of its own, prefixed
+// GALACTIC_ and upper cased, e.g. journalctl GALACTIC_VPC=0000000000ab.
+type journal struct {
+	conn *net.UnixConn
+}
+
+func newJournal() (*journal, error) {
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: journalSocket, Net: "unixgram"})
+	if err != nil {
+		return nil, err
+	}
+	return &journal{conn: conn}, nil
+}
+
+func (j *journal) write(level slog.Level, msg string, fields []field) error {
+	var b bytes.Buffer
+	writeJournalField(&b, "MESSAGE", logfmt(msg, fields))
+	writeJournalField(&b, "PRIORITY", strconv.Itoa(int(priority(level))))
+	writeJournalField(&b, "SYSLOG_IDENTIFIER", Identifier)
+	for _, f := range fields {
+		writeJournalField(&b, "GALACTIC_"+journalFieldName(f.key), f.value)
+	}
+	_, err := j.conn.Write(b.Bytes())
+	return err
+}
+
+func (j *journal) Close() error {
+	return j.conn.Close()
+}
+
+// journalFieldName maps a key to the journal's [A-Z0-9_] alphabet.
+func journalFieldName(k string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+		return '_'
+	}, k)
+}
+
+// writeJournalField appends one field; values containing a newline use the
+// length-prefixed form of the protocol.
+func writeJournalField(b *bytes.Buffer, name, value string) {
+	b.WriteString(name)
+	if !strings.Contains(value, "\n") {
+		b.WriteByte('=')
+		b.WriteString(value)
+		b.WriteByte('\n')
+		return
+	}
+	b.WriteByte('\n')
+	_ = binary.Write(b, binary.LittleEndian, uint64(len(value)))
+	b.WriteString(value)
+	b.WriteByte('\n')
+}
+
+// syslogWriter writes events as logfmt messages at LOG_DAEMON.
+type syslogWriter struct {
+	w *syslog.Writer
+}
+
+func newSyslog(network, addr string) (*syslogWriter, error) {
+	w, err := syslog.Dial(network, addr, syslog.LOG_INFO|syslog.LOG_DAEMON, Identifier)
+	if err != nil {
+		return nil, err
+	}
+	return &syslogWriter{w: w}, nil
+}
+
+func (s *syslogWriter) write(level slog.Level, msg string, fields []field) error {
+	line := logfmt(msg, fields)
+	switch priority(level) {
+	case syslog.LOG_ERR:
+		return s.w.Err(line)
+	case syslog.LOG_WARNING:
+		return s.w.Warning(line)
+	case syslog.LOG_INFO:
+		return s.w.Info(line)
+	}
+	return s.w.Debug(line)
+}
+
+func (s *syslogWriter) Close() error {
+	return s.w.Close()
+}
+
+// priority maps a level to its syslog(3) severity.
+func priority(level slog.Level) syslog.Priority {
+	switch {
+	case level >= slog.LevelError:
+		return syslog.LOG_ERR
+	case level >= slog.LevelWarn:
+		return syslog.LOG_WARNING
+	case level >= slog.LevelInfo:
+		return syslog.LOG_INFO
+	}
+	return syslog.LOG_DEBUG
+}
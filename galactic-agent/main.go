@@ -2,47 +2,186 @@ package main
 
 import (
 	"context"
-	"log"
+	"errors"
+	"log/slog"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"sync"
 	"syscall"
+	"time"
 
 	"golang.org/x/sync/errgroup"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
-	"google.golang.org/protobuf/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
+	"github.com/datum-cloud/galactic-agent/admin"
+	"github.com/datum-cloud/galactic-agent/api/companion"
 	"github.com/datum-cloud/galactic-agent/api/local"
 	"github.com/datum-cloud/galactic-agent/api/remote"
-	"github.com/datum-cloud/galactic-agent/srv6"
-	"github.com/datum-cloud/galactic-common/util"
+	"github.com/datum-cloud/galactic-agent/dashboard"
+	"github.com/datum-cloud/galactic-agent/fips"
+	"github.com/datum-cloud/galactic-agent/identity"
+	"github.com/datum-cloud/galactic-agent/ipam"
+	"github.com/datum-cloud/galactic-agent/logging"
+	"github.com/datum-cloud/galactic-agent/metrics"
+	"github.com/datum-cloud/galactic-agent/prefixpolicy"
+	"github.com/datum-cloud/galactic-agent/quota"
+	"github.com/datum-cloud/galactic-agent/ratelimit"
+	"github.com/datum-cloud/galactic-agent/replay"
+	"github.com/datum-cloud/galactic-agent/srv6/alias"
+	"github.com/datum-cloud/galactic-agent/srv6/allowlist"
+	"github.com/datum-cloud/galactic-agent/srv6/depth"
+	"github.com/datum-cloud/galactic-agent/srv6/dryrun"
+	"github.com/datum-cloud/galactic-agent/srv6/endpoint"
+	"github.com/datum-cloud/galactic-agent/srv6/linkcache"
+	"github.com/datum-cloud/galactic-agent/srv6/routecache"
+	"github.com/datum-cloud/galactic-agent/state"
+	"github.com/datum-cloud/galactic-agent/tlsreload"
 )
 
 var configFile string
 
 func initConfig() {
 	viper.SetDefault("srv6_net", "fc00::/56")
+	viper.SetDefault("log_level", "info")
+	viper.SetDefault("log_format", logging.Text)
+	viper.SetDefault("log_repeat_window", time.Minute)
+	viper.SetDefault("log_output", logging.Stderr)
 	viper.SetDefault("socket_path", "/var/run/galactic/agent.sock")
+	viper.SetDefault("drain_timeout", 10*time.Second)
+	viper.SetDefault("startup_preflight", "fail")
+	viper.SetDefault("state_dir", "/var/lib/galactic")
+	viper.SetDefault("duplicate_networks", duplicateReject)
 	viper.SetDefault("mqtt_url", "tcp://mqtt:1883")
 	viper.SetDefault("mqtt_qos", 1)
+	viper.SetDefault("mqtt_clientid_version", true)
 	viper.SetDefault("mqtt_topic_receive", "galactic/default/receive")
 	viper.SetDefault("mqtt_topic_send", "galactic/default/send")
+	viper.SetDefault("mqtt_topic_telemetry", "galactic/default/telemetry")
+	viper.SetDefault("companion_interface", "eth0")
+	viper.SetDefault("dashboard_addr", "localhost:7380")
+	viper.SetDefault("probe_addr", "localhost:7381")
+	viper.SetDefault("audit_file_path", "/var/log/galactic/audit.log")
+	viper.SetDefault("audit_file_max_size_mb", 100)
+	viper.SetDefault("audit_file_max_backups", 10)
+	viper.SetDefault("tracing_sample_ratio", 1.0)
+	viper.SetDefault("tracing_interval", 5*time.Second)
+	viper.SetDefault("agentx_oid", defaultAgentXOID)
+	viper.SetDefault("frr_vtysh", []string{"vtysh"})
+	viper.SetDefault("frr_import_protocols", []string{"bgp"})
+	viper.SetDefault("frr_sync_interval", 30*time.Second)
+	viper.SetDefault("route_batch_chunk", 1000)
+	viper.SetDefault("replay_workers", 16)
+	viper.SetDefault("replay_prune", false)
+	viper.SetDefault("outbox_size", 1024)
+	viper.SetDefault("datapath", "kernel")
+	viper.SetDefault("dns_domain", "galactic")
+	viper.SetDefault("dns_ttl", 30)
+	viper.SetDefault("dns_retry_interval", 30*time.Second)
+	viper.SetDefault("route_monitor_interval", time.Second)
+	viper.SetDefault("flow_export_sample_rate", 1000)
+	viper.SetDefault("flow_export_interval", 10*time.Second)
+	viper.SetDefault("flow_export_nflog_group", 4739)
+	viper.SetDefault("flow_export_nft", []string{"nft"})
+	viper.SetDefault("flow_export_max_flows", 65536)
+	viper.SetDefault("probe_responder", true)
+	viper.SetDefault("probe_port", 7362)
+	viper.SetDefault("probe_timeout", 2*time.Second)
+	viper.SetDefault("probe_window", 20)
+	viper.SetDefault("probe_fail_after", 3)
+	viper.SetDefault("probe_withdraw_min_nodes", 2)
+	viper.SetDefault("ovs_bridge", "br-galactic")
+	viper.SetDefault("ovs_encap", "srv6")
+	viper.SetDefault("ovs_vsctl", []string{"ovs-vsctl"})
+	viper.SetDefault("ovs_ofctl", []string{"ovs-ofctl", "-O", "OpenFlow15"})
 	if configFile != "" {
 		viper.SetConfigFile(configFile)
 	}
 	viper.AutomaticEnv()
-	if err := viper.ReadInConfig(); err == nil {
-		log.Printf("Using config file: %s\n", viper.ConfigFileUsed())
+	err := viper.ReadInConfig()
+	// the profile may set the log level and format too
+	profileErr := applyProfile()
+	if err := logging.Setup(os.Stderr, logOptions()); err != nil {
+		fatal("Config invalid", "err", err)
+	}
+	if err == nil {
+		slog.Info("Using config file", "path", viper.ConfigFileUsed())
 	} else {
-		log.Printf("No config file found - using defaults.")
+		slog.Info("No config file found - using defaults.")
+	}
+	if profileErr != nil {
+		fatal("Config invalid", "err", profileErr)
+	}
+	if profile := viper.GetString("profile"); profile != "" {
+		slog.Info("Using config profile", "profile", profile)
+	}
+	// the files default to state_dir, wherever the config or flag put it
+	dir := viper.GetString("state_dir")
+	viper.SetDefault("state_path", filepath.Join(dir, "state.json"))
+	viper.SetDefault("ipam_path", filepath.Join(dir, "ipam.json"))
+	viper.SetDefault("credentials_path", filepath.Join(dir, "credentials.bin"))
+	viper.SetDefault("journal_path", filepath.Join(dir, "journal.log"))
+}
+
+// logOptions are the log_* settings.
+func logOptions() logging.Options {
+	return logging.Options{
+		Level:         viper.GetString("log_level"),
+		Format:        viper.GetString("log_format"),
+		Output:        viper.GetString("log_output"),
+		SyslogNetwork: viper.GetString("log_syslog_network"),
+		SyslogAddress: viper.GetString("log_syslog_address"),
+		RepeatWindow:  viper.GetDuration("log_repeat_window"),
+	}
+}
+
+// fatal logs msg with args as an error and exits.
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
+// endpointAttrs are the log fields of the attachment srv6Endpoint belongs
+// to.
+func endpointAttrs(srv6Endpoint string) []any {
+	attrs := []any{"srv6_endpoint", srv6Endpoint}
+	if vpc, vpcAttachment, err := endpointAttachment(srv6Endpoint); err == nil {
+		attrs = append(attrs, "vpc", vpc, "vpcattachment", vpcAttachment)
 	}
+	return attrs
 }
 
 var (
 	l local.Local
-	r remote.Remote
+	r remote.Transport
+)
+
+const skewUnexpectedKind = "unexpected envelope kind"
+
+// sendTimeout bounds how long a local API call waits for the controller's
+// broker to acknowledge the resulting envelope, including time spent queued
+// while disconnected.
+const sendTimeout = 10 * time.Second
+
+var envelopeSkew = metrics.NewCounter(
+	"galactic_agent_envelope_schema_skew_total",
+	"Received envelopes containing fields or kinds this agent does not understand.",
+	"reason",
+)
+
+var envelopeReplayed = metrics.NewCounter(
+	"galactic_agent_envelope_replay_rejected_total",
+	"Received envelopes dropped as replayed, stale or unstamped.",
+)
+
+var envelopeUnverified = metrics.NewCounter(
+	"galactic_agent_envelope_unverified_total",
+	"Received envelopes dropped because they were not signed by a key in route_trust_bundle.",
 )
 
 func main() {
@@ -55,113 +194,595 @@ func main() {
 		Run: func(cmd *cobra.Command, args []string) {
 			ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 			defer stop() //nolint:errcheck
+			// a second signal cuts the drain short
+			context.AfterFunc(ctx, stop)
+			drainTimeout := viper.GetDuration("drain_timeout")
+			slog.Info("Galactic Agent", currentBuild().logAttrs()...)
+
+			if viper.GetBool("dry_run") {
+				dryrun.Enable()
+				slog.Warn("Dry run: the kernel is not changed")
+			}
+
+			if viper.GetBool("node_mode") {
+				if err := applyNodeMode(); err != nil {
+					fatal("Node mode", "err", err)
+				}
+			}
 
-			_, err := util.EncodeSRv6Endpoint(viper.GetString("srv6_net"), "ffffffffffff", "ffff")
+			_, err := endpoint.Encode(viper.GetString("srv6_net"), "ffffffffffff", "ffff")
 			if err != nil {
-				log.Fatalf("srv6_endpoint invalid: %v", err)
+				fatal("srv6_endpoint invalid", "err", err)
 			}
 
-			l = local.Local{
-				SocketPath: viper.GetString("socket_path"),
-				RegisterHandler: func(vpc, vpcAttachment string, networks []string) error {
-					srv6_endpoint, err := util.EncodeSRv6Endpoint(viper.GetString("srv6_net"), vpc, vpcAttachment)
-					if err != nil {
-						return err
-					}
-					if err := srv6.RouteIngressAdd(srv6_endpoint); err != nil {
-						return err
-					}
-					for _, n := range networks {
-						log.Printf("REGISTER: network='%s', srv6_endpoint='%s'", n, srv6_endpoint)
-						payload, err := proto.Marshal(&remote.Envelope{
-							Kind: &remote.Envelope_Register{
-								Register: &remote.Register{
-									Network:      n,
-									Srv6Endpoint: srv6_endpoint,
-								},
-							},
-						})
-						if err != nil {
-							return err
+			fipsMode := viper.GetBool("fips_mode")
+			if fipsMode {
+				if err := fips.Require(); err != nil {
+					fatal("fips_mode", "err", err)
+				}
+				slog.Info("FIPS 140-3 mode")
+			}
+
+			auditor, err = openAudit()
+			if err != nil {
+				fatal("Startup failed", "err", err)
+			}
+			defer auditor.Close() //nolint:errcheck
+			if viper.GetString("dashboard_addr") != "" {
+				auditor = append(auditor, recentEvents)
+			}
+			auditor = append(auditor, eventFeed{})
+
+			tenantMap, err = loadTenants()
+			if err != nil {
+				fatal("Startup failed", "err", err)
+			}
+
+			key, err := stateKey()
+			if err != nil {
+				fatal("State key", "err", err)
+			}
+			windows, err = loadCompanion()
+			if err != nil {
+				fatal("Startup failed", "err", err)
+			}
+			for _, t := range tenantMap.All() {
+				if err := adoptLegacyState(t.StatePath); err != nil {
+					fatal("State store", "tenant", t.Name, "err", err)
+				}
+				store, err := state.OpenSealed(t.StatePath, key)
+				if err != nil {
+					fatal("State store", "tenant", t.Name, "err", err)
+				}
+				trackState(store.Snapshot())
+				store.OnChange = storeChanged
+				allocator, err := ipam.Open(t.IPAMPath, t.SRv6Net)
+				if err != nil {
+					fatal("IPAM", "tenant", t.Name, "err", err)
+				}
+				domains[t] = &domain{Tenant: t, store: store, allocator: allocator}
+				countAttachments(domains[t])
+			}
+			if err := openJournal(key); err != nil {
+				fatal("Journal", "err", err)
+			}
+			if wal != nil {
+				defer wal.Close() //nolint:errcheck
+			}
+
+			aliases, err := alias.Parse(viper.GetStringMapString("segment_aliases"))
+			if err != nil {
+				fatal("segment_aliases invalid", "err", err)
+			}
+
+			var policy local.Policy
+			if err := viper.UnmarshalKey("local_authz", &policy); err != nil {
+				fatal("local_authz invalid", "err", err)
+			}
+			if err := policy.Validate(); err != nil {
+				fatal("local_authz invalid", "err", err)
+			}
+
+			registerLimit := ratelimit.New("register", viper.GetFloat64("rate_limit_register"), viper.GetInt("rate_limit_register_burst"))
+			routeLimit := ratelimit.New("route", viper.GetFloat64("rate_limit_routes"), viper.GetInt("rate_limit_routes_burst"))
+
+			limiters := map[string]*ratelimit.Limiter{"register": registerLimit, "route": routeLimit}
+			var gnmiCerts *tlsreload.Watcher
+			telemetry, gnmiCerts, err = loadGNMI(limiters)
+			if err != nil {
+				fatal("Startup failed", "err", err)
+			}
+			nc, rc, netconfCerts, err := loadNETCONF(limiters)
+			if err != nil {
+				fatal("Startup failed", "err", err)
+			}
+			subagent, err := loadAgentX()
+			if err != nil {
+				fatal("Startup failed", "err", err)
+			}
+			if dp, err = loadDatapath(); err != nil {
+				fatal("Startup failed", "err", err)
+			}
+			if kernelDatapath() {
+				if viper.GetBool("manage_sysctls") {
+					manageSysctls()
+				}
+				if err := checkKernel(); err != nil {
+					fatal("Startup failed", "err", err)
+				}
+			}
+			if routing, err = loadFRR(); err != nil {
+				fatal("Startup failed", "err", err)
+			}
+			if names, err = loadDNS(); err != nil {
+				fatal("Startup failed", "err", err)
+			}
+			if monitor, err = loadRouteMonitor(); err != nil {
+				fatal("Startup failed", "err", err)
+			}
+			if flows, err = loadFlowExport(); err != nil {
+				fatal("Startup failed", "err", err)
+			}
+			if probes, err = loadProbes(); err != nil {
+				fatal("Startup failed", "err", err)
+			}
+			coalesce = loadCoalescer(viper.GetDuration("route_coalesce_window"))
+			if size := viper.GetInt("outbox_size"); size > 0 {
+				outbox = newOutbox(size)
+			} else {
+				fatal("outbox_size must be positive")
+			}
+
+			var trusted remote.Keys
+			if bundle := viper.GetString("route_trust_bundle"); bundle != "" {
+				if trusted, err = remote.LoadKeys(bundle); err != nil {
+					fatal("route_trust_bundle invalid", "err", err)
+				}
+				if fipsMode {
+					for id, key := range trusted {
+						if err := fips.CheckKey(key); err != nil {
+							fatal("route_trust_bundle key invalid", "key", id, "err", err)
 						}
-						r.Send(payload)
 					}
-					return nil
-				},
-				DeregisterHandler: func(vpc, vpcAttachment string, networks []string) error {
-					srv6_endpoint, err := util.EncodeSRv6Endpoint(viper.GetString("srv6_net"), vpc, vpcAttachment)
-					if err != nil {
-						return err
+				}
+			}
+
+			replays := &replay.Guard{Window: viper.GetDuration("replay_window")}
+
+			segmentAllowlist, err := allowlist.Parse(viper.GetStringSlice("segment_allowlist"))
+			if err != nil {
+				fatal("segment_allowlist invalid", "err", err)
+			}
+
+			underlayMTU := viper.GetInt("underlay_mtu")
+			if underlayMTU != 0 && underlayMTU < minMTU+depth.Overhead(1) {
+				fatal("underlay_mtu invalid: leaves no room for one segment", "underlay_mtu", underlayMTU)
+			}
+
+			duplicates := viper.GetString("duplicate_networks")
+			if duplicates != duplicateReject && duplicates != duplicateReplace {
+				fatal("duplicate_networks invalid", "duplicate_networks", duplicates)
+			}
+
+			var routeQuota quota.Limits
+			if err := viper.UnmarshalKey("route_quota", &routeQuota); err != nil {
+				fatal("route_quota invalid", "err", err)
+			}
+
+			var prefixPolicies prefixpolicy.Policies
+			if err := viper.UnmarshalKey("prefix_policy", &prefixPolicies); err != nil {
+				fatal("prefix_policy invalid", "err", err)
+			}
+			if err := prefixPolicies.Normalize(); err != nil {
+				fatal("prefix_policy invalid", "err", err)
+			}
+
+			vpcDSCP, err := loadVPCDSCP()
+			if err != nil {
+				fatal("vpc_dscp invalid", "err", err)
+			}
+			for _, d := range domains {
+				markAttachments(vpcDSCP, d.store.Snapshot().Attachments)
+			}
+
+			batchChunk := viper.GetInt("route_batch_chunk")
+			if batchChunk <= 0 {
+				fatal("route_batch_chunk must be positive")
+			}
+			credKey, err := credentialKey()
+			if err != nil {
+				fatal("Startup failed", "err", err)
+			}
+			h := &handlers{
+				registerLimit:    registerLimit,
+				routeLimit:       routeLimit,
+				duplicates:       duplicates,
+				vpcDSCP:          vpcDSCP,
+				aliases:          aliases,
+				prefixPolicies:   prefixPolicies,
+				segmentAllowlist: segmentAllowlist,
+				underlayMTU:      underlayMTU,
+				routeQuota:       routeQuota,
+				batchChunk:       batchChunk,
+				trusted:          trusted,
+				replays:          replays,
+				credKey:          credKey,
+				fipsMode:         fipsMode,
+				envelopes:        sync.Pool{New: func() any { return &remote.Envelope{} }},
+			}
+
+			l = local.Local{
+				SocketPath:        viper.GetString("socket_path"),
+				DrainTimeout:      drainTimeout,
+				RegisterHandler:   h.register,
+				DeregisterHandler: h.deregister,
+				AllocateHandler: func(vpc, owner string) (string, error) {
+					vpcAttachment, err := domainFor(vpc).allocator.Allocate(vpc, owner)
+					switch {
+					case errors.Is(err, endpoint.ErrMalformedID), errors.Is(err, ipam.ErrNoOwner):
+						return "", status.Error(codes.InvalidArgument, err.Error())
+					case errors.Is(err, ipam.ErrExhausted):
+						return "", status.Error(codes.FailedPrecondition, err.Error())
+					case err != nil:
+						return "", err
 					}
-					if err := srv6.RouteIngressDel(srv6_endpoint); err != nil {
-						return err
+					slog.Info("ALLOCATE", "vpc", vpc, "vpcattachment", vpcAttachment, "owner", owner)
+					return vpcAttachment, nil
+				},
+				ListHandler: func(vpc string) ([]*local.Attachment, error) {
+					attachments, err := listAttachments(vpc)
+					if errors.Is(err, endpoint.ErrMalformedID) {
+						return nil, status.Error(codes.InvalidArgument, err.Error())
 					}
-					for _, n := range networks {
-						log.Printf("DEREGISTER: network='%s', srv6_endpoint='%s'", n, srv6_endpoint)
-						payload, err := proto.Marshal(&remote.Envelope{
-							Kind: &remote.Envelope_Deregister{
-								Deregister: &remote.Deregister{
-									Network:      n,
-									Srv6Endpoint: srv6_endpoint,
-								},
-							},
-						})
-						if err != nil {
-							return err
-						}
-						r.Send(payload)
+					return attachments, err
+				},
+				Policy:        policy,
+				ProbeHandler:  traceProbe,
+				RoutesHandler: getRoutes,
+				StatusHandler: agentStatus,
+				ReleaseHandler: func(vpc, vpcAttachment string) error {
+					err := domainFor(vpc).allocator.Release(vpc, vpcAttachment)
+					if errors.Is(err, endpoint.ErrMalformedID) {
+						return status.Error(codes.InvalidArgument, err.Error())
 					}
-					return nil
+					return err
 				},
 			}
 
-			r = remote.Remote{
-				URL:      viper.GetString("mqtt_url"),
-				ClientID: viper.GetString("mqtt_clientid"),
-				Username: viper.GetString("mqtt_username"),
-				Password: viper.GetString("mqtt_password"),
-				QoS:      byte(viper.GetInt("mqtt_qos")),
-				TopicRX:  viper.GetString("mqtt_topic_receive"),
-				TopicTX:  viper.GetString("mqtt_topic_send"),
-				ReceiveHandler: func(payload []byte) error {
-					envelope := &remote.Envelope{}
-					if err := proto.Unmarshal(payload, envelope); err != nil {
-						return err
+			def := domains[tenantMap.All()[0]]
+			mqttRemote := &remote.Remote{
+				URL:            def.MQTTURL,
+				ClientID:       clientID(def.MQTTClientID),
+				Username:       def.MQTTUsername,
+				Password:       def.MQTTPassword,
+				QoS:            byte(viper.GetInt("mqtt_qos")),
+				TopicRX:        def.MQTTTopicReceive,
+				TopicTX:        def.MQTTTopicSend,
+				TopicTelemetry: def.MQTTTopicTelemetry,
+				ReceiveHandler: h.receive(def),
+				DrainTimeout:   drainTimeout,
+
+				ReconnectInterval: viper.GetDuration("mqtt_reconnect_interval"),
+			}
+			def.remote = mqttRemote
+
+			r = tenantTransport{}
+
+			// with a workload identity the SVID authenticates the agent to
+			// the broker and signs everything it sends
+			var svids *identity.Source
+			if viper.GetBool("spiffe_enabled") {
+				svids = &identity.Source{Socket: viper.GetString("spiffe_socket"), OnRotate: reconnectAll}
+				mqttRemote.TLSConfig = svids.TLSConfig()
+				mqttRemote.Signer = svids.Sign
+			}
+
+			var certs *tlsreload.Watcher
+			if certFile := viper.GetString("mqtt_tls_cert_file"); certFile != "" {
+				if svids != nil {
+					fatal("mqtt_tls_cert_file and spiffe_enabled are mutually exclusive")
+				}
+				certs, err = tlsreload.New(certFile, viper.GetString("mqtt_tls_key_file"), viper.GetString("mqtt_tls_ca_file"))
+				if err != nil {
+					fatal("MQTT TLS", "err", err)
+				}
+				certs.OnChange = reconnectAll
+				mqttRemote.TLSConfig = certs.ClientConfig()
+			}
+
+			// a bare signing key gives end-to-end authenticity without a
+			// workload identity
+			if keyFile := viper.GetString("signing_key_file"); keyFile != "" {
+				if svids != nil {
+					fatal("signing_key_file and spiffe_enabled are mutually exclusive")
+				}
+				key, err := remote.LoadSigningKey(keyFile)
+				if err != nil {
+					fatal("Signing key", "err", err)
+				}
+				if fipsMode {
+					if err := fips.CheckKey(key.Public()); err != nil {
+						fatal("Signing key", "err", err)
 					}
-					switch kind := envelope.Kind.(type) {
-					case *remote.Envelope_Route:
-						log.Printf("ROUTE: status='%s', network='%s', srv6_endpoint='%s', srv6_segments='%s'", kind.Route.Status, kind.Route.Network, kind.Route.Srv6Endpoint, kind.Route.Srv6Segments)
-						switch kind.Route.Status {
-						case remote.Route_ADD:
-							if err := srv6.RouteEgressAdd(kind.Route.Network, kind.Route.Srv6Endpoint, kind.Route.Srv6Segments); err != nil {
-								return err
-							}
-						case remote.Route_DELETE:
-							if err := srv6.RouteEgressDel(kind.Route.Network, kind.Route.Srv6Endpoint, kind.Route.Srv6Segments); err != nil {
+				}
+				keyID := viper.GetString("signing_key_id")
+				if keyID == "" {
+					if keyID, err = remote.KeyID(key.Public()); err != nil {
+						fatal("Signing key", "err", err)
+					}
+				}
+				slog.Info("Signing envelopes", "key", keyID)
+				mqttRemote.Signer = func(envelope *remote.Envelope) error {
+					return remote.SignWithKey(envelope, keyID, key)
+				}
+			}
+
+			if fipsMode {
+				mqttRemote.TLSConfig = fips.RestrictTLS(mqttRemote.TLSConfig)
+				if svids != nil {
+					// SVIDs rotate, so their keys are checked at each use
+					mqttRemote.Signer = func(envelope *remote.Envelope) error {
+						if svid := svids.SVID(); svid != nil {
+							if err := fips.CheckKey(svid.Certificate.Leaf.PublicKey); err != nil {
 								return err
 							}
 						}
+						return svids.Sign(envelope)
 					}
-					return nil
-				},
+				}
+			}
+
+			// the other tenants' remotes use the host's identity too
+			for _, t := range tenantMap.All()[1:] {
+				d := domains[t]
+				d.remote = &remote.Remote{
+					URL:            t.MQTTURL,
+					ClientID:       clientID(t.MQTTClientID),
+					Username:       t.MQTTUsername,
+					Password:       t.MQTTPassword,
+					QoS:            mqttRemote.QoS,
+					TopicRX:        t.MQTTTopicReceive,
+					TopicTX:        t.MQTTTopicSend,
+					TopicTelemetry: t.MQTTTopicTelemetry,
+					TLSConfig:      mqttRemote.TLSConfig,
+					Signer:         mqttRemote.Signer,
+					ReceiveHandler: h.receive(d),
+					DrainTimeout:   drainTimeout,
+
+					ReconnectInterval: mqttRemote.ReconnectInterval,
+				}
+				slog.Info("Tenant", "tenant", t.Name, "vpcs", t.VPCs, "srv6_net", t.SRv6Net, "broker", t.MQTTURL)
 			}
 
+			if credKey != nil {
+				for _, t := range tenantMap.All() {
+					if err := loadCredentials(domains[t].remote, t.CredentialsPath, credKey, fipsMode); err != nil {
+						fatal("Rotated credentials", "tenant", t.Name, "err", err)
+					}
+				}
+			}
+
+			id, err := agentID()
+			if err != nil {
+				fatal("Agent ID", "err", err)
+			}
+			slog.Info("Agent ID", "id", id)
+			features := agentFeatures(credKey != nil && trusted != nil)
+			spans := loadTracing(id)
+
 			g, ctx := errgroup.WithContext(ctx)
+			if svids != nil {
+				g.Go(func() error {
+					return svids.Run(ctx)
+				})
+			}
+			if certs != nil {
+				g.Go(func() error {
+					return certs.Run(ctx)
+				})
+			}
+			g.Go(func() error {
+				return linkcache.Links.Run(ctx)
+			})
+			g.Go(func() error {
+				return routecache.Routes.Run(ctx)
+			})
+			g.Go(func() error {
+				startup.run(viper.GetInt("replay_workers"), viper.GetBool("replay_prune"))
+				return nil
+			})
+			// on shutdown the local API stops first, then the outbox drains
+			// into the remotes, which flush and disconnect last
+			served := make(chan struct{})
+			remoteCtx, stopRemotes := context.WithCancel(context.WithoutCancel(ctx))
+			// each controller learns of the agent whenever it connects,
+			// and the WatchEvents subscribers of every connect and drop too
+			for _, t := range tenantMap.All() {
+				d := domains[t]
+				hello := helloSender(remoteCtx, d, id, features)
+				d.remote.OnConnect = func() {
+					publishConnected(d)
+					hello()
+				}
+				d.remote.OnConnectionLost = func(err error) {
+					publishError(d.Name, "mqtt", err)
+				}
+			}
 			g.Go(func() error {
+				defer stopRemotes()
+				return outbox.run(ctx, served, drainTimeout)
+			})
+			g.Go(func() error {
+				defer close(served)
 				return l.Serve(ctx)
 			})
 			g.Go(func() error {
-				return r.Run(ctx)
+				return reloadOnHangup(ctx)
+			})
+			g.Go(func() error {
+				return notifySystemd(ctx)
+			})
+			g.Go(func() error {
+				if svids != nil {
+					if err := svids.Wait(ctx); err != nil {
+						return nil
+					}
+				}
+				// routes the controller sends must not be overtaken by
+				// stale ones from the store
+				if err := startup.wait(ctx); err != nil {
+					return nil
+				}
+				return r.Run(remoteCtx)
 			})
+			if telemetry != nil {
+				if gnmiCerts != nil {
+					g.Go(func() error {
+						return gnmiCerts.Run(ctx)
+					})
+				}
+				g.Go(func() error {
+					return telemetry.Serve(ctx)
+				})
+			}
+			if netconfCerts != nil {
+				g.Go(func() error {
+					return netconfCerts.Run(ctx)
+				})
+			}
+			if nc != nil {
+				g.Go(func() error {
+					return nc.Serve(ctx)
+				})
+			}
+			if rc != nil {
+				g.Go(func() error {
+					return rc.Serve(ctx)
+				})
+			}
+			if routing != nil {
+				g.Go(func() error {
+					return routing.run(ctx)
+				})
+			}
+			g.Go(func() error {
+				return routeCounts.run(ctx)
+			})
+			if names != nil {
+				g.Go(func() error {
+					return names.run(ctx)
+				})
+			}
+			if monitor != nil {
+				g.Go(func() error {
+					return monitor.run(ctx)
+				})
+			}
+			if flows != nil {
+				g.Go(func() error {
+					return flows.run(ctx)
+				})
+			}
+			if probes != nil {
+				g.Go(func() error {
+					return probes.run(ctx)
+				})
+			}
+			if subagent != nil {
+				g.Go(func() error {
+					return subagent.Run(ctx)
+				})
+			}
+			if windows != nil {
+				s := &companion.Server{
+					Addr:              viper.GetString("companion_addr"),
+					Token:             viper.GetString("companion_token"),
+					RoutesHandler:     windows.routeSet,
+					InterfacesHandler: windows.report,
+				}
+				g.Go(func() error {
+					return s.Serve(ctx)
+				})
+				g.Go(func() error {
+					return windows.run(ctx, s)
+				})
+			}
+			if interval := viper.GetDuration("usage_interval"); interval > 0 {
+				g.Go(func() error {
+					return heartbeat(ctx, interval)
+				})
+			}
+			if addr := viper.GetString("metrics_addr"); addr != "" {
+				g.Go(func() error {
+					return metrics.Serve(ctx, addr)
+				})
+			}
+			if addr := viper.GetString("dashboard_addr"); addr != "" {
+				g.Go(func() error {
+					return dashboard.Serve(ctx, addr, dashboardSnapshot)
+				})
+			}
+			if addr, socket := viper.GetString("admin_addr"), viper.GetString("admin_socket"); addr != "" || socket != "" {
+				g.Go(func() error {
+					return admin.Serve(ctx, addr, socket, adminSource())
+				})
+			}
+			if spans != nil {
+				g.Go(func() error {
+					return spans.Run(ctx)
+				})
+			}
+			if addr := viper.GetString("pprof_addr"); addr != "" {
+				slog.Warn("Profiling enabled", "addr", addr)
+				g.Go(func() error {
+					return metrics.ServePprof(ctx, addr)
+				})
+			}
+			if addr := viper.GetString("probe_addr"); addr != "" {
+				g.Go(func() error {
+					return metrics.ServeProbes(ctx, addr, readyChecks())
+				})
+			}
 			if err := g.Wait(); err != nil {
-				log.Printf("Error: %v", err)
+				slog.Error("Error", "err", err)
+			}
+			// routes received in the last window are not lost on shutdown
+			if coalesce != nil {
+				coalesce.flush()
 			}
-			log.Printf("Shutdown")
+			slog.Info("Shutdown")
 		},
 	}
 	cmd.PersistentFlags().StringVar(&configFile, "config", "", "config file")
+	cmd.PersistentFlags().String("profile", "", "profile of the config file to apply over its top-level settings")
+	viper.BindPFlag("profile", cmd.PersistentFlags().Lookup("profile")) //nolint:errcheck
+	cmd.PersistentFlags().String("state-dir", "", "directory of the state, IPAM and credentials files (default /var/lib/galactic)")
+	viper.BindPFlag("state_dir", cmd.PersistentFlags().Lookup("state-dir")) //nolint:errcheck
+	cmd.Flags().Bool("node-mode", false, "run as a Kubernetes DaemonSet pod, named after $NODE_NAME")
+	viper.BindPFlag("node_mode", cmd.Flags().Lookup("node-mode")) //nolint:errcheck
+	cmd.Flags().Bool("dry-run", false, "log the routes and neighbors the agent would program instead of programming them")
+	viper.BindPFlag("dry_run", cmd.Flags().Lookup("dry-run")) //nolint:errcheck
+	cmd.Flags().String("pprof-addr", "", "serve net/http/pprof on this address, e.g. localhost:6060")
+	viper.BindPFlag("pprof_addr", cmd.Flags().Lookup("pprof-addr")) //nolint:errcheck
+	cmd.Flags().Bool("route-monitor", false, "stream kernel route changes in attachment VRFs to the controller")
+	viper.BindPFlag("route_monitor", cmd.Flags().Lookup("route-monitor")) //nolint:errcheck
+	cmd.AddCommand(newAPILoadCmd())
+	cmd.AddCommand(newBenchCmd())
+	cmd.AddCommand(newConformanceCmd())
+	cmd.AddCommand(newE2ETestCmd())
+	cmd.AddCommand(newEnrollCmd())
+	cmd.AddCommand(newFixturesCmd())
+	cmd.AddCommand(newHookCmd())
+	cmd.AddCommand(newLoadgenCmd())
+	cmd.AddCommand(newPreflightCmd())
+	cmd.AddCommand(newProbeCmd())
+	cmd.AddCommand(newRecordCmd())
+	cmd.AddCommand(newReplayCmd())
+	cmd.AddCommand(newRoutesCmd())
+	cmd.AddCommand(newStatusCmd())
+	cmd.AddCommand(newVersionCmd())
 	cmd.SetArgs(os.Args[1:])
 	if err := cmd.Execute(); err != nil {
-		log.Fatalf("Execution failed: %v", err)
+		fatal("Execution failed", "err", err)
 	}
 }
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/spf13/viper"
+
+	"github.com/datum-cloud/galactic-agent/api/remote"
+	"github.com/datum-cloud/galactic-agent/srv6/endpoint"
+	"github.com/datum-cloud/galactic-agent/srv6/trafficclass"
+	"github.com/datum-cloud/galactic-agent/state"
+)
+
+// loadVPCDSCP reads vpc_dscp, the DSCP of all traffic of a VPC's
+// attachments, by padded VPC id.
+func loadVPCDSCP() (map[string]uint8, error) {
+	var raw map[string]int
+	if err := viper.UnmarshalKey("vpc_dscp", &raw); err != nil {
+		return nil, err
+	}
+	dscps := make(map[string]uint8, len(raw))
+	for vpc, dscp := range raw {
+		padded, _, err := endpoint.PadHex(vpc, "0")
+		if err != nil {
+			return nil, err
+		}
+		if dscp < 0 || dscp > trafficclass.MaxDSCP {
+			return nil, fmt.Errorf("vpc %s: dscp %d out of range 0-%d", vpc, dscp, trafficclass.MaxDSCP)
+		}
+		dscps[padded] = uint8(dscp)
+	}
+	return dscps, nil
+}
+
+// markAttachment applies the DSCP configured for vpc to the attachment of
+// srv6Endpoint, or removes marking left from an earlier configuration.
+func markAttachment(dscps map[string]uint8, vpc, srv6Endpoint string) error {
+	if padded, _, err := endpoint.PadHex(vpc, "0"); err == nil {
+		vpc = padded
+	}
+	if dscp, ok := dscps[vpc]; ok {
+		return dp.SetDSCP(srv6Endpoint, "", dscp)
+	}
+	return dp.ClearDSCP(srv6Endpoint, "")
+}
+
+// markAttachments applies vpc_dscp to the attachments registered before
+// the agent started, so configuration changes take effect on restart.
+func markAttachments(dscps map[string]uint8, attachments []state.Attachment) {
+	for _, a := range attachments {
+		if err := markAttachment(dscps, a.VPC, a.Endpoint); err != nil {
+			slog.Error("DSCP", append(endpointAttrs(a.Endpoint), "err", err)...)
+		}
+	}
+}
+
+// checkDSCP refuses a route with a DSCP out of range before it is
+// programmed.
+func checkDSCP(route *remote.Route) error {
+	if route.Dscp > trafficclass.MaxDSCP {
+		return fmt.Errorf("dscp %d out of range 0-%d", route.Dscp, trafficclass.MaxDSCP)
+	}
+	return nil
+}
+
+// markRoute applies the DSCP of a received route, or removes the marking
+// of the route it replaces.
+func markRoute(store *state.Store, route *remote.Route) error {
+	if route.Dscp != 0 {
+		return dp.SetDSCP(route.Srv6Endpoint, route.Network, uint8(route.Dscp))
+	}
+	return unmarkRoute(store, route)
+}
+
+// unmarkRoute removes the marking of a route that had a DSCP.
+func unmarkRoute(store *state.Store, route *remote.Route) error {
+	if old, ok := store.Egress(route.Network, route.Srv6Endpoint); ok && old.DSCP != 0 {
+		return dp.ClearDSCP(route.Srv6Endpoint, route.Network)
+	}
+	return nil
+}
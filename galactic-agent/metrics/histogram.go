@@ -0,0 +1,100 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// LatencyBuckets are the bucket bounds, in seconds, for operations that
+// take from well under a millisecond to several seconds.
+var LatencyBuckets = []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Histogram counts observations in cumulative buckets, optionally split by
+// labels.
+type Histogram struct {
+	name    string
+	help    string
+	labels  []string
+	buckets []float64
+
+	mu     sync.Mutex
+	series map[string]*histogramSeries
+}
+
+type histogramSeries struct {
+	counts []uint64 // per bucket, not cumulative
+	count  uint64
+	sum    float64
+}
+
+// NewHistogram registers a histogram with the upper bounds buckets, in
+// increasing order; the +Inf bucket is implied.
+func NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	h := &Histogram{name: name, help: help, labels: labels, buckets: buckets, series: make(map[string]*histogramSeries)}
+	register(h)
+	return h
+}
+
+// Observe records value.
+func (h *Histogram) Observe(value float64, labelValues ...string) {
+	k := labelKey(h.name, h.labels, labelValues)
+	i := sort.SearchFloat64s(h.buckets, value)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s, ok := h.series[k]
+	if !ok {
+		s = &histogramSeries{counts: make([]uint64, len(h.buckets))}
+		h.series[k] = s
+	}
+	if i < len(h.buckets) {
+		s.counts[i]++
+	}
+	s.count++
+	s.sum += value
+}
+
+func (h *Histogram) write(w io.Writer) error {
+	h.mu.Lock()
+	keys := make([]string, 0, len(h.series))
+	series := make(map[string]histogramSeries, len(h.series))
+	for k, s := range h.series {
+		keys = append(keys, k)
+		c := *s
+		c.counts = append([]uint64(nil), s.counts...)
+		series[k] = c
+	}
+	h.mu.Unlock()
+	sort.Strings(keys)
+
+	if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", h.name, h.help, h.name); err != nil {
+		return err
+	}
+	for _, k := range keys {
+		s := series[k]
+		prefix := k
+		if prefix != "" {
+			prefix += ","
+		}
+		var cumulative uint64
+		for i, b := range h.buckets {
+			cumulative += s.counts[i]
+			if _, err := fmt.Fprintf(w, "%s_bucket{%sle=%q} %d\n", h.name, prefix, strconv.FormatFloat(b, 'g', -1, 64), cumulative); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintf(w, "%s_bucket{%sle=\"+Inf\"} %d\n", h.name, prefix, s.count); err != nil {
+			return err
+		}
+		labels := ""
+		if k != "" {
+			labels = "{" + k + "}"
+		}
+		if _, err := fmt.Fprintf(w, "%s_sum%s %g\n%s_count%s %d\n", h.name, labels, s.sum, h.name, labels, s.count); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,256 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The agent exposes a handful of counters, gauges and histograms in the
+// Prometheus text format. This is intentionally tiny: metrics are registered
+// once at package init time and never removed.
+
+type metric interface {
+	write(w io.Writer) error
+}
+
+var (
+	registryMu sync.Mutex
+	registry   []metric
+)
+
+func register(m metric) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry = append(registry, m)
+}
+
+type vec struct {
+	name   string
+	help   string
+	kind   string
+	labels []string
+
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+func newVec(kind, name, help string, labels []string) *vec {
+	v := &vec{name: name, help: help, kind: kind, labels: labels, values: make(map[string]float64)}
+	register(v)
+	return v
+}
+
+func (v *vec) key(labelValues []string) string {
+	return labelKey(v.name, v.labels, labelValues)
+}
+
+// labelKey renders the labels of a series of the metric name as they
+// appear between its braces.
+func labelKey(name string, labels, labelValues []string) string {
+	if len(labelValues) != len(labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", name, len(labels), len(labelValues)))
+	}
+	pairs := make([]string, len(labels))
+	for i, l := range labels {
+		pairs[i] = fmt.Sprintf("%s=%q", l, labelValues[i])
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (v *vec) add(delta float64, labelValues []string) {
+	k := v.key(labelValues)
+	v.mu.Lock()
+	v.values[k] += delta
+	v.mu.Unlock()
+}
+
+func (v *vec) set(value float64, labelValues []string) {
+	k := v.key(labelValues)
+	v.mu.Lock()
+	v.values[k] = value
+	v.mu.Unlock()
+}
+
+func (v *vec) delete(labelValues []string) {
+	k := v.key(labelValues)
+	v.mu.Lock()
+	delete(v.values, k)
+	v.mu.Unlock()
+}
+
+func (v *vec) get(labelValues []string) float64 {
+	k := v.key(labelValues)
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.values[k]
+}
+
+func (v *vec) write(w io.Writer) error {
+	v.mu.Lock()
+	keys := make([]string, 0, len(v.values))
+	for k := range v.values {
+		keys = append(keys, k)
+	}
+	values := make(map[string]float64, len(v.values))
+	for k, val := range v.values {
+		values[k] = val
+	}
+	v.mu.Unlock()
+	sort.Strings(keys)
+
+	if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", v.name, v.help, v.name, v.kind); err != nil {
+		return err
+	}
+	for _, k := range keys {
+		name := v.name
+		if k != "" {
+			name += "{" + k + "}"
+		}
+		if _, err := fmt.Fprintf(w, "%s %g\n", name, values[k]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Counter is a monotonically increasing value, optionally split by labels.
+type Counter struct{ v *vec }
+
+func NewCounter(name, help string, labels ...string) *Counter {
+	return &Counter{newVec("counter", name, help, labels)}
+}
+
+func (c *Counter) Inc(labelValues ...string) { c.v.add(1, labelValues) }
+
+func (c *Counter) Add(delta float64, labelValues ...string) { c.v.add(delta, labelValues) }
+
+func (c *Counter) Get(labelValues ...string) float64 { return c.v.get(labelValues) }
+
+// Gauge is a value that can go up and down, optionally split by labels.
+type Gauge struct{ v *vec }
+
+func NewGauge(name, help string, labels ...string) *Gauge {
+	return &Gauge{newVec("gauge", name, help, labels)}
+}
+
+func (g *Gauge) Set(value float64, labelValues ...string) { g.v.set(value, labelValues) }
+
+func (g *Gauge) Add(delta float64, labelValues ...string) { g.v.add(delta, labelValues) }
+
+func (g *Gauge) Get(labelValues ...string) float64 { return g.v.get(labelValues) }
+
+// Delete drops the series of labelValues, e.g. of an attachment that is
+// gone, rather than leaving it at its last value.
+func (g *Gauge) Delete(labelValues ...string) { g.v.delete(labelValues) }
+
+// Write renders every registered metric in the Prometheus text format.
+func Write(w io.Writer) error {
+	registryMu.Lock()
+	metrics := append([]metric(nil), registry...)
+	registryMu.Unlock()
+	for _, m := range metrics {
+		if err := m.write(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		if err := Write(w); err != nil {
+			slog.Warn("Metrics write failed", "err", err)
+		}
+	})
+}
+
+// Serve exposes /metrics on addr until ctx is done.
+func Serve(ctx context.Context, addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Handler())
+	return serve(ctx, addr, mux, "Metrics", "/metrics")
+}
+
+// Check is a named readiness check.
+type Check struct {
+	Name string
+	Run  func() error
+}
+
+// ServeProbes exposes Kubernetes style probes on addr until ctx is done:
+// /healthz succeeds while the process serves, /readyz while every check
+// passes. /readyz lists the checks that fail, and with ?verbose those that
+// pass too, so a partial failure shows which part it is.
+func ServeProbes(ctx context.Context, addr string, checks []Check) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
+		io.WriteString(w, "ok\n") //nolint:errcheck
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		_, verbose := r.URL.Query()["verbose"]
+		var b strings.Builder
+		failed := false
+		for _, c := range checks {
+			if err := c.Run(); err != nil {
+				failed = true
+				fmt.Fprintf(&b, "[-]%s failed: %v\n", c.Name, err)
+			} else if verbose {
+				fmt.Fprintf(&b, "[+]%s ok\n", c.Name)
+			}
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		if failed {
+			b.WriteString("readyz check failed\n")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		} else {
+			b.WriteString("ok\n")
+		}
+		io.WriteString(w, b.String()) //nolint:errcheck
+	})
+	return serve(ctx, addr, mux, "Probes", "/healthz")
+}
+
+// ServePprof exposes the net/http/pprof profiles under /debug/pprof/ on
+// addr until ctx is done.
+func ServePprof(ctx context.Context, addr string) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	return serve(ctx, addr, mux, "Profiling", "/debug/pprof/")
+}
+
+func serve(ctx context.Context, addr string, handler http.Handler, what, path string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	s := &http.Server{Handler: handler, ReadHeaderTimeout: 5 * time.Second}
+
+	routineErr := make(chan error, 1)
+	go func() {
+		slog.Info(what+" listening", "address", "http://"+listener.Addr().String()+path)
+		if err := s.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			routineErr <- err
+			return
+		}
+		routineErr <- nil
+	}()
+
+	<-ctx.Done()
+	s.Close() //nolint:errcheck
+	return <-routineErr
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+
+	"github.com/spf13/viper"
+
+	"github.com/datum-cloud/galactic-agent/ratelimit"
+	"github.com/datum-cloud/galactic-agent/yang"
+)
+
+// agentLeaves walks the tenants' stores into the tree yang/galactic-agent.yang
+// describes:
+//
+//	/config/rate-limits/limit[operation=register|route]/{rate,burst}
+//	/state/tenants/tenant[name]/{broker,connected}
+//	/state/attachments/attachment[vpc][vpc-attachment]/{tenant,vrf,table,host-interface,srv6-endpoint,networks,mtu}
+//	/state/routes/route[srv6-endpoint][network]/{segments,dscp}
+//	/state/vpcs/vpc[id]/counters/{sent,received}-{bytes,packets,bytes-per-second}
+//	/state/imported-routes/route[vrf][prefix]/{protocol,next-hops,segments}
+func agentLeaves(limiters map[string]*ratelimit.Limiter) []yang.Leaf {
+	var leaves []yang.Leaf
+	add := func(path string, v any) {
+		leaves = append(leaves, yang.Leaf{Path: path, Value: v})
+	}
+
+	operations := make([]string, 0, len(limiters))
+	for op := range limiters {
+		operations = append(operations, op)
+	}
+	sort.Strings(operations)
+	for _, op := range operations {
+		rate, burst := limiters[op].Limits()
+		prefix := fmt.Sprintf("/config/rate-limits/limit[operation=%s]", op)
+		leaves = append(leaves,
+			yang.Leaf{Path: prefix + "/rate", Value: rate, Config: true},
+			yang.Leaf{Path: prefix + "/burst", Value: uint32(burst), Config: true})
+	}
+
+	vpcs := make(map[string]bool)
+	for _, t := range tenantMap.All() {
+		d := domains[t]
+		prefix := fmt.Sprintf("/state/tenants/tenant[name=%s]", t.Name)
+		add(prefix+"/broker", t.MQTTURL)
+		add(prefix+"/connected", d.remote.Connected())
+
+		st := d.store.Snapshot()
+		for _, a := range st.Attachments {
+			vpcs[a.VPC] = true
+			prefix := fmt.Sprintf("/state/attachments/attachment[vpc=%s][vpc-attachment=%s]", a.VPC, a.VPCAttachment)
+			add(prefix+"/tenant", t.Name)
+			add(prefix+"/vrf", a.VRF)
+			add(prefix+"/table", uint32(a.Table))
+			add(prefix+"/host-interface", a.Host)
+			add(prefix+"/srv6-endpoint", a.Endpoint)
+			add(prefix+"/networks", a.Networks)
+			if a.MTU != 0 {
+				add(prefix+"/mtu", uint32(a.MTU))
+			}
+		}
+		for _, e := range st.Egress {
+			prefix := fmt.Sprintf("/state/routes/route[srv6-endpoint=%s][network=%s]", e.Endpoint, e.Network)
+			add(prefix+"/segments", e.Segments)
+			if e.DSCP != 0 {
+				add(prefix+"/dscp", uint32(e.DSCP))
+			}
+		}
+	}
+
+	// counters are only kept while usage is metered
+	if viper.GetDuration("usage_interval") > 0 {
+		ids := make([]string, 0, len(vpcs))
+		for vpc := range vpcs {
+			ids = append(ids, vpc)
+		}
+		sort.Strings(ids)
+		for _, vpc := range ids {
+			prefix := fmt.Sprintf("/state/vpcs/vpc[id=%s]/counters", vpc)
+			for _, dir := range []string{"sent", "received"} {
+				add(prefix+"/"+dir+"-bytes", uint64(vpcBytes.Get(vpc, dir)))
+				add(prefix+"/"+dir+"-packets", uint64(vpcPackets.Get(vpc, dir)))
+				add(prefix+"/"+dir+"-bytes-per-second", vpcRate.Get(vpc, dir))
+			}
+		}
+	}
+
+	if routing != nil {
+		for _, r := range routing.routes() {
+			prefix := fmt.Sprintf("/state/imported-routes/route[vrf=%s][prefix=%s]", r.VRF, r.Prefix)
+			nexthops, segments := nexthopStrings(r)
+			add(prefix+"/protocol", r.Protocol)
+			add(prefix+"/next-hops", nexthops)
+			if len(segments) > 0 {
+				add(prefix+"/segments", segments)
+			}
+		}
+	}
+	return leaves
+}
+
+// setConfig applies rate limit updates once all of them are valid. Only
+// the rate limits can be set; changes last until the agent restarts. via
+// names the request in the log.
+func setConfig(limiters map[string]*ratelimit.Limiter, updates []yang.Leaf, via string) error {
+	type limits struct {
+		rate  float64
+		burst int
+	}
+	changed := make(map[string]*limits)
+	for _, u := range updates {
+		elems, err := yang.ParsePath(u.Path)
+		if err != nil {
+			return yang.Errorf(yang.TagInvalidValue, u.Path, "%v", err)
+		}
+		if len(elems) != 4 || elems[0].Name != "config" || elems[1].Name != "rate-limits" || elems[2].Name != "limit" || len(elems[2].Keys) != 1 {
+			return yang.Errorf(yang.TagInvalidValue, u.Path, "can't be set")
+		}
+		op := elems[2].Keys[0].Value
+		l, ok := limiters[op]
+		if !ok {
+			return yang.Errorf(yang.TagDataMissing, u.Path, "no rate limit for operation %q", op)
+		}
+		c := changed[op]
+		if c == nil {
+			rate, burst := l.Limits()
+			c = &limits{rate: rate, burst: burst}
+			changed[op] = c
+		}
+		v, err := number(u.Value)
+		if err != nil {
+			return yang.Errorf(yang.TagInvalidValue, u.Path, "%v", err)
+		}
+		switch elems[3].Name {
+		case "rate":
+			if v < 0 {
+				return yang.Errorf(yang.TagInvalidValue, u.Path, "negative rate")
+			}
+			c.rate = v
+		case "burst":
+			if v < 1 || v != float64(int(v)) {
+				return yang.Errorf(yang.TagInvalidValue, u.Path, "burst must be a positive integer")
+			}
+			c.burst = int(v)
+		default:
+			return yang.Errorf(yang.TagUnknownElement, u.Path, "can't be set")
+		}
+	}
+	for op, c := range changed {
+		limiters[op].SetRate(c.rate, c.burst)
+		rate, burst := limiters[op].Limits()
+		slog.Info("Rate limit", "via", via, "operation", op, "rate", rate, "burst", burst)
+	}
+	if len(changed) > 0 && telemetry != nil {
+		telemetry.Notify()
+	}
+	return nil
+}
+
+// number reads a numeric leaf: clients send strings.
+func number(v any) (float64, error) {
+	switch v := v.(type) {
+	case float64:
+		return v, nil
+	case string:
+		return strconv.ParseFloat(v, 64)
+	}
+	return 0, fmt.Errorf("not a number: %v", v)
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/spf13/viper"
+
+	"github.com/datum-cloud/galactic-agent/netconf"
+	"github.com/datum-cloud/galactic-agent/ratelimit"
+	"github.com/datum-cloud/galactic-agent/tlsreload"
+	"github.com/datum-cloud/galactic-agent/yang"
+)
+
+// loadNETCONF builds the NETCONF server from netconf_addr and the RESTCONF
+// server from restconf_addr; either is nil unless its address is set. They
+// share netconf_tls_cert_file, netconf_tls_key_file and netconf_tls_ca_file:
+// NETCONF needs all three, as RFC 7589 has clients authenticate with
+// certificates, and RESTCONF serves plaintext only on localhost. The
+// returned watcher, if any, has to run.
+func loadNETCONF(limiters map[string]*ratelimit.Limiter) (*netconf.Server, *netconf.RESTCONF, *tlsreload.Watcher, error) {
+	ncAddr, rcAddr := viper.GetString("netconf_addr"), viper.GetString("restconf_addr")
+	if ncAddr == "" && rcAddr == "" {
+		return nil, nil, nil, nil
+	}
+	data := func() ([]yang.Leaf, error) { return agentLeaves(limiters), nil }
+
+	var certs *tlsreload.Watcher
+	if certFile := viper.GetString("netconf_tls_cert_file"); certFile != "" {
+		var err error
+		certs, err = tlsreload.New(certFile, viper.GetString("netconf_tls_key_file"), viper.GetString("netconf_tls_ca_file"))
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("netconf tls: %w", err)
+		}
+	}
+
+	var nc *netconf.Server
+	if ncAddr != "" {
+		if certs == nil || viper.GetString("netconf_tls_ca_file") == "" {
+			return nil, nil, nil, errors.New("netconf_addr needs netconf_tls_cert_file, netconf_tls_key_file and netconf_tls_ca_file")
+		}
+		nc = &netconf.Server{
+			Addr:      ncAddr,
+			TLSConfig: certs.ServerConfig(),
+			Data:      data,
+			Edit:      func(updates []yang.Leaf) error { return setConfig(limiters, updates, "NETCONF EDIT") },
+		}
+	}
+
+	var rc *netconf.RESTCONF
+	if rcAddr != "" {
+		rc = &netconf.RESTCONF{
+			Addr: rcAddr,
+			Data: data,
+			Edit: func(updates []yang.Leaf) error { return setConfig(limiters, updates, "RESTCONF EDIT") },
+		}
+		if certs != nil {
+			rc.TLSConfig = certs.ServerConfig()
+		} else {
+			host, _, err := net.SplitHostPort(rcAddr)
+			if err != nil {
+				return nil, nil, nil, fmt.Errorf("restconf_addr invalid: %w", err)
+			}
+			if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+				return nil, nil, nil, errors.New("netconf_tls_cert_file is required unless restconf_addr is on localhost")
+			}
+		}
+	}
+	return nc, rc, certs, nil
+}
@@ -0,0 +1,529 @@
+// Package netconf serves the galactic-agent YANG tree to orchestrators:
+// NETCONF (RFC 6241) over mutually authenticated TLS (RFC 7589), and
+// RESTCONF (RFC 8040). Both read the tree from a Data function and hand
+// edits of it to an Edit function, which knows which leaves can change.
+package netconf
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/datum-cloud/galactic-agent/yang"
+)
+
+const (
+	baseNamespace       = "urn:ietf:params:xml:ns:netconf:base:1.0"
+	monitoringNamespace = "urn:ietf:params:xml:ns:yang:ietf-netconf-monitoring"
+	base10              = "urn:ietf:params:netconf:base:1.0"
+	base11              = "urn:ietf:params:netconf:base:1.1"
+	endOfMessage        = "]]>]]>"
+	maxMessageLength    = 1 << 20
+)
+
+// capabilities are what the server announces in its hello.
+var capabilities = []string{
+	base10,
+	base11,
+	"urn:ietf:params:netconf:capability:writable-running:1.0",
+	monitoringNamespace + "?module=ietf-netconf-monitoring&revision=2010-10-04",
+	yang.Namespace + "?module=" + yang.Name + "&revision=" + yang.Revision,
+}
+
+// Server serves NETCONF sessions on the running datastore: get,
+// get-config, edit-config, lock, unlock, close-session and get-schema.
+type Server struct {
+	Addr string
+	// TLSConfig has to require client certificates.
+	TLSConfig *tls.Config
+
+	// Data returns every leaf of the tree.
+	Data func() ([]yang.Leaf, error)
+	// Edit applies all of updates, or none of them and returns an error.
+	Edit func(updates []yang.Leaf) error
+
+	mu       sync.Mutex
+	sessions uint32
+	locked   uint32 // the session holding the running datastore's lock
+}
+
+func (s *Server) Serve(ctx context.Context) error {
+	listener, err := tls.Listen("tcp", s.Addr, s.TLSConfig)
+	if err != nil {
+		return err
+	}
+	slog.Info("NETCONF listening", "address", "tls://"+listener.Addr().String())
+
+	var wg sync.WaitGroup
+	routineErr := make(chan error, 1)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				routineErr <- err
+				return
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				s.session(ctx, conn)
+			}()
+		}
+	}()
+
+	select {
+	case <-ctx.Done():
+	case err := <-routineErr:
+		listener.Close() //nolint:errcheck
+		return err
+	}
+	listener.Close() //nolint:errcheck
+	wg.Wait()
+	slog.Info("NETCONF stopped")
+	return nil
+}
+
+// session serves one client until it closes the session, goes away or
+// ctx is done.
+func (s *Server) session(ctx context.Context, conn net.Conn) {
+	defer conn.Close() //nolint:errcheck
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close() //nolint:errcheck
+		case <-done:
+		}
+	}()
+
+	s.mu.Lock()
+	s.sessions++
+	id := s.sessions
+	s.mu.Unlock()
+	defer s.unlock(id) //nolint:errcheck
+
+	peer := conn.RemoteAddr().String()
+	if tc, ok := conn.(*tls.Conn); ok {
+		if err := tc.HandshakeContext(ctx); err != nil {
+			slog.Warn("NETCONF", "peer", peer, "err", err)
+			return
+		}
+		if certs := tc.ConnectionState().PeerCertificates; len(certs) > 0 {
+			peer = certs[0].Subject.CommonName
+		}
+	}
+
+	t := &transport{r: bufio.NewReader(conn), w: conn}
+	var hello bytes.Buffer
+	fmt.Fprintf(&hello, `<?xml version="1.0" encoding="UTF-8"?><hello xmlns="%s"><capabilities>`, baseNamespace)
+	for _, c := range capabilities {
+		hello.WriteString("<capability>")
+		xml.EscapeText(&hello, []byte(c)) //nolint:errcheck
+		hello.WriteString("</capability>")
+	}
+	fmt.Fprintf(&hello, "</capabilities><session-id>%d</session-id></hello>", id)
+	if err := t.write(hello.Bytes()); err != nil {
+		return
+	}
+	msg, err := t.read()
+	if err != nil {
+		return
+	}
+	var clientHello struct {
+		XMLName      xml.Name `xml:"hello"`
+		Capabilities []string `xml:"capabilities>capability"`
+	}
+	if err := xml.Unmarshal(msg, &clientHello); err != nil {
+		slog.Warn("NETCONF bad hello", "peer", peer, "err", err)
+		return
+	}
+	for _, c := range clientHello.Capabilities {
+		if strings.TrimSpace(c) == base11 {
+			t.chunked = true
+		}
+	}
+	slog.Info("NETCONF session opened", "session", id, "peer", peer)
+	defer slog.Info("NETCONF session closed", "session", id)
+
+	for {
+		msg, err := t.read()
+		if err != nil {
+			if !errors.Is(err, io.EOF) && ctx.Err() == nil {
+				slog.Warn("NETCONF session", "session", id, "err", err)
+			}
+			return
+		}
+		reply, closing := s.rpc(id, peer, msg)
+		if err := t.write(reply); err != nil || closing {
+			return
+		}
+	}
+}
+
+// element is any XML element, for reading requests.
+type element struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr `xml:",any,attr"`
+	Children []element  `xml:",any"`
+	Text     string     `xml:",chardata"`
+}
+
+func (e *element) child(name string) *element {
+	for i := range e.Children {
+		if e.Children[i].XMLName.Local == name {
+			return &e.Children[i]
+		}
+	}
+	return nil
+}
+
+func (e *element) attr(name string) string {
+	for _, a := range e.Attrs {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// rpc answers one message; closing is set once the session should end.
+func (s *Server) rpc(id uint32, peer string, msg []byte) (reply []byte, closing bool) {
+	var req element
+	if err := xml.Unmarshal(msg, &req); err != nil || req.XMLName.Local != "rpc" || len(req.Children) != 1 {
+		return s.reply(&req, nil, yang.Errorf(yang.TagMalformedMessage, "", "not a NETCONF rpc")), false
+	}
+	messageID := req.attr("message-id")
+	if messageID == "" {
+		return s.reply(&req, nil, yang.Errorf(yang.TagMissingElement, "", "message-id is missing")), false
+	}
+	op := &req.Children[0]
+	var body []byte
+	var err error
+	switch op.XMLName.Local {
+	case "get":
+		body, err = s.get(op, false)
+	case "get-config":
+		if err = running(op, "source"); err == nil {
+			body, err = s.get(op, true)
+		}
+	case "edit-config":
+		if err = running(op, "target"); err == nil {
+			err = s.editConfig(id, peer, op)
+		}
+	case "lock":
+		if err = running(op, "target"); err == nil {
+			err = s.lock(id)
+		}
+	case "unlock":
+		if err = running(op, "target"); err == nil {
+			err = s.unlock(id)
+		}
+	case "close-session":
+		closing = true
+	case "get-schema":
+		body, err = getSchema(op)
+	default:
+		err = yang.Errorf(yang.TagOperationNotSupported, "", "%s is not supported", op.XMLName.Local)
+	}
+	return s.reply(&req, body, err), closing
+}
+
+// running checks that the datastore named by op's field is running, the
+// only one there is.
+func running(op *element, field string) error {
+	ds := op.child(field)
+	if ds == nil {
+		return yang.Errorf(yang.TagMissingElement, "", "%s is missing", field)
+	}
+	if len(ds.Children) != 1 || ds.Children[0].XMLName.Local != "running" {
+		return yang.Errorf(yang.TagInvalidValue, "", "only the running datastore is supported")
+	}
+	return nil
+}
+
+func (s *Server) reply(req *element, body []byte, err error) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, `<rpc-reply xmlns="%s"`, baseNamespace)
+	if id := req.attr("message-id"); id != "" {
+		b.WriteString(` message-id="`)
+		xml.EscapeText(&b, []byte(id)) //nolint:errcheck
+		b.WriteString(`"`)
+	}
+	b.WriteString(">")
+	switch {
+	case err != nil:
+		var e *yang.Error
+		if !errors.As(err, &e) {
+			e = &yang.Error{Tag: "operation-failed", Message: err.Error()}
+		}
+		errorType := "application"
+		switch e.Tag {
+		case yang.TagMalformedMessage:
+			errorType = "rpc"
+		case yang.TagOperationNotSupported, yang.TagMissingElement:
+			errorType = "protocol"
+		}
+		fmt.Fprintf(&b, "<rpc-error><error-type>%s</error-type><error-tag>%s</error-tag><error-severity>error</error-severity>", errorType, e.Tag)
+		if e.Path != "" {
+			b.WriteString("<error-path>")
+			xml.EscapeText(&b, []byte(e.Path)) //nolint:errcheck
+			b.WriteString("</error-path>")
+		}
+		b.WriteString(`<error-message xml:lang="en">`)
+		xml.EscapeText(&b, []byte(e.Message)) //nolint:errcheck
+		b.WriteString("</error-message></rpc-error>")
+	case body != nil:
+		b.Write(body)
+	default:
+		b.WriteString("<ok/>")
+	}
+	b.WriteString("</rpc-reply>")
+	return b.Bytes()
+}
+
+// get answers get and get-config, applying a subtree filter if there is
+// one.
+func (s *Server) get(op *element, configOnly bool) ([]byte, error) {
+	root, err := tree(s.Data, configOnly)
+	if err != nil {
+		return nil, err
+	}
+	if f := op.child("filter"); f != nil {
+		if t := f.attr("type"); t != "" && t != "subtree" {
+			return nil, yang.Errorf(yang.TagOperationNotSupported, "", "%s filters are not supported", t)
+		}
+		filter := &yang.Node{Children: readNodes(f.Children, true)}
+		if len(filter.Children) == 0 {
+			root = &yang.Node{}
+		} else if root = yang.Filter(root, filter); root == nil {
+			root = &yang.Node{}
+		}
+	}
+	var b bytes.Buffer
+	b.WriteString("<data>")
+	if err := yang.WriteXML(&b, root.Children); err != nil {
+		return nil, err
+	}
+	b.WriteString("</data>")
+	return b.Bytes(), nil
+}
+
+// tree builds the tree Data returns, or only its config leaves.
+func tree(data func() ([]yang.Leaf, error), configOnly bool) (*yang.Node, error) {
+	leaves, err := data()
+	if err != nil {
+		return nil, err
+	}
+	if configOnly {
+		var config []yang.Leaf
+		for _, l := range leaves {
+			if l.Config {
+				config = append(config, l)
+			}
+		}
+		leaves = config
+	}
+	return yang.Build(leaves)
+}
+
+// readNodes reads elements into nodes. At the top, elements of other modules
+// are left out: they select nothing.
+func readNodes(elements []element, top bool) []*yang.Node {
+	var out []*yang.Node
+	for _, e := range elements {
+		if top && e.XMLName.Space != yang.Namespace {
+			continue
+		}
+		n := &yang.Node{Elem: yang.Elem{Name: e.XMLName.Local}}
+		if len(e.Children) == 0 {
+			if text := strings.TrimSpace(e.Text); text != "" {
+				n.Value = text
+			}
+		} else {
+			n.Children = readNodes(e.Children, false)
+		}
+		out = append(out, n)
+	}
+	return out
+}
+
+func (s *Server) editConfig(id uint32, peer string, op *element) error {
+	s.mu.Lock()
+	locked := s.locked
+	s.mu.Unlock()
+	if locked != 0 && locked != id {
+		return yang.Errorf(yang.TagInUse, "", "the running datastore is locked by session %d", locked)
+	}
+	if d := op.child("default-operation"); d != nil && strings.TrimSpace(d.Text) != "merge" {
+		return yang.Errorf(yang.TagOperationNotSupported, "", "default-operation %s is not supported", strings.TrimSpace(d.Text))
+	}
+	if t := op.child("test-option"); t != nil && strings.TrimSpace(t.Text) == "test-only" {
+		return yang.Errorf(yang.TagOperationNotSupported, "", "test-only is not supported")
+	}
+	config := op.child("config")
+	if config == nil {
+		return yang.Errorf(yang.TagMissingElement, "", "config is missing")
+	}
+	if err := checkOperations(config.Children); err != nil {
+		return err
+	}
+	for _, e := range config.Children {
+		if e.XMLName.Space != yang.Namespace {
+			return yang.Errorf(yang.TagUnknownNamespace, "", "unknown namespace %s", e.XMLName.Space)
+		}
+	}
+	updates, err := yang.Leaves(nil, readNodes(config.Children, true))
+	if err != nil {
+		return err
+	}
+	if err := s.Edit(updates); err != nil {
+		return err
+	}
+	slog.Info("NETCONF session edited", "session", id, "peer", peer, "leaves", len(updates))
+	return nil
+}
+
+// checkOperations refuses the operations other than merge: the tree's
+// shape is fixed, only values change.
+func checkOperations(elements []element) error {
+	for _, e := range elements {
+		for _, a := range e.Attrs {
+			if a.Name.Local != "operation" || a.Name.Space != baseNamespace {
+				continue
+			}
+			switch a.Value {
+			case "merge":
+			case "create":
+				return yang.Errorf(yang.TagDataExists, e.XMLName.Local, "%s already exists", e.XMLName.Local)
+			default:
+				return yang.Errorf(yang.TagOperationNotSupported, e.XMLName.Local, "operation %s is not supported", a.Value)
+			}
+		}
+		if err := checkOperations(e.Children); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Server) lock(id uint32) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.locked != 0 {
+		return yang.Errorf(yang.TagLockDenied, "", "locked by session %d", s.locked)
+	}
+	s.locked = id
+	return nil
+}
+
+func (s *Server) unlock(id uint32) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.locked != id {
+		return yang.Errorf(yang.TagOperationNotSupported, "", "the running datastore is not locked by this session")
+	}
+	s.locked = 0
+	return nil
+}
+
+// getSchema returns the module (RFC 6022 section 3.1).
+func getSchema(op *element) ([]byte, error) {
+	identifier := op.child("identifier")
+	if identifier == nil {
+		return nil, yang.Errorf(yang.TagMissingElement, "", "identifier is missing")
+	}
+	if strings.TrimSpace(identifier.Text) != yang.Name {
+		return nil, yang.Errorf(yang.TagInvalidValue, "", "no schema %s", strings.TrimSpace(identifier.Text))
+	}
+	if v := op.child("version"); v != nil && strings.TrimSpace(v.Text) != "" && strings.TrimSpace(v.Text) != yang.Revision {
+		return nil, yang.Errorf(yang.TagInvalidValue, "", "no revision %s", strings.TrimSpace(v.Text))
+	}
+	if f := op.child("format"); f != nil && !strings.HasSuffix(strings.TrimSpace(f.Text), "yang") {
+		return nil, yang.Errorf(yang.TagInvalidValue, "", "only the yang format is available")
+	}
+	var b bytes.Buffer
+	fmt.Fprintf(&b, `<data xmlns="%s">`, monitoringNamespace)
+	xml.EscapeText(&b, []byte(yang.Module)) //nolint:errcheck
+	b.WriteString("</data>")
+	return b.Bytes(), nil
+}
+
+// transport frames messages: with end-of-message markers until both ends
+// have said hello with base:1.1, with chunks after.
+type transport struct {
+	r       *bufio.Reader
+	w       io.Writer
+	chunked bool
+}
+
+func (t *transport) write(msg []byte) error {
+	if t.chunked {
+		_, err := fmt.Fprintf(t.w, "\n#%d\n%s\n##\n", len(msg), msg)
+		return err
+	}
+	_, err := fmt.Fprintf(t.w, "%s%s", msg, endOfMessage)
+	return err
+}
+
+func (t *transport) read() ([]byte, error) {
+	if t.chunked {
+		return t.readChunks()
+	}
+	var msg []byte
+	for {
+		b, err := t.r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		msg = append(msg, b)
+		if bytes.HasSuffix(msg, []byte(endOfMessage)) {
+			return msg[:len(msg)-len(endOfMessage)], nil
+		}
+		if len(msg) > maxMessageLength {
+			return nil, errors.New("message too long")
+		}
+	}
+}
+
+func (t *transport) readChunks() ([]byte, error) {
+	var msg []byte
+	for {
+		header, err := t.r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		if header == "\n" {
+			// the newline that starts every chunk header
+			if header, err = t.r.ReadString('\n'); err != nil {
+				return nil, err
+			}
+		}
+		size, ok := strings.CutPrefix(strings.TrimSuffix(header, "\n"), "#")
+		if !ok {
+			return nil, fmt.Errorf("bad chunk header %q", header)
+		}
+		if size == "#" {
+			return msg, nil
+		}
+		n, err := strconv.Atoi(size)
+		if err != nil || n < 1 || len(msg)+n > maxMessageLength {
+			return nil, fmt.Errorf("bad chunk size %q", size)
+		}
+		chunk := make([]byte, n)
+		if _, err := io.ReadFull(t.r, chunk); err != nil {
+			return nil, err
+		}
+		msg = append(msg, chunk...)
+	}
+}
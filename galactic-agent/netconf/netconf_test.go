@@ -0,0 +1,286 @@
+package netconf
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/datum-cloud/galactic-agent/yang"
+)
+
+// The client side below frames and writes its messages by hand, after the
+// examples of RFC 6241 and RFC 6242, and reads replies with its own
+// decoding rather than the server's.
+
+func testData() ([]yang.Leaf, error) {
+	return []yang.Leaf{
+		{Path: "/config/rate-limits/limit[operation=register]/rate", Value: float64(10), Config: true},
+		{Path: "/config/rate-limits/limit[operation=register]/burst", Value: uint32(20), Config: true},
+		{Path: "/state/tenants/tenant[name=t1]/connected", Value: true},
+	}, nil
+}
+
+// client is the orchestrator's end of a session.
+type client struct {
+	t       *testing.T
+	conn    net.Conn
+	r       *bufio.Reader
+	chunked bool
+}
+
+func (c *client) send(s string) {
+	c.t.Helper()
+	c.conn.SetWriteDeadline(time.Now().Add(5 * time.Second)) //nolint:errcheck
+	if _, err := io.WriteString(c.conn, s); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+// receive reads a message: up to ]]>]]>, or one chunk and the end of
+// chunks marker.
+func (c *client) receive() ([]byte, error) {
+	c.conn.SetReadDeadline(time.Now().Add(5 * time.Second)) //nolint:errcheck
+	if !c.chunked {
+		var msg []byte
+		for !bytes.HasSuffix(msg, []byte("]]>]]>")) {
+			b, err := c.r.ReadByte()
+			if err != nil {
+				return nil, err
+			}
+			msg = append(msg, b)
+		}
+		return msg[:len(msg)-6], nil
+	}
+	var n int
+	if _, err := fmt.Fscanf(c.r, "\n#%d\n", &n); err != nil {
+		return nil, err
+	}
+	msg := make([]byte, n)
+	if _, err := io.ReadFull(c.r, msg); err != nil {
+		return nil, err
+	}
+	end := make([]byte, 4)
+	if _, err := io.ReadFull(c.r, end); err != nil || string(end) != "\n##\n" {
+		return nil, fmt.Errorf("chunk ends with %q, %v", end, err)
+	}
+	return msg, nil
+}
+
+type reply struct {
+	XMLName   xml.Name  `xml:"urn:ietf:params:xml:ns:netconf:base:1.0 rpc-reply"`
+	MessageID string    `xml:"message-id,attr"`
+	OK        *struct{} `xml:"ok"`
+	Data      *struct {
+		Config *struct {
+			Limits []struct {
+				Operation string `xml:"operation"`
+				Rate      string `xml:"rate"`
+				Burst     string `xml:"burst"`
+			} `xml:"rate-limits>limit"`
+		} `xml:"urn:datum:yang:galactic-agent config"`
+		State *struct {
+			Tenants []struct {
+				Name      string `xml:"name"`
+				Connected string `xml:"connected"`
+			} `xml:"tenants>tenant"`
+		} `xml:"urn:datum:yang:galactic-agent state"`
+	} `xml:"data"`
+	Errors []struct {
+		Type string `xml:"error-type"`
+		Tag  string `xml:"error-tag"`
+	} `xml:"rpc-error"`
+}
+
+func (c *client) reply(messageID string) *reply {
+	c.t.Helper()
+	msg, err := c.receive()
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	var r reply
+	if err := xml.Unmarshal(msg, &r); err != nil {
+		c.t.Fatalf("%v: %s", err, msg)
+	}
+	if r.MessageID != messageID {
+		c.t.Errorf("reply to %q, want %q", r.MessageID, messageID)
+	}
+	return &r
+}
+
+// open starts a session and exchanges hellos, the client's announcing
+// capabilities.
+func open(t *testing.T, edits *[]yang.Leaf, capabilities ...string) *client {
+	t.Helper()
+	s := &Server{
+		Data: testData,
+		Edit: func(updates []yang.Leaf) error {
+			*edits = append(*edits, updates...)
+			return nil
+		},
+	}
+	serverConn, clientConn := net.Pipe()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.session(ctx, serverConn)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+		clientConn.Close() //nolint:errcheck
+	})
+
+	c := &client{t: t, conn: clientConn, r: bufio.NewReader(clientConn)}
+	msg, err := c.receive()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var hello struct {
+		XMLName      xml.Name `xml:"urn:ietf:params:xml:ns:netconf:base:1.0 hello"`
+		Capabilities []string `xml:"capabilities>capability"`
+		SessionID    string   `xml:"session-id"`
+	}
+	if err := xml.Unmarshal(msg, &hello); err != nil {
+		t.Fatalf("%v: %s", err, msg)
+	}
+	for _, want := range []string{"urn:ietf:params:netconf:base:1.0", "urn:ietf:params:netconf:base:1.1", "urn:datum:yang:galactic-agent?module=galactic-agent&revision=" + yang.Revision} {
+		if !slices.Contains(hello.Capabilities, want) {
+			t.Errorf("hello without %s: %v", want, hello.Capabilities)
+		}
+	}
+	if hello.SessionID != "1" {
+		t.Errorf("session-id %q", hello.SessionID)
+	}
+
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n" + `<hello xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">` + "\n  <capabilities>\n")
+	for _, cap := range capabilities {
+		b.WriteString("    <capability>" + cap + "</capability>\n")
+	}
+	b.WriteString("  </capabilities>\n</hello>\n]]>]]>")
+	c.send(b.String())
+	c.chunked = slices.Contains(capabilities, "urn:ietf:params:netconf:base:1.1")
+	return c
+}
+
+// chunk frames msg as chunks of at most size bytes.
+func chunk(msg string, size int) string {
+	var b strings.Builder
+	for len(msg) > 0 {
+		n := min(size, len(msg))
+		fmt.Fprintf(&b, "\n#%d\n%s", n, msg[:n])
+		msg = msg[n:]
+	}
+	return b.String() + "\n##\n"
+}
+
+func TestSessionInterop(t *testing.T) {
+	var edits []yang.Leaf
+	c := open(t, &edits, "urn:ietf:params:netconf:base:1.0", "urn:ietf:params:netconf:base:1.1")
+
+	// a message split across chunks
+	c.send(chunk(`<rpc message-id="101" xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">
+  <get-config>
+    <source><running/></source>
+  </get-config>
+</rpc>`, 16))
+	r := c.reply("101")
+	if r.Data == nil || r.Data.State != nil || r.Data.Config == nil || len(r.Data.Config.Limits) != 1 {
+		t.Fatalf("get-config data %+v", r.Data)
+	}
+	if l := r.Data.Config.Limits[0]; l.Operation != "register" || l.Rate != "10.000" || l.Burst != "20" {
+		t.Errorf("limit %+v", l)
+	}
+
+	c.send(chunk(`<rpc message-id="102" xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">
+  <get>
+    <filter type="subtree">
+      <state xmlns="urn:datum:yang:galactic-agent"><tenants/></state>
+    </filter>
+  </get>
+</rpc>`, 4096))
+	r = c.reply("102")
+	if r.Data == nil || r.Data.Config != nil || r.Data.State == nil || len(r.Data.State.Tenants) != 1 {
+		t.Fatalf("filtered get data %+v", r.Data)
+	}
+	if tenant := r.Data.State.Tenants[0]; tenant.Name != "t1" || tenant.Connected != "true" {
+		t.Errorf("tenant %+v", tenant)
+	}
+
+	c.send(chunk(`<rpc message-id="103" xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">
+  <edit-config>
+    <target><running/></target>
+    <config>
+      <config xmlns="urn:datum:yang:galactic-agent">
+        <rate-limits>
+          <limit>
+            <operation>register</operation>
+            <rate>5</rate>
+          </limit>
+        </rate-limits>
+      </config>
+    </config>
+  </edit-config>
+</rpc>`, 4096))
+	if r = c.reply("103"); r.OK == nil || len(r.Errors) != 0 {
+		t.Errorf("edit-config: %+v", r.Errors)
+	}
+	if len(edits) != 1 || edits[0].Path != "/config/rate-limits/limit[operation=register]/rate" || edits[0].Value != "5" {
+		t.Errorf("edits %v", edits)
+	}
+
+	// only merge is supported
+	edits = nil
+	c.send(chunk(`<rpc message-id="104" xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" xmlns:nc="urn:ietf:params:xml:ns:netconf:base:1.0">
+  <edit-config>
+    <target><running/></target>
+    <config>
+      <config xmlns="urn:datum:yang:galactic-agent">
+        <rate-limits nc:operation="replace"/>
+      </config>
+    </config>
+  </edit-config>
+</rpc>`, 4096))
+	if r = c.reply("104"); len(r.Errors) != 1 || r.Errors[0].Tag != "operation-not-supported" || len(edits) != 0 {
+		t.Errorf("replace: %+v, edits %v", r.Errors, edits)
+	}
+
+	c.send(chunk(`<rpc message-id="105" xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><kill-session><session-id>2</session-id></kill-session></rpc>`, 4096))
+	if r = c.reply("105"); len(r.Errors) != 1 || r.Errors[0].Type != "protocol" || r.Errors[0].Tag != "operation-not-supported" {
+		t.Errorf("kill-session: %+v", r.Errors)
+	}
+
+	c.send(chunk(`<rpc message-id="106" xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><close-session/></rpc>`, 4096))
+	if r = c.reply("106"); r.OK == nil {
+		t.Errorf("close-session: %+v", r.Errors)
+	}
+	// the server closed the connection
+	if _, err := c.receive(); !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("after close-session: %v", err)
+	}
+}
+
+// TestSessionBase10 checks a client without base:1.1 keeps the
+// end-of-message framing.
+func TestSessionBase10(t *testing.T) {
+	var edits []yang.Leaf
+	c := open(t, &edits, "urn:ietf:params:netconf:base:1.0")
+	c.send(`<?xml version="1.0" encoding="UTF-8"?>
+<rpc message-id="1" xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">
+  <get-config><source><running/></source></get-config>
+</rpc>
+]]>]]>`)
+	if r := c.reply("1"); r.Data == nil || r.Data.Config == nil {
+		t.Errorf("get-config data %+v", r.Data)
+	}
+}
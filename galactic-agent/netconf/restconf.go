@@ -0,0 +1,290 @@
+package netconf
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/datum-cloud/galactic-agent/yang"
+)
+
+const (
+	restconfRoot       = "/restconf"
+	mediaJSON          = "application/yang-data+json"
+	yangLibraryVersion = "2019-01-04"
+)
+
+// RESTCONF serves the tree over RESTCONF in JSON, the one encoding it
+// speaks: GET on {+restconf}/data and below, and plain PATCH, which
+// merges, on the config leaves.
+type RESTCONF struct {
+	Addr string
+	// TLSConfig secures the listener; nil serves plaintext.
+	TLSConfig *tls.Config
+
+	// Data returns every leaf of the tree.
+	Data func() ([]yang.Leaf, error)
+	// Edit applies all of updates, or none of them and returns an error.
+	Edit func(updates []yang.Leaf) error
+}
+
+func (rc *RESTCONF) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/host-meta", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/xrd+xml")
+		io.WriteString(w, `<XRD xmlns="http://docs.oasis-open.org/ns/xri/xrd-1.0"><Link rel="restconf" href="`+restconfRoot+`"/></XRD>`) //nolint:errcheck
+	})
+	mux.HandleFunc("GET "+restconfRoot+"/{$}", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{"ietf-restconf:restconf": map[string]any{
+			"data":                 map[string]any{},
+			"operations":           map[string]any{},
+			"yang-library-version": yangLibraryVersion,
+		}})
+	})
+	mux.HandleFunc("GET "+restconfRoot+"/yang-library-version", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{"ietf-restconf:yang-library-version": yangLibraryVersion})
+	})
+	mux.HandleFunc("GET "+restconfRoot+"/operations", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{"ietf-restconf:operations": map[string]any{}})
+	})
+	mux.HandleFunc(restconfRoot+"/data", rc.data)
+	mux.HandleFunc(restconfRoot+"/data/", rc.data)
+	return mux
+}
+
+func (rc *RESTCONF) data(w http.ResponseWriter, r *http.Request) {
+	path, err := resourcePath(strings.TrimPrefix(r.URL.EscapedPath(), restconfRoot+"/data"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		rc.get(w, r, path)
+	case http.MethodPatch:
+		rc.edit(w, r, path)
+	default:
+		w.Header().Set("Allow", "GET, HEAD, PATCH")
+		writeError(w, http.StatusMethodNotAllowed, yang.Errorf(yang.TagOperationNotSupported, "", "%s is not supported", r.Method))
+	}
+}
+
+// resourcePath parses the data resource path of RFC 8040 section 3.5.3:
+// /galactic-agent:state/attachments/attachment=vpc,attachment.
+func resourcePath(s string) ([]yang.Elem, error) {
+	var elems []yang.Elem
+	for _, segment := range strings.Split(strings.Trim(s, "/"), "/") {
+		if segment == "" {
+			continue
+		}
+		name, keys, hasKeys := strings.Cut(segment, "=")
+		if module, local, ok := strings.Cut(name, ":"); ok {
+			if module != yang.Name {
+				return nil, yang.Errorf(yang.TagUnknownNamespace, "", "unknown module %s", module)
+			}
+			name = local
+		} else if len(elems) == 0 {
+			return nil, yang.Errorf(yang.TagInvalidValue, "", "%s is not qualified with its module", name)
+		}
+		e := yang.Elem{Name: name}
+		if hasKeys {
+			names := yang.Lists[yang.SchemaPath(append(elems, e))]
+			values := strings.Split(keys, ",")
+			if len(values) != len(names) {
+				return nil, yang.Errorf(yang.TagInvalidValue, "", "%s takes keys %s", name, strings.Join(names, ","))
+			}
+			for i, v := range values {
+				v, err := url.PathUnescape(v)
+				if err != nil {
+					return nil, yang.Errorf(yang.TagInvalidValue, "", "%s: %v", name, err)
+				}
+				e.Keys = append(e.Keys, yang.Key{Name: names[i], Value: v})
+			}
+		}
+		elems = append(elems, e)
+	}
+	return elems, nil
+}
+
+func (rc *RESTCONF) get(w http.ResponseWriter, r *http.Request, path []yang.Elem) {
+	if !acceptsJSON(r) {
+		writeError(w, http.StatusNotAcceptable, yang.Errorf(yang.TagInvalidValue, "", "only %s is served", mediaJSON))
+		return
+	}
+	content := r.URL.Query().Get("content")
+	leaves, err := rc.Data()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	var selected []yang.Leaf
+	for _, l := range leaves {
+		switch {
+		case content == "config" && !l.Config, content == "nonconfig" && l.Config:
+			continue
+		}
+		selected = append(selected, l)
+	}
+	root, err := yang.Build(selected)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	target := root.Find(path)
+	if target == nil {
+		writeError(w, http.StatusNotFound, yang.Errorf(yang.TagInvalidValue, yang.PathString(path), "no such resource"))
+		return
+	}
+	if len(path) == 0 {
+		writeJSON(w, http.StatusOK, map[string]any{"ietf-restconf:data": root.JSON(true)})
+		return
+	}
+	writeJSON(w, http.StatusOK, target.MemberJSON())
+}
+
+// edit merges the body, which holds the target resource, into the tree.
+func (rc *RESTCONF) edit(w http.ResponseWriter, r *http.Request, path []yang.Elem) {
+	if mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mt != mediaJSON {
+		writeError(w, http.StatusUnsupportedMediaType, yang.Errorf(yang.TagInvalidValue, "", "only %s is accepted", mediaJSON))
+		return
+	}
+	d := json.NewDecoder(io.LimitReader(r.Body, maxMessageLength))
+	d.UseNumber()
+	var obj map[string]any
+	if err := d.Decode(&obj); err != nil {
+		writeError(w, http.StatusBadRequest, yang.Errorf(yang.TagMalformedMessage, "", "%v", err))
+		return
+	}
+	if data, ok := obj["ietf-restconf:data"].(map[string]any); ok && len(path) == 0 {
+		obj = data
+	}
+	nodes, err := yang.FromJSON(obj)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	var prefix []yang.Elem
+	if len(path) > 0 {
+		prefix = path[:len(path)-1]
+		last := path[len(path)-1]
+		for _, n := range nodes {
+			if n.Name != last.Name {
+				writeError(w, http.StatusBadRequest, yang.Errorf(yang.TagInvalidValue, yang.PathString(path), "the body holds %s, not %s", n.Name, last.Name))
+				return
+			}
+		}
+		if len(last.Keys) > 0 {
+			for _, n := range nodes {
+				for _, k := range last.Keys {
+					if !hasLeaf(n, k) {
+						writeError(w, http.StatusBadRequest, yang.Errorf(yang.TagInvalidValue, yang.PathString(path), "the body's %s does not match the URI", k.Name))
+						return
+					}
+				}
+			}
+		}
+	}
+	updates, err := yang.Leaves(prefix, nodes)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := rc.Edit(updates); err != nil {
+		writeError(w, editStatus(err), err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func hasLeaf(n *yang.Node, k yang.Key) bool {
+	for _, c := range n.Children {
+		if c.Name == k.Name && len(c.Children) == 0 && yang.Text(c.Value) == k.Value {
+			return true
+		}
+	}
+	return false
+}
+
+// editStatus maps an edit's error tag to its status, RFC 8040 section 7.
+func editStatus(err error) int {
+	var e *yang.Error
+	if !errors.As(err, &e) {
+		return http.StatusInternalServerError
+	}
+	switch e.Tag {
+	case yang.TagDataMissing, yang.TagDataExists, yang.TagInUse, yang.TagLockDenied:
+		return http.StatusConflict
+	case yang.TagOperationNotSupported:
+		return http.StatusMethodNotAllowed
+	}
+	return http.StatusBadRequest
+}
+
+// acceptsJSON reports whether the client takes JSON, or did not say.
+func acceptsJSON(r *http.Request) bool {
+	accept := r.Header.Get("Accept")
+	return accept == "" || strings.Contains(accept, "json") || strings.Contains(accept, "*/*") || strings.Contains(accept, "application/*")
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", mediaJSON)
+	w.WriteHeader(code)
+	e := json.NewEncoder(w)
+	e.SetIndent("", "  ")
+	e.Encode(v) //nolint:errcheck
+}
+
+// writeError writes an ietf-restconf errors document.
+func writeError(w http.ResponseWriter, code int, err error) {
+	var e *yang.Error
+	if !errors.As(err, &e) {
+		e = &yang.Error{Tag: "operation-failed", Message: err.Error()}
+	}
+	errorType := "application"
+	if e.Tag == yang.TagMalformedMessage {
+		errorType = "rpc"
+	}
+	body := map[string]any{"error-type": errorType, "error-tag": e.Tag, "error-message": e.Message}
+	if e.Path != "" {
+		body["error-path"] = e.Path
+	}
+	writeJSON(w, code, map[string]any{"ietf-restconf:errors": map[string]any{"error": []any{body}}})
+}
+
+func (rc *RESTCONF) Serve(ctx context.Context) error {
+	listener, err := net.Listen("tcp", rc.Addr)
+	if err != nil {
+		return err
+	}
+	scheme := "http"
+	if rc.TLSConfig != nil {
+		listener = tls.NewListener(listener, rc.TLSConfig)
+		scheme = "https"
+	}
+	s := &http.Server{Handler: rc.Handler(), ReadHeaderTimeout: 5 * time.Second}
+
+	routineErr := make(chan error, 1)
+	go func() {
+		slog.Info("RESTCONF listening", "address", scheme+"://"+listener.Addr().String()+restconfRoot)
+		if err := s.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			routineErr <- err
+			return
+		}
+		routineErr <- nil
+	}()
+
+	<-ctx.Done()
+	s.Close() //nolint:errcheck
+	slog.Info("RESTCONF stopped")
+	return <-routineErr
+}
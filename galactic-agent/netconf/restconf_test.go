@@ -0,0 +1,125 @@
+package netconf
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/datum-cloud/galactic-agent/yang"
+)
+
+// The requests below are those of the examples of RFC 8040, against the
+// module's tree, and the responses are checked as RFC 7951 JSON.
+
+func TestRESTCONFInterop(t *testing.T) {
+	var edits []yang.Leaf
+	rc := &RESTCONF{
+		Data: testData,
+		Edit: func(updates []yang.Leaf) error {
+			edits = append(edits, updates...)
+			return nil
+		},
+	}
+	srv := httptest.NewServer(rc.Handler())
+	defer srv.Close()
+
+	do := func(method, path, contentType, accept, body string) (int, map[string]any) {
+		t.Helper()
+		req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close() //nolint:errcheck
+		b, err := io.ReadAll(res.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var doc map[string]any
+		if len(b) > 0 && strings.HasPrefix(res.Header.Get("Content-Type"), "application/yang-data+json") {
+			if err := json.Unmarshal(b, &doc); err != nil {
+				t.Fatalf("%s %s: %v: %s", method, path, err, b)
+			}
+		}
+		return res.StatusCode, doc
+	}
+	// errorTag is the tag of the one error of an ietf-restconf:errors
+	// document.
+	errorTag := func(doc map[string]any) any {
+		errors, _ := doc["ietf-restconf:errors"].(map[string]any)
+		list, _ := errors["error"].([]any)
+		if len(list) != 1 {
+			return nil
+		}
+		return list[0].(map[string]any)["error-tag"]
+	}
+
+	// root resource discovery, RFC 8040 section 3.1
+	res, err := http.Get(srv.URL + "/.well-known/host-meta")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := io.ReadAll(res.Body)
+	res.Body.Close() //nolint:errcheck
+	if !strings.Contains(string(b), `<Link rel="restconf" href="/restconf"/>`) {
+		t.Errorf("host-meta %s", b)
+	}
+
+	code, doc := do("GET", "/restconf/data", "", "application/yang-data+json", "")
+	data, _ := doc["ietf-restconf:data"].(map[string]any)
+	config, _ := data["galactic-agent:config"].(map[string]any)
+	if code != http.StatusOK || config == nil || data["galactic-agent:state"] == nil {
+		t.Fatalf("data: %d %v", code, doc)
+	}
+	limits, _ := config["rate-limits"].(map[string]any)["limit"].([]any)
+	// decimal64 is a string, uint32 a number: RFC 7951 section 6.1
+	if len(limits) != 1 || limits[0].(map[string]any)["rate"] != "10.000" || limits[0].(map[string]any)["burst"] != float64(20) {
+		t.Errorf("limits %v", limits)
+	}
+
+	code, doc = do("GET", "/restconf/data/galactic-agent:config/rate-limits/limit=register?content=config", "", "", "")
+	if entries, _ := doc["galactic-agent:limit"].([]any); code != http.StatusOK || len(entries) != 1 || entries[0].(map[string]any)["operation"] != "register" {
+		t.Errorf("limit resource: %d %v", code, doc)
+	}
+	code, doc = do("GET", "/restconf/data/galactic-agent:config?content=nonconfig", "", "", "")
+	if code != http.StatusNotFound || errorTag(doc) != "invalid-value" {
+		t.Errorf("config as nonconfig: %d %v", code, doc)
+	}
+	if code, _ = do("GET", "/restconf/data", "", "application/yang-data+xml", ""); code != http.StatusNotAcceptable {
+		t.Errorf("XML requested: %d", code)
+	}
+
+	// a plain patch, RFC 8040 section 4.6.1
+	code, doc = do("PATCH", "/restconf/data/galactic-agent:config/rate-limits/limit=register", "application/yang-data+json",
+		"", `{"galactic-agent:limit": [{"operation": "register", "rate": "5.5"}]}`)
+	if code != http.StatusNoContent {
+		t.Errorf("patch: %d %v", code, doc)
+	}
+	if len(edits) != 1 || edits[0].Path != "/config/rate-limits/limit[operation=register]/rate" || edits[0].Value != "5.5" {
+		t.Errorf("edits %v", edits)
+	}
+
+	edits = nil
+	code, _ = do("PATCH", "/restconf/data/galactic-agent:config/rate-limits/limit=register", "application/yang-data+xml",
+		"", `<limit xmlns="urn:datum:yang:galactic-agent"><operation>register</operation><rate>5</rate></limit>`)
+	if code != http.StatusUnsupportedMediaType || len(edits) != 0 {
+		t.Errorf("XML patch: %d, edits %v", code, edits)
+	}
+	code, doc = do("PUT", "/restconf/data/galactic-agent:config/rate-limits/limit=register", "application/yang-data+json",
+		"", `{"galactic-agent:limit": [{"operation": "register", "rate": "5"}]}`)
+	if code != http.StatusMethodNotAllowed || errorTag(doc) != "operation-not-supported" || len(edits) != 0 {
+		t.Errorf("put: %d %v, edits %v", code, doc, edits)
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// labelSRv6Net, set in the labels file, gives the node its SRv6 locator.
+const labelSRv6Net = "galactic.datum.net/srv6-net"
+
+// applyNodeMode configures the agent for a Kubernetes DaemonSet pod. The
+// node name, from NODE_NAME, names the agent on the broker: it becomes the
+// client id and the topics the controller addresses it on. Everything it
+// derives is a default, so the config file and environment still win.
+func applyNodeMode() error {
+	viper.SetDefault("node_labels_file", "/etc/podinfo/labels")
+	viper.SetDefault("host_netns", "/host/proc/1/ns/net")
+
+	node := viper.GetString("node_name")
+	if node == "" {
+		return errors.New("node_name is not set: pass spec.nodeName as NODE_NAME")
+	}
+	if strings.ContainsAny(node, "/+#") {
+		return fmt.Errorf("node_name %q is not usable in an MQTT topic", node)
+	}
+	labels, err := readLabels(viper.GetString("node_labels_file"))
+	if err != nil {
+		return err
+	}
+
+	viper.SetDefault("mqtt_clientid", "galactic-agent-"+node)
+	viper.SetDefault("mqtt_topic_receive", "galactic/"+node+"/receive")
+	viper.SetDefault("mqtt_topic_send", "galactic/"+node+"/send")
+	viper.SetDefault("mqtt_topic_telemetry", "galactic/"+node+"/telemetry")
+	if srv6Net := labels[labelSRv6Net]; srv6Net != "" {
+		viper.SetDefault("srv6_net", srv6Net)
+	}
+	viper.SetDefault("metrics_addr", ":8080")
+	viper.SetDefault("probe_addr", ":8081")
+	slog.Info("Node mode", "node", node, "client_id", viper.GetString("mqtt_clientid"))
+
+	return checkHostNetns(viper.GetString("host_netns"))
+}
+
+// readLabels reads a labels file in the downward API format, one
+// key="value" per line. A missing file has no labels.
+func readLabels(path string) (map[string]string, error) {
+	labels := make(map[string]string)
+	if path == "" {
+		return labels, nil
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return labels, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close() //nolint:errcheck
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		key, quoted, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("%s: malformed label %q", path, line)
+		}
+		value, err := strconv.Unquote(quoted)
+		if err != nil {
+			return nil, fmt.Errorf("%s: label %s: %w", path, key, err)
+		}
+		labels[key] = value
+	}
+	return labels, scanner.Err()
+}
+
+// checkHostNetns makes sure the agent runs in the host's network namespace,
+// where the VRFs and routes it manages have to live. path is a handle on the
+// host's namespace mounted into the pod; without it the check is skipped.
+func checkHostNetns(path string) error {
+	if path == "" {
+		return nil
+	}
+	host, err := os.Stat(path)
+	if err != nil {
+		slog.Warn("Node mode: not checking the network namespace", "err", err)
+		return nil
+	}
+	self, err := os.Stat("/proc/self/ns/net")
+	if err != nil {
+		return err
+	}
+	if !os.SameFile(host, self) {
+		return fmt.Errorf("not in the host network namespace %s: run the pod with hostNetwork: true", path)
+	}
+	return nil
+}
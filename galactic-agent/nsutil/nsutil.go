@@ -0,0 +1,49 @@
+package nsutil
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/vishvananda/netns"
+)
+
+// Do runs fn with the calling OS thread switched to ns. Sockets and links
+// created by fn stay in ns after Do returns.
+func Do(ns netns.NsHandle, fn func() error) error {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	orig, err := netns.Get()
+	if err != nil {
+		return err
+	}
+	defer orig.Close() //nolint:errcheck
+	if err := netns.Set(ns); err != nil {
+		return err
+	}
+	defer netns.Set(orig) //nolint:errcheck
+	return fn()
+}
+
+// New creates the named namespace, replacing any leftover of the same name,
+// without leaving the calling thread in it.
+func New(name string) (netns.NsHandle, error) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	orig, err := netns.Get()
+	if err != nil {
+		return netns.None(), err
+	}
+	defer orig.Close()    //nolint:errcheck
+	defer netns.Set(orig) //nolint:errcheck
+	_ = netns.DeleteNamed(name)
+	return netns.NewNamed(name)
+}
+
+// SetSysctl writes a sysctl of the current namespace, e.g.
+// "net/ipv6/conf/all/forwarding".
+func SetSysctl(key, value string) error {
+	return os.WriteFile(filepath.Join("/proc/sys", filepath.FromSlash(key)), []byte(value), 0o644)
+}
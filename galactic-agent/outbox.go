@@ -0,0 +1,307 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/datum-cloud/galactic-agent/api/remote"
+	"github.com/datum-cloud/galactic-agent/metrics"
+	"github.com/datum-cloud/galactic-agent/tracing"
+)
+
+var (
+	outboxDepth = metrics.NewGauge(
+		"galactic_agent_outbox_depth",
+		"Register and Deregister envelopes queued for the controller.",
+	)
+	outboxEnvelopes = metrics.NewCounter(
+		"galactic_agent_outbox_envelopes_total",
+		"Register and Deregister envelopes for the controller, by outcome: delivered, retried after the broker did not take them, failed as the outbox was drained, or refused because the queue was full.",
+		"outcome",
+	)
+)
+
+// errOutboxFull refuses an envelope when the outbox is full; local API
+// callers see it as ResourceExhausted and retry.
+var errOutboxFull = errors.New("outbound queue to the controller is full")
+
+const (
+	// outboxInflight is how many envelopes, of as many attachments, the
+	// outbox publishes at a time.
+	outboxInflight = 32
+	// outboxMaxBackoff caps the wait, doubling from a second, before an
+	// envelope the broker did not take is published again.
+	outboxMaxBackoff = 30 * time.Second
+)
+
+// outbox publishes the Register and Deregister envelopes of the local API
+// from a bounded queue on its own goroutines, so that callers never wait on
+// the broker. Each attachment's envelopes are published in order, those of
+// different attachments side by side, and one the broker did not take is
+// published again until it does or the outbox is drained. How each
+// attachment's envelopes fared is kept for the local API to report.
+var outbox *outboxQueue
+
+type outboxQueue struct {
+	size  int
+	wake  chan struct{}
+	slots chan struct{} // one per envelope being published
+
+	mu       sync.Mutex
+	queued   int                  // envelopes queued or being published
+	ready    []string             // endpoints with envelopes and no sender
+	delivery map[string]*delivery // by SRv6 endpoint
+}
+
+// delivery is how the envelopes for an attachment fared: pending while
+// some are queued, then failed if any of them did.
+type delivery struct {
+	queue   []outgoingEnvelope // the first is being published
+	sending bool
+	err     error
+	status  string
+}
+
+type outgoingEnvelope struct {
+	endpoint string
+	envelope *remote.Envelope
+	// done, if set, is called with how the envelope fared
+	done func(error)
+	// trace is the span the envelope was queued in, if traced
+	trace tracing.SpanContext
+}
+
+func newOutbox(size int) *outboxQueue {
+	return &outboxQueue{
+		size:     size,
+		wake:     make(chan struct{}, 1),
+		slots:    make(chan struct{}, outboxInflight),
+		delivery: make(map[string]*delivery),
+	}
+}
+
+// send queues envelopes for the attachment srv6Endpoint, all or none of
+// them, in order. done, if set, is called on an outbox goroutine as each
+// of them is delivered or fails. Their publishing is traced as part of the
+// span in ctx.
+func (o *outboxQueue) send(ctx context.Context, srv6Endpoint string, done func(error), envelopes ...*remote.Envelope) error {
+	trace := tracing.FromContext(ctx)
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.size-o.queued < len(envelopes) {
+		outboxEnvelopes.Inc("refused")
+		return errOutboxFull
+	}
+	d := o.delivery[srv6Endpoint]
+	if d == nil {
+		d = &delivery{}
+		o.delivery[srv6Endpoint] = d
+	}
+	for _, e := range envelopes {
+		d.queue = append(d.queue, outgoingEnvelope{endpoint: srv6Endpoint, envelope: e, done: done, trace: trace})
+	}
+	o.queued += len(envelopes)
+	outboxDepth.Set(float64(o.queued))
+	if !d.sending && len(envelopes) > 0 {
+		d.sending = true
+		o.ready = append(o.ready, srv6Endpoint)
+		select {
+		case o.wake <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// status returns how the envelopes for srv6Endpoint fared, see
+// local.Attachment.
+func (o *outboxQueue) status(srv6Endpoint string) string {
+	if o == nil {
+		return ""
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	d := o.delivery[srv6Endpoint]
+	switch {
+	case d == nil:
+		return ""
+	case len(d.queue) > 0:
+		return "pending"
+	}
+	return d.status
+}
+
+// run publishes queued envelopes until ctx is done. It then waits for
+// served to be closed, once the local API no longer queues any, and
+// publishes those left for up to drainTimeout; those still left then
+// fail.
+func (o *outboxQueue) run(ctx context.Context, served <-chan struct{}, drainTimeout time.Duration) error {
+	// publishing outlives ctx by the drain
+	sendCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var wg sync.WaitGroup
+	dispatch := func() {
+		o.mu.Lock()
+		ready := o.ready
+		o.ready = nil
+		o.mu.Unlock()
+		for _, srv6Endpoint := range ready {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				o.sendAll(sendCtx, srv6Endpoint)
+			}()
+		}
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			<-served
+			dispatch()
+			drained := make(chan struct{})
+			go func() {
+				wg.Wait()
+				close(drained)
+			}()
+			select {
+			case <-drained:
+				return nil
+			case <-time.After(drainTimeout):
+			}
+			o.mu.Lock()
+			n := o.queued
+			o.mu.Unlock()
+			slog.Warn("Outbox not drained", "envelopes", n)
+			cancel()
+			<-drained
+			return nil
+		case <-o.wake:
+			dispatch()
+		}
+	}
+}
+
+// sendAll publishes the envelopes queued for srv6Endpoint, in order, until
+// none is left.
+func (o *outboxQueue) sendAll(ctx context.Context, srv6Endpoint string) {
+	for {
+		o.mu.Lock()
+		d := o.delivery[srv6Endpoint]
+		out := d.queue[0]
+		o.mu.Unlock()
+
+		err := o.deliver(ctx, out)
+
+		o.mu.Lock()
+		d.queue = d.queue[1:]
+		o.queued--
+		outboxDepth.Set(float64(o.queued))
+		if d.err == nil {
+			d.err = err
+		}
+		if len(d.queue) > 0 {
+			o.mu.Unlock()
+			continue
+		}
+		d.sending = false
+		d.status = "delivered"
+		if d.err != nil {
+			d.status = "failed: " + d.err.Error()
+		}
+		d.err = nil
+		o.mu.Unlock()
+
+		// only registered attachments are listed with their delivery, so
+		// once the last envelope of a gone one is settled it is not
+		// reported again
+		if !attachmentRegistered(srv6Endpoint) {
+			o.mu.Lock()
+			if o.delivery[srv6Endpoint] == d && !d.sending {
+				delete(o.delivery, srv6Endpoint)
+			}
+			o.mu.Unlock()
+		}
+		return
+	}
+}
+
+// deliver publishes out, each attempt waiting up to sendTimeout for the
+// broker, until it is delivered or ctx is done, and records how it fared.
+func (o *outboxQueue) deliver(ctx context.Context, out outgoingEnvelope) error {
+	var span *tracing.Span
+	if out.trace.Valid() {
+		span = tracing.StartFrom(out.trace, "Publish", tracing.Producer, "kind", envelopeKind(out.envelope), "srv6_endpoint", out.endpoint)
+	}
+	var err error
+	backoff := time.Second
+	for {
+		if err = o.publish(ctx, out); err == nil || ctx.Err() != nil {
+			break
+		}
+		slog.Warn("Outbox send", "kind", envelopeKind(out.envelope), "srv6_endpoint", out.endpoint, "retry", backoff, "err", err)
+		outboxEnvelopes.Inc("retried")
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+		}
+		backoff = min(2*backoff, outboxMaxBackoff)
+	}
+	span.End(err)
+
+	if err != nil {
+		slog.Error("Outbox send", "kind", envelopeKind(out.envelope), "srv6_endpoint", out.endpoint, "err", err)
+		outboxEnvelopes.Inc("failed")
+	} else {
+		outboxEnvelopes.Inc("delivered")
+	}
+	if out.done != nil {
+		out.done(err)
+	}
+	return err
+}
+
+// publish publishes out once, in one of the outbox's slots, waiting up to
+// sendTimeout for the broker.
+func (o *outboxQueue) publish(ctx context.Context, out outgoingEnvelope) error {
+	select {
+	case o.slots <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	defer func() { <-o.slots }()
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+	return r.SendEnvelope(ctx, out.envelope)
+}
+
+// sendLocal queues envelopes of the local API for the attachment
+// srv6Endpoint, refusing them with ResourceExhausted when the outbox is
+// full.
+func sendLocal(srv6Endpoint string, envelopes ...*remote.Envelope) error {
+	return sendLocalThen(context.Background(), srv6Endpoint, nil, envelopes...)
+}
+
+// sendLocalThen is sendLocal calling done as each envelope is delivered
+// or fails, traced as part of the span in ctx.
+func sendLocalThen(ctx context.Context, srv6Endpoint string, done func(error), envelopes ...*remote.Envelope) error {
+	if err := outbox.send(ctx, srv6Endpoint, done, envelopes...); err != nil {
+		return status.Error(codes.ResourceExhausted, err.Error())
+	}
+	return nil
+}
+
+func envelopeKind(e *remote.Envelope) string {
+	switch e.Kind.(type) {
+	case *remote.Envelope_Register:
+		return "register"
+	case *remote.Envelope_Deregister:
+		return "deregister"
+	}
+	return "envelope"
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/datum-cloud/galactic-agent/api/remote"
+	"github.com/datum-cloud/galactic-agent/srv6/endpoint"
+	"github.com/datum-cloud/galactic-agent/state"
+	"github.com/datum-cloud/galactic-agent/tenant"
+)
+
+// flakyTransport refuses the first fail envelopes, then records the
+// networks of those it takes.
+type flakyTransport struct {
+	mu   sync.Mutex
+	fail int
+	sent []string
+}
+
+func (f *flakyTransport) Run(ctx context.Context) error { return nil }
+
+func (f *flakyTransport) SendEnvelope(ctx context.Context, e *remote.Envelope) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.fail > 0 {
+		f.fail--
+		return errors.New("broker down")
+	}
+	f.sent = append(f.sent, e.GetRegister().GetNetwork()+e.GetDeregister().GetNetwork())
+	return nil
+}
+
+// TestOutboxRetry checks an envelope the broker did not take is published
+// again ahead of those queued after it, and that the delivery of an
+// attachment that is not registered is forgotten once settled.
+func TestOutboxRetry(t *testing.T) {
+	const srv6Net = "fc00:0:0:1::/64"
+	var err error
+	tenantMap, err = tenant.New(tenant.Tenant{SRv6Net: srv6Net}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := state.Open("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	def := tenantMap.All()[0]
+	domains = map[*tenant.Tenant]*domain{def: {Tenant: def, store: store}}
+	fake := &flakyTransport{fail: 1}
+	r = fake
+	o := newOutbox(4)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan struct{})
+	close(served)
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		if err := o.run(ctx, served, time.Second); err != nil {
+			t.Error(err)
+		}
+	}()
+	defer func() {
+		cancel()
+		<-stopped
+	}()
+
+	ep, err := endpoint.Encode(srv6Net, "1", "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	delivered := make(chan error, 3)
+	done := func(err error) { delivered <- err }
+	var envelopes []*remote.Envelope
+	for _, n := range []string{"10.0.0.1/32", "10.0.0.2/32", "10.0.0.3/32"} {
+		envelopes = append(envelopes, &remote.Envelope{Kind: &remote.Envelope_Register{Register: &remote.Register{Network: n, Srv6Endpoint: ep}}})
+	}
+	if err := o.send(ctx, ep, done, envelopes...); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.send(ctx, ep, done, envelopes[:2]...); !errors.Is(err, errOutboxFull) {
+		t.Errorf("overfull send: %v", err)
+	}
+	if got := o.status(ep); got != "pending" {
+		t.Errorf("status %q, want pending", got)
+	}
+	for range envelopes {
+		select {
+		case err := <-delivered:
+			if err != nil {
+				t.Fatal(err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("envelope not delivered")
+		}
+	}
+	fake.mu.Lock()
+	sent := fake.sent
+	fake.mu.Unlock()
+	if want := []string{"10.0.0.1/32", "10.0.0.2/32", "10.0.0.3/32"}; !slices.Equal(sent, want) {
+		t.Errorf("sent %v, want %v", sent, want)
+	}
+	// sendAll forgets the delivery right after the last done
+	deadline := time.Now().Add(time.Second)
+	for o.status(ep) != "" && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := o.status(ep); got != "" {
+		t.Errorf("status of an unregistered attachment %q, want none", got)
+	}
+}
@@ -0,0 +1,111 @@
+// Package overlap notices when the same or overlapping RFC 1918 space is in
+// use by more than one VPC on an agent. That is legal, since VPCs are
+// separate VRFs, but is usually a misconfiguration that only shows once
+// traffic leaves through a shared gateway.
+package overlap
+
+import (
+	"net/netip"
+	"sync"
+)
+
+var private = []netip.Prefix{
+	netip.MustParsePrefix("10.0.0.0/8"),
+	netip.MustParsePrefix("172.16.0.0/12"),
+	netip.MustParsePrefix("192.168.0.0/16"),
+}
+
+type key struct {
+	vpc    string
+	prefix netip.Prefix
+}
+
+// Overlap is a pair of prefixes of different VPCs that share addresses.
+type Overlap struct {
+	VPC, Network           string
+	OtherVPC, OtherNetwork string
+}
+
+// Tracker holds the private prefixes in use per VPC. A prefix may be used
+// by several owners, e.g. as the network of an attachment and as a received
+// route, and stays until every owner removed it.
+type Tracker struct {
+	mu     sync.Mutex
+	owners map[key]map[string]struct{}
+	pairs  int
+}
+
+func isPrivate(prefix netip.Prefix) bool {
+	for _, p := range private {
+		if p.Overlaps(prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// overlaps lists the prefixes of other VPCs overlapping k; the caller holds
+// t.mu.
+func (t *Tracker) overlaps(k key) []Overlap {
+	var found []Overlap
+	for other := range t.owners {
+		if other.vpc != k.vpc && other.prefix.Overlaps(k.prefix) {
+			found = append(found, Overlap{
+				VPC: k.vpc, Network: k.prefix.String(),
+				OtherVPC: other.vpc, OtherNetwork: other.prefix.String(),
+			})
+		}
+	}
+	return found
+}
+
+// Add records network as used by owner in vpc and returns what it newly
+// overlaps. Networks that aren't private IPv4 space are ignored.
+func (t *Tracker) Add(vpc, network, owner string) []Overlap {
+	prefix, err := netip.ParsePrefix(network)
+	if err != nil || !isPrivate(prefix) {
+		return nil
+	}
+	k := key{vpc, prefix.Masked()}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.owners == nil {
+		t.owners = make(map[key]map[string]struct{})
+	}
+	if owners, ok := t.owners[k]; ok {
+		owners[owner] = struct{}{}
+		return nil
+	}
+	t.owners[k] = map[string]struct{}{owner: {}}
+	found := t.overlaps(k)
+	t.pairs += len(found)
+	return found
+}
+
+// Remove undoes Add for owner.
+func (t *Tracker) Remove(vpc, network, owner string) {
+	prefix, err := netip.ParsePrefix(network)
+	if err != nil {
+		return
+	}
+	k := key{vpc, prefix.Masked()}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	owners, ok := t.owners[k]
+	if !ok {
+		return
+	}
+	delete(owners, owner)
+	if len(owners) > 0 {
+		return
+	}
+	delete(t.owners, k)
+	t.pairs -= len(t.overlaps(k))
+}
+
+// Pairs is the number of overlapping prefix pairs currently in use.
+func (t *Tracker) Pairs() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.pairs
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"log/slog"
+
+	"github.com/datum-cloud/galactic-agent/metrics"
+	"github.com/datum-cloud/galactic-agent/overlap"
+	"github.com/datum-cloud/galactic-agent/srv6/endpoint"
+	"github.com/datum-cloud/galactic-agent/state"
+)
+
+var (
+	overlaps overlap.Tracker
+
+	overlapPairs = metrics.NewGauge(
+		"galactic_agent_vpc_overlap_pairs",
+		"Pairs of overlapping RFC 1918 prefixes in use by different VPCs on this agent.",
+	)
+	overlapsDetected = metrics.NewCounter(
+		"galactic_agent_vpc_overlaps_detected_total",
+		"RFC 1918 prefixes that overlapped space of another VPC when first used.",
+		"vpc",
+	)
+)
+
+// trackNetwork records network as used by owner, an attachment or a route,
+// in vpc and warns about any other VPC using the same space.
+func trackNetwork(vpc, network, owner string) {
+	if padded, _, err := endpoint.PadHex(vpc, "0"); err == nil {
+		vpc = padded
+	}
+	for _, o := range overlaps.Add(vpc, network, owner) {
+		slog.Warn("OVERLAP", "vpc", o.VPC, "network", o.Network, "other_vpc", o.OtherVPC, "other_network", o.OtherNetwork)
+		overlapsDetected.Inc(o.VPC)
+	}
+	overlapPairs.Set(float64(overlaps.Pairs()))
+}
+
+func untrackNetwork(vpc, network, owner string) {
+	if padded, _, err := endpoint.PadHex(vpc, "0"); err == nil {
+		vpc = padded
+	}
+	overlaps.Remove(vpc, network, owner)
+	overlapPairs.Set(float64(overlaps.Pairs()))
+}
+
+// trackState seeds the tracker from the persisted state after a restart.
+func trackState(st state.State) {
+	for _, a := range st.Attachments {
+		for _, n := range a.Networks {
+			trackNetwork(a.VPC, n, "attachment:"+a.Endpoint)
+		}
+	}
+	for _, e := range st.Egress {
+		trackNetwork(endpointVPC(e.Endpoint), e.Network, "route:"+e.Endpoint)
+	}
+}
@@ -0,0 +1,323 @@
+// Package ovs realizes attachments and routes on an Open vSwitch bridge,
+// through ovs-vsctl and ovs-ofctl, for hosts that standardize on OVS
+// rather than VRFs and seg6 routes.
+//
+// The host interface of each attachment is a port of the bridge. Table 0
+// tags what arrives from it with the attachment id, the low 64 bits of its
+// SRv6 endpoint, in the metadata, answers ARP and neighbor solicitations
+// as the kernel's proxies would, and learns the workload's MAC into table
+// 3. Table 1 routes tagged packets by destination prefix to a tunnel port.
+// Tunnelled packets for an endpoint are tagged in table 0 and delivered to
+// the workload by table 3.
+package ovs
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"net"
+	"os/exec"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vishvananda/netlink"
+
+	"github.com/datum-cloud/galactic-agent/srv6/endpoint"
+	"github.com/datum-cloud/galactic-agent/srv6/linkcache"
+	"github.com/datum-cloud/galactic-common/util"
+)
+
+// Encapsulations of the tunnels between hosts.
+const (
+	// EncapSRv6 sends through an srv6 port per segment list, which needs
+	// OVS 3.2 or later.
+	EncapSRv6 = "srv6"
+	// EncapVXLAN sends through a single vxlan port to the last segment,
+	// which must be routed to the host owning it, as SRv6 locators are.
+	EncapVXLAN = "vxlan"
+)
+
+// ErrUnsupported is returned for what the OVS datapath cannot do.
+var ErrUnsupported = errors.New("not supported by the ovs datapath")
+
+const (
+	defaultTimeout = 30 * time.Second
+
+	rxPort = "gx-rx" // receives the tunnels of every attachment
+
+	tableClassify = 0
+	tableRoute    = 1
+	tableDeliver  = 3
+)
+
+// Datapath is the OVS datapath. Vsctl and Ofctl are how to start the
+// tools, such as ["ovs-vsctl"] or ["ovs-ofctl", "-O", "OpenFlow15"].
+type Datapath struct {
+	Bridge  string
+	Encap   string
+	Vsctl   []string
+	Ofctl   []string
+	Timeout time.Duration
+
+	mu    sync.Mutex
+	ports map[string]bool // srv6 ports known to exist
+}
+
+func (d *Datapath) run(command []string, stdin string, args ...string) error {
+	timeout := d.Timeout
+	if timeout == 0 {
+		timeout = defaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, command[0], append(slices.Clone(command[1:]), args...)...)
+	if stdin != "" {
+		cmd.Stdin = strings.NewReader(stdin)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if out, err := cmd.Output(); err != nil {
+		return fmt.Errorf("%s: %w: %s", command[0], err, bytes.TrimSpace(append(stderr.Bytes(), out...)))
+	}
+	return nil
+}
+
+func (d *Datapath) vsctl(args ...string) error {
+	return d.run(d.Vsctl, "", args...)
+}
+
+// addFlows adds flows, replacing those with the same match and priority.
+func (d *Datapath) addFlows(flows ...string) error {
+	return d.run(d.Ofctl, strings.Join(flows, "\n")+"\n", "add-flows", d.Bridge, "-")
+}
+
+func (d *Datapath) delFlows(strict bool, flows ...string) error {
+	args := []string{"del-flows", d.Bridge, "-"}
+	if strict {
+		args = append([]string{"--strict"}, args...)
+	}
+	return d.run(d.Ofctl, strings.Join(flows, "\n")+"\n", args...)
+}
+
+// Setup creates the bridge and the port receiving tunnels. The bridge is
+// in secure fail mode: it forwards nothing but what the flows say.
+func (d *Datapath) Setup() error {
+	rx := []string{"type=" + d.Encap, "options:remote_ip=flow"}
+	switch d.Encap {
+	case EncapSRv6:
+	case EncapVXLAN:
+		rx = append(rx, "options:key=flow")
+	default:
+		return fmt.Errorf("encap %q: want %s or %s", d.Encap, EncapSRv6, EncapVXLAN)
+	}
+	args := []string{
+		"--may-exist", "add-br", d.Bridge,
+		"--", "set-fail-mode", d.Bridge, "secure",
+		"--", "set", "bridge", d.Bridge, "protocols=OpenFlow13,OpenFlow15",
+		"--", "--may-exist", "add-port", d.Bridge, rxPort,
+		"--", "set", "interface", rxPort,
+	}
+	return d.vsctl(append(args, rx...)...)
+}
+
+// attachment is an attachment as the flows know it.
+type attachment struct {
+	id       string // metadata, hex
+	endpoint net.IP
+	host     string
+}
+
+func parseAttachment(srv6Endpoint string) (attachment, error) {
+	ip, err := util.ParseIP(srv6Endpoint)
+	if err != nil {
+		return attachment{}, fmt.Errorf("invalid src: %w", err)
+	}
+	vpc, vpcAttachment, err := endpoint.IDs(ip)
+	if err != nil {
+		return attachment{}, fmt.Errorf("could not extract SRv6 endpoint: %w", err)
+	}
+	return attachment{
+		id:       fmt.Sprintf("%#x", binary.BigEndian.Uint64(ip.To16()[8:])),
+		endpoint: ip,
+		host:     util.GenerateInterfaceNameHost(vpc, vpcAttachment),
+	}, nil
+}
+
+// IngressAdd moves the host interface of the attachment from its VRF to the
+// bridge and adds the flows of the attachment.
+func (d *Datapath) IngressAdd(srv6Endpoint string) error {
+	a, err := parseAttachment(srv6Endpoint)
+	if err != nil {
+		return err
+	}
+	link, err := linkcache.Links.LinkByName(a.host)
+	if err != nil {
+		return fmt.Errorf("host interface: %w", err)
+	}
+	if master := link.Attrs().MasterIndex; master != 0 {
+		if m, err := netlink.LinkByIndex(master); err == nil && m.Type() == "vrf" {
+			if err := netlink.LinkSetNoMaster(link); err != nil {
+				return fmt.Errorf("leave vrf %s: %w", m.Attrs().Name, err)
+			}
+		}
+	}
+	if err := d.vsctl("--may-exist", "add-port", d.Bridge, a.host); err != nil {
+		return err
+	}
+
+	mac := link.Attrs().HardwareAddr.String()
+	host := fmt.Sprintf("table=%d,in_port=%s", tableClassify, a.host)
+	deliver := fmt.Sprintf("set_field:%s->metadata,set_field:%s->eth_src,resubmit(,%d)", a.id, mac, tableDeliver)
+	if d.Encap == EncapSRv6 {
+		// srv6 decapsulates to bare IP packets
+		deliver = "encap(ethernet)," + deliver
+	}
+	return d.addFlows(
+		// the workload's packets are routed out of its attachment, and
+		// its MAC learned for the packets to it
+		fmt.Sprintf("%s,priority=100,actions=set_field:%s->metadata,"+
+			"learn(table=%d,priority=100,OXM_OF_METADATA[],load:NXM_OF_ETH_SRC[]->NXM_OF_ETH_DST[],output:NXM_OF_IN_PORT[]),"+
+			"resubmit(,%d)", host, a.id, tableDeliver, tableRoute),
+		// the host interface answers ARP for every address, as with
+		// proxy_arp, but for probes of the workload's own address
+		fmt.Sprintf("%s,priority=210,arp,arp_spa=0.0.0.0,actions=drop", host),
+		fmt.Sprintf("%s,priority=200,arp,arp_op=1,actions="+
+			"move:NXM_OF_ETH_SRC[]->NXM_OF_ETH_DST[],set_field:%s->eth_src,set_field:2->arp_op,"+
+			"move:NXM_NX_ARP_SHA[]->NXM_NX_ARP_THA[],set_field:%s->arp_sha,"+
+			"move:NXM_OF_ARP_TPA[]->NXM_NX_REG0[],move:NXM_OF_ARP_SPA[]->NXM_OF_ARP_TPA[],move:NXM_NX_REG0[]->NXM_OF_ARP_SPA[],"+
+			"in_port", host, mac, mac),
+		// and neighbor solicitations likewise
+		fmt.Sprintf("%s,priority=210,icmp6,icmp_type=135,ipv6_src=::,actions=drop", host),
+		fmt.Sprintf("%s,priority=200,icmp6,icmp_type=135,actions="+
+			"move:NXM_OF_ETH_SRC[]->NXM_OF_ETH_DST[],set_field:%s->eth_src,"+
+			"move:NXM_NX_IPV6_SRC[]->NXM_NX_IPV6_DST[],move:NXM_NX_ND_TARGET[]->NXM_NX_IPV6_SRC[],"+
+			"set_field:255->nw_ttl,set_field:136->icmpv6_type,set_field:0xe0000000->nd_reserved,"+
+			"set_field:2->nd_options_type,set_field:%s->nd_tll,in_port", host, mac, mac),
+		fmt.Sprintf("table=%d,priority=100,in_port=%s,tun_ipv6_dst=%s,actions=%s", tableClassify, rxPort, a.endpoint, deliver),
+	)
+}
+
+// IngressDel removes the flows of the attachment, its routes included, and
+// its host interface from the bridge.
+func (d *Datapath) IngressDel(srv6Endpoint string) error {
+	a, err := parseAttachment(srv6Endpoint)
+	if err != nil {
+		return err
+	}
+	err = d.delFlows(false,
+		fmt.Sprintf("table=%d,tun_ipv6_dst=%s", tableClassify, a.endpoint),
+		fmt.Sprintf("table=%d,metadata=%s", tableRoute, a.id),
+		fmt.Sprintf("table=%d,metadata=%s", tableDeliver, a.id),
+	)
+	if err != nil {
+		return err
+	}
+	// the flows matching the port go with it
+	return d.vsctl("--if-exists", "del-port", d.Bridge, a.host)
+}
+
+// routeMatch matches the packets of the attachment to prefix, at a
+// priority making the longest prefix win.
+func routeMatch(a attachment, prefix string) (string, error) {
+	ip, n, err := net.ParseCIDR(prefix)
+	if err != nil {
+		return "", fmt.Errorf("invalid prefix: %w", err)
+	}
+	ones, _ := n.Mask.Size()
+	if ip.To4() != nil {
+		return fmt.Sprintf("table=%d,priority=%d,metadata=%s,ip,nw_dst=%s", tableRoute, 100+ones, a.id, n), nil
+	}
+	return fmt.Sprintf("table=%d,priority=%d,metadata=%s,ipv6,ipv6_dst=%s", tableRoute, 100+ones, a.id, n), nil
+}
+
+// EgressAdd routes prefix to the tunnel through segments. Routes have no
+// MTU of their own in OVS, so mtu is left to SetMTU.
+func (d *Datapath) EgressAdd(prefix, srv6Endpoint string, segments []string, mtu int) error {
+	a, err := parseAttachment(srv6Endpoint)
+	if err != nil {
+		return err
+	}
+	match, err := routeMatch(a, prefix)
+	if err != nil {
+		return err
+	}
+	if _, err := util.ParseSegments(segments); err != nil {
+		return fmt.Errorf("invalid segments: %w", err)
+	}
+	var actions string
+	switch d.Encap {
+	case EncapVXLAN:
+		actions = fmt.Sprintf("set_field:%s->tun_ipv6_dst,output:%s", segments[len(segments)-1], rxPort)
+	default:
+		port, err := d.segmentPort(segments)
+		if err != nil {
+			return err
+		}
+		actions = "output:" + port
+	}
+	return d.addFlows(match + ",actions=" + actions)
+}
+
+func (d *Datapath) EgressDel(prefix, srv6Endpoint string, segments []string) error {
+	a, err := parseAttachment(srv6Endpoint)
+	if err != nil {
+		return err
+	}
+	match, err := routeMatch(a, prefix)
+	if err != nil {
+		return err
+	}
+	return d.delFlows(true, match)
+}
+
+// segmentPort returns the srv6 port encapsulating with segments, creating
+// it the first time. Ports are named by a hash of their segments and
+// shared by every route through them.
+func (d *Datapath) segmentPort(segments []string) (string, error) {
+	h := fnv.New32a()
+	h.Write([]byte(strings.Join(segments, ","))) //nolint:errcheck
+	port := fmt.Sprintf("gx-%08x", h.Sum32())
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.ports[port] {
+		return port, nil
+	}
+	err := d.vsctl("--may-exist", "add-port", d.Bridge, port,
+		"--", "set", "interface", port, "type=srv6",
+		"options:remote_ip="+segments[0],
+		fmt.Sprintf("options:srv6_segs=%q", strings.Join(segments, ",")),
+	)
+	if err != nil {
+		return "", err
+	}
+	if d.ports == nil {
+		d.ports = make(map[string]bool)
+	}
+	d.ports[port] = true
+	return port, nil
+}
+
+// SetMTU applies mtu to the host interface of the attachment.
+func (d *Datapath) SetMTU(srv6Endpoint string, mtu int) error {
+	a, err := parseAttachment(srv6Endpoint)
+	if err != nil {
+		return err
+	}
+	return d.vsctl("set", "interface", a.host, fmt.Sprintf("mtu_request=%d", mtu))
+}
+
+// SetDSCP fails: marking is not implemented on the bridge.
+func (d *Datapath) SetDSCP(srv6Endpoint, prefix string, dscp uint8) error {
+	return fmt.Errorf("dscp: %w", ErrUnsupported)
+}
+
+// ClearDSCP does nothing, since nothing is ever marked.
+func (d *Datapath) ClearDSCP(srv6Endpoint, prefix string) error {
+	return nil
+}
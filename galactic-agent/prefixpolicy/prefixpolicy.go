@@ -0,0 +1,128 @@
+// Package prefixpolicy decides which route prefixes the agent accepts from
+// the controller, so a misbehaving controller can't hijack a VPC's default
+// route or program nonsense space into the kernel.
+package prefixpolicy
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+
+	"github.com/datum-cloud/galactic-agent/srv6/endpoint"
+)
+
+// ErrRejected is matched by every *RejectedError.
+var ErrRejected = errors.New("prefix rejected")
+
+const (
+	ReasonInvalid       = "invalid"
+	ReasonDefaultRoute  = "default_route"
+	ReasonTooAggregated = "too_aggregated"
+	ReasonBogon         = "bogon"
+)
+
+// RejectedError reports why a prefix was refused.
+type RejectedError struct {
+	Network string
+	Reason  string
+}
+
+func (e *RejectedError) Error() string {
+	return fmt.Sprintf("%s: %s: %v", e.Network, e.Reason, ErrRejected)
+}
+
+func (e *RejectedError) Unwrap() error {
+	return ErrRejected
+}
+
+// Policy is what a VPC accepts. The zero value rejects only default routes.
+type Policy struct {
+	// AllowDefaultRoute accepts ::/0 and 0.0.0.0/0.
+	AllowDefaultRoute bool `mapstructure:"allow_default_route"`
+	// MinLengthV4 and MinLengthV6 are the shortest prefixes accepted, other
+	// than a default route; 0 accepts any length.
+	MinLengthV4 int `mapstructure:"min_length_v4"`
+	MinLengthV6 int `mapstructure:"min_length_v6"`
+	// RejectBogons refuses prefixes overlapping space that is never a
+	// valid destination, see bogons. Private and ULA space is tenant
+	// address space and is not a bogon here.
+	RejectBogons bool `mapstructure:"reject_bogons"`
+}
+
+// Policies is the agent wide default plus per VPC overrides, keyed by hex
+// VPC id. An override replaces the default entirely.
+type Policies struct {
+	Default Policy            `mapstructure:"default"`
+	VPCs    map[string]Policy `mapstructure:"vpcs"`
+}
+
+// Normalize pads the VPC ids of overrides, rejecting malformed ones.
+func (p *Policies) Normalize() error {
+	vpcs := make(map[string]Policy, len(p.VPCs))
+	for vpc, policy := range p.VPCs {
+		padded, _, err := endpoint.PadHex(vpc, "0")
+		if err != nil {
+			return err
+		}
+		vpcs[padded] = policy
+	}
+	p.VPCs = vpcs
+	return nil
+}
+
+// For returns the policy of vpc, a hex id.
+func (p Policies) For(vpc string) Policy {
+	if padded, _, err := endpoint.PadHex(vpc, "0"); err == nil {
+		if policy, ok := p.VPCs[padded]; ok {
+			return policy
+		}
+	}
+	return p.Default
+}
+
+var bogons = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("127.0.0.0/8"),
+	netip.MustParsePrefix("169.254.0.0/16"),
+	netip.MustParsePrefix("192.0.2.0/24"),
+	netip.MustParsePrefix("198.51.100.0/24"),
+	netip.MustParsePrefix("203.0.113.0/24"),
+	netip.MustParsePrefix("224.0.0.0/4"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("::/128"),
+	netip.MustParsePrefix("::1/128"),
+	netip.MustParsePrefix("::ffff:0:0/96"),
+	netip.MustParsePrefix("2001:db8::/32"),
+	netip.MustParsePrefix("fe80::/10"),
+	netip.MustParsePrefix("ff00::/8"),
+}
+
+// Check returns a *RejectedError if network is not acceptable.
+func (p Policy) Check(network string) error {
+	prefix, err := netip.ParsePrefix(network)
+	if err != nil {
+		return &RejectedError{Network: network, Reason: ReasonInvalid}
+	}
+	prefix = prefix.Masked()
+	if prefix.Bits() == 0 {
+		if p.AllowDefaultRoute {
+			return nil
+		}
+		return &RejectedError{Network: network, Reason: ReasonDefaultRoute}
+	}
+	minLength := p.MinLengthV6
+	if prefix.Addr().Is4() {
+		minLength = p.MinLengthV4
+	}
+	if prefix.Bits() < minLength {
+		return &RejectedError{Network: network, Reason: ReasonTooAggregated}
+	}
+	if p.RejectBogons {
+		for _, bogon := range bogons {
+			if bogon.Overlaps(prefix) {
+				return &RejectedError{Network: network, Reason: ReasonBogon}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/datum-cloud/galactic-agent/preflight"
+	"github.com/datum-cloud/galactic-agent/srv6/dryrun"
+	"github.com/datum-cloud/galactic-agent/srv6/routeegress"
+)
+
+// preflightFailures are the failed kernel checks a degraded agent runs
+// with.
+var preflightFailures []preflight.Result
+
+// checkKernel runs the datapath checks of preflight before the agent
+// serves, as startup_preflight says: fail stops the agent if one fails,
+// degraded logs the failures and runs on, off skips the checks. A dry run
+// leaves out the checks that program the kernel.
+func checkKernel() error {
+	mode := viper.GetString("startup_preflight")
+	switch mode {
+	case "off":
+		return nil
+	case "fail", "degraded":
+	default:
+		return fmt.Errorf("startup_preflight %q: want fail, degraded or off", mode)
+	}
+	failed := preflight.Failed(preflight.Datapath(routeegress.LoopbackDevice, !dryrun.Enabled()))
+	for _, r := range failed {
+		slog.Error("Preflight failed", "check", r.Name, "err", r.Err)
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	if mode == "fail" {
+		return fmt.Errorf("%d kernel checks failed; fix them, or set startup_preflight to degraded to run anyway", len(failed))
+	}
+	preflightFailures = failed
+	slog.Warn("Running degraded: routes may fail to program")
+	return nil
+}
+
+func newPreflightCmd() *cobra.Command {
+	var (
+		timeout    time.Duration
+		skipBroker bool
+	)
+	cmd := &cobra.Command{
+		Use:   "preflight",
+		Short: "Check that this host can run the agent",
+		Long: `Check that this host can run the agent.
+
+The exit code ORs one bit per failed class, so scripts can test for
+exactly what is missing:
+
+   2  config       invalid srv6_net, mqtt_url or mqtt_qos
+   4  permissions  missing CAP_NET_ADMIN or unwritable socket/state directory
+   8  kernel       kernel too old, IPv6, seg6 or vrf support missing, or
+                  the kernel refuses the routes and devices the agent adds
+  16  broker       the configured MQTT broker does not accept a connection`,
+		Run: func(cmd *cobra.Command, args []string) {
+			results := preflight.Run(preflight.Options{
+				SRv6Net:       viper.GetString("srv6_net"),
+				SocketPath:    viper.GetString("socket_path"),
+				StatePath:     viper.GetString("state_path"),
+				MQTTURL:       viper.GetString("mqtt_url"),
+				QoS:           viper.GetInt("mqtt_qos"),
+				Username:      viper.GetString("mqtt_username"),
+				Password:      viper.GetString("mqtt_password"),
+				BrokerTimeout: timeout,
+				SkipBroker:    skipBroker,
+				Loopback:      routeegress.LoopbackDevice,
+			})
+			preflight.Write(os.Stdout, results)
+			os.Exit(preflight.ExitCode(results))
+		},
+	}
+	cmd.Flags().DurationVar(&timeout, "broker-timeout", 5*time.Second, "time allowed to connect to the broker")
+	cmd.Flags().BoolVar(&skipBroker, "skip-broker", false, "do not try to connect to the broker")
+	return cmd
+}
@@ -0,0 +1,161 @@
+package preflight
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netlink/nl"
+)
+
+// The datapath checks program routes to the documentation prefix scratch in
+// scratchTable, whose VRF is scratchVRF, to see whether the kernel accepts
+// them. They are removed again right away.
+const (
+	scratchTable = 0xfffffff0
+	scratchVRF   = "galactic-pf"
+)
+
+var scratch = net.ParseIP("2001:db8:ffff::")
+
+// Datapath checks what the kernel datapath programs, the way the agent
+// programs it: seg6 encap routes on loopback, End.DT46 routes into VRF
+// tables and VRF devices. Unless program is set, it only checks what it
+// can without changing the kernel.
+func Datapath(loopback string, program bool) []Result {
+	var results []Result
+	add := func(name string, err error) {
+		results = append(results, Result{Class: Kernel, Name: name, Err: err})
+	}
+	loopbackErr := checkLoopback(loopback)
+	add(loopback, loopbackErr)
+	if !program {
+		add("net.vrf.strict_mode", checkStrictMode())
+		return results
+	}
+	if loopbackErr != nil {
+		add("seg6 lwtunnel", fmt.Errorf("skipped, no %s to add routes to", loopback))
+	} else {
+		add("seg6 lwtunnel", checkSeg6Encap(loopback))
+	}
+	// adding a VRF device loads the vrf module, which strict_mode comes with,
+	// and End.DT46 routes need a VRF device bound to their table
+	vrf, err := addScratchVRF()
+	add("vrf device", err)
+	add("net.vrf.strict_mode", checkStrictMode())
+	if err != nil {
+		add("End.DT46", fmt.Errorf("skipped, no vrf device"))
+		return results
+	}
+	add("End.DT46", checkEndDT46(vrf))
+	if err := netlink.LinkDel(vrf); err != nil {
+		add("vrf device", fmt.Errorf("remove %s: %w", scratchVRF, err))
+	}
+	return results
+}
+
+// Failed returns the failed checks of results.
+func Failed(results []Result) []Result {
+	var failed []Result
+	for _, r := range results {
+		if r.Err != nil {
+			failed = append(failed, r)
+		}
+	}
+	return failed
+}
+
+func checkLoopback(name string) error {
+	link, err := netlink.LinkByName(name)
+	var notFound netlink.LinkNotFoundError
+	if errors.As(err, &notFound) {
+		return fmt.Errorf("missing, egress routes go through it (ip link add %s type dummy && ip link set %s up)", name, name)
+	}
+	if err != nil {
+		return err
+	}
+	if link.Attrs().Flags&net.FlagUp == 0 {
+		return fmt.Errorf("down (ip link set %s up)", name)
+	}
+	return nil
+}
+
+// checkStrictMode: the kernel refuses End.DT46 routes into VRF tables
+// unless VRFs are in strict mode.
+func checkStrictMode() error {
+	b, err := os.ReadFile("/proc/sys/net/vrf/strict_mode")
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("vrf module not loaded (modprobe vrf)")
+	}
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(string(b)) != "1" {
+		return fmt.Errorf("End.DT46 routes need it (sysctl -w net.vrf.strict_mode=1)")
+	}
+	return nil
+}
+
+// scratchRoute adds r to scratchTable on link and deletes it again.
+func scratchRoute(r *netlink.Route, link netlink.Link) error {
+	r.Dst = netlink.NewIPNet(scratch)
+	r.Table = scratchTable
+	r.LinkIndex = link.Attrs().Index
+	if err := netlink.RouteReplace(r); err != nil {
+		return err
+	}
+	return netlink.RouteDel(r)
+}
+
+func checkSeg6Encap(loopback string) error {
+	link, err := netlink.LinkByName(loopback)
+	if err != nil {
+		return err
+	}
+	err = scratchRoute(&netlink.Route{
+		Encap: &netlink.SEG6Encap{Mode: nl.SEG6_IPTUN_MODE_ENCAP, Segments: []net.IP{scratch}},
+	}, link)
+	if err != nil {
+		return fmt.Errorf("seg6 encap route refused, the kernel needs CONFIG_IPV6_SEG6_LWTUNNEL: %w", err)
+	}
+	return nil
+}
+
+// checkEndDT46 adds the route to the scratch VRF, as the agent adds ingress
+// routes to the host side of attachments.
+func checkEndDT46(vrf *netlink.Vrf) error {
+	if checkStrictMode() != nil {
+		return fmt.Errorf("skipped, net.vrf.strict_mode is not 1")
+	}
+	var flags [nl.SEG6_LOCAL_MAX]bool
+	flags[nl.SEG6_LOCAL_ACTION] = true
+	flags[nl.SEG6_LOCAL_VRFTABLE] = true
+	err := scratchRoute(&netlink.Route{
+		Encap: &netlink.SEG6LocalEncap{Action: nl.SEG6_LOCAL_ACTION_END_DT46, Flags: flags, VrfTable: scratchTable},
+	}, vrf)
+	if err != nil {
+		return fmt.Errorf("End.DT46 route refused, the kernel needs 5.14 or later with CONFIG_IPV6_SEG6_LWTUNNEL: %w", err)
+	}
+	return nil
+}
+
+// addScratchVRF adds the VRF device of scratchTable and sets it up, first
+// removing one left behind.
+func addScratchVRF() (*netlink.Vrf, error) {
+	if old, err := netlink.LinkByName(scratchVRF); err == nil {
+		netlink.LinkDel(old) //nolint:errcheck
+	}
+	vrf := &netlink.Vrf{LinkAttrs: netlink.LinkAttrs{Name: scratchVRF}, Table: scratchTable}
+	if err := netlink.LinkAdd(vrf); err != nil {
+		return nil, fmt.Errorf("vrf device refused, the kernel needs CONFIG_NET_VRF (modprobe vrf): %w", err)
+	}
+	// routes are only added to devices that are up
+	if err := netlink.LinkSetUp(vrf); err != nil {
+		netlink.LinkDel(vrf) //nolint:errcheck
+		return nil, err
+	}
+	return vrf, nil
+}
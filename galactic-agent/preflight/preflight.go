@@ -0,0 +1,256 @@
+// Package preflight checks whether a host can run the agent. Failures are
+// grouped into classes with fixed exit code bits, so provisioning scripts
+// can branch on exactly what is missing.
+package preflight
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"golang.org/x/sys/unix"
+
+	"github.com/datum-cloud/galactic-agent/srv6/endpoint"
+)
+
+// Class is a failure class. Its value is the bit it sets in the exit code;
+// bit 0 is left to mean that preflight itself could not run.
+type Class int
+
+const (
+	Config      Class = 1 << 1
+	Permissions Class = 1 << 2
+	Kernel      Class = 1 << 3
+	Broker      Class = 1 << 4
+)
+
+func (c Class) String() string {
+	switch c {
+	case Config:
+		return "config"
+	case Permissions:
+		return "permissions"
+	case Kernel:
+		return "kernel"
+	case Broker:
+		return "broker"
+	}
+	return fmt.Sprintf("class(%d)", int(c))
+}
+
+// Options are the agent settings the checks validate.
+type Options struct {
+	SRv6Net    string
+	SocketPath string
+	StatePath  string
+	MQTTURL    string
+	QoS        int
+	Username   string
+	Password   string
+
+	// BrokerTimeout bounds the broker connect; SkipBroker leaves it out.
+	BrokerTimeout time.Duration
+	SkipBroker    bool
+
+	// Loopback is the device egress routes go through.
+	Loopback string
+}
+
+type Result struct {
+	Class Class
+	Name  string
+	Err   error
+}
+
+// Run performs every check. Checks are independent so one failure never
+// hides another.
+func Run(o Options) []Result {
+	var results []Result
+	add := func(class Class, name string, err error) {
+		results = append(results, Result{Class: class, Name: name, Err: err})
+	}
+
+	_, err := endpoint.Encode(o.SRv6Net, "ffffffffffff", "ffff")
+	add(Config, "srv6_net", err)
+	add(Config, "mqtt_url", checkURL(o.MQTTURL))
+	add(Config, "mqtt_qos", checkQoS(o.QoS))
+
+	add(Permissions, "CAP_NET_ADMIN", checkCapability(unix.CAP_NET_ADMIN))
+	add(Permissions, "socket_path", checkWritableDir(o.SocketPath))
+	if o.StatePath != "" {
+		add(Permissions, "state_path", checkWritableDir(o.StatePath))
+	}
+
+	add(Kernel, "version >= 5.14 (End.DT46)", checkKernelVersion(5, 14))
+	add(Kernel, "ipv6", checkPath("/proc/sys/net/ipv6"))
+	add(Kernel, "seg6", checkPath("/proc/sys/net/ipv6/conf/all/seg6_enabled"))
+	add(Kernel, "vrf", checkVRF())
+	results = append(results, Datapath(o.Loopback, true)...)
+
+	if !o.SkipBroker {
+		add(Broker, "connect", checkBroker(o))
+	}
+	return results
+}
+
+// ExitCode ORs the classes of all failed checks.
+func ExitCode(results []Result) int {
+	code := 0
+	for _, r := range results {
+		if r.Err != nil {
+			code |= int(r.Class)
+		}
+	}
+	return code
+}
+
+func Write(w io.Writer, results []Result) {
+	for _, r := range results {
+		if r.Err != nil {
+			fmt.Fprintf(w, "FAIL %-12s %-28s %v\n", r.Class, r.Name, r.Err) //nolint:errcheck
+			continue
+		}
+		fmt.Fprintf(w, "ok   %-12s %s\n", r.Class, r.Name) //nolint:errcheck
+	}
+}
+
+func checkURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	switch u.Scheme {
+	case "tcp", "ssl", "tls", "mqtt", "mqtts", "ws", "wss":
+	default:
+		return fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("no host in %q", raw)
+	}
+	return nil
+}
+
+func checkQoS(qos int) error {
+	if qos < 0 || qos > 2 {
+		return fmt.Errorf("%d is not 0, 1 or 2", qos)
+	}
+	return nil
+}
+
+// checkCapability reads the effective capability set of this process.
+func checkCapability(capability int) error {
+	f, err := os.Open("/proc/self/status")
+	if err != nil {
+		return err
+	}
+	defer f.Close() //nolint:errcheck
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		hex, ok := strings.CutPrefix(scanner.Text(), "CapEff:")
+		if !ok {
+			continue
+		}
+		caps, err := strconv.ParseUint(strings.TrimSpace(hex), 16, 64)
+		if err != nil {
+			return err
+		}
+		if caps&(1<<capability) == 0 {
+			return fmt.Errorf("not in the effective capability set")
+		}
+		return nil
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return fmt.Errorf("CapEff not found in /proc/self/status")
+}
+
+// checkWritableDir verifies the directory that will hold path exists, or
+// could be created, and is writable.
+func checkWritableDir(path string) error {
+	dir := filepath.Dir(path)
+	for {
+		if _, err := os.Stat(dir); err == nil {
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	if err := unix.Access(dir, unix.W_OK); err != nil {
+		return fmt.Errorf("%s: %w", dir, err)
+	}
+	return nil
+}
+
+func checkKernelVersion(major, minor int) error {
+	var u unix.Utsname
+	if err := unix.Uname(&u); err != nil {
+		return err
+	}
+	release := unix.ByteSliceToString(u.Release[:])
+	var gotMajor, gotMinor int
+	if _, err := fmt.Sscanf(release, "%d.%d", &gotMajor, &gotMinor); err != nil {
+		return fmt.Errorf("cannot parse release %q", release)
+	}
+	if gotMajor < major || gotMajor == major && gotMinor < minor {
+		return fmt.Errorf("running %s", release)
+	}
+	return nil
+}
+
+func checkPath(path string) error {
+	_, err := os.Stat(path)
+	return err
+}
+
+// checkVRF looks for the vrf module, either loaded or built in.
+func checkVRF() error {
+	if _, err := os.Stat("/proc/sys/net/vrf"); err == nil {
+		return nil
+	}
+	if _, err := os.Stat("/sys/module/vrf"); err == nil {
+		return nil
+	}
+	return fmt.Errorf("vrf module not loaded (modprobe vrf)")
+}
+
+func checkBroker(o Options) error {
+	if err := checkURL(o.MQTTURL); err != nil {
+		return fmt.Errorf("skipped, mqtt_url is invalid")
+	}
+	timeout := o.BrokerTimeout
+	if timeout == 0 {
+		timeout = 5 * time.Second
+	}
+	opts := mqtt.NewClientOptions().
+		AddBroker(o.MQTTURL).
+		SetConnectTimeout(timeout).
+		SetConnectRetry(false).
+		SetAutoReconnect(false)
+	if o.Username != "" {
+		opts.SetUsername(o.Username)
+	}
+	if o.Password != "" {
+		opts.SetPassword(o.Password)
+	}
+	client := mqtt.NewClient(opts)
+	token := client.Connect()
+	if !token.WaitTimeout(timeout) {
+		return fmt.Errorf("no CONNACK within %s", timeout)
+	}
+	if err := token.Error(); err != nil {
+		return err
+	}
+	client.Disconnect(250)
+	return nil
+}
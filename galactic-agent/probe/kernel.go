@@ -0,0 +1,75 @@
+package probe
+
+import (
+	"net"
+
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netlink/nl"
+	"golang.org/x/sys/unix"
+
+	"github.com/datum-cloud/galactic-agent/srv6/linkcache"
+	"github.com/datum-cloud/galactic-agent/srv6/routecache"
+	"github.com/datum-cloud/galactic-agent/srv6/routeegress"
+)
+
+// AddResponder routes the responder SID to an End.DT6 into the main table.
+// Like the reply address, it is on the egress loopback device: the kernel
+// turns routes through lo into reject routes.
+func AddResponder(responder net.IP) error {
+	link, err := linkcache.Links.LinkByName(routeegress.LoopbackDevice)
+	if err != nil {
+		return err
+	}
+	var flags [nl.SEG6_LOCAL_MAX]bool
+	flags[nl.SEG6_LOCAL_ACTION] = true
+	flags[nl.SEG6_LOCAL_TABLE] = true
+	route := &netlink.Route{
+		Dst:       netlink.NewIPNet(responder),
+		LinkIndex: link.Attrs().Index,
+		Encap: &netlink.SEG6LocalEncap{
+			Action: nl.SEG6_LOCAL_ACTION_END_DT6,
+			Flags:  flags,
+			Table:  unix.RT_TABLE_MAIN,
+		},
+	}
+	return routecache.Routes.Replace(route)
+}
+
+func DeleteResponder(responder net.IP) error {
+	link, err := linkcache.Links.LinkByName(routeegress.LoopbackDevice)
+	if err != nil {
+		return err
+	}
+	route := &netlink.Route{
+		Dst:       netlink.NewIPNet(responder),
+		LinkIndex: link.Attrs().Index,
+		Encap:     &netlink.SEG6LocalEncap{},
+	}
+	return routecache.Routes.Delete(route)
+}
+
+// AddReply makes reply a local address for probes to come back to.
+func AddReply(reply net.IP) error {
+	link, err := linkcache.Links.LinkByName(routeegress.LoopbackDevice)
+	if err != nil {
+		return err
+	}
+	return netlink.AddrReplace(link, replyAddr(reply))
+}
+
+func DeleteReply(reply net.IP) error {
+	link, err := linkcache.Links.LinkByName(routeegress.LoopbackDevice)
+	if err != nil {
+		return err
+	}
+	return netlink.AddrDel(link, replyAddr(reply))
+}
+
+func replyAddr(reply net.IP) *netlink.Addr {
+	return &netlink.Addr{
+		IPNet: netlink.NewIPNet(reply),
+		// the locator is routed already; the address needs neither a
+		// prefix route nor duplicate detection
+		Flags: unix.IFA_F_NODAD | unix.IFA_F_NOPREFIXROUTE,
+	}
+}
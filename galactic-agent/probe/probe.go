@@ -0,0 +1,363 @@
+// Package probe checks the SRv6 data plane between nodes. Every node
+// answers probes at its responder, an End.DT6 SID decapsulating into the
+// main table. A probe is a UDP packet from and to the prober's reply
+// address, encapsulated towards a remote responder through the segments a
+// route would take: the responder decapsulates it and routes it back over
+// the underlay, so an answered probe shows the encapsulation, the path and
+// the remote decapsulation all work.
+package probe
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// State is what the probes of a destination have shown so far.
+type State int
+
+const (
+	// Unknown destinations have neither answered nor failed enough probes.
+	Unknown State = iota
+	Up
+	// Down destinations lost the last FailAfter probes.
+	Down
+)
+
+func (s State) String() string {
+	switch s {
+	case Up:
+		return "up"
+	case Down:
+		return "down"
+	}
+	return "unknown"
+}
+
+func (s State) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// Target is a destination to probe: the Responder of a node, reached
+// through Segments, in route order and ending at the responder. Probes come
+// back to Reply, a local address.
+type Target struct {
+	Responder net.IP
+	Segments  []net.IP
+	Reply     net.IP
+}
+
+// Key identifies the target: its responder and the path to it.
+func (t Target) Key() string {
+	s := make([]string, len(t.Segments))
+	for i, seg := range t.Segments {
+		s[i] = seg.String()
+	}
+	return strings.Join(s, ",")
+}
+
+// Result is how the probes of a target fared.
+type Result struct {
+	Responder string   `json:"responder"`
+	Segments  []string `json:"segments"`
+	State     State    `json:"state"`
+	Sent      uint64   `json:"sent"`
+	Received  uint64   `json:"received"`
+	// Loss is the share of the last Window probes that were lost.
+	Loss float64 `json:"loss"`
+	// RTT is that of the last answered probe.
+	RTT       time.Duration `json:"rtt"`
+	LastReply time.Time     `json:"last_reply,omitzero"`
+}
+
+type destination struct {
+	target   Target
+	sent     uint64
+	received uint64
+	outcomes []bool // answered, oldest first, at most Window
+	failed   int    // consecutive lost probes
+	rtt      time.Duration
+	last     time.Time
+}
+
+func (d *destination) record(answered bool, window int) {
+	d.outcomes = append(d.outcomes, answered)
+	if len(d.outcomes) > window {
+		d.outcomes = d.outcomes[len(d.outcomes)-window:]
+	}
+	if answered {
+		d.failed = 0
+	} else {
+		d.failed++
+	}
+}
+
+type outstanding struct {
+	key  string
+	sent time.Time
+}
+
+const (
+	magic      = 0x47505242 // "GPRB"
+	payloadLen = 16         // magic, sequence number, send time
+	udpLen     = 8 + payloadLen
+	innerLen   = 40 + udpLen
+	hopLimit   = 64
+)
+
+// Prober sends probes and tracks their results per target. Open it, call
+// Receive in a goroutine of its own and Probe every interval.
+type Prober struct {
+	// Port is the UDP port probes come back to.
+	Port int
+	// Timeout is how long a probe may take to come back before it counts
+	// as lost.
+	Timeout time.Duration
+	// Window is the number of recent probes Loss is computed over, and
+	// FailAfter the number of probes in a row a target must lose to be
+	// Down.
+	Window    int
+	FailAfter int
+
+	raw  int
+	conn *net.UDPConn
+
+	mu          sync.Mutex
+	seq         uint32
+	outstanding map[uint32]outstanding
+	targets     map[string]*destination
+}
+
+// Open opens the sockets probes are sent and received on.
+func (p *Prober) Open() error {
+	raw, err := openRaw()
+	if err != nil {
+		return err
+	}
+	conn, err := net.ListenUDP("udp6", &net.UDPAddr{Port: p.Port})
+	if err != nil {
+		unix.Close(raw)
+		return err
+	}
+	p.raw, p.conn = raw, conn
+	p.outstanding = make(map[uint32]outstanding)
+	p.targets = make(map[string]*destination)
+	return nil
+}
+
+// Probe counts the probes that timed out as lost, forgets the targets no
+// longer given and sends a probe to each of targets.
+func (p *Prober) Probe(targets []Target, now time.Time) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for seq, o := range p.outstanding {
+		if now.Sub(o.sent) < p.Timeout {
+			continue
+		}
+		delete(p.outstanding, seq)
+		if d, ok := p.targets[o.key]; ok {
+			d.record(false, p.Window)
+		}
+	}
+	keep := make(map[string]bool, len(targets))
+	var errs []error
+	for _, t := range targets {
+		key := t.Key()
+		keep[key] = true
+		d, ok := p.targets[key]
+		if !ok {
+			d = &destination{target: t}
+			p.targets[key] = d
+		}
+		p.seq++
+		if err := p.send(t, p.seq, now); err != nil {
+			errs = append(errs, fmt.Errorf("probe %s: %w", key, err))
+			// a probe that could not be sent is lost all the same
+			d.record(false, p.Window)
+			continue
+		}
+		d.sent++
+		p.outstanding[p.seq] = outstanding{key: key, sent: now}
+	}
+	for key := range p.targets {
+		if !keep[key] {
+			delete(p.targets, key)
+		}
+	}
+	for seq, o := range p.outstanding {
+		if !keep[o.key] {
+			delete(p.outstanding, seq)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (p *Prober) send(t Target, seq uint32, now time.Time) error {
+	if len(t.Segments) == 0 {
+		return errors.New("no segments")
+	}
+	return sendRaw(p.raw, t.Segments[0], packet(t, p.Port, seq, now))
+}
+
+// packet encapsulates a probe towards the first segment of t, with a
+// segment routing header listing the rest as the kernel's seg6 encap would.
+func packet(t Target, port int, seq uint32, now time.Time) []byte {
+	n := len(t.Segments)
+	b := make([]byte, 8, 8+16*n+innerLen)
+	b[0] = unix.IPPROTO_IPV6 // next header
+	b[1] = byte(2 * n)       // length in 8 octets, after the first 8
+	b[2] = 4                 // type: segment routing header
+	b[3] = byte(n - 1)       // segments left
+	b[4] = byte(n - 1)       // last entry
+	for _, seg := range slices.Backward(t.Segments) {
+		b = append(b, seg.To16()...)
+	}
+
+	// the probe: UDP from and to the reply address
+	reply := t.Reply.To16()
+	b = append(b, 0x60, 0, 0, 0)
+	b = binary.BigEndian.AppendUint16(b, udpLen)
+	b = append(b, unix.IPPROTO_UDP, hopLimit)
+	b = append(b, reply...)
+	b = append(b, reply...)
+	udp := len(b)
+	b = binary.BigEndian.AppendUint16(b, uint16(port))
+	b = binary.BigEndian.AppendUint16(b, uint16(port))
+	b = binary.BigEndian.AppendUint16(b, udpLen)
+	b = append(b, 0, 0) // checksum
+	b = binary.BigEndian.AppendUint32(b, magic)
+	b = binary.BigEndian.AppendUint32(b, seq)
+	b = binary.BigEndian.AppendUint64(b, uint64(now.UnixNano()))
+	binary.BigEndian.PutUint16(b[udp+6:], checksum(reply, reply, b[udp:]))
+	return b
+}
+
+// openRaw opens a socket to send packets from packet on. The kernel adds
+// the outer IPv6 header; the routing header and the probe inside are ours.
+func openRaw() (int, error) {
+	raw, err := unix.Socket(unix.AF_INET6, unix.SOCK_RAW|unix.SOCK_CLOEXEC, unix.IPPROTO_ROUTING)
+	if err != nil {
+		return -1, fmt.Errorf("probe socket: %w", err)
+	}
+	// the socket only sends; a filter keeps routed packets out of it
+	drop := []unix.SockFilter{{Code: unix.BPF_RET | unix.BPF_K, K: 0}}
+	if err := unix.SetsockoptSockFprog(raw, unix.SOL_SOCKET, unix.SO_ATTACH_FILTER, &unix.SockFprog{Len: 1, Filter: &drop[0]}); err != nil {
+		unix.Close(raw)
+		return -1, fmt.Errorf("probe socket: %w", err)
+	}
+	return raw, nil
+}
+
+func sendRaw(raw int, to net.IP, b []byte) error {
+	return unix.Sendto(raw, b, 0, &unix.SockaddrInet6{Addr: [16]byte(to.To16())})
+}
+
+// checksum is the UDP checksum of segment over the IPv6 pseudo header.
+func checksum(src, dst net.IP, segment []byte) uint16 {
+	var sum uint32
+	add := func(b []byte) {
+		for i := 0; i+1 < len(b); i += 2 {
+			sum += uint32(binary.BigEndian.Uint16(b[i:]))
+		}
+		if len(b)%2 == 1 {
+			sum += uint32(b[len(b)-1]) << 8
+		}
+	}
+	add(src)
+	add(dst)
+	sum += uint32(len(segment)) + unix.IPPROTO_UDP
+	add(segment)
+	for sum > 0xffff {
+		sum = sum&0xffff + sum>>16
+	}
+	if c := ^uint16(sum); c != 0 {
+		return c
+	}
+	return 0xffff
+}
+
+// Receive accounts the probes that come back until Close is called.
+func (p *Prober) Receive() error {
+	buf := make([]byte, 64)
+	for {
+		n, err := p.conn.Read(buf)
+		if err != nil {
+			return err
+		}
+		p.answered(buf[:n], time.Now())
+	}
+}
+
+func (p *Prober) answered(b []byte, now time.Time) {
+	seq, ok := payload(b)
+	if !ok {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	o, ok := p.outstanding[seq]
+	if !ok {
+		// late, after it was counted as lost
+		return
+	}
+	delete(p.outstanding, seq)
+	d, ok := p.targets[o.key]
+	if !ok {
+		return
+	}
+	d.received++
+	d.rtt = now.Sub(o.sent)
+	d.last = now
+	d.record(true, p.Window)
+}
+
+// Results returns the results of the current targets.
+func (p *Prober) Results() []Result {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	results := make([]Result, 0, len(p.targets))
+	for _, d := range p.targets {
+		r := Result{
+			Responder: d.target.Responder.String(),
+			Sent:      d.sent,
+			Received:  d.received,
+			RTT:       d.rtt,
+			LastReply: d.last,
+		}
+		for _, seg := range d.target.Segments {
+			r.Segments = append(r.Segments, seg.String())
+		}
+		lost := 0
+		for _, answered := range d.outcomes {
+			if !answered {
+				lost++
+			}
+		}
+		if len(d.outcomes) > 0 {
+			r.Loss = float64(lost) / float64(len(d.outcomes))
+		}
+		switch {
+		case d.failed >= p.FailAfter:
+			r.State = Down
+		case d.received > 0:
+			r.State = Up
+		}
+		results = append(results, r)
+	}
+	slices.SortFunc(results, func(a, b Result) int {
+		return strings.Compare(strings.Join(a.Segments, ","), strings.Join(b.Segments, ","))
+	})
+	return results
+}
+
+// Close closes the sockets, ending Receive.
+func (p *Prober) Close() error {
+	return errors.Join(unix.Close(p.raw), p.conn.Close())
+}
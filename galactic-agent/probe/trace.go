@@ -0,0 +1,196 @@
+package probe
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// HopKind is what came back for a probe of a trace.
+type HopKind int
+
+const (
+	// Timeout: nothing came back in time.
+	Timeout HopKind = iota
+	// TimeExceeded: a router on the way ran out of hop limit.
+	TimeExceeded
+	// Unreachable: a router could not forward the probe.
+	Unreachable
+	// Reply: the responder sent the probe back.
+	Reply
+)
+
+// Hop is what came back for the probe of a trace sent with HopLimit, from
+// Address, RTT after it was sent.
+type Hop struct {
+	HopLimit int
+	Kind     HopKind
+	Address  net.IP
+	RTT      time.Duration
+	// Code is the ICMPv6 code of TimeExceeded and Unreachable hops.
+	Code int
+}
+
+// ICMPv6 types, see RFC 4443
+const (
+	icmpUnreachable  = 1
+	icmpTimeExceeded = 3
+)
+
+// Trace probes t with outer hop limits from 1 up to maxHops, one at a
+// time and waiting up to timeout for each, until the responder answers or
+// a router reports it unreachable. t.Reply must be a local address; the
+// probes come back to an ephemeral port on it, so traces may run
+// alongside a Prober.
+func Trace(ctx context.Context, t Target, maxHops int, timeout time.Duration) ([]Hop, error) {
+	if len(t.Segments) == 0 {
+		return nil, errors.New("no segments")
+	}
+	raw, err := openRaw()
+	if err != nil {
+		return nil, err
+	}
+	defer unix.Close(raw) //nolint:errcheck
+	conn, err := net.ListenUDP("udp6", &net.UDPAddr{IP: t.Reply})
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close() //nolint:errcheck
+	icmp, err := net.ListenPacket("ip6:ipv6-icmp", "::")
+	if err != nil {
+		return nil, fmt.Errorf("icmp socket: %w", err)
+	}
+	defer icmp.Close() //nolint:errcheck
+	port := conn.LocalAddr().(*net.UDPAddr).Port
+
+	hops := make(chan Hop, 16)
+	go func() {
+		buf := make([]byte, 64)
+		for {
+			n, err := conn.Read(buf)
+			if err != nil {
+				return
+			}
+			// replies come from the reply address itself; the responder
+			// is what answered
+			if seq, ok := payload(buf[:n]); ok {
+				deliver(hops, Hop{HopLimit: int(seq), Kind: Reply, Address: t.Responder})
+			}
+		}
+	}()
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			n, from, err := icmp.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if hop, ok := icmpHop(buf[:n], port); ok {
+				hop.Address = from.(*net.IPAddr).IP
+				deliver(hops, hop)
+			}
+		}
+	}()
+
+	var trace []Hop
+	for limit := 1; limit <= maxHops; limit++ {
+		if err := unix.SetsockoptInt(raw, unix.IPPROTO_IPV6, unix.IPV6_UNICAST_HOPS, limit); err != nil {
+			return trace, err
+		}
+		sent := time.Now()
+		if err := sendRaw(raw, t.Segments[0], packet(t, port, uint32(limit), sent)); err != nil {
+			return trace, err
+		}
+		hop := Hop{HopLimit: limit, Kind: Timeout}
+		deadline := time.NewTimer(timeout)
+	wait:
+		for {
+			select {
+			case <-ctx.Done():
+				deadline.Stop()
+				return trace, ctx.Err()
+			case <-deadline.C:
+				break wait
+			case h := <-hops:
+				// late answers to earlier probes are dropped
+				if h.HopLimit != limit {
+					continue
+				}
+				deadline.Stop()
+				hop = h
+				hop.RTT = time.Since(sent)
+				break wait
+			}
+		}
+		trace = append(trace, hop)
+		if hop.Kind == Reply || hop.Kind == Unreachable {
+			break
+		}
+	}
+	return trace, nil
+}
+
+// deliver passes hop on unless Trace stopped listening.
+func deliver(hops chan<- Hop, hop Hop) {
+	select {
+	case hops <- hop:
+	default:
+	}
+}
+
+// payload returns the sequence number of a probe's UDP payload.
+func payload(b []byte) (uint32, bool) {
+	if len(b) != payloadLen || binary.BigEndian.Uint32(b) != magic {
+		return 0, false
+	}
+	return binary.BigEndian.Uint32(b[4:]), true
+}
+
+// icmpHop reads an ICMPv6 error about a probe of a trace, which comes back
+// to port. The packet it quotes is the encapsulated probe, or the probe
+// alone when the responder's node could not send it back.
+func icmpHop(b []byte, port int) (Hop, bool) {
+	if len(b) < 8 {
+		return Hop{}, false
+	}
+	var hop Hop
+	switch b[0] {
+	case icmpTimeExceeded:
+		hop.Kind = TimeExceeded
+	case icmpUnreachable:
+		hop.Kind = Unreachable
+	default:
+		return Hop{}, false
+	}
+	hop.Code = int(b[1])
+	quoted := b[8:]
+	if len(quoted) < 40 {
+		return Hop{}, false
+	}
+	inner := quoted
+	if quoted[6] == unix.IPPROTO_ROUTING {
+		srh := quoted[40:]
+		if len(srh) < 8 || len(srh) < 8+8*int(srh[1]) {
+			return Hop{}, false
+		}
+		inner = srh[8+8*int(srh[1]):]
+	}
+	if len(inner) < innerLen || inner[6] != unix.IPPROTO_UDP {
+		return Hop{}, false
+	}
+	udp := inner[40:]
+	if int(binary.BigEndian.Uint16(udp[2:])) != port {
+		return Hop{}, false
+	}
+	seq, ok := payload(udp[8:udpLen])
+	if !ok {
+		return Hop{}, false
+	}
+	hop.HopLimit = int(seq)
+	return hop, true
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/datum-cloud/galactic-agent/api/remote"
+	"github.com/datum-cloud/galactic-agent/metrics"
+	"github.com/datum-cloud/galactic-agent/probe"
+	"github.com/datum-cloud/galactic-agent/srv6/endpoint"
+	"github.com/datum-cloud/galactic-agent/tenant"
+	"github.com/datum-cloud/galactic-common/util"
+)
+
+var (
+	probeReachable = metrics.NewGauge(
+		"galactic_agent_probe_reachable",
+		"Whether the last probes to a node's responder through segments came back: 1 if so, 0 if they were lost or none was answered yet.",
+		"responder", "segments",
+	)
+	probeLoss = metrics.NewGauge(
+		"galactic_agent_probe_loss_ratio",
+		"Share of the last probe_window probes to a node's responder through segments that were lost.",
+		"responder", "segments",
+	)
+	probeRTT = metrics.NewGauge(
+		"galactic_agent_probe_rtt_seconds",
+		"Round trip time of the last answered probe to a node's responder through segments.",
+		"responder", "segments",
+	)
+	probeWithdrawn = metrics.NewGauge(
+		"galactic_agent_probe_withdrawn",
+		"Whether the tenant's networks are withdrawn from its controller because the local datapath failed its probes.",
+		"tenant",
+	)
+)
+
+// probes answers the probes of other nodes and, if probe_interval is set,
+// probes the nodes routes lead to; nil unless the datapath is the kernel's
+// and probe_responder or probe_interval is set.
+var probes *prober
+
+type prober struct {
+	responder   bool
+	interval    time.Duration
+	withdraw    bool
+	withdrawMin int
+	p           *probe.Prober
+
+	// tenants is the tenant of every target probed, by key; only run uses
+	// it and withdrawn
+	tenants   map[string]*tenant.Tenant
+	withdrawn map[*tenant.Tenant]bool
+}
+
+func loadProbes() (*prober, error) {
+	responder := viper.GetBool("probe_responder")
+	interval := viper.GetDuration("probe_interval")
+	if !kernelDatapath() {
+		if interval > 0 {
+			return nil, errors.New("probe_interval needs the kernel datapath")
+		}
+		return nil, nil
+	}
+	if !responder && interval <= 0 {
+		return nil, nil
+	}
+	pr := &prober{responder: responder, interval: interval}
+	if interval <= 0 {
+		return pr, nil
+	}
+	pr.withdraw = viper.GetBool("probe_withdraw")
+	pr.withdrawMin = viper.GetInt("probe_withdraw_min_nodes")
+	pr.p = &probe.Prober{
+		Port:      viper.GetInt("probe_port"),
+		Timeout:   viper.GetDuration("probe_timeout"),
+		Window:    viper.GetInt("probe_window"),
+		FailAfter: viper.GetInt("probe_fail_after"),
+	}
+	switch {
+	case pr.p.Port <= 0 || pr.p.Port > 0xffff:
+		return nil, errors.New("probe_port out of range 1-65535")
+	case pr.p.Timeout <= 0:
+		return nil, errors.New("probe_timeout must be positive")
+	case pr.p.Window <= 0:
+		return nil, errors.New("probe_window must be positive")
+	case pr.p.FailAfter <= 0:
+		return nil, errors.New("probe_fail_after must be positive")
+	case pr.withdrawMin <= 0:
+		return nil, errors.New("probe_withdraw_min_nodes must be positive")
+	}
+	if err := pr.p.Open(); err != nil {
+		return nil, err
+	}
+	return pr, nil
+}
+
+// probeAddresses returns the responder and reply address of t on this node.
+func probeAddresses(t *tenant.Tenant) (net.IP, net.IP, error) {
+	s, err := endpoint.Encode(t.SRv6Net, endpoint.ProbeVPC, endpoint.ProbeResponder)
+	if err != nil {
+		return nil, nil, err
+	}
+	responder, err := util.ParseIP(s)
+	if err != nil {
+		return nil, nil, err
+	}
+	reply, err := endpoint.Sibling(responder, endpoint.ProbeVPC, endpoint.ProbeReply)
+	if err != nil {
+		return nil, nil, err
+	}
+	return responder, reply, nil
+}
+
+// run installs the responders and reply addresses, then probes every interval until ctx is
+// done. The responders and reply addresses stay when it returns, like the
+// routes, so other nodes' probes keep passing across restarts.
+func (pr *prober) run(ctx context.Context) error {
+	if pr.p != nil {
+		defer pr.p.Close() //nolint:errcheck
+		go func() {
+			if err := pr.p.Receive(); err != nil && ctx.Err() == nil {
+				slog.Warn("Probe", "err", err)
+			}
+		}()
+	}
+	for _, t := range tenantMap.All() {
+		responder, reply, err := probeAddresses(t)
+		if err != nil {
+			return err
+		}
+		// a node without them only fails the probes, not its attachments
+		if pr.responder {
+			if err := probe.AddResponder(responder); err != nil {
+				slog.Warn("Probe responder", "tenant", t.Name, "err", err)
+			} else {
+				slog.Info("Probe responder", "tenant", t.Name, "address", responder)
+			}
+		}
+		if pr.p != nil {
+			if err := probe.AddReply(reply); err != nil {
+				slog.Warn("Probe reply address", "tenant", t.Name, "err", err)
+			}
+		}
+	}
+	if pr.p == nil {
+		return nil
+	}
+
+	ticker := time.NewTicker(pr.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			for t, withdrawn := range pr.withdrawn {
+				if withdrawn {
+					slog.Warn("Probe: stopping with networks withdrawn; they are advertised again as attachments register", "tenant", t.Name)
+				}
+			}
+			return nil
+		case now := <-ticker.C:
+			targets := pr.targets()
+			if err := pr.p.Probe(targets, now); err != nil {
+				slog.Warn("Probe", "err", err)
+			}
+			results := pr.p.Results()
+			for _, r := range results {
+				segments := strings.Join(r.Segments, ",")
+				reachable := 0.0
+				if r.State == probe.Up {
+					reachable = 1
+				}
+				probeReachable.Set(reachable, r.Responder, segments)
+				probeLoss.Set(r.Loss, r.Responder, segments)
+				probeRTT.Set(r.RTT.Seconds(), r.Responder, segments)
+			}
+			if pr.withdraw {
+				pr.checkDatapath(results)
+			}
+		}
+	}
+}
+
+// targets returns the responders of the other nodes each tenant's routes
+// lead to, through the routes' segments.
+func (pr *prober) targets() []probe.Target {
+	tenants := make(map[string]*tenant.Tenant)
+	var targets []probe.Target
+	for _, t := range tenantMap.All() {
+		own, reply, err := probeAddresses(t)
+		if err != nil {
+			continue
+		}
+		for _, e := range domains[t].store.Snapshot().Egress {
+			segments, err := util.ParseSegments(e.Segments)
+			if err != nil || len(segments) == 0 {
+				continue
+			}
+			// ParseSegments returns them in the kernel's order
+			slices.Reverse(segments)
+			responder, err := endpoint.Sibling(segments[len(segments)-1], endpoint.ProbeVPC, endpoint.ProbeResponder)
+			if err != nil || responder.Equal(own) {
+				continue
+			}
+			segments[len(segments)-1] = responder
+			target := probe.Target{Responder: responder, Segments: segments, Reply: reply}
+			if _, ok := tenants[target.Key()]; ok {
+				continue
+			}
+			tenants[target.Key()] = t
+			targets = append(targets, target)
+		}
+	}
+	pr.tenants = tenants
+	return targets
+}
+
+// checkDatapath withdraws the networks of a tenant once every node its
+// routes lead to is down, if they are at least withdrawMin nodes: all of
+// them failing at once points at the local datapath rather than at them.
+// The networks are advertised again as soon as any node answers.
+func (pr *prober) checkDatapath(results []probe.Result) {
+	if pr.withdrawn == nil {
+		pr.withdrawn = make(map[*tenant.Tenant]bool)
+	}
+	up := make(map[*tenant.Tenant]bool)
+	down := make(map[*tenant.Tenant]map[string]bool)
+	for _, r := range results {
+		t, ok := pr.tenants[strings.Join(r.Segments, ",")]
+		if !ok {
+			continue
+		}
+		switch r.State {
+		case probe.Up:
+			up[t] = true
+		case probe.Down:
+			if down[t] == nil {
+				down[t] = make(map[string]bool)
+			}
+			down[t][r.Responder] = true
+		}
+	}
+	for _, t := range tenantMap.All() {
+		switch {
+		case !pr.withdrawn[t] && !up[t] && len(down[t]) >= pr.withdrawMin:
+			slog.Warn("Probe: no node the routes lead to answers, withdrawing networks", "tenant", t.Name, "nodes", len(down[t]))
+			pr.withdrawn[t] = true
+			probeWithdrawn.Set(1, t.Name)
+			advertise(domains[t], false)
+		case pr.withdrawn[t] && up[t]:
+			slog.Info("Probe: nodes the routes lead to answer again, advertising networks", "tenant", t.Name)
+			pr.withdrawn[t] = false
+			probeWithdrawn.Set(0, t.Name)
+			advertise(domains[t], true)
+		}
+	}
+}
+
+// advertise registers the networks of every attachment of d with its
+// controller again, or deregisters them, leaving the attachments and their
+// routes as they are.
+func advertise(d *domain, register bool) {
+	for _, a := range d.store.Snapshot().Attachments {
+		var envelopes []*remote.Envelope
+		for _, n := range a.Networks {
+			if register {
+				envelopes = append(envelopes, &remote.Envelope{
+					Kind: &remote.Envelope_Register{
+						Register: &remote.Register{
+							Network:      n,
+							Srv6Endpoint: a.Endpoint,
+							Anycast:      slices.Contains(a.Anycast, n),
+						},
+					},
+				})
+			} else {
+				envelopes = append(envelopes, &remote.Envelope{
+					Kind: &remote.Envelope_Deregister{
+						Deregister: &remote.Deregister{
+							Network:      n,
+							Srv6Endpoint: a.Endpoint,
+						},
+					},
+				})
+			}
+		}
+		if err := sendLocal(a.Endpoint, envelopes...); err != nil {
+			slog.Error("Probe", append(endpointAttrs(a.Endpoint), "err", err)...)
+		}
+	}
+}
+
+// results returns the results of the probes, nil unless probing.
+func (pr *prober) results() []probe.Result {
+	if pr.p == nil {
+		return nil
+	}
+	return pr.p.Results()
+}
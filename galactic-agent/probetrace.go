@@ -0,0 +1,248 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/datum-cloud/galactic-agent/api/local"
+	"github.com/datum-cloud/galactic-agent/client"
+	"github.com/datum-cloud/galactic-agent/probe"
+	"github.com/datum-cloud/galactic-agent/srv6/endpoint"
+	"github.com/datum-cloud/galactic-agent/state"
+)
+
+// Bounds of a Probe request, so that a trace ends in under a minute.
+const (
+	traceMaxHops    = 64
+	traceMaxTimeout = 5 * time.Second
+)
+
+// traceProbe serves the Probe RPC: it looks the destination up in the VRF
+// table of the attachment, as the kernel would, and traces the node
+// responder at the end of the route's segments through them.
+func traceProbe(ctx context.Context, req *local.ProbeRequest) (*local.ProbeReply, error) {
+	if !kernelDatapath() {
+		return nil, status.Error(codes.FailedPrecondition, "probing needs the kernel datapath")
+	}
+	given := req.GetVpcattachment() != ""
+	vpcAttachment := req.GetVpcattachment()
+	if !given {
+		vpcAttachment = "0"
+	}
+	vpc, vpcAttachment, err := endpoint.PadHex(req.GetVpc(), vpcAttachment)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	dst := net.ParseIP(req.GetDestination())
+	if dst == nil {
+		return nil, status.Errorf(codes.InvalidArgument, "destination %q is not an address", req.GetDestination())
+	}
+	maxHops := int(req.GetMaxHops())
+	if maxHops == 0 {
+		maxHops = 30
+	}
+	timeout := time.Duration(req.GetTimeoutMs()) * time.Millisecond
+	if timeout == 0 {
+		timeout = time.Second
+	}
+	if maxHops > traceMaxHops || timeout > traceMaxTimeout {
+		return nil, status.Errorf(codes.InvalidArgument, "max_hops is at most %d and timeout_ms at most %d", traceMaxHops, traceMaxTimeout.Milliseconds())
+	}
+
+	t := tenantMap.For(vpc)
+	a, ok := probeAttachment(domains[t].store.Snapshot(), vpc, vpcAttachment, given)
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "no attachment of vpc %s registered", req.GetVpc())
+	}
+	reply := &local.ProbeReply{Vpcattachment: a.VPCAttachment, Vrf: a.VRF, Table: uint32(a.Table)}
+	route, err := lookupRoute(a.Table, dst)
+	if err != nil {
+		return nil, err
+	}
+	if route.Dst != nil {
+		reply.Network = route.Dst.String()
+	}
+	encap, ok := route.Encap.(*netlink.SEG6Encap)
+	if !ok || len(encap.Segments) == 0 {
+		return nil, status.Errorf(codes.FailedPrecondition, "route %s in table %d is not over SRv6", reply.Network, a.Table)
+	}
+	// the kernel holds segments last first, see util.ParseSegments
+	segments := slices.Clone(encap.Segments)
+	slices.Reverse(segments)
+	for _, s := range segments {
+		reply.Srv6Segments = append(reply.Srv6Segments, s.String())
+	}
+
+	own, replyAddr, err := probeAddresses(t)
+	if err != nil {
+		return nil, err
+	}
+	responder, err := endpoint.Sibling(segments[len(segments)-1], endpoint.ProbeVPC, endpoint.ProbeResponder)
+	if err != nil {
+		return nil, err
+	}
+	reply.Responder = responder.String()
+	if responder.Equal(own) {
+		reply.Local = true
+		return reply, nil
+	}
+	segments[len(segments)-1] = responder
+	if err := probe.AddReply(replyAddr); err != nil {
+		return nil, fmt.Errorf("probe reply address: %w", err)
+	}
+	hops, err := probe.Trace(ctx, probe.Target{Responder: responder, Segments: segments, Reply: replyAddr}, maxHops, timeout)
+	if err != nil {
+		return nil, err
+	}
+	for _, h := range hops {
+		hop := &local.ProbeHop{
+			HopLimit: uint32(h.HopLimit),
+			Kind:     local.ProbeHop_Kind(h.Kind),
+			RttNanos: h.RTT.Nanoseconds(),
+			IcmpCode: uint32(h.Code),
+		}
+		if h.Address != nil {
+			hop.Address = h.Address.String()
+			hop.Segment = slices.ContainsFunc(segments, h.Address.Equal)
+		}
+		reply.Hops = append(reply.Hops, hop)
+		reply.Reached = reply.Reached || h.Kind == probe.Reply
+	}
+	return reply, nil
+}
+
+// probeAttachment returns the attachment of vpc to probe from: the given
+// one, or the first registered if none was given.
+func probeAttachment(st state.State, vpc, vpcAttachment string, given bool) (state.Attachment, bool) {
+	for _, a := range st.Attachments {
+		if a.VPC == vpc && (!given || a.VPCAttachment == vpcAttachment) {
+			return a, true
+		}
+	}
+	return state.Attachment{}, false
+}
+
+// lookupRoute returns the most specific route of table covering dst.
+func lookupRoute(table int, dst net.IP) (netlink.Route, error) {
+	family, bits := netlink.FAMILY_V6, 128
+	if dst.To4() != nil {
+		family, bits = netlink.FAMILY_V4, 32
+	}
+	routes, err := netlink.RouteListFiltered(family, &netlink.Route{Table: table}, netlink.RT_FILTER_TABLE)
+	if err != nil {
+		return netlink.Route{}, err
+	}
+	var best netlink.Route
+	found, bestLen := false, -1
+	for _, r := range routes {
+		if r.Type != unix.RTN_UNICAST {
+			continue
+		}
+		ones := 0
+		if r.Dst != nil {
+			if !r.Dst.Contains(dst) {
+				continue
+			}
+			var size int
+			if ones, size = r.Dst.Mask.Size(); size != bits {
+				continue
+			}
+		}
+		if ones > bestLen {
+			best, found, bestLen = r, true, ones
+		}
+	}
+	if !found {
+		return netlink.Route{}, status.Errorf(codes.NotFound, "no route to %s in table %d", dst, table)
+	}
+	return best, nil
+}
+
+func newProbeCmd() *cobra.Command {
+	var socketPath string
+	var timeout time.Duration
+	var maxHops uint32
+	req := &local.ProbeRequest{}
+	cmd := &cobra.Command{
+		Use:   "probe",
+		Short: "Trace a destination through the VRF and segments the agent programmed for it",
+		Long: `Ask the running agent to look the destination up in the VRF of an
+attachment and trace the route's segments to the responder of the node they
+lead to, one hop limit at a time. The command exits non-zero unless the
+responder answers.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+			defer stop()
+
+			if socketPath == "" {
+				socketPath = viper.GetString("socket_path")
+			}
+			c, err := client.New(socketPath)
+			if err != nil {
+				return err
+			}
+			defer c.Close() //nolint:errcheck
+			req.MaxHops = maxHops
+			req.TimeoutMs = uint32(timeout.Milliseconds())
+			reply, err := c.Probe(ctx, req)
+			if err != nil {
+				return err
+			}
+			writeProbe(reply)
+			if !reply.GetLocal() && !reply.GetReached() {
+				return errors.New("responder not reached")
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&socketPath, "socket", "", "agent socket (default: socket_path)")
+	cmd.Flags().StringVar(&req.Vpc, "vpc", "", "vpc id (hex)")
+	cmd.Flags().StringVar(&req.Vpcattachment, "vpcattachment", "", "attachment whose VRF to look up (default: the first of the vpc)")
+	cmd.Flags().StringVar(&req.Destination, "dest", "", "address in the vpc to probe")
+	cmd.Flags().Uint32Var(&maxHops, "max-hops", 30, "largest hop limit to probe with")
+	cmd.Flags().DurationVar(&timeout, "timeout", time.Second, "how long to wait for each hop")
+	_ = cmd.MarkFlagRequired("vpc")
+	_ = cmd.MarkFlagRequired("dest")
+	return cmd
+}
+
+// writeProbe prints a Probe reply the way traceroute would.
+func writeProbe(reply *local.ProbeReply) {
+	fmt.Printf("%s via %s in %s (table %d) of attachment %s\n", reply.GetNetwork(), strings.Join(reply.GetSrv6Segments(), ","), reply.GetVrf(), reply.GetTable(), reply.GetVpcattachment())
+	if reply.GetLocal() {
+		fmt.Printf("segments lead to this node (responder %s), not traced\n", reply.GetResponder())
+		return
+	}
+	fmt.Printf("tracing responder %s\n", reply.GetResponder())
+	for _, h := range reply.GetHops() {
+		if h.GetKind() == local.ProbeHop_TIMEOUT {
+			fmt.Printf("%3d  *\n", h.GetHopLimit())
+			continue
+		}
+		line := fmt.Sprintf("%3d  %s  %s", h.GetHopLimit(), h.GetAddress(), time.Duration(h.GetRttNanos()).Round(time.Microsecond))
+		switch h.GetKind() {
+		case local.ProbeHop_UNREACHABLE:
+			line += fmt.Sprintf("  unreachable (code %d)", h.GetIcmpCode())
+		case local.ProbeHop_REPLY:
+			line += "  reply"
+		}
+		if h.GetSegment() {
+			line += "  [segment]"
+		}
+		fmt.Println(line)
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// applyProfile merges the settings of the profile named by profile, from
+// the profiles of the config file, over those at its top level. Profiles
+// keep the settings that differ between environments, such as brokers and
+// topics, in one file:
+//
+//	mqtt_qos: 1
+//	profiles:
+//	  staging:
+//	    mqtt_url: "tls://mqtt.staging.example:8883"
+//	  prod:
+//	    mqtt_url: "tls://mqtt.example:8883"
+func applyProfile() error {
+	name := viper.GetString("profile")
+	if name == "" {
+		return nil
+	}
+	// viper lowercases keys
+	p, ok := viper.GetStringMap("profiles")[strings.ToLower(name)]
+	if !ok {
+		return fmt.Errorf("profile %q not in config file", name)
+	}
+	settings, ok := p.(map[string]any)
+	if !ok {
+		return fmt.Errorf("profile %q invalid: not a map of settings", name)
+	}
+	return viper.MergeConfigMap(settings)
+}
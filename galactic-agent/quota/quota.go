@@ -0,0 +1,50 @@
+// Package quota caps how much kernel state a single VPC attachment may
+// accumulate from controller routes.
+package quota
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrExceeded is matched by every *ExceededError.
+var ErrExceeded = errors.New("quota exceeded")
+
+const (
+	ResourceRoutes          = "routes"
+	ResourceNeighborProxies = "neighbor_proxies"
+)
+
+// ExceededError reports which attachment ran out of which resource.
+type ExceededError struct {
+	Resource string
+	Endpoint string
+	Limit    int
+}
+
+func (e *ExceededError) Error() string {
+	return fmt.Sprintf("%s: %s limit of %d: %v", e.Endpoint, e.Resource, e.Limit, ErrExceeded)
+}
+
+func (e *ExceededError) Unwrap() error {
+	return ErrExceeded
+}
+
+// Limits are per attachment, identified by its SRv6 endpoint. Zero means
+// unlimited.
+type Limits struct {
+	Routes          int `mapstructure:"routes"`
+	NeighborProxies int `mapstructure:"neighbor_proxies"`
+}
+
+// Check reports whether one more route, a host route if host is set, fits
+// next to the routes and hosts the attachment already has.
+func (l Limits) Check(endpoint string, routes, hosts int, host bool) error {
+	if l.Routes > 0 && routes >= l.Routes {
+		return &ExceededError{Resource: ResourceRoutes, Endpoint: endpoint, Limit: l.Routes}
+	}
+	if host && l.NeighborProxies > 0 && hosts >= l.NeighborProxies {
+		return &ExceededError{Resource: ResourceNeighborProxies, Endpoint: endpoint, Limit: l.NeighborProxies}
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+// Package ratelimit bounds how fast control operations are accepted per
+// VPC, so one runaway caller or controller can't monopolise the kernel or
+// the broker.
+package ratelimit
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrLimited is returned by Take when a VPC is over its rate.
+var ErrLimited = errors.New("rate limit exceeded")
+
+// LimitError names the operation and VPC that hit the limit. It matches
+// ErrLimited.
+type LimitError struct {
+	Operation string
+	VPC       string
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%s for vpc %s: %v", e.Operation, e.VPC, ErrLimited)
+}
+
+func (e *LimitError) Unwrap() error {
+	return ErrLimited
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Limiter is a token bucket per VPC refilled at Rate tokens per second up
+// to Burst. A zero Rate disables limiting.
+type Limiter struct {
+	Operation string
+	Rate      float64
+	Burst     int
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+// New returns a limiter for operation; burst defaults to one second of rate.
+func New(operation string, rate float64, burst int) *Limiter {
+	if burst <= 0 {
+		burst = max(1, int(rate))
+	}
+	return &Limiter{Operation: operation, Rate: rate, Burst: burst}
+}
+
+// Take consumes a token for vpc, or returns a *LimitError if none is left.
+func (l *Limiter) Take(vpc string) error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.Rate <= 0 {
+		return nil
+	}
+	if l.buckets == nil {
+		l.buckets = make(map[string]*bucket)
+	}
+	now := time.Now()
+	if len(l.buckets) > maxBuckets {
+		l.prune(now)
+	}
+	b, ok := l.buckets[vpc]
+	if !ok {
+		b = &bucket{tokens: float64(l.Burst), last: now}
+		l.buckets[vpc] = b
+	}
+	b.tokens = min(float64(l.Burst), b.tokens+now.Sub(b.last).Seconds()*l.Rate)
+	b.last = now
+	if b.tokens < 1 {
+		return &LimitError{Operation: l.Operation, VPC: vpc}
+	}
+	b.tokens--
+	return nil
+}
+
+// SetRate changes the rate and burst of a limiter in use; burst defaults as
+// in New. Buckets keep their tokens, capped to the new burst.
+func (l *Limiter) SetRate(rate float64, burst int) {
+	if burst <= 0 {
+		burst = max(1, int(rate))
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.Rate, l.Burst = rate, burst
+}
+
+// Limits returns the current rate and burst.
+func (l *Limiter) Limits() (float64, int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.Rate, l.Burst
+}
+
+// maxBuckets is how many VPCs are tracked before refilled buckets are
+// dropped; a full bucket is the same as no bucket.
+const maxBuckets = 1024
+
+func (l *Limiter) prune(now time.Time) {
+	for vpc, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.Rate >= float64(l.Burst) {
+			delete(l.buckets, vpc)
+		}
+	}
+}
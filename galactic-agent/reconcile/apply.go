@@ -0,0 +1,61 @@
+package reconcile
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Apply fixes changes with up to workers fixes at a time. The changes of
+// one attachment, those with the same Endpoint, are fixed in the order
+// given by a single worker; other changes are independent. done, if set,
+// is called after each fix, from the worker. Apply returns the errors of
+// the fixes that failed, in no particular order.
+func Apply(changes []Change, workers int, done func(Change, error)) []error {
+	var groups [][]Change
+	byEndpoint := make(map[string]int)
+	for _, c := range changes {
+		if c.Endpoint == "" {
+			groups = append(groups, []Change{c})
+			continue
+		}
+		i, ok := byEndpoint[c.Endpoint]
+		if !ok {
+			i = len(groups)
+			byEndpoint[c.Endpoint] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], c)
+	}
+
+	queue := make(chan []Change)
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	for range max(1, min(workers, len(groups))) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for group := range queue {
+				for _, c := range group {
+					err := c.Fix()
+					if err != nil {
+						mu.Lock()
+						errs = append(errs, fmt.Errorf("%s: %w", c, err))
+						mu.Unlock()
+					}
+					if done != nil {
+						done(c, err)
+					}
+				}
+			}
+		}()
+	}
+	for _, g := range groups {
+		queue <- g
+	}
+	close(queue)
+	wg.Wait()
+	return errs
+}
@@ -0,0 +1,143 @@
+package reconcile
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/vishvananda/netlink"
+
+	"github.com/datum-cloud/galactic-agent/nsutil"
+	"github.com/datum-cloud/galactic-agent/state"
+	"github.com/datum-cloud/galactic-common/sysctl"
+)
+
+// HostSysctls are the host-wide settings SRv6 forwarding between VRFs needs.
+var HostSysctls = map[string]string{
+	"net/ipv6/conf/all/forwarding":   "1",
+	"net/ipv4/conf/all/forwarding":   "1",
+	"net/ipv6/conf/all/seg6_enabled": "1",
+	"net/vrf/strict_mode":            "1",
+}
+
+// interfaceSysctls mirrors what sysctl.ConfigureInterfaceSysctls sets on a
+// host interface, %s being its name.
+var interfaceSysctls = map[string]string{
+	"net/ipv4/conf/%s/rp_filter":  "0",
+	"net/ipv4/conf/%s/forwarding": "1",
+	"net/ipv6/conf/%s/forwarding": "1",
+	"net/ipv4/conf/%s/proxy_arp":  "1",
+	"net/ipv6/conf/%s/proxy_ndp":  "1",
+}
+
+// Host compares what the routes of Diff depend on with the kernel: the
+// host-wide sysctls and the VRF and host interface of every registered
+// attachment. Host network resets, such as WSL's, drop them without notice.
+// Fix the changes before diffing routes, which go into the VRFs.
+//
+// A missing host interface is reported but can't be fixed: its guest side
+// went with it, and only the container runtime can recreate that.
+func Host(st state.State) ([]Change, error) {
+	var changes []Change
+
+	for _, key := range sortedKeys(HostSysctls) {
+		if c, ok := checkSysctl(key, HostSysctls[key]); ok {
+			c.fix = func() error { return nsutil.SetSysctl(key, HostSysctls[key]) }
+			changes = append(changes, c)
+		}
+	}
+
+	for _, a := range st.Attachments {
+		object := fmt.Sprintf("%s/%s", a.VPC, a.VPCAttachment)
+		link, err := netlink.LinkByName(a.VRF)
+		var lnf netlink.LinkNotFoundError
+		switch {
+		case errors.As(err, &lnf):
+			changes = append(changes, Change{Op: Missing, Kind: "vrf", Object: object, Detail: a.VRF,
+				fix: func() error { return addVRF(a.VRF, a.Table) }})
+		case err != nil:
+			return nil, err
+		default:
+			if v, ok := link.(*netlink.Vrf); !ok || int(v.Table) != a.Table {
+				changes = append(changes, Change{Op: Mismatch, Kind: "vrf", Object: object,
+					Detail: fmt.Sprintf("%s is not the VRF of table %d", a.VRF, a.Table),
+					fix:    func() error { return fmt.Errorf("%s must be recreated by hand", a.VRF) }})
+				continue
+			}
+			if link.Attrs().Flags&net.FlagUp == 0 {
+				changes = append(changes, Change{Op: Mismatch, Kind: "vrf", Object: object, Detail: a.VRF + " down",
+					fix: func() error { return netlink.LinkSetUp(link) }})
+			}
+		}
+
+		host, err := netlink.LinkByName(a.Host)
+		if errors.As(err, &lnf) {
+			changes = append(changes, Change{Op: Missing, Kind: "link", Object: object, Detail: a.Host,
+				fix: func() error { return fmt.Errorf("%s is created with its workload; reattach it", a.Host) }})
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if link == nil || host.Attrs().MasterIndex != link.Attrs().Index {
+			changes = append(changes, Change{Op: Mismatch, Kind: "link", Object: object, Detail: a.Host + " not in " + a.VRF,
+				fix: func() error {
+					vrfLink, err := netlink.LinkByName(a.VRF)
+					if err != nil {
+						return err
+					}
+					return netlink.LinkSetMaster(host, vrfLink)
+				}})
+		}
+		if host.Attrs().Flags&net.FlagUp == 0 {
+			changes = append(changes, Change{Op: Mismatch, Kind: "link", Object: object, Detail: a.Host + " down",
+				fix: func() error { return netlink.LinkSetUp(host) }})
+		}
+		for _, format := range sortedKeys(interfaceSysctls) {
+			if c, ok := checkSysctl(fmt.Sprintf(format, a.Host), interfaceSysctls[format]); ok {
+				c.fix = func() error { return sysctl.ConfigureInterfaceSysctls(a.Host) }
+				changes = append(changes, c)
+			}
+		}
+	}
+	return changes, nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// checkSysctl reports a sysctl that is not set to want; the caller sets the
+// fix.
+func checkSysctl(key, want string) (Change, bool) {
+	c := Change{Op: Mismatch, Kind: "sysctl", Object: key}
+	b, err := os.ReadFile(filepath.Join("/proc/sys", filepath.FromSlash(key)))
+	if err != nil {
+		c.Detail = err.Error()
+		return c, true
+	}
+	if got := strings.TrimSpace(string(b)); got != want {
+		c.Detail = fmt.Sprintf("%s, want %s", got, want)
+		return c, true
+	}
+	return c, false
+}
+
+// addVRF recreates a VRF with the table the registry recorded for it, so
+// that ingress routes pointing at the table stay valid.
+func addVRF(name string, table int) error {
+	link := &netlink.Vrf{LinkAttrs: netlink.LinkAttrs{Name: name}, Table: uint32(table)}
+	if err := netlink.LinkAdd(link); err != nil {
+		return err
+	}
+	return netlink.LinkSetUp(link)
+}
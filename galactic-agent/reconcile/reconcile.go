@@ -0,0 +1,495 @@
+package reconcile
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netlink/nl"
+	"golang.org/x/sys/unix"
+
+	"github.com/datum-cloud/galactic-agent/srv6"
+	"github.com/datum-cloud/galactic-agent/srv6/dryrun"
+	"github.com/datum-cloud/galactic-agent/srv6/endpoint"
+	"github.com/datum-cloud/galactic-agent/srv6/linkcache"
+	"github.com/datum-cloud/galactic-agent/srv6/neighborproxy"
+	"github.com/datum-cloud/galactic-agent/srv6/routecache"
+	"github.com/datum-cloud/galactic-agent/srv6/routeegress"
+	"github.com/datum-cloud/galactic-agent/state"
+	"github.com/datum-cloud/galactic-common/util"
+	"github.com/datum-cloud/galactic-common/vrf"
+)
+
+type Op string
+
+const (
+	Missing  Op = "+" // desired but not in the kernel
+	Extra    Op = "-" // in the kernel but not desired
+	Mismatch Op = "~" // in both, but programmed differently
+)
+
+// Change is one difference between the desired state and the kernel.
+type Change struct {
+	Op     Op
+	Kind   string // ingress, egress, neighbor, or sysctl, vrf and link for Host
+	Object string
+	Detail string
+	// Endpoint is the SRv6 endpoint of the attachment a change to desired
+	// state belongs to, empty for other changes.
+	Endpoint string
+
+	fix func() error
+}
+
+func (c Change) String() string {
+	s := fmt.Sprintf("%s %-8s %s", c.Op, c.Kind, c.Object)
+	if c.Detail != "" {
+		s += " (" + c.Detail + ")"
+	}
+	return s
+}
+
+// Fix applies the change to the kernel so that it matches the desired state.
+func (c Change) Fix() error {
+	return c.fix()
+}
+
+func Write(w io.Writer, changes []Change) {
+	for _, c := range changes {
+		fmt.Fprintln(w, c) //nolint:errcheck
+	}
+}
+
+// attachment is a VPC attachment as present in the kernel, named by the
+// base62 ids used in interface names.
+type attachment struct {
+	vpc, vpcAttachment string
+	table              int
+	host               netlink.Link
+}
+
+// attachments indexes galactic VRFs by routing table. Registered
+// attachments are taken from the registry; state files that predate it fall
+// back to parsing the names of the VRFs an End.DT46 route inside locator
+// decapsulates to, so that the VRFs of other tenants are left alone.
+func attachments(registry []state.Attachment, locator *net.IPNet) (map[int]*attachment, error) {
+	if len(registry) > 0 {
+		byTable := make(map[int]*attachment)
+		for _, r := range registry {
+			vpc, vpcAttachment, err := endpointIDs(r.Endpoint)
+			if err != nil {
+				return nil, fmt.Errorf("registry %s/%s: %w", r.VPC, r.VPCAttachment, err)
+			}
+			a := &attachment{vpc: vpc, vpcAttachment: vpcAttachment, table: r.Table}
+			if host, err := netlink.LinkByName(r.Host); err == nil {
+				a.host = host
+			}
+			byTable[a.table] = a
+		}
+		return byTable, nil
+	}
+	ingress, err := readIngress(locator)
+	if err != nil {
+		return nil, err
+	}
+	tables := make(map[int]bool, len(ingress))
+	for _, r := range ingress {
+		tables[r.Encap.(*netlink.SEG6LocalEncap).VrfTable] = true
+	}
+	links, err := vrf.ListVRFLinks()
+	if err != nil {
+		return nil, err
+	}
+	byTable := make(map[int]*attachment)
+	for _, l := range links {
+		if !tables[int(l.Table)] {
+			continue
+		}
+		name := l.Attrs().Name
+		// G<vpc:9><attachment:3>V, see util.GenerateInterfaceNameVRF
+		if len(name) != 14 || name[0] != 'G' || name[13] != 'V' {
+			continue
+		}
+		a := &attachment{vpc: name[1:10], vpcAttachment: name[10:13], table: int(l.Table)}
+		if host, err := netlink.LinkByName(util.GenerateInterfaceNameHost(a.vpc, a.vpcAttachment)); err == nil {
+			a.host = host
+		}
+		byTable[a.table] = a
+	}
+	return byTable, nil
+}
+
+// endpointIDs returns the base62 ids of the attachment an SRv6 endpoint
+// belongs to.
+func endpointIDs(ep string) (string, string, error) {
+	ip, err := util.ParseIP(ep)
+	if err != nil {
+		return "", "", err
+	}
+	return endpoint.IDs(ip)
+}
+
+// endpointTable returns the VRF table of the attachment an SRv6 endpoint
+// belongs to.
+func endpointTable(endpoint string) (int, error) {
+	vpc, vpcAttachment, err := endpointIDs(endpoint)
+	if err != nil {
+		return 0, err
+	}
+	table, err := linkcache.Links.VRFTable(vpc, vpcAttachment)
+	return int(table), err
+}
+
+func prefixKey(table int, dst *net.IPNet) string {
+	return fmt.Sprintf("%d/%s", table, dst)
+}
+
+// Diff compares the desired state with the routes and proxy neighbors in
+// the kernel. Only ingress routes inside srv6Net and routes in galactic VRF
+// tables are considered, so unrelated kernel state is never reported.
+func Diff(st state.State, srv6Net string) ([]Change, error) {
+	_, locator, err := net.ParseCIDR(srv6Net)
+	if err != nil {
+		return nil, fmt.Errorf("invalid srv6_net: %w", err)
+	}
+	atts, err := attachments(st.Attachments, locator)
+	if err != nil {
+		return nil, err
+	}
+	tables := make(map[string]int, len(st.Attachments))
+	mtus := make(map[string]int, len(st.Attachments))
+	for _, a := range st.Attachments {
+		tables[a.Endpoint] = a.Table
+		mtus[a.Endpoint] = a.MTU
+	}
+	tableOf := func(endpoint string) (int, error) {
+		if table, ok := tables[endpoint]; ok {
+			return table, nil
+		}
+		return endpointTable(endpoint)
+	}
+	var changes []Change
+
+	k, err := readKernel(atts, locator)
+	if err != nil {
+		return nil, err
+	}
+	kernelIngress, kernelEgress, kernelNeigh := k.ingress, k.egress, k.neigh
+
+	// ingress: End.DT46 routes for registered endpoints
+	for _, endpoint := range st.Ingress {
+		ip, err := util.ParseIP(endpoint)
+		if err != nil {
+			return nil, fmt.Errorf("desired ingress %s: %w", endpoint, err)
+		}
+		// the kernel lost what the route cache holds
+		fix := func() error {
+			routecache.Routes.Forget(unix.RT_TABLE_MAIN, netlink.NewIPNet(ip))
+			return srv6.RouteIngressAdd(endpoint)
+		}
+		r, ok := kernelIngress[ip.String()]
+		if !ok {
+			changes = append(changes, Change{Op: Missing, Kind: "ingress", Object: endpoint, Endpoint: endpoint, fix: fix})
+			continue
+		}
+		delete(kernelIngress, ip.String())
+		table, err := tableOf(endpoint)
+		if err != nil {
+			changes = append(changes, Change{Op: Mismatch, Kind: "ingress", Object: endpoint, Endpoint: endpoint, Detail: err.Error(), fix: fix})
+			continue
+		}
+		encap := r.Encap.(*netlink.SEG6LocalEncap)
+		switch {
+		case encap.Action != nl.SEG6_LOCAL_ACTION_END_DT46:
+			changes = append(changes, Change{Op: Mismatch, Kind: "ingress", Object: endpoint, Endpoint: endpoint,
+				Detail: fmt.Sprintf("action %s, want End.DT46", nl.SEG6LocalActionString(encap.Action)), fix: fix})
+		case encap.VrfTable != table:
+			changes = append(changes, Change{Op: Mismatch, Kind: "ingress", Object: endpoint, Endpoint: endpoint,
+				Detail: fmt.Sprintf("vrftable %d, want %d", encap.VrfTable, table), fix: fix})
+		}
+	}
+	for _, r := range kernelIngress {
+		changes = append(changes, Change{Op: Extra, Kind: "ingress", Object: r.Dst.IP.String(),
+			fix: func() error { return routecache.Routes.Delete(&r) }})
+	}
+
+	// egress: encap routes in VRF tables, plus proxy neighbors for hosts
+	for _, e := range st.Egress {
+		fix := func() error {
+			vpc, vpcAttachment, err := endpointIDs(e.Endpoint)
+			if err != nil {
+				return err
+			}
+			prefix, err := netlink.ParseIPNet(e.Network)
+			if err != nil {
+				return err
+			}
+			segments, err := util.ParseSegments(e.Segments)
+			if err != nil {
+				return err
+			}
+			if table, err := tableOf(e.Endpoint); err == nil {
+				routecache.Routes.Forget(table, prefix)
+			}
+			return routeegress.Add(vpc, vpcAttachment, prefix, segments, mtus[e.Endpoint])
+		}
+		object := fmt.Sprintf("%s via %s", e.Network, e.Endpoint)
+		table, err := tableOf(e.Endpoint)
+		if err != nil {
+			changes = append(changes, Change{Op: Missing, Kind: "egress", Object: object, Endpoint: e.Endpoint, Detail: err.Error(), fix: fix})
+			continue
+		}
+		prefix, err := netlink.ParseIPNet(e.Network)
+		if err != nil {
+			return nil, fmt.Errorf("desired egress %s: %w", object, err)
+		}
+		if util.IsHost(prefix) {
+			key := fmt.Sprintf("%d/%s", table, prefix.IP)
+			if _, ok := kernelNeigh[key]; ok {
+				delete(kernelNeigh, key)
+			} else {
+				changes = append(changes, Change{Op: Missing, Kind: "neighbor", Object: object, Endpoint: e.Endpoint, fix: func() error {
+					vpc, vpcAttachment, err := endpointIDs(e.Endpoint)
+					if err != nil {
+						return err
+					}
+					return neighborproxy.Add(prefix, vpc, vpcAttachment)
+				}})
+			}
+		}
+		key := prefixKey(table, prefix)
+		r, ok := kernelEgress[key]
+		if !ok {
+			changes = append(changes, Change{Op: Missing, Kind: "egress", Object: object, Endpoint: e.Endpoint, fix: fix})
+			continue
+		}
+		delete(kernelEgress, key)
+		if detail := egressMismatch(r, e.Segments); detail != "" {
+			changes = append(changes, Change{Op: Mismatch, Kind: "egress", Object: object, Endpoint: e.Endpoint, Detail: detail, fix: fix})
+		}
+	}
+	for _, r := range kernelEgress {
+		changes = append(changes, Change{Op: Extra, Kind: "egress", Object: fmt.Sprintf("%s table %d", r.Dst, r.Table),
+			fix: func() error { return routecache.Routes.Delete(&r) }})
+	}
+	for key, n := range kernelNeigh {
+		changes = append(changes, Change{Op: Extra, Kind: "neighbor", Object: fmt.Sprintf("%s table %s", n.IP, strings.SplitN(key, "/", 2)[0]),
+			fix: func() error {
+				if dryrun.Enabled() {
+					dryrun.Neigh("delete", &n)
+					return nil
+				}
+				return netlink.NeighDel(&n)
+			}})
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		if changes[i].Kind != changes[j].Kind {
+			return changes[i].Kind < changes[j].Kind
+		}
+		return changes[i].Object < changes[j].Object
+	})
+	return changes, nil
+}
+
+// kernel holds the galactic routes and proxy neighbors read from the
+// kernel: ingress routes by endpoint address, egress routes by prefixKey and
+// neighbors by table and address.
+type kernel struct {
+	ingress map[string]netlink.Route
+	egress  map[string]netlink.Route
+	neigh   map[string]netlink.Neigh
+}
+
+// readKernel reads the End.DT46 routes inside locator from the main table,
+// and the encap routes and proxy neighbors of the attachments atts.
+func readKernel(atts map[int]*attachment, locator *net.IPNet) (kernel, error) {
+	k := kernel{
+		ingress: make(map[string]netlink.Route),
+		egress:  make(map[string]netlink.Route),
+		neigh:   make(map[string]netlink.Neigh),
+	}
+	ingress, err := readIngress(locator)
+	if err != nil {
+		return k, err
+	}
+	for _, r := range ingress {
+		k.ingress[r.Dst.IP.String()] = r
+	}
+	for table, a := range atts {
+		routes, err := netlink.RouteListFiltered(netlink.FAMILY_ALL, &netlink.Route{Table: table}, netlink.RT_FILTER_TABLE)
+		if err != nil {
+			return k, err
+		}
+		for _, r := range routes {
+			if _, ok := r.Encap.(*netlink.SEG6Encap); ok && r.Dst != nil && !daemonRoute(r) {
+				k.egress[prefixKey(table, r.Dst)] = r
+			}
+		}
+		if a.host == nil {
+			continue
+		}
+		for _, family := range []int{netlink.FAMILY_V4, netlink.FAMILY_V6} {
+			neighs, err := netlink.NeighProxyList(a.host.Attrs().Index, family)
+			if err != nil {
+				return k, err
+			}
+			for _, n := range neighs {
+				k.neigh[fmt.Sprintf("%d/%s", table, n.IP)] = n
+			}
+		}
+	}
+	return k, nil
+}
+
+// readIngress reads the SRv6 local routes inside locator from the main
+// table, leaving out those of routing daemons and probes.
+func readIngress(locator *net.IPNet) ([]netlink.Route, error) {
+	routes, err := netlink.RouteListFiltered(netlink.FAMILY_V6, &netlink.Route{Table: unix.RT_TABLE_MAIN}, netlink.RT_FILTER_TABLE)
+	if err != nil {
+		return nil, err
+	}
+	var ingress []netlink.Route
+	for _, r := range routes {
+		if r.Dst == nil || !locator.Contains(r.Dst.IP) || daemonRoute(r) || probeRoute(r) {
+			continue
+		}
+		if _, ok := r.Encap.(*netlink.SEG6LocalEncap); ok {
+			ingress = append(ingress, r)
+		}
+	}
+	return ingress, nil
+}
+
+// Route is a galactic route or proxy neighbor as the kernel holds it.
+type Route struct {
+	Kind string // ingress, egress or neighbor
+	// Table is the VRF table of an egress route or neighbor, and the table
+	// an ingress route decapsulates to.
+	Table int
+	// Dst is the endpoint address of an ingress route, the prefix of an
+	// egress route and the address of a neighbor.
+	Dst string
+	// Segments are those of an egress route, first first as in the state.
+	Segments []string
+	// Detail tells how the route is programmed other than the agent
+	// programs any, empty if it is not.
+	Detail string
+}
+
+// Kernel lists the routes and proxy neighbors the kernel holds for the
+// attachments of st: their ingress routes inside srv6Net, and the egress
+// routes and neighbors in their VRF tables.
+func Kernel(st state.State, srv6Net string) ([]Route, error) {
+	_, locator, err := net.ParseCIDR(srv6Net)
+	if err != nil {
+		return nil, fmt.Errorf("invalid srv6_net: %w", err)
+	}
+	atts, err := attachments(st.Attachments, locator)
+	if err != nil {
+		return nil, err
+	}
+	k, err := readKernel(atts, locator)
+	if err != nil {
+		return nil, err
+	}
+	type ids struct{ vpc, vpcAttachment string }
+	registered := make(map[ids]bool, len(atts))
+	for _, a := range atts {
+		registered[ids{a.vpc, a.vpcAttachment}] = true
+	}
+	var routes []Route
+	for dst, r := range k.ingress {
+		vpc, vpcAttachment, err := endpoint.IDs(r.Dst.IP)
+		if err != nil || !registered[ids{vpc, vpcAttachment}] {
+			continue
+		}
+		encap := r.Encap.(*netlink.SEG6LocalEncap)
+		route := Route{Kind: "ingress", Table: encap.VrfTable, Dst: dst}
+		if encap.Action != nl.SEG6_LOCAL_ACTION_END_DT46 {
+			route.Detail = fmt.Sprintf("action %s, want End.DT46", nl.SEG6LocalActionString(encap.Action))
+		}
+		routes = append(routes, route)
+	}
+	for _, r := range k.egress {
+		encap := r.Encap.(*netlink.SEG6Encap)
+		route := Route{Kind: "egress", Table: r.Table, Dst: r.Dst.String()}
+		// the kernel holds segments last first, see util.ParseSegments
+		for i := len(encap.Segments) - 1; i >= 0; i-- {
+			route.Segments = append(route.Segments, encap.Segments[i].String())
+		}
+		if encap.Mode != nl.SEG6_IPTUN_MODE_ENCAP {
+			route.Detail = fmt.Sprintf("mode %s, want encap", nl.SEG6EncapModeString(encap.Mode))
+		}
+		routes = append(routes, route)
+	}
+	for key, n := range k.neigh {
+		table, _ := strconv.Atoi(strings.SplitN(key, "/", 2)[0])
+		routes = append(routes, Route{Kind: "neighbor", Table: table, Dst: n.IP.String()})
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Kind != routes[j].Kind {
+			return routes[i].Kind < routes[j].Kind
+		}
+		if routes[i].Table != routes[j].Table {
+			return routes[i].Table < routes[j].Table
+		}
+		return routes[i].Dst < routes[j].Dst
+	})
+	return routes, nil
+}
+
+// Desired lists the ingress and egress routes of st as Missing changes,
+// fixed by ingress and egress, for datapaths Diff cannot read: replaying
+// them programs the whole desired state again.
+func Desired(st state.State, ingress func(endpoint string) error, egress func(state.Egress) error) []Change {
+	changes := make([]Change, 0, len(st.Ingress)+len(st.Egress))
+	for _, endpoint := range st.Ingress {
+		changes = append(changes, Change{Op: Missing, Kind: "ingress", Object: endpoint, Endpoint: endpoint, fix: func() error {
+			return ingress(endpoint)
+		}})
+	}
+	for _, e := range st.Egress {
+		object := fmt.Sprintf("%s via %s", e.Network, e.Endpoint)
+		changes = append(changes, Change{Op: Missing, Kind: "egress", Object: object, Endpoint: e.Endpoint, fix: func() error {
+			return egress(e)
+		}})
+	}
+	return changes
+}
+
+// daemonRoute reports whether a routing daemon, such as FRR's zebra,
+// installed r: its protocol is above static. Those routes are the daemon's
+// to keep, whatever the agent wants.
+func daemonRoute(r netlink.Route) bool {
+	return r.Protocol > unix.RTPROT_STATIC
+}
+
+// probeRoute reports whether r is for an endpoint reserved for probing,
+// which the prober keeps.
+func probeRoute(r netlink.Route) bool {
+	vpc, vpcAttachment, err := util.DecodeSRv6Endpoint(r.Dst.IP)
+	return err == nil && endpoint.Reserved(vpc, vpcAttachment)
+}
+
+func egressMismatch(r netlink.Route, segments []string) string {
+	encap := r.Encap.(*netlink.SEG6Encap)
+	if encap.Mode != nl.SEG6_IPTUN_MODE_ENCAP {
+		return fmt.Sprintf("mode %s, want encap", nl.SEG6EncapModeString(encap.Mode))
+	}
+	want, err := util.ParseSegments(segments)
+	if err != nil {
+		return err.Error()
+	}
+	if !slices.EqualFunc(encap.Segments, want, func(a, b net.IP) bool { return a.Equal(b) }) {
+		return fmt.Sprintf("segments %v, want %v", encap.Segments, want)
+	}
+	if link, err := linkcache.Links.LinkByName(routeegress.LoopbackDevice); err == nil && r.LinkIndex != link.Attrs().Index {
+		return fmt.Sprintf("link index %d, want %s", r.LinkIndex, routeegress.LoopbackDevice)
+	}
+	return ""
+}
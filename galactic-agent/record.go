@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/datum-cloud/galactic-agent/api/remote"
+	"github.com/datum-cloud/galactic-agent/record"
+)
+
+func newRecordCmd() *cobra.Command {
+	var output, topic string
+	cmd := &cobra.Command{
+		Use:   "record",
+		Short: "Capture received control messages to a file",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+			defer stop()
+
+			if topic == "" {
+				topic = viper.GetString("mqtt_topic_receive")
+			}
+			f, err := os.OpenFile(output, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+			if err != nil {
+				return err
+			}
+			defer f.Close() //nolint:errcheck
+			w := record.NewWriter(f)
+
+			count := 0
+			r := remote.Remote{
+				URL:      viper.GetString("mqtt_url"),
+				Username: viper.GetString("mqtt_username"),
+				Password: viper.GetString("mqtt_password"),
+				QoS:      byte(viper.GetInt("mqtt_qos")),
+				TopicRX:  topic,
+				ReceiveHandler: func(payload []byte) error {
+					count++
+					return w.Write(record.Entry{Time: time.Now(), Topic: topic, Payload: payload})
+				},
+			}
+			slog.Info("Recording", "topic", topic, "output", output)
+			if err := r.Run(ctx); err != nil {
+				return err
+			}
+			slog.Info("Recorded", "messages", count)
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&output, "output", "o", "galactic-record.jsonl", "file to append captured messages to")
+	cmd.Flags().StringVar(&topic, "topic", "", "topic to capture (default: mqtt_topic_receive)")
+	return cmd
+}
+
+func newReplayCmd() *cobra.Command {
+	var (
+		input, topic string
+		speed        float64
+	)
+	cmd := &cobra.Command{
+		Use:   "replay",
+		Short: "Publish previously recorded control messages to an agent",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+			defer stop()
+
+			if topic == "" {
+				topic = viper.GetString("mqtt_topic_receive")
+			}
+			f, err := os.Open(input)
+			if err != nil {
+				return err
+			}
+			defer f.Close() //nolint:errcheck
+
+			opts := mqtt.NewClientOptions().AddBroker(viper.GetString("mqtt_url"))
+			if username := viper.GetString("mqtt_username"); username != "" {
+				opts.SetUsername(username)
+			}
+			if password := viper.GetString("mqtt_password"); password != "" {
+				opts.SetPassword(password)
+			}
+			client := mqtt.NewClient(opts)
+			if tok := client.Connect(); tok.Wait() && tok.Error() != nil {
+				return tok.Error()
+			}
+			defer client.Disconnect(250)
+
+			qos := byte(viper.GetInt("mqtt_qos"))
+			count, err := record.Replay(ctx, record.NewReader(f), speed, func(e record.Entry) error {
+				token := client.Publish(topic, qos, false, e.Payload)
+				token.Wait()
+				return token.Error()
+			})
+			slog.Info("Replayed", "messages", count, "topic", topic)
+			if err != nil {
+				return fmt.Errorf("replay failed: %w", err)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&input, "input", "i", "galactic-record.jsonl", "recording to replay")
+	cmd.Flags().StringVar(&topic, "topic", "", "topic to publish to (default: mqtt_topic_receive)")
+	cmd.Flags().Float64Var(&speed, "speed", 1, "time scale factor; 2 replays twice as fast, 0 disables delays")
+	return cmd
+}
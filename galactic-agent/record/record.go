@@ -0,0 +1,80 @@
+package record
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// Entry is one captured control message. Payload is the raw envelope as it
+// was received from the broker.
+type Entry struct {
+	Time    time.Time `json:"time"`
+	Topic   string    `json:"topic"`
+	Payload []byte    `json:"payload"`
+}
+
+// Writer appends entries as JSON lines.
+type Writer struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{enc: json.NewEncoder(w)}
+}
+
+func (w *Writer) Write(e Entry) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.enc.Encode(e)
+}
+
+type Reader struct {
+	dec *json.Decoder
+}
+
+func NewReader(r io.Reader) *Reader {
+	return &Reader{dec: json.NewDecoder(r)}
+}
+
+// Next returns the next entry, or io.EOF at the end of the recording.
+func (r *Reader) Next() (Entry, error) {
+	var e Entry
+	err := r.dec.Decode(&e)
+	return e, err
+}
+
+// Replay hands every entry of r to publish, preserving the original spacing
+// between messages divided by speed. A speed of 0 replays without delays.
+func Replay(ctx context.Context, r *Reader, speed float64, publish func(Entry) error) (int, error) {
+	var (
+		count int
+		prev  time.Time
+	)
+	for {
+		e, err := r.Next()
+		if err == io.EOF {
+			return count, nil
+		}
+		if err != nil {
+			return count, err
+		}
+		if speed > 0 && !prev.IsZero() {
+			if gap := e.Time.Sub(prev); gap > 0 {
+				select {
+				case <-time.After(time.Duration(float64(gap) / speed)):
+				case <-ctx.Done():
+					return count, ctx.Err()
+				}
+			}
+		}
+		prev = e.Time
+		if err := publish(e); err != nil {
+			return count, err
+		}
+		count++
+	}
+}
@@ -0,0 +1,340 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"sync"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/datum-cloud/galactic-agent/api/local"
+	"github.com/datum-cloud/galactic-agent/api/remote"
+	"github.com/datum-cloud/galactic-agent/audit"
+	"github.com/datum-cloud/galactic-agent/srv6/endpoint"
+	"github.com/datum-cloud/galactic-agent/state"
+	"github.com/datum-cloud/galactic-common/util"
+	"github.com/datum-cloud/galactic-common/vrf"
+)
+
+// describeAttachment builds the registry entry of an attachment from what
+// the CNI provisioned for it. Ids are returned padded, see endpoint.PadHex.
+func describeAttachment(vpc, vpcAttachment, srv6Endpoint string, networks []string) (state.Attachment, error) {
+	vpc, vpcAttachment, err := endpoint.PadHex(vpc, vpcAttachment)
+	if err != nil {
+		return state.Attachment{}, err
+	}
+	ip, err := util.ParseIP(srv6Endpoint)
+	if err != nil {
+		return state.Attachment{}, err
+	}
+	vpc62, vpcAttachment62, err := endpoint.IDs(ip)
+	if err != nil {
+		return state.Attachment{}, err
+	}
+	table, err := vrf.GetVRFIdForVPC(vpc62, vpcAttachment62)
+	if err != nil {
+		return state.Attachment{}, err
+	}
+	return state.Attachment{
+		VPC:           vpc,
+		VPCAttachment: vpcAttachment,
+		VRF:           util.GenerateInterfaceNameVRF(vpc62, vpcAttachment62),
+		Table:         int(table),
+		Host:          util.GenerateInterfaceNameHost(vpc62, vpcAttachment62),
+		Guest:         util.GenerateInterfaceNameGuest(vpc62, vpcAttachment62),
+		Endpoint:      srv6Endpoint,
+		Networks:      networks,
+	}, nil
+}
+
+// Policies for a network another attachment of the same VPC already
+// registered, unless both registrations are anycast.
+const (
+	duplicateReject  = "reject"
+	duplicateReplace = "replace"
+)
+
+// withdrawal is a network an attachment loses to a newer registration.
+type withdrawal struct {
+	attachment state.Attachment
+	network    string
+}
+
+// checkDuplicates applies policy to the networks of a registration. With
+// duplicateReject a duplicate fails the whole registration with
+// AlreadyExists; with duplicateReplace the previous owners are returned for
+// the caller to withdraw once the registration succeeded.
+func checkDuplicates(store *state.Store, policy, vpc, vpcAttachment string, networks []string, anycast bool) ([]withdrawal, error) {
+	vpc, vpcAttachment, err := endpoint.PadHex(vpc, vpcAttachment)
+	if err != nil {
+		return nil, err
+	}
+	var withdrawals []withdrawal
+	for _, n := range networks {
+		for _, owner := range store.NetworkOwners(vpc, vpcAttachment, n) {
+			if anycast && slices.Contains(owner.Anycast, n) {
+				continue
+			}
+			if policy != duplicateReplace {
+				return nil, status.Errorf(codes.AlreadyExists, "network %s of vpc %s is registered by attachment %s", n, vpc, owner.VPCAttachment)
+			}
+			withdrawals = append(withdrawals, withdrawal{attachment: owner, network: n})
+		}
+	}
+	return withdrawals, nil
+}
+
+// withdraw takes a network away from an attachment that lost it to a newer
+// registration and tells the controller.
+func withdraw(store *state.Store, w withdrawal) error {
+	a := w.attachment
+	slog.Info("DEREGISTER, replaced by another attachment", append(endpointAttrs(a.Endpoint), "network", w.network)...)
+	if err := store.DeregisterAttachment(a.VPC, a.VPCAttachment, []string{w.network}); err != nil {
+		slog.Error("State store", append(endpointAttrs(a.Endpoint), "network", w.network, "err", err)...)
+	}
+	untrackNetwork(a.VPC, w.network, "attachment:"+a.Endpoint)
+	auditor.Record(audit.ActionDeregister, map[string]string{
+		"vpc":           a.VPC,
+		"vpcattachment": a.VPCAttachment,
+		"network":       w.network,
+		"srv6_endpoint": a.Endpoint,
+		"reason":        "replaced",
+		"result":        audit.ResultOK,
+	})
+	return sendLocal(a.Endpoint, &remote.Envelope{
+		Kind: &remote.Envelope_Deregister{
+			Deregister: &remote.Deregister{
+				Network:      w.network,
+				Srv6Endpoint: a.Endpoint,
+			},
+		},
+	})
+}
+
+// storeChanged is called by the tenant stores after every change, with the
+// store locked.
+func storeChanged() {
+	l.Notify()
+	routeCounts.notify()
+	if windows != nil {
+		windows.notify()
+	}
+	if routing != nil {
+		routing.notify()
+	}
+	if names != nil {
+		names.notify()
+	}
+	if monitor != nil {
+		monitor.notify()
+	}
+	if flows != nil {
+		flows.notify()
+	}
+	if telemetry != nil {
+		telemetry.Notify()
+	}
+}
+
+// listAttachments lists the attachments of vpc, or of all tenants if vpc
+// is empty.
+func listAttachments(vpc string) ([]*local.Attachment, error) {
+	var snapshot []state.Attachment
+	routes := make(map[string]uint32)
+	add := func(st state.State) {
+		snapshot = append(snapshot, st.Attachments...)
+		for _, e := range st.Egress {
+			routes[e.Endpoint]++
+		}
+	}
+	if vpc != "" {
+		var err error
+		if vpc, _, err = endpoint.PadHex(vpc, "0"); err != nil {
+			return nil, err
+		}
+		add(domainFor(vpc).store.Snapshot())
+	} else {
+		for _, t := range tenantMap.All() {
+			add(domains[t].store.Snapshot())
+		}
+	}
+	var attachments []*local.Attachment
+	for _, a := range snapshot {
+		if vpc != "" && a.VPC != vpc {
+			continue
+		}
+		attachments = append(attachments, &local.Attachment{
+			Vpc:            a.VPC,
+			Vpcattachment:  a.VPCAttachment,
+			Vrf:            a.VRF,
+			Table:          uint32(a.Table),
+			HostInterface:  a.Host,
+			GuestInterface: a.Guest,
+			Srv6Endpoint:   a.Endpoint,
+			Networks:       a.Networks,
+			CreatedUnix:    a.Created.Unix(),
+			Anycast:        a.Anycast,
+			Mtu:            uint32(a.MTU),
+			Routes:         routes[a.Endpoint],
+			Delivery:       outbox.status(a.Endpoint),
+			Name:           a.Name,
+			Generation:     a.Generation,
+		})
+	}
+	return attachments, nil
+}
+
+// checkGeneration orders a registration, or with register false a
+// deregistration, of generation of an attachment after those the store
+// saw. It reports whether the call repeats the last one, to be confirmed
+// without doing anything again, and fails for a stale one. Generation 0
+// is always applied.
+func checkGeneration(store *state.Store, vpc, vpcAttachment string, generation uint64, register bool) (bool, error) {
+	if generation == 0 {
+		return false, nil
+	}
+	vpc, vpcAttachment, err := endpoint.PadHex(vpc, vpcAttachment)
+	if err != nil {
+		return false, status.Error(codes.InvalidArgument, err.Error())
+	}
+	current, registered := store.Generation(vpc, vpcAttachment)
+	switch {
+	case generation > current:
+		return false, nil
+	case generation == current && registered == register:
+		return true, nil
+	case generation == current && registered:
+		// the deregistration of the registration in place
+		return false, nil
+	}
+	if registered {
+		return false, status.Errorf(codes.FailedPrecondition, "generation %d of attachment %s/%s is stale: it is registered at %d", generation, vpc, vpcAttachment, current)
+	}
+	return false, status.Errorf(codes.FailedPrecondition, "generation %d of attachment %s/%s is stale: it was deregistered at %d", generation, vpc, vpcAttachment, current)
+}
+
+// setGeneration records the generation of a call checkGeneration let
+// through. It is done only once the controller is sure to be told, so that
+// the retry of a call that failed before is not taken for a repeat.
+func setGeneration(store *state.Store, vpc, vpcAttachment string, generation uint64) {
+	if generation == 0 {
+		return
+	}
+	vpc, vpcAttachment, err := endpoint.PadHex(vpc, vpcAttachment)
+	if err != nil {
+		return
+	}
+	if err := store.SetGeneration(vpc, vpcAttachment, generation); err != nil {
+		slog.Error("State store", "vpc", vpc, "vpcattachment", vpcAttachment, "err", err)
+	}
+}
+
+// attachmentLocks serializes the registrations and deregistrations of each
+// attachment, so that none is ordered by checkGeneration while another
+// one it races with has yet to record its generation.
+var attachmentLocks = struct {
+	sync.Mutex
+	held map[string]*attachmentLock
+}{held: make(map[string]*attachmentLock)}
+
+type attachmentLock struct {
+	sync.Mutex
+	// users holding or waiting for the lock
+	users int
+}
+
+// lockAttachment locks an attachment and returns the unlock.
+func lockAttachment(vpc, vpcAttachment string) func() {
+	if v, a, err := endpoint.PadHex(vpc, vpcAttachment); err == nil {
+		vpc, vpcAttachment = v, a
+	}
+	k := vpc + "/" + vpcAttachment
+	attachmentLocks.Lock()
+	l, ok := attachmentLocks.held[k]
+	if !ok {
+		l = &attachmentLock{}
+		attachmentLocks.held[k] = l
+	}
+	l.users++
+	attachmentLocks.Unlock()
+	l.Lock()
+	return func() {
+		l.Unlock()
+		attachmentLocks.Lock()
+		if l.users--; l.users == 0 {
+			delete(attachmentLocks.held, k)
+		}
+		attachmentLocks.Unlock()
+	}
+}
+
+// minMTU is the smallest MTU an attachment may be given, the IPv6 minimum.
+const minMTU = 1280
+
+// endpointAttachment returns the padded ids of the attachment srv6Endpoint
+// belongs to.
+func endpointAttachment(srv6Endpoint string) (string, string, error) {
+	ip, err := util.ParseIP(srv6Endpoint)
+	if err != nil {
+		return "", "", err
+	}
+	return util.DecodeSRv6Endpoint(ip)
+}
+
+// attachmentRegistered reports whether the attachment srv6Endpoint
+// belongs to is registered.
+func attachmentRegistered(srv6Endpoint string) bool {
+	vpc, vpcAttachment, err := endpointAttachment(srv6Endpoint)
+	if err != nil {
+		return false
+	}
+	d := domainFor(vpc)
+	if d == nil {
+		return false
+	}
+	_, ok := d.store.Attachment(vpc, vpcAttachment)
+	return ok
+}
+
+// attachmentMTU returns the MTU pushed for the attachment srv6Endpoint
+// belongs to, 0 if none was.
+func attachmentMTU(store *state.Store, srv6Endpoint string) int {
+	vpc, vpcAttachment, err := endpointAttachment(srv6Endpoint)
+	if err != nil {
+		return 0
+	}
+	a, _ := store.Attachment(vpc, vpcAttachment)
+	return a.MTU
+}
+
+// setMTU applies an MTU the controller pushed for an attachment and records
+// it, so that routes added later get it too.
+func setMTU(store *state.Store, m *remote.SetMTU) error {
+	if m.Mtu < minMTU || m.Mtu > 65535 {
+		return fmt.Errorf("mtu %d out of range %d-65535", m.Mtu, minMTU)
+	}
+	vpc, vpcAttachment, err := endpointAttachment(m.Srv6Endpoint)
+	if err != nil {
+		return err
+	}
+	if _, ok := store.Attachment(vpc, vpcAttachment); !ok {
+		return fmt.Errorf("mtu for %s: attachment %s/%s is not registered", m.Srv6Endpoint, vpc, vpcAttachment)
+	}
+	if err := dp.SetMTU(m.Srv6Endpoint, int(m.Mtu)); err != nil {
+		return err
+	}
+	if err := store.SetMTU(vpc, vpcAttachment, int(m.Mtu)); err != nil {
+		slog.Error("State store", append(endpointAttrs(m.Srv6Endpoint), "mtu", m.Mtu, "err", err)...)
+	}
+	auditor.Record(audit.ActionSetMTU, map[string]string{
+		"vpc":           vpc,
+		"vpcattachment": vpcAttachment,
+		"srv6_endpoint": m.Srv6Endpoint,
+		"mtu":           strconv.Itoa(int(m.Mtu)),
+		"result":        audit.ResultOK,
+	})
+	return nil
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+
+	"github.com/datum-cloud/galactic-agent/api/remote"
+	"github.com/datum-cloud/galactic-agent/logging"
+	"github.com/datum-cloud/galactic-agent/tenant"
+)
+
+// reloadable are the settings a reload applies; changes to any other need
+// a restart.
+var reloadable = []string{
+	"socket_path",
+	"tenants",
+	// the settings of profiles are compared as applied
+	"profiles",
+	"mqtt_url",
+	"mqtt_clientid",
+	"mqtt_clientid_version",
+	"mqtt_username",
+	"mqtt_password",
+	"mqtt_qos",
+	"mqtt_topic_receive",
+	"mqtt_topic_send",
+	"mqtt_topic_telemetry",
+	"mqtt_reconnect_interval",
+	"log_level",
+	"log_format",
+	"log_repeat_window",
+	"log_output",
+	"log_syslog_network",
+	"log_syslog_address",
+}
+
+// configSettle is how long a watched config file must stay unchanged
+// before it is reloaded, so that a file written in parts is read whole.
+const configSettle = 500 * time.Millisecond
+
+// reloadOnHangup reloads the config file on every SIGHUP and, with
+// config_watch, whenever the file changes, until ctx is done.
+func reloadOnHangup(ctx context.Context) error {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	var changed <-chan struct{}
+	if path := viper.ConfigFileUsed(); path != "" && viper.GetBool("config_watch") {
+		var err error
+		if changed, err = watchConfig(ctx, path); err != nil {
+			return fmt.Errorf("config_watch: %w", err)
+		}
+		slog.Info("Reload: watching config", "path", path)
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-hup:
+		case <-changed:
+		}
+		if err := reload(ctx); err != nil {
+			slog.Error("Reload", "path", viper.ConfigFileUsed(), "err", err)
+		}
+	}
+}
+
+// watchConfig signals on the returned channel when the file at path
+// changed, configSettle after the last change. It watches the directory,
+// so that a file replaced by a rename, as editors and the ConfigMap volumes
+// of Kubernetes do, is followed.
+func watchConfig(ctx context.Context, path string) (<-chan struct{}, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	if err := w.Add(filepath.Dir(path)); err != nil {
+		w.Close() //nolint:errcheck
+		return nil, err
+	}
+	changed := make(chan struct{}, 1)
+	go func() {
+		defer w.Close() //nolint:errcheck
+		// a ConfigMap volume swaps the directory path's symlink leads to
+		target, _ := filepath.EvalSymlinks(path)
+		var settle <-chan time.Time
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case err, ok := <-w.Errors:
+				if !ok {
+					return
+				}
+				slog.Warn("Reload: watch", "path", path, "err", err)
+			case e, ok := <-w.Events:
+				if !ok {
+					return
+				}
+				t, _ := filepath.EvalSymlinks(path)
+				if e.Name != path && t == target {
+					continue
+				}
+				target = t
+				settle = time.After(configSettle)
+			case <-settle:
+				settle = nil
+				select {
+				case changed <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+	return changed, nil
+}
+
+// reload re-reads the config file and applies changed broker settings and
+// socket_path: each tenant whose broker settings changed switches to a new
+// connection, as on a credential rotation, and the local API moves to the
+// new socket. Nothing else is touched, so routes stay programmed.
+func reload(ctx context.Context) error {
+	before := viper.AllSettings()
+	if err := viper.ReadInConfig(); err != nil {
+		return err
+	}
+	if err := applyProfile(); err != nil {
+		return err
+	}
+	slog.Info("Reload: read config", "path", viper.ConfigFileUsed())
+	var errs []error
+	if err := logging.Setup(os.Stderr, logOptions()); err != nil {
+		errs = append(errs, err)
+	}
+	after := viper.AllSettings()
+	for key := range after {
+		if _, ok := before[key]; !ok {
+			before[key] = nil
+		}
+	}
+	for key := range before {
+		if !slices.Contains(reloadable, key) && !reflect.DeepEqual(before[key], after[key]) {
+			slog.Warn("Reload: setting changed; restart to apply", "key", key)
+		}
+	}
+
+	tenants, err := loadTenants()
+	if err != nil {
+		return errors.Join(append(errs, err)...)
+	}
+	for _, t := range tenants.All() {
+		if err := reloadTenant(ctx, t); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, t := range tenantMap.All() {
+		if tenants.Get(t.Name) == nil {
+			slog.Warn("Reload: tenant removed; restart to apply", "tenant", t.Name)
+		}
+	}
+
+	if socketPath := viper.GetString("socket_path"); socketPath != l.SocketPath {
+		if err := l.Rebind(socketPath); err != nil {
+			errs = append(errs, err)
+		} else {
+			slog.Info("Reload: local API moved", "path", socketPath)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// reloadTenant switches the remote of the running tenant named like t to
+// the broker settings of t, if they changed.
+func reloadTenant(ctx context.Context, t *tenant.Tenant) error {
+	cur := tenantMap.Get(t.Name)
+	if cur == nil {
+		slog.Warn("Reload: tenant added; restart to apply", "tenant", t.Name)
+		return nil
+	}
+	if !reflect.DeepEqual(withoutBroker(*t), withoutBroker(*cur)) {
+		slog.Warn("Reload: tenant changed besides its broker settings; restart to apply", "tenant", t.Name)
+	}
+	d := domains[cur]
+	s := d.remote.Settings()
+	next := s
+	next.URL, next.ClientID, next.QoS = t.MQTTURL, clientID(t.MQTTClientID), byte(viper.GetInt("mqtt_qos"))
+	next.TopicRX, next.TopicTX, next.TopicTelemetry = t.MQTTTopicReceive, t.MQTTTopicSend, t.MQTTTopicTelemetry
+	next.ReconnectInterval = viper.GetDuration("mqtt_reconnect_interval")
+	// rotated credentials stay in use unless the configured ones changed
+	if t.MQTTUsername != cur.MQTTUsername || t.MQTTPassword != cur.MQTTPassword {
+		next.Username, next.Password = t.MQTTUsername, t.MQTTPassword
+	}
+	if next == s {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+	if err := d.remote.Reconfigure(ctx, next); err != nil {
+		return err
+	}
+	// only reload reads the broker settings of running tenants
+	cur.MQTTURL, cur.MQTTClientID, cur.MQTTUsername, cur.MQTTPassword = t.MQTTURL, t.MQTTClientID, t.MQTTUsername, t.MQTTPassword
+	cur.MQTTTopicReceive, cur.MQTTTopicSend, cur.MQTTTopicTelemetry = t.MQTTTopicReceive, t.MQTTTopicSend, t.MQTTTopicTelemetry
+	slog.Info("Reload: tenant switched broker", "tenant", t.Name, "broker", brokerDescription(next))
+	return nil
+}
+
+// withoutBroker is t without the settings reloadTenant applies.
+func withoutBroker(t tenant.Tenant) tenant.Tenant {
+	t.MQTTURL, t.MQTTClientID, t.MQTTUsername, t.MQTTPassword = "", "", "", ""
+	t.MQTTTopicReceive, t.MQTTTopicSend, t.MQTTTopicTelemetry = "", "", ""
+	return t
+}
+
+func brokerDescription(s remote.Settings) string {
+	return s.URL + " (receive " + s.TopicRX + ", send " + s.TopicTX + ")"
+}
@@ -0,0 +1,66 @@
+// Package replay refuses control messages that were already received or
+// are too old, so a captured Route can't be replayed later to resurrect a
+// withdrawn route.
+package replay
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/datum-cloud/galactic-agent/api/remote"
+)
+
+var (
+	ErrUnstamped = errors.New("envelope has no timestamp or nonce")
+	ErrStale     = errors.New("envelope outside acceptance window")
+	ErrReplayed  = errors.New("envelope nonce already seen")
+)
+
+// Guard accepts an envelope once, if its send time is within Window of the
+// local clock. Nonces are remembered for twice the window, after which the
+// timestamp alone rejects them.
+type Guard struct {
+	Window time.Duration
+
+	mu   sync.Mutex
+	seen map[string]time.Time // nonce -> when it may be forgotten
+	next time.Time            // next sweep of seen
+}
+
+// Check records envelope and returns an error if it must be dropped. A nil
+// Guard or a zero Window accepts everything.
+func (g *Guard) Check(envelope *remote.Envelope) error {
+	if g == nil || g.Window <= 0 {
+		return nil
+	}
+	if envelope.SentUnixNano == 0 || len(envelope.Nonce) == 0 {
+		return ErrUnstamped
+	}
+	now := time.Now()
+	sent := time.Unix(0, envelope.SentUnixNano)
+	if skew := now.Sub(sent); skew > g.Window || skew < -g.Window {
+		return fmt.Errorf("%w: sent %s ago", ErrStale, skew.Round(time.Millisecond))
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.seen == nil {
+		g.seen = make(map[string]time.Time)
+	}
+	if now.After(g.next) {
+		for nonce, expiry := range g.seen {
+			if now.After(expiry) {
+				delete(g.seen, nonce)
+			}
+		}
+		g.next = now.Add(g.Window)
+	}
+	nonce := string(envelope.Nonce)
+	if _, ok := g.seen[nonce]; ok {
+		return ErrReplayed
+	}
+	g.seen[nonce] = now.Add(2 * g.Window)
+	return nil
+}
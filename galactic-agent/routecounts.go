@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/datum-cloud/galactic-agent/metrics"
+	"github.com/datum-cloud/galactic-agent/state"
+)
+
+var (
+	attachmentRoutes = metrics.NewGauge(
+		"galactic_agent_attachment_routes",
+		"Routes of each attachment, by kind: ingress, egress, or neighbor for the proxy neighbor entries of its host routes.",
+		"vpc", "vpcattachment", "kind",
+	)
+	attachmentChanged = metrics.NewGauge(
+		"galactic_agent_attachment_last_change_timestamp_seconds",
+		"When the routes of each attachment last changed, as a Unix time.",
+		"vpc", "vpcattachment",
+	)
+)
+
+// The kinds of routes counted, indexes of routeCount.
+const (
+	ingressRoutes = iota
+	egressRoutes
+	neighborRoutes
+)
+
+// routeKinds are the kind labels of attachmentRoutes.
+var routeKinds = [...]string{"ingress", "egress", "neighbor"}
+
+type routeCount [len(routeKinds)]int
+
+type attachmentID struct {
+	vpc, vpcAttachment string
+}
+
+// routeCounter keeps the attachment gauges in line with the tenant stores.
+type routeCounter struct {
+	wake   chan struct{}
+	counts map[attachmentID]routeCount
+}
+
+var routeCounts = &routeCounter{wake: make(chan struct{}, 1)}
+
+// notify asks run to count again; it never blocks, so stores may call it
+// with their lock held.
+func (c *routeCounter) notify() {
+	select {
+	case c.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run counts the routes of every attachment whenever a store changes.
+func (c *routeCounter) run(ctx context.Context) error {
+	c.notify()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-c.wake:
+			c.update(time.Now())
+		}
+	}
+}
+
+// count tallies the routes of each attachment in the tenant stores.
+func (c *routeCounter) count() map[attachmentID]routeCount {
+	counts := make(map[attachmentID]routeCount)
+	add := func(srv6Endpoint string, kind int) {
+		vpc, vpcAttachment, err := endpointAttachment(srv6Endpoint)
+		if err != nil {
+			return
+		}
+		id := attachmentID{vpc, vpcAttachment}
+		n := counts[id]
+		n[kind]++
+		counts[id] = n
+	}
+	for _, t := range tenantMap.All() {
+		st := domains[t].store.Snapshot()
+		for _, ep := range st.Ingress {
+			add(ep, ingressRoutes)
+		}
+		for _, e := range st.Egress {
+			add(e.Endpoint, egressRoutes)
+			if state.IsHost(e.Network) {
+				add(e.Endpoint, neighborRoutes)
+			}
+		}
+	}
+	return counts
+}
+
+// update sets the gauges of the attachments whose counts changed since the
+// last update, stamped now, and drops those of attachments left with none.
+func (c *routeCounter) update(now time.Time) {
+	counts := c.count()
+	for id, n := range counts {
+		if old, ok := c.counts[id]; ok && old == n {
+			continue
+		}
+		for kind, name := range routeKinds {
+			attachmentRoutes.Set(float64(n[kind]), id.vpc, id.vpcAttachment, name)
+		}
+		attachmentChanged.Set(float64(now.Unix()), id.vpc, id.vpcAttachment)
+	}
+	for id := range c.counts {
+		if _, ok := counts[id]; ok {
+			continue
+		}
+		for _, name := range routeKinds {
+			attachmentRoutes.Delete(id.vpc, id.vpcAttachment, name)
+		}
+		attachmentChanged.Delete(id.vpc, id.vpcAttachment)
+	}
+	c.counts = counts
+}
@@ -0,0 +1,123 @@
+// Package routeleak lets hosts outside the VPCs, such as the Windows side
+// of WSL, reach VPC prefixes: policy rules send their traffic, arriving on
+// the uplink interface, into the VRF of an attachment, and routes in the
+// VRF send the replies back out of the uplink.
+package routeleak
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+)
+
+// RulePriority is the priority of the leak rules; it orders them ahead of
+// the main table but leaves room for the l3mdev rule at 1000.
+const RulePriority = 900
+
+// Leak routes traffic for Prefixes into the VRF with Table.
+type Leak struct {
+	Table    int
+	Prefixes []*net.IPNet
+}
+
+// Sync makes the kernel hold exactly the rules for leaks on the uplink
+// iface, and a route back to each of hosts in every leaked table.
+func Sync(iface string, leaks []Leak, hosts []net.IP) error {
+	link, err := netlink.LinkByName(iface)
+	if err != nil {
+		return err
+	}
+	want := make(map[string]netlink.Rule)
+	for _, l := range leaks {
+		for _, p := range l.Prefixes {
+			r := netlink.NewRule()
+			r.Priority = RulePriority
+			r.Family = family(p.IP)
+			r.IifName = iface
+			r.Dst = p
+			r.Table = l.Table
+			want[ruleKey(*r)] = *r
+		}
+	}
+	for _, f := range []int{netlink.FAMILY_V4, netlink.FAMILY_V6} {
+		rules, err := netlink.RuleListFiltered(f, &netlink.Rule{Priority: RulePriority}, netlink.RT_FILTER_PRIORITY)
+		if err != nil {
+			return err
+		}
+		for _, r := range rules {
+			if r.IifName != iface {
+				continue
+			}
+			if _, ok := want[ruleKey(r)]; ok {
+				delete(want, ruleKey(r))
+				continue
+			}
+			if err := netlink.RuleDel(&r); err != nil {
+				return fmt.Errorf("delete rule to %s: %w", r.Dst, err)
+			}
+		}
+	}
+	for _, r := range want {
+		if err := netlink.RuleAdd(&r); err != nil {
+			return fmt.Errorf("add rule to %s: %w", r.Dst, err)
+		}
+	}
+
+	for _, l := range leaks {
+		if err := syncReturn(link, l.Table, hosts); err != nil {
+			return fmt.Errorf("table %d: %w", l.Table, err)
+		}
+	}
+	return nil
+}
+
+// syncReturn keeps one link route per host out of the uplink in table. Such
+// routes are only ever added by Sync, as the uplink is not in a VRF.
+func syncReturn(link netlink.Link, table int, hosts []net.IP) error {
+	want := make(map[string]net.IP, len(hosts))
+	for _, h := range hosts {
+		want[h.String()] = h
+	}
+	routes, err := netlink.RouteListFiltered(netlink.FAMILY_ALL, &netlink.Route{Table: table, LinkIndex: link.Attrs().Index},
+		netlink.RT_FILTER_TABLE|netlink.RT_FILTER_OIF)
+	if err != nil {
+		return err
+	}
+	for _, r := range routes {
+		if r.Dst != nil {
+			if ones, bits := r.Dst.Mask.Size(); ones == bits {
+				if _, ok := want[r.Dst.IP.String()]; ok {
+					delete(want, r.Dst.IP.String())
+					continue
+				}
+			}
+		}
+		if err := netlink.RouteDel(&r); err != nil {
+			return err
+		}
+	}
+	for _, h := range want {
+		if err := netlink.RouteReplace(&netlink.Route{
+			Dst:       netlink.NewIPNet(h),
+			LinkIndex: link.Attrs().Index,
+			Table:     table,
+			Scope:     netlink.SCOPE_LINK,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func family(ip net.IP) int {
+	if ip.To4() != nil {
+		return unix.AF_INET
+	}
+	return unix.AF_INET6
+}
+
+func ruleKey(r netlink.Rule) string {
+	return fmt.Sprintf("%s/%d", r.Dst, r.Table)
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"slices"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+
+	"github.com/datum-cloud/galactic-agent/api/remote"
+	"github.com/datum-cloud/galactic-agent/metrics"
+	"github.com/datum-cloud/galactic-agent/state"
+	"github.com/datum-cloud/galactic-agent/tenant"
+)
+
+// maxRouteChanges bounds the changes held while the controller's broker is
+// slow; more are counted and dropped.
+const maxRouteChanges = 4096
+
+var (
+	routeMonitorChanges = metrics.NewCounter(
+		"galactic_agent_route_monitor_changes_total",
+		"Kernel route changes in attachment VRFs reported to the controller, by op and whether the agent's state held the route.",
+		"op", "desired",
+	)
+	routeMonitorDropped = metrics.NewCounter(
+		"galactic_agent_route_monitor_dropped_total",
+		"Kernel route changes in attachment VRFs dropped because the controller's broker did not keep up.",
+	)
+)
+
+// monitor streams the kernel route changes in the VRFs of registered
+// attachments to their controllers; nil unless route_monitor is set.
+var monitor *routeMonitor
+
+type routeMonitor struct {
+	interval time.Duration
+	stale    atomic.Bool // the VRF index needs rebuilding
+}
+
+// observed is a route change in the VRF of an attachment of tenant.
+type observed struct {
+	tenant *tenant.Tenant
+	change *remote.RouteChange
+}
+
+// vrfOwner is the attachment a VRF table belongs to.
+type vrfOwner struct {
+	tenant   *tenant.Tenant
+	endpoint string
+}
+
+func loadRouteMonitor() (*routeMonitor, error) {
+	if !viper.GetBool("route_monitor") {
+		return nil, nil
+	}
+	if !kernelDatapath() {
+		return nil, errors.New("route_monitor needs the kernel datapath")
+	}
+	interval := viper.GetDuration("route_monitor_interval")
+	if interval <= 0 {
+		return nil, errors.New("route_monitor_interval must be positive")
+	}
+	m := &routeMonitor{interval: interval}
+	m.stale.Store(true)
+	return m, nil
+}
+
+// notify makes the monitor look up the VRFs of the attachments again; it
+// never blocks, so stores may call it with their lock held.
+func (m *routeMonitor) notify() {
+	m.stale.Store(true)
+}
+
+// run watches the kernel's routes until ctx is done, subscribing again
+// whenever the subscription fails, and sends what changed every interval.
+func (m *routeMonitor) run(ctx context.Context) error {
+	batches := make(chan []observed)
+	go m.send(ctx, batches)
+	var pending []observed
+	for {
+		err := m.watch(ctx, batches, &pending)
+		if ctx.Err() != nil {
+			return nil
+		}
+		slog.Warn("Route monitor", "err", err)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+func (m *routeMonitor) watch(ctx context.Context, batches chan<- []observed, pending *[]observed) error {
+	done := make(chan struct{})
+	defer close(done)
+
+	updates := make(chan netlink.RouteUpdate, 256)
+	failed := make(chan error, 1)
+	onError := func(err error) {
+		select {
+		case failed <- err:
+		default:
+		}
+	}
+	if err := netlink.RouteSubscribeWithOptions(updates, done, netlink.RouteSubscribeOptions{ErrorCallback: onError}); err != nil {
+		return err
+	}
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+	var owners map[int]vrfOwner
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-failed:
+			return err
+		case u, ok := <-updates:
+			if !ok {
+				return errClosed
+			}
+			if m.stale.Swap(false) {
+				owners = vrfOwners()
+			}
+			owner, ok := owners[u.Table]
+			if !ok || !reportable(u.Route) {
+				continue
+			}
+			if len(*pending) == maxRouteChanges {
+				routeMonitorDropped.Inc()
+				continue
+			}
+			*pending = append(*pending, observed{tenant: owner.tenant, change: routeChange(u, owner.endpoint)})
+		case <-ticker.C:
+			if len(*pending) == 0 {
+				continue
+			}
+			// a sender still busy with the last batch gets this one, and
+			// what changes meanwhile, at a later tick
+			select {
+			case batches <- *pending:
+				*pending = nil
+			default:
+			}
+		}
+	}
+}
+
+var errClosed = errors.New("netlink subscription closed")
+
+// vrfOwners indexes the VRF tables of the registered attachments.
+func vrfOwners() map[int]vrfOwner {
+	owners := make(map[int]vrfOwner)
+	for _, t := range tenantMap.All() {
+		for _, a := range domains[t].store.Snapshot().Attachments {
+			owners[a.Table] = vrfOwner{tenant: t, endpoint: a.Endpoint}
+		}
+	}
+	return owners
+}
+
+// reportable leaves out the routes the kernel derives from addresses and
+// the local and broadcast ones, which only follow interface changes.
+func reportable(r netlink.Route) bool {
+	return r.Protocol != unix.RTPROT_KERNEL && r.Type == unix.RTN_UNICAST
+}
+
+func routeChange(u netlink.RouteUpdate, endpoint string) *remote.RouteChange {
+	c := &remote.RouteChange{
+		Srv6Endpoint:     endpoint,
+		Table:            uint32(u.Table),
+		Protocol:         uint32(u.Protocol),
+		ObservedUnixNano: time.Now().UnixNano(),
+	}
+	if u.Type == unix.RTM_DELROUTE {
+		c.Op = remote.RouteChange_DELETE
+	}
+	switch {
+	case u.Dst != nil:
+		c.Network = u.Dst.String()
+	case u.Family == unix.AF_INET6:
+		c.Network = "::/0"
+	default:
+		c.Network = "0.0.0.0/0"
+	}
+	if encap, ok := u.Encap.(*netlink.SEG6Encap); ok {
+		// the kernel holds segments last first, see util.ParseSegments
+		for i := len(encap.Segments) - 1; i >= 0; i-- {
+			c.Srv6Segments = append(c.Srv6Segments, encap.Segments[i].String())
+		}
+		return c
+	}
+	if u.Gw != nil {
+		c.Gateway = u.Gw.String()
+	}
+	if link, err := netlink.LinkByIndex(u.LinkIndex); err == nil {
+		c.Device = link.Attrs().Name
+	} else if u.LinkIndex != 0 {
+		c.Device = strconv.Itoa(u.LinkIndex)
+	}
+	return c
+}
+
+// send reports each batch to the controllers of the tenants it concerns,
+// marking the changes the agent's state accounts for.
+func (m *routeMonitor) send(ctx context.Context, batches <-chan []observed) {
+	for {
+		var batch []observed
+		select {
+		case <-ctx.Done():
+			return
+		case batch = <-batches:
+		}
+		byTenant := make(map[*tenant.Tenant]*remote.RouteChanges)
+		for _, o := range batch {
+			rc, ok := byTenant[o.tenant]
+			if !ok {
+				rc = &remote.RouteChanges{}
+				byTenant[o.tenant] = rc
+			}
+			rc.Changes = append(rc.Changes, o.change)
+		}
+		for t, rc := range byTenant {
+			d := domains[t]
+			desired := desiredRoutes(d.store.Snapshot())
+			for _, c := range rc.Changes {
+				segments, ok := desired[routeKey{c.Table, c.Network}]
+				c.Desired = ok && (c.Op == remote.RouteChange_DELETE || slices.Equal(segments, c.Srv6Segments))
+				routeMonitorChanges.Inc(c.Op.String(), strconv.FormatBool(c.Desired))
+			}
+			sendRouteChanges(ctx, d, rc)
+		}
+	}
+}
+
+type routeKey struct {
+	table   uint32
+	network string
+}
+
+// desiredRoutes maps the egress routes of st, in the VRF table of their
+// attachment, to their segments, all in canonical form.
+func desiredRoutes(st state.State) map[routeKey][]string {
+	tables := make(map[string]uint32, len(st.Attachments))
+	for _, a := range st.Attachments {
+		tables[canonicalIP(a.Endpoint)] = uint32(a.Table)
+	}
+	desired := make(map[routeKey][]string, len(st.Egress))
+	for _, e := range st.Egress {
+		table, ok := tables[canonicalIP(e.Endpoint)]
+		if !ok {
+			continue
+		}
+		_, network, err := net.ParseCIDR(e.Network)
+		if err != nil {
+			continue
+		}
+		segments := make([]string, 0, len(e.Segments))
+		for _, s := range e.Segments {
+			segments = append(segments, canonicalIP(s))
+		}
+		desired[routeKey{table, network.String()}] = segments
+	}
+	return desired
+}
+
+func sendRouteChanges(ctx context.Context, d *domain, rc *remote.RouteChanges) {
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+	tenantEnvelopes.Inc(d.Name, "send")
+	if err := d.remote.SendTelemetry(ctx, &remote.Envelope{Kind: &remote.Envelope_RouteChanges{RouteChanges: rc}}); err != nil {
+		slog.Warn("Send route changes", "tenant", d.Name, "err", err)
+	}
+}
+
+// canonicalIP formats ip as net.IP does, so that equal addresses compare
+// equal as strings.
+func canonicalIP(ip string) string {
+	if parsed := net.ParseIP(ip); parsed != nil {
+		return parsed.String()
+	}
+	return ip
+}
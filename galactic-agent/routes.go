@@ -0,0 +1,298 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/datum-cloud/galactic-agent/api/local"
+	"github.com/datum-cloud/galactic-agent/client"
+	"github.com/datum-cloud/galactic-agent/reconcile"
+	"github.com/datum-cloud/galactic-agent/srv6/endpoint"
+	"github.com/datum-cloud/galactic-agent/state"
+)
+
+func newRoutesCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "routes",
+		Short: "Inspect the routes programmed by the agent",
+	}
+	cmd.AddCommand(newRoutesDiffCmd(), newRoutesShowCmd())
+	return cmd
+}
+
+func newRoutesDiffCmd() *cobra.Command {
+	var fix bool
+	cmd := &cobra.Command{
+		Use:   "diff",
+		Short: "Compare the agent's desired state with the kernel",
+		Long: `Compare the agent's desired state with the routes and proxy neighbors in
+the kernel. Lines start with + for state missing from the kernel, - for
+kernel state the agent does not want and ~ for state programmed differently.
+The command exits non-zero when differences remain.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			key, err := stateKey()
+			if err != nil {
+				return fmt.Errorf("state key: %w", err)
+			}
+			tenants, err := loadTenants()
+			if err != nil {
+				return err
+			}
+			var changes []reconcile.Change
+			for _, t := range tenants.All() {
+				st, err := state.LoadSealed(t.StatePath, key)
+				if err != nil {
+					return fmt.Errorf("state store of tenant %s: %w", t.Name, err)
+				}
+				c, err := reconcile.Diff(st, t.SRv6Net)
+				if err != nil {
+					return err
+				}
+				changes = append(changes, c...)
+			}
+			reconcile.Write(os.Stdout, changes)
+			if len(changes) == 0 {
+				return nil
+			}
+			if !fix {
+				return fmt.Errorf("%d differences", len(changes))
+			}
+			if failed := fixChanges(changes); failed > 0 {
+				return fmt.Errorf("%d of %d differences could not be fixed", failed, len(changes))
+			}
+			fmt.Printf("Fixed %d differences\n", len(changes))
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&fix, "fix", false, "reconcile the kernel with the desired state")
+	return cmd
+}
+
+// fixChanges applies changes, reporting failures on stderr, and returns how
+// many failed.
+func fixChanges(changes []reconcile.Change) int {
+	failed := 0
+	for _, c := range changes {
+		if err := c.Fix(); err != nil {
+			fmt.Fprintf(os.Stderr, "fix %s: %v\n", c, err) //nolint:errcheck
+			failed++
+		}
+	}
+	return failed
+}
+
+func newRoutesShowCmd() *cobra.Command {
+	var socketPath string
+	req := &local.GetRoutesRequest{}
+	cmd := &cobra.Command{
+		Use:   "show",
+		Short: "List the routes of a VPC in the running agent's state and in the kernel",
+		Long: `Ask the running agent for the ingress and egress routes and proxy
+neighbors of a VPC, or of one attachment, as its state and the kernel hold
+them. Lines start with + for routes missing from the kernel, - for routes
+only the kernel holds and ~ for routes programmed differently.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+			defer stop()
+
+			if socketPath == "" {
+				socketPath = viper.GetString("socket_path")
+			}
+			c, err := client.New(socketPath)
+			if err != nil {
+				return err
+			}
+			defer c.Close() //nolint:errcheck
+			reply, err := c.GetRoutes(ctx, req)
+			if err != nil {
+				return err
+			}
+			writeRoutes(reply)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&socketPath, "socket", "", "agent socket (default: socket_path)")
+	cmd.Flags().StringVar(&req.Vpc, "vpc", "", "vpc id (hex)")
+	cmd.Flags().StringVar(&req.Vpcattachment, "vpcattachment", "", "attachment id (hex) (default: all of the vpc)")
+	_ = cmd.MarkFlagRequired("vpc")
+	return cmd
+}
+
+// writeRoutes prints a GetRoutes reply, marking routes the way routes diff
+// does.
+func writeRoutes(reply *local.GetRoutesReply) {
+	if e := reply.GetKernelError(); e != "" {
+		fmt.Printf("kernel not read: %s\n", e)
+	}
+	for _, r := range reply.GetRoutes() {
+		op := " "
+		switch {
+		case !r.GetInKernel() && reply.GetKernelError() == "":
+			op = string(reconcile.Missing)
+		case !r.GetInState():
+			op = string(reconcile.Extra)
+		case r.GetMismatch() != "":
+			op = string(reconcile.Mismatch)
+		}
+		line := fmt.Sprintf("%s %-8s %s table %d", op, r.GetKind(), r.GetVpcattachment(), r.GetTable())
+		if r.GetNetwork() != "" {
+			line += " " + r.GetNetwork()
+		}
+		line += " via " + r.GetSrv6Endpoint()
+		segments := r.GetSrv6Segments()
+		if !r.GetInState() {
+			segments = r.GetKernelSegments()
+		}
+		if len(segments) > 0 {
+			line += " segments " + strings.Join(segments, ",")
+		}
+		if r.GetMismatch() != "" {
+			line += " (" + r.GetMismatch() + ")"
+		}
+		fmt.Println(line)
+	}
+}
+
+// getRoutes serves the GetRoutes RPC: it lists the routes of the attachments
+// of the VPC in the state of its tenant and, with the kernel datapath, sets
+// those the kernel holds for them beside.
+func getRoutes(ctx context.Context, req *local.GetRoutesRequest) (*local.GetRoutesReply, error) {
+	given := req.GetVpcattachment() != ""
+	vpcAttachment := req.GetVpcattachment()
+	if !given {
+		vpcAttachment = "0"
+	}
+	vpc, vpcAttachment, err := endpoint.PadHex(req.GetVpc(), vpcAttachment)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	t := tenantMap.For(vpc)
+	st := vpcState(domains[t].store.Snapshot(), vpc, vpcAttachment, given)
+	if len(st.Attachments) == 0 {
+		return nil, status.Errorf(codes.NotFound, "no attachment of vpc %s registered", req.GetVpc())
+	}
+
+	byEndpoint := make(map[string]state.Attachment, len(st.Attachments))
+	byTable := make(map[int]state.Attachment, len(st.Attachments))
+	for _, a := range st.Attachments {
+		byEndpoint[canonicalIP(a.Endpoint)] = a
+		byTable[a.Table] = a
+	}
+	type routeID struct {
+		kind, endpoint, dst string
+	}
+	rows := make(map[routeID]*local.ProgrammedRoute)
+	row := func(kind string, a state.Attachment, dst string) *local.ProgrammedRoute {
+		id := routeID{kind, canonicalIP(a.Endpoint), dst}
+		r, ok := rows[id]
+		if !ok {
+			r = &local.ProgrammedRoute{Kind: kind, Vpcattachment: a.VPCAttachment, Srv6Endpoint: a.Endpoint, Table: uint32(a.Table)}
+			if kind != "ingress" {
+				r.Network = dst
+			}
+			rows[id] = r
+		}
+		return r
+	}
+	for _, ep := range st.Ingress {
+		row("ingress", byEndpoint[canonicalIP(ep)], "").InState = true
+	}
+	for _, e := range st.Egress {
+		a := byEndpoint[canonicalIP(e.Endpoint)]
+		network := e.Network
+		if _, n, err := net.ParseCIDR(e.Network); err == nil {
+			network = n.String()
+		}
+		r := row("egress", a, network)
+		r.InState, r.Srv6Segments = true, e.Segments
+		if state.IsHost(e.Network) {
+			row("neighbor", a, canonicalIP(strings.SplitN(e.Network, "/", 2)[0])).InState = true
+		}
+	}
+
+	reply := &local.GetRoutesReply{}
+	if kernelDatapath() {
+		kernel, err := reconcile.Kernel(st, t.SRv6Net)
+		if err != nil {
+			reply.KernelError = err.Error()
+		}
+		for _, k := range kernel {
+			var r *local.ProgrammedRoute
+			if k.Kind == "ingress" {
+				a, ok := byEndpoint[k.Dst]
+				if !ok {
+					a.Endpoint = k.Dst
+					if _, id, err := endpointAttachment(k.Dst); err == nil {
+						a.VPCAttachment = id
+					}
+				}
+				r = row(k.Kind, a, "")
+				if ok && k.Table != a.Table && k.Detail == "" {
+					k.Detail = fmt.Sprintf("vrftable %d, want %d", k.Table, a.Table)
+				}
+			} else {
+				r = row(k.Kind, byTable[k.Table], k.Dst)
+			}
+			r.InKernel, r.KernelSegments, r.Mismatch = true, k.Segments, k.Detail
+			if r.InState && r.Mismatch == "" && !sameIPs(r.Srv6Segments, k.Segments) {
+				r.Mismatch = fmt.Sprintf("segments %v, want %v", k.Segments, r.Srv6Segments)
+			}
+		}
+	} else {
+		reply.KernelError = "the datapath is not the kernel's"
+	}
+
+	for _, r := range rows {
+		reply.Routes = append(reply.Routes, r)
+	}
+	slices.SortFunc(reply.Routes, func(a, b *local.ProgrammedRoute) int {
+		return cmp.Or(
+			cmp.Compare(a.GetKind(), b.GetKind()),
+			cmp.Compare(a.GetVpcattachment(), b.GetVpcattachment()),
+			cmp.Compare(a.GetNetwork(), b.GetNetwork()),
+		)
+	})
+	return reply, nil
+}
+
+// vpcState returns the part of st that concerns the attachments of
+// vpc, or only vpcAttachment if given: their registry entries, ingress and
+// egress routes.
+func vpcState(st state.State, vpc, vpcAttachment string, given bool) state.State {
+	var out state.State
+	endpoints := make(map[string]bool)
+	for _, a := range st.Attachments {
+		if a.VPC == vpc && (!given || a.VPCAttachment == vpcAttachment) {
+			out.Attachments = append(out.Attachments, a)
+			endpoints[canonicalIP(a.Endpoint)] = true
+		}
+	}
+	for _, ep := range st.Ingress {
+		if endpoints[canonicalIP(ep)] {
+			out.Ingress = append(out.Ingress, ep)
+		}
+	}
+	for _, e := range st.Egress {
+		if endpoints[canonicalIP(e.Endpoint)] {
+			out.Egress = append(out.Egress, e)
+		}
+	}
+	return out
+}
+
+// sameIPs reports whether a and b list equal addresses in the same order.
+func sameIPs(a, b []string) bool {
+	return slices.EqualFunc(a, b, func(x, y string) bool { return canonicalIP(x) == canonicalIP(y) })
+}
@@ -0,0 +1,67 @@
+// Package sdnotify speaks the service notification protocol of systemd, see
+// sd_notify(3): the process reports readiness and status, and feeds the
+// watchdog of units with WatchdogSec set.
+package sdnotify
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+// States a process may notify.
+const (
+	Ready    = "READY=1"
+	Stopping = "STOPPING=1"
+	Watchdog = "WATCHDOG=1"
+)
+
+// Status is the state showing s in systemctl status.
+func Status(s string) string {
+	return "STATUS=" + s
+}
+
+// Enabled reports whether the process runs under a service manager
+// listening for notifications.
+func Enabled() bool {
+	return os.Getenv("NOTIFY_SOCKET") != ""
+}
+
+// Notify sends states, one per line, to the service manager. It does
+// nothing unless Enabled.
+func Notify(states ...string) error {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return nil
+	}
+	// a leading @ names a socket in the abstract namespace
+	if socket[0] == '@' {
+		socket = "\x00" + socket[1:]
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	defer conn.Close() //nolint:errcheck
+	var b []byte
+	for _, s := range states {
+		b = append(b, s...)
+		b = append(b, '\n')
+	}
+	_, err = conn.Write(b)
+	return err
+}
+
+// WatchdogInterval returns the time within which the service manager
+// expects Watchdog after the last one, 0 if it does not watch this process.
+func WatchdogInterval() time.Duration {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+	return time.Duration(usec) * time.Microsecond
+}
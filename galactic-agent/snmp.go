@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/viper"
+
+	"github.com/datum-cloud/galactic-agent/agentx"
+)
+
+// defaultAgentXOID is below net-snmp's playpen, 1.3.6.1.4.1.8072.9999.9999,
+// which mibs/GALACTIC-AGENT-MIB.txt is written against. Operators with their
+// own enterprise number set agentx_oid instead.
+const defaultAgentXOID = "1.3.6.1.4.1.8072.9999.9999.7380"
+
+// loadAgentX builds the SNMP subagent from agentx_socket; nil unless it
+// is set.
+func loadAgentX() (*agentx.Subagent, error) {
+	socket := viper.GetString("agentx_socket")
+	if socket == "" {
+		return nil, nil
+	}
+	root, err := agentx.ParseOID(viper.GetString("agentx_oid"))
+	if err != nil {
+		return nil, fmt.Errorf("agentx_oid: %w", err)
+	}
+	return &agentx.Subagent{
+		Socket:  socket,
+		Root:    root,
+		Name:    "galactic-agent",
+		Handler: func() ([]agentx.Var, error) { return snmpVars(root), nil },
+	}, nil
+}
+
+// snmpVars lays out GALACTIC-AGENT-MIB under root:
+//
+//	root.1        scalars: healthy, attachments, ingress routes, egress
+//	              routes, brokers connected, brokers
+//	root.2.1.c.t  attachment table, indexed by VRF table number
+//	root.3.1.c.n  tenant table, indexed by position
+func snmpVars(root agentx.OID) []agentx.Var {
+	var vars []agentx.Var
+	var attachments, ingress, egress, connected, brokers uint32
+	tenants := tenantMap.All()
+	for i, t := range tenants {
+		d := domains[t]
+		st := d.store.Snapshot()
+		up := d.remote.Connected()
+		brokers++
+		if up {
+			connected++
+		}
+		attachments += uint32(len(st.Attachments))
+		ingress += uint32(len(st.Ingress))
+		egress += uint32(len(st.Egress))
+
+		n := uint32(i + 1)
+		entry := root.Append(3, 1)
+		vars = append(vars,
+			agentx.NewInteger(entry.Append(1, n), int64(n)),
+			agentx.NewString(entry.Append(2, n), t.Name),
+			agentx.NewString(entry.Append(3, n), t.MQTTURL),
+			agentx.NewInteger(entry.Append(4, n), truthValue(up)),
+			agentx.NewGauge32(entry.Append(5, n), uint32(len(st.Attachments))),
+			agentx.NewGauge32(entry.Append(6, n), uint32(len(st.Egress))))
+
+		for _, a := range st.Attachments {
+			routes := 0
+			for _, e := range st.Egress {
+				if e.Endpoint == a.Endpoint {
+					routes++
+				}
+			}
+			idx := uint32(a.Table)
+			entry := root.Append(2, 1)
+			vars = append(vars,
+				agentx.NewInteger(entry.Append(1, idx), int64(a.Table)),
+				agentx.NewString(entry.Append(2, idx), a.VPC),
+				agentx.NewString(entry.Append(3, idx), a.VPCAttachment),
+				agentx.NewString(entry.Append(4, idx), a.VRF),
+				agentx.NewString(entry.Append(5, idx), a.Host),
+				agentx.NewString(entry.Append(6, idx), a.Endpoint),
+				agentx.NewString(entry.Append(7, idx), strings.Join(a.Networks, ",")),
+				agentx.NewGauge32(entry.Append(8, idx), uint32(routes)),
+				agentx.NewInteger(entry.Append(9, idx), int64(a.MTU)),
+				agentx.NewString(entry.Append(10, idx), t.Name))
+		}
+	}
+	scalars := root.Append(1)
+	vars = append(vars,
+		agentx.NewInteger(scalars.Append(1, 0), truthValue(connected == brokers)),
+		agentx.NewGauge32(scalars.Append(2, 0), attachments),
+		agentx.NewGauge32(scalars.Append(3, 0), ingress),
+		agentx.NewGauge32(scalars.Append(4, 0), egress),
+		agentx.NewGauge32(scalars.Append(5, 0), connected),
+		agentx.NewGauge32(scalars.Append(6, 0), brokers))
+	return vars
+}
+
+// truthValue is SNMPv2-TC's TruthValue.
+func truthValue(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 2
+}
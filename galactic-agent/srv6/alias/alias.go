@@ -0,0 +1,48 @@
+// Package alias resolves symbolic segment names, such as pop-ams-gw1, to
+// SRv6 SIDs so controllers and operators need not repeat raw addresses.
+package alias
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// Table maps segment names to SIDs. Names are case-insensitive, matching
+// how viper reads map keys from config.
+type Table map[string]string
+
+// Parse validates an alias table, typically the segment_aliases config map.
+// Names must not themselves parse as addresses and SIDs must be IPv6.
+func Parse(aliases map[string]string) (Table, error) {
+	t := make(Table, len(aliases))
+	for name, sid := range aliases {
+		if net.ParseIP(name) != nil {
+			return nil, fmt.Errorf("alias %q is an address", name)
+		}
+		ip := net.ParseIP(sid)
+		if ip == nil || ip.To4() != nil {
+			return nil, fmt.Errorf("alias %q: %q is not an IPv6 address", name, sid)
+		}
+		t[strings.ToLower(name)] = ip.String()
+	}
+	return t, nil
+}
+
+// Resolve returns segments with every alias replaced by its SID. Addresses
+// are passed through unchanged; unknown names are an error.
+func (t Table) Resolve(segments []string) ([]string, error) {
+	resolved := make([]string, len(segments))
+	for i, s := range segments {
+		if net.ParseIP(s) != nil {
+			resolved[i] = s
+			continue
+		}
+		sid, ok := t[strings.ToLower(s)]
+		if !ok {
+			return nil, fmt.Errorf("unknown segment alias %q", s)
+		}
+		resolved[i] = sid
+	}
+	return resolved, nil
+}
@@ -0,0 +1,69 @@
+// Package allowlist restricts the SIDs received segment lists may contain
+// to known locators, so a compromised controller can't steer tenant
+// traffic to arbitrary destinations.
+package allowlist
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+)
+
+// ErrRejected is matched by every *RejectedError.
+var ErrRejected = errors.New("segment not in allowlist")
+
+// RejectedError names the first segment outside the allowlist.
+type RejectedError struct {
+	Segment string
+}
+
+func (e *RejectedError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Segment, ErrRejected)
+}
+
+func (e *RejectedError) Unwrap() error {
+	return ErrRejected
+}
+
+// List is a set of IPv6 locator prefixes. An empty list allows any segment.
+type List []netip.Prefix
+
+// Parse validates prefixes, typically the segment_allowlist config.
+func Parse(prefixes []string) (List, error) {
+	l := make(List, 0, len(prefixes))
+	for _, p := range prefixes {
+		prefix, err := netip.ParsePrefix(p)
+		if err != nil {
+			return nil, err
+		}
+		if !prefix.Addr().Is6() || prefix.Addr().Is4In6() {
+			return nil, fmt.Errorf("%q is not an IPv6 prefix", p)
+		}
+		l = append(l, prefix.Masked())
+	}
+	return l, nil
+}
+
+// Check returns a *RejectedError for the first segment outside the list.
+// Segments must already be resolved to addresses.
+func (l List) Check(segments []string) error {
+	if len(l) == 0 {
+		return nil
+	}
+	for _, s := range segments {
+		addr, err := netip.ParseAddr(s)
+		if err != nil || !l.contains(addr) {
+			return &RejectedError{Segment: s}
+		}
+	}
+	return nil
+}
+
+func (l List) contains(addr netip.Addr) bool {
+	for _, p := range l {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,88 @@
+package srv6
+
+import (
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/datum-cloud/galactic-agent/srv6/endpoint"
+	"github.com/datum-cloud/galactic-common/util"
+)
+
+// maxParsed bounds the parse caches; past it they start over, which only
+// costs the parsing they save.
+const maxParsed = 65536
+
+// parsed caches what the egress path parses again for every route: the
+// addresses of segments and the ids of endpoints. A controller names the
+// same few of both over and over.
+var parsed = struct {
+	mu        sync.RWMutex
+	segments  map[string]net.IP
+	endpoints map[string]endpointIDs
+}{
+	segments:  make(map[string]net.IP),
+	endpoints: make(map[string]endpointIDs),
+}
+
+type endpointIDs struct {
+	vpc, vpcAttachment string
+}
+
+// parseSegments is util.ParseSegments with the addresses cached. Like it,
+// it returns the segments in reverse, as the SRH lists them. The addresses
+// are shared and must not be modified.
+func parseSegments(segmentsStr []string) ([]net.IP, error) {
+	if len(segmentsStr) == 0 {
+		return nil, fmt.Errorf("no segments parsed: %v", segmentsStr)
+	}
+	segments := make([]net.IP, len(segmentsStr))
+	for i, s := range segmentsStr {
+		parsed.mu.RLock()
+		ip, ok := parsed.segments[s]
+		parsed.mu.RUnlock()
+		if !ok {
+			var err error
+			if ip, err = util.ParseIP(s); err != nil {
+				return nil, fmt.Errorf("could not parse ip (%s): %v", s, err)
+			}
+			if ip.To4() != nil {
+				return nil, fmt.Errorf("not an ipv6 address: %s", s)
+			}
+			parsed.mu.Lock()
+			if len(parsed.segments) >= maxParsed {
+				clear(parsed.segments)
+			}
+			parsed.segments[s] = ip
+			parsed.mu.Unlock()
+		}
+		segments[len(segments)-1-i] = ip
+	}
+	return segments, nil
+}
+
+// parseEndpoint returns the vpc and attachment ids of the SRv6 endpoint
+// srcStr, caching those of valid endpoints.
+func parseEndpoint(srcStr string) (string, string, error) {
+	parsed.mu.RLock()
+	ids, ok := parsed.endpoints[srcStr]
+	parsed.mu.RUnlock()
+	if ok {
+		return ids.vpc, ids.vpcAttachment, nil
+	}
+	src, err := util.ParseIP(srcStr)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid src: %w", err)
+	}
+	vpc, vpcAttachment, err := endpoint.IDs(src)
+	if err != nil {
+		return "", "", fmt.Errorf("could not extract SRv6 endpoint: %w", err)
+	}
+	parsed.mu.Lock()
+	if len(parsed.endpoints) >= maxParsed {
+		clear(parsed.endpoints)
+	}
+	parsed.endpoints[srcStr] = endpointIDs{vpc: vpc, vpcAttachment: vpcAttachment}
+	parsed.mu.Unlock()
+	return vpc, vpcAttachment, nil
+}
@@ -0,0 +1,68 @@
+// Package depth checks received segment lists against what the kernel's
+// seg6 encapsulation can carry, so a route is refused up front instead of
+// being truncated or having its packets dropped.
+package depth
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	// MaxSegments is the most segments an SRH holds: its length field
+	// counts 8 octet units beyond the first 8 octets and is one octet wide.
+	MaxSegments = 255 * 8 / segmentLen
+
+	ipv6HeaderLen = 40
+	srhHeaderLen  = 8
+	segmentLen    = 16
+)
+
+// Overhead returns the octets encapsulation with n segments adds: the outer
+// IPv6 header and an SRH with n segments.
+func Overhead(n int) int {
+	return ipv6HeaderLen + srhHeaderLen + n*segmentLen
+}
+
+// Reasons a segment list is refused.
+const (
+	ReasonDepth = "depth"
+	ReasonMTU   = "mtu"
+)
+
+// ErrExceeded is matched by every *ExceededError.
+var ErrExceeded = errors.New("segment list exceeds limits")
+
+// ExceededError describes a segment list that can't be carried.
+type ExceededError struct {
+	Reason   string
+	Segments int
+	Limit    int
+}
+
+func (e *ExceededError) Error() string {
+	if e.Reason == ReasonMTU {
+		return fmt.Sprintf("%d segments exceed the underlay MTU budget of %d segments: %v", e.Segments, e.Limit, ErrExceeded)
+	}
+	return fmt.Sprintf("%d segments exceed the kernel maximum of %d: %v", e.Segments, e.Limit, ErrExceeded)
+}
+
+func (e *ExceededError) Unwrap() error {
+	return ErrExceeded
+}
+
+// Check returns an *ExceededError if n segments exceed the SRH maximum or,
+// when underlayMTU is not 0, don't leave room for packets of innerMTU.
+func Check(n, innerMTU, underlayMTU int) error {
+	if n > MaxSegments {
+		return &ExceededError{Reason: ReasonDepth, Segments: n, Limit: MaxSegments}
+	}
+	if underlayMTU == 0 {
+		return nil
+	}
+	if innerMTU+Overhead(n) > underlayMTU {
+		limit := (underlayMTU - innerMTU - Overhead(0)) / segmentLen
+		return &ExceededError{Reason: ReasonMTU, Segments: n, Limit: max(limit, 0)}
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+// Package dryrun keeps the datapath from changing the kernel. Enabled, the
+// routes, proxy neighbors and interface settings the agent would program
+// are logged instead, so that a controller's messages can be checked on a
+// production host before the agent enforces them.
+package dryrun
+
+import (
+	"log/slog"
+	"sync/atomic"
+
+	"github.com/vishvananda/netlink"
+)
+
+var enabled atomic.Bool
+
+// Enable turns dry-run on for the rest of the process.
+func Enable() {
+	enabled.Store(true)
+}
+
+// Enabled reports whether changes are logged rather than made.
+func Enabled() bool {
+	return enabled.Load()
+}
+
+// Route logs the route operation op on r. The route's own String leaves
+// out its MTU.
+func Route(op string, r *netlink.Route) {
+	slog.Info("Dry run", "op", op, "route", r.String(), "mtu", r.MTU)
+}
+
+// Neigh logs the neighbor operation op on n.
+func Neigh(op string, n *netlink.Neigh) {
+	slog.Info("Dry run", "op", op, "link", n.LinkIndex, "ip", n.IP.String(),
+		"permanent", n.State == netlink.NUD_PERMANENT, "proxy", n.Flags&netlink.NTF_PROXY != 0)
+}
+
+// Log logs the operation op described by args.
+func Log(op string, args ...any) {
+	slog.Info("Dry run", append([]any{"op", op}, args...)...)
+}
@@ -0,0 +1,247 @@
+// Package endpoint converts between VPC attachment ids and SRv6 endpoint
+// addresses. It wraps the galactic-common helpers with strict validation and
+// typed errors, so callers can tell a misconfigured locator from bad ids.
+package endpoint
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/datum-cloud/galactic-common/util"
+)
+
+const (
+	// VPCHexWidth and AttachmentHexWidth are the widths of the ids in the
+	// low 64 bits of an endpoint: <locator:64><vpc:48><attachment:16>.
+	VPCHexWidth        = 12
+	AttachmentHexWidth = 4
+)
+
+// IDBits is the width of the ids, which always fill the low bits of an
+// endpoint.
+const IDBits = 4 * (VPCHexWidth + AttachmentHexWidth)
+
+// NodeBits is the most bits between the locator and the ids that identify
+// the node, taken from the configured address. Sites are given a /48 each,
+// so a shorter srv6_net is a whole SID block configured by mistake, which
+// would have the node claim the endpoints of the others.
+const NodeBits = 16
+
+// Locators may be anything from /48 to /64.
+const (
+	MaxLocatorLength = 128 - IDBits
+	MinLocatorLength = MaxLocatorLength - NodeBits
+)
+
+var (
+	// ErrLocator is returned when srv6_net cannot hold endpoints, or an
+	// endpoint is not inside it.
+	ErrLocator = errors.New("wrong SRv6 locator")
+	// ErrMalformedID is returned for ids that are not hex or do not fit.
+	ErrMalformedID = errors.New("malformed id")
+)
+
+// IDError reports which id was rejected and why. It matches ErrMalformedID.
+type IDError struct {
+	Field string // vpc or vpcattachment
+	Value string
+	Err   error
+}
+
+func (e *IDError) Error() string {
+	return fmt.Sprintf("invalid %s %q: %v", e.Field, e.Value, e.Err)
+}
+
+func (e *IDError) Unwrap() []error {
+	return []error{ErrMalformedID, e.Err}
+}
+
+// LocatorError reports a locator problem. It matches ErrLocator.
+type LocatorError struct {
+	Locator  string
+	Endpoint net.IP // nil when the locator itself is invalid
+	Err      error
+}
+
+func (e *LocatorError) Error() string {
+	if e.Endpoint != nil {
+		return fmt.Sprintf("endpoint %s not in srv6_net %s", e.Endpoint, e.Locator)
+	}
+	return fmt.Sprintf("invalid srv6_net %q: %v", e.Locator, e.Err)
+}
+
+func (e *LocatorError) Unwrap() []error {
+	if e.Err == nil {
+		return []error{ErrLocator}
+	}
+	return []error{ErrLocator, e.Err}
+}
+
+func parseID(field, value string, width int) (uint64, error) {
+	if value == "" || len(value) > width {
+		return 0, &IDError{Field: field, Value: value, Err: fmt.Errorf("want 1 to %d hex digits", width)}
+	}
+	n, err := strconv.ParseUint(value, 16, 64)
+	if err != nil {
+		return 0, &IDError{Field: field, Value: value, Err: errors.Unwrap(err)}
+	}
+	return n, nil
+}
+
+// PadHex validates hex ids and returns them in canonical form: lower case
+// and left-padded with zeros to VPCHexWidth and AttachmentHexWidth. Shorter
+// ids are accepted; wider ones are rejected even if the extra digits are
+// leading zeros, since they most likely come from a different id scheme.
+func PadHex(vpc, vpcAttachment string) (string, string, error) {
+	v, err := parseID("vpc", vpc, VPCHexWidth)
+	if err != nil {
+		return "", "", err
+	}
+	a, err := parseID("vpcattachment", vpcAttachment, AttachmentHexWidth)
+	if err != nil {
+		return "", "", err
+	}
+	return fmt.Sprintf("%0*x", VPCHexWidth, v), fmt.Sprintf("%0*x", AttachmentHexWidth, a), nil
+}
+
+const (
+	// VPCBase62Width and AttachmentBase62Width are the widths of the ids in
+	// interface names, see util.InterfaceNameTemplate.
+	VPCBase62Width        = 9
+	AttachmentBase62Width = 3
+)
+
+func base62ToHex(field, value string, base62Width, hexWidth int) (string, error) {
+	if value == "" || len(value) > base62Width {
+		return "", &IDError{Field: field, Value: value, Err: fmt.Errorf("want 1 to %d base62 digits", base62Width)}
+	}
+	hex, err := util.Base62ToHex(value)
+	if err != nil {
+		return "", &IDError{Field: field, Value: value, Err: err}
+	}
+	// 62^9 and 62^3 exceed 2^48 and 2^16, so the width alone is not enough
+	hex = strings.TrimLeft(hex, "0")
+	if len(hex) > hexWidth {
+		return "", &IDError{Field: field, Value: value, Err: fmt.Errorf("exceeds %d bits", hexWidth*4)}
+	}
+	if hex == "" {
+		hex = "0"
+	}
+	return hex, nil
+}
+
+// Base62ToHex converts base62 ids, as found in interface names, to padded
+// hex ids.
+func Base62ToHex(vpc, vpcAttachment string) (string, string, error) {
+	v, err := base62ToHex("vpc", vpc, VPCBase62Width, VPCHexWidth)
+	if err != nil {
+		return "", "", err
+	}
+	a, err := base62ToHex("vpcattachment", vpcAttachment, AttachmentBase62Width, AttachmentHexWidth)
+	if err != nil {
+		return "", "", err
+	}
+	return PadHex(v, a)
+}
+
+func parseLocator(srv6Net string) (*net.IPNet, error) {
+	ip, locator, err := net.ParseCIDR(srv6Net)
+	if err != nil {
+		return nil, &LocatorError{Locator: srv6Net, Err: err}
+	}
+	if ip.To4() != nil {
+		return nil, &LocatorError{Locator: srv6Net, Err: errors.New("not IPv6")}
+	}
+	ones, _ := locator.Mask.Size()
+	if ones < MinLocatorLength || ones > MaxLocatorLength {
+		return nil, &LocatorError{Locator: srv6Net, Err: fmt.Errorf("prefix length /%d outside /%d to /%d", ones, MinLocatorLength, MaxLocatorLength)}
+	}
+	return locator, nil
+}
+
+// Encode returns the endpoint of a VPC attachment inside srv6Net. Ids are
+// hex and padded as described for PadHex.
+func Encode(srv6Net, vpc, vpcAttachment string) (string, error) {
+	if _, err := parseLocator(srv6Net); err != nil {
+		return "", err
+	}
+	vpc, vpcAttachment, err := PadHex(vpc, vpcAttachment)
+	if err != nil {
+		return "", err
+	}
+	return util.EncodeSRv6Endpoint(srv6Net, vpc, vpcAttachment)
+}
+
+// Decode returns the hex ids of an endpoint, which must be inside srv6Net.
+func Decode(srv6Net string, endpoint net.IP) (string, string, error) {
+	locator, err := parseLocator(srv6Net)
+	if err != nil {
+		return "", "", err
+	}
+	if endpoint.To4() != nil || !locator.Contains(endpoint) {
+		return "", "", &LocatorError{Locator: srv6Net, Endpoint: endpoint}
+	}
+	return util.DecodeSRv6Endpoint(endpoint)
+}
+
+// IDs returns the base62 ids used in interface names for an endpoint. The
+// locator is not checked.
+func IDs(endpoint net.IP) (string, string, error) {
+	if endpoint.To16() == nil || endpoint.To4() != nil {
+		return "", "", fmt.Errorf("not an IPv6 address: %s", endpoint)
+	}
+	vpcHex, vpcAttachmentHex, err := util.DecodeSRv6Endpoint(endpoint)
+	if err != nil {
+		return "", "", err
+	}
+	vpc, err := util.HexToBase62(vpcHex)
+	if err != nil {
+		return "", "", &IDError{Field: "vpc", Value: vpcHex, Err: err}
+	}
+	vpcAttachment, err := util.HexToBase62(vpcAttachmentHex)
+	if err != nil {
+		return "", "", &IDError{Field: "vpcattachment", Value: vpcAttachmentHex, Err: err}
+	}
+	return vpc, vpcAttachment, nil
+}
+
+// The ids of the last VPC's last attachments are reserved for connectivity
+// probing: every node answers probes at its ProbeResponder endpoint, and its
+// own probes come back to its ProbeReply endpoint.
+const (
+	ProbeVPC       = "ffffffffffff"
+	ProbeResponder = "ffff"
+	ProbeReply     = "fffe"
+)
+
+// Reserved reports whether the ids are reserved for probing, so no
+// attachment may have them. Malformed ids are not reserved.
+func Reserved(vpc, vpcAttachment string) bool {
+	vpc, vpcAttachment, err := PadHex(vpc, vpcAttachment)
+	return err == nil && vpc == ProbeVPC && (vpcAttachment == ProbeResponder || vpcAttachment == ProbeReply)
+}
+
+// Sibling returns the endpoint of vpc and vpcAttachment on the node of
+// endpoint, which need not be in a locator the agent knows.
+func Sibling(endpoint net.IP, vpc, vpcAttachment string) (net.IP, error) {
+	ip := endpoint.To16()
+	if ip == nil || endpoint.To4() != nil {
+		return nil, fmt.Errorf("not an IPv6 address: %s", endpoint)
+	}
+	vpc, vpcAttachment, err := PadHex(vpc, vpcAttachment)
+	if err != nil {
+		return nil, err
+	}
+	ids, err := strconv.ParseUint(vpc+vpcAttachment, 16, 64)
+	if err != nil {
+		return nil, err
+	}
+	sibling := make(net.IP, net.IPv6len)
+	copy(sibling, ip[:8])
+	binary.BigEndian.PutUint64(sibling[8:], ids)
+	return sibling, nil
+}
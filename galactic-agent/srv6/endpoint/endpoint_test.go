@@ -0,0 +1,219 @@
+package endpoint
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"testing/quick"
+
+	"github.com/datum-cloud/galactic-common/util"
+)
+
+var locators = []string{"fd00:1:2:3::/64", "fd00:1:2:300::/56", "fd00:1:2::/48"}
+
+// ids is a random VPC attachment, written as hex of any width that fits.
+type ids struct {
+	vpc, vpcAttachment uint64
+	vpcWidth           int
+	attachmentWidth    int
+	upper              bool
+}
+
+func (i ids) hex() (string, string) {
+	vpc := fmt.Sprintf("%0*x", i.vpcWidth, i.vpc)
+	vpcAttachment := fmt.Sprintf("%0*x", i.attachmentWidth, i.vpcAttachment)
+	if i.upper {
+		vpc, vpcAttachment = strings.ToUpper(vpc), strings.ToUpper(vpcAttachment)
+	}
+	return vpc, vpcAttachment
+}
+
+func (i ids) padded() (string, string) {
+	return fmt.Sprintf("%012x", i.vpc), fmt.Sprintf("%04x", i.vpcAttachment)
+}
+
+// digits is the number of hex digits of n, at least 1.
+func digits(n uint64) int {
+	return max(1, len(fmt.Sprintf("%x", n)))
+}
+
+func randomIDs(vpc uint64, vpcAttachment uint16, vpcPad, attachmentPad uint8, upper bool) ids {
+	i := ids{vpc: vpc & (1<<48 - 1), vpcAttachment: uint64(vpcAttachment), upper: upper}
+	i.vpcWidth = digits(i.vpc) + int(vpcPad)%(VPCHexWidth-digits(i.vpc)+1)
+	i.attachmentWidth = digits(i.vpcAttachment) + int(attachmentPad)%(AttachmentHexWidth-digits(i.vpcAttachment)+1)
+	return i
+}
+
+func TestPadHexRoundTrip(t *testing.T) {
+	f := func(vpc uint64, vpcAttachment uint16, vpcPad, attachmentPad uint8, upper bool) bool {
+		i := randomIDs(vpc, vpcAttachment, vpcPad, attachmentPad, upper)
+		v, a, err := PadHex(i.hex())
+		wantV, wantA := i.padded()
+		if err != nil || v != wantV || a != wantA {
+			t.Logf("PadHex(%v) = %q, %q, %v, want %q, %q", i, v, a, err, wantV, wantA)
+			return false
+		}
+		// padded ids are canonical
+		v2, a2, err := PadHex(v, a)
+		return err == nil && v2 == v && a2 == a
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEncodeRoundTrip(t *testing.T) {
+	f := func(vpc uint64, vpcAttachment uint16, vpcPad, attachmentPad uint8, upper bool, l uint8) bool {
+		i := randomIDs(vpc, vpcAttachment, vpcPad, attachmentPad, upper)
+		locator := locators[int(l)%len(locators)]
+		vpcHex, vpcAttachmentHex := i.hex()
+		e, err := Encode(locator, vpcHex, vpcAttachmentHex)
+		if err != nil {
+			t.Logf("Encode(%s, %v): %v", locator, i, err)
+			return false
+		}
+		v, a, err := Decode(locator, net.ParseIP(e))
+		wantV, wantA := i.padded()
+		if err != nil || v != wantV || a != wantA {
+			t.Logf("Decode(%s, %s) = %q, %q, %v, want %q, %q", locator, e, v, a, err, wantV, wantA)
+			return false
+		}
+		// the base62 ids of the interface names lead back to the endpoint
+		v62, a62, err := IDs(net.ParseIP(e))
+		if err != nil {
+			t.Logf("IDs(%s): %v", e, err)
+			return false
+		}
+		v, a, err = Base62ToHex(v62, a62)
+		if err != nil || v != wantV || a != wantA {
+			t.Logf("Base62ToHex(%q, %q) = %q, %q, %v, want %q, %q", v62, a62, v, a, err, wantV, wantA)
+			return false
+		}
+		return true
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMalformedIDs(t *testing.T) {
+	for _, tt := range []struct {
+		vpc, vpcAttachment string
+	}{
+		{"", "1"},
+		{"1", ""},
+		{"1234567890abc", "1"},
+		{"0000000000001", "1"},
+		{"1", "12345"},
+		{"1", "00001"},
+		{"xyz", "1"},
+		{"1", "g"},
+		{"-1", "1"},
+		{"+1", "1"},
+		{"0x1", "1"},
+		{" 1", "1"},
+		{"1", "1 "},
+	} {
+		if _, _, err := PadHex(tt.vpc, tt.vpcAttachment); !errors.Is(err, ErrMalformedID) {
+			t.Errorf("PadHex(%q, %q) = %v, want ErrMalformedID", tt.vpc, tt.vpcAttachment, err)
+		}
+		if _, err := Encode(locators[0], tt.vpc, tt.vpcAttachment); !errors.Is(err, ErrMalformedID) || errors.Is(err, ErrLocator) {
+			t.Errorf("Encode(%q, %q) = %v, want ErrMalformedID", tt.vpc, tt.vpcAttachment, err)
+		}
+	}
+}
+
+func TestMalformedBase62IDs(t *testing.T) {
+	for _, tt := range []struct {
+		vpc, vpcAttachment string
+	}{
+		{"", "1"},
+		{"1", ""},
+		{"1234567890", "1"},
+		{"1", "1234"},
+		{"zzzzzzzzz", "1"}, // 62^9 > 2^48
+		{"1", "zzz"},       // 62^3 > 2^16
+		{"a-b", "1"},
+		{"1", "_"},
+	} {
+		if _, _, err := Base62ToHex(tt.vpc, tt.vpcAttachment); !errors.Is(err, ErrMalformedID) {
+			t.Errorf("Base62ToHex(%q, %q) = %v, want ErrMalformedID", tt.vpc, tt.vpcAttachment, err)
+		}
+	}
+}
+
+func TestLocatorErrors(t *testing.T) {
+	for _, locator := range []string{"", "fd00::", "10.0.0.0/8", "fd00::/47", "fd00::/65", "fd00::/128"} {
+		if _, err := Encode(locator, "1", "1"); !errors.Is(err, ErrLocator) || errors.Is(err, ErrMalformedID) {
+			t.Errorf("Encode(%q) = %v, want ErrLocator", locator, err)
+		}
+	}
+	for _, e := range []string{"fd00:1:2:4::1", "10.0.0.1", "::ffff:10.0.0.1"} {
+		if _, _, err := Decode(locators[0], net.ParseIP(e)); !errors.Is(err, ErrLocator) {
+			t.Errorf("Decode(%s) = %v, want ErrLocator", e, err)
+		}
+	}
+}
+
+// TestLocatorBounds checks the shortest and longest locators hold the
+// largest ids, keeping the node bits of the configured address, and that
+// the lengths just outside are refused.
+func TestLocatorBounds(t *testing.T) {
+	for _, tt := range []struct {
+		locator string
+		ok      bool
+		want    string
+	}{
+		{fmt.Sprintf("fd00:1:2:3::/%d", MinLocatorLength-1), false, ""},
+		{fmt.Sprintf("fd00:1:2:3::/%d", MinLocatorLength), true, "fd00:1:2:3:ffff:ffff:ffff:fffd"},
+		{fmt.Sprintf("fd00:1:2:3::/%d", MaxLocatorLength), true, "fd00:1:2:3:ffff:ffff:ffff:fffd"},
+		{fmt.Sprintf("fd00:1:2:3::/%d", MaxLocatorLength+1), false, ""},
+	} {
+		e, err := Encode(tt.locator, "ffffffffffff", "fffd")
+		if !tt.ok {
+			if !errors.Is(err, ErrLocator) {
+				t.Errorf("Encode(%s) = %q, %v, want ErrLocator", tt.locator, e, err)
+			}
+			continue
+		}
+		if err != nil || e != tt.want {
+			t.Errorf("Encode(%s) = %q, %v, want %s", tt.locator, e, err, tt.want)
+			continue
+		}
+		if v, a, err := Decode(tt.locator, net.ParseIP(e)); err != nil || v != "ffffffffffff" || a != "fffd" {
+			t.Errorf("Decode(%s, %s) = %q, %q, %v", tt.locator, e, v, a, err)
+		}
+	}
+}
+
+// FuzzPadHex checks that whatever PadHex accepts is canonical and encodes
+// into an endpoint decoding back to it.
+func FuzzPadHex(f *testing.F) {
+	f.Add("1", "1")
+	f.Add("ffffffffffff", "ffff")
+	f.Add("00000000000A", "000b")
+	f.Add("1234567890abc", "1")
+	f.Add("", "")
+	f.Fuzz(func(t *testing.T, vpc, vpcAttachment string) {
+		v, a, err := PadHex(vpc, vpcAttachment)
+		if err != nil {
+			if !errors.Is(err, ErrMalformedID) {
+				t.Fatalf("PadHex(%q, %q): %v does not match ErrMalformedID", vpc, vpcAttachment, err)
+			}
+			return
+		}
+		if len(v) != VPCHexWidth || len(a) != AttachmentHexWidth || strings.ToLower(v) != v || strings.ToLower(a) != a {
+			t.Fatalf("PadHex(%q, %q) = %q, %q, not canonical", vpc, vpcAttachment, v, a)
+		}
+		e, err := Encode(locators[0], vpc, vpcAttachment)
+		if err != nil {
+			t.Fatalf("Encode(%q, %q): %v", vpc, vpcAttachment, err)
+		}
+		dv, da, err := util.DecodeSRv6Endpoint(net.ParseIP(e))
+		if err != nil || dv != v || da != a {
+			t.Fatalf("decoding %s = %q, %q, %v, want %q, %q", e, dv, da, err, v, a)
+		}
+	})
+}
@@ -0,0 +1,90 @@
+package srv6
+
+import (
+	"errors"
+
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+
+	"github.com/datum-cloud/galactic-agent/metrics"
+)
+
+var netlinkErrors = metrics.NewCounter(
+	"galactic_agent_netlink_errors_total",
+	"Failed dataplane changes, by operation and class: exists, no_device, not_found, permission, rejected or other.",
+	"op", "class",
+)
+
+// Class is the kind of failure behind an Error, so that an interface gone
+// missing can be told from the kernel refusing a route.
+type Class string
+
+const (
+	// Exists is EEXIST: what was added is there already.
+	Exists Class = "exists"
+	// NoDevice is ENODEV or a link not found: an interface of the
+	// attachment, or its VRF, is missing.
+	NoDevice Class = "no_device"
+	// NotFound is ENOENT or ESRCH: what was deleted or changed is not
+	// there.
+	NotFound Class = "not_found"
+	// Permission is EPERM or EACCES: the agent lacks CAP_NET_ADMIN.
+	Permission Class = "permission"
+	// Rejected is EINVAL, EOPNOTSUPP, EAFNOSUPPORT or EPROTONOSUPPORT: the
+	// kernel refused the request, e.g. an encap it does not support.
+	Rejected Class = "rejected"
+	// Other is any other failure.
+	Other Class = "other"
+)
+
+// Error is a failed dataplane change: Op is the change, Class the kind of
+// failure. Its message is that of Err.
+type Error struct {
+	Op    string
+	Class Class
+	Err   error
+}
+
+func (e *Error) Error() string { return e.Err.Error() }
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// Classify returns the class of err, empty for nil.
+func Classify(err error) Class {
+	var notFound netlink.LinkNotFoundError
+	switch {
+	case err == nil:
+		return ""
+	case errors.As(err, &notFound), errors.Is(err, unix.ENODEV):
+		return NoDevice
+	case errors.Is(err, unix.EEXIST):
+		return Exists
+	case errors.Is(err, unix.ENOENT), errors.Is(err, unix.ESRCH):
+		return NotFound
+	case errors.Is(err, unix.EPERM), errors.Is(err, unix.EACCES):
+		return Permission
+	case errors.Is(err, unix.EINVAL), errors.Is(err, unix.EOPNOTSUPP), errors.Is(err, unix.EAFNOSUPPORT), errors.Is(err, unix.EPROTONOSUPPORT):
+		return Rejected
+	}
+	return Other
+}
+
+// ClassOf returns the class of the Error in err's chain, Other if there is
+// none and empty for nil.
+func ClassOf(err error) Class {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Class
+	}
+	if err == nil {
+		return ""
+	}
+	return Other
+}
+
+// failed classifies and counts the failure err of op.
+func failed(op string, err error) error {
+	class := Classify(err)
+	netlinkErrors.Inc(op, string(class))
+	return &Error{Op: op, Class: class, Err: err}
+}
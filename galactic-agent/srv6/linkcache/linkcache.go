@@ -0,0 +1,169 @@
+// Package linkcache remembers the links and VRF tables that route
+// operations look up by name, so that programming routes in bulk does not
+// cost a kernel round trip, or a walk of every VRF, per route.
+package linkcache
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/vishvananda/netlink"
+
+	"github.com/datum-cloud/galactic-common/util"
+	"github.com/datum-cloud/galactic-common/vrf"
+)
+
+var errClosed = errors.New("netlink subscription closed")
+
+// Links is the cache of this process. It caches nothing until Run watches
+// the kernel for it.
+var Links Cache
+
+// Cache maps interface names to their links and VRF tables. Every link or
+// address change the kernel reports drops what it knows of that link, and
+// losing the subscription drops everything.
+type Cache struct {
+	mu       sync.Mutex
+	watching bool
+	gen      uint64 // bumped on every invalidation
+	links    map[string]netlink.Link
+	tables   map[string]uint32 // by VRF interface name
+}
+
+// LinkByName is netlink.LinkByName, cached. The link is shared and must
+// not be modified.
+func (c *Cache) LinkByName(name string) (netlink.Link, error) {
+	c.mu.Lock()
+	link, ok := c.links[name]
+	gen := c.gen
+	c.mu.Unlock()
+	if ok {
+		return link, nil
+	}
+	link, err := netlink.LinkByName(name)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	if c.watching && c.gen == gen {
+		c.links[name] = link
+	}
+	c.mu.Unlock()
+	return link, nil
+}
+
+// VRFTable is vrf.GetVRFIdForVPC, cached.
+func (c *Cache) VRFTable(vpc, vpcAttachment string) (uint32, error) {
+	name := util.GenerateInterfaceNameVRF(vpc, vpcAttachment)
+	c.mu.Lock()
+	table, ok := c.tables[name]
+	gen := c.gen
+	c.mu.Unlock()
+	if ok {
+		return table, nil
+	}
+	table, err := vrf.GetVRFIdForInterface(name)
+	if err != nil {
+		return 0, err
+	}
+	c.mu.Lock()
+	if c.watching && c.gen == gen {
+		c.tables[name] = table
+	}
+	c.mu.Unlock()
+	return table, nil
+}
+
+// Run subscribes to link and address changes and keeps the cache valid by
+// them until ctx is done. It subscribes again after a failure.
+func (c *Cache) Run(ctx context.Context) error {
+	for {
+		err := c.watch(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		slog.Warn("Link cache", "err", err)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+func (c *Cache) watch(ctx context.Context) error {
+	done := make(chan struct{})
+	defer close(done)
+	defer c.stop()
+
+	links := make(chan netlink.LinkUpdate, 64)
+	addrs := make(chan netlink.AddrUpdate, 64)
+	failed := make(chan error, 2)
+	onError := func(err error) {
+		select {
+		case failed <- err:
+		default:
+		}
+	}
+	if err := netlink.LinkSubscribeWithOptions(links, done, netlink.LinkSubscribeOptions{ErrorCallback: onError}); err != nil {
+		return err
+	}
+	if err := netlink.AddrSubscribeWithOptions(addrs, done, netlink.AddrSubscribeOptions{ErrorCallback: onError}); err != nil {
+		return err
+	}
+	// whatever was looked up before the subscription may be stale already
+	c.start()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-failed:
+			return err
+		case u, ok := <-links:
+			if !ok {
+				return errClosed
+			}
+			c.forget(u.Attrs().Name, u.Attrs().Index)
+		case u, ok := <-addrs:
+			if !ok {
+				return errClosed
+			}
+			c.forget("", u.LinkIndex)
+		}
+	}
+}
+
+func (c *Cache) start() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.watching = true
+	c.gen++
+	c.links = make(map[string]netlink.Link)
+	c.tables = make(map[string]uint32)
+}
+
+func (c *Cache) stop() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.watching = false
+	c.gen++
+	c.links = nil
+	c.tables = nil
+}
+
+// forget drops the link called name or with index, and its VRF table.
+func (c *Cache) forget(name string, index int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	for n, link := range c.links {
+		if n == name || link.Attrs().Index == index {
+			delete(c.links, n)
+			delete(c.tables, n)
+		}
+	}
+	delete(c.tables, name)
+}
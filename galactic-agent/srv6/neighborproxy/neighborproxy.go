@@ -5,12 +5,14 @@ import (
 
 	"github.com/vishvananda/netlink"
 
+	"github.com/datum-cloud/galactic-agent/srv6/dryrun"
+	"github.com/datum-cloud/galactic-agent/srv6/linkcache"
 	"github.com/datum-cloud/galactic-common/util"
 )
 
 func Add(ipnet *net.IPNet, vpc, vpcAttachment string) error {
 	dev := util.GenerateInterfaceNameHost(vpc, vpcAttachment)
-	link, err := netlink.LinkByName(dev)
+	link, err := linkcache.Links.LinkByName(dev)
 	if err != nil {
 		return err
 	}
@@ -22,12 +24,16 @@ func Add(ipnet *net.IPNet, vpc, vpcAttachment string) error {
 		Flags:     netlink.NTF_PROXY,
 	}
 
+	if dryrun.Enabled() {
+		dryrun.Neigh("add", neigh)
+		return nil
+	}
 	return netlink.NeighAdd(neigh)
 }
 
 func Delete(ipnet *net.IPNet, vpc, vpcAttachment string) error {
 	dev := util.GenerateInterfaceNameHost(vpc, vpcAttachment)
-	link, err := netlink.LinkByName(dev)
+	link, err := linkcache.Links.LinkByName(dev)
 	if err != nil {
 		return err
 	}
@@ -39,5 +45,9 @@ func Delete(ipnet *net.IPNet, vpc, vpcAttachment string) error {
 		Flags:     netlink.NTF_PROXY,
 	}
 
+	if dryrun.Enabled() {
+		dryrun.Neigh("delete", neigh)
+		return nil
+	}
 	return netlink.NeighDel(neigh)
 }
@@ -0,0 +1,255 @@
+// Package routecache remembers the routes the agent programmed, so that
+// controllers re-pushing whole tables do not turn into a RouteReplace per
+// route when nothing changed.
+package routecache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+
+	"github.com/datum-cloud/galactic-agent/srv6/dryrun"
+)
+
+var errClosed = errors.New("netlink subscription closed")
+
+// Routes is the cache of the routes programmed by this process. It caches
+// nothing until Run watches the kernel for it.
+var Routes Cache
+
+// Cache maps the table and prefix of each programmed route to what it was
+// programmed with: link, MTU and encap, which includes the segments. It
+// only knows what went through it; every route the kernel reports deleted,
+// or replaced with something else, is forgotten, and losing the
+// subscription forgets everything.
+type Cache struct {
+	mu       sync.Mutex
+	watching bool
+	epoch    uint64                    // bumped on every (un)subscription
+	tables   map[int]map[string]string // by table and prefix
+	inflight map[key]*replace          // replaces not returned yet
+}
+
+type key struct {
+	table int
+	dst   string
+}
+
+// replace tracks the replaces of a route in flight. Its generation is
+// bumped by every change the kernel reports to the route other than the
+// one being programmed, which may have been made before or after it.
+type replace struct {
+	n     int
+	value string
+	gen   uint64
+}
+
+func table(t int) int {
+	if t == 0 {
+		return unix.RT_TABLE_MAIN
+	}
+	return t
+}
+
+func value(r *netlink.Route) string {
+	encap := ""
+	if r.Encap != nil {
+		encap = r.Encap.String()
+	}
+	return fmt.Sprintf("%d/%d/%s", r.LinkIndex, r.MTU, encap)
+}
+
+// Programmed reports whether r was programmed exactly as it is.
+func (c *Cache) Programmed(r *netlink.Route) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v, ok := c.tables[table(r.Table)][r.Dst.String()]
+	return ok && v == value(r)
+}
+
+// Replace is netlink.RouteReplace, skipped when r is programmed already.
+// In dry-run, r is logged every time and not remembered.
+func (c *Cache) Replace(r *netlink.Route) error {
+	if dryrun.Enabled() {
+		dryrun.Route("replace", r)
+		return nil
+	}
+	if c.Programmed(r) {
+		return nil
+	}
+	k, v := key{table(r.Table), r.Dst.String()}, value(r)
+	c.mu.Lock()
+	// forget first: a failed replace leaves the route unknown
+	delete(c.tables[k.table], k.dst)
+	if c.inflight == nil {
+		c.inflight = make(map[key]*replace)
+	}
+	f := c.inflight[k]
+	if f == nil {
+		f = &replace{}
+		c.inflight[k] = f
+	}
+	f.n++
+	f.value = v
+	gen, epoch := f.gen, c.epoch
+	c.mu.Unlock()
+
+	err := netlink.RouteReplace(r)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if f.n--; f.n == 0 {
+		delete(c.inflight, k)
+	}
+	// a change reported meanwhile may have come after r
+	if err != nil || !c.watching || c.epoch != epoch || f.gen != gen || f.value != v {
+		return err
+	}
+	if c.tables[k.table] == nil {
+		c.tables[k.table] = make(map[string]string)
+	}
+	c.tables[k.table][k.dst] = v
+	return nil
+}
+
+// Delete is netlink.RouteDel, forgetting r whatever the outcome.
+func (c *Cache) Delete(r *netlink.Route) error {
+	c.Forget(r.Table, r.Dst)
+	if dryrun.Enabled() {
+		dryrun.Route("delete", r)
+		return nil
+	}
+	return netlink.RouteDel(r)
+}
+
+// Forget drops the route to dst in table t.
+func (c *Cache) Forget(t int, dst *net.IPNet) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.tables[table(t)], dst.String())
+}
+
+// ForgetTable drops the routes of table t, as when its VRF is replaced.
+func (c *Cache) ForgetTable(t int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.tables, table(t))
+}
+
+// Run subscribes to route changes and forgets the routes the kernel
+// deletes or replaces, by whoever, until ctx is done. It subscribes again after a
+// failure.
+func (c *Cache) Run(ctx context.Context) error {
+	for {
+		err := c.watch(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		slog.Warn("Route cache", "err", err)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+func (c *Cache) watch(ctx context.Context) error {
+	done := make(chan struct{})
+	defer close(done)
+	defer c.stop()
+
+	updates := make(chan netlink.RouteUpdate, 256)
+	failed := make(chan error, 1)
+	onError := func(err error) {
+		select {
+		case failed <- err:
+		default:
+		}
+	}
+	if err := netlink.RouteSubscribeWithOptions(updates, done, netlink.RouteSubscribeOptions{ErrorCallback: onError}); err != nil {
+		return err
+	}
+	// whatever was programmed before the subscription may be gone already
+	c.start()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-failed:
+			return err
+		case u, ok := <-updates:
+			if !ok {
+				return errClosed
+			}
+			switch u.Type {
+			case unix.RTM_DELROUTE:
+				c.deleted(u.Table, u.Dst)
+			case unix.RTM_NEWROUTE:
+				c.added(&u.Route)
+			}
+		}
+	}
+}
+
+func (c *Cache) start() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.watching = true
+	c.epoch++
+	c.tables = make(map[int]map[string]string)
+}
+
+func (c *Cache) stop() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.watching = false
+	c.epoch++
+	c.tables = nil
+}
+
+// deleted forgets the route to dst in table t, the whole table if dst is
+// unknown.
+func (c *Cache) deleted(t int, dst *net.IPNet) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t = table(t)
+	if dst == nil {
+		delete(c.tables, t)
+		for k, f := range c.inflight {
+			if k.table == t {
+				f.gen++
+			}
+		}
+		return
+	}
+	k := key{t, dst.String()}
+	delete(c.tables[t], k.dst)
+	if f := c.inflight[k]; f != nil {
+		f.gen++
+	}
+}
+
+// added forgets the route r replaced when it differs from what was
+// programmed, or is being programmed.
+func (c *Cache) added(r *netlink.Route) {
+	if r.Dst == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	k, v := key{table(r.Table), r.Dst.String()}, value(r)
+	if cached, ok := c.tables[k.table][k.dst]; ok && cached != v {
+		delete(c.tables[k.table], k.dst)
+	}
+	if f := c.inflight[k]; f != nil && f.value != v {
+		f.gen++
+	}
+}
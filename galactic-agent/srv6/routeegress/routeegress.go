@@ -6,18 +6,22 @@ import (
 	"github.com/vishvananda/netlink"
 	"github.com/vishvananda/netlink/nl"
 
-	"github.com/datum-cloud/galactic-common/vrf"
+	"github.com/datum-cloud/galactic-agent/srv6/dryrun"
+	"github.com/datum-cloud/galactic-agent/srv6/linkcache"
+	"github.com/datum-cloud/galactic-agent/srv6/routecache"
 )
 
 const LoopbackDevice = "lo-galactic"
 
-func Add(vpc, vpcAttachment string, prefix *net.IPNet, segments []net.IP) error {
-	link, err := netlink.LinkByName(LoopbackDevice)
+// Add programs the route to prefix in the VRF of the attachment. A non-zero
+// mtu is set as the route's MTU.
+func Add(vpc, vpcAttachment string, prefix *net.IPNet, segments []net.IP, mtu int) error {
+	link, err := linkcache.Links.LinkByName(LoopbackDevice)
 	if err != nil {
 		return err
 	}
 
-	vrfId, err := vrf.GetVRFIdForVPC(vpc, vpcAttachment)
+	vrfId, err := linkcache.Links.VRFTable(vpc, vpcAttachment)
 	if err != nil {
 		return err
 	}
@@ -31,17 +35,54 @@ func Add(vpc, vpcAttachment string, prefix *net.IPNet, segments []net.IP) error
 		Table:     int(vrfId),
 		LinkIndex: link.Attrs().Index,
 		Encap:     encap,
+		MTU:       mtu,
+	}
+	return routecache.Routes.Replace(route)
+}
+
+// SetMTU sets the MTU of every encap route in the VRF of the attachment.
+func SetMTU(vpc, vpcAttachment string, mtu int) error {
+	vrfId, err := linkcache.Links.VRFTable(vpc, vpcAttachment)
+	if err != nil {
+		return err
+	}
+	routes, err := netlink.RouteListFiltered(netlink.FAMILY_ALL, &netlink.Route{Table: int(vrfId)}, netlink.RT_FILTER_TABLE)
+	if err != nil {
+		return err
+	}
+	// the routes change behind the cache, which Add then replaces again
+	routecache.Routes.ForgetTable(int(vrfId))
+	for _, r := range routes {
+		if _, ok := r.Encap.(*netlink.SEG6Encap); !ok || r.MTU == mtu {
+			continue
+		}
+		r.MTU = mtu
+		if dryrun.Enabled() {
+			dryrun.Route("replace", &r)
+			continue
+		}
+		if err := netlink.RouteReplace(&r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Forget drops the cached routes of the VRF of the attachment, whose table
+// may have been replaced.
+func Forget(vpc, vpcAttachment string) {
+	if vrfId, err := linkcache.Links.VRFTable(vpc, vpcAttachment); err == nil {
+		routecache.Routes.ForgetTable(int(vrfId))
 	}
-	return netlink.RouteReplace(route)
 }
 
 func Delete(vpc, vpcAttachment string, prefix *net.IPNet, segments []net.IP) error {
-	link, err := netlink.LinkByName(LoopbackDevice)
+	link, err := linkcache.Links.LinkByName(LoopbackDevice)
 	if err != nil {
 		return err
 	}
 
-	vrfId, err := vrf.GetVRFIdForVPC(vpc, vpcAttachment)
+	vrfId, err := linkcache.Links.VRFTable(vpc, vpcAttachment)
 	if err != nil {
 		return err
 	}
@@ -51,5 +92,5 @@ func Delete(vpc, vpcAttachment string, prefix *net.IPNet, segments []net.IP) err
 		Table:     int(vrfId),
 		LinkIndex: link.Attrs().Index,
 	}
-	return netlink.RouteDel(route)
+	return routecache.Routes.Delete(route)
 }
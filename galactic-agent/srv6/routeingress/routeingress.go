@@ -6,18 +6,19 @@ import (
 	"github.com/vishvananda/netlink"
 	"github.com/vishvananda/netlink/nl"
 
+	"github.com/datum-cloud/galactic-agent/srv6/linkcache"
+	"github.com/datum-cloud/galactic-agent/srv6/routecache"
 	"github.com/datum-cloud/galactic-common/util"
-	"github.com/datum-cloud/galactic-common/vrf"
 )
 
 func Add(ip *net.IPNet, vpc, vpcAttachment string) error {
 	dev := util.GenerateInterfaceNameHost(vpc, vpcAttachment)
-	link, err := netlink.LinkByName(dev)
+	link, err := linkcache.Links.LinkByName(dev)
 	if err != nil {
 		return err
 	}
 
-	vrfId, err := vrf.GetVRFIdForVPC(vpc, vpcAttachment)
+	vrfId, err := linkcache.Links.VRFTable(vpc, vpcAttachment)
 	if err != nil {
 		return err
 	}
@@ -35,12 +36,12 @@ func Add(ip *net.IPNet, vpc, vpcAttachment string) error {
 		LinkIndex: link.Attrs().Index,
 		Encap:     encap,
 	}
-	return netlink.RouteReplace(route)
+	return routecache.Routes.Replace(route)
 }
 
 func Delete(ip *net.IPNet, vpc, vpcAttachment string) error {
 	dev := util.GenerateInterfaceNameHost(vpc, vpcAttachment)
-	link, err := netlink.LinkByName(dev)
+	link, err := linkcache.Links.LinkByName(dev)
 	if err != nil {
 		return err
 	}
@@ -50,5 +51,5 @@ func Delete(ip *net.IPNet, vpc, vpcAttachment string) error {
 		LinkIndex: link.Attrs().Index,
 		Encap:     &netlink.SEG6LocalEncap{},
 	}
-	return netlink.RouteDel(route)
+	return routecache.Routes.Delete(route)
 }
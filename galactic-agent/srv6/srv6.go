@@ -3,12 +3,16 @@ package srv6
 import (
 	"errors"
 	"fmt"
+	"net"
 
 	"github.com/vishvananda/netlink"
 
+	"github.com/datum-cloud/galactic-agent/srv6/dryrun"
+	"github.com/datum-cloud/galactic-agent/srv6/endpoint"
 	"github.com/datum-cloud/galactic-agent/srv6/neighborproxy"
 	"github.com/datum-cloud/galactic-agent/srv6/routeegress"
 	"github.com/datum-cloud/galactic-agent/srv6/routeingress"
+	"github.com/datum-cloud/galactic-agent/srv6/trafficclass"
 	"github.com/datum-cloud/galactic-common/util"
 )
 
@@ -17,21 +21,15 @@ func RouteIngressAdd(ipStr string) error {
 	if err != nil {
 		return fmt.Errorf("invalid ip: %w", err)
 	}
-	vpc, vpcAttachment, err := util.DecodeSRv6Endpoint(ip)
+	vpc, vpcAttachment, err := endpoint.IDs(ip)
 	if err != nil {
 		return fmt.Errorf("could not extract SRv6 endpoint: %w", err)
 	}
-	vpc, err = util.HexToBase62(vpc)
-	if err != nil {
-		return fmt.Errorf("invalid vpc: %w", err)
-	}
-	vpcAttachment, err = util.HexToBase62(vpcAttachment)
-	if err != nil {
-		return fmt.Errorf("invalid vpcattachment: %w", err)
-	}
 
+	// a new attachment may reuse the table of one gone
+	routeegress.Forget(vpc, vpcAttachment)
 	if err := routeingress.Add(netlink.NewIPNet(ip), vpc, vpcAttachment); err != nil {
-		return fmt.Errorf("routeingress add failed: %w", err)
+		return fmt.Errorf("routeingress add failed: %w", failed("ingress_add", err))
 	}
 	return nil
 }
@@ -41,60 +39,75 @@ func RouteIngressDel(ipStr string) error {
 	if err != nil {
 		return fmt.Errorf("invalid ip: %w", err)
 	}
-	vpc, vpcAttachment, err := util.DecodeSRv6Endpoint(ip)
+	vpc, vpcAttachment, err := endpoint.IDs(ip)
 	if err != nil {
 		return fmt.Errorf("could not extract SRv6 endpoint: %w", err)
 	}
-	vpc, err = util.HexToBase62(vpc)
-	if err != nil {
-		return fmt.Errorf("invalid vpc: %w", err)
-	}
-	vpcAttachment, err = util.HexToBase62(vpcAttachment)
-	if err != nil {
-		return fmt.Errorf("invalid vpcattachment: %w", err)
-	}
 
+	routeegress.Forget(vpc, vpcAttachment)
 	if err := routeingress.Delete(netlink.NewIPNet(ip), vpc, vpcAttachment); err != nil {
-		return fmt.Errorf("routeingress delete failed: %w", err)
+		return fmt.Errorf("routeingress delete failed: %w", failed("ingress_delete", err))
 	}
 	return nil
 }
 
 func RouteEgressAdd(prefixStr, srcStr string, segmentsStr []string) error {
+	return RouteEgressAddMTU(prefixStr, srcStr, segmentsStr, 0)
+}
+
+// RouteEgressAddMTU is RouteEgressAdd with the route MTU set to a non-zero
+// mtu, see SetMTU.
+func RouteEgressAddMTU(prefixStr, srcStr string, segmentsStr []string, mtu int) error {
 	prefix, err := netlink.ParseIPNet(prefixStr)
 	if err != nil {
 		return fmt.Errorf("invalid prefix: %w", err)
 	}
-	src, err := util.ParseIP(srcStr)
+	vpc, vpcAttachment, err := parseEndpoint(srcStr)
 	if err != nil {
-		return fmt.Errorf("invalid src: %w", err)
+		return err
 	}
-	segments, err := util.ParseSegments(segmentsStr)
+	segments, err := parseSegments(segmentsStr)
 	if err != nil {
 		return fmt.Errorf("invalid segments: %w", err)
 	}
 
-	vpc, vpcAttachment, err := util.DecodeSRv6Endpoint(src)
+	var errs []error
+	if util.IsHost(prefix) {
+		if err := neighborproxy.Add(prefix, vpc, vpcAttachment); err != nil {
+			errs = append(errs, fmt.Errorf("neighborproxy add failed: %w", failed("neighbor_add", err)))
+		}
+	}
+	if err := routeegress.Add(vpc, vpcAttachment, prefix, segments, mtu); err != nil {
+		errs = append(errs, fmt.Errorf("routeegress add failed: %w", failed("egress_add", err)))
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	return nil
+}
+
+func RouteEgressDel(prefixStr, srcStr string, segmentsStr []string) error {
+	prefix, err := netlink.ParseIPNet(prefixStr)
 	if err != nil {
-		return fmt.Errorf("could not extract SRv6 endpoint: %w", err)
+		return fmt.Errorf("invalid prefix: %w", err)
 	}
-	vpc, err = util.HexToBase62(vpc)
+	vpc, vpcAttachment, err := parseEndpoint(srcStr)
 	if err != nil {
-		return fmt.Errorf("invalid vpc: %w", err)
+		return err
 	}
-	vpcAttachment, err = util.HexToBase62(vpcAttachment)
+	segments, err := parseSegments(segmentsStr)
 	if err != nil {
-		return fmt.Errorf("invalid vpcattachment: %w", err)
+		return fmt.Errorf("invalid segments: %w", err)
 	}
 
 	var errs []error
 	if util.IsHost(prefix) {
-		if err := neighborproxy.Add(prefix, vpc, vpcAttachment); err != nil {
-			errs = append(errs, fmt.Errorf("neighborproxy add failed: %w", err))
+		if err := neighborproxy.Delete(prefix, vpc, vpcAttachment); err != nil {
+			errs = append(errs, fmt.Errorf("neighborproxy delete failed: %w", failed("neighbor_delete", err)))
 		}
 	}
-	if err := routeegress.Add(vpc, vpcAttachment, prefix, segments); err != nil {
-		errs = append(errs, fmt.Errorf("routeegress add failed: %w", err))
+	if err := routeegress.Delete(vpc, vpcAttachment, prefix, segments); err != nil {
+		errs = append(errs, fmt.Errorf("routeegress delete failed: %w", failed("egress_delete", err)))
 	}
 	if len(errs) > 0 {
 		return errors.Join(errs...)
@@ -102,44 +115,99 @@ func RouteEgressAdd(prefixStr, srcStr string, segmentsStr []string) error {
 	return nil
 }
 
-func RouteEgressDel(prefixStr, srcStr string, segmentsStr []string) error {
-	prefix, err := netlink.ParseIPNet(prefixStr)
-	if err != nil {
-		return fmt.Errorf("invalid prefix: %w", err)
-	}
+// SetMTU applies mtu to the host interface of the attachment src belongs to
+// and to the egress routes in its VRF.
+func SetMTU(srcStr string, mtu int) error {
 	src, err := util.ParseIP(srcStr)
 	if err != nil {
 		return fmt.Errorf("invalid src: %w", err)
 	}
-	segments, err := util.ParseSegments(segmentsStr)
+	vpc, vpcAttachment, err := endpoint.IDs(src)
 	if err != nil {
-		return fmt.Errorf("invalid segments: %w", err)
+		return fmt.Errorf("could not extract SRv6 endpoint: %w", err)
 	}
 
-	vpc, vpcAttachment, err := util.DecodeSRv6Endpoint(src)
+	link, err := netlink.LinkByName(util.GenerateInterfaceNameHost(vpc, vpcAttachment))
 	if err != nil {
-		return fmt.Errorf("could not extract SRv6 endpoint: %w", err)
+		return fmt.Errorf("host interface: %w", failed("set_mtu", err))
+	}
+	if dryrun.Enabled() {
+		dryrun.Log("link set mtu", "link", link.Attrs().Name, "mtu", mtu)
+	} else if err := netlink.LinkSetMTU(link, mtu); err != nil {
+		return fmt.Errorf("set interface mtu failed: %w", failed("set_mtu", err))
+	}
+	if err := routeegress.SetMTU(vpc, vpcAttachment, mtu); err != nil {
+		return fmt.Errorf("set route mtu failed: %w", failed("set_mtu", err))
 	}
-	vpc, err = util.HexToBase62(vpc)
+	return nil
+}
+
+// SetDSCP marks the traffic of the attachment src belongs to with dscp:
+// the traffic to prefixStr, or all its traffic if prefixStr is empty.
+func SetDSCP(srcStr, prefixStr string, dscp uint8) error {
+	vpc, vpcAttachment, prefix, err := dscpTarget(srcStr, prefixStr)
 	if err != nil {
-		return fmt.Errorf("invalid vpc: %w", err)
+		return err
+	}
+	if dryrun.Enabled() {
+		dryrun.Log("set dscp", "vpc", vpc, "vpcattachment", vpcAttachment, "prefix", prefix, "dscp", dscp)
+		return nil
 	}
-	vpcAttachment, err = util.HexToBase62(vpcAttachment)
+	if err := trafficclass.Set(vpc, vpcAttachment, prefix, dscp); err != nil {
+		return fmt.Errorf("set dscp failed: %w", failed("set_dscp", err))
+	}
+	return nil
+}
+
+// ClearDSCP removes marking set by SetDSCP.
+func ClearDSCP(srcStr, prefixStr string) error {
+	vpc, vpcAttachment, prefix, err := dscpTarget(srcStr, prefixStr)
 	if err != nil {
-		return fmt.Errorf("invalid vpcattachment: %w", err)
+		return err
+	}
+	if dryrun.Enabled() {
+		dryrun.Log("clear dscp", "vpc", vpc, "vpcattachment", vpcAttachment, "prefix", prefix)
+		return nil
 	}
+	if err := trafficclass.Clear(vpc, vpcAttachment, prefix); err != nil {
+		return fmt.Errorf("clear dscp failed: %w", failed("clear_dscp", err))
+	}
+	return nil
+}
 
-	var errs []error
-	if util.IsHost(prefix) {
-		if err := neighborproxy.Delete(prefix, vpc, vpcAttachment); err != nil {
-			errs = append(errs, fmt.Errorf("neighborproxy delete failed: %w", err))
+func dscpTarget(srcStr, prefixStr string) (string, string, *net.IPNet, error) {
+	src, err := util.ParseIP(srcStr)
+	if err != nil {
+		return "", "", nil, fmt.Errorf("invalid src: %w", err)
+	}
+	vpc, vpcAttachment, err := endpoint.IDs(src)
+	if err != nil {
+		return "", "", nil, fmt.Errorf("could not extract SRv6 endpoint: %w", err)
+	}
+	var prefix *net.IPNet
+	if prefixStr != "" {
+		if prefix, err = netlink.ParseIPNet(prefixStr); err != nil {
+			return "", "", nil, fmt.Errorf("invalid prefix: %w", err)
 		}
+		prefix.IP = prefix.IP.Mask(prefix.Mask)
 	}
-	if err := routeegress.Delete(vpc, vpcAttachment, prefix, segments); err != nil {
-		errs = append(errs, fmt.Errorf("routeegress delete failed: %w", err))
+	return vpc, vpcAttachment, prefix, nil
+}
+
+// AttachmentMTU returns the MTU of the host interface of the attachment
+// src belongs to.
+func AttachmentMTU(srcStr string) (int, error) {
+	src, err := util.ParseIP(srcStr)
+	if err != nil {
+		return 0, fmt.Errorf("invalid src: %w", err)
 	}
-	if len(errs) > 0 {
-		return errors.Join(errs...)
+	vpc, vpcAttachment, err := endpoint.IDs(src)
+	if err != nil {
+		return 0, fmt.Errorf("could not extract SRv6 endpoint: %w", err)
 	}
-	return nil
+	link, err := netlink.LinkByName(util.GenerateInterfaceNameHost(vpc, vpcAttachment))
+	if err != nil {
+		return 0, fmt.Errorf("host interface: %w", err)
+	}
+	return link.Attrs().MTU, nil
 }
@@ -0,0 +1,178 @@
+// Package trafficclass sets the DSCP of packets an attachment sends. The
+// kernel's SRv6 encapsulation copies the inner traffic class into the outer
+// IPv6 header, so marking packets as they enter the host from the
+// attachment's host interface marks the encapsulated packets the underlay
+// sees. ECN bits are left alone.
+package trafficclass
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"net"
+
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netlink/nl"
+	"golang.org/x/sys/unix"
+
+	"github.com/datum-cloud/galactic-common/util"
+)
+
+// MaxDSCP is the largest DSCP value.
+const MaxDSCP = 63
+
+// prioAll is the priority of the filters marking all traffic of an
+// attachment. Prefix filters get 1 plus the number of host bits of their
+// prefix, so longer prefixes are matched first and win over prioAll.
+const prioAll = 200
+
+type filter struct {
+	protocol uint16
+	prio     uint16
+	handle   uint32
+	prefix   *net.IPNet
+}
+
+// filters returns the filters marking traffic to prefix; a nil prefix
+// covers all IPv4 and IPv6 traffic.
+func filters(prefix *net.IPNet) []filter {
+	if prefix == nil {
+		return []filter{
+			{protocol: unix.ETH_P_IP, prio: prioAll, handle: 1},
+			{protocol: unix.ETH_P_IPV6, prio: prioAll, handle: 1},
+		}
+	}
+	ones, bits := prefix.Mask.Size()
+	protocol := uint16(unix.ETH_P_IPV6)
+	if bits == net.IPv4len*8 {
+		protocol = unix.ETH_P_IP
+	}
+	h := fnv.New32a()
+	h.Write([]byte(prefix.String())) //nolint:errcheck
+	return []filter{{protocol: protocol, prio: uint16(1 + bits - ones), handle: h.Sum32() | 1, prefix: prefix}}
+}
+
+// Set marks the packets of the attachment to prefix with dscp, replacing
+// any earlier marking of prefix. A nil prefix marks all its packets not
+// covered by a prefix.
+func Set(vpc, vpcAttachment string, prefix *net.IPNet, dscp uint8) error {
+	if dscp > MaxDSCP {
+		return fmt.Errorf("dscp %d out of range 0-%d", dscp, MaxDSCP)
+	}
+	link, err := netlink.LinkByName(util.GenerateInterfaceNameHost(vpc, vpcAttachment))
+	if err != nil {
+		return err
+	}
+	err = netlink.QdiscReplace(&netlink.GenericQdisc{
+		QdiscAttrs: netlink.QdiscAttrs{
+			LinkIndex: link.Attrs().Index,
+			Handle:    netlink.MakeHandle(0xffff, 0),
+			Parent:    netlink.HANDLE_CLSACT,
+		},
+		QdiscType: "clsact",
+	})
+	if err != nil {
+		return fmt.Errorf("clsact: %w", err)
+	}
+	for _, f := range filters(prefix) {
+		if err := f.add(link.Attrs().Index, dscp); err != nil {
+			return fmt.Errorf("filter: %w", err)
+		}
+	}
+	return nil
+}
+
+// Clear removes the marking of prefix, or of all traffic if prefix is nil.
+func Clear(vpc, vpcAttachment string, prefix *net.IPNet) error {
+	link, err := netlink.LinkByName(util.GenerateInterfaceNameHost(vpc, vpcAttachment))
+	var notFound netlink.LinkNotFoundError
+	if errors.As(err, &notFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, f := range filters(prefix) {
+		err := netlink.FilterDel(&netlink.Flower{FilterAttrs: netlink.FilterAttrs{
+			LinkIndex: link.Attrs().Index,
+			Parent:    netlink.HANDLE_MIN_INGRESS,
+			Handle:    f.handle,
+			Priority:  f.prio,
+			Protocol:  f.protocol,
+		}})
+		if err != nil && !errors.Is(err, unix.ENOENT) {
+			return err
+		}
+	}
+	return nil
+}
+
+// add installs f on the ingress of link. The netlink library's pedit action
+// can't rewrite the traffic class, so the request is built here.
+func (f filter) add(link int, dscp uint8) error {
+	req := nl.NewNetlinkRequest(unix.RTM_NEWTFILTER, unix.NLM_F_CREATE|unix.NLM_F_ACK)
+	req.AddData(&nl.TcMsg{
+		Family:  nl.FAMILY_ALL,
+		Ifindex: int32(link),
+		Handle:  f.handle,
+		Parent:  netlink.HANDLE_MIN_INGRESS,
+		Info:    netlink.MakeHandle(f.prio, nl.Swap16(f.protocol)),
+	})
+	req.AddData(nl.NewRtAttr(nl.TCA_KIND, nl.ZeroTerminated("flower")))
+
+	options := nl.NewRtAttr(nl.TCA_OPTIONS, nil)
+	options.AddRtAttr(nl.TCA_FLOWER_KEY_ETH_TYPE, be16(f.protocol))
+	if f.prefix != nil {
+		if f.protocol == unix.ETH_P_IP {
+			options.AddRtAttr(nl.TCA_FLOWER_KEY_IPV4_DST, f.prefix.IP.To4())
+			options.AddRtAttr(nl.TCA_FLOWER_KEY_IPV4_DST_MASK, []byte(f.prefix.Mask))
+		} else {
+			options.AddRtAttr(nl.TCA_FLOWER_KEY_IPV6_DST, f.prefix.IP.To16())
+			options.AddRtAttr(nl.TCA_FLOWER_KEY_IPV6_DST_MASK, []byte(f.prefix.Mask))
+		}
+	}
+	options.AddRtAttr(nl.TCA_FLOWER_FLAGS, nl.Uint32Attr(nl.TCA_CLS_FLAGS_SKIP_HW))
+
+	actions := options.AddRtAttr(nl.TCA_FLOWER_ACT, nil)
+	pedit := nl.TcPedit{}
+	// pedit rewrites a 32 bit word as (word & mask) ^ val, both in network
+	// byte order; the DSCP is the upper six bits of the traffic class (IPv6)
+	// or TOS (IPv4) byte
+	shift := 22
+	header := nl.TCA_PEDIT_KEY_EX_HDR_TYPE_IP6
+	if f.protocol == unix.ETH_P_IP {
+		shift = 18
+		header = nl.TCA_PEDIT_KEY_EX_HDR_TYPE_IP4
+	}
+	pedit.Keys = []nl.TcPeditKey{{
+		Mask: be32(^(uint32(MaxDSCP) << shift)),
+		Val:  be32(uint32(dscp) << shift),
+	}}
+	pedit.KeysEx = []nl.TcPeditKeyEx{{HeaderType: nl.PeditHeaderType(header), Cmd: nl.TCA_PEDIT_KEY_EX_CMD_SET}}
+	pedit.Sel.NKeys = 1
+	pedit.Sel.Action = int32(netlink.TC_ACT_OK)
+	if f.protocol == unix.ETH_P_IP {
+		// the IPv4 header checksum covers the TOS byte
+		pedit.Sel.Action = int32(netlink.TC_ACT_PIPE)
+	}
+	pedit.Encode(actions.AddRtAttr(1, nil))
+	if f.protocol == unix.ETH_P_IP {
+		csum := actions.AddRtAttr(2, nil)
+		csum.AddRtAttr(nl.TCA_ACT_KIND, nl.ZeroTerminated("csum"))
+		parms := nl.TcCsum{TcGen: nl.TcGen{Action: int32(netlink.TC_ACT_OK)}, UpdateFlags: uint32(netlink.TCA_CSUM_UPDATE_FLAG_IPV4HDR)}
+		csum.AddRtAttr(nl.TCA_ACT_OPTIONS, nil).AddRtAttr(nl.TCA_CSUM_PARMS, parms.Serialize())
+	}
+	req.AddData(options)
+	_, err := req.Execute(unix.NETLINK_ROUTE, 0)
+	return err
+}
+
+func be16(v uint16) []byte {
+	return binary.BigEndian.AppendUint16(nil, v)
+}
+
+// be32 returns v laid out in network byte order, as a native integer.
+func be32(v uint32) uint32 {
+	return nl.NativeEndian().Uint32(binary.BigEndian.AppendUint32(nil, v))
+}
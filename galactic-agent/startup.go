@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/datum-cloud/galactic-agent/metrics"
+	"github.com/datum-cloud/galactic-agent/reconcile"
+	"github.com/datum-cloud/galactic-agent/srv6/dryrun"
+	"github.com/datum-cloud/galactic-agent/state"
+)
+
+var replayDuration = metrics.NewGauge(
+	"galactic_agent_replay_seconds",
+	"How long the startup replay of the persisted state took.",
+)
+
+// startup is the replay of the persisted state into the kernel when the
+// agent starts, which readiness and the controller connections wait for.
+var startup = &stateReplay{finished: make(chan struct{})}
+
+type stateReplay struct {
+	total, done atomic.Int64
+	finished    chan struct{}
+}
+
+// ready fails until the replay finished, with its progress.
+func (p *stateReplay) ready() error {
+	select {
+	case <-p.finished:
+		return nil
+	default:
+	}
+	return fmt.Errorf("replaying persisted state: %d of %d changes", p.done.Load(), p.total.Load())
+}
+
+// wait returns once the replay finished, or ctx is done.
+func (p *stateReplay) wait(ctx context.Context) error {
+	select {
+	case <-p.finished:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// run reconciles the kernel with the state stores, with up to workers
+// netlink calls at a time. It first restores what the routes go into, such
+// as the VRFs a reboot took along, then programs what the stores hold and
+// the kernel lacks, or has programmed differently. With prune it also
+// removes the routes and proxy neighbors of galactic tables the stores do
+// not hold; without, they are left to routes diff and hook. An empty store
+// or registry, as of a lost state file or a new tenant, is never pruned
+// from: what the kernel holds may be all there is left of it. A datapath
+// other than the kernel gets the whole state. The changes left pending in
+// the journal are made last.
+func (p *stateReplay) run(workers int, prune bool) {
+	defer close(p.finished)
+	// what a crash interrupted is made again on top of the state
+	defer replayJournal()
+	start := time.Now()
+	if kernelDatapath() {
+		p.fixHost()
+	}
+	var changes []reconcile.Change
+	for _, t := range tenantMap.All() {
+		store := domains[t].store
+		if !kernelDatapath() {
+			// other datapaths cannot be read back: everything is
+			// programmed again
+			changes = append(changes, reconcile.Desired(store.Snapshot(), dp.IngressAdd, func(e state.Egress) error {
+				return dp.EgressAdd(e.Network, e.Endpoint, e.Segments, attachmentMTU(store, e.Endpoint))
+			})...)
+			continue
+		}
+		snap := store.Snapshot()
+		c, err := reconcile.Diff(snap, t.SRv6Net)
+		if err != nil {
+			slog.Error("Replay", "tenant", t.Name, "err", err)
+			continue
+		}
+		pruneTenant := prune && len(snap.Attachments) > 0 && len(snap.Ingress)+len(snap.Egress) > 0
+		for _, c := range c {
+			if pruneTenant || c.Op != reconcile.Extra {
+				changes = append(changes, c)
+			}
+		}
+	}
+	p.total.Store(int64(len(changes)))
+	if len(changes) == 0 {
+		return
+	}
+	slog.Info("Replaying persisted state", "changes", len(changes), "workers", workers)
+	step := max(int64(len(changes))/10, 1)
+	errs := reconcile.Apply(changes, workers, func(reconcile.Change, error) {
+		if n := p.done.Add(1); n%step == 0 {
+			slog.Info("Replay progress", "done", n, "changes", len(changes))
+		}
+	})
+	// after a reboot whole attachments are gone, so only a sample of the
+	// failures is worth logging
+	for _, err := range errs[:min(len(errs), 10)] {
+		slog.Warn("Replay", "err", err)
+	}
+	elapsed := time.Since(start)
+	replayDuration.Set(elapsed.Seconds())
+	slog.Info("Replayed persisted state", "changes", len(changes), "elapsed", elapsed.Round(time.Millisecond), "failed", len(errs))
+}
+
+// fixHost restores the host sysctls and the VRFs of the attachments the
+// state stores hold. A missing host interface can't be restored; its
+// attachment's routes then fail to replay until the workload is attached
+// again.
+func (p *stateReplay) fixHost() {
+	for _, t := range tenantMap.All() {
+		changes, err := reconcile.Host(domains[t].store.Snapshot())
+		if err != nil {
+			slog.Error("Replay", "tenant", t.Name, "err", err)
+			continue
+		}
+		for _, c := range changes {
+			if dryrun.Enabled() {
+				dryrun.Log("fix", "change", c.String())
+				continue
+			}
+			if err := c.Fix(); err != nil {
+				slog.Warn("Replay: fix", "change", c.String(), "err", err)
+				continue
+			}
+			slog.Info("Replay: fixed", "change", c.String())
+		}
+	}
+}
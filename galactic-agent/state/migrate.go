@@ -0,0 +1,45 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Version is the layout of the state files this agent writes. Bump it
+// and add a migration whenever a change would make older files read
+// wrong.
+const Version = 1
+
+// migrations[i] rewrites a file of version i into version i+1, on the
+// file decoded into generic JSON values.
+var migrations = []func(map[string]any) error{
+	// Files from before versioning have the layout of version 1.
+	func(map[string]any) error { return nil },
+}
+
+// migrate brings the state file b up to Version.
+func migrate(b []byte) ([]byte, error) {
+	var head struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(b, &head); err != nil {
+		return nil, err
+	}
+	if head.Version > Version {
+		return nil, fmt.Errorf("state file version %d is newer than this agent's %d", head.Version, Version)
+	}
+	if head.Version == Version {
+		return b, nil
+	}
+	var st map[string]any
+	if err := json.Unmarshal(b, &st); err != nil {
+		return nil, err
+	}
+	for v := head.Version; v < Version; v++ {
+		if err := migrations[v](st); err != nil {
+			return nil, fmt.Errorf("migrating state file from version %d: %w", v, err)
+		}
+	}
+	st["version"] = Version
+	return json.Marshal(st)
+}
@@ -0,0 +1,70 @@
+package state
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+)
+
+// KeySize is the length of a state key: the file is sealed with AES-256-GCM.
+const KeySize = 32
+
+// ErrSealed is returned when an encrypted state file is read without its
+// key.
+var ErrSealed = errors.New("state file is encrypted")
+
+// sealedMagic starts every encrypted state file, followed by the nonce and
+// the sealed JSON.
+var sealedMagic = []byte("galactic-state-aes256gcm\n")
+
+func aead(key []byte) (cipher.AEAD, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("state key must be %d bytes, got %d", KeySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func seal(key, plaintext []byte) ([]byte, error) {
+	gcm, err := aead(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	out := append(bytes.Clone(sealedMagic), nonce...)
+	return gcm.Seal(out, nonce, plaintext, sealedMagic), nil
+}
+
+// unseal returns the JSON in b. Plain files are passed through so that an
+// existing store can be encrypted by configuring a key; the next save
+// seals it.
+func unseal(key, b []byte) ([]byte, error) {
+	if !bytes.HasPrefix(b, sealedMagic) {
+		return b, nil
+	}
+	if key == nil {
+		return nil, ErrSealed
+	}
+	gcm, err := aead(key)
+	if err != nil {
+		return nil, err
+	}
+	b = b[len(sealedMagic):]
+	if len(b) < gcm.NonceSize() {
+		return nil, errors.New("state file is truncated")
+	}
+	plaintext, err := gcm.Open(nil, b[:gcm.NonceSize()], b[gcm.NonceSize():], sealedMagic)
+	if err != nil {
+		return nil, fmt.Errorf("state file: %w", err)
+	}
+	return plaintext, nil
+}
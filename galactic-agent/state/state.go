@@ -0,0 +1,536 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Egress is a route received from the controller: traffic from the
+// attachment identified by Endpoint to Network is encapsulated with Segments.
+type Egress struct {
+	Network  string   `json:"network"`
+	Endpoint string   `json:"srv6_endpoint"`
+	Segments []string `json:"srv6_segments"`
+	DSCP     int      `json:"dscp,omitempty"`
+}
+
+// Attachment is a registered VPC attachment and what the agent knows about
+// it, so consumers need not re-derive it from interface names.
+type Attachment struct {
+	VPC           string    `json:"vpc"`
+	VPCAttachment string    `json:"vpcattachment"`
+	VRF           string    `json:"vrf"`
+	Table         int       `json:"table"`
+	Host          string    `json:"host_interface"`
+	Guest         string    `json:"guest_interface"`
+	Endpoint      string    `json:"srv6_endpoint"`
+	Networks      []string  `json:"networks"`
+	Anycast       []string  `json:"anycast,omitempty"`
+	Name          string    `json:"name,omitempty"`
+	MTU           int       `json:"mtu,omitempty"`
+	Created       time.Time `json:"created"`
+
+	// Generation is the highest generation the attachment was registered
+	// or deregistered with, 0 if its callers give none.
+	Generation uint64 `json:"generation,omitempty"`
+}
+
+// Deregistered is the generation an attachment was last deregistered with,
+// kept once the attachment is gone so that a registration of that
+// generation or an older one, retried late, is told apart from a new one.
+type Deregistered struct {
+	VPC           string `json:"vpc"`
+	VPCAttachment string `json:"vpcattachment"`
+	Generation    uint64 `json:"generation"`
+}
+
+// State is the dataplane the agent wants the kernel to hold: one ingress
+// End.DT46 route per registered SRv6 endpoint and one egress route per
+// received Route, plus the registry of attachments.
+type State struct {
+	// Version is the layout of the file, see Version.
+	Version     int          `json:"version"`
+	Ingress     []string     `json:"ingress"`
+	Egress      []Egress     `json:"egress"`
+	Attachments []Attachment `json:"attachments,omitempty"`
+	// Deregistered are the generations of attachments deregistered with
+	// one, until they are registered again.
+	Deregistered []Deregistered `json:"deregistered,omitempty"`
+}
+
+type egressKey struct {
+	endpoint, network string
+}
+
+type attachmentKey struct {
+	vpc, vpcAttachment string
+}
+
+// Store tracks the desired state in memory and, when it has a path, mirrors
+// every change to a JSON file so other processes (routes diff) can read it.
+// The changes of a bulk are saved together under a Hold.
+type Store struct {
+	path string
+	key  []byte
+
+	// OnChange, if set, is called after every change with the store
+	// locked; it must neither block nor use the store.
+	OnChange func()
+
+	mu          sync.Mutex
+	ingress     map[string]struct{}
+	egress      map[egressKey]Egress
+	attachments map[attachmentKey]*Attachment
+	// deregistered are the generations of the attachments gone
+	deregistered map[attachmentKey]uint64
+	// changes counts the changes made, saved those the file holds
+	changes, saved uint64
+	// held counts the Holds not released; changes are only saved once
+	// none is left
+	held int
+
+	// writing is held while the file is written; changes made meanwhile
+	// are saved together by the next write
+	writing sync.Mutex
+}
+
+// Open loads the store at path if it exists. An empty path keeps the state
+// in memory only.
+func Open(path string) (*Store, error) {
+	return OpenSealed(path, nil)
+}
+
+// OpenSealed is Open for a state file encrypted with key, see KeySize. A
+// nil key stores plain JSON.
+func OpenSealed(path string, key []byte) (*Store, error) {
+	if key != nil {
+		if _, err := aead(key); err != nil {
+			return nil, err
+		}
+	}
+	s := &Store{
+		path:        path,
+		key:         key,
+		ingress:     make(map[string]struct{}),
+		egress:      make(map[egressKey]Egress),
+		attachments: make(map[attachmentKey]*Attachment),
+
+		deregistered: make(map[attachmentKey]uint64),
+	}
+	if path == "" {
+		return s, nil
+	}
+	st, err := LoadSealed(path, key)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range st.Ingress {
+		s.ingress[e] = struct{}{}
+	}
+	for _, e := range st.Egress {
+		s.egress[egressKey{e.Endpoint, e.Network}] = e
+	}
+	for _, a := range st.Attachments {
+		s.attachments[attachmentKey{a.VPC, a.VPCAttachment}] = &a
+	}
+	for _, d := range st.Deregistered {
+		s.deregistered[attachmentKey{d.VPC, d.VPCAttachment}] = d.Generation
+	}
+	return s, nil
+}
+
+// Load reads a state file written by a Store.
+func Load(path string) (State, error) {
+	return LoadSealed(path, nil)
+}
+
+// LoadSealed reads a state file written by a Store with key. Files of
+// earlier versions are migrated; those of later versions, written by a
+// newer agent, are refused rather than read wrong.
+func LoadSealed(path string, key []byte) (State, error) {
+	var st State
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return st, err
+	}
+	if b, err = unseal(key, b); err != nil {
+		return st, err
+	}
+	if b, err = migrate(b); err != nil {
+		return st, fmt.Errorf("%s: %w", path, err)
+	}
+	err = json.Unmarshal(b, &st)
+	return st, err
+}
+
+func (s *Store) AddIngress(endpoint string) error {
+	return s.update(func() error {
+		s.ingress[endpoint] = struct{}{}
+		return nil
+	})
+}
+
+func (s *Store) DelIngress(endpoint string) error {
+	return s.update(func() error {
+		delete(s.ingress, endpoint)
+		return nil
+	})
+}
+
+func (s *Store) AddEgress(network, endpoint string, segments []string, dscp int) error {
+	return s.update(func() error {
+		s.egress[egressKey{endpoint, network}] = Egress{Network: network, Endpoint: endpoint, Segments: slices.Clone(segments), DSCP: dscp}
+		return nil
+	})
+}
+
+// Egress returns the egress route of endpoint to network.
+func (s *Store) Egress(network, endpoint string) (Egress, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.egress[egressKey{endpoint, network}]
+	e.Segments = slices.Clone(e.Segments)
+	return e, ok
+}
+
+func (s *Store) DelEgress(network, endpoint string) error {
+	return s.update(func() error {
+		delete(s.egress, egressKey{endpoint, network})
+		return nil
+	})
+}
+
+// EgressUsage counts the egress routes of endpoint, other than the one to
+// network, and how many of those are host routes.
+func (s *Store) EgressUsage(endpoint, network string) (routes, hosts int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for k := range s.egress {
+		if k.endpoint != endpoint || k.network == network {
+			continue
+		}
+		routes++
+		if IsHost(k.network) {
+			hosts++
+		}
+	}
+	return routes, hosts
+}
+
+// IsHost reports whether network is a single address, which the agent
+// answers neighbor solicitations for.
+func IsHost(network string) bool {
+	_, n, err := net.ParseCIDR(network)
+	if err != nil {
+		return false
+	}
+	ones, bits := n.Mask.Size()
+	return ones == bits
+}
+
+// RegisterAttachment records a, adding its networks to those already
+// registered. The creation time and MTU of a known attachment are kept, and
+// its name unless a has one. Its generation never goes back.
+func (s *Store) RegisterAttachment(a Attachment) error {
+	return s.update(func() error {
+		k := attachmentKey{a.VPC, a.VPCAttachment}
+		a.Networks = slices.Clone(a.Networks)
+		a.Anycast = slices.Clone(a.Anycast)
+		a.Generation = max(a.Generation, s.deregistered[k])
+		delete(s.deregistered, k)
+		if old, ok := s.attachments[k]; ok {
+			a.Created = old.Created
+			a.Generation = max(a.Generation, old.Generation)
+			if a.MTU == 0 {
+				a.MTU = old.MTU
+			}
+			if a.Name == "" {
+				a.Name = old.Name
+			}
+			for _, n := range old.Networks {
+				if !slices.Contains(a.Networks, n) {
+					a.Networks = append(a.Networks, n)
+					if slices.Contains(old.Anycast, n) {
+						a.Anycast = append(a.Anycast, n)
+					}
+				}
+			}
+		}
+		if a.Created.IsZero() {
+			a.Created = time.Now().UTC()
+		}
+		sort.Strings(a.Networks)
+		sort.Strings(a.Anycast)
+		s.attachments[k] = &a
+		return nil
+	})
+}
+
+// DeregisterAttachment removes networks from an attachment and forgets the
+// attachment once none are left, keeping its generation if it has one.
+func (s *Store) DeregisterAttachment(vpc, vpcAttachment string, networks []string) error {
+	return s.update(func() error {
+		k := attachmentKey{vpc, vpcAttachment}
+		a, ok := s.attachments[k]
+		if !ok {
+			return errUnchanged
+		}
+		a.Networks = slices.DeleteFunc(a.Networks, func(n string) bool {
+			return slices.Contains(networks, n)
+		})
+		a.Anycast = slices.DeleteFunc(a.Anycast, func(n string) bool {
+			return slices.Contains(networks, n)
+		})
+		if len(a.Networks) == 0 {
+			delete(s.attachments, k)
+			if a.Generation > 0 {
+				s.deregistered[k] = a.Generation
+			}
+		}
+		return nil
+	})
+}
+
+// SetGeneration records generation for an attachment: that of its
+// registrations if it is registered, the one it was deregistered with
+// otherwise. A generation never goes back.
+func (s *Store) SetGeneration(vpc, vpcAttachment string, generation uint64) error {
+	return s.update(func() error {
+		k := attachmentKey{vpc, vpcAttachment}
+		if a, ok := s.attachments[k]; ok {
+			if generation <= a.Generation {
+				return errUnchanged
+			}
+			a.Generation = generation
+			return nil
+		}
+		if generation <= s.deregistered[k] {
+			return errUnchanged
+		}
+		s.deregistered[k] = generation
+		return nil
+	})
+}
+
+// SetMTU records the MTU of a registered attachment.
+func (s *Store) SetMTU(vpc, vpcAttachment string, mtu int) error {
+	return s.update(func() error {
+		a, ok := s.attachments[attachmentKey{vpc, vpcAttachment}]
+		if !ok {
+			return fmt.Errorf("attachment %s/%s is not registered", vpc, vpcAttachment)
+		}
+		a.MTU = mtu
+		return nil
+	})
+}
+
+// Attachment returns a copy of the registered attachment.
+func (s *Store) Attachment(vpc, vpcAttachment string) (Attachment, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a, ok := s.attachments[attachmentKey{vpc, vpcAttachment}]
+	if !ok {
+		return Attachment{}, false
+	}
+	c := *a
+	c.Networks = slices.Clone(a.Networks)
+	c.Anycast = slices.Clone(a.Anycast)
+	return c, true
+}
+
+// Generation returns the generation of an attachment and whether it is
+// registered: that of its registrations, or the one it was deregistered
+// with. 0 if none was given.
+func (s *Store) Generation(vpc, vpcAttachment string) (uint64, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k := attachmentKey{vpc, vpcAttachment}
+	if a, ok := s.attachments[k]; ok {
+		return a.Generation, true
+	}
+	return s.deregistered[k], false
+}
+
+// NetworkOwners returns copies of the attachments of vpc, other than
+// vpcAttachment, that registered network.
+func (s *Store) NetworkOwners(vpc, vpcAttachment, network string) []Attachment {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var owners []Attachment
+	for k, a := range s.attachments {
+		if k.vpc != vpc || k.vpcAttachment == vpcAttachment || !slices.Contains(a.Networks, network) {
+			continue
+		}
+		c := *a
+		c.Networks = slices.Clone(a.Networks)
+		c.Anycast = slices.Clone(a.Anycast)
+		owners = append(owners, c)
+	}
+	return owners
+}
+
+// Snapshot returns a sorted copy of the desired state.
+func (s *Store) Snapshot() State {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.snapshot()
+}
+
+func (s *Store) snapshot() State {
+	st := State{
+		Version: Version,
+		Ingress: make([]string, 0, len(s.ingress)),
+		Egress:  make([]Egress, 0, len(s.egress)),
+	}
+	for e := range s.ingress {
+		st.Ingress = append(st.Ingress, e)
+	}
+	for _, e := range s.egress {
+		e.Segments = slices.Clone(e.Segments)
+		st.Egress = append(st.Egress, e)
+	}
+	for _, a := range s.attachments {
+		c := *a
+		c.Networks = slices.Clone(a.Networks)
+		c.Anycast = slices.Clone(a.Anycast)
+		st.Attachments = append(st.Attachments, c)
+	}
+	for k, g := range s.deregistered {
+		st.Deregistered = append(st.Deregistered, Deregistered{VPC: k.vpc, VPCAttachment: k.vpcAttachment, Generation: g})
+	}
+	sort.Strings(st.Ingress)
+	sort.Slice(st.Egress, func(i, j int) bool {
+		if st.Egress[i].Endpoint != st.Egress[j].Endpoint {
+			return st.Egress[i].Endpoint < st.Egress[j].Endpoint
+		}
+		return st.Egress[i].Network < st.Egress[j].Network
+	})
+	sort.Slice(st.Attachments, func(i, j int) bool {
+		if st.Attachments[i].VPC != st.Attachments[j].VPC {
+			return st.Attachments[i].VPC < st.Attachments[j].VPC
+		}
+		return st.Attachments[i].VPCAttachment < st.Attachments[j].VPCAttachment
+	})
+	sort.Slice(st.Deregistered, func(i, j int) bool {
+		if st.Deregistered[i].VPC != st.Deregistered[j].VPC {
+			return st.Deregistered[i].VPC < st.Deregistered[j].VPC
+		}
+		return st.Deregistered[i].VPCAttachment < st.Deregistered[j].VPCAttachment
+	})
+	return st
+}
+
+// errUnchanged ends an update that changed nothing.
+var errUnchanged = errors.New("unchanged")
+
+// update makes change with the store locked, then saves it. change returns
+// errUnchanged when it had nothing to do.
+func (s *Store) update(change func() error) error {
+	s.mu.Lock()
+	if err := change(); err != nil {
+		s.mu.Unlock()
+		if errors.Is(err, errUnchanged) {
+			return nil
+		}
+		return err
+	}
+	if s.OnChange != nil {
+		s.OnChange()
+	}
+	s.changes++
+	held := s.held > 0
+	s.mu.Unlock()
+	if held {
+		return nil
+	}
+	return s.save()
+}
+
+// Hold keeps changes from being saved, by anyone, until Release, so that
+// a bulk of changes is written once rather than once each.
+func (s *Store) Hold() {
+	s.mu.Lock()
+	s.held++
+	s.mu.Unlock()
+}
+
+// Release ends a Hold, saving the changes made during it once no other
+// Hold is left.
+func (s *Store) Release() error {
+	s.mu.Lock()
+	s.held--
+	held := s.held > 0
+	s.mu.Unlock()
+	if held {
+		return nil
+	}
+	return s.save()
+}
+
+// save writes the state atomically once the changes made until now are
+// in it. Writes are made one at a time, each of all the changes made
+// while the one before it was written.
+func (s *Store) save() error {
+	if s.path == "" {
+		return nil
+	}
+	s.writing.Lock()
+	defer s.writing.Unlock()
+	s.mu.Lock()
+	if s.saved == s.changes {
+		// the write before saved them
+		s.mu.Unlock()
+		return nil
+	}
+	changes, st := s.changes, s.snapshot()
+	s.mu.Unlock()
+	if err := s.write(st); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.saved = changes
+	s.mu.Unlock()
+	return nil
+}
+
+// write replaces the file with st, synced to disk before it takes the
+// place of the old one.
+func (s *Store) write(st State) error {
+	b, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+	if s.key != nil {
+		if b, err = seal(s.key, b); err != nil {
+			return err
+		}
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) //nolint:errcheck
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close() //nolint:errcheck
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close() //nolint:errcheck
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
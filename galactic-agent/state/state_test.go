@@ -0,0 +1,48 @@
+package state
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// TestHold checks changes made during a Hold are saved once it is
+// released, and not before.
+func TestHold(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddIngress("fc00::1"); err != nil {
+		t.Fatal(err)
+	}
+	s.Hold()
+	s.Hold()
+	for _, n := range []string{"10.0.0.1/32", "10.0.0.2/32"} {
+		if err := s.AddEgress(n, "fc00::1", []string{"fc00::2"}, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	saved := func() int {
+		st, err := Load(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(st.Egress)
+	}
+	if n := saved(); n != 0 {
+		t.Errorf("%d routes saved during the hold", n)
+	}
+	if err := s.Release(); err != nil {
+		t.Fatal(err)
+	}
+	if n := saved(); n != 0 {
+		t.Errorf("%d routes saved with a hold left", n)
+	}
+	if err := s.Release(); err != nil {
+		t.Fatal(err)
+	}
+	if n := saved(); n != 2 {
+		t.Errorf("%d routes saved after the hold, want 2", n)
+	}
+}
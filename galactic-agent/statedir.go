@@ -0,0 +1,57 @@
+package main
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/viper"
+)
+
+// legacyStateDir is where the state file was kept before state_dir. It is
+// a tmpfs on most hosts, so the state did not survive a reboot.
+const legacyStateDir = "/var/run/galactic"
+
+// adoptLegacyState moves the state file an older agent left in
+// legacyStateDir to path, the first time the agent starts with the state
+// in state_dir. A path configured elsewhere, or one that exists, is left
+// alone.
+func adoptLegacyState(path string) error {
+	if path == "" || filepath.Dir(path) != filepath.Clean(viper.GetString("state_dir")) {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	legacy := filepath.Join(legacyStateDir, filepath.Base(path))
+	b, err := os.ReadFile(legacy)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	// the directories are usually different filesystems, so copy and
+	// rename rather than rename the file across
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) //nolint:errcheck
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close() //nolint:errcheck
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	slog.Info("Moved state file", "from", legacy, "to", path)
+	return os.Remove(legacy)
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/datum-cloud/galactic-agent/state"
+)
+
+// stateKey returns the key the state file is encrypted with, or nil if it
+// is stored in the clear. It comes from the first of state_key (base64),
+// state_key_file (raw or base64) or state_key_command, which prints the
+// key in base64 and is how a KMS or secret store is consulted.
+func stateKey() ([]byte, error) {
+	if v := viper.GetString("state_key"); v != "" {
+		return decodeStateKey([]byte(v))
+	}
+	if path := viper.GetString("state_key_file"); path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if len(b) == state.KeySize {
+			return b, nil
+		}
+		return decodeStateKey(b)
+	}
+	if argv := viper.GetStringSlice("state_key_command"); len(argv) > 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+		cmd.Stderr = os.Stderr
+		out, err := cmd.Output()
+		if err != nil {
+			return nil, fmt.Errorf("state_key_command: %w", err)
+		}
+		return decodeStateKey(out)
+	}
+	return nil, nil
+}
+
+func decodeStateKey(b []byte) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(b)))
+	if err != nil {
+		return nil, fmt.Errorf("state key is not base64: %w", err)
+	}
+	if len(key) != state.KeySize {
+		return nil, fmt.Errorf("state key must be %d bytes, got %d", state.KeySize, len(key))
+	}
+	return key, nil
+}
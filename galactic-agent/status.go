@@ -0,0 +1,140 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/datum-cloud/galactic-agent/api/local"
+	"github.com/datum-cloud/galactic-agent/client"
+	"github.com/datum-cloud/galactic-agent/srv6/dryrun"
+)
+
+// startedAt is when the agent started, for its uptime.
+var startedAt = time.Now()
+
+// agentStatus serves the Status RPC.
+func agentStatus() (*local.StatusReply, error) {
+	b := currentBuild()
+	reply := &local.StatusReply{
+		Version:    b.Version,
+		Commit:     b.Commit,
+		BuildDate:  b.BuildDate,
+		Schema:     b.Schema,
+		ConfigFile: viper.ConfigFileUsed(),
+		Profile:    viper.GetString("profile"),
+		DryRun:     dryrun.Enabled(),
+
+		UptimeSeconds: int64(time.Since(startedAt).Seconds()),
+		LastErrors:    recentErrors(),
+	}
+	vrfs := make(map[attachmentID]string)
+	for _, t := range tenantMap.All() {
+		reply.Tenants = append(reply.Tenants, t.Name)
+		d := domains[t]
+		st := d.store.Snapshot()
+		ts := &local.TenantStatus{
+			Name:        t.Name,
+			Srv6Net:     d.SRv6Net,
+			Broker:      d.remote.Settings().URL,
+			Connected:   d.remote.Connected(),
+			Attachments: uint32(len(st.Attachments)),
+			Routes:      uint32(len(st.Egress)),
+		}
+		if err := d.remote.LastError(); err != nil {
+			ts.BrokerError = err.Error()
+		}
+		reply.TenantStatus = append(reply.TenantStatus, ts)
+		for _, a := range st.Attachments {
+			vrfs[attachmentID{a.VPC, a.VPCAttachment}] = a.VRF
+		}
+	}
+	counts := routeCounts.count()
+	ids := slices.SortedFunc(maps.Keys(counts), func(a, b attachmentID) int {
+		return cmp.Or(cmp.Compare(a.vpc, b.vpc), cmp.Compare(a.vpcAttachment, b.vpcAttachment))
+	})
+	for _, id := range ids {
+		n := counts[id]
+		reply.Vrfs = append(reply.Vrfs, &local.VRFStatus{
+			Vrf:           vrfs[id],
+			Vpc:           id.vpc,
+			Vpcattachment: id.vpcAttachment,
+			IngressRoutes: uint32(n[ingressRoutes]),
+			EgressRoutes:  uint32(n[egressRoutes]),
+			Neighbors:     uint32(n[neighborRoutes]),
+		})
+	}
+	for _, r := range preflightFailures {
+		reply.PreflightFailures = append(reply.PreflightFailures, r.Name+": "+r.Err.Error())
+	}
+	return reply, nil
+}
+
+func newStatusCmd() *cobra.Command {
+	var socketPath string
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show the build and configuration of the running agent",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+			defer stop()
+
+			if socketPath == "" {
+				socketPath = viper.GetString("socket_path")
+			}
+			c, err := client.New(socketPath)
+			if err != nil {
+				return err
+			}
+			defer c.Close() //nolint:errcheck
+			reply, err := c.Status(ctx)
+			if err != nil {
+				return err
+			}
+			profile := reply.GetProfile()
+			if profile == "" {
+				profile = "-"
+			}
+			fmt.Printf("version:     %s (commit %s, built %s)\n", reply.GetVersion(), reply.GetCommit(), reply.GetBuildDate())
+			fmt.Printf("schema:      %s\n", reply.GetSchema())
+			fmt.Printf("config file: %s\n", reply.GetConfigFile())
+			fmt.Printf("profile:     %s\n", profile)
+			fmt.Printf("tenants:     %s\n", strings.Join(reply.GetTenants(), ", "))
+			if reply.GetDryRun() {
+				fmt.Println("dry run:     the kernel is not changed")
+			}
+			for _, f := range reply.GetPreflightFailures() {
+				fmt.Printf("degraded:    %s\n", f)
+			}
+			fmt.Printf("uptime:      %s\n", time.Duration(reply.GetUptimeSeconds())*time.Second)
+			for _, t := range reply.GetTenantStatus() {
+				broker := "connected"
+				if !t.GetConnected() {
+					broker = "disconnected"
+					if t.GetBrokerError() != "" {
+						broker += ": " + t.GetBrokerError()
+					}
+				}
+				fmt.Printf("tenant:      %s: srv6_net %s, %d attachments, %d routes, broker %s %s\n", t.GetName(), t.GetSrv6Net(), t.GetAttachments(), t.GetRoutes(), t.GetBroker(), broker)
+			}
+			for _, v := range reply.GetVrfs() {
+				fmt.Printf("vrf:         %s: vpc %s attachment %s, %d ingress, %d egress, %d neighbors\n", v.GetVrf(), v.GetVpc(), v.GetVpcattachment(), v.GetIngressRoutes(), v.GetEgressRoutes(), v.GetNeighbors())
+			}
+			for _, e := range reply.GetLastErrors() {
+				fmt.Printf("error:       %s %s %s: %s\n", time.Unix(e.GetTimeUnix(), 0).Format(time.RFC3339), e.GetTenant(), e.GetOperation(), e.GetError())
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&socketPath, "socket", "", "agent socket (default: socket_path)")
+	return cmd
+}
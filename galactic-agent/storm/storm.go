@@ -0,0 +1,291 @@
+package storm
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/datum-cloud/galactic-agent/api/remote"
+	"github.com/datum-cloud/galactic-agent/broker"
+)
+
+// Config controls a reconnect storm. Every cycle the agent's connection is
+// dropped by the broker, the harness waits for remote.Remote to reconnect and
+// resubscribe, and then publishes a seeded random batch of routes that must
+// all be delivered exactly once before the next cycle.
+type Config struct {
+	Cycles         int
+	RoutesPerCycle int
+	Seed           int64
+	Timeout        time.Duration // per cycle
+
+	// MaxHeapGrowth and MaxGoroutineGrowth bound what may be retained after
+	// the storm compared to after the first cycle.
+	MaxHeapGrowth      uint64
+	MaxGoroutineGrowth int
+}
+
+// Result summarizes a storm that passed all checks.
+type Result struct {
+	Cycles           int
+	Routes           int
+	HeapBefore       uint64
+	HeapAfter        uint64
+	GoroutinesBefore int
+	GoroutinesAfter  int
+	Duration         time.Duration
+}
+
+const (
+	agentID    = "galactic-storm-agent"
+	topicRX    = "galactic/storm/agent/receive"
+	publisher  = "galactic-storm-publisher"
+	waitPeriod = time.Millisecond
+)
+
+// receiver counts deliveries per route network.
+type receiver struct {
+	mu        sync.Mutex
+	delivered map[string]int
+	arrived   chan struct{}
+}
+
+func (r *receiver) handle(payload []byte) error {
+	envelope := &remote.Envelope{}
+	if err := proto.Unmarshal(payload, envelope); err != nil {
+		return err
+	}
+	route := envelope.GetRoute()
+	if route == nil {
+		return fmt.Errorf("unexpected envelope %T", envelope.Kind)
+	}
+	r.mu.Lock()
+	r.delivered[route.Network]++
+	r.mu.Unlock()
+	select {
+	case r.arrived <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (r *receiver) count(network string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.delivered[network]
+}
+
+// network derives a unique route prefix from a sequence number.
+func network(seq int) string {
+	return fmt.Sprintf("10.%d.%d.%d/32", (seq>>16)&0xff, (seq>>8)&0xff, seq&0xff)
+}
+
+// Run drives remote.Remote through c.Cycles disconnect/reconnect cycles
+// against an embedded broker and fails on the first duplicate subscription,
+// lost or duplicated route, or when memory is not released afterwards.
+func Run(ctx context.Context, c Config) (*Result, error) {
+	if c.Cycles == 0 {
+		c.Cycles = 200
+	}
+	if c.RoutesPerCycle == 0 {
+		c.RoutesPerCycle = 10
+	}
+	if c.Timeout == 0 {
+		c.Timeout = 5 * time.Second
+	}
+	if c.MaxHeapGrowth == 0 {
+		c.MaxHeapGrowth = 4 << 20
+	}
+	if c.MaxGoroutineGrowth == 0 {
+		c.MaxGoroutineGrowth = 10
+	}
+	rng := rand.New(rand.NewSource(c.Seed))
+
+	dir, err := os.MkdirTemp("", "galactic-storm-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir) //nolint:errcheck
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	b := broker.New()
+	listener, err := net.Listen("unix", filepath.Join(dir, "broker.sock"))
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		if err := b.Serve(ctx, listener); err != nil {
+			slog.Error("storm: broker failed", "err", err)
+		}
+	}()
+	url := "unix://" + listener.Addr().String()
+
+	recv := &receiver{delivered: make(map[string]int), arrived: make(chan struct{}, 1)}
+	r := &remote.Remote{
+		URL:               url,
+		ClientID:          agentID,
+		QoS:               1,
+		TopicRX:           topicRX,
+		TopicTX:           "galactic/storm/agent/send",
+		ReceiveHandler:    recv.handle,
+		ReconnectInterval: 10 * time.Millisecond,
+	}
+	runErr := make(chan error, 1)
+	go func() {
+		runErr <- r.Run(ctx)
+	}()
+
+	pub := mqtt.NewClient(mqtt.NewClientOptions().AddBroker(url).SetClientID(publisher))
+	if tok := pub.Connect(); tok.Wait() && tok.Error() != nil {
+		return nil, tok.Error()
+	}
+	defer pub.Disconnect(0)
+
+	res := &Result{Cycles: c.Cycles}
+	start := time.Now()
+	seq := 0
+	for cycle := 0; cycle < c.Cycles; cycle++ {
+		if cycle > 0 {
+			if n := b.Drop(agentID); n != 1 {
+				return res, fmt.Errorf("cycle %d: dropped %d connections, want 1", cycle, n)
+			}
+		}
+		if err := awaitSubscription(ctx, b, c.Timeout); err != nil {
+			return res, fmt.Errorf("cycle %d: %w", cycle, err)
+		}
+
+		batch := 1 + rng.Intn(c.RoutesPerCycle)
+		networks := make([]string, batch)
+		for i := range networks {
+			networks[i] = network(seq)
+			seq++
+			payload, err := proto.Marshal(&remote.Envelope{
+				Kind: &remote.Envelope_Route{
+					Route: &remote.Route{
+						Status:       remote.Route_ADD,
+						Network:      networks[i],
+						Srv6Endpoint: "fc00::1",
+						Srv6Segments: []string{"fc00::2"},
+					},
+				},
+			})
+			if err != nil {
+				return res, err
+			}
+			if tok := pub.Publish(topicRX, 1, false, payload); tok.Wait() && tok.Error() != nil {
+				return res, tok.Error()
+			}
+		}
+		if err := awaitDelivery(ctx, recv, networks, c.Timeout); err != nil {
+			return res, fmt.Errorf("cycle %d: %w", cycle, err)
+		}
+		res.Routes += batch
+
+		if cycle == 0 {
+			res.HeapBefore, res.GoroutinesBefore = usage()
+		}
+	}
+	res.Duration = time.Since(start)
+
+	// give paho a moment to retire the goroutines of the last dropped
+	// connection before measuring
+	time.Sleep(100 * time.Millisecond)
+	res.HeapAfter, res.GoroutinesAfter = usage()
+
+	// deliveries must not have changed after the fact
+	for s := 0; s < seq; s++ {
+		if n := recv.count(network(s)); n != 1 {
+			return res, fmt.Errorf("route %s delivered %d times", network(s), n)
+		}
+	}
+	if res.HeapAfter > res.HeapBefore && res.HeapAfter-res.HeapBefore > c.MaxHeapGrowth {
+		return res, fmt.Errorf("heap grew by %d bytes over %d cycles", res.HeapAfter-res.HeapBefore, c.Cycles)
+	}
+	if res.GoroutinesAfter-res.GoroutinesBefore > c.MaxGoroutineGrowth {
+		return res, fmt.Errorf("goroutines grew from %d to %d over %d cycles", res.GoroutinesBefore, res.GoroutinesAfter, c.Cycles)
+	}
+
+	cancel()
+	if err := <-runErr; err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+// awaitSubscription waits until the agent has exactly one live connection
+// subscribed to exactly its receive topic.
+func awaitSubscription(ctx context.Context, b *broker.Broker, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		subs := b.Subscriptions(agentID)
+		if len(subs) > 1 {
+			return fmt.Errorf("%d concurrent connections for %s", len(subs), agentID)
+		}
+		if len(subs) == 1 {
+			switch {
+			case len(subs[0]) > 1:
+				return fmt.Errorf("duplicate subscriptions: %v", subs[0])
+			case len(subs[0]) == 1 && subs[0][0] != topicRX:
+				return fmt.Errorf("unexpected subscription %q", subs[0][0])
+			case len(subs[0]) == 1:
+				return nil
+			}
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("agent did not resubscribe within %s", timeout)
+		}
+		select {
+		case <-time.After(waitPeriod):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// awaitDelivery waits until every network has been delivered once and fails
+// as soon as one is delivered twice.
+func awaitDelivery(ctx context.Context, recv *receiver, networks []string, timeout time.Duration) error {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		missing := 0
+		for _, n := range networks {
+			switch recv.count(n) {
+			case 0:
+				missing++
+			case 1:
+			default:
+				return fmt.Errorf("route %s delivered %d times", n, recv.count(n))
+			}
+		}
+		if missing == 0 {
+			return nil
+		}
+		select {
+		case <-recv.arrived:
+		case <-timer.C:
+			return fmt.Errorf("%d of %d routes lost", missing, len(networks))
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func usage() (uint64, int) {
+	runtime.GC()
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return m.HeapAlloc, runtime.NumGoroutine()
+}
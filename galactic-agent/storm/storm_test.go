@@ -0,0 +1,64 @@
+package storm
+
+import (
+	"context"
+	"io"
+	"log"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestReconnectStorm(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	for _, tt := range []struct {
+		name string
+		c    Config
+	}{
+		{"many cycles", Config{Cycles: 200, RoutesPerCycle: 10, Seed: 1}},
+		{"large batches", Config{Cycles: 20, RoutesPerCycle: 200, Seed: 2}},
+		{"one route per cycle", Config{Cycles: 100, RoutesPerCycle: 1, Seed: 3}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if testing.Short() {
+				tt.c.Cycles = min(tt.c.Cycles, 10)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+			defer cancel()
+			res, err := Run(ctx, tt.c)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Cycles != tt.c.Cycles {
+				t.Errorf("ran %d cycles, want %d", res.Cycles, tt.c.Cycles)
+			}
+			if res.Routes < tt.c.Cycles || res.Routes > tt.c.Cycles*tt.c.RoutesPerCycle {
+				t.Errorf("delivered %d routes over %d cycles of at most %d", res.Routes, tt.c.Cycles, tt.c.RoutesPerCycle)
+			}
+			t.Logf("%d routes in %s, heap %d->%d, goroutines %d->%d", res.Routes, res.Duration.Round(time.Millisecond),
+				res.HeapBefore, res.HeapAfter, res.GoroutinesBefore, res.GoroutinesAfter)
+		})
+	}
+}
+
+// TestReconnectStormDeterministic checks that a seed always publishes the
+// same routes, so a failing storm can be replayed.
+func TestReconnectStormDeterministic(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	c := Config{Cycles: 10, RoutesPerCycle: 50, Seed: 42}
+	var routes []int
+	for range 2 {
+		res, err := Run(context.Background(), c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		routes = append(routes, res.Routes)
+	}
+	if routes[0] != routes[1] {
+		t.Errorf("seed %d delivered %d then %d routes", c.Seed, routes[0], routes[1])
+	}
+}
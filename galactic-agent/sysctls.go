@@ -0,0 +1,39 @@
+package main
+
+import (
+	"log/slog"
+
+	"github.com/spf13/viper"
+
+	"github.com/datum-cloud/galactic-agent/srv6/dryrun"
+	"github.com/datum-cloud/galactic-agent/sysctls"
+)
+
+// manageSysctls sets the sysctls the kernel datapath needs, seg6_enabled on
+// sysctl_interfaces or else the uplinks, and warns about host configuration
+// working against them. Failures are logged; the preflight checks that
+// follow tell whether the agent can run.
+func manageSysctls() {
+	interfaces := viper.GetStringSlice("sysctl_interfaces")
+	if len(interfaces) == 0 {
+		var err error
+		if interfaces, err = sysctls.Uplinks(); err != nil {
+			slog.Error("Sysctls: uplinks", "err", err)
+		}
+	}
+	settings := sysctls.Required(interfaces)
+	changes, err := sysctls.Apply(settings, !dryrun.Enabled())
+	for _, c := range changes {
+		if dryrun.Enabled() {
+			dryrun.Log("sysctl", "key", c.Key, "from", c.From, "to", c.To)
+			continue
+		}
+		slog.Info("Sysctl set", "key", c.Key, "from", c.From, "to", c.To)
+	}
+	if err != nil {
+		slog.Error("Sysctls not set", "err", err)
+	}
+	for _, c := range sysctls.Conflicts(settings) {
+		slog.Warn("Sysctl conflict", "key", c.Key, "detail", c.Detail)
+	}
+}
@@ -0,0 +1,198 @@
+// Package sysctls sets the host sysctls SRv6 forwarding between VRFs
+// needs, and finds what on the host works against them. Without them the
+// agent programs its routes fine and the traffic is dropped.
+package sysctls
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/vishvananda/netlink"
+
+	"github.com/datum-cloud/galactic-agent/nsutil"
+	"github.com/datum-cloud/galactic-agent/reconcile"
+)
+
+// Setting is a sysctl, named as under /proc/sys, and the value the
+// datapath needs.
+type Setting struct {
+	Key   string
+	Value string
+}
+
+// Required returns the host-wide settings of reconcile.HostSysctls and
+// seg6_enabled on each of interfaces, the interfaces SRv6 traffic arrives
+// on.
+func Required(interfaces []string) []Setting {
+	var settings []Setting
+	for key, value := range reconcile.HostSysctls {
+		settings = append(settings, Setting{Key: key, Value: value})
+	}
+	slices.SortFunc(settings, func(a, b Setting) int { return strings.Compare(a.Key, b.Key) })
+	for _, name := range interfaces {
+		settings = append(settings, Setting{Key: "net/ipv6/conf/" + name + "/seg6_enabled", Value: "1"})
+	}
+	return settings
+}
+
+// Uplinks returns the interfaces of the IPv6 default routes.
+func Uplinks() ([]string, error) {
+	routes, err := netlink.RouteList(nil, netlink.FAMILY_V6)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, r := range routes {
+		if r.Dst != nil && !r.Dst.IP.IsUnspecified() || r.LinkIndex == 0 {
+			continue
+		}
+		link, err := netlink.LinkByIndex(r.LinkIndex)
+		if err != nil {
+			return nil, err
+		}
+		if !slices.Contains(names, link.Attrs().Name) {
+			names = append(names, link.Attrs().Name)
+		}
+	}
+	return names, nil
+}
+
+// Change is a setting Apply changed, or would change.
+type Change struct {
+	Key  string
+	From string
+	To   string
+}
+
+// Apply sets the settings the kernel does not have yet and returns them.
+// Unless write is set it only reports them. A setting that fails does not
+// keep the others from being set.
+func Apply(settings []Setting, write bool) ([]Change, error) {
+	var changes []Change
+	var errs []error
+	for _, s := range settings {
+		got, err := Get(s.Key)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if got == s.Value {
+			continue
+		}
+		if write {
+			if err := nsutil.SetSysctl(s.Key, s.Value); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", s.Key, err))
+				continue
+			}
+		}
+		changes = append(changes, Change{Key: s.Key, From: got, To: s.Value})
+	}
+	return changes, errors.Join(errs...)
+}
+
+// Get reads the sysctl key.
+func Get(key string) (string, error) {
+	b, err := os.ReadFile(filepath.Join("/proc/sys", filepath.FromSlash(key)))
+	if os.IsNotExist(err) && strings.HasPrefix(key, "net/vrf/") {
+		return "", fmt.Errorf("%s: vrf module not loaded (modprobe vrf)", key)
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+// Conflict is a sysctl that works against the settings.
+type Conflict struct {
+	Key    string
+	Detail string
+}
+
+// sysctlDirs are where systemd-sysctl reads sysctl.d files from, most
+// important first: a file masks those of the same name in later
+// directories.
+var sysctlDirs = []string{"/etc/sysctl.d", "/run/sysctl.d", "/usr/local/lib/sysctl.d", "/usr/lib/sysctl.d"}
+
+// Conflicts reports sysctl.d files and /etc/sysctl.conf setting one of
+// settings to another value, which undoes it at the next boot or sysctl
+// reload, and strict reverse path filtering on all interfaces, which
+// overrides the loose filtering of host interfaces.
+func Conflicts(settings []Setting) []Conflict {
+	var conflicts []Conflict
+	configured := configuredSysctls()
+	for _, s := range settings {
+		if c, ok := configured[s.Key]; ok && c.value != s.Value {
+			conflicts = append(conflicts, Conflict{Key: s.Key,
+				Detail: fmt.Sprintf("%s sets %s, the datapath needs %s", c.file, c.value, s.Value)})
+		}
+	}
+	if v, err := Get("net/ipv4/conf/all/rp_filter"); err == nil && v == "1" {
+		conflicts = append(conflicts, Conflict{Key: "net/ipv4/conf/all/rp_filter",
+			Detail: "strict on all interfaces, which overrides the loose filtering of host interfaces and can drop traffic routed between VRFs; set it to 0 or 2"})
+	}
+	return conflicts
+}
+
+type configured struct {
+	value string
+	file  string
+}
+
+// configuredSysctls returns the sysctls the sysctl.d files and
+// /etc/sysctl.conf set, as systemd-sysctl applies them: files sorted by
+// name, later assignments winning.
+func configuredSysctls() map[string]configured {
+	files := make(map[string]string)
+	for _, dir := range sysctlDirs {
+		matches, _ := filepath.Glob(filepath.Join(dir, "*.conf"))
+		for _, m := range matches {
+			if _, ok := files[filepath.Base(m)]; !ok {
+				files[filepath.Base(m)] = m
+			}
+		}
+	}
+	var paths []string
+	for _, name := range slices.Sorted(maps.Keys(files)) {
+		paths = append(paths, files[name])
+	}
+	paths = append(paths, "/etc/sysctl.conf")
+
+	sysctls := make(map[string]configured)
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			continue
+		}
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || line[0] == '#' || line[0] == ';' {
+				continue
+			}
+			key, value, ok := strings.Cut(line, "=")
+			if !ok {
+				continue
+			}
+			key = strings.TrimPrefix(strings.TrimSpace(key), "-")
+			sysctls[keyPath(key)] = configured{value: strings.TrimSpace(value), file: path}
+		}
+		f.Close() //nolint:errcheck
+	}
+	return sysctls
+}
+
+// keyPath turns the dotted names of sysctl.d into /proc/sys paths. Names
+// with a slash are paths already, and their dots belong to the name, as in
+// net/ipv6/conf/eth0.100/seg6_enabled.
+func keyPath(key string) string {
+	if strings.Contains(key, "/") {
+		return key
+	}
+	return strings.ReplaceAll(key, ".", "/")
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/vishvananda/netlink"
+
+	"github.com/datum-cloud/galactic-agent/metrics"
+	"github.com/datum-cloud/galactic-agent/sdnotify"
+)
+
+// notifySystemd tells systemd the agent is ready once the local API serves,
+// the persisted state is replayed and every tenant's broker subscription is
+// up. Under WatchdogSec it then feeds the watchdog for as long as the agent
+// is alive, so that systemd restarts a wedged agent. It does nothing unless
+// systemd started the agent with notifications.
+func notifySystemd(ctx context.Context) error {
+	if !sdnotify.Enabled() {
+		return nil
+	}
+	interval := time.Second
+	watchdog := sdnotify.WatchdogInterval()
+	if watchdog > 0 {
+		interval = min(interval, watchdog/2)
+		slog.Info("systemd watchdog", "interval", watchdog)
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	ready, lastErr := false, ""
+	for {
+		select {
+		case <-ctx.Done():
+			return sdnotify.Notify(sdnotify.Stopping)
+		case <-ticker.C:
+		}
+		var states []string
+		if !ready {
+			if err := agentReady(); err != nil {
+				states = append(states, sdnotify.Status(err.Error()))
+			} else {
+				ready = true
+				states = append(states, sdnotify.Ready, sdnotify.Status("ready"))
+			}
+		}
+		if watchdog > 0 {
+			err := agentAlive(ready)
+			switch {
+			case err == nil:
+				states = append(states, sdnotify.Watchdog)
+				if lastErr != "" && ready {
+					states = append(states, sdnotify.Status("ready"))
+				}
+				lastErr = ""
+			case err.Error() != lastErr:
+				lastErr = err.Error()
+				slog.Warn("systemd watchdog not fed", "err", err)
+				states = append(states, sdnotify.Status(lastErr))
+			}
+		}
+		if len(states) == 0 {
+			continue
+		}
+		if err := sdnotify.Notify(states...); err != nil {
+			slog.Error("systemd notify", "err", err)
+		}
+	}
+}
+
+// agentReady fails until the agent serves both sides.
+func agentReady() error {
+	for _, c := range readyChecks() {
+		if err := c.Run(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readyChecks are what /readyz and agentReady check: the local API
+// serves, the persisted state is replayed, every tenant's broker
+// subscription is up and the kernel answers netlink requests.
+func readyChecks() []metrics.Check {
+	return []metrics.Check{
+		{Name: "local-api", Run: func() error {
+			if !l.Serving() {
+				return errors.New("local API not serving")
+			}
+			return nil
+		}},
+		{Name: "startup", Run: startup.ready},
+		{Name: "mqtt", Run: tenantTransport{}.ready},
+		{Name: "netlink", Run: netlinkAlive},
+	}
+}
+
+// agentAlive fails when the kernel no longer answers netlink requests or,
+// once the agent was ready, a broker connection is down.
+func agentAlive(ready bool) error {
+	if err := netlinkAlive(); err != nil {
+		return err
+	}
+	if ready {
+		return tenantTransport{}.ready()
+	}
+	return nil
+}
+
+func netlinkAlive() error {
+	if _, err := netlink.LinkByName("lo"); err != nil {
+		return fmt.Errorf("netlink: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+// Package tenant maps VPCs to the control domain serving them, so that one
+// agent can serve several tenants, each with its own locator, topics, broker
+// and state.
+package tenant
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"path/filepath"
+	"strings"
+
+	"github.com/datum-cloud/galactic-agent/srv6/endpoint"
+)
+
+// Default is the name of the tenant configured by the top-level settings.
+// It serves every VPC not mapped to another tenant.
+const Default = "default"
+
+// Tenant is a control domain. Each has a locator of its own, so kernel
+// routes can be told apart. Unset broker settings are inherited from the
+// default tenant; topics, state and ipam files are derived from the name so
+// tenants never share them by accident.
+type Tenant struct {
+	Name string   `mapstructure:"name"`
+	VPCs []string `mapstructure:"vpcs"`
+
+	SRv6Net            string `mapstructure:"srv6_net"`
+	MQTTURL            string `mapstructure:"mqtt_url"`
+	MQTTClientID       string `mapstructure:"mqtt_clientid"`
+	MQTTUsername       string `mapstructure:"mqtt_username"`
+	MQTTPassword       string `mapstructure:"mqtt_password"`
+	MQTTTopicReceive   string `mapstructure:"mqtt_topic_receive"`
+	MQTTTopicSend      string `mapstructure:"mqtt_topic_send"`
+	MQTTTopicTelemetry string `mapstructure:"mqtt_topic_telemetry"`
+
+	StatePath       string `mapstructure:"state_path"`
+	IPAMPath        string `mapstructure:"ipam_path"`
+	CredentialsPath string `mapstructure:"credentials_path"`
+}
+
+// Map resolves VPCs to tenants.
+type Map struct {
+	tenants []*Tenant
+	byVPC   map[string]*Tenant
+}
+
+// New validates tenants and completes them from def, the default tenant.
+// A VPC may belong to one tenant only.
+func New(def Tenant, tenants []Tenant) (*Map, error) {
+	def.Name = Default
+	def.VPCs = nil
+	m := &Map{tenants: []*Tenant{&def}, byVPC: make(map[string]*Tenant)}
+	_, defNet, err := net.ParseCIDR(def.SRv6Net)
+	if err != nil {
+		return nil, fmt.Errorf("srv6_net invalid: %w", err)
+	}
+	locators := []*net.IPNet{defNet}
+	names := map[string]bool{Default: true}
+	for i := range tenants {
+		t := tenants[i]
+		if t.Name == "" || strings.ContainsAny(t.Name, "/ ") {
+			return nil, fmt.Errorf("tenant %d: invalid name %q", i, t.Name)
+		}
+		if names[t.Name] {
+			return nil, fmt.Errorf("tenant %s: defined twice", t.Name)
+		}
+		names[t.Name] = true
+		if len(t.VPCs) == 0 {
+			return nil, fmt.Errorf("tenant %s: no vpcs", t.Name)
+		}
+		for j, vpc := range t.VPCs {
+			padded, _, err := endpoint.PadHex(vpc, "0")
+			if err != nil {
+				return nil, fmt.Errorf("tenant %s: %w", t.Name, err)
+			}
+			if other, ok := m.byVPC[padded]; ok {
+				return nil, fmt.Errorf("tenant %s: vpc %s already belongs to tenant %s", t.Name, padded, other.Name)
+			}
+			t.VPCs[j] = padded
+			m.byVPC[padded] = &t
+		}
+		if _, err := endpoint.Encode(t.SRv6Net, "ffffffffffff", "ffff"); err != nil {
+			return nil, fmt.Errorf("tenant %s: srv6_net invalid: %w", t.Name, err)
+		}
+		_, locator, _ := net.ParseCIDR(t.SRv6Net)
+		for j, other := range locators {
+			if locator.Contains(other.IP) || other.Contains(locator.IP) {
+				return nil, fmt.Errorf("tenant %s: srv6_net %s overlaps that of tenant %s", t.Name, locator, m.tenants[j].Name)
+			}
+		}
+		locators = append(locators, locator)
+		t.inherit(def)
+		m.tenants = append(m.tenants, &t)
+	}
+	return m, nil
+}
+
+// inherit fills the unset fields of t from def.
+func (t *Tenant) inherit(def Tenant) {
+	set := func(field *string, value string) {
+		if *field == "" {
+			*field = value
+		}
+	}
+	set(&t.MQTTURL, def.MQTTURL)
+	set(&t.MQTTUsername, def.MQTTUsername)
+	set(&t.MQTTPassword, def.MQTTPassword)
+	if def.MQTTClientID != "" {
+		set(&t.MQTTClientID, def.MQTTClientID+"-"+t.Name)
+	}
+	set(&t.MQTTTopicReceive, "galactic/"+t.Name+"/receive")
+	set(&t.MQTTTopicSend, "galactic/"+t.Name+"/send")
+	set(&t.MQTTTopicTelemetry, "galactic/"+t.Name+"/telemetry")
+	set(&t.StatePath, suffixed(def.StatePath, t.Name))
+	set(&t.IPAMPath, suffixed(def.IPAMPath, t.Name))
+	set(&t.CredentialsPath, suffixed(def.CredentialsPath, t.Name))
+}
+
+// suffixed turns /dir/state.json into /dir/state-name.json.
+func suffixed(path, name string) string {
+	if path == "" {
+		return ""
+	}
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "-" + name + ext
+}
+
+// For returns the tenant serving vpc, the default tenant if no other claims
+// it or the id is malformed.
+func (m *Map) For(vpc string) *Tenant {
+	if padded, _, err := endpoint.PadHex(vpc, "0"); err == nil {
+		if t, ok := m.byVPC[padded]; ok {
+			return t
+		}
+	}
+	return m.tenants[0]
+}
+
+// All returns every tenant, the default one first.
+func (m *Map) All() []*Tenant {
+	return m.tenants
+}
+
+// Get returns the tenant called name, nil if there is none.
+func (m *Map) Get(name string) *Tenant {
+	for _, t := range m.tenants {
+		if t.Name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+// ErrForeignVPC is returned when a tenant's controller sends control
+// messages for a VPC served by another tenant.
+var ErrForeignVPC = errors.New("vpc belongs to another tenant")
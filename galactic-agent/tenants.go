@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/viper"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/datum-cloud/galactic-agent/api/remote"
+	"github.com/datum-cloud/galactic-agent/ipam"
+	"github.com/datum-cloud/galactic-agent/metrics"
+	"github.com/datum-cloud/galactic-agent/state"
+	"github.com/datum-cloud/galactic-agent/tenant"
+)
+
+// loadTenants reads the top-level settings as the default tenant and the
+// tenants list.
+func loadTenants() (*tenant.Map, error) {
+	def := tenant.Tenant{
+		SRv6Net:            viper.GetString("srv6_net"),
+		MQTTURL:            viper.GetString("mqtt_url"),
+		MQTTClientID:       viper.GetString("mqtt_clientid"),
+		MQTTUsername:       viper.GetString("mqtt_username"),
+		MQTTPassword:       viper.GetString("mqtt_password"),
+		MQTTTopicReceive:   viper.GetString("mqtt_topic_receive"),
+		MQTTTopicSend:      viper.GetString("mqtt_topic_send"),
+		MQTTTopicTelemetry: viper.GetString("mqtt_topic_telemetry"),
+		StatePath:          viper.GetString("state_path"),
+		IPAMPath:           viper.GetString("ipam_path"),
+		CredentialsPath:    viper.GetString("credentials_path"),
+	}
+	var tenants []tenant.Tenant
+	if err := viper.UnmarshalKey("tenants", &tenants); err != nil {
+		return nil, fmt.Errorf("tenants invalid: %w", err)
+	}
+	m, err := tenant.New(def, tenants)
+	if err != nil {
+		return nil, fmt.Errorf("tenants invalid: %w", err)
+	}
+	return m, nil
+}
+
+// domain is everything the agent keeps per tenant.
+type domain struct {
+	*tenant.Tenant
+	store     *state.Store
+	allocator *ipam.Allocator
+	remote    *remote.Remote
+}
+
+var (
+	tenantMap *tenant.Map
+	domains   = make(map[*tenant.Tenant]*domain)
+
+	tenantAttachments = metrics.NewGauge(
+		"galactic_agent_tenant_attachments",
+		"Attachments registered on this agent, by tenant.",
+		"tenant",
+	)
+	tenantEnvelopes = metrics.NewCounter(
+		"galactic_agent_tenant_envelopes_total",
+		"Envelopes exchanged with the controller of each tenant.",
+		"tenant", "direction",
+	)
+	tenantForeign = metrics.NewCounter(
+		"galactic_agent_tenant_foreign_routes_total",
+		"Routes and other control messages dropped because a tenant's controller sent them for a VPC of another tenant.",
+		"tenant",
+	)
+)
+
+// domainFor returns the domain serving vpc.
+func domainFor(vpc string) *domain {
+	return domains[tenantMap.For(vpc)]
+}
+
+// countAttachments refreshes the attachment gauge of d.
+func countAttachments(d *domain) {
+	tenantAttachments.Set(float64(len(d.store.Snapshot().Attachments)), d.Name)
+}
+
+// checkTenant refuses a control message d's controller sent for an
+// endpoint in another tenant's VPC, so one control domain can't program
+// routes into another.
+func checkTenant(d *domain, srv6Endpoint string) error {
+	vpc := endpointVPC(srv6Endpoint)
+	if owner := tenantMap.For(vpc); owner != d.Tenant {
+		tenantForeign.Inc(d.Name)
+		return fmt.Errorf("%s in vpc %s from tenant %s: %w", srv6Endpoint, vpc, d.Name, tenant.ErrForeignVPC)
+	}
+	return nil
+}
+
+// tenantTransport sends each envelope through the remote of the tenant
+// serving the VPC it concerns.
+type tenantTransport struct{}
+
+func (tenantTransport) Run(ctx context.Context) error {
+	g, ctx := errgroup.WithContext(ctx)
+	for _, t := range tenantMap.All() {
+		d := domains[t]
+		g.Go(func() error {
+			return d.remote.Run(ctx)
+		})
+	}
+	return g.Wait()
+}
+
+// ready fails while any tenant's broker connection is down.
+func (tenantTransport) ready() error {
+	for _, t := range tenantMap.All() {
+		m := domains[t].remote
+		if m.Connected() {
+			continue
+		}
+		if err := m.LastError(); err != nil {
+			return fmt.Errorf("tenant %s: broker %s not connected: %w", t.Name, m.Settings().URL, err)
+		}
+		return fmt.Errorf("tenant %s: broker %s not connected", t.Name, m.Settings().URL)
+	}
+	return nil
+}
+
+func (tenantTransport) SendEnvelope(ctx context.Context, envelope *remote.Envelope) error {
+	var srv6Endpoint string
+	switch kind := envelope.Kind.(type) {
+	case *remote.Envelope_Register:
+		srv6Endpoint = kind.Register.Srv6Endpoint
+	case *remote.Envelope_Deregister:
+		srv6Endpoint = kind.Deregister.Srv6Endpoint
+	case *remote.Envelope_Nack:
+		srv6Endpoint = kind.Nack.Route.GetSrv6Endpoint()
+	}
+	d := domainFor(endpointVPC(srv6Endpoint))
+	tenantEnvelopes.Inc(d.Name, "send")
+	return d.remote.SendEnvelope(ctx, envelope)
+}
+
+// reconnectAll reconnects every tenant's remote, after the client
+// certificate changed.
+func reconnectAll() {
+	for _, d := range domains {
+		d.remote.Reconnect()
+	}
+}
@@ -0,0 +1,168 @@
+// Package tlsreload serves TLS material from files that are replaced while
+// the agent runs, as short-lived certificates are. Handshakes always use the
+// latest material; OnChange lets long-lived connections be re-established
+// before the material they were made with expires.
+package tlsreload
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+type material struct {
+	cert  *tls.Certificate
+	roots *x509.CertPool // nil: system roots
+	stamp string
+}
+
+// Watcher polls CertFile, KeyFile and CAFile. Polling rather than inotify
+// also catches the symlink swaps used by Kubernetes secret volumes.
+type Watcher struct {
+	CertFile string
+	KeyFile  string
+	CAFile   string // optional
+	Interval time.Duration
+	OnChange func()
+
+	current atomic.Pointer[material]
+}
+
+// New loads the initial material so that errors surface at startup.
+func New(certFile, keyFile, caFile string) (*Watcher, error) {
+	w := &Watcher{CertFile: certFile, KeyFile: keyFile, CAFile: caFile}
+	m, err := w.load()
+	if err != nil {
+		return nil, err
+	}
+	w.current.Store(m)
+	return w, nil
+}
+
+// stamp identifies the current contents of the files cheaply.
+func (w *Watcher) stamp() (string, error) {
+	var s string
+	for _, f := range []string{w.CertFile, w.KeyFile, w.CAFile} {
+		if f == "" {
+			continue
+		}
+		fi, err := os.Stat(f)
+		if err != nil {
+			return "", err
+		}
+		s += fmt.Sprintf("%s:%d:%d;", f, fi.ModTime().UnixNano(), fi.Size())
+	}
+	return s, nil
+}
+
+func (w *Watcher) load() (*material, error) {
+	stamp, err := w.stamp()
+	if err != nil {
+		return nil, err
+	}
+	cert, err := tls.LoadX509KeyPair(w.CertFile, w.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	m := &material{cert: &cert, stamp: stamp}
+	if w.CAFile != "" {
+		pem, err := os.ReadFile(w.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		m.roots = x509.NewCertPool()
+		if !m.roots.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("%s: no certificates found", w.CAFile)
+		}
+	}
+	return m, nil
+}
+
+// Run polls for changes until ctx is done. A file caught mid-write fails to
+// load and is retried on the next poll, so the previous material stays in
+// use until a complete replacement is in place.
+func (w *Watcher) Run(ctx context.Context) error {
+	interval := w.Interval
+	if interval == 0 {
+		interval = 10 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		stamp, err := w.stamp()
+		if err != nil || stamp == w.current.Load().stamp {
+			continue
+		}
+		m, err := w.load()
+		if err != nil {
+			slog.Warn("tlsreload: not reloaded", "cert", w.CertFile, "err", err)
+			continue
+		}
+		w.current.Store(m)
+		slog.Info("tlsreload: reloaded", "cert", w.CertFile)
+		if w.OnChange != nil {
+			w.OnChange()
+		}
+	}
+}
+
+// ClientConfig returns a client configuration that looks up the current
+// material on every handshake.
+func (w *Watcher) ClientConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return w.current.Load().cert, nil
+		},
+		// the default verification cannot follow RootCAs changes, so the
+		// chain is verified against the current roots below
+		InsecureSkipVerify: true, //nolint:gosec
+		VerifyConnection: func(cs tls.ConnectionState) error {
+			if len(cs.PeerCertificates) == 0 {
+				return errors.New("peer presented no certificate")
+			}
+			intermediates := x509.NewCertPool()
+			for _, c := range cs.PeerCertificates[1:] {
+				intermediates.AddCert(c)
+			}
+			_, err := cs.PeerCertificates[0].Verify(x509.VerifyOptions{
+				DNSName:       cs.ServerName,
+				Roots:         w.current.Load().roots,
+				Intermediates: intermediates,
+			})
+			return err
+		},
+	}
+}
+
+// ServerConfig returns a server configuration that looks up the current
+// material on every handshake and, with a CAFile, requires client
+// certificates issued by it.
+func (w *Watcher) ServerConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			m := w.current.Load()
+			c := &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*m.cert},
+			}
+			if m.roots != nil {
+				c.ClientCAs = m.roots
+				c.ClientAuth = tls.RequireAndVerifyClientCert
+			}
+			return c, nil
+		},
+	}
+}
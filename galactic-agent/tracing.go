@@ -0,0 +1,46 @@
+package main
+
+import (
+	"log/slog"
+	"os"
+
+	"github.com/spf13/viper"
+
+	"github.com/datum-cloud/galactic-agent/srv6"
+	"github.com/datum-cloud/galactic-agent/tracing"
+)
+
+// loadTracing starts tracing the Register, Deregister and Route flows when
+// tracing_endpoint names an OTLP/HTTP collector, and returns the exporter
+// to run. id is the agent ID, which tells the agents' spans apart.
+func loadTracing(id string) *tracing.Exporter {
+	endpoint := viper.GetString("tracing_endpoint")
+	if endpoint == "" {
+		return nil
+	}
+	hostname, _ := os.Hostname()
+	e := &tracing.Exporter{
+		Endpoint: endpoint,
+		Headers:  viper.GetStringMapString("tracing_headers"),
+		Resource: map[string]string{
+			"service.name":        "galactic-agent",
+			"service.version":     currentBuild().Version,
+			"service.instance.id": id,
+			"host.name":           hostname,
+		},
+		SampleRatio: viper.GetFloat64("tracing_sample_ratio"),
+		Interval:    viper.GetDuration("tracing_interval"),
+	}
+	tracing.Install(e)
+	slog.Info("Tracing", "endpoint", endpoint, "sample_ratio", e.SampleRatio)
+	return e
+}
+
+// endDataplane ends span, a change of the dataplane, tagged with the class
+// of its failure if it failed.
+func endDataplane(span *tracing.Span, err error) {
+	if err != nil {
+		span.SetAttr("error.class", string(srv6.ClassOf(err)))
+	}
+	span.End(err)
+}
@@ -0,0 +1,203 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/datum-cloud/galactic-agent/metrics"
+)
+
+var spansExported = metrics.NewCounter(
+	"galactic_agent_spans_total",
+	"Trace spans, by outcome: exported, failed to export or rejected by the collector, or dropped because the queue was full.",
+	"outcome",
+)
+
+// Exporter batches ended spans and posts them to an OTLP/HTTP collector,
+// encoded with the OTLP protobuf types.
+type Exporter struct {
+	// Endpoint is the collector's base URL, e.g. http://collector:4318;
+	// spans go to its /v1/traces.
+	Endpoint string
+	// Headers are added to every request, e.g. for authentication.
+	Headers map[string]string
+	// Resource describes the agent, e.g. service.name and host.name.
+	Resource map[string]string
+	// SampleRatio is the share of traces recorded, from 0 to 1.
+	SampleRatio float64
+	// Interval is how often spans are posted; MaxQueue how many may wait,
+	// beyond which new ones are dropped.
+	Interval time.Duration
+	MaxQueue int
+
+	client *http.Client
+
+	mu    sync.Mutex
+	queue []*Span
+}
+
+// Install starts recording spans for e, which Run then exports.
+func Install(e *Exporter) {
+	if e.Interval <= 0 {
+		e.Interval = 5 * time.Second
+	}
+	if e.MaxQueue <= 0 {
+		e.MaxQueue = 4096
+	}
+	e.client = &http.Client{Timeout: 10 * time.Second}
+	exporter.Store(e)
+}
+
+func (e *Exporter) add(s *Span) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if len(e.queue) >= e.MaxQueue {
+		spansExported.Inc("dropped")
+		return
+	}
+	e.queue = append(e.queue, s)
+}
+
+// Run posts the queued spans every Interval until ctx is done, then once
+// more. Spans ended after that are dropped.
+func (e *Exporter) Run(ctx context.Context) error {
+	ticker := time.NewTicker(e.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			exporter.CompareAndSwap(e, nil)
+			flushCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), e.client.Timeout)
+			defer cancel()
+			e.flush(flushCtx)
+			return nil
+		case <-ticker.C:
+			e.flush(ctx)
+		}
+	}
+}
+
+func (e *Exporter) flush(ctx context.Context) {
+	e.mu.Lock()
+	spans := e.queue
+	e.queue = nil
+	e.mu.Unlock()
+	if len(spans) == 0 {
+		return
+	}
+	rejected, err := e.post(ctx, spans)
+	if err != nil {
+		slog.Warn("Trace export", "endpoint", e.Endpoint, "spans", len(spans), "err", err)
+		spansExported.Add(float64(len(spans)), "failed")
+		return
+	}
+	if rejected > 0 {
+		spansExported.Add(float64(rejected), "failed")
+	}
+	spansExported.Add(float64(int64(len(spans))-rejected), "exported")
+}
+
+// post exports spans and returns how many of them the collector rejected.
+func (e *Exporter) post(ctx context.Context, spans []*Span) (int64, error) {
+	body, err := proto.Marshal(e.request(spans))
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(e.Endpoint, "/")+"/v1/traces", bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	for k, v := range e.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close() //nolint:errcheck
+	if resp.StatusCode/100 != 2 {
+		io.Copy(io.Discard, resp.Body) //nolint:errcheck
+		return 0, fmt.Errorf("collector answered %s", resp.Status)
+	}
+	b, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return 0, err
+	}
+	// an empty body is a full success
+	var res coltracepb.ExportTraceServiceResponse
+	if err := proto.Unmarshal(b, &res); err != nil {
+		return 0, fmt.Errorf("collector response: %w", err)
+	}
+	if p := res.GetPartialSuccess(); p.GetRejectedSpans() > 0 {
+		slog.Warn("Trace export: spans rejected", "endpoint", e.Endpoint, "spans", p.GetRejectedSpans(), "reason", p.GetErrorMessage())
+		return min(p.GetRejectedSpans(), int64(len(spans))), nil
+	}
+	return 0, nil
+}
+
+func (e *Exporter) request(spans []*Span) *coltracepb.ExportTraceServiceRequest {
+	var resource []*commonpb.KeyValue
+	keys := make([]string, 0, len(e.Resource))
+	for k := range e.Resource {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		resource = append(resource, otlpAttr(k, e.Resource[k]))
+	}
+	out := make([]*tracepb.Span, 0, len(spans))
+	for _, s := range spans {
+		s.mu.Lock()
+		o := &tracepb.Span{
+			TraceId:           s.sc.TraceID[:],
+			SpanId:            s.sc.SpanID[:],
+			Name:              s.name,
+			Kind:              tracepb.Span_SpanKind(s.kind),
+			StartTimeUnixNano: uint64(s.start.UnixNano()),
+			EndTimeUnixNano:   uint64(s.end.UnixNano()),
+		}
+		if s.parent != [8]byte{} {
+			o.ParentSpanId = s.parent[:]
+		}
+		for _, a := range s.attrs {
+			o.Attributes = append(o.Attributes, otlpAttr(a.key, a.value))
+		}
+		if s.err != "" {
+			o.Status = &tracepb.Status{Code: tracepb.Status_STATUS_CODE_ERROR, Message: s.err}
+		}
+		s.mu.Unlock()
+		out = append(out, o)
+	}
+	return &coltracepb.ExportTraceServiceRequest{ResourceSpans: []*tracepb.ResourceSpans{{
+		Resource:   &resourcepb.Resource{Attributes: resource},
+		ScopeSpans: []*tracepb.ScopeSpans{{Scope: &commonpb.InstrumentationScope{Name: "galactic-agent"}, Spans: out}},
+	}}}
+}
+
+func otlpAttr(key string, value any) *commonpb.KeyValue {
+	a := &commonpb.KeyValue{Key: key, Value: &commonpb.AnyValue{}}
+	switch v := value.(type) {
+	case string:
+		a.Value.Value = &commonpb.AnyValue_StringValue{StringValue: v}
+	case int64:
+		a.Value.Value = &commonpb.AnyValue_IntValue{IntValue: v}
+	case bool:
+		a.Value.Value = &commonpb.AnyValue_BoolValue{BoolValue: v}
+	}
+	return a
+}
@@ -0,0 +1,92 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// TestExportOTLP checks spans reach a collector decoding them with the
+// OTLP types, and that spans the collector rejects are counted as such.
+func TestExportOTLP(t *testing.T) {
+	requests := make(chan *coltracepb.ExportTraceServiceRequest, 1)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" || r.Header.Get("Content-Type") != "application/x-protobuf" || r.Header.Get("Authorization") != "Bearer t" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		var req coltracepb.ExportTraceServiceRequest
+		if err := proto.Unmarshal(b, &req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		requests <- &req
+		res, _ := proto.Marshal(&coltracepb.ExportTraceServiceResponse{
+			PartialSuccess: &coltracepb.ExportTracePartialSuccess{RejectedSpans: 1, ErrorMessage: "too old"},
+		})
+		w.Header().Set("Content-Type", "application/x-protobuf")
+		w.Write(res) //nolint:errcheck
+	}))
+	defer collector.Close()
+
+	e := &Exporter{
+		Endpoint:    collector.URL + "/",
+		Headers:     map[string]string{"Authorization": "Bearer t"},
+		Resource:    map[string]string{"service.name": "galactic-agent"},
+		SampleRatio: 1,
+	}
+	Install(e)
+	defer exporter.Store(nil)
+
+	ctx, parent := Start(context.Background(), "Register", Server, "vpc", "0000000000ab", "networks", 2, "anycast", true)
+	_, child := Start(ctx, "publish", Producer)
+	child.End(errors.New("broker down"))
+	parent.End(nil)
+
+	rejected, err := e.post(context.Background(), e.queue)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rejected != 1 {
+		t.Errorf("%d spans rejected, want 1", rejected)
+	}
+	req := <-requests
+	rs := req.GetResourceSpans()
+	if len(rs) != 1 || rs[0].GetResource().GetAttributes()[0].GetValue().GetStringValue() != "galactic-agent" {
+		t.Fatalf("resource spans %v", rs)
+	}
+	spans := rs[0].GetScopeSpans()[0].GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("%d spans, want 2", len(spans))
+	}
+	c, p := spans[0], spans[1]
+	if !bytes.Equal(c.GetTraceId(), p.GetTraceId()) || !bytes.Equal(c.GetParentSpanId(), p.GetSpanId()) || len(p.GetParentSpanId()) != 0 {
+		t.Errorf("child %x/%x of %x, parent %x/%x", c.GetTraceId(), c.GetParentSpanId(), c.GetSpanId(), p.GetTraceId(), p.GetSpanId())
+	}
+	if c.GetKind() != tracepb.Span_SPAN_KIND_PRODUCER || p.GetKind() != tracepb.Span_SPAN_KIND_SERVER {
+		t.Errorf("kinds %v and %v", c.GetKind(), p.GetKind())
+	}
+	if s := c.GetStatus(); s.GetCode() != tracepb.Status_STATUS_CODE_ERROR || s.GetMessage() != "broker down" {
+		t.Errorf("child status %v", s)
+	}
+	attrs := p.GetAttributes()
+	if len(attrs) != 3 || attrs[0].GetValue().GetStringValue() != "0000000000ab" || attrs[1].GetValue().GetIntValue() != 2 || !attrs[2].GetValue().GetBoolValue() {
+		t.Errorf("attributes %v", attrs)
+	}
+	if p.GetEndTimeUnixNano() < p.GetStartTimeUnixNano() || p.GetStartTimeUnixNano() == 0 {
+		t.Errorf("span from %d to %d", p.GetStartTimeUnixNano(), p.GetEndTimeUnixNano())
+	}
+}
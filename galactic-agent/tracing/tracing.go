@@ -0,0 +1,194 @@
+// Package tracing records spans of the agent's control flows and exports
+// them to an OpenTelemetry collector over OTLP/HTTP, encoded with the OTLP
+// protobuf types. Like metrics it is intentionally tiny: spans have a name,
+// a kind, string, integer and boolean attributes and an error status, and
+// nothing else.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Kind is the OTLP span kind.
+type Kind int
+
+const (
+	Internal Kind = 1
+	Server   Kind = 2
+	Producer Kind = 4
+	Consumer Kind = 5
+)
+
+// exporter receives the ended spans; nil while tracing is off.
+var exporter atomic.Pointer[Exporter]
+
+// Enabled reports whether spans are recorded.
+func Enabled() bool {
+	return exporter.Load() != nil
+}
+
+// SpanContext identifies a span across goroutines, for children started
+// after its context is gone.
+type SpanContext struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+}
+
+// Valid reports whether sc identifies a span.
+func (sc SpanContext) Valid() bool {
+	return sc.TraceID != [16]byte{}
+}
+
+// Span is an operation being traced. A nil Span, as Start returns when
+// tracing is off or the trace is not sampled, records nothing.
+type Span struct {
+	sc     SpanContext
+	parent [8]byte
+	name   string
+	kind   Kind
+	start  time.Time
+
+	mu    sync.Mutex
+	end   time.Time
+	attrs []attribute
+	err   string
+}
+
+type attribute struct {
+	key   string
+	value any // string, int64 or bool
+}
+
+type spanKey struct{}
+
+// Start starts a span named name as a child of the span in ctx, or as the
+// root of a new trace, and returns a context holding it. attrs are
+// key-value pairs as for SetAttr.
+func Start(ctx context.Context, name string, kind Kind, attrs ...any) (context.Context, *Span) {
+	var parent SpanContext
+	if p, ok := ctx.Value(spanKey{}).(*Span); ok && p != nil {
+		parent = p.sc
+	} else if p, ok := ctx.Value(spanKey{}).(SpanContext); ok {
+		parent = p
+	}
+	s := StartFrom(parent, name, kind, attrs...)
+	if s == nil {
+		return ctx, nil
+	}
+	return context.WithValue(ctx, spanKey{}, s), s
+}
+
+// StartFrom is Start with the parent given by its SpanContext, invalid for
+// a root span.
+func StartFrom(parent SpanContext, name string, kind Kind, attrs ...any) *Span {
+	e := exporter.Load()
+	if e == nil {
+		return nil
+	}
+	s := &Span{name: name, kind: kind, start: time.Now()}
+	if parent.Valid() {
+		s.sc.TraceID = parent.TraceID
+		s.parent = parent.SpanID
+	} else {
+		rand.Read(s.sc.TraceID[:]) //nolint:errcheck
+		if !e.sampled(s.sc.TraceID) {
+			return nil
+		}
+	}
+	rand.Read(s.sc.SpanID[:]) //nolint:errcheck
+	s.SetAttr(attrs...)
+	return s
+}
+
+// Context returns the SpanContext of s, invalid for a nil span.
+func (s *Span) Context() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.sc
+}
+
+// SetAttr sets the attributes given as key-value pairs. Values other than
+// strings, integers and booleans are dropped.
+func (s *Span) SetAttr(kv ...any) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := 0; i+1 < len(kv); i += 2 {
+		key, ok := kv[i].(string)
+		if !ok {
+			continue
+		}
+		var value any
+		switch v := kv[i+1].(type) {
+		case string, bool:
+			value = v
+		case int:
+			value = int64(v)
+		case int64:
+			value = v
+		case uint32:
+			value = int64(v)
+		default:
+			continue
+		}
+		s.attrs = append(s.attrs, attribute{key, value})
+	}
+}
+
+// End ends s, failed with err if it is not nil, and hands it to the
+// exporter.
+func (s *Span) End(err error) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.end = time.Now()
+	if err != nil {
+		s.err = err.Error()
+	}
+	s.mu.Unlock()
+	if e := exporter.Load(); e != nil {
+		e.add(s)
+	}
+}
+
+// WithSpanContext returns ctx with sc as the parent of the spans started
+// from it.
+func WithSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	if !sc.Valid() {
+		return ctx
+	}
+	return context.WithValue(ctx, spanKey{}, sc)
+}
+
+// FromContext returns the SpanContext of the span in ctx, if any.
+func FromContext(ctx context.Context) SpanContext {
+	switch v := ctx.Value(spanKey{}).(type) {
+	case *Span:
+		return v.Context()
+	case SpanContext:
+		return v
+	}
+	return SpanContext{}
+}
+
+// sampled decides on a new trace from its ID, so that every span of it
+// agrees.
+func (e *Exporter) sampled(traceID [16]byte) bool {
+	if e.SampleRatio >= 1 {
+		return true
+	}
+	if e.SampleRatio <= 0 {
+		return false
+	}
+	return binary.BigEndian.Uint64(traceID[8:]) < uint64(e.SampleRatio*math.MaxUint64)
+}
@@ -0,0 +1,124 @@
+// Package usage meters the traffic of each VPC on the agent. All traffic of
+// an attachment crosses its host interface, the member of the attachment's
+// VRF facing the workload, so the kernel's interface counters already count
+// it per VRF: reading them over netlink needs no nft rules or BPF programs.
+package usage
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/vishvananda/netlink"
+
+	"github.com/datum-cloud/galactic-agent/state"
+)
+
+// Counters count traffic from the workloads' side: sent into the VPC,
+// received from it.
+type Counters struct {
+	SentBytes       uint64
+	SentPackets     uint64
+	ReceivedBytes   uint64
+	ReceivedPackets uint64
+}
+
+func (c *Counters) add(o Counters) {
+	c.SentBytes += o.SentBytes
+	c.SentPackets += o.SentPackets
+	c.ReceivedBytes += o.ReceivedBytes
+	c.ReceivedPackets += o.ReceivedPackets
+}
+
+// linkKey tells a host interface from one recreated under the same name.
+type linkKey struct {
+	name  string
+	index int
+}
+
+// Meter accumulates the counters of each VPC across samples. Host
+// interfaces come and go with attachments and start counting from zero when
+// recreated, so Meter only adds what each interface counted since it was
+// last read and VPC totals never go backwards.
+type Meter struct {
+	mu     sync.Mutex
+	links  map[linkKey]Counters
+	totals map[string]Counters
+}
+
+// Sample reads the host interfaces of attachments and returns how much the
+// counters of each of their VPCs grew since the previous sample. An
+// interface read for the first time contributes everything it counted.
+// Attachments whose interface is gone are skipped.
+func (m *Meter) Sample(attachments []state.Attachment) (map[string]Counters, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.links == nil {
+		m.links = make(map[linkKey]Counters)
+		m.totals = make(map[string]Counters)
+	}
+	links := make(map[linkKey]Counters, len(attachments))
+	deltas := make(map[string]Counters)
+	var lnf netlink.LinkNotFoundError
+	for _, a := range attachments {
+		link, err := netlink.LinkByName(a.Host)
+		if errors.As(err, &lnf) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		stats := link.Attrs().Statistics
+		if stats == nil {
+			continue
+		}
+		// the host side receives what the workload sends
+		c := Counters{
+			SentBytes:       stats.RxBytes,
+			SentPackets:     stats.RxPackets,
+			ReceivedBytes:   stats.TxBytes,
+			ReceivedPackets: stats.TxPackets,
+		}
+		key := linkKey{name: a.Host, index: link.Attrs().Index}
+		links[key] = c
+		d := deltas[a.VPC]
+		d.add(since(c, m.links[key]))
+		deltas[a.VPC] = d
+	}
+	m.links = links
+	for vpc, d := range deltas {
+		t := m.totals[vpc]
+		t.add(d)
+		m.totals[vpc] = t
+	}
+	return deltas, nil
+}
+
+// Totals returns the counters of every VPC seen since the Meter was
+// created.
+func (m *Meter) Totals() map[string]Counters {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	totals := make(map[string]Counters, len(m.totals))
+	for vpc, t := range m.totals {
+		totals[vpc] = t
+	}
+	return totals
+}
+
+// since returns what c counted after last. A counter smaller than before
+// has wrapped or been reset, and counts from zero.
+func since(c, last Counters) Counters {
+	return Counters{
+		SentBytes:       delta(c.SentBytes, last.SentBytes),
+		SentPackets:     delta(c.SentPackets, last.SentPackets),
+		ReceivedBytes:   delta(c.ReceivedBytes, last.ReceivedBytes),
+		ReceivedPackets: delta(c.ReceivedPackets, last.ReceivedPackets),
+	}
+}
+
+func delta(v, last uint64) uint64 {
+	if v < last {
+		return v
+	}
+	return v - last
+}
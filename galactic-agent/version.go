@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/datum-cloud/galactic-agent/api/remote"
+)
+
+// Set at build time, e.g. with
+//
+//	go build -ldflags "-X main.version=v1.2.0 -X main.commit=$(git rev-parse HEAD) -X main.buildDate=$(date -u +%FT%TZ)"
+//
+// Unset, version and commit come from the module and VCS information Go
+// stamps into the binary, if any.
+var (
+	version   string
+	commit    string
+	buildDate string
+)
+
+// buildInfo identifies the build of the running agent.
+type buildInfo struct {
+	Version   string
+	Commit    string
+	BuildDate string
+	// Schema is the fingerprint of the envelope schema, see
+	// remote.SchemaVersion.
+	Schema string
+	Go     string
+}
+
+func currentBuild() buildInfo {
+	b := buildInfo{Version: version, Commit: commit, BuildDate: buildDate, Schema: remote.SchemaVersion, Go: runtime.Version()}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		if b.Version == "" && info.Main.Version != "(devel)" {
+			b.Version = info.Main.Version
+		}
+		modified := false
+		revision := ""
+		for _, s := range info.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				revision = s.Value
+			case "vcs.modified":
+				modified = s.Value == "true"
+			}
+		}
+		if b.Commit == "" && revision != "" {
+			b.Commit = revision
+			if modified {
+				b.Commit += "-dirty"
+			}
+		}
+	}
+	if b.Version == "" {
+		b.Version = "dev"
+	}
+	if b.Commit == "" {
+		b.Commit = "unknown"
+	}
+	if b.BuildDate == "" {
+		b.BuildDate = "unknown"
+	}
+	return b
+}
+
+// logAttrs are the log fields of b.
+func (b buildInfo) logAttrs() []any {
+	return []any{"version", b.Version, "commit", b.Commit, "build_date", b.BuildDate, "schema", b.Schema}
+}
+
+// clientID is the MQTT client ID for the configured id: id followed by the
+// version, so that the broker's client list shows what every agent runs.
+// An empty id is left to the broker to assign, and mqtt_clientid_version
+// false keeps id as it is.
+func clientID(id string) string {
+	if id == "" || !viper.GetBool("mqtt_clientid_version") {
+		return id
+	}
+	return id + "-" + currentBuild().Version
+}
+
+func newVersionCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "version",
+		Short: "Print the version, commit, build date and envelope schema of this build",
+		Run: func(cmd *cobra.Command, args []string) {
+			b := currentBuild()
+			fmt.Fprintf(os.Stdout, "version:    %s\n", b.Version)   //nolint:errcheck
+			fmt.Fprintf(os.Stdout, "commit:     %s\n", b.Commit)    //nolint:errcheck
+			fmt.Fprintf(os.Stdout, "build date: %s\n", b.BuildDate) //nolint:errcheck
+			fmt.Fprintf(os.Stdout, "schema:     %s\n", b.Schema)    //nolint:errcheck
+			fmt.Fprintf(os.Stdout, "go:         %s\n", b.Go)        //nolint:errcheck
+		},
+	}
+}
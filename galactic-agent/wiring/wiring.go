@@ -0,0 +1,160 @@
+// Package wiring builds the host side of a VPC attachment the way the agent
+// expects to find it: a VRF and a veth pair named after the attachment, the
+// host side enslaved to the VRF and answering as the workload's gateway.
+// Container runtime integrations use it and move the guest side into the
+// workload themselves.
+package wiring
+
+import (
+	"errors"
+	"net"
+
+	"github.com/vishvananda/netlink"
+
+	"github.com/datum-cloud/galactic-common/sysctl"
+	"github.com/datum-cloud/galactic-common/util"
+	"github.com/datum-cloud/galactic-common/vrf"
+)
+
+// The host side of every attachment answers as the workload's gateway on
+// these link-local addresses.
+var (
+	Gateway4 = net.ParseIP("169.254.1.1")
+	Gateway6 = net.ParseIP("fe80::1")
+)
+
+// ifaNoDAD is IFA_F_NODAD: the gateway address is usable immediately.
+const ifaNoDAD = 0x02
+
+// Attachment names the interfaces of a VPC attachment.
+type Attachment struct {
+	VPC, VPCAttachment string
+	VRF, Host, Guest   string
+
+	vpc, vpcAttachment string // base62
+}
+
+// New derives the interface names of the attachment with hex ids vpc and
+// vpcAttachment.
+func New(vpc, vpcAttachment string) (Attachment, error) {
+	a := Attachment{VPC: vpc, VPCAttachment: vpcAttachment}
+	var err error
+	if a.vpc, err = util.HexToBase62(vpc); err != nil {
+		return a, err
+	}
+	if a.vpcAttachment, err = util.HexToBase62(vpcAttachment); err != nil {
+		return a, err
+	}
+	a.VRF = util.GenerateInterfaceNameVRF(a.vpc, a.vpcAttachment)
+	a.Host = util.GenerateInterfaceNameHost(a.vpc, a.vpcAttachment)
+	a.Guest = util.GenerateInterfaceNameGuest(a.vpc, a.vpcAttachment)
+	return a, nil
+}
+
+// NotFound reports whether err is a missing link.
+func NotFound(err error) bool {
+	var lnf netlink.LinkNotFoundError
+	return errors.As(err, &lnf)
+}
+
+// Gateway returns the gateway for ip's address family.
+func Gateway(ip net.IP) net.IP {
+	if ip.To4() != nil {
+		return Gateway4
+	}
+	return Gateway6
+}
+
+// SetupHost creates the VRF and veth pair, enslaves the host side to the VRF
+// and routes addrs over it. The guest side is left in the current network
+// namespace. Pieces that already exist are reused, so SetupHost can be
+// repeated.
+func (a Attachment) SetupHost(addrs []*net.IPNet) error {
+	vrfLink, err := netlink.LinkByName(a.VRF)
+	if NotFound(err) {
+		if err := vrf.Add(a.vpc, a.vpcAttachment); err != nil {
+			return err
+		}
+		vrfLink, err = netlink.LinkByName(a.VRF)
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := netlink.LinkByName(a.Host); NotFound(err) {
+		veth := &netlink.Veth{LinkAttrs: netlink.LinkAttrs{Name: a.Host}, PeerName: a.Guest}
+		if err := netlink.LinkAdd(veth); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+	host, err := netlink.LinkByName(a.Host)
+	if err != nil {
+		return err
+	}
+	if err := netlink.LinkSetMaster(host, vrfLink); err != nil {
+		return err
+	}
+	for _, gw := range []net.IP{Gateway4, Gateway6} {
+		addr := &netlink.Addr{IPNet: netlink.NewIPNet(gw)}
+		if gw.To4() == nil {
+			addr.Flags = ifaNoDAD
+		}
+		if err := netlink.AddrReplace(host, addr); err != nil {
+			return err
+		}
+	}
+	if err := sysctl.ConfigureInterfaceSysctls(a.Host); err != nil {
+		return err
+	}
+	if err := netlink.LinkSetUp(host); err != nil {
+		return err
+	}
+	table, err := vrf.GetVRFIdForVPC(a.vpc, a.vpcAttachment)
+	if err != nil {
+		return err
+	}
+	for _, n := range addrs {
+		if err := netlink.RouteReplace(&netlink.Route{
+			Dst:       n,
+			LinkIndex: host.Attrs().Index,
+			Table:     int(table),
+			Scope:     netlink.SCOPE_LINK,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Teardown removes the host side and the VRF; deleting the host veth
+// removes the guest side with it. Pieces already gone are ignored.
+func (a Attachment) Teardown() error {
+	if host, err := netlink.LinkByName(a.Host); err == nil {
+		if err := netlink.LinkDel(host); err != nil {
+			return err
+		}
+	} else if !NotFound(err) {
+		return err
+	}
+	if _, err := netlink.LinkByName(a.VRF); err == nil {
+		return vrf.Delete(a.vpc, a.vpcAttachment)
+	} else if !NotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// HostNetworks returns addrs as host networks, the form workload addresses
+// are registered with the agent in.
+func HostNetworks(addrs ...net.IP) []*net.IPNet {
+	nets := make([]*net.IPNet, len(addrs))
+	for i, ip := range addrs {
+		bits := 128
+		if v4 := ip.To4(); v4 != nil {
+			ip, bits = v4, 32
+		}
+		nets[i] = &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}
+	}
+	return nets
+}
@@ -0,0 +1,316 @@
+package yang
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// Node is a container, list entry or leaf of the tree. The entries of a
+// list are siblings sharing a Name, each with its Keys; their key leaves
+// come first among their Children.
+type Node struct {
+	Elem
+	Value    any
+	Children []*Node
+}
+
+// Build assembles leaves into a tree under an unnamed root, in the order
+// the leaves come.
+func Build(leaves []Leaf) (*Node, error) {
+	root := &Node{}
+	for _, l := range leaves {
+		elems, err := ParsePath(l.Path)
+		if err != nil {
+			return nil, err
+		}
+		n := root
+		for i, e := range elems {
+			n = n.child(e, i == len(elems)-1)
+		}
+		n.Value = l.Value
+	}
+	return root, nil
+}
+
+// child returns n's child at e, adding it if it is missing.
+func (n *Node) child(e Elem, leaf bool) *Node {
+	for _, c := range n.Children {
+		if c.Elem.equal(e) {
+			return c
+		}
+	}
+	c := &Node{Elem: e}
+	if !leaf {
+		for _, k := range e.Keys {
+			c.Children = append(c.Children, &Node{Elem: Elem{Name: k.Name}, Value: k.Value})
+		}
+	}
+	n.Children = append(n.Children, c)
+	return c
+}
+
+func (e Elem) equal(o Elem) bool {
+	return e.Name == o.Name && slices.Equal(e.Keys, o.Keys)
+}
+
+// Find returns the node at elems below n, or nil.
+func (n *Node) Find(elems []Elem) *Node {
+	for _, e := range elems {
+		var next *Node
+		for _, c := range n.Children {
+			if c.Elem.equal(e) {
+				next = c
+				break
+			}
+		}
+		if next == nil {
+			return nil
+		}
+		n = next
+	}
+	return n
+}
+
+func (n *Node) isLeaf() bool {
+	return len(n.Children) == 0 && n.Value != nil
+}
+
+// Filter returns what the NETCONF subtree filter f (RFC 6241 section 6)
+// selects of n, or nil: leaves of f with a value are content matches,
+// empty ones select whole nodes and the others contain further filters.
+func Filter(n, f *Node) *Node {
+	var matches, others []*Node
+	for _, c := range f.Children {
+		if c.Value != nil && Text(c.Value) != "" && len(c.Children) == 0 {
+			matches = append(matches, c)
+		} else {
+			others = append(others, c)
+		}
+	}
+	for _, m := range matches {
+		found := false
+		for _, c := range n.Children {
+			if c.Name == m.Name && c.isLeaf() && Text(c.Value) == Text(m.Value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil
+		}
+	}
+	if len(others) == 0 {
+		return n
+	}
+	out := &Node{Elem: n.Elem}
+	selected := false
+	for _, c := range n.Children {
+		keep := c.isLeaf() && slices.ContainsFunc(n.Keys, func(k Key) bool { return k.Name == c.Name })
+		keep = keep || slices.ContainsFunc(matches, func(m *Node) bool { return m.Name == c.Name })
+		for _, o := range others {
+			if o.Name != c.Name {
+				continue
+			}
+			if len(o.Children) == 0 {
+				out.Children = append(out.Children, c)
+				selected = true
+				keep = false
+				break
+			}
+			if r := Filter(c, o); r != nil {
+				out.Children = append(out.Children, r)
+				selected = true
+				keep = false
+				break
+			}
+		}
+		if keep {
+			out.Children = append(out.Children, c)
+		}
+	}
+	if !selected {
+		return nil
+	}
+	return out
+}
+
+// Leaves flattens nodes, found at prefix, back into leaves. The key leaves
+// of list entries become their keys.
+func Leaves(prefix []Elem, nodes []*Node) ([]Leaf, error) {
+	var leaves []Leaf
+	for _, n := range nodes {
+		e := Elem{Name: n.Name}
+		path := append(slices.Clip(prefix), e)
+		if len(n.Children) == 0 {
+			if n.Value != nil {
+				leaves = append(leaves, Leaf{Path: PathString(path), Value: n.Value})
+			}
+			continue
+		}
+		children := n.Children
+		if keys, ok := Lists[SchemaPath(path)]; ok {
+			children = nil
+			for _, k := range keys {
+				i := slices.IndexFunc(n.Children, func(c *Node) bool { return c.Name == k && c.isLeaf() })
+				if i < 0 {
+					return nil, Errorf(TagMissingElement, PathString(path), "key %s is missing", k)
+				}
+				e.Keys = append(e.Keys, Key{Name: k, Value: Text(n.Children[i].Value)})
+			}
+			for _, c := range n.Children {
+				if !slices.Contains(keys, c.Name) {
+					children = append(children, c)
+				}
+			}
+			path[len(path)-1] = e
+		}
+		below, err := Leaves(path, children)
+		if err != nil {
+			return nil, err
+		}
+		leaves = append(leaves, below...)
+	}
+	return leaves, nil
+}
+
+// qualified is how RFC 7951 names a top level member.
+func qualified(name string) string { return Name + ":" + name }
+
+// JSON encodes n's children as RFC 7951 members, the top level ones
+// qualified with the module name.
+func (n *Node) JSON(top bool) map[string]any {
+	obj := make(map[string]any)
+	for _, c := range n.Children {
+		name := c.Name
+		if top {
+			name = qualified(name)
+		}
+		switch {
+		case len(c.Keys) > 0:
+			entries, _ := obj[name].([]any)
+			obj[name] = append(entries, c.JSON(false))
+		case c.isLeaf():
+			obj[name] = jsonValue(c.Value)
+		default:
+			obj[name] = c.JSON(false)
+		}
+	}
+	return obj
+}
+
+// MemberJSON encodes n itself as the one member of an object, as RESTCONF
+// returns a resource: a list entry becomes a list of one.
+func (n *Node) MemberJSON() map[string]any {
+	parent := &Node{Children: []*Node{n}}
+	return parent.JSON(true)
+}
+
+// jsonValue follows RFC 7951 section 6: 64 bit integers and decimals are
+// strings so that no precision is lost.
+func jsonValue(v any) any {
+	switch v := v.(type) {
+	case uint64, float64:
+		return Text(v)
+	case []string:
+		values := make([]any, len(v))
+		for i, s := range v {
+			values[i] = s
+		}
+		return values
+	}
+	return v
+}
+
+// FromJSON reads the members of an RFC 7951 object into nodes, with
+// string values. Member names may be qualified with the module name.
+func FromJSON(obj map[string]any) ([]*Node, error) {
+	var nodes []*Node
+	for name, v := range obj {
+		if module, local, ok := strings.Cut(name, ":"); ok {
+			if module != Name {
+				return nil, Errorf(TagUnknownNamespace, "", "unknown module %s", module)
+			}
+			name = local
+		}
+		switch v := v.(type) {
+		case map[string]any:
+			children, err := FromJSON(v)
+			if err != nil {
+				return nil, err
+			}
+			nodes = append(nodes, &Node{Elem: Elem{Name: name}, Children: children})
+		case []any:
+			var values []string
+			for _, entry := range v {
+				if entry, ok := entry.(map[string]any); ok {
+					children, err := FromJSON(entry)
+					if err != nil {
+						return nil, err
+					}
+					nodes = append(nodes, &Node{Elem: Elem{Name: name}, Children: children})
+					continue
+				}
+				values = append(values, fmt.Sprint(entry))
+			}
+			if values != nil {
+				nodes = append(nodes, &Node{Elem: Elem{Name: name}, Value: values})
+			}
+		case nil:
+			return nil, Errorf(TagInvalidValue, name, "null is not a value")
+		case json.Number:
+			nodes = append(nodes, &Node{Elem: Elem{Name: name}, Value: v.String()})
+		default:
+			nodes = append(nodes, &Node{Elem: Elem{Name: name}, Value: fmt.Sprint(v)})
+		}
+	}
+	return nodes, nil
+}
+
+// WriteXML encodes nodes as XML elements in the module's namespace.
+func WriteXML(w io.Writer, nodes []*Node) error {
+	for _, n := range nodes {
+		if err := n.writeXML(w, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (n *Node) writeXML(w io.Writer, top bool) error {
+	start := "<" + n.Name
+	if top {
+		start += ` xmlns="` + Namespace + `"`
+	}
+	if n.isLeaf() {
+		values := []string{Text(n.Value)}
+		if v, ok := n.Value.([]string); ok {
+			values = v
+		}
+		for _, v := range values {
+			if _, err := io.WriteString(w, start+">"); err != nil {
+				return err
+			}
+			if err := xml.EscapeText(w, []byte(v)); err != nil {
+				return err
+			}
+			if _, err := io.WriteString(w, "</"+n.Name+">"); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if _, err := io.WriteString(w, start+">"); err != nil {
+		return err
+	}
+	for _, c := range n.Children {
+		if err := c.writeXML(w, false); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, "</"+n.Name+">")
+	return err
+}
@@ -0,0 +1,164 @@
+// Package yang carries galactic-agent.yang, the agent's YANG module, and
+// the data tree it describes: the tree gNMI serves, and that NETCONF and
+// RESTCONF encode as XML and JSON.
+package yang
+
+import (
+	_ "embed"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Module is the text of galactic-agent.yang.
+//
+//go:embed galactic-agent.yang
+var Module string
+
+const (
+	Name      = "galactic-agent"
+	Namespace = "urn:datum:yang:galactic-agent"
+	Revision  = "2026-10-16"
+)
+
+// Lists maps each list of the module, by schema path, to its keys in the
+// order the module declares them.
+var Lists = map[string][]string{
+	"/config/rate-limits/limit":     {"operation"},
+	"/state/tenants/tenant":         {"name"},
+	"/state/attachments/attachment": {"vpc", "vpc-attachment"},
+	"/state/routes/route":           {"srv6-endpoint", "network"},
+	"/state/vpcs/vpc":               {"id"},
+	"/state/imported-routes/route":  {"vrf", "prefix"},
+}
+
+// Leaf is one value of the tree. Value is a string, bool, uint32, uint64,
+// float64 (decimal64) or []string (leaf-list); values parsed from clients
+// are strings. Config leaves are the ones edits may change.
+type Leaf struct {
+	Path   string
+	Value  any
+	Config bool
+}
+
+// Key is one key of a list entry.
+type Key struct {
+	Name  string
+	Value string
+}
+
+// Elem is one element of a path; entries of lists have Keys.
+type Elem struct {
+	Name string
+	Keys []Key
+}
+
+// ParsePath parses /elem/list[key=value]/leaf, keys in the module's order.
+// Key values may contain / but not ].
+func ParsePath(s string) ([]Elem, error) {
+	var elems []Elem
+	s = strings.TrimPrefix(s, "/")
+	for s != "" {
+		end := strings.IndexAny(s, "/[")
+		if end < 0 {
+			end = len(s)
+		}
+		elem := Elem{Name: s[:end]}
+		if elem.Name == "" {
+			return nil, fmt.Errorf("empty element in path %q", s)
+		}
+		s = s[end:]
+		for strings.HasPrefix(s, "[") {
+			end := strings.IndexByte(s, ']')
+			k, v, ok := strings.Cut(s[1:max(end, 1)], "=")
+			if end < 0 || !ok || k == "" {
+				return nil, fmt.Errorf("malformed key in path at %q", s)
+			}
+			elem.Keys = append(elem.Keys, Key{Name: k, Value: v})
+			s = s[end+1:]
+		}
+		if s != "" && s[0] != '/' {
+			return nil, fmt.Errorf("unexpected %q in path", s)
+		}
+		s = strings.TrimPrefix(s, "/")
+		elems = append(elems, elem)
+	}
+	return elems, nil
+}
+
+// PathString renders elems in the form ParsePath reads.
+func PathString(elems []Elem) string {
+	var b strings.Builder
+	for _, e := range elems {
+		b.WriteString("/")
+		b.WriteString(e.Name)
+		for _, k := range e.Keys {
+			fmt.Fprintf(&b, "[%s=%s]", k.Name, k.Value)
+		}
+	}
+	if b.Len() == 0 {
+		return "/"
+	}
+	return b.String()
+}
+
+// SchemaPath is elems without their keys, as Lists is keyed.
+func SchemaPath(elems []Elem) string {
+	var b strings.Builder
+	for _, e := range elems {
+		b.WriteString("/")
+		b.WriteString(e.Name)
+	}
+	return b.String()
+}
+
+// Text is the canonical string form of a leaf's value.
+func Text(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case uint32:
+		return strconv.FormatUint(uint64(v), 10)
+	case uint64:
+		return strconv.FormatUint(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', 3, 64)
+	case []string:
+		return strings.Join(v, " ")
+	}
+	return fmt.Sprint(v)
+}
+
+// Error is a failed operation, tagged with the NETCONF error-tag (RFC 6241
+// appendix A), which RESTCONF and gNMI map to their own codes.
+type Error struct {
+	Tag     string
+	Path    string
+	Message string
+}
+
+const (
+	TagInvalidValue          = "invalid-value"
+	TagMalformedMessage      = "malformed-message"
+	TagMissingElement        = "missing-element"
+	TagUnknownElement        = "unknown-element"
+	TagUnknownNamespace      = "unknown-namespace"
+	TagDataExists            = "data-exists"
+	TagDataMissing           = "data-missing"
+	TagOperationNotSupported = "operation-not-supported"
+	TagInUse                 = "in-use"
+	TagLockDenied            = "lock-denied"
+)
+
+func Errorf(tag, path, format string, args ...any) *Error {
+	return &Error{Tag: tag, Path: path, Message: fmt.Sprintf(format, args...)}
+}
+
+func (e *Error) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+	return e.Path + ": " + e.Message
+}
@@ -484,7 +484,7 @@ def test_mqtt_injection():
     
     # Run the test script
     print_step(3, "Injecting test routes via MQTT...")
-    result = run_wsl(f"cd {WSL_LAB_DIR} && /usr/local/go/bin/go run test-mqtt-route.go", timeout=30)
+    result = run_wsl(f"cd {WSL_LAB_DIR}/galactic-agent && /usr/local/go/bin/go build -o galactic-testctl ./cmd/galactic-testctl && sudo ./galactic-testctl --config ../galactic-agent-config.yaml demo", timeout=30)
     
     if result:
         print(result.stdout)
@@ -611,17 +611,17 @@ def show_topology():
     print(f"\n{Colors.BOLD}Lab Testing: Protobuf Route Injection via MQTT:{Colors.ENDC}")
     protobuf_info = """
     ┌─────────────────────────────────────────────────────────────────────────────────────┐
-    │  HOW TO INJECT ROUTES FOR TESTING (Using test-mqtt-route.go)                        │
+    │  HOW TO INJECT ROUTES FOR TESTING (Using galactic-testctl)                          │
     ├─────────────────────────────────────────────────────────────────────────────────────┤
     │                                                                                     │
-    │  1. The test script (test-mqtt-route.go) does:                                      │
+    │  1. The test tool (galactic-agent/cmd/galactic-testctl) does:                       │
     │     • Creates a Protobuf RouteUpdate message                                        │
     │     • Serializes it to binary format                                                │
     │     • Publishes to MQTT topic: galactic/routes/wsl                                  │
     │                                                                                     │
     │  2. Run the test:                                                                   │
-    │     cd ~/datum/galantic-vpc                                                         │
-    │     go run test-mqtt-route.go                                                       │
+    │     cd ~/datum/galantic-vpc/galactic-agent                                          │
+    │     sudo ./galactic-testctl --config ../galactic-agent-config.yaml demo             │
     │                                                                                     │
     │  3. What gets injected (example):                                                   │
     │     RouteUpdate {                                                                   │
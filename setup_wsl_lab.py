@@ -432,7 +432,6 @@ def setup_lab_directory():
         "galactic-agent-config.yaml",
         "galactic-manifest.yaml",
         "agent.yaml",
-        "test-mqtt-route.go",
         "TUTORIAL.md",
         "README.md",
         "CHANGELOG.md",
@@ -511,8 +510,9 @@ def print_final_instructions():
    sudo ./galactic-agent -config ../galactic-agent-config.yaml{Colors.ENDC}
 
 5. Test MQTT route injection:
-   {Colors.CYAN}cd ~/datum/galantic-vpc
-   go run test-mqtt-route.go{Colors.ENDC}
+   {Colors.CYAN}cd ~/datum/galantic-vpc/galactic-agent
+   go build -o galactic-testctl ./cmd/galactic-testctl
+   sudo ./galactic-testctl --config ../galactic-agent-config.yaml demo{Colors.ENDC}
 
 {Colors.BOLD}Useful Commands:{Colors.ENDC}
    - Check lab status:    {Colors.CYAN}sudo netlab status{Colors.ENDC}